@@ -36,3 +36,7 @@ type RequestWithDbAndCollection interface {
 	GetDb() string
 	GetCollection() string
 }
+
+type RequestWithFilter interface {
+	GetFilter() []byte
+}
@@ -16,6 +16,7 @@ package api
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang/protobuf/proto" //nolint:staticcheck
@@ -56,6 +57,44 @@ import (
 //   }
 // }
 //
+// The errors the request can be retried as it is on have "retryable", with the delay to wait before retrying in
+// "retry_after_ms" if it is known, and the subsystem the errors originate from is in "subsystem":
+// {
+//   "error": {
+//      "code": "RESOURCE_EXHAUSTED"
+//      "message": "the write requests of the namespace 'ns' are limited to 100 per second"
+//      "retry": {
+//         "delay" : 10
+//      }
+//      "retryable": true
+//      "retry_after_ms": 10
+//      "subsystem": "quota"
+//   }
+// }
+// In GRPC, they are in the metadata of the ErrorInfo with the extended code.
+//
+// The fields of a document failing the validation are listed in the "field_violations" of the error:
+// {
+//   "error": {
+//      "code": "INVALID_ARGUMENT"
+//      "message": "json schema validation failed for field 'obj/name' reason 'expected string, but got number'"
+//      "field_violations": [{"field": "obj.name", "description": "expected string, but got number", "constraint": "type: \"string\""}]
+//   }
+// }
+//
+// Each violation has the constraint of the schema it violates. In GRPC, the constraints are in the metadata of the
+// ErrorInfo with the extended code, under the "constraints." key of the path of the field, as a JSON list in the order
+// of the field violations of the field.
+//
+// The keys an interactive transaction conflicted on are listed in the "conflicts" of the error:
+// {
+//   "error": {
+//      "code": "ABORTED"
+//      "message": "transaction not committed due to conflict with another transaction"
+//      "conflicts": [{"collection": "orders", "description": "conflict on collection 'orders' keys near id=12345"}]
+//   }
+// }
+//
 // The flow:
 //   * Server uses `api.Errorf({tigris code}, ...)` to report a TigrisError
 //   * We provide TigrisError.As(*runtime.HTTPStatusError) to be able to override HTTP
@@ -79,8 +118,25 @@ type TigrisError struct {
 	// Contains extended error information.
 	// For example retry information.
 	Details []proto.Message `json:"details,omitempty"`
+
+	// Retryable is true if the request can be retried as it is, Subsystem is the subsystem the error originates from.
+	// They are set by errors.WithRetryInfo.
+	Retryable bool   `json:"retryable,omitempty"`
+	Subsystem string `json:"subsystem,omitempty"`
+
+	// Constraints maps the path of a field failing the validation to the constraints of the schema it violates, in the
+	// order of its field violations. They are set by WithValidationViolation.
+	Constraints map[string][]string `json:"constraints,omitempty"`
 }
 
+// The keys of the metadata of the ErrorInfo of the GRPC status.
+const (
+	retryableMetadataKey = "retryable"
+	subsystemMetadataKey = "subsystem"
+	// constraintsMetadataKeyPrefix is followed by the path of the field in the key of its constraints.
+	constraintsMetadataKeyPrefix = "constraints."
+)
+
 // Error to return the underlying error message.
 func (e *TigrisError) Error() string {
 	return e.Message
@@ -113,6 +169,162 @@ func (e *TigrisError) RetryDelay() time.Duration {
 	return dur
 }
 
+// WithSubsystem sets the subsystem the error originates from.
+func (e *TigrisError) WithSubsystem(subsystem string) *TigrisError {
+	e.Subsystem = subsystem
+	return e
+}
+
+// WithFieldViolation attaches a field of a document failing the validation to the error.
+func (e *TigrisError) WithFieldViolation(field string, description string) *TigrisError {
+	for _, d := range e.Details {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: field, Description: description})
+			return e
+		}
+	}
+
+	return e.WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: field, Description: description}},
+	})
+}
+
+// FieldViolations returns the fields failing the validation attached to the error.
+func (e *TigrisError) FieldViolations() []*errdetails.BadRequest_FieldViolation {
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, d := range e.Details {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			violations = append(violations, br.FieldViolations...)
+		}
+	}
+
+	return violations
+}
+
+// WithValidationViolation attaches a field of a document failing the validation with the constraint of the schema it
+// violates to the error, the constraint is empty if the field doesn't violate a keyword of the schema.
+func (e *TigrisError) WithValidationViolation(field string, reason string, constraint string) *TigrisError {
+	e.WithFieldViolation(field, reason)
+
+	// the field may have been attached by WithFieldViolation before, without constraints
+	count := 0
+	for _, f := range e.FieldViolations() {
+		if f.Field == field {
+			count++
+		}
+	}
+	if e.Constraints == nil {
+		e.Constraints = make(map[string][]string)
+	}
+	c := e.Constraints[field]
+	for len(c) < count-1 {
+		c = append(c, "")
+	}
+	e.Constraints[field] = append(c, constraint)
+
+	return e
+}
+
+// ValidationViolations returns the fields failing the validation attached to the error with their constraints, the
+// constraints are empty if they are not attached to the error.
+func (e *TigrisError) ValidationViolations() []ValidationViolation {
+	return validationViolations(e.FieldViolations(), e.Constraints)
+}
+
+// validationViolations pairs the field violations with the constraints of their fields. The constraints of a field
+// are ignored if there are not as many of them as field violations of the field.
+func validationViolations(fields []*errdetails.BadRequest_FieldViolation, constraints map[string][]string) []ValidationViolation {
+	counts := make(map[string]int)
+	for _, f := range fields {
+		counts[f.Field]++
+	}
+
+	var violations []ValidationViolation
+	seen := make(map[string]int)
+	for _, f := range fields {
+		v := ValidationViolation{FieldPath: f.Field, Reason: f.Description}
+		if c := constraints[f.Field]; len(c) == counts[f.Field] {
+			v.Constraint = c[seen[f.Field]]
+		}
+		seen[f.Field]++
+		violations = append(violations, v)
+	}
+
+	return violations
+}
+
+// constraintsFromMetadata parses the constraints of the fields from the metadata of the ErrorInfo, the constraints
+// that are not a JSON list are ignored.
+func constraintsFromMetadata(metadata map[string]string) map[string][]string {
+	var constraints map[string][]string
+	for k, v := range metadata {
+		if !strings.HasPrefix(k, constraintsMetadataKeyPrefix) {
+			continue
+		}
+		var c []string
+		if err := jsoniter.Unmarshal([]byte(v), &c); err != nil {
+			continue
+		}
+		if constraints == nil {
+			constraints = make(map[string][]string)
+		}
+		constraints[strings.TrimPrefix(k, constraintsMetadataKeyPrefix)] = c
+	}
+
+	return constraints
+}
+
+// ConflictResourceType is the type of the resource info details of the conflicts.
+const ConflictResourceType = "collection"
+
+// WithConflict attaches a collection a transaction conflicted on to the error, the description tells the keys of the
+// conflict.
+func (e *TigrisError) WithConflict(collection string, description string) *TigrisError {
+	return e.WithDetails(&errdetails.ResourceInfo{ResourceType: ConflictResourceType, ResourceName: collection, Description: description})
+}
+
+// Conflicts returns the collections a transaction conflicted on attached to the error.
+func (e *TigrisError) Conflicts() []*errdetails.ResourceInfo {
+	var conflicts []*errdetails.ResourceInfo
+	for _, d := range e.Details {
+		if ri, ok := d.(*errdetails.ResourceInfo); ok && ri.ResourceType == ConflictResourceType {
+			conflicts = append(conflicts, ri)
+		}
+	}
+
+	return conflicts
+}
+
+// Conflict is a collection a transaction conflicted on in the HTTP errors.
+type Conflict struct {
+	Collection  string `json:"collection"`
+	Description string `json:"description"`
+}
+
+// FieldViolation is a field failing the validation with the constraint of the schema it violates in the HTTP errors.
+type FieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+	Constraint  string `json:"constraint,omitempty"`
+}
+
+// ValidationViolation is a field failing the validation with the constraint of the schema it violates.
+type ValidationViolation struct {
+	FieldPath  string `json:"field_path"`
+	Reason     string `json:"reason"`
+	Constraint string `json:"constraint,omitempty"`
+}
+
+// httpError is the error of the HTTP responses.
+type httpError struct {
+	ErrorDetails
+	FieldViolations []FieldViolation `json:"field_violations,omitempty"`
+	Conflicts       []Conflict       `json:"conflicts,omitempty"`
+	Retryable       bool             `json:"retryable,omitempty"`
+	RetryAfterMs    int64            `json:"retry_after_ms,omitempty"`
+	Subsystem       string           `json:"subsystem,omitempty"`
+}
+
 // ToGRPCCode converts Tigris error code to GRPC code
 // Extended codes converted to 'Unknown' GRPC code.
 func ToGRPCCode(code Code) codes.Code {
@@ -238,8 +450,22 @@ func (e *TigrisError) As(i any) bool {
 
 // GRPCStatus converts the TigrisError and return status.Status. This is used to return grpc status to the grpc clients.
 func (e *TigrisError) GRPCStatus() *status.Status {
-	st, _ := status.New(ToGRPCCode(e.Code), e.Message).
-		WithDetails(&errdetails.ErrorInfo{Reason: CodeToString(e.Code)})
+	info := &errdetails.ErrorInfo{Reason: CodeToString(e.Code)}
+	if e.Retryable || e.Subsystem != "" || len(e.Constraints) > 0 {
+		info.Metadata = map[string]string{}
+		if e.Retryable {
+			info.Metadata[retryableMetadataKey] = "true"
+		}
+		if e.Subsystem != "" {
+			info.Metadata[subsystemMetadataKey] = e.Subsystem
+		}
+		for field, c := range e.Constraints {
+			if b, err := jsoniter.Marshal(c); err == nil {
+				info.Metadata[constraintsMetadataKeyPrefix+field] = string(b)
+			}
+		}
+	}
+	st, _ := status.New(ToGRPCCode(e.Code), e.Message).WithDetails(info)
 
 	if e.Details != nil {
 		st, _ = st.WithDetails(e.Details...)
@@ -251,10 +477,12 @@ func (e *TigrisError) GRPCStatus() *status.Status {
 // MarshalStatus marshal status object.
 func MarshalStatus(status *spb.Status) ([]byte, error) {
 	resp := struct {
-		Error ErrorDetails `json:"error"`
+		Error httpError `json:"error"`
 	}{}
 
 	resp.Error.Message = status.Message
+	var fields []*errdetails.BadRequest_FieldViolation
+	var constraints map[string][]string
 	// Get standard GRPC code first
 	resp.Error.Code = Code_name[int32(ToTigrisCode(codes.Code(status.Code)))]
 
@@ -267,6 +495,9 @@ func MarshalStatus(status *spb.Status) ([]byte, error) {
 				return nil, err
 			}
 			resp.Error.Code = ei.Reason
+			resp.Error.Retryable = ei.Metadata[retryableMetadataKey] == "true"
+			resp.Error.Subsystem = ei.Metadata[subsystemMetadataKey]
+			constraints = constraintsFromMetadata(ei.Metadata)
 		}
 		var ri errdetails.RetryInfo
 		if d.MessageIs(&ri) {
@@ -277,8 +508,30 @@ func MarshalStatus(status *spb.Status) ([]byte, error) {
 			resp.Error.Retry = &RetryInfo{
 				Delay: int32(ri.RetryDelay.AsDuration().Milliseconds()),
 			}
+			resp.Error.RetryAfterMs = ri.RetryDelay.AsDuration().Milliseconds()
+		}
+		var br errdetails.BadRequest
+		if d.MessageIs(&br) {
+			err := d.UnmarshalTo(&br)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, br.FieldViolations...)
+		}
+		var rsi errdetails.ResourceInfo
+		if d.MessageIs(&rsi) {
+			err := d.UnmarshalTo(&rsi)
+			if err != nil {
+				return nil, err
+			}
+			if rsi.ResourceType == ConflictResourceType {
+				resp.Error.Conflicts = append(resp.Error.Conflicts, Conflict{Collection: rsi.ResourceName, Description: rsi.Description})
+			}
 		}
 	}
+	for _, v := range validationViolations(fields, constraints) {
+		resp.Error.FieldViolations = append(resp.Error.FieldViolations, FieldViolation{Field: v.FieldPath, Description: v.Reason, Constraint: v.Constraint})
+	}
 
 	return jsoniter.Marshal(&resp)
 }
@@ -306,14 +559,23 @@ func FromErrorDetails(e *ErrorDetails) *TigrisError {
 // UnmarshalStatus reconstruct TigrisError from HTTP error JSON body.
 func UnmarshalStatus(b []byte) *TigrisError {
 	resp := struct {
-		Error ErrorDetails `json:"error"`
+		Error httpError `json:"error"`
 	}{}
 
 	if err := jsoniter.Unmarshal(b, &resp); err != nil {
 		return &TigrisError{Code: Code_UNKNOWN, Message: err.Error()}
 	}
 
-	return FromErrorDetails(&resp.Error)
+	te := FromErrorDetails(&resp.Error.ErrorDetails)
+	te.Retryable, te.Subsystem = resp.Error.Retryable, resp.Error.Subsystem
+	for _, v := range resp.Error.FieldViolations {
+		te = te.WithValidationViolation(v.Field, v.Description, v.Constraint)
+	}
+	for _, c := range resp.Error.Conflicts {
+		te = te.WithConflict(c.Collection, c.Description)
+	}
+
+	return te
 }
 
 // FromStatusError parses GRPC status from error into TigrisError.
@@ -321,17 +583,28 @@ func FromStatusError(err error) *TigrisError {
 	st := status.Convert(err)
 	code := ToTigrisCode(st.Code())
 
-	var details []proto.Message
+	var (
+		details     []proto.Message
+		retryable   bool
+		subsystem   string
+		constraints map[string][]string
+	)
 	for _, v := range st.Details() {
 		switch d := v.(type) {
 		case *errdetails.ErrorInfo:
 			code = CodeFromString(d.Reason)
+			retryable, subsystem = d.Metadata[retryableMetadataKey] == "true", d.Metadata[subsystemMetadataKey]
+			constraints = constraintsFromMetadata(d.Metadata)
 		case *errdetails.RetryInfo:
 			details = append(details, &errdetails.RetryInfo{RetryDelay: d.RetryDelay})
+		case *errdetails.BadRequest:
+			details = append(details, &errdetails.BadRequest{FieldViolations: d.FieldViolations})
+		case *errdetails.ResourceInfo:
+			details = append(details, &errdetails.ResourceInfo{ResourceType: d.ResourceType, ResourceName: d.ResourceName, Description: d.Description})
 		}
 	}
 
-	return &TigrisError{Code: code, Message: st.Message(), Details: details}
+	return &TigrisError{Code: code, Message: st.Message(), Details: details, Retryable: retryable, Subsystem: subsystem, Constraints: constraints}
 }
 
 // Errorf constructs TigrisError.
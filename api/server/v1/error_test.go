@@ -0,0 +1,148 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+func conflicts(err *TigrisError) []string {
+	var c []string
+	for _, v := range err.Conflicts() {
+		c = append(c, v.GetResourceName()+": "+v.GetDescription())
+	}
+	return c
+}
+
+func TestConflicts(t *testing.T) {
+	err := Errorf(Code_ABORTED, "transaction not committed due to conflict with another transaction").
+		WithConflict("orders", "conflict on collection 'orders' keys near id=12345").
+		WithConflict("users", "conflict on collection 'users'")
+	expConflicts := []string{"orders: conflict on collection 'orders' keys near id=12345", "users: conflict on collection 'users'"}
+	require.Equal(t, expConflicts, conflicts(err))
+
+	// the conflicts are in the details of the GRPC status and in the HTTP errors
+	require.Equal(t, expConflicts, conflicts(FromStatusError(err)))
+	body, mErr := MarshalStatus(err.GRPCStatus().Proto())
+	require.NoError(t, mErr)
+	require.JSONEq(t, `{"error": {
+		"code": "ABORTED",
+		"message": "transaction not committed due to conflict with another transaction",
+		"conflicts": [
+			{"collection": "orders", "description": "conflict on collection 'orders' keys near id=12345"},
+			{"collection": "users", "description": "conflict on collection 'users'"}
+		]
+	}}`, string(body))
+	require.Equal(t, expConflicts, conflicts(UnmarshalStatus(body)))
+}
+
+func TestValidationViolations(t *testing.T) {
+	err := Errorf(Code_INVALID_ARGUMENT, "json schema validation failed with 2 violations").
+		WithValidationViolation("id", "expected integer, but got string", `type: "integer"`).
+		WithValidationViolation("obj.name", "length must be <= 3, but got 4", "maxLength: 3")
+	expViolations := []ValidationViolation{
+		{FieldPath: "id", Reason: "expected integer, but got string", Constraint: `type: "integer"`},
+		{FieldPath: "obj.name", Reason: "length must be <= 3, but got 4", Constraint: "maxLength: 3"},
+	}
+	require.Equal(t, expViolations, err.ValidationViolations())
+
+	// the violations are in the details of the GRPC status and in the HTTP errors
+	require.Equal(t, expViolations, FromStatusError(err).ValidationViolations())
+	body, mErr := MarshalStatus(err.GRPCStatus().Proto())
+	require.NoError(t, mErr)
+	require.JSONEq(t, `{"error": {
+		"code": "INVALID_ARGUMENT",
+		"message": "json schema validation failed with 2 violations",
+		"field_violations": [
+			{"field": "id", "description": "expected integer, but got string", "constraint": "type: \"integer\""},
+			{"field": "obj.name", "description": "length must be <= 3, but got 4", "constraint": "maxLength: 3"}
+		]
+	}}`, string(body))
+	require.Equal(t, expViolations, UnmarshalStatus(body).ValidationViolations())
+
+	// the constraints are paired with the violations of their field, a field can violate several constraints and the
+	// constraints can have new lines
+	err = Errorf(Code_INVALID_ARGUMENT, "json schema validation failed with 4 violations").
+		WithValidationViolation("name", "length must be >= 2, but got 1", "minLength: 2").
+		WithFieldViolation("id", "expected integer, but got string").
+		WithValidationViolation("name", "does not match pattern", "pattern: \"^[a-z]+\n$\"").
+		WithFieldViolation("obj", "missing properties").
+		WithValidationViolation("obj", "additionalProperties 'x' not allowed", "additionalProperties")
+	expViolations = []ValidationViolation{
+		{FieldPath: "name", Reason: "length must be >= 2, but got 1", Constraint: "minLength: 2"},
+		{FieldPath: "id", Reason: "expected integer, but got string"},
+		{FieldPath: "name", Reason: "does not match pattern", Constraint: "pattern: \"^[a-z]+\n$\""},
+		{FieldPath: "obj", Reason: "missing properties"},
+		{FieldPath: "obj", Reason: "additionalProperties 'x' not allowed", Constraint: "additionalProperties"},
+	}
+	require.Equal(t, expViolations, err.ValidationViolations())
+
+	// the extended code and the constraints are in the same ErrorInfo
+	infos := 0
+	for _, d := range err.GRPCStatus().Details() {
+		if ei, ok := d.(*errdetails.ErrorInfo); ok {
+			infos++
+			require.Equal(t, "INVALID_ARGUMENT", ei.Reason)
+		}
+	}
+	require.Equal(t, 1, infos)
+	grpcErr := FromStatusError(err)
+	require.Equal(t, Code_INVALID_ARGUMENT, grpcErr.Code)
+	require.Equal(t, expViolations, grpcErr.ValidationViolations())
+	body, mErr = MarshalStatus(err.GRPCStatus().Proto())
+	require.NoError(t, mErr)
+	require.Equal(t, "INVALID_ARGUMENT", UnmarshalStatus(body).Code.String())
+	require.Equal(t, expViolations, UnmarshalStatus(body).ValidationViolations())
+
+	// the field violations attached without constraints have empty constraints
+	err = Errorf(Code_INVALID_ARGUMENT, "invalid document").WithFieldViolation("id", "expected integer, but got string")
+	require.Equal(t, []ValidationViolation{{FieldPath: "id", Reason: "expected integer, but got string"}}, err.ValidationViolations())
+	require.Equal(t, err.ValidationViolations(), UnmarshalStatus([]byte(`{"error": {"code": "INVALID_ARGUMENT", "message": "invalid document",
+		"field_violations": [{"field": "id", "description": "expected integer, but got string"}]}}`)).ValidationViolations())
+}
+
+func retryInfo(err *TigrisError) []interface{} {
+	return []interface{}{err.Code, err.Message, err.RetryDelay(), err.Retryable, err.Subsystem}
+}
+
+func TestRetryInfo(t *testing.T) {
+	err := Errorf(Code_RESOURCE_EXHAUSTED, "the write requests of the namespace 'ns' are limited to 100 per second").
+		WithRetry(10 * time.Millisecond).WithSubsystem("quota")
+	err.Retryable = true
+
+	// the retry information is in the details of the GRPC status and in the HTTP errors
+	require.Equal(t, retryInfo(err), retryInfo(FromStatusError(err)))
+	body, mErr := MarshalStatus(err.GRPCStatus().Proto())
+	require.NoError(t, mErr)
+	require.JSONEq(t, `{"error": {
+		"code": "RESOURCE_EXHAUSTED",
+		"message": "the write requests of the namespace 'ns' are limited to 100 per second",
+		"retry": {"delay": 10},
+		"retryable": true,
+		"retry_after_ms": 10,
+		"subsystem": "quota"
+	}}`, string(body))
+	require.Equal(t, retryInfo(err), retryInfo(UnmarshalStatus(body)))
+
+	// the errors that can't be retried don't have the retry information
+	err = Errorf(Code_NOT_FOUND, "database doesn't exist")
+	body, mErr = MarshalStatus(err.GRPCStatus().Proto())
+	require.NoError(t, mErr)
+	require.JSONEq(t, `{"error": {"code": "NOT_FOUND", "message": "database doesn't exist"}}`, string(body))
+}
@@ -31,8 +31,56 @@ const (
 
 	HeaderAccessControlAllowOrigin = "Access-Control-Allow-Origin"
 
+	// HeaderRequestId is used to correlate the request across the client and the server. The server generates it if
+	// the client doesn't pass one and always echoes it back in the response headers.
+	HeaderRequestId = "X-Request-Id"
+
 	HeaderPrefix = "Tigris-"
 
+	// HeaderSearchWarnings is set in the response of a search across multiple collections when the search failed on
+	// some of the collections and only the hits of the remaining collections are returned. It has a value per failure.
+	HeaderSearchWarnings = "Tigris-Search-Warnings"
+
+	// HeaderCdcResumeToken is the base64 encoded id of the last event received by the client, the change stream is
+	// resumed right after it.
+	HeaderCdcResumeToken = "Tigris-Cdc-Resume-Token"
+	// HeaderCdcIncludeBefore asks the change stream to emit the old document of the changes along with the new one, the
+	// old documents are only stored for the collections with "pre_images" enabled in the schema.
+	HeaderCdcIncludeBefore = "Tigris-Cdc-Include-Before"
+	// HeaderCdcFilter is the filter, in the grammar of the reads, that the events of a change stream must match.
+	HeaderCdcFilter = "Tigris-Cdc-Filter"
+	// HeaderCdcFields is the projection, in the grammar of the reads, applied to the documents of a change stream.
+	HeaderCdcFields = "Tigris-Cdc-Fields"
+	// HeaderCdcGroup is the name of the consumer group the change stream joins. The events are spread across the
+	// streams of the group and the group resumes from the position committed by its members.
+	HeaderCdcGroup = "Tigris-Cdc-Group"
+	// HeaderCdcGroupMember is the id of the member of the consumer group, it is generated if not set and returned in
+	// the response headers.
+	HeaderCdcGroupMember = "Tigris-Cdc-Group-Member"
+	// HeaderCdcGroupReset moves the committed position of the consumer group before joining it, the value is either
+	// a base64 encoded event id or "latest".
+	HeaderCdcGroupReset = "Tigris-Cdc-Group-Reset"
+
+	// HeaderSizeExact asks DescribeDatabase and DescribeCollection for the sizes computed by reading the documents
+	// instead of the estimates of the storage, the call fails if there are more documents than the row limit.
+	HeaderSizeExact = "Tigris-Size-Exact"
+	// HeaderSizeRowLimit is the maximum number of documents read to compute the exact sizes.
+	HeaderSizeRowLimit = "Tigris-Size-Row-Limit"
+	// HeaderSizeEstimated is set in the response of DescribeDatabase and DescribeCollection, it is "true" when the
+	// sizes are the estimates of the storage.
+	HeaderSizeEstimated = "Tigris-Size-Estimated"
+	// HeaderDescribeCollections is the comma separated list of the collections DescribeDatabase describes, in this
+	// order, instead of all the collections of the database.
+	HeaderDescribeCollections = "Tigris-Describe-Collections"
+	// HeaderCollectionsMetadata is set in the response of DescribeDatabase and DescribeCollection, it is the JSON
+	// object of the metadata of the described collections keyed by their names.
+	HeaderCollectionsMetadata = "Tigris-Collections-Metadata"
+
+	// HeaderTraceparent and HeaderTracestate carry the W3C trace context of the caller, the spans of the request are
+	// exported as its children.
+	HeaderTraceparent = "Traceparent"
+	HeaderTracestate  = "Tracestate"
+
 	HeaderTxID        = "Tigris-Tx-Id"
 	HeaderTxOrigin    = "Tigris-Tx-Origin"
 	SetCookie         = "Set-Cookie"
@@ -43,7 +91,8 @@ const (
 func CustomMatcher(key string) (string, bool) {
 	key = textproto.CanonicalMIMEHeaderKey(key)
 	switch key {
-	case HeaderRequestTimeout, HeaderAccessControlAllowOrigin, SetCookie, Cookie:
+	case HeaderRequestTimeout, HeaderAccessControlAllowOrigin, HeaderRequestId, SetCookie, Cookie, HeaderTraceparent,
+		HeaderTracestate:
 		return key, true
 	default:
 		if strings.HasPrefix(key, HeaderPrefix) {
@@ -37,13 +37,18 @@ const (
 
 	EventsMethodName = apiMethodPrefix + "Events"
 
+	PublishMethodName = apiMethodPrefix + "Publish"
+
+	BeginTransactionMethodName = apiMethodPrefix + "BeginTransaction"
+
 	CommitTransactionMethodName   = apiMethodPrefix + "CommitTransaction"
 	RollbackTransactionMethodName = apiMethodPrefix + "RollbackTransaction"
 
 	CreateOrUpdateCollectionMethodName = apiMethodPrefix + "CreateOrUpdateCollection"
 	DropCollectionMethodName           = apiMethodPrefix + "DropCollection"
 
-	DropDatabaseMethodName = apiMethodPrefix + "DropDatabase"
+	CreateDatabaseMethodName = apiMethodPrefix + "CreateDatabase"
+	DropDatabaseMethodName   = apiMethodPrefix + "DropDatabase"
 
 	ListDatabasesMethodName   = apiMethodPrefix + "ListDatabases"
 	ListCollectionsMethodName = apiMethodPrefix + "ListCollections"
@@ -56,6 +61,12 @@ const (
 	CreateNamespaceMethodName    = ManagementMethodPrefix + "CreateNamespace"
 	ListNamespaceMethodName      = ManagementMethodPrefix + "ListNamespaces"
 	DescribeNamespacesMethodName = ManagementMethodPrefix + "DescribeNamespaces"
+	DescribeNamespaceMethodName  = ManagementMethodPrefix + "DescribeNamespace"
+	DeleteNamespaceMethodName    = ManagementMethodPrefix + "DeleteNamespace"
+
+	// AdminRoutesMethodPrefix is the prefix of the methods the admin HTTP routes are authorized as, like the admin
+	// methods.
+	AdminRoutesMethodPrefix = "/tigrisdata.admin.v1.AdminRoutes/"
 
 	AuthMethodPrefix         = "/tigrisdata.auth.v1.Auth/"
 	GetAccessTokenMethodName = AuthMethodPrefix + "GetAccessToken"
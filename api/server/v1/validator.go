@@ -16,6 +16,7 @@ package api
 
 import (
 	"regexp"
+	"strings"
 
 	"github.com/tigrisdata/tigris/util"
 )
@@ -135,8 +136,40 @@ func (x *ReadRequest) Validate() error {
 	return nil
 }
 
+// SearchAllCollections is passed as the collection of the search request to search all the collections of the database.
+const SearchAllCollections = "*"
+
+// IsMultiCollection returns true if the search request targets more than a single collection, i.e. the collection
+// is either "*" or a comma separated list of collections.
+func (x *SearchRequest) IsMultiCollection() bool {
+	return x.GetCollection() == SearchAllCollections || strings.Contains(x.GetCollection(), ",")
+}
+
+// GetCollections returns the list of the collections of a multi-collection search request. It returns nil when all
+// the collections of the database are searched.
+func (x *SearchRequest) GetCollections() []string {
+	if x.GetCollection() == SearchAllCollections {
+		return nil
+	}
+
+	collections := strings.Split(x.GetCollection(), ",")
+	for i := range collections {
+		collections[i] = strings.TrimSpace(collections[i])
+	}
+	return collections
+}
+
 func (x *SearchRequest) Validate() error {
-	if err := isValidCollectionAndDatabase(x.Collection, x.Db); err != nil {
+	if x.IsMultiCollection() {
+		if err := isValidDatabase(x.Db); err != nil {
+			return err
+		}
+		for _, c := range x.GetCollections() {
+			if err := isValidCollection(c); err != nil {
+				return err
+			}
+		}
+	} else if err := isValidCollectionAndDatabase(x.Collection, x.Db); err != nil {
 		return err
 	}
 
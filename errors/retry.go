@@ -0,0 +1,151 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"google.golang.org/grpc/status"
+)
+
+// The subsystems the errors originate from.
+const (
+	SubsystemKV         = "kv"
+	SubsystemSearch     = "search"
+	SubsystemValidation = "validation"
+	SubsystemQuota      = "quota"
+	// SubsystemConcurrency is the concurrency limiter of the server, it rejects the requests while too many are served.
+	SubsystemConcurrency = "concurrency"
+)
+
+// SubsystemError is implemented by the errors that are not API errors to report the subsystem they originate from.
+type SubsystemError interface {
+	ErrorSubsystem() string
+}
+
+// RetryableError is implemented by the errors whose request can't be retried the same way as the other errors of their
+// code, like the quota errors that fail again until the usage of the namespace changes.
+type RetryableError interface {
+	ErrorRetryable() bool
+}
+
+// retryableCodes are the codes of the errors the requests can be retried as they are on, the other errors fail again
+// unless the request is changed.
+var retryableCodes = map[api.Code]struct{}{
+	api.Code_DEADLINE_EXCEEDED:  {},
+	api.Code_RESOURCE_EXHAUSTED: {},
+	api.Code_ABORTED:            {},
+	api.Code_UNAVAILABLE:        {},
+	api.Code_BAD_GATEWAY:        {},
+}
+
+// codeSubsystems are the subsystems of the errors of the codes that only one subsystem returns, the errors of the
+// other codes set their subsystem.
+var codeSubsystems = map[api.Code]string{
+	api.Code_ABORTED: SubsystemKV,
+}
+
+// nonRetryableError is an error the request can't be retried on as it is, whatever its code.
+type nonRetryableError struct {
+	*api.TigrisError
+}
+
+func (e *nonRetryableError) Unwrap() error {
+	return e.TigrisError
+}
+
+func (e *nonRetryableError) ErrorRetryable() bool {
+	return false
+}
+
+// NonRetryable marks the error as not retryable, like the errors of the requests exceeding a size limit that fail
+// again however many times they are retried.
+func NonRetryable(err *api.TigrisError) error {
+	return &nonRetryableError{err}
+}
+
+// IsRetryable returns true if the request failing with the error can be retried as it is. The errors with a retry
+// delay are retryable, the other ones are retryable if their code is.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var retryable RetryableError
+	if As(err, &retryable) {
+		return retryable.ErrorRetryable()
+	}
+
+	var tigrisErr *api.TigrisError
+	if As(err, &tigrisErr) && (tigrisErr.Retryable || tigrisErr.RetryDelay() > 0) {
+		return true
+	}
+
+	_, ok := retryableCodes[errorCode(err)]
+	return ok
+}
+
+// Subsystem returns the subsystem the error originates from, it is empty if it is not known.
+func Subsystem(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var subsystem SubsystemError
+	if As(err, &subsystem) {
+		return subsystem.ErrorSubsystem()
+	}
+
+	var tigrisErr *api.TigrisError
+	if As(err, &tigrisErr) && tigrisErr.Subsystem != "" {
+		return tigrisErr.Subsystem
+	}
+
+	return codeSubsystems[errorCode(err)]
+}
+
+// WithRetryInfo returns the error as an API error with whether the request can be retried on it and the subsystem it
+// originates from. The error is copied, it is often shared by the requests.
+func WithRetryInfo(err error) *api.TigrisError {
+	if err == nil {
+		return nil
+	}
+
+	var tigrisErr *api.TigrisError
+	if e, ok := err.(*api.TigrisError); ok {
+		c := *e
+		tigrisErr = &c
+	} else {
+		tigrisErr = api.FromStatusError(err)
+	}
+	tigrisErr.Retryable = IsRetryable(err)
+	tigrisErr.Subsystem = Subsystem(err)
+
+	return tigrisErr
+}
+
+// errorCode returns the code the error is returned to the clients with, the errors without a GRPC status are unknown
+// errors.
+func errorCode(err error) api.Code {
+	var tigrisErr *api.TigrisError
+	if As(err, &tigrisErr) {
+		return tigrisErr.Code
+	}
+
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if As(err, &grpcErr) {
+		return api.ToTigrisCode(grpcErr.GRPCStatus().Code())
+	}
+	return api.Code_UNKNOWN
+}
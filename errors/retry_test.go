@@ -0,0 +1,87 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+)
+
+type testStoreError struct {
+	retryable bool
+}
+
+func (e *testStoreError) Error() string {
+	return "store error"
+}
+
+func (e *testStoreError) ErrorSubsystem() string {
+	return SubsystemKV
+}
+
+func (e *testStoreError) ErrorRetryable() bool {
+	return e.retryable
+}
+
+func TestRetryInfo(t *testing.T) {
+	cases := []struct {
+		err       error
+		retryable bool
+		subsystem string
+	}{
+		{nil, false, ""},
+		{Aborted("transaction not committed due to conflict with another transaction"), true, SubsystemKV},
+		{Unavailable("search is unavailable"), true, ""},
+		{api.Errorf(api.Code_UNAVAILABLE, "search is unavailable").WithSubsystem(SubsystemSearch), true, SubsystemSearch},
+		{ResourceExhausted("request rate exceeded").WithRetry(time.Second).WithSubsystem(SubsystemQuota), true, SubsystemQuota},
+		{ResourceExhausted("too many concurrent requests").WithSubsystem(SubsystemConcurrency), true, SubsystemConcurrency},
+		{NonRetryable(ResourceExhausted("request body too large")), false, ""},
+		{api.Errorf(api.Code_INVALID_ARGUMENT, "invalid document").WithSubsystem(SubsystemValidation), false, SubsystemValidation},
+		{InvalidArgument("invalid filter"), false, ""},
+		{NotFound("database doesn't exist"), false, ""},
+		{&testStoreError{retryable: true}, true, SubsystemKV},
+		{fmt.Errorf("insert: %w", &testStoreError{retryable: false}), false, SubsystemKV},
+		{fmt.Errorf("other"), false, ""},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.retryable, IsRetryable(c.err), c.err)
+		require.Equal(t, c.subsystem, Subsystem(c.err), c.err)
+	}
+
+	// the errors are copied with their retry information
+	shared := ResourceExhausted("request read rate exceeded").WithSubsystem(SubsystemQuota)
+	err := WithRetryInfo(shared)
+	require.Equal(t, &api.TigrisError{
+		Code:      api.Code_RESOURCE_EXHAUSTED,
+		Message:   "request read rate exceeded",
+		Retryable: true,
+		Subsystem: SubsystemQuota,
+	}, err)
+	require.False(t, shared.Retryable)
+
+	// the errors that are not API errors are unknown errors
+	err = WithRetryInfo(&testStoreError{retryable: true})
+	require.Equal(t, &api.TigrisError{Code: api.Code_UNKNOWN, Message: "store error", Retryable: true, Subsystem: SubsystemKV}, err)
+	require.Nil(t, WithRetryInfo(nil))
+
+	// the retry information is kept once it is attached
+	require.True(t, IsRetryable(err))
+	require.Equal(t, SubsystemKV, Subsystem(err))
+	require.Equal(t, err, WithRetryInfo(api.FromStatusError(err)))
+}
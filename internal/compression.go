@@ -0,0 +1,118 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+// Compression is the codec compressing the encoded table data before it is stored.
+type Compression byte
+
+// Note: Do not change the order. The codec is stored in the header of the compressed values, check Compress to see
+// how it is getting used.
+const (
+	NoCompression Compression = iota
+	ZstdCompression
+	SnappyCompression
+)
+
+// maxDecompressedSize bounds the memory used to decompress a corrupted value.
+const maxDecompressedSize = 64 << 20
+
+var (
+	// the encoder and the decoder are safe for concurrent use with EncodeAll and DecodeAll.
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0), zstd.WithDecoderMaxMemory(maxDecompressedSize))
+)
+
+var compressionNames = map[Compression]string{
+	NoCompression:     "none",
+	ZstdCompression:   "zstd",
+	SnappyCompression: "snappy",
+}
+
+// ParseCompression returns the codec with the name, an empty name is no compression.
+func ParseCompression(name string) (Compression, error) {
+	if len(name) == 0 {
+		return NoCompression, nil
+	}
+	for c, n := range compressionNames {
+		if n == name {
+			return c, nil
+		}
+	}
+
+	return NoCompression, errors.InvalidArgument("unsupported compression '%s', supported are 'none', 'zstd' and 'snappy'", name)
+}
+
+func (c Compression) String() string {
+	if n, ok := compressionNames[c]; ok {
+		return n
+	}
+	return "unknown"
+}
+
+// Compress compresses the value returned by Encode with the codec. The compressed value starts with the
+// CompressedTableDataType followed by the codec, so that Decode reads the compressed and the uncompressed values alike.
+// The value is returned as it is if the codec doesn't make it smaller.
+func Compress(encoded []byte, c Compression) []byte {
+	if c == NoCompression || len(encoded) == 0 || DataType(encoded[0]) != TableDataType {
+		return encoded
+	}
+
+	header := []byte{byte(CompressedTableDataType), byte(c)}
+	var compressed []byte
+	switch c {
+	case ZstdCompression:
+		compressed = zstdEncoder.EncodeAll(encoded[1:], header)
+	case SnappyCompression:
+		compressed = append(header, snappy.Encode(nil, encoded[1:])...)
+	default:
+		return encoded
+	}
+	if len(compressed) >= len(encoded) {
+		return encoded
+	}
+
+	return compressed
+}
+
+// decompress returns the encoded table data of the compressed value without its header.
+func decompress(c Compression, compressed []byte) ([]byte, error) {
+	var (
+		decompressed []byte
+		err          error
+	)
+	switch c {
+	case ZstdCompression:
+		decompressed, err = zstdDecoder.DecodeAll(compressed, nil)
+	case SnappyCompression:
+		var size int
+		if size, err = snappy.DecodedLen(compressed); err == nil && size > maxDecompressedSize {
+			return nil, errors.Internal("unable to decompress table data of %d bytes", size)
+		}
+		decompressed, err = snappy.Decode(nil, compressed)
+	default:
+		return nil, errors.Internal("unable to decompress table data with codec '%v'", byte(c))
+	}
+	if err != nil {
+		return nil, errors.Internal("unable to decompress table data with codec '%s': %s", c, err.Error())
+	}
+
+	return decompressed, nil
+}
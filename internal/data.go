@@ -39,6 +39,8 @@ type DataType byte
 const (
 	Unknown DataType = iota
 	TableDataType
+	// CompressedTableDataType is the table data compressed by the codec stored in the second byte, check Compress.
+	CompressedTableDataType
 )
 
 const (
@@ -146,6 +148,16 @@ func Decode(b []byte) (*TableData, error) {
 		return nil, errors.Internal("unable to decode table data is empty")
 	}
 	dataType := DataType(b[0])
+	if dataType == CompressedTableDataType {
+		if len(b) < 2 {
+			return nil, errors.Internal("unable to decode compressed table data without codec")
+		}
+		decompressed, err := decompress(Compression(b[1]), b[2:])
+		if err != nil {
+			return nil, err
+		}
+		return decodeInternal(TableDataType, decompressed)
+	}
 	return decodeInternal(dataType, b[1:])
 }
 
@@ -15,6 +15,7 @@
 package internal
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -32,6 +33,34 @@ func TestEncode_Decode(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, d, data)
 	})
+	t.Run("compressed", func(t *testing.T) {
+		d := NewTableData(bytes.Repeat([]byte(`{"name": "this is a verbose document", "count": 1}`), 100))
+		encoded, err := Encode(d)
+		require.NoError(t, err)
+
+		for _, c := range []Compression{ZstdCompression, SnappyCompression} {
+			compressed := Compress(encoded, c)
+			require.Equal(t, []byte{byte(CompressedTableDataType), byte(c)}, compressed[:2])
+			require.Less(t, len(compressed)*5, len(encoded), c.String())
+			// the compressed values are not compressed again
+			require.Equal(t, compressed, Compress(compressed, c))
+
+			data, err := Decode(compressed)
+			require.NoError(t, err)
+			require.Equal(t, d, data)
+		}
+		require.Equal(t, encoded, Compress(encoded, NoCompression))
+
+		// the values that don't compress are stored as they are
+		small, err := Encode(NewTableData([]byte(`{"a": 1}`)))
+		require.NoError(t, err)
+		require.Equal(t, small, Compress(small, ZstdCompression))
+
+		_, err = Decode([]byte{byte(CompressedTableDataType), 9, 1, 2})
+		require.Equal(t, errors.Internal("unable to decompress table data with codec '9'"), err)
+		_, err = Decode([]byte{byte(CompressedTableDataType)})
+		require.Equal(t, errors.Internal("unable to decode compressed table data without codec"), err)
+	})
 	t.Run("not_implemented", func(t *testing.T) {
 		data, err := Decode([]byte(`{"a": 1, "b": "foo"}`))
 		require.Equal(t, errors.Internal("unable to decode '123'"), err)
@@ -39,6 +68,17 @@ func TestEncode_Decode(t *testing.T) {
 	})
 }
 
+func TestParseCompression(t *testing.T) {
+	for name, c := range map[string]Compression{"": NoCompression, "none": NoCompression, "zstd": ZstdCompression, "snappy": SnappyCompression} {
+		parsed, err := ParseCompression(name)
+		require.NoError(t, err)
+		require.Equal(t, c, parsed)
+	}
+
+	_, err := ParseCompression("gzip")
+	require.Equal(t, errors.InvalidArgument("unsupported compression 'gzip', supported are 'none', 'zstd' and 'snappy'"), err)
+}
+
 func Benchmark_MsgPack(b *testing.B) {
 	v := &TableData{
 		RawData: []byte(`"K1": "vK1", "K2": 1, "D1": "vD1", "random", "this is a long string, with many characters"}`),
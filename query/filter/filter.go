@@ -16,6 +16,8 @@ package filter
 
 import (
 	"bytes"
+	"fmt"
+	"strings"
 
 	"github.com/buger/jsonparser"
 	jsoniter "github.com/json-iterator/go"
@@ -60,39 +62,167 @@ func (f *EmptyFilter) Matches(_ []byte) bool                    { return true }
 func (f *EmptyFilter) MatchesDoc(_ map[string]interface{}) bool { return true }
 func (f *EmptyFilter) ToSearchFilter() []string                 { return nil }
 
+// WrappedFilter is the filter of the request. When the filter is evaluated by the search backend, only the part of
+// the filter that the search backend supports is translated to the search filter, the remaining part is evaluated
+// by the server on the documents returned by the search backend, see MatchesSearchDoc.
 type WrappedFilter struct {
 	Filter
 
 	searchFilter []string
+	// postFilter is the part of the filter that is not passed to the search backend, nil if the search filter is
+	// equivalent to the filter.
+	postFilter Filter
+	// unsupported are the predicates that can neither be evaluated by the search backend nor by the server.
+	unsupported []Filter
 }
 
 func NewWrappedFilter(filters []Filter) *WrappedFilter {
-	if len(filters) == 0 {
-		return &WrappedFilter{
-			Filter:       emptyFilter,
-			searchFilter: emptyFilter.ToSearchFilter(),
-		}
-	} else if len(filters) <= 1 {
-		return &WrappedFilter{
-			Filter:       filters[0],
-			searchFilter: filters[0].ToSearchFilter(),
+	var f Filter
+	switch len(filters) {
+	case 0:
+		f = emptyFilter
+	case 1:
+		f = filters[0]
+	default:
+		f = &AndFilter{
+			filter: filters,
 		}
 	}
 
-	andF := &AndFilter{
-		filter: filters,
+	w := &WrappedFilter{
+		Filter: f,
 	}
 
-	return &WrappedFilter{
-		Filter:       andF,
-		searchFilter: andF.ToSearchFilter(),
+	var pushed Filter
+	pushed, w.postFilter, w.unsupported = splitForSearch(f)
+	if pushed != nil {
+		w.searchFilter = pushed.ToSearchFilter()
 	}
+
+	return w
 }
 
+// SearchFilter returns the filter that is passed to the search backend.
 func (w *WrappedFilter) SearchFilter() []string {
 	return w.searchFilter
 }
 
+// SearchError returns an error listing the predicates that can't be evaluated for a search. These are the predicates
+// that the search backend doesn't support and which at the same time can't be evaluated by the server, like checking
+// if an array contains a string that has a backtick.
+func (w *WrappedFilter) SearchError() error {
+	if len(w.unsupported) == 0 {
+		return nil
+	}
+
+	predicates := make([]string, 0, len(w.unsupported))
+	for _, u := range w.unsupported {
+		predicates = append(predicates, fmt.Sprintf("%s", u))
+	}
+
+	return errors.InvalidArgument("filter is not supported by search, unsupported predicates: %s",
+		strings.Join(predicates, ", "))
+}
+
+// MatchesSearchDoc returns true if the document returned by the search backend passes the filter. The doc is the
+// parsed document and raw is its JSON encoding. The part of the filter that is not passed to the search backend is
+// applied here, which means a page of the search results may have fewer hits than the page size, and the found count
+// and the facets returned by the search backend don't account for it.
+func (w *WrappedFilter) MatchesSearchDoc(doc map[string]interface{}, raw []byte) bool {
+	if !w.MatchesDoc(doc) {
+		return false
+	}
+
+	return w.postFilter == nil || w.postFilter.Matches(raw)
+}
+
+// splitForSearch splits the filter into the part that is passed to the search backend and the part that is evaluated
+// by the server on the documents returned by the search backend. A predicate under an $and can be left out of the
+// search filter as the server then narrows down the hits, but an $or is either passed as a whole or evaluated as a
+// whole by the server, as leaving out any of its predicates drops the hits that match it.
+func splitForSearch(f Filter) (Filter, Filter, []Filter) {
+	switch ft := f.(type) {
+	case *Selector:
+		if ft.isSearchable() {
+			return ft, nil, nil
+		}
+		if ft.isPostFilterable() {
+			return nil, ft, nil
+		}
+		return nil, nil, []Filter{ft}
+	case *AndFilter:
+		var pushed, post, unsupported []Filter
+		for _, child := range ft.filter {
+			p, pf, u := splitForSearch(child)
+			if p != nil {
+				pushed = append(pushed, p)
+			}
+			if pf != nil {
+				post = append(post, pf)
+			}
+			unsupported = append(unsupported, u...)
+		}
+		return toAndFilter(pushed), toAndFilter(post), unsupported
+	case *OrFilter:
+		var unsupported []Filter
+		postFilter := false
+		for _, child := range ft.filter {
+			_, pf, u := splitForSearch(child)
+			unsupported = append(unsupported, u...)
+			if pf != nil {
+				postFilter = true
+			}
+		}
+		if len(unsupported) > 0 {
+			return nil, nil, unsupported
+		}
+		if !postFilter {
+			return ft, nil, nil
+		}
+		// the server needs to evaluate all the predicates of the $or
+		if u := notPostFilterable(ft); len(u) > 0 {
+			return nil, nil, u
+		}
+		return nil, ft, nil
+	}
+
+	return f, nil, nil
+}
+
+func toAndFilter(filters []Filter) Filter {
+	switch len(filters) {
+	case 0:
+		return nil
+	case 1:
+		return filters[0]
+	default:
+		return &AndFilter{filter: filters}
+	}
+}
+
+func notPostFilterable(f Filter) []Filter {
+	switch ft := f.(type) {
+	case *Selector:
+		if !ft.isPostFilterable() {
+			return []Filter{ft}
+		}
+	case *AndFilter:
+		var u []Filter
+		for _, child := range ft.filter {
+			u = append(u, notPostFilterable(child)...)
+		}
+		return u
+	case *OrFilter:
+		var u []Filter
+		for _, child := range ft.filter {
+			u = append(u, notPostFilterable(child)...)
+		}
+		return u
+	}
+
+	return nil
+}
+
 func None(reqFilter []byte) bool {
 	return len(reqFilter) == 0 || bytes.Equal(reqFilter, filterNone)
 }
@@ -82,3 +82,69 @@ func testLogicalSearch(t *testing.T, js []byte, factory Factory, expConverted []
 	toSearch := wrapped.Filter.ToSearchFilter()
 	require.Equal(t, expConverted, toSearch)
 }
+
+func TestSearchFilterSplit(t *testing.T) {
+	factory := Factory{
+		fields: []*schema.QueryableField{
+			schema.NewQueryableField("a", schema.Int64Type, schema.UnknownType, nil, nil),
+			schema.NewQueryableField("b", schema.Int64Type, schema.UnknownType, nil, nil),
+			schema.NewQueryableField("s", schema.StringType, schema.UnknownType, nil, nil),
+			schema.NewQueryableField("bin", schema.ByteType, schema.UnknownType, nil, nil),
+			schema.NewQueryableField("arr", schema.ArrayType, schema.StringType, nil, nil),
+		},
+	}
+
+	cases := []struct {
+		js         string
+		search     []string
+		postFilter bool
+		err        string
+	}{
+		{`{"a": 10, "s": "foo, bar"}`, []string{"a:=10&&s:=`foo, bar`"}, false, ""},
+		{`{"arr": "foo"}`, []string{"arr:=`foo`"}, false, ""},
+		// range on strings and bytes are not supported by the search backend
+		{`{"a": 10, "s": {"$gt": "foo"}}`, []string{"a:=10"}, true, ""},
+		{`{"bin": "YWJj"}`, nil, true, ""},
+		{`{"$or": [{"a": 10}, {"b": 5}]}`, []string{"a:=10", "b:=5"}, false, ""},
+		// the $or is evaluated as a whole by the server
+		{`{"a": 10, "$or": [{"b": 5}, {"s": {"$lt": "foo"}}]}`, []string{"a:=10"}, true, ""},
+		{`{"arr": "fo` + "`" + `o"}`, nil, false, "filter is not supported by search, unsupported predicates: {arr:{$eq:fo`o}}"},
+		{`{"$or": [{"arr": "foo"}, {"s": {"$lt": "foo"}}]}`, nil, false, "filter is not supported by search, unsupported predicates: {arr:{$eq:foo}}"},
+	}
+	for _, c := range cases {
+		wrapped, err := factory.WrappedFilter([]byte(c.js))
+		require.NoError(t, err)
+		require.Equal(t, c.search, wrapped.SearchFilter(), c.js)
+		require.Equal(t, c.postFilter, wrapped.postFilter != nil, c.js)
+		if len(c.err) > 0 {
+			require.EqualError(t, wrapped.SearchError(), c.err)
+		} else {
+			require.NoError(t, wrapped.SearchError())
+		}
+	}
+}
+
+func TestSearchFilterMatchesSearchDoc(t *testing.T) {
+	factory := Factory{
+		fields: []*schema.QueryableField{
+			schema.NewQueryableField("a", schema.Int64Type, schema.UnknownType, nil, nil),
+			schema.NewQueryableField("s", schema.StringType, schema.UnknownType, nil, nil),
+			schema.NewQueryableField("obj.s", schema.StringType, schema.UnknownType, nil, nil),
+		},
+	}
+
+	wrapped, err := factory.WrappedFilter([]byte(`{"a": 10, "s": {"$gt": "foo"}, "obj.s": {"$lte": "b"}}`))
+	require.NoError(t, err)
+
+	for _, c := range []struct {
+		doc     map[string]any
+		raw     string
+		matches bool
+	}{
+		{map[string]any{"a": 10, "s": "goo"}, `{"a": 10, "s": "goo", "obj": {"s": "a"}}`, true},
+		{map[string]any{"a": 10, "s": "bar"}, `{"a": 10, "s": "bar", "obj": {"s": "a"}}`, false},
+		{map[string]any{"a": 10, "s": "goo"}, `{"a": 10, "s": "goo", "obj": {"s": "c"}}`, false},
+	} {
+		require.Equal(t, c.matches, wrapped.MatchesSearchDoc(c.doc, []byte(c.raw)), c.raw)
+	}
+}
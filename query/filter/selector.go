@@ -17,6 +17,7 @@ package filter
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/buger/jsonparser"
 	api "github.com/tigrisdata/tigris/api/server/v1"
@@ -84,7 +85,7 @@ func (s *Selector) MatchesDoc(doc map[string]interface{}) bool {
 
 // Matches returns true if the input doc matches this filter.
 func (s *Selector) Matches(doc []byte) bool {
-	docValue, dtp, _, err := jsonparser.Get(doc, s.Field.Name())
+	docValue, dtp, _, err := jsonparser.Get(doc, strings.Split(s.Field.Name(), schema.ObjFlattenDelimiter)...)
 	if ulog.E(err) {
 		return false
 	}
@@ -105,6 +106,69 @@ func (s *Selector) Matches(doc []byte) bool {
 	return s.Matcher.Matches(val)
 }
 
+// isSearchable returns true if the search backend is able to evaluate this selector. The search backend only supports
+// exact match on strings and the strings are quoted using backticks, so a string that has a backtick can't be passed.
+func (s *Selector) isSearchable() bool {
+	if !s.Field.Indexed {
+		return false
+	}
+
+	switch toSearchValueType(s.Field) {
+	case schema.StringType, schema.UUIDType, schema.BoolType:
+		if s.Matcher.Type() != EQ {
+			return false
+		}
+
+		v := s.Matcher.GetValue()
+		if arr, ok := v.(*value.ArrayValue); ok {
+			for _, item := range arr.AsInterface().([]any) {
+				if strings.Contains(fmt.Sprint(item), "`") {
+					return false
+				}
+			}
+			return true
+		}
+		return !strings.Contains(v.String(), "`")
+	}
+
+	return true
+}
+
+// isPostFilterable returns true if the selector can be evaluated by the server on the documents returned by the search
+// backend.
+func (s *Selector) isPostFilterable() bool {
+	if s.Field.IsReserved() {
+		// metadata fields are not part of the document
+		return false
+	}
+
+	if s.Field.DataType == schema.ArrayType {
+		// a value on an array field is a contains check, which only the search backend can evaluate
+		_, ok := s.Matcher.GetValue().(*value.ArrayValue)
+		return ok
+	}
+
+	return true
+}
+
+// toSearchValueType returns the type of the values of the field, which is the type of the elements for an array.
+func toSearchValueType(field *schema.QueryableField) schema.FieldType {
+	if field.DataType == schema.ArrayType {
+		return field.SubType
+	}
+	return field.DataType
+}
+
+// toSearchValue quotes the strings so that the characters that have a meaning in the filter syntax of the search
+// backend, like commas or parentheses, are matched as is.
+func toSearchValue(field *schema.QueryableField, v any) any {
+	switch toSearchValueType(field) {
+	case schema.StringType, schema.UUIDType:
+		return fmt.Sprintf("`%v`", v)
+	}
+	return v
+}
+
 func (s *Selector) ToSearchFilter() []string {
 	var op string
 	switch s.Matcher.Type() {
@@ -137,12 +201,12 @@ func (s *Selector) ToSearchFilter() []string {
 				if i != 0 {
 					filterString += "&&"
 				}
-				filterString += fmt.Sprintf(op, s.Field.InMemoryName(), item)
+				filterString += fmt.Sprintf(op, s.Field.InMemoryName(), toSearchValue(s.Field, item))
 			}
 			return []string{filterString}
 		}
 	}
-	return []string{fmt.Sprintf(op, s.Field.InMemoryName(), v.AsInterface())}
+	return []string{fmt.Sprintf(op, s.Field.InMemoryName(), toSearchValue(s.Field, v.AsInterface()))}
 }
 
 // String a helpful method for logging.
@@ -17,6 +17,7 @@ package read
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/buger/jsonparser"
 	jsoniter "github.com/json-iterator/go"
@@ -108,8 +109,11 @@ func (factory *FieldFactory) Apply(document []byte) ([]byte, error) {
 		return document, nil
 	}
 
-	factory.FetchedValues = make(map[string]*JSONObject)
 	var err error
+	// nested fields are removed upfront so that they are neither part of the top level fields nor the nested included fields
+	document = factory.excludeNested(document)
+
+	factory.FetchedValues = make(map[string]*JSONObject)
 	// first extract all fields that may be useful
 	err = jsonparser.ObjectEach(document, func(key []byte, value []byte, dataType jsonparser.ValueType, offset int) error {
 		if err != nil {
@@ -149,7 +153,13 @@ func (factory *FieldFactory) applyIncludeOnly() ([]byte, error) {
 	}
 
 	index := 0
+	var nested []*SimpleField
 	for _, f := range factory.Include {
+		if sf, ok := f.(*SimpleField); ok && sf.isNested() {
+			nested = append(nested, sf)
+			continue
+		}
+
 		newValue, err := f.Apply(factory.FetchedValues)
 		if err != nil {
 			return nil, err
@@ -187,7 +197,56 @@ func (factory *FieldFactory) applyIncludeOnly() ([]byte, error) {
 		return nil, errors.Internal(err.Error())
 	}
 
-	return bb.Bytes(), nil
+	return factory.includeNested(bb.Bytes(), nested)
+}
+
+// includeNested sets the value of the nested fields in the document, the parent objects are created in the document
+// only with the included nested fields.
+func (factory *FieldFactory) includeNested(document []byte, fields []*SimpleField) ([]byte, error) {
+	for _, f := range fields {
+		keys := strings.Split(f.Name, ".")
+		parent, ok := factory.FetchedValues[keys[0]]
+		if !ok || parent.DataType != jsonparser.Object {
+			continue
+		}
+
+		value, dataType, _, err := jsonparser.Get(parent.Value, keys[1:]...)
+		if err == jsonparser.KeyPathNotFoundError {
+			continue
+		}
+		if err != nil {
+			return nil, errors.InvalidArgument(err.Error())
+		}
+		if dataType == jsonparser.String {
+			value = []byte(fmt.Sprintf(`"%s"`, value))
+		}
+
+		if document, err = jsonparser.Set(document, value, keys...); err != nil {
+			return nil, errors.Internal(err.Error())
+		}
+	}
+
+	return document, nil
+}
+
+// excludeNested removes the nested fields from a copy of the document, the top level fields are excluded when the
+// document is traversed.
+func (factory *FieldFactory) excludeNested(document []byte) []byte {
+	copied := false
+	for alias := range factory.Exclude {
+		if !strings.Contains(alias, ".") {
+			continue
+		}
+
+		if !copied {
+			// delete is in place, the caller's document must not be modified
+			document = append([]byte(nil), document...)
+			copied = true
+		}
+		document = jsonparser.Delete(document, strings.Split(alias, ".")...)
+	}
+
+	return document
 }
 
 func (factory *FieldFactory) applyExcludeOnly() ([]byte, error) {
@@ -258,6 +317,11 @@ func (s *SimpleField) GetJSONAlias() []byte {
 	return []byte(fmt.Sprintf(`"%s"`, s.Name))
 }
 
+// isNested returns true if the field is a dotted path to a field of an object.
+func (s *SimpleField) isNested() bool {
+	return strings.Contains(s.Name, ".")
+}
+
 func (s *SimpleField) Apply(data map[string]*JSONObject) ([]byte, error) {
 	if js, ok := data[s.Name]; ok {
 		return js.GetValue(), nil
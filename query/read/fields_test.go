@@ -43,3 +43,36 @@ func TestBuildFields(t *testing.T) {
 	require.Nil(t, err)
 	require.Equal(t, len(f.Include), 4)
 }
+
+func TestFieldFactoryApply(t *testing.T) {
+	doc := []byte(`{"a":1,"b":"foo","c":{"d":"bar","e":2,"f":{"g":true}}}`)
+
+	t.Run("exclude nested", func(t *testing.T) {
+		f, err := BuildFields([]byte(`{"c.d": false, "c.f.g": false}`))
+		require.NoError(t, err)
+
+		actual, err := f.Apply(doc)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"a":1,"b":"foo","c":{"e":2,"f":{}}}`, string(actual))
+		// the input document is not modified
+		require.JSONEq(t, `{"a":1,"b":"foo","c":{"d":"bar","e":2,"f":{"g":true}}}`, string(doc))
+	})
+
+	t.Run("include nested", func(t *testing.T) {
+		f, err := BuildFields([]byte(`{"a": true, "c.d": true, "c.f.g": true, "c.missing": true}`))
+		require.NoError(t, err)
+
+		actual, err := f.Apply(doc)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"a":1,"c":{"d":"bar","f":{"g":true}}}`, string(actual))
+	})
+
+	t.Run("include and exclude nested", func(t *testing.T) {
+		f, err := BuildFields([]byte(`{"b": true, "c": true, "c.d": false}`))
+		require.NoError(t, err)
+
+		actual, err := f.Apply(doc)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"b":"foo","c":{"e":2,"f":{"g":true}}}`, string(actual))
+	})
+}
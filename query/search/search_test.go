@@ -36,7 +36,7 @@ func TestSearchBuilder(t *testing.T) {
 
 	b := NewBuilder()
 	q := b.Filter(wrappedF).Query("test").Build()
-	require.Equal(t, []string{"a:=4&&int_value:=1&&string_value1:=shoe"}, q.WrappedF.SearchFilter())
+	require.Equal(t, []string{"a:=4&&int_value:=1&&string_value1:=`shoe`"}, q.WrappedF.SearchFilter())
 	require.Equal(t, "test", q.Q)
 }
 
@@ -15,9 +15,12 @@
 package sort
 
 import (
+	"strings"
+
 	"github.com/buger/jsonparser"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/tigrisdata/tigris/errors"
+	"golang.org/x/text/language"
 )
 
 // TODO: Update this to 3 once https://github.com/typesense/typesense/issues/690 is resolved.
@@ -26,8 +29,53 @@ const maxSortOrders = 2
 const (
 	ASC  = "$asc"
 	DESC = "$desc"
+
+	// NullsKey is the optional key of a sort order to control where the null values are sorted, for example
+	// {"field_1": "$asc", "$nulls": "first"}.
+	NullsKey = "$nulls"
+
+	// OrderKey and LocaleKey are the keys of the object form of a sort order, which allows to set the locale used to
+	// collate the string values, for example {"field_1": {"order": "$asc", "locale": "de"}}.
+	OrderKey  = "order"
+	LocaleKey = "locale"
+
+	// CountKey and LengthKey are the keys of a computed sort order on the number of elements of an array field, for
+	// example {"$count": "tags", "order": "$asc"}.
+	CountKey  = "$count"
+	LengthKey = "$length"
+
+	// DescPrefix is the prefix of a field name of the compact form of the sort orders for a descending order, for
+	// example ["name", "-created_at"].
+	DescPrefix = "-"
+)
+
+// Aggregate is the value computed from a field to sort on, the field itself is sorted on by default.
+type Aggregate uint8
+
+const (
+	NoAggregate Aggregate = iota
+	// CountAggregate sorts on the number of elements of an array field.
+	CountAggregate
 )
 
+// NullsPolicy tells where the null values of a field are sorted.
+type NullsPolicy uint8
+
+const (
+	// TreatNullAsMissing sorts the null values together with the missing values, this is the default.
+	TreatNullAsMissing NullsPolicy = iota
+	// NullsFirst sorts the null values at the top irrespective of where the missing values are sorted.
+	NullsFirst
+	// NullsLast sorts the null values at the end irrespective of where the missing values are sorted.
+	NullsLast
+)
+
+var nullsPolicies = map[string]NullsPolicy{
+	"missing": TreatNullAsMissing,
+	"first":   NullsFirst,
+	"last":    NullsLast,
+}
+
 type Ordering = []SortField
 
 type SortField struct {
@@ -38,18 +86,41 @@ type SortField struct {
 	// Optional; True if missing/empty/null values to be presented at the top of sort order,
 	// else they are sorted to the end by default
 	MissingValuesFirst bool
+	// Optional; where the null values are sorted, by default they are treated as missing values
+	Nulls NullsPolicy
+	// Optional; the BCP-47 tag of the locale the string values are collated with, none by default. The queries reject
+	// the sort orders with a locale until the search backend can collate with it.
+	Locale string
+	// Optional; the value computed from the field to sort on, the field itself by default
+	Aggregate Aggregate
 }
 
 func newSortField(order jsoniter.RawMessage) (SortField, error) {
+	for _, key := range []string{CountKey, LengthKey} {
+		if _, dataType, _, _ := jsonparser.Get(order, key); dataType != jsonparser.NotExist {
+			return newComputedSortField(order)
+		}
+	}
+
 	var s SortField
 	err := jsonparser.ObjectEach(order, func(k []byte, v []byte, vt jsonparser.ValueType, offset int) error {
-		switch string(v) {
-		case ASC:
-			s.Ascending = true
-		case DESC:
-			s.Ascending = false
-		default:
-			return errors.InvalidArgument("Sort order can only be `%s` or `%s`", ASC, DESC)
+		if string(k) == NullsKey {
+			policy, ok := nullsPolicies[string(v)]
+			if !ok {
+				return errors.InvalidArgument("`%s` can only be `missing`, `first` or `last`", NullsKey)
+			}
+			s.Nulls = policy
+			return nil
+		}
+
+		var err error
+		if vt == jsonparser.Object {
+			s.Ascending, s.Locale, err = parseOrderObject(v)
+		} else {
+			s.Ascending, err = parseOrder(v)
+		}
+		if err != nil {
+			return err
 		}
 		s.Name = string(k)
 		s.MissingValuesFirst = false // Forcing empty/null/missing values to the end
@@ -58,30 +129,144 @@ func newSortField(order jsoniter.RawMessage) (SortField, error) {
 	if err != nil {
 		return s, err
 	}
+	if len(s.Name) == 0 {
+		return s, errors.InvalidArgument("Sort order is missing the field name")
+	}
+	return s, nil
+}
+
+// newComputedSortField parses a sort order on a value computed from a field, like {"$count": "tags", "order": "$asc"}.
+func newComputedSortField(order jsoniter.RawMessage) (SortField, error) {
+	var (
+		s        SortField
+		hasOrder bool
+	)
+	err := jsonparser.ObjectEach(order, func(k []byte, v []byte, vt jsonparser.ValueType, offset int) error {
+		var err error
+		switch string(k) {
+		case CountKey, LengthKey:
+			if s.Aggregate != NoAggregate {
+				return errors.InvalidArgument("Sort order can only have one of `%s` or `%s`", CountKey, LengthKey)
+			}
+			if vt != jsonparser.String || len(v) == 0 {
+				return errors.InvalidArgument("`%s` must be the name of an array field", k)
+			}
+			s.Name, s.Aggregate = string(v), CountAggregate
+		case OrderKey:
+			s.Ascending, err = parseOrder(v)
+			hasOrder = true
+		default:
+			return errors.InvalidArgument("Sort order on `%s` can only have `%s`", CountKey, OrderKey)
+		}
+		return err
+	})
+	if err != nil {
+		return s, err
+	}
+	if !hasOrder {
+		return s, errors.InvalidArgument("Sort order is missing `%s`", OrderKey)
+	}
 	return s, nil
 }
 
-// UnmarshalSort expects a json array input. Examples:
+func parseOrder(order []byte) (bool, error) {
+	switch string(order) {
+	case ASC:
+		return true, nil
+	case DESC:
+		return false, nil
+	default:
+		return false, errors.InvalidArgument("Sort order can only be `%s` or `%s`", ASC, DESC)
+	}
+}
+
+// parseOrderObject parses the object form of a sort order, it returns the direction and the canonical form of the
+// locale.
+func parseOrderObject(order []byte) (bool, string, error) {
+	var (
+		ascending, hasOrder bool
+		locale              string
+	)
+	err := jsonparser.ObjectEach(order, func(k []byte, v []byte, vt jsonparser.ValueType, offset int) error {
+		var err error
+		switch string(k) {
+		case OrderKey:
+			ascending, err = parseOrder(v)
+			hasOrder = true
+		case LocaleKey:
+			tag, perr := language.Parse(string(v))
+			if vt != jsonparser.String || perr != nil {
+				return errors.InvalidArgument("`%s` is not a valid BCP-47 language tag", v)
+			}
+			locale = tag.String()
+		default:
+			return errors.InvalidArgument("Sort order can only have `%s` and `%s`", OrderKey, LocaleKey)
+		}
+		return err
+	})
+	if err != nil {
+		return false, "", err
+	}
+	if !hasOrder {
+		return false, "", errors.InvalidArgument("Sort order is missing `%s`", OrderKey)
+	}
+	return ascending, locale, nil
+}
+
+// newCompactSortField parses a sort order of the compact form, the name of the field prefixed with "-" for a descending
+// order.
+func newCompactSortField(name []byte) (SortField, error) {
+	s := SortField{Name: string(name), Ascending: true}
+	if strings.HasPrefix(s.Name, DescPrefix) {
+		s.Name, s.Ascending = strings.TrimPrefix(s.Name, DescPrefix), false
+	}
+	if len(s.Name) == 0 {
+		return s, errors.InvalidArgument("Sort order is missing the field name")
+	}
+	if strings.HasPrefix(s.Name, "$") {
+		return s, errors.InvalidArgument("`%s` is not allowed in the compact form of the sort orders", s.Name)
+	}
+	return s, nil
+}
+
+// UnmarshalSort expects a json array input, either of objects or of field names, the compact form, where a name
+// prefixed with "-" is sorted in descending order. Examples:
 //
 //	[{"field_1": "$asc"}, {"field_2": "$desc"}]
+//	[{"field_1": "$asc", "$nulls": "first"}]
+//	[{"field_1": {"order": "$asc", "locale": "de"}}]
+//	["field_1", "-field_2"]
 //	[]
 func UnmarshalSort(input jsoniter.RawMessage) (*Ordering, error) {
 	if len(input) == 0 {
 		return nil, nil
 	}
 
+	if _, dataType, _, _ := jsonparser.Get(input); dataType == jsonparser.Object {
+		return nil, errors.InvalidArgument("Invalid value for `%s`", "sort")
+	}
+
 	orders := Ordering{}
-	var err error
+	var (
+		err   error
+		first jsonparser.ValueType
+	)
 	_, err2 := jsonparser.ArrayEach(input, func(item []byte, vt jsonparser.ValueType, offset int, err1 error) {
 		if err1 != nil {
 			err = err1
 			return
 		}
 
-		if vt != jsonparser.Object {
+		if vt != jsonparser.Object && vt != jsonparser.String {
 			err = errors.InvalidArgument("Invalid value for `%s`", "sort")
 			return
 		}
+		if len(orders) == 0 {
+			first = vt
+		} else if vt != first {
+			err = errors.InvalidArgument("Sort orders can't mix the field names and the objects")
+			return
+		}
 
 		if len(orders) >= maxSortOrders {
 			err = errors.InvalidArgument("Sorting can support up to `%d` fields only", maxSortOrders)
@@ -89,7 +274,11 @@ func UnmarshalSort(input jsoniter.RawMessage) (*Ordering, error) {
 		}
 
 		var f SortField
-		f, err = newSortField(item)
+		if vt == jsonparser.String {
+			f, err = newCompactSortField(item)
+		} else {
+			f, err = newSortField(item)
+		}
 		if err != nil {
 			return
 		}
@@ -106,3 +295,31 @@ func UnmarshalSort(input jsoniter.RawMessage) (*Ordering, error) {
 
 	return &orders, nil
 }
+
+// UnmarshalSortOrDefault is UnmarshalSort, except that the default ordering is returned if the input has no sort
+// orders, either because it is empty or because it is an empty array. The default ordering is not validated.
+func UnmarshalSortOrDefault(input jsoniter.RawMessage, defaultOrdering Ordering) (*Ordering, error) {
+	ordering, err := UnmarshalSort(input)
+	if err != nil {
+		return nil, err
+	}
+	if ordering == nil || len(*ordering) == 0 {
+		if len(defaultOrdering) == 0 {
+			return ordering, nil
+		}
+		ordering = &Ordering{}
+		*ordering = append(*ordering, defaultOrdering...)
+	}
+
+	return ordering, nil
+}
+
+// Ascending returns the ordering on the fields in ascending order, in the order of the fields.
+func Ascending(fields ...string) Ordering {
+	ordering := make(Ordering, 0, len(fields))
+	for _, f := range fields {
+		ordering = append(ordering, SortField{Name: f, Ascending: true})
+	}
+
+	return ordering
+}
@@ -94,6 +94,79 @@ func TestUnmarshalSort(t *testing.T) {
 		assert.Nil(t, sort)
 	})
 
+	t.Run("with default nulls policy", func(t *testing.T) {
+		sort, err := UnmarshalSort([]byte(`[{"field_1":"$asc"}]`))
+		assert.NoError(t, err)
+		assert.Equal(t, TreatNullAsMissing, (*sort)[0].Nulls)
+	})
+
+	t.Run("with nulls policy", func(t *testing.T) {
+		for input, expected := range map[string]NullsPolicy{
+			`[{"field_1":"$asc","$nulls":"missing"}]`: TreatNullAsMissing,
+			`[{"field_1":"$asc","$nulls":"first"}]`:   NullsFirst,
+			`[{"$nulls":"last","field_1":"$desc"}]`:   NullsLast,
+		} {
+			sort, err := UnmarshalSort([]byte(input))
+			assert.NoError(t, err)
+			assert.Len(t, *sort, 1)
+
+			order := (*sort)[0]
+			assert.Equal(t, "field_1", order.Name)
+			assert.Equal(t, expected, order.Nulls)
+			assert.False(t, order.MissingValuesFirst)
+		}
+	})
+
+	t.Run("with nulls policy per order", func(t *testing.T) {
+		sort, err := UnmarshalSort([]byte(`[{"field_1":"$asc","$nulls":"first"},{"field_2":"$desc"}]`))
+		assert.NoError(t, err)
+		assert.Exactly(t, []SortField{
+			{Name: "field_1", Ascending: true, Nulls: NullsFirst},
+			{Name: "field_2", Ascending: false, Nulls: TreatNullAsMissing},
+		}, *sort)
+	})
+
+	t.Run("with invalid nulls policy", func(t *testing.T) {
+		sort, err := UnmarshalSort([]byte(`[{"field_1":"$asc","$nulls":"top"}]`))
+		assert.ErrorContains(t, err, "`$nulls` can only be `missing`, `first` or `last`")
+		assert.Nil(t, sort)
+	})
+
+	t.Run("with nulls policy but no field", func(t *testing.T) {
+		sort, err := UnmarshalSort([]byte(`[{"$nulls":"first"}]`))
+		assert.ErrorContains(t, err, "Sort order is missing the field name")
+		assert.Nil(t, sort)
+	})
+
+	t.Run("with locale", func(t *testing.T) {
+		for input, expected := range map[string]SortField{
+			`[{"name":{"order":"$asc","locale":"de"}}]`:                  {Name: "name", Ascending: true, Locale: "de"},
+			`[{"name":{"locale":"de-AT","order":"$desc"}}]`:              {Name: "name", Ascending: false, Locale: "de-AT"},
+			`[{"name":{"order":"$asc","locale":"sr-latn"}}]`:             {Name: "name", Ascending: true, Locale: "sr-Latn"},
+			`[{"name":{"order":"$asc"}}]`:                                {Name: "name", Ascending: true},
+			`[{"name":{"order":"$desc","locale":"sv"},"$nulls":"last"}]`: {Name: "name", Locale: "sv", Nulls: NullsLast},
+		} {
+			sort, err := UnmarshalSort([]byte(input))
+			assert.NoError(t, err)
+			assert.Exactly(t, []SortField{expected}, *sort)
+		}
+	})
+
+	t.Run("with invalid locale", func(t *testing.T) {
+		for input, expected := range map[string]string{
+			`[{"name":{"order":"$asc","locale":"de_DE!"}}]`: "`de_DE!` is not a valid BCP-47 language tag",
+			`[{"name":{"order":"$asc","locale":"xx"}}]`:     "`xx` is not a valid BCP-47 language tag",
+			`[{"name":{"order":"$asc","locale":1}}]`:        "`1` is not a valid BCP-47 language tag",
+			`[{"name":{"order":"asc","locale":"de"}}]`:      "Sort order can only be `$asc` or `$desc`",
+			`[{"name":{"locale":"de"}}]`:                    "Sort order is missing `order`",
+			`[{"name":{"order":"$asc","collation":"de"}}]`:  "Sort order can only have `order` and `locale`",
+		} {
+			sort, err := UnmarshalSort([]byte(input))
+			assert.ErrorContains(t, err, expected)
+			assert.Nil(t, sort)
+		}
+	})
+
 	t.Run("Unmarshal 4 sort orders", func(t *testing.T) {
 		rawInput := []byte(`[{"field_1":"$asc"},{"field_2":"$desc"},{"field_3":"$asc"},{"field_4":"$asc"}]`)
 		sort, err := UnmarshalSort(rawInput)
@@ -102,7 +175,7 @@ func TestUnmarshalSort(t *testing.T) {
 	})
 
 	t.Run("with invalid array object", func(t *testing.T) {
-		sort, err := UnmarshalSort([]byte(`["field_1"]`))
+		sort, err := UnmarshalSort([]byte(`[1]`))
 		assert.ErrorContains(t, err, "Invalid value for `sort`")
 		assert.Nil(t, sort)
 	})
@@ -113,3 +186,111 @@ func TestUnmarshalSort(t *testing.T) {
 		assert.Nil(t, sort)
 	})
 }
+
+func TestUnmarshalCompactSort(t *testing.T) {
+	t.Run("with ascending and descending", func(t *testing.T) {
+		sort, err := UnmarshalSort([]byte(`["name", "-created_at"]`))
+		assert.NoError(t, err)
+		assert.Equal(t, &Ordering{
+			{Name: "name", Ascending: true},
+			{Name: "created_at", Ascending: false},
+		}, sort)
+	})
+
+	t.Run("with descending only", func(t *testing.T) {
+		sort, err := UnmarshalSort([]byte(`["-created_at"]`))
+		assert.NoError(t, err)
+		assert.Equal(t, &Ordering{{Name: "created_at", Ascending: false}}, sort)
+	})
+
+	t.Run("with invalid names", func(t *testing.T) {
+		for input, expected := range map[string]string{
+			`["$asc"]`:                 "`$asc` is not allowed in the compact form of the sort orders",
+			`["-$count"]`:              "`$count` is not allowed in the compact form of the sort orders",
+			`[""]`:                     "Sort order is missing the field name",
+			`["-"]`:                    "Sort order is missing the field name",
+			`["a", "b", "c"]`:          "Sorting can support up to `2` fields",
+			`["name", {"age":"$asc"}]`: "Sort orders can't mix the field names and the objects",
+			`[{"age":"$asc"}, "name"]`: "Sort orders can't mix the field names and the objects",
+		} {
+			sort, err := UnmarshalSort([]byte(input))
+			assert.ErrorContains(t, err, expected, input)
+			assert.Nil(t, sort)
+		}
+	})
+}
+
+func TestUnmarshalComputedSort(t *testing.T) {
+	t.Run("with count", func(t *testing.T) {
+		for input, expected := range map[string]SortField{
+			`[{"$count":"tags","order":"$asc"}]`:       {Name: "tags", Ascending: true, Aggregate: CountAggregate},
+			`[{"order":"$desc","$count":"tags"}]`:      {Name: "tags", Ascending: false, Aggregate: CountAggregate},
+			`[{"$length":"obj.tags","order":"$desc"}]`: {Name: "obj.tags", Ascending: false, Aggregate: CountAggregate},
+		} {
+			sort, err := UnmarshalSort([]byte(input))
+			assert.NoError(t, err)
+			assert.Exactly(t, []SortField{expected}, *sort)
+		}
+	})
+
+	t.Run("with count and field", func(t *testing.T) {
+		sort, err := UnmarshalSort([]byte(`[{"$count":"tags","order":"$desc"},{"name":"$asc"}]`))
+		assert.NoError(t, err)
+		assert.Exactly(t, []SortField{
+			{Name: "tags", Ascending: false, Aggregate: CountAggregate},
+			{Name: "name", Ascending: true},
+		}, *sort)
+	})
+
+	t.Run("with invalid count", func(t *testing.T) {
+		for input, expected := range map[string]string{
+			`[{"$count":"tags"}]`:                                "Sort order is missing `order`",
+			`[{"$count":"tags","order":"asc"}]`:                  "Sort order can only be `$asc` or `$desc`",
+			`[{"$count":1,"order":"$asc"}]`:                      "`$count` must be the name of an array field",
+			`[{"$count":"","order":"$asc"}]`:                     "`$count` must be the name of an array field",
+			`[{"$count":"tags","$length":"ids","order":"$asc"}]`: "Sort order can only have one of `$count` or `$length`",
+			`[{"$count":"tags","order":"$asc","name":"$asc"}]`:   "Sort order on `$count` can only have `order`",
+			`[{"$count":"tags","order":"$asc","$nulls":"last"}]`: "Sort order on `$count` can only have `order`",
+		} {
+			sort, err := UnmarshalSort([]byte(input))
+			assert.ErrorContains(t, err, expected)
+			assert.Nil(t, sort)
+		}
+	})
+}
+
+func TestUnmarshalSortOrDefault(t *testing.T) {
+	defaultOrdering := Ascending("id", "created_at")
+
+	t.Run("empty input yields the default", func(t *testing.T) {
+		for _, input := range []string{``, `[]`} {
+			sort, err := UnmarshalSortOrDefault([]byte(input), defaultOrdering)
+			assert.NoError(t, err)
+			assert.Exactly(t, &Ordering{{Name: "id", Ascending: true}, {Name: "created_at", Ascending: true}}, sort)
+		}
+
+		sort, err := UnmarshalSortOrDefault(nil, defaultOrdering)
+		assert.NoError(t, err)
+		assert.Exactly(t, &Ordering{{Name: "id", Ascending: true}, {Name: "created_at", Ascending: true}}, sort)
+
+		// the default isn't shared with the returned ordering
+		(*sort)[0].Name = "name"
+		assert.Equal(t, "id", defaultOrdering[0].Name)
+	})
+
+	t.Run("explicit input overrides the default", func(t *testing.T) {
+		sort, err := UnmarshalSortOrDefault([]byte(`["-name"]`), defaultOrdering)
+		assert.NoError(t, err)
+		assert.Exactly(t, &Ordering{{Name: "name", Ascending: false}}, sort)
+
+		sort, err = UnmarshalSortOrDefault([]byte(`[{"name":"descending"}]`), defaultOrdering)
+		assert.ErrorContains(t, err, "Sort order can only be `$asc` or `$desc`")
+		assert.Nil(t, sort)
+	})
+
+	t.Run("no default", func(t *testing.T) {
+		sort, err := UnmarshalSortOrDefault(nil, nil)
+		assert.NoError(t, err)
+		assert.Nil(t, sort)
+	})
+}
@@ -0,0 +1,144 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package update
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+
+	"github.com/buger/jsonparser"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/schema"
+)
+
+// numberCoercer converts the numbers of the "$set" operator to the declared type of their field, so that an integer
+// set on a "number" field is stored as a float and an integral float set on an integer field is stored as an integer.
+type numberCoercer struct {
+	collection *schema.DefaultCollection
+}
+
+func (c *numberCoercer) coerce(field string, value []byte, dataType jsonparser.ValueType) ([]byte, error) {
+	switch dataType {
+	case jsonparser.Number:
+		if f, err := c.collection.GetQueryableField(field); err == nil {
+			return coerceNumber(field, f.DataType, value)
+		}
+	case jsonparser.Object:
+		return c.coerceObject(field, value)
+	case jsonparser.Array:
+		if f, err := c.collection.GetQueryableField(field); err == nil && f.DataType == schema.ArrayType {
+			return coerceArray(field, f.SubType, value)
+		}
+	}
+
+	return value, nil
+}
+
+func (c *numberCoercer) coerceObject(parent string, value []byte) ([]byte, error) {
+	output := value
+	err := jsonparser.ObjectEach(value, func(key []byte, nested []byte, dataType jsonparser.ValueType, _ int) error {
+		coerced, err := c.coerce(parent+schema.ObjFlattenDelimiter+string(key), nested, dataType)
+		if err != nil || bytes.Equal(coerced, nested) {
+			return err
+		}
+
+		output, err = jsonparser.Set(output, coerced, string(key))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+func coerceArray(field string, itemType schema.FieldType, value []byte) ([]byte, error) {
+	var (
+		output  = []byte{'['}
+		mutated bool
+		err     error
+	)
+	_, arrErr := jsonparser.ArrayEach(value, func(item []byte, dataType jsonparser.ValueType, _ int, _ error) {
+		if err != nil {
+			return
+		}
+		if len(output) > 1 {
+			output = append(output, ',')
+		}
+
+		switch dataType {
+		case jsonparser.Number:
+			var coerced []byte
+			if coerced, err = coerceNumber(field, itemType, item); err != nil {
+				return
+			}
+			mutated = mutated || !bytes.Equal(coerced, item)
+			output = append(output, coerced...)
+		case jsonparser.String:
+			output = append(append(append(output, '"'), item...), '"')
+		default:
+			output = append(output, item...)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if arrErr != nil || !mutated {
+		return value, nil
+	}
+
+	return append(output, ']'), nil
+}
+
+func coerceNumber(field string, fieldType schema.FieldType, value []byte) ([]byte, error) {
+	switch fieldType {
+	case schema.DoubleType:
+		if bytes.ContainsAny(value, ".eE") {
+			return value, nil
+		}
+		f, err := strconv.ParseFloat(string(value), 64)
+		if err != nil {
+			return nil, errors.InvalidArgument("field '%s' is not a valid number", field)
+		}
+		return append([]byte(strconv.FormatFloat(f, 'f', -1, 64)), '.', '0'), nil
+	case schema.Int32Type:
+		return coerceInteger(field, value, math.MinInt32, math.MaxInt32)
+	case schema.Int64Type:
+		return coerceInteger(field, value, math.MinInt64, math.MaxInt64)
+	}
+
+	return value, nil
+}
+
+func coerceInteger(field string, value []byte, min int64, max int64) ([]byte, error) {
+	if i, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+		if i < min || i > max {
+			return nil, errors.InvalidArgument("value of field '%s' is out of the range of its integer type", field)
+		}
+		return value, nil
+	}
+
+	f, err := strconv.ParseFloat(string(value), 64)
+	if err != nil || f != math.Trunc(f) {
+		return nil, errors.InvalidArgument("field '%s' is an integer, '%s' is not an integer", field, value)
+	}
+	// max+1 is the first value out of the range, as a float64 it is exact for both the int32 and int64 ranges
+	if f < float64(min) || f >= float64(max)+1 {
+		return nil, errors.InvalidArgument("value of field '%s' is out of the range of its integer type", field)
+	}
+
+	return []byte(strconv.FormatInt(int64(f), 10)), nil
+}
@@ -15,11 +15,17 @@
 package update
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/buger/jsonparser"
 	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/schema"
 	"github.com/tigrisdata/tigris/util/log"
 )
 
@@ -29,6 +35,16 @@ type FieldOPType string
 const (
 	Set   FieldOPType = "$set"
 	UnSet FieldOPType = "$unset"
+	Bit   FieldOPType = "$bit"
+)
+
+// BitwiseOPType is the bitwise operation applied by the "$bit" field operator.
+type BitwiseOPType string
+
+const (
+	BitAnd BitwiseOPType = "and"
+	BitOr  BitwiseOPType = "or"
+	BitXor BitwiseOPType = "xor"
 )
 
 // BuildFieldOperators un-marshals request "fields" present in the Update API and returns a FieldOperatorFactory
@@ -46,6 +62,8 @@ func BuildFieldOperators(reqFields []byte) (*FieldOperatorFactory, error) {
 			operators[string(Set)] = NewFieldOperator(Set, val)
 		} else if op == string(UnSet) {
 			operators[string(UnSet)] = NewFieldOperator(UnSet, val)
+		} else if op == string(Bit) {
+			operators[string(Bit)] = NewFieldOperator(Bit, val)
 		}
 	}
 
@@ -58,43 +76,669 @@ func BuildFieldOperators(reqFields []byte) (*FieldOperatorFactory, error) {
 // MergeAndGet method to convert the input to the output JSON that needs to be persisted in the database.
 type FieldOperatorFactory struct {
 	FieldOperators map[string]*FieldOperator
+
+	coercer       *numberCoercer
+	bitCollection *schema.DefaultCollection
+	bestEffort    bool
+	canonical     bool
+}
+
+// RejectedField is a field of the request that is not applied by MergeAndGet in the best-effort mode.
+type RejectedField struct {
+	Field  string
+	Reason string
+}
+
+// BestEffort makes MergeAndGet apply the fields of the "$set" and "$bit" operators that are valid and reject the
+// others, instead of failing the whole merge on the first invalid field. The merge still fails if the request itself
+// is malformed.
+func (factory *FieldOperatorFactory) BestEffort() {
+	factory.bestEffort = true
+}
+
+// CoerceNumbers enables the schema-aware coercion of the numbers of the "$set" operator in MergeAndGet. The integers
+// set on a "number" field are stored as floats, and the integral numbers set on an integer field are stored as
+// integers. A number outside the range of its integer field is rejected.
+func (factory *FieldOperatorFactory) CoerceNumbers(collection *schema.DefaultCollection) {
+	factory.coercer = &numberCoercer{collection: collection}
+}
+
+// CheckBitFields makes MergeAndGet check the fields of the "$bit" operator against the schema of the collection, the
+// operator is only applied to the fields declared as integers, whatever the value of the field in the document.
+func (factory *FieldOperatorFactory) CheckBitFields(collection *schema.DefaultCollection) {
+	factory.bitCollection = collection
+}
+
+// Canonicalize makes MergeAndGet emit the keys of the objects of the merged document sorted, recursively, so that
+// the same document is always stored with the same bytes whatever the order of its keys in the existing document and
+// in the request. The order of the elements of the arrays is kept and the whitespaces are removed.
+func (factory *FieldOperatorFactory) Canonicalize() {
+	factory.canonical = true
+}
+
+// Operators returns the names of the field operators of the request, sorted.
+func (factory *FieldOperatorFactory) Operators() []string {
+	operators := make([]string, 0, len(factory.FieldOperators))
+	for op := range factory.FieldOperators {
+		operators = append(operators, op)
+	}
+	sort.Strings(operators)
+
+	return operators
+}
+
+// Fields returns the fields changed by the field operators of the request, in the dotted form used by the operators.
+func (factory *FieldOperatorFactory) Fields() ([]string, error) {
+	var fields []string
+	for _, op := range factory.Operators() {
+		fieldOp := factory.FieldOperators[op]
+		if fieldOp.Op == UnSet {
+			unsetFields, err := parseUnset(fieldOp.Input)
+			if err != nil {
+				return nil, err
+			}
+			for _, unset := range unsetFields {
+				fields = append(fields, unset.field)
+			}
+			continue
+		}
+
+		err := jsonparser.ObjectEach(fieldOp.Input, func(key []byte, _ []byte, _ jsonparser.ValueType, _ int) error {
+			fields = append(fields, string(key))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return fields, nil
 }
 
 // MergeAndGet method to converts the input to the output after applying all the operators. First "$set" operation is
-// applied and then "$unset" which means if a field is present in both $set and $unset then it won't be stored in the
-// resulting document.
+// applied, then "$bit" and then "$unset" which means if a field is present in both $set and $unset then it won't be
+// stored in the resulting document.
 func (factory *FieldOperatorFactory) MergeAndGet(existingDoc jsoniter.RawMessage) (jsoniter.RawMessage, error) {
+	out, _, err := factory.MergeAndGetWithRejected(existingDoc)
+	return out, err
+}
+
+// MergeAndGetWithRejected is MergeAndGet that also returns the fields rejected in the best-effort mode, in the order of
+// the operators they are rejected by. Nothing is rejected in the default strict mode, the merge fails instead.
+func (factory *FieldOperatorFactory) MergeAndGetWithRejected(existingDoc jsoniter.RawMessage) (jsoniter.RawMessage, []RejectedField, error) {
 	out := existingDoc
-	var err error
+	var (
+		rejected []RejectedField
+		err      error
+	)
 	if setFieldOp, ok := factory.FieldOperators[string(Set)]; ok {
-		if out, err = factory.set(out, setFieldOp.Input); err != nil {
-			return nil, err
+		if out, err = factory.set(out, setFieldOp.Input, &rejected); err != nil {
+			return nil, nil, err
+		}
+	}
+	if bitFieldOp, ok := factory.FieldOperators[string(Bit)]; ok {
+		if out, err = factory.bit(out, bitFieldOp.Input, &rejected); err != nil {
+			return nil, nil, err
 		}
 	}
 	if unsetFieldOp, ok := factory.FieldOperators[string(UnSet)]; ok {
 		if out, err = factory.remove(out, unsetFieldOp.Input); err != nil {
+			return nil, nil, err
+		}
+	}
+	if factory.canonical {
+		if out, err = canonicalize(out); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return out, rejected, nil
+}
+
+// MergeAndGetWithPatch is MergeAndGet that also returns the JSON merge patch (RFC 7386) of the net change to the
+// existing document: the added and changed fields, and the removed fields as null. The keys of the patch are sorted. A
+// field set to null is in the patch as it is, the same way as a removed field, and a number is changed if its
+// representation is. The patch is an empty object if the operators don't change the document.
+func (factory *FieldOperatorFactory) MergeAndGetWithPatch(existingDoc jsoniter.RawMessage) (jsoniter.RawMessage, jsoniter.RawMessage, error) {
+	out, _, err := factory.MergeAndGetWithRejected(existingDoc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	existing, existingType, _, err := jsonparser.Get(existingDoc)
+	if err != nil || existingType != jsonparser.Object {
+		return nil, nil, errors.InvalidArgument("invalid document: expected an object")
+	}
+	merged, _, _, err := jsonparser.Get(out)
+	if err != nil {
+		return nil, nil, errors.InvalidArgument("invalid document: %s", err.Error())
+	}
+
+	var patch bytes.Buffer
+	if _, err = writeMergePatch(&patch, existing, merged); err != nil {
+		return nil, nil, err
+	}
+	return out, patch.Bytes(), nil
+}
+
+// ByteRange is the range [Start, End) of the bytes of a document, it is empty for an insertion at Start.
+type ByteRange struct {
+	Start int
+	End   int
+}
+
+// WriteAmplification is the estimate of how much of the existing document MergeAndGet rewrites.
+type WriteAmplification struct {
+	// FullRewrite is true if the whole document is re-encoded, instead of the targeted changes of jsonparser.Set and
+	// jsonparser.Delete.
+	FullRewrite bool
+	// Ranges are the ranges of the existing document that are replaced or that the new fields are inserted at, sorted
+	// and merged.
+	Ranges []ByteRange
+	// RewrittenBytes is the number of bytes of the existing document that are replaced.
+	RewrittenBytes int
+}
+
+// EstimateWriteAmplification returns how the operators change the existing document in MergeAndGet. The ranges are
+// estimated on the existing document, the changes of a field that is set by the operators one after the other are
+// merged.
+func (factory *FieldOperatorFactory) EstimateWriteAmplification(existingDoc jsoniter.RawMessage) (*WriteAmplification, error) {
+	_, dataType, _, err := jsonparser.Get(existingDoc)
+	if factory.canonical || err != nil || dataType != jsonparser.Object {
+		return &WriteAmplification{
+			FullRewrite:    true,
+			Ranges:         []ByteRange{{Start: 0, End: len(existingDoc)}},
+			RewrittenBytes: len(existingDoc),
+		}, nil
+	}
+
+	var ranges []ByteRange
+	for _, op := range factory.Operators() {
+		fieldOp := factory.FieldOperators[op]
+		if fieldOp.Op == UnSet {
+			unsetFields, err := parseUnset(fieldOp.Input)
+			if err != nil {
+				return nil, err
+			}
+			for _, unset := range unsetFields {
+				// the missing fields are not removed
+				if r, found := fieldRange(existingDoc, strings.Split(unset.field, ".")); found {
+					ranges = append(ranges, r)
+				}
+			}
+			continue
+		}
+
+		err := jsonparser.ObjectEach(fieldOp.Input, func(key []byte, _ []byte, _ jsonparser.ValueType, _ int) error {
+			r, _ := fieldRange(existingDoc, strings.Split(string(key), "."))
+			ranges = append(ranges, r)
+			return nil
+		})
+		if err != nil {
 			return nil, err
 		}
 	}
 
-	return out, nil
+	estimate := &WriteAmplification{Ranges: mergeRanges(ranges)}
+	for _, r := range estimate.Ranges {
+		estimate.RewrittenBytes += r.End - r.Start
+	}
+	return estimate, nil
+}
+
+// fieldRange returns the range of the value of the field in the document and true if the field exists. The new fields
+// are inserted at the end of their closest existing object, the value of an ancestor that is not an object is replaced.
+func fieldRange(doc []byte, keys []string) (ByteRange, bool) {
+	for i := len(keys); i > 0; i-- {
+		value, dataType, end, err := jsonparser.Get(doc, keys[:i]...)
+		if err != nil || dataType == jsonparser.NotExist {
+			continue
+		}
+
+		start := end - len(value)
+		if dataType == jsonparser.String {
+			// the strings are returned without their quotes
+			start -= 2
+		}
+		if i < len(keys) && dataType == jsonparser.Object {
+			return ByteRange{Start: end - 1, End: end - 1}, false
+		}
+		return ByteRange{Start: start, End: end}, i == len(keys)
+	}
+
+	end := bytes.LastIndexByte(doc, '}')
+	return ByteRange{Start: end, End: end}, false
+}
+
+// mergeRanges sorts the ranges and merges the overlapping ones, the insertions in a replaced range are part of it.
+func mergeRanges(ranges []ByteRange) []ByteRange {
+	sort.Slice(ranges, func(i, j int) bool {
+		if ranges[i].Start == ranges[j].Start {
+			return ranges[i].End < ranges[j].End
+		}
+		return ranges[i].Start < ranges[j].Start
+	})
+
+	var merged []ByteRange
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && overlaps(merged[n-1], r) {
+			if r.End > merged[n-1].End {
+				merged[n-1].End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// overlaps returns true if the range starting after the previous one overlaps it, an insertion at the end of a range
+// is part of it.
+func overlaps(previous ByteRange, r ByteRange) bool {
+	if r.Start == previous.End {
+		return r.Start == r.End || previous.Start == previous.End
+	}
+	return r.Start < previous.End
+}
+
+// canonicalize returns the document with the keys of its objects sorted. The values are copied as is, the numbers keep
+// their representation.
+func canonicalize(doc jsoniter.RawMessage) (jsoniter.RawMessage, error) {
+	value, dataType, _, err := jsonparser.Get(doc)
+	if err != nil {
+		return nil, errors.InvalidArgument("invalid document: %s", err.Error())
+	}
+
+	var buf bytes.Buffer
+	if err = writeCanonical(&buf, value, dataType); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeCanonical(buf *bytes.Buffer, value []byte, dataType jsonparser.ValueType) error {
+	switch dataType {
+	case jsonparser.Object:
+		members, err := objectMembers(value)
+		if err != nil {
+			return err
+		}
+
+		buf.WriteByte('{')
+		for i, m := range members {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err = writeKey(buf, m.key); err != nil {
+				return err
+			}
+			if err = writeCanonical(buf, m.value, m.dataType); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case jsonparser.Array:
+		var err error
+		first := true
+		buf.WriteByte('[')
+		_, arrErr := jsonparser.ArrayEach(value, func(item []byte, itemType jsonparser.ValueType, _ int, _ error) {
+			if err != nil {
+				return
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			err = writeCanonical(buf, item, itemType)
+		})
+		if err != nil {
+			return err
+		}
+		if arrErr != nil {
+			return errors.InvalidArgument("invalid document: %s", arrErr.Error())
+		}
+		buf.WriteByte(']')
+	case jsonparser.String:
+		// the strings are returned still escaped and without their quotes
+		buf.WriteByte('"')
+		buf.Write(value)
+		buf.WriteByte('"')
+	default:
+		buf.Write(value)
+	}
+	return nil
+}
+
+type objectMember struct {
+	key      string
+	value    []byte
+	dataType jsonparser.ValueType
+}
+
+// objectMembers returns the members of the object sorted by key, the order of the duplicated keys is kept.
+func objectMembers(value []byte) ([]objectMember, error) {
+	var members []objectMember
+	err := jsonparser.ObjectEach(value, func(key []byte, nested []byte, nestedType jsonparser.ValueType, _ int) error {
+		members = append(members, objectMember{key: string(key), value: nested, dataType: nestedType})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.InvalidArgument("invalid document: %s", err.Error())
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].key < members[j].key
+	})
+	return members, nil
+}
+
+func writeKey(buf *bytes.Buffer, key string) error {
+	// the keys are unescaped by the parser
+	encoded, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	buf.Write(encoded)
+	buf.WriteByte(':')
+	return nil
+}
+
+// writeMergePatch writes the JSON merge patch (RFC 7386) turning the existing object into the merged one and returns
+// false if the objects are the same. The removed fields are null, the objects are patched field by field and the other
+// values are replaced as a whole if their canonical forms differ, the arrays included.
+func writeMergePatch(buf *bytes.Buffer, existing []byte, merged []byte) (bool, error) {
+	existingMembers, err := objectMembers(existing)
+	if err != nil {
+		return false, err
+	}
+	mergedMembers, err := objectMembers(merged)
+	if err != nil {
+		return false, err
+	}
+
+	changed := false
+	writeField := func(key string) error {
+		if changed {
+			buf.WriteByte(',')
+		}
+		changed = true
+		return writeKey(buf, key)
+	}
+
+	buf.WriteByte('{')
+	i, j := 0, 0
+	for i < len(existingMembers) || j < len(mergedMembers) {
+		switch {
+		case j == len(mergedMembers) || (i < len(existingMembers) && existingMembers[i].key < mergedMembers[j].key):
+			if err = writeField(existingMembers[i].key); err != nil {
+				return false, err
+			}
+			buf.WriteString("null")
+			i++
+		case i == len(existingMembers) || mergedMembers[j].key < existingMembers[i].key:
+			if err = writeField(mergedMembers[j].key); err != nil {
+				return false, err
+			}
+			if err = writeCanonical(buf, mergedMembers[j].value, mergedMembers[j].dataType); err != nil {
+				return false, err
+			}
+			j++
+		default:
+			if err = writeMemberPatch(buf, existingMembers[i], mergedMembers[j], writeField); err != nil {
+				return false, err
+			}
+			i++
+			j++
+		}
+	}
+	buf.WriteByte('}')
+
+	return changed, nil
+}
+
+// writeMemberPatch writes the patch of a field that is both in the existing and in the merged object, if it changed.
+func writeMemberPatch(buf *bytes.Buffer, existing objectMember, merged objectMember, writeField func(string) error) error {
+	if existing.dataType == jsonparser.Object && merged.dataType == jsonparser.Object {
+		var nested bytes.Buffer
+		changed, err := writeMergePatch(&nested, existing.value, merged.value)
+		if err != nil || !changed {
+			return err
+		}
+		if err = writeField(merged.key); err != nil {
+			return err
+		}
+		buf.Write(nested.Bytes())
+		return nil
+	}
+
+	var existingValue, mergedValue bytes.Buffer
+	if err := writeCanonical(&existingValue, existing.value, existing.dataType); err != nil {
+		return err
+	}
+	if err := writeCanonical(&mergedValue, merged.value, merged.dataType); err != nil {
+		return err
+	}
+	if existing.dataType == merged.dataType && bytes.Equal(existingValue.Bytes(), mergedValue.Bytes()) {
+		return nil
+	}
+	if err := writeField(merged.key); err != nil {
+		return err
+	}
+	buf.Write(mergedValue.Bytes())
+	return nil
+}
+
+// reject records the error of the field in the best-effort mode and returns nil so that the other fields are still
+// applied, in the strict mode it returns the error.
+func (factory *FieldOperatorFactory) reject(rejected *[]RejectedField, field []byte, err error) error {
+	if !factory.bestEffort {
+		return err
+	}
+
+	*rejected = append(*rejected, RejectedField{Field: string(field), Reason: err.Error()})
+	return nil
 }
 
 func (factory *FieldOperatorFactory) remove(out jsoniter.RawMessage, toRemove jsoniter.RawMessage) (jsoniter.RawMessage, error) {
-	var unsetArray []string
-	if err := jsoniter.Unmarshal(toRemove, &unsetArray); err != nil {
+	unsetFields, err := parseUnset(toRemove)
+	if err != nil {
 		return nil, err
 	}
 
-	for _, unset := range unsetArray {
-		unsetKeys := strings.Split(unset, ".")
-		out = jsonparser.Delete(out, unsetKeys...)
+	for _, unset := range unsetFields {
+		unsetKeys := strings.Split(unset.field, ".")
+		if unset.predicate == nil {
+			out = jsonparser.Delete(out, unsetKeys...)
+			continue
+		}
+		if out, err = removeMatching(out, unset, unsetKeys); err != nil {
+			return nil, err
+		}
 	}
 
 	return out, nil
 }
 
-func (factory *FieldOperatorFactory) set(existingDoc jsoniter.RawMessage, setDoc jsoniter.RawMessage) (jsoniter.RawMessage, error) {
+// unsetField is an entry of the "$unset" operator, either the name of a field to remove or an array field with the
+// predicate of the elements to remove from it.
+type unsetField struct {
+	field     string
+	predicate interface{}
+}
+
+// parseUnset parses the entries of the "$unset" operator: ["a", {"items": {"status": "deleted"}}].
+func parseUnset(input jsoniter.RawMessage) ([]unsetField, error) {
+	var entries []jsoniter.RawMessage
+	if err := jsoniter.Unmarshal(input, &entries); err != nil {
+		return nil, err
+	}
+
+	unsetFields := make([]unsetField, 0, len(entries))
+	for _, entry := range entries {
+		var field string
+		if err := jsoniter.Unmarshal(entry, &field); err == nil {
+			unsetFields = append(unsetFields, unsetField{field: field})
+			continue
+		}
+
+		var predicates map[string]jsoniter.RawMessage
+		if err := jsoniter.Unmarshal(entry, &predicates); err != nil || len(predicates) != 1 {
+			return nil, errors.InvalidArgument("$unset expects field names or objects with a single array field and the predicate of its elements to remove")
+		}
+		for field, rawPredicate := range predicates {
+			if len(rawPredicate) == 0 || string(rawPredicate) == "null" {
+				return nil, errors.InvalidArgument("$unset predicate of the field '%s' can't be null", field)
+			}
+			predicate, err := decodeValue(rawPredicate)
+			if err != nil {
+				return nil, err
+			}
+			unsetFields = append(unsetFields, unsetField{field: field, predicate: predicate})
+		}
+	}
+
+	return unsetFields, nil
+}
+
+// removeMatching removes the elements matching the predicate from the array field, a missing field is ignored.
+func removeMatching(out []byte, unset unsetField, keys []string) ([]byte, error) {
+	existing, existingType, _, err := jsonparser.Get(out, keys...)
+	if existingType == jsonparser.NotExist {
+		return out, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if existingType != jsonparser.Array {
+		return nil, errors.InvalidArgument("$unset with a predicate can only be applied to an array, field '%s' is not an array", unset.field)
+	}
+
+	var (
+		buf     bytes.Buffer
+		removed bool
+	)
+	buf.WriteByte('[')
+	_, err = jsonparser.ArrayEach(existing, func(item []byte, itemType jsonparser.ValueType, _ int, _ error) {
+		if err != nil {
+			return
+		}
+		if itemType == jsonparser.String {
+			// the strings are returned without their quotes
+			item = append(append([]byte{'"'}, item...), '"')
+		}
+
+		var element interface{}
+		if element, err = decodeValue(item); err != nil {
+			return
+		}
+		if matchesPredicate(element, unset.predicate) {
+			removed = true
+			return
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.Write(item)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !removed {
+		return out, nil
+	}
+	buf.WriteByte(']')
+
+	return jsonparser.Set(out, buf.Bytes(), keys...)
+}
+
+// numberDecoder keeps the numbers as json.Number, so that the large integers are compared exactly.
+var numberDecoder = jsoniter.Config{UseNumber: true}.Froze()
+
+func decodeValue(raw []byte) (interface{}, error) {
+	var value interface{}
+	if err := numberDecoder.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// matchesPredicate returns true if the array element matches the predicate. The elements are matched by the fields of
+// an object predicate, which can be dotted to match the nested fields, and the other elements are matched if they are
+// equal to the predicate.
+func matchesPredicate(element interface{}, predicate interface{}) bool {
+	fields, ok := predicate.(map[string]interface{})
+	if !ok {
+		return valuesEqual(element, predicate)
+	}
+
+	doc, ok := element.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for field, expected := range fields {
+		value, found := lookupField(doc, strings.Split(field, "."))
+		if !found || !valuesEqual(value, expected) {
+			return false
+		}
+	}
+	return true
+}
+
+func lookupField(doc map[string]interface{}, keys []string) (interface{}, bool) {
+	value, found := doc[keys[0]]
+	if !found || len(keys) == 1 {
+		return value, found
+	}
+	nested, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return lookupField(nested, keys[1:])
+}
+
+// valuesEqual compares the decoded JSON values, the numbers are compared by their value.
+func valuesEqual(a interface{}, b interface{}) bool {
+	switch av := a.(type) {
+	case json.Number:
+		bv, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if ai, err := av.Int64(); err == nil {
+			if bi, err := bv.Int64(); err == nil {
+				return ai == bi
+			}
+		}
+		af, aerr := av.Float64()
+		bf, berr := bv.Float64()
+		return aerr == nil && berr == nil && af == bf
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			if other, found := bv[k]; !found || !valuesEqual(v, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !valuesEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+func (factory *FieldOperatorFactory) set(existingDoc jsoniter.RawMessage, setDoc jsoniter.RawMessage, rejected *[]RejectedField) (jsoniter.RawMessage, error) {
 	var (
 		output []byte = existingDoc
 		err    error
@@ -102,13 +746,18 @@ func (factory *FieldOperatorFactory) set(existingDoc jsoniter.RawMessage, setDoc
 	err = jsonparser.ObjectEach(setDoc, func(key []byte, value []byte, dataType jsonparser.ValueType, offset int) error {
 		if dataType == jsonparser.String {
 			value = []byte(fmt.Sprintf(`"%s"`, value))
+		} else if factory.coercer != nil {
+			if value, err = factory.coercer.coerce(string(key), value, dataType); err != nil {
+				return factory.reject(rejected, key, err)
+			}
 		}
 
 		keys := strings.Split(string(key), ".")
-		output, err = jsonparser.Set(output, value, keys...)
+		merged, err := jsonparser.Set(output, value, keys...)
 		if err != nil {
-			return err
+			return factory.reject(rejected, key, err)
 		}
+		output = merged
 		return nil
 	})
 
@@ -119,10 +768,108 @@ func (factory *FieldOperatorFactory) set(existingDoc jsoniter.RawMessage, setDoc
 	return output, nil
 }
 
+// bit applies the bitwise operation on the existing integer value of the field, a missing field is treated as 0.
+func (factory *FieldOperatorFactory) bit(existingDoc jsoniter.RawMessage, bitDoc jsoniter.RawMessage, rejected *[]RejectedField) (jsoniter.RawMessage, error) {
+	var (
+		output []byte = existingDoc
+		err    error
+	)
+	err = jsonparser.ObjectEach(bitDoc, func(key []byte, value []byte, dataType jsonparser.ValueType, offset int) error {
+		if err := factory.checkBitField(key); err != nil {
+			return factory.reject(rejected, key, err)
+		}
+		merged, err := bitField(output, key, value, dataType)
+		if err != nil {
+			return factory.reject(rejected, key, err)
+		}
+		output = merged
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// checkBitField checks that the field of the "$bit" operator is declared as an integer if the fields are checked
+// against the schema.
+func (factory *FieldOperatorFactory) checkBitField(key []byte) error {
+	if factory.bitCollection == nil {
+		return nil
+	}
+
+	field, err := factory.bitCollection.GetQueryableField(string(key))
+	if err != nil {
+		return err
+	}
+	if field.DataType != schema.Int32Type && field.DataType != schema.Int64Type {
+		return errors.InvalidArgument("$bit can only be applied to an integer field, field '%s' is not an integer", key)
+	}
+	return nil
+}
+
+// bitField applies the bitwise operation of a single field of the "$bit" operator.
+func bitField(output []byte, key []byte, value []byte, dataType jsonparser.ValueType) ([]byte, error) {
+	if dataType != jsonparser.Object {
+		return nil, errors.InvalidArgument("$bit expects an object with one of 'and', 'or', 'xor' for field '%s'", key)
+	}
+
+	var operations map[BitwiseOPType]jsoniter.RawMessage
+	if err := jsoniter.Unmarshal(value, &operations); err != nil {
+		return nil, err
+	}
+	if len(operations) != 1 {
+		return nil, errors.InvalidArgument("$bit expects exactly one of 'and', 'or', 'xor' for field '%s'", key)
+	}
+
+	keys := strings.Split(string(key), ".")
+	existing, existingType, _, err := jsonparser.Get(output, keys...)
+	if err != nil && existingType != jsonparser.NotExist {
+		return nil, err
+	}
+
+	var current int64
+	if existingType != jsonparser.NotExist {
+		if current, err = parseBitInteger(existing, existingType); err != nil {
+			return nil, errors.InvalidArgument("$bit can only be applied to an integer field, field '%s' is not an integer", key)
+		}
+	}
+
+	for op, rawOperand := range operations {
+		operand, err := parseBitInteger(rawOperand, jsonparser.Number)
+		if err != nil {
+			return nil, errors.InvalidArgument("$bit operand for field '%s' is not an integer", key)
+		}
+
+		switch op {
+		case BitAnd:
+			current &= operand
+		case BitOr:
+			current |= operand
+		case BitXor:
+			current ^= operand
+		default:
+			return nil, errors.InvalidArgument("unsupported $bit operation '%s', only 'and', 'or', 'xor' are supported", op)
+		}
+	}
+
+	return jsonparser.Set(output, []byte(strconv.FormatInt(current, 10)), keys...)
+}
+
+func parseBitInteger(value []byte, dataType jsonparser.ValueType) (int64, error) {
+	if dataType != jsonparser.Number {
+		return 0, fmt.Errorf("not a number")
+	}
+
+	return strconv.ParseInt(string(value), 10, 64)
+}
+
 // A FieldOperator can be of the following type:
 // { "$set": { <field1>: <value1>, ... } }
 // { "$incr": { <field1>: <value> } }
-// { "$unset": ["d"] }.
+// { "$bit": { <field1>: { <and|or|xor>: <int> } } }
+// { "$unset": ["d", { <array field>: <predicate of the elements to remove> }] }.
 type FieldOperator struct {
 	Op    FieldOPType
 	Input jsoniter.RawMessage
@@ -21,7 +21,9 @@ import (
 	"github.com/buger/jsonparser"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/lib/json"
+	"github.com/tigrisdata/tigris/schema"
 )
 
 func TestMergeAndGet(t *testing.T) {
@@ -245,3 +247,493 @@ func (factory *FieldOperatorFactory) testSetNoDeserialization(input jsoniter.Raw
 
 	return nil
 }
+
+func TestMergeAndGetWithBit(t *testing.T) {
+	cases := []struct {
+		inputBit    jsoniter.RawMessage
+		existingDoc jsoniter.RawMessage
+		outputDoc   jsoniter.RawMessage
+	}{
+		{
+			[]byte(`{"flags": {"and": 6}}`),
+			[]byte(`{"a": 1, "flags": 13}`),
+			[]byte(`{"a": 1, "flags": 4}`),
+		}, {
+			[]byte(`{"flags": {"or": 2}}`),
+			[]byte(`{"a": 1, "flags": 13}`),
+			[]byte(`{"a": 1, "flags": 15}`),
+		}, {
+			[]byte(`{"flags": {"xor": 5}}`),
+			[]byte(`{"a": 1, "flags": 13}`),
+			[]byte(`{"a": 1, "flags": 8}`),
+		}, {
+			[]byte(`{"d.flags": {"or": 3}}`),
+			[]byte(`{"a": 1, "d": {"flags": 4}}`),
+			[]byte(`{"a": 1, "d": {"flags": 7}}`),
+		}, {
+			// missing field defaults to 0
+			[]byte(`{"flags": {"or": 5}}`),
+			[]byte(`{"a": 1}`),
+			[]byte(`{"a": 1,"flags":5}`),
+		}, {
+			[]byte(`{"flags": {"and": 5}}`),
+			[]byte(`{"a": 1}`),
+			[]byte(`{"a": 1,"flags":0}`),
+		},
+	}
+	for _, c := range cases {
+		reqInput := []byte(fmt.Sprintf(`{"%s": %s}`, Bit, c.inputBit))
+		f, err := BuildFieldOperators(reqInput)
+		require.NoError(t, err)
+
+		actualOut, err := f.MergeAndGet(c.existingDoc)
+		require.NoError(t, err)
+		require.Equal(t, c.outputDoc, actualOut, fmt.Sprintf("exp '%s' actual '%s'", string(c.outputDoc), string(actualOut)))
+	}
+}
+
+func TestMergeAndGetWithBit_Invalid(t *testing.T) {
+	cases := []struct {
+		inputBit    jsoniter.RawMessage
+		existingDoc jsoniter.RawMessage
+	}{
+		// non-integer targets
+		{[]byte(`{"flags": {"and": 6}}`), []byte(`{"flags": 1.5}`)},
+		{[]byte(`{"flags": {"and": 6}}`), []byte(`{"flags": "13"}`)},
+		{[]byte(`{"flags": {"and": 6}}`), []byte(`{"flags": null}`)},
+		// non-integer operand
+		{[]byte(`{"flags": {"or": 1.5}}`), []byte(`{"flags": 1}`)},
+		// unsupported or multiple operations
+		{[]byte(`{"flags": {"nand": 6}}`), []byte(`{"flags": 1}`)},
+		{[]byte(`{"flags": {"and": 6, "or": 1}}`), []byte(`{"flags": 1}`)},
+		{[]byte(`{"flags": 6}`), []byte(`{"flags": 1}`)},
+	}
+	for _, c := range cases {
+		reqInput := []byte(fmt.Sprintf(`{"%s": %s}`, Bit, c.inputBit))
+		f, err := BuildFieldOperators(reqInput)
+		require.NoError(t, err)
+
+		_, err = f.MergeAndGet(c.existingDoc)
+		require.Error(t, err, string(c.inputBit))
+	}
+}
+
+func TestMergeAndGetWithBit_Schema(t *testing.T) {
+	reqSchema := []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": { "type": "integer" },
+		"price": { "type": "number" },
+		"qty": { "type": "integer", "format": "int32" }
+	},
+	"primary_key": ["id"]
+}`)
+	schFactory, err := schema.Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	coll := schema.NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
+	existingDoc := []byte(`{"id": 1, "price": 4, "qty": 1}`)
+
+	// the integer fields of the schema are applied
+	f, err := BuildFieldOperators([]byte(`{"$bit": {"qty": {"or": 2}}}`))
+	require.NoError(t, err)
+	f.CheckBitFields(coll)
+	out, err := f.MergeAndGet(existingDoc)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id": 1, "price": 4, "qty": 3}`, string(out))
+
+	// a "number" field is rejected even if its value is an integer, the same as an unknown field
+	f, err = BuildFieldOperators([]byte(`{"$bit": {"price": {"and": 4}}}`))
+	require.NoError(t, err)
+	f.CheckBitFields(coll)
+	_, err = f.MergeAndGet(existingDoc)
+	require.Equal(t, errors.InvalidArgument("$bit can only be applied to an integer field, field 'price' is not an integer"), err)
+
+	f, err = BuildFieldOperators([]byte(`{"$bit": {"other": {"and": 4}}}`))
+	require.NoError(t, err)
+	f.CheckBitFields(coll)
+	_, err = f.MergeAndGet(existingDoc)
+	require.Error(t, err)
+}
+
+func TestFieldOperatorFactory_Fields(t *testing.T) {
+	factory, err := BuildFieldOperators([]byte(`{"$unset": ["a", "d.e"], "$set": {"b": 1, "d.f": {"g": 1}}, "$bit": {"c": {"or": 1}}}`))
+	require.NoError(t, err)
+	fields, err := factory.Fields()
+	require.NoError(t, err)
+	require.Equal(t, []string{"c", "b", "d.f", "a", "d.e"}, fields)
+
+	factory, err = BuildFieldOperators([]byte(`{"$unset": ["a", {"d.items": {"status": "deleted"}}]}`))
+	require.NoError(t, err)
+	fields, err = factory.Fields()
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "d.items"}, fields)
+
+	factory, err = BuildFieldOperators([]byte(`{"$unset": {"a": 1}}`))
+	require.NoError(t, err)
+	_, err = factory.Fields()
+	require.Error(t, err)
+}
+
+func TestMergeAndGet_UnsetPredicate(t *testing.T) {
+	existingDoc := []byte(`{"a":1,"tags":["new","old","old"],"items":[{"id":1,"status":"deleted"},{"id":2,"status":"active"},{"id":3,"status":"deleted","meta":{"by":"x"}}],"order":{"lines":[{"qty":1},{"qty":2.0},{"qty":3}]}}`)
+
+	cases := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{
+			"elements matching the fields",
+			`{"$unset": [{"items": {"status": "deleted"}}]}`,
+			`{"a":1,"tags":["new","old","old"],"items":[{"id":2,"status":"active"}],"order":{"lines":[{"qty":1},{"qty":2.0},{"qty":3}]}}`,
+		}, {
+			"all the fields of the predicate must match",
+			`{"$unset": [{"items": {"status": "deleted", "id": 3}}]}`,
+			`{"a":1,"tags":["new","old","old"],"items":[{"id":1,"status":"deleted"},{"id":2,"status":"active"}],"order":{"lines":[{"qty":1},{"qty":2.0},{"qty":3}]}}`,
+		}, {
+			"nested field of the elements",
+			`{"$unset": [{"items": {"meta.by": "x"}}]}`,
+			`{"a":1,"tags":["new","old","old"],"items":[{"id":1,"status":"deleted"},{"id":2,"status":"active"}],"order":{"lines":[{"qty":1},{"qty":2.0},{"qty":3}]}}`,
+		}, {
+			"nested array and numbers compared by value",
+			`{"$unset": [{"order.lines": {"qty": 2}}]}`,
+			`{"a":1,"tags":["new","old","old"],"items":[{"id":1,"status":"deleted"},{"id":2,"status":"active"},{"id":3,"status":"deleted","meta":{"by":"x"}}],"order":{"lines":[{"qty":1},{"qty":3}]}}`,
+		}, {
+			"elements equal to a value",
+			`{"$unset": [{"tags": "old"}]}`,
+			`{"a":1,"tags":["new"],"items":[{"id":1,"status":"deleted"},{"id":2,"status":"active"},{"id":3,"status":"deleted","meta":{"by":"x"}}],"order":{"lines":[{"qty":1},{"qty":2.0},{"qty":3}]}}`,
+		}, {
+			"nothing matches",
+			`{"$unset": [{"items": {"status": "archived"}}, {"missing": {"status": "deleted"}}]}`,
+			string(existingDoc),
+		}, {
+			"mixed with the field names",
+			`{"$set": {"b": 2}, "$unset": ["a", {"items": {"status": "deleted"}}, "order.lines", {"tags": "new"}]}`,
+			`{"tags":["old","old"],"items":[{"id":2,"status":"active"}],"order":{},"b":2}`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f, err := BuildFieldOperators([]byte(c.input))
+			require.NoError(t, err)
+
+			actualOut, err := f.MergeAndGet(existingDoc)
+			require.NoError(t, err)
+			require.JSONEq(t, c.output, string(actualOut))
+		})
+	}
+
+	errCases := []struct {
+		input string
+		err   error
+	}{
+		{
+			`{"$unset": [{"a": {"status": "deleted"}}]}`,
+			errors.InvalidArgument("$unset with a predicate can only be applied to an array, field 'a' is not an array"),
+		}, {
+			`{"$unset": [{"items": {"status": "deleted"}, "tags": "old"}]}`,
+			errors.InvalidArgument("$unset expects field names or objects with a single array field and the predicate of its elements to remove"),
+		}, {
+			`{"$unset": [1]}`,
+			errors.InvalidArgument("$unset expects field names or objects with a single array field and the predicate of its elements to remove"),
+		}, {
+			`{"$unset": [{"items": null}]}`,
+			errors.InvalidArgument("$unset predicate of the field 'items' can't be null"),
+		},
+	}
+	for _, c := range errCases {
+		f, err := BuildFieldOperators([]byte(c.input))
+		require.NoError(t, err)
+
+		_, err = f.MergeAndGet(existingDoc)
+		require.Equal(t, c.err, err, c.input)
+	}
+}
+
+func TestFieldOperatorFactory_Operators(t *testing.T) {
+	factory, err := BuildFieldOperators([]byte(`{"$unset": ["a"], "$set": {"b": 1}, "$bit": {"c": {"or": 1}}, "$unknown": {}}`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"$bit", "$set", "$unset"}, factory.Operators())
+}
+
+func TestMergeAndGetCoerceNumbers(t *testing.T) {
+	reqSchema := []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": { "type": "integer" },
+		"price": { "type": "number" },
+		"qty": { "type": "integer", "format": "int32" },
+		"total": { "type": "integer" },
+		"name": { "type": "string" },
+		"scores": { "type": "array", "items": { "type": "number" } },
+		"dims": { "type": "object", "properties": { "w": { "type": "number" }, "h": { "type": "integer", "format": "int32" } } }
+	},
+	"primary_key": ["id"]
+}`)
+	schFactory, err := schema.Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	coll := schema.NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
+
+	cases := []struct {
+		inputDoc  jsoniter.RawMessage
+		outputDoc jsoniter.RawMessage
+	}{
+		{
+			// integer to number
+			[]byte(`{"price": 5}`),
+			[]byte(`{"id": 1,"price":5.0}`),
+		}, {
+			[]byte(`{"price": -12, "name": "5"}`),
+			[]byte(`{"id": 1,"price":-12.0,"name":"5"}`),
+		}, {
+			// numbers already stored as floats are untouched
+			[]byte(`{"price": 5.5, "scores": [1.5, 2e3]}`),
+			[]byte(`{"id": 1,"price":5.5,"scores":[1.5, 2e3]}`),
+		}, {
+			// number to integer
+			[]byte(`{"qty": 5.0, "total": 3e2}`),
+			[]byte(`{"id": 1,"qty":5,"total":300}`),
+		}, {
+			[]byte(`{"qty": -2147483648, "total": 9223372036854775807}`),
+			[]byte(`{"id": 1,"qty":-2147483648,"total":9223372036854775807}`),
+		}, {
+			// arrays and nested objects
+			[]byte(`{"scores": [1, 2.5, 3], "dims": {"w": 2, "h": 4.0}}`),
+			[]byte(`{"id": 1,"scores":[1.0,2.5,3.0],"dims":{"w": 2.0, "h": 4}}`),
+		}, {
+			[]byte(`{"dims.w": 7}`),
+			[]byte(`{"id": 1,"dims":{"w":7.0}}`),
+		}, {
+			// the fields which are not in the schema are untouched
+			[]byte(`{"other": 1}`),
+			[]byte(`{"id": 1,"other":1}`),
+		},
+	}
+	for _, c := range cases {
+		f, err := BuildFieldOperators([]byte(fmt.Sprintf(`{"%s": %s}`, Set, c.inputDoc)))
+		require.NoError(t, err)
+		f.CoerceNumbers(coll)
+
+		actualOut, err := f.MergeAndGet([]byte(`{"id": 1}`))
+		require.NoError(t, err)
+		require.Equal(t, c.outputDoc, actualOut, fmt.Sprintf("exp '%s' actual '%s'", string(c.outputDoc), string(actualOut)))
+	}
+
+	for _, invalid := range []string{
+		`{"qty": 2147483648}`,
+		`{"qty": -2147483649}`,
+		`{"qty": 3e10}`,
+		`{"qty": 5.5}`,
+		`{"total": 9223372036854775808}`,
+		`{"total": 1e19}`,
+		`{"dims": {"h": 2147483648}}`,
+		`{"dims.h": 1.5}`,
+	} {
+		f, err := BuildFieldOperators([]byte(fmt.Sprintf(`{"%s": %s}`, Set, invalid)))
+		require.NoError(t, err)
+		f.CoerceNumbers(coll)
+
+		_, err = f.MergeAndGet([]byte(`{"id": 1}`))
+		require.Error(t, err, invalid)
+	}
+
+	// without the collection the numbers are stored as they are sent
+	f, err := BuildFieldOperators([]byte(`{"$set": {"price": 5, "qty": 5.0}}`))
+	require.NoError(t, err)
+	actualOut, err := f.MergeAndGet([]byte(`{"id": 1, "price": 1.5}`))
+	require.NoError(t, err)
+	require.Equal(t, jsoniter.RawMessage(`{"id": 1, "price": 5,"qty":5.0}`), actualOut)
+}
+
+func TestMergeAndGetBestEffort(t *testing.T) {
+	reqSchema := []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": { "type": "integer" },
+		"price": { "type": "number" },
+		"qty": { "type": "integer", "format": "int32" },
+		"flags": { "type": "integer" },
+		"name": { "type": "string" }
+	},
+	"primary_key": ["id"]
+}`)
+	schFactory, err := schema.Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	coll := schema.NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
+
+	// a mixed request, "qty" is out of the int32 range and "$bit" can't be applied on "name"
+	reqInput := []byte(`{"$set": {"price": 5, "qty": 2147483648, "name": "b"}, "$bit": {"flags": {"or": 2}, "name": {"and": 1}}, "$unset": ["other"]}`)
+	existingDoc := []byte(`{"id": 1, "qty": 1, "flags": 1, "name": "a", "other": true}`)
+
+	t.Run("strict", func(t *testing.T) {
+		f, err := BuildFieldOperators(reqInput)
+		require.NoError(t, err)
+		f.CoerceNumbers(coll)
+
+		_, err = f.MergeAndGet(existingDoc)
+		require.Equal(t, errors.InvalidArgument("value of field 'qty' is out of the range of its integer type"), err)
+
+		out, rejected, err := f.MergeAndGetWithRejected(existingDoc)
+		require.Error(t, err)
+		require.Nil(t, out)
+		require.Nil(t, rejected)
+	})
+
+	t.Run("best_effort", func(t *testing.T) {
+		f, err := BuildFieldOperators(reqInput)
+		require.NoError(t, err)
+		f.CoerceNumbers(coll)
+		f.BestEffort()
+
+		out, rejected, err := f.MergeAndGetWithRejected(existingDoc)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id": 1, "qty": 1, "flags": 3, "name": "b", "price": 5.0}`, string(out))
+		require.Equal(t, []RejectedField{
+			{Field: "qty", Reason: "value of field 'qty' is out of the range of its integer type"},
+			{Field: "name", Reason: "$bit can only be applied to an integer field, field 'name' is not an integer"},
+		}, rejected)
+
+		// MergeAndGet applies the same fields and drops the rejections
+		merged, err := f.MergeAndGet(existingDoc)
+		require.NoError(t, err)
+		require.Equal(t, out, merged)
+
+		// nothing is rejected when all the fields are valid
+		f, err = BuildFieldOperators([]byte(`{"$set": {"price": 5}}`))
+		require.NoError(t, err)
+		f.CoerceNumbers(coll)
+		f.BestEffort()
+		_, rejected, err = f.MergeAndGetWithRejected(existingDoc)
+		require.NoError(t, err)
+		require.Empty(t, rejected)
+
+		// a malformed operator still fails the merge
+		f, err = BuildFieldOperators([]byte(`{"$unset": {"a": 1}}`))
+		require.NoError(t, err)
+		f.BestEffort()
+		_, _, err = f.MergeAndGetWithRejected(existingDoc)
+		require.Error(t, err)
+	})
+}
+
+func TestMergeAndGetCanonicalize(t *testing.T) {
+	existingDoc := []byte(`{"c": 1.50, "a": {"z": [{"y": 1, "x": 2}, 3], "b": "b\"q\""}, "b": null}`)
+	reqInput := []byte(`{"$set": {"e": {"g": 1, "f": true}, "a.c": "new"}, "$unset": ["b"]}`)
+
+	f, err := BuildFieldOperators(reqInput)
+	require.NoError(t, err)
+	out, err := f.MergeAndGet(existingDoc)
+	require.NoError(t, err)
+	// the existing order is kept and the new keys are appended by default
+	require.JSONEq(t, `{"c": 1.50, "a": {"z": [{"y": 1, "x": 2}, 3], "b": "b\"q\"", "c": "new"}, "e": {"g": 1, "f": true}}`, string(out))
+
+	f.Canonicalize()
+	out, err = f.MergeAndGet(existingDoc)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":{"b":"b\"q\"","c":"new","z":[{"x":2,"y":1},3]},"c":1.50,"e":{"f":true,"g":1}}`, string(out))
+
+	// the same document merged from a different key order has the same bytes
+	reordered, err := f.MergeAndGet([]byte(`{"b": null, "a": {"b": "b\"q\"", "z": [{"x": 2, "y": 1}, 3]}, "c": 1.50}`))
+	require.NoError(t, err)
+	require.Equal(t, out, reordered)
+}
+
+func TestMergeAndGetWithPatch(t *testing.T) {
+	existingDoc := []byte(`{"a":1,"b":"first","c":1.01,"nested":{"f":22,"g":44,"h":{"i":1}},"arr":[1,2],"n":null}`)
+	cases := []struct {
+		reqInput jsoniter.RawMessage
+		patch    string
+	}{
+		{
+			[]byte(`{"$set": {"a": 10, "e": "new"}, "$unset": ["b"]}`),
+			`{"a":10,"b":null,"e":"new"}`,
+		}, {
+			// the nested fields are patched field by field
+			[]byte(`{"$set": {"nested.f": 29, "nested.h.j": true}, "$unset": ["nested.g", "c"]}`),
+			`{"c":null,"nested":{"f":29,"g":null,"h":{"j":true}}}`,
+		}, {
+			// the arrays are replaced as a whole
+			[]byte(`{"$set": {"arr": [1, 2, 3]}, "$unset": ["nested"]}`),
+			`{"arr":[1,2,3],"nested":null}`,
+		}, {
+			// a field both set and unset is removed
+			[]byte(`{"$set": {"a": 10, "z": 1}, "$unset": ["a", "z"]}`),
+			`{"a":null}`,
+		}, {
+			// the fields set to their existing value, the missing fields unset and the empty objects are not changes
+			[]byte(`{"$set": {"a": 1, "arr": [1,2], "nested.h": {"i": 1}, "n": null}, "$unset": ["missing", "nested.missing"]}`),
+			`{}`,
+		}, {
+			// an object replaced by another type, and a value set to null is in the patch as a removed field
+			[]byte(`{"$set": {"nested": "flat", "b": null, "new": {"y": 2, "x": 1}}}`),
+			`{"b":null,"nested":"flat","new":{"x":1,"y":2}}`,
+		},
+	}
+	for _, c := range cases {
+		f, err := BuildFieldOperators(c.reqInput)
+		require.NoError(t, err)
+
+		expOut, err := f.MergeAndGet(existingDoc)
+		require.NoError(t, err)
+		out, patch, err := f.MergeAndGetWithPatch(existingDoc)
+		require.NoError(t, err)
+		require.Equal(t, expOut, out)
+		require.Equal(t, c.patch, string(patch), string(c.reqInput))
+	}
+
+	f, err := BuildFieldOperators([]byte(`{"$set": {"a": 1}}`))
+	require.NoError(t, err)
+	_, _, err = f.MergeAndGetWithPatch([]byte(`[1]`))
+	require.Error(t, err)
+}
+
+func TestEstimateWriteAmplification(t *testing.T) {
+	existingDoc := []byte(`{"a":1,"b":"foo","d":{"f":22,"g":44}}`)
+
+	cases := []struct {
+		fields   string
+		expected *WriteAmplification
+	}{
+		{
+			// only the values of the top level fields are replaced
+			`{"$set": {"a": 10, "b": "bar"}}`,
+			&WriteAmplification{Ranges: []ByteRange{{5, 6}, {11, 16}}, RewrittenBytes: 6},
+		}, {
+			`{"$set": {"d.f": 1}}`,
+			&WriteAmplification{Ranges: []ByteRange{{26, 28}}, RewrittenBytes: 2},
+		}, {
+			// the new fields are inserted at the end of their object
+			`{"$set": {"d.h": 1, "e.x": 1}}`,
+			&WriteAmplification{Ranges: []ByteRange{{35, 35}, {36, 36}}},
+		}, {
+			// the nested object is replaced as a whole, the changes of its fields are part of it
+			`{"$set": {"d": {"h": 1}, "d.f": 1}, "$bit": {"d.g": {"and": 4}}}`,
+			&WriteAmplification{Ranges: []ByteRange{{21, 36}}, RewrittenBytes: 15},
+		}, {
+			`{"$unset": ["b", "missing"], "$bit": {"a": {"or": 2}}}`,
+			&WriteAmplification{Ranges: []ByteRange{{5, 6}, {11, 16}}, RewrittenBytes: 6},
+		},
+	}
+	for _, c := range cases {
+		factory, err := BuildFieldOperators([]byte(c.fields))
+		require.NoError(t, err)
+		estimate, err := factory.EstimateWriteAmplification(existingDoc)
+		require.NoError(t, err)
+		require.Equal(t, c.expected, estimate, c.fields)
+	}
+
+	t.Run("full rewrite", func(t *testing.T) {
+		factory, err := BuildFieldOperators([]byte(`{"$set": {"a": 10}}`))
+		require.NoError(t, err)
+		factory.Canonicalize()
+		estimate, err := factory.EstimateWriteAmplification(existingDoc)
+		require.NoError(t, err)
+		require.Equal(t, &WriteAmplification{FullRewrite: true, Ranges: []ByteRange{{0, 37}}, RewrittenBytes: 37}, estimate)
+
+		factory, err = BuildFieldOperators([]byte(`{"$set": {"a": 10}}`))
+		require.NoError(t, err)
+		estimate, err = factory.EstimateWriteAmplification([]byte(`[]`))
+		require.NoError(t, err)
+		require.True(t, estimate.FullRewrite)
+	})
+}
@@ -0,0 +1,347 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"bytes"
+
+	"github.com/buger/jsonparser"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+const allOfKey = "allOf"
+
+// allOfConflictKeywords are the keywords of a field that must be the same in all the subschemas defining the field.
+var allOfConflictKeywords = []string{"type", "format"}
+
+// keyword is a keyword of a schema object with its raw JSON value.
+type keyword struct {
+	key   string
+	value []byte
+}
+
+// allOfMerger merges the subschemas of the "allOf" of a schema, the subschemas can reference the definitions of the
+// schema.
+type allOfMerger struct {
+	refs *refResolver
+}
+
+// MergeAllOf returns the schema with every "allOf" replaced by the merge of its subschemas. The properties of the
+// subschemas are merged in their order, followed by the properties of the schema itself, and the required fields are
+// the union of the required fields. The other keywords of the schema take precedence over the ones of its subschemas.
+// A field defined by several subschemas must have the same type and format in all of them, the properties of an object
+// field are merged. The definitions are kept, so that the references outside "allOf" are still resolved.
+func MergeAllOf(reqSchema jsoniter.RawMessage) (jsoniter.RawMessage, error) {
+	if !bytes.Contains(reqSchema, []byte(`"`+allOfKey+`"`)) {
+		return reqSchema, nil
+	}
+
+	refs, err := newRefResolver(reqSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &allOfMerger{refs: refs}
+	return m.merge(reqSchema, jsonparser.Object, nil)
+}
+
+func (m *allOfMerger) merge(value []byte, dataType jsonparser.ValueType, stack []string) ([]byte, error) {
+	switch dataType {
+	case jsonparser.Object:
+		var (
+			own   []keyword
+			parts [][]keyword
+		)
+		err := jsonparser.ObjectEach(value, func(key []byte, nested []byte, nestedType jsonparser.ValueType, _ int) error {
+			if string(key) == allOfKey {
+				var err error
+				parts, err = m.subschemas(nested, nestedType, stack)
+				return err
+			}
+
+			merged, err := m.merge(nested, nestedType, stack)
+			if err != nil {
+				return err
+			}
+			own = append(own, keyword{key: string(key), value: merged})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if parts == nil {
+			return encodeKeywords(own), nil
+		}
+
+		var merged []keyword
+		for _, part := range append(parts, own) {
+			if merged, err = mergeKeywords("", merged, part); err != nil {
+				return nil, err
+			}
+		}
+		return encodeKeywords(merged), nil
+	case jsonparser.Array:
+		var (
+			buf bytes.Buffer
+			err error
+		)
+		buf.WriteByte('[')
+		_, arrErr := jsonparser.ArrayEach(value, func(item []byte, itemType jsonparser.ValueType, _ int, _ error) {
+			if err != nil {
+				return
+			}
+			var merged []byte
+			if merged, err = m.merge(item, itemType, stack); err != nil {
+				return
+			}
+			if buf.Len() > 1 {
+				buf.WriteByte(',')
+			}
+			buf.Write(merged)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if arrErr != nil {
+			return nil, errors.InvalidArgument("invalid array in the schema")
+		}
+		buf.WriteByte(']')
+		return buf.Bytes(), nil
+	case jsonparser.String:
+		// the strings are returned without their quotes
+		return append(append([]byte{'"'}, value...), '"'), nil
+	default:
+		return value, nil
+	}
+}
+
+// subschemas returns the keywords of the subschemas of an "allOf", with their own "allOf" merged and their references
+// resolved.
+func (m *allOfMerger) subschemas(value []byte, dataType jsonparser.ValueType, stack []string) ([][]keyword, error) {
+	if dataType != jsonparser.Array {
+		return nil, errors.InvalidArgument("'%s' must be an array of schemas", allOfKey)
+	}
+
+	parts := [][]keyword{}
+	var err error
+	_, arrErr := jsonparser.ArrayEach(value, func(item []byte, itemType jsonparser.ValueType, _ int, _ error) {
+		if err != nil {
+			return
+		}
+		if itemType != jsonparser.Object {
+			err = errors.InvalidArgument("'%s' must be an array of schemas", allOfKey)
+			return
+		}
+
+		var part []keyword
+		if part, err = m.subschema(item, stack); err == nil {
+			parts = append(parts, part)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if arrErr != nil {
+		return nil, errors.InvalidArgument("'%s' must be an array of schemas", allOfKey)
+	}
+
+	return parts, nil
+}
+
+func (m *allOfMerger) subschema(item []byte, stack []string) ([]keyword, error) {
+	ref, refType, _, _ := jsonparser.Get(item, refKey)
+	if refType == jsonparser.NotExist {
+		merged, err := m.merge(item, jsonparser.Object, stack)
+		if err != nil {
+			return nil, err
+		}
+		return decodeKeywords(merged)
+	}
+	if refType != jsonparser.String {
+		return nil, errors.InvalidArgument("'%s' must be a string", refKey)
+	}
+
+	if _, ok := RefName(string(ref)); !ok {
+		return nil, errors.InvalidArgument("unsupported $ref '%s', only the local definitions are supported", ref)
+	}
+	def, ok := m.refs.definitions[string(ref)]
+	if !ok {
+		return nil, errors.InvalidArgument("$ref '%s' is not defined", ref)
+	}
+	for _, s := range stack {
+		if s == string(ref) {
+			return nil, errors.InvalidArgument("recursive $ref '%s' is not supported", ref)
+		}
+	}
+
+	merged, err := m.merge(def, jsonparser.Object, append(stack, string(ref)))
+	if err != nil {
+		return nil, err
+	}
+	part, err := decodeKeywords(merged)
+	if err != nil {
+		return nil, err
+	}
+
+	// the keywords next to the reference override the ones of the definition
+	siblings, err := m.merge(jsonparser.Delete(append([]byte(nil), item...), refKey), jsonparser.Object, stack)
+	if err != nil {
+		return nil, err
+	}
+	own, err := decodeKeywords(siblings)
+	if err != nil {
+		return nil, err
+	}
+	return mergeKeywords("", part, own)
+}
+
+// mergeKeywords merges the keywords of a schema into the keywords merged so far, field is the path of the field the
+// schemas define.
+func mergeKeywords(field string, merged []keyword, schema []keyword) ([]keyword, error) {
+	for _, kw := range schema {
+		i := findKeyword(merged, kw.key)
+		if i < 0 {
+			merged = append(merged, kw)
+			continue
+		}
+
+		var err error
+		switch kw.key {
+		case "properties":
+			merged[i].value, err = mergeProperties(field, merged[i].value, kw.value)
+		case "required":
+			merged[i].value, err = mergeRequired(merged[i].value, kw.value)
+		default:
+			if field != "" && isConflictKeyword(kw.key) && !bytes.Equal(merged[i].value, kw.value) {
+				return nil, errors.InvalidArgument("conflicting %s of the field '%s' in %s: %s and %s", kw.key,
+					field, allOfKey, merged[i].value, kw.value)
+			}
+			merged[i].value = kw.value
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return merged, nil
+}
+
+func mergeProperties(parent string, merged []byte, properties []byte) ([]byte, error) {
+	mergedFields, err := decodeKeywords(merged)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := decodeKeywords(properties)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range fields {
+		i := findKeyword(mergedFields, f.key)
+		if i < 0 {
+			mergedFields = append(mergedFields, f)
+			continue
+		}
+
+		existing, err := decodeKeywords(mergedFields[i].value)
+		if err != nil {
+			return nil, err
+		}
+		field, err := decodeKeywords(f.value)
+		if err != nil {
+			return nil, err
+		}
+		if existing, err = mergeKeywords(buildPath(parent, f.key), existing, field); err != nil {
+			return nil, err
+		}
+		mergedFields[i].value = encodeKeywords(existing)
+	}
+
+	return encodeKeywords(mergedFields), nil
+}
+
+func mergeRequired(merged []byte, required []byte) ([]byte, error) {
+	var mergedFields, fields []string
+	if err := jsoniter.Unmarshal(merged, &mergedFields); err != nil {
+		return nil, errors.InvalidArgument("'required' must be an array of field names")
+	}
+	if err := jsoniter.Unmarshal(required, &fields); err != nil {
+		return nil, errors.InvalidArgument("'required' must be an array of field names")
+	}
+
+	for _, f := range fields {
+		found := false
+		for _, m := range mergedFields {
+			found = found || m == f
+		}
+		if !found {
+			mergedFields = append(mergedFields, f)
+		}
+	}
+
+	return jsoniter.Marshal(mergedFields)
+}
+
+func isConflictKeyword(key string) bool {
+	for _, k := range allOfConflictKeywords {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func findKeyword(keywords []keyword, key string) int {
+	for i, kw := range keywords {
+		if kw.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// decodeKeywords returns the keywords of a schema object in their order.
+func decodeKeywords(value []byte) ([]keyword, error) {
+	var keywords []keyword
+	err := jsonparser.ObjectEach(value, func(key []byte, nested []byte, nestedType jsonparser.ValueType, _ int) error {
+		if nestedType == jsonparser.String {
+			nested = append(append([]byte{'"'}, nested...), '"')
+		}
+		keywords = append(keywords, keyword{key: string(key), value: nested})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.InvalidArgument("the schema of a field must be an object")
+	}
+
+	return keywords, nil
+}
+
+func encodeKeywords(keywords []keyword) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, kw := range keywords {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('"')
+		buf.WriteString(kw.key)
+		buf.WriteString(`":`)
+		buf.Write(kw.value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
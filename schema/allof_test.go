@@ -0,0 +1,166 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/buger/jsonparser"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+const allOfSchema = `{
+	"title": "orders",
+	"definitions": {
+		"base": {
+			"type": "object",
+			"properties": {
+				"id": { "type": "integer" },
+				"created": { "type": "string", "format": "date-time" },
+				"meta": { "type": "object", "properties": { "source": { "type": "string" } } }
+			},
+			"required": ["id", "created"]
+		}
+	},
+	"allOf": [
+		{ "$ref": "#/definitions/base" },
+		{ "properties": { "meta": { "type": "object", "properties": { "region": { "type": "string" } } } } }
+	],
+	"properties": {
+		"created": { "type": "string", "format": "date-time", "description": "creation time" },
+		"total": { "type": "number" }
+	},
+	"required": ["total", "id"],
+	"primary_key": ["id"]
+}`
+
+func TestMergeAllOf(t *testing.T) {
+	t.Run("merged", func(t *testing.T) {
+		merged, err := MergeAllOf([]byte(allOfSchema))
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+	"title": "orders",
+	"definitions": {
+		"base": {
+			"type": "object",
+			"properties": {
+				"id": { "type": "integer" },
+				"created": { "type": "string", "format": "date-time" },
+				"meta": { "type": "object", "properties": { "source": { "type": "string" } } }
+			},
+			"required": ["id", "created"]
+		}
+	},
+	"type": "object",
+	"properties": {
+		"id": { "type": "integer" },
+		"created": { "type": "string", "format": "date-time", "description": "creation time" },
+		"meta": { "type": "object", "properties": { "source": { "type": "string" }, "region": { "type": "string" } } },
+		"total": { "type": "number" }
+	},
+	"required": ["id", "created", "total"],
+	"primary_key": ["id"]
+}`, string(merged))
+
+		// the properties are merged in the order of the subschemas
+		var fields []string
+		properties, _, _, err := jsonparser.Get(merged, "properties")
+		require.NoError(t, err)
+		keywords, err := decodeKeywords(properties)
+		require.NoError(t, err)
+		for _, kw := range keywords {
+			fields = append(fields, kw.key)
+		}
+		require.Equal(t, []string{"id", "created", "meta", "total"}, fields)
+	})
+
+	t.Run("no_all_of", func(t *testing.T) {
+		reqSchema := []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}}}`)
+		merged, err := MergeAllOf(reqSchema)
+		require.NoError(t, err)
+		require.Equal(t, reqSchema, []byte(merged))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		cases := []struct {
+			schema string
+			err    error
+		}{
+			{
+				`{"allOf": [{"properties": {"a": {"type": "string"}}}, {"properties": {"a": {"type": "integer"}}}]}`,
+				errors.InvalidArgument(`conflicting type of the field 'a' in allOf: "string" and "integer"`),
+			}, {
+				`{"allOf": [{"properties": {"a": {"type": "object", "properties": {"b": {"type": "string", "format": "uuid"}}}}}],
+				  "properties": {"a": {"type": "object", "properties": {"b": {"type": "string", "format": "date-time"}}}}}`,
+				errors.InvalidArgument(`conflicting format of the field 'a.b' in allOf: "uuid" and "date-time"`),
+			}, {
+				`{"allOf": {"properties": {}}}`,
+				errors.InvalidArgument("'allOf' must be an array of schemas"),
+			}, {
+				`{"allOf": [1]}`,
+				errors.InvalidArgument("'allOf' must be an array of schemas"),
+			}, {
+				`{"allOf": [{"$ref": "#/definitions/missing"}]}`,
+				errors.InvalidArgument("$ref '#/definitions/missing' is not defined"),
+			}, {
+				`{"definitions": {"a": {"allOf": [{"$ref": "#/definitions/a"}]}}, "allOf": [{"$ref": "#/definitions/a"}]}`,
+				errors.InvalidArgument("recursive $ref '#/definitions/a' is not supported"),
+			},
+		}
+		for _, c := range cases {
+			_, err := MergeAllOf([]byte(c.schema))
+			require.Equal(t, c.err, err, c.schema)
+		}
+	})
+}
+
+func TestBuildAllOf(t *testing.T) {
+	t.Run("merged", func(t *testing.T) {
+		factory, err := Build("orders", []byte(allOfSchema), false)
+		require.NoError(t, err)
+		require.NotContains(t, string(factory.Schema), allOfKey)
+
+		var fields []string
+		for _, f := range factory.Fields {
+			fields = append(fields, f.FieldName)
+		}
+		require.Equal(t, []string{"id", "created", "meta", "total"}, fields)
+		require.Equal(t, "id", factory.Indexes.PrimaryKey.Fields[0].FieldName)
+
+		coll := NewDefaultCollection("orders", 1, 1, factory.CollectionType, factory, "orders", nil)
+		require.NoError(t, coll.Validate(map[string]interface{}{
+			"id": 1, "created": "2022-10-11T04:19:32+05:30", "total": 1.5, "meta": map[string]interface{}{"region": "us"},
+		}))
+		// the required fields of the subschemas are required
+		require.Error(t, coll.Validate(map[string]interface{}{"id": 1, "total": 1.5}))
+
+		// the generated types have the fields of the subschemas
+		generated, err := Generate(factory.Schema, "go")
+		require.NoError(t, err)
+		require.Contains(t, string(generated), "Created")
+		require.Contains(t, string(generated), "Region")
+	})
+
+	t.Run("conflicting", func(t *testing.T) {
+		_, err := Build("orders", []byte(`{
+	"title": "orders",
+	"allOf": [{ "properties": { "id": { "type": "integer" } } }],
+	"properties": { "id": { "type": "string" } },
+	"primary_key": ["id"]
+}`), false)
+		require.Equal(t, errors.InvalidArgument(`conflicting type of the field 'id' in allOf: "integer" and "string"`), err)
+	})
+}
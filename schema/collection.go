@@ -16,23 +16,95 @@ package schema
 
 import (
 	"bytes"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"math"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/buger/jsonparser"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/santhosh-tekuri/jsonschema/v5"
+	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
 	"github.com/tigrisdata/tigris/lib/container"
 	tsApi "github.com/typesense/typesense-go/typesense/api"
 )
 
 const (
 	ObjFlattenDelimiter = "."
+	// DefaultMaxNestingDepth is the default maximum nesting depth of the documents.
+	DefaultMaxNestingDepth = 100
 )
 
+// MaxNestingDepth is the maximum number of nested objects and arrays of a document, the top level object included.
+// The documents nested deeper are rejected by Validate before they reach the recursive validator. Zero disables the
+// limit.
+var MaxNestingDepth = DefaultMaxNestingDepth
+
+// StrictDateTime requires the date-time values to carry an explicit timezone offset that the server can parse with
+// DateTimeFormat. The offset "-00:00", which RFC 3339 reserves for the local times of an unknown offset, is rejected
+// as well as the lowercase "t" and "z" separators. The values without an offset are rejected in both modes.
+var StrictDateTime = false
+
+// ErrorVerbosity is the level of detail of the messages of the errors returned when a document doesn't match the
+// schema.
+type ErrorVerbosity string
+
+const (
+	// TerseErrors only reports the field and the reason of the failure.
+	TerseErrors ErrorVerbosity = "terse"
+	// VerboseErrors also reports the path of the failing keyword in the schema and the value of the keyword.
+	VerboseErrors ErrorVerbosity = "verbose"
+)
+
+// ValidationErrorVerbosity is the verbosity of the messages of Validate.
+var ValidationErrorVerbosity = TerseErrors
+
+// ParseErrorVerbosity returns the verbosity of the name, the empty name is the terse verbosity.
+func ParseErrorVerbosity(name string) (ErrorVerbosity, error) {
+	switch ErrorVerbosity(name) {
+	case "", TerseErrors:
+		return TerseErrors, nil
+	case VerboseErrors:
+		return VerboseErrors, nil
+	default:
+		return "", errors.InvalidArgument("unknown validation error verbosity '%s'", name)
+	}
+}
+
+// NestingDepthError is returned by Validate when a document is nested deeper than MaxNestingDepth.
+type NestingDepthError struct {
+	*api.TigrisError
+
+	// Field is the first field found above the limit.
+	Field    string
+	MaxDepth int
+}
+
+func newNestingDepthError(field string, maxDepth int) *NestingDepthError {
+	return &NestingDepthError{
+		TigrisError: api.Errorf(api.Code_INVALID_ARGUMENT,
+			"json schema validation failed for field '%s' reason 'document exceeds the maximum nesting depth of %d'",
+			field, maxDepth),
+		Field:    field,
+		MaxDepth: maxDepth,
+	}
+}
+
+func (e *NestingDepthError) Unwrap() error {
+	return e.TigrisError
+}
+
+// timeOfDay is HH:MM:SS with optional fractional seconds and timezone. The timezone is required by RFC 3339 full-time,
+// but it is optional here so that a local time of day like a schedule can be stored.
+var timeOfDay = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]:([0-5][0-9]|60)(\.[0-9]+)?([zZ]|[+-]([01][0-9]|2[0-3]):[0-5][0-9])?$`)
+
 // DefaultCollection is used to represent a collection. The tenant in the metadata package is responsible for creating
 // the collection.
 type DefaultCollection struct {
@@ -64,8 +136,42 @@ type DefaultCollection struct {
 	Int64FieldsPath map[string]struct{}
 	// PartitionFields are the fields that make up the partition key, if applicable to the collection.
 	PartitionFields []*Field
+	// SearchHiddenFields are the paths of the fields annotated with "searchReturn": false. These fields are indexed
+	// but removed from the search hits. A hidden object field hides all of its nested fields.
+	SearchHiddenFields []string
+	// ImmutableFields are the paths of the fields annotated with "x-tigris-immutable": true, the updates are not
+	// allowed to change them. An immutable object field makes all of its nested fields immutable.
+	ImmutableFields []string
+	// ComputedFields are the fields annotated with "x-tigris-computed", the clients can't set them and they are
+	// populated by the hooks when the documents are normalized.
+	ComputedFields []ComputedField
+	// DefaultFields are the fields annotated with "default", their value is set in the documents inserted without them.
+	DefaultFields []DefaultField
+	// FieldAliases are the alternate names of the fields annotated with "x-tigris-aliases", the documents are
+	// normalized to the names of the fields.
+	FieldAliases []FieldAlias
 	// This is the existing fields in search
 	FieldsInSearch []tsApi.Field
+	// PreImages is set if the change stream of the collection carries the documents before the change, it is enabled
+	// with "pre_images" in the schema.
+	PreImages bool
+	// AppendOnly is set if the collection is annotated with "x-tigris-append-only": true, the documents can only be
+	// inserted, the updates, the replaces and the deletes are rejected.
+	AppendOnly bool
+	// Compression is the codec compressing the documents written to the collection, it is set with "compression" in
+	// the schema. The documents already stored keep the codec they were written with.
+	Compression internal.Compression
+
+	// expandedSchema is the schema the validator is compiled from, the keywords of the verbose errors are read from it
+	expandedSchema []byte
+	// partial is the validator of the partial documents, it is compiled on its first use
+	partial *partialValidator
+}
+
+// partialValidator is the validator of the partial documents, the required fields are not checked.
+type partialValidator struct {
+	once      sync.Once
+	validator *jsonschema.Schema
 }
 
 type CollectionType string
@@ -84,11 +190,13 @@ func disableAdditionalProperties(properties map[string]*jsonschema.Schema) {
 	}
 }
 
-func NewDefaultCollection(name string, id uint32, schVer int, ctype CollectionType, factory *Factory, searchCollectionName string, fieldsInSearch []tsApi.Field) *DefaultCollection {
+// compileValidator compiles the expanded schema of the collection, the validator doesn't allow additional
+// properties.
+func compileValidator(name string, expanded []byte) *jsonschema.Schema {
 	url := name + ".json"
 	compiler := jsonschema.NewCompiler()
 	compiler.Draft = jsonschema.Draft7 // Format is only working for draft7
-	if err := compiler.AddResource(url, bytes.NewReader(factory.Schema)); err != nil {
+	if err := compiler.AddResource(url, bytes.NewReader(expanded)); err != nil {
 		panic(err)
 	}
 
@@ -101,6 +209,81 @@ func NewDefaultCollection(name string, id uint32, schVer int, ctype CollectionTy
 	// schema validation.
 	validator.AdditionalProperties = false
 	disableAdditionalProperties(validator.Properties)
+	setContainsBounds(validator, expanded)
+
+	return validator
+}
+
+// setContainsBounds sets "minContains" and "maxContains" of the array fields, only the drafts after draft7, that the
+// validator is compiled with, load them.
+func setContainsBounds(s *jsonschema.Schema, raw []byte) {
+	if s == nil {
+		return
+	}
+	if s.Contains != nil {
+		if minContains, err := jsonparser.GetInt(raw, "minContains"); err == nil {
+			s.MinContains = int(minContains)
+		}
+		if maxContains, err := jsonparser.GetInt(raw, "maxContains"); err == nil {
+			s.MaxContains = int(maxContains)
+		}
+	}
+
+	_ = jsonparser.ObjectEach(raw, func(key []byte, value []byte, dataType jsonparser.ValueType, _ int) error {
+		if dataType == jsonparser.Object {
+			setContainsBounds(s.Properties[string(key)], value)
+		}
+		return nil
+	}, "properties")
+	if items, ok := s.Items.(*jsonschema.Schema); ok {
+		if value, dataType, _, err := jsonparser.Get(raw, "items"); err == nil && dataType == jsonparser.Object {
+			setContainsBounds(items, value)
+		}
+	}
+}
+
+// disableRequired removes the required fields of the schema and of its subschemas.
+func disableRequired(s *jsonschema.Schema, visited map[*jsonschema.Schema]struct{}) {
+	if s == nil {
+		return
+	}
+	if _, ok := visited[s]; ok {
+		return
+	}
+	visited[s] = struct{}{}
+
+	s.Required, s.DependentRequired = nil, nil
+	for _, p := range s.Properties {
+		disableRequired(p, visited)
+	}
+	subschemas := []*jsonschema.Schema{s.Ref, s.Not, s.If, s.Then, s.Else, s.Items2020}
+	subschemas = append(subschemas, s.AllOf...)
+	subschemas = append(subschemas, s.AnyOf...)
+	subschemas = append(subschemas, s.OneOf...)
+	subschemas = append(subschemas, s.PrefixItems...)
+	for _, sub := range subschemas {
+		disableRequired(sub, visited)
+	}
+	switch items := s.Items.(type) {
+	case *jsonschema.Schema:
+		disableRequired(items, visited)
+	case []*jsonschema.Schema:
+		for _, item := range items {
+			disableRequired(item, visited)
+		}
+	}
+	if additional, ok := s.AdditionalProperties.(*jsonschema.Schema); ok {
+		disableRequired(additional, visited)
+	}
+}
+
+func NewDefaultCollection(name string, id uint32, schVer int, ctype CollectionType, factory *Factory, searchCollectionName string, fieldsInSearch []tsApi.Field) *DefaultCollection {
+	// the references are expanded, so that the additional properties are also disabled on the shared definitions
+	expanded, err := ExpandRefs(factory.Schema)
+	if err != nil {
+		panic(err)
+	}
+	validator := compileValidator(name, expanded)
 
 	queryableFields := BuildQueryableFields(factory.Fields, fieldsInSearch)
 	partitionFields := BuildPartitionFields(factory.Fields)
@@ -119,10 +302,24 @@ func NewDefaultCollection(name string, id uint32, schVer int, ctype CollectionTy
 		Int64FieldsPath: make(map[string]struct{}),
 		PartitionFields: partitionFields,
 		FieldsInSearch:  fieldsInSearch,
+		PreImages:       factory.PreImages,
+		AppendOnly:      factory.AppendOnly,
+		Compression:     factory.Compression,
+		expandedSchema:  expanded,
+		partial:         &partialValidator{},
 	}
 
 	// set paths for int64 fields
 	d.setInt64Fields("", d.Fields)
+	// set paths for the fields that are not returned in search hits
+	d.setSearchHiddenFields("", d.Fields)
+	// set paths for the fields that can't be updated
+	d.setImmutableFields("", d.Fields)
+	// set paths for the fields derived by the compute hooks
+	d.setComputedFields("", d.Fields)
+	// set paths for the fields with a default value and the aliases of the fields
+	d.setDefaultFields("", d.Fields)
+	d.setFieldAliases("", d.Fields)
 
 	return d
 }
@@ -170,24 +367,408 @@ func (d *DefaultCollection) GetField(name string) *Field {
 	return nil
 }
 
-// Validate expects an unmarshalled document which it will validate again the schema of this collection.
+// Validate expects an unmarshalled document which it will validate again the schema of this collection. The
+// documents setting a computed field are rejected. The messages of the errors have the ValidationErrorVerbosity.
 func (d *DefaultCollection) Validate(document interface{}) error {
-	err := d.Validator.Validate(document)
-	if err == nil {
-		return nil
+	return d.ValidateWithVerbosity(document, ValidationErrorVerbosity)
+}
+
+// ValidateWithVerbosity is Validate with the verbosity of the messages of the errors.
+func (d *DefaultCollection) ValidateWithVerbosity(document interface{}, verbosity ErrorVerbosity) error {
+	if err := d.rejectComputedValues(document); err != nil {
+		return err
+	}
+	if err := validateValues("", document, 1); err != nil {
+		return err
+	}
+
+	return d.validateSchema(document, verbosity)
+}
+
+// ValidatePartial is Validate for the partial documents of the updates, only the fields that are present in the
+// document are validated and the required fields are not checked.
+func (d *DefaultCollection) ValidatePartial(document interface{}) error {
+	if err := d.rejectComputedValues(document); err != nil {
+		return err
+	}
+	if err := validateValues("", document, 1); err != nil {
+		return err
+	}
+
+	return d.validateSchemaWith(d.partialValidator(), document, ValidationErrorVerbosity)
+}
+
+func (d *DefaultCollection) partialValidator() *jsonschema.Schema {
+	compile := func() *jsonschema.Schema {
+		validator := compileValidator(d.Name, d.expandedSchema)
+		disableRequired(validator, make(map[*jsonschema.Schema]struct{}))
+		return validator
+	}
+	if d.partial == nil {
+		return compile()
+	}
+
+	d.partial.once.Do(func() {
+		d.partial.validator = compile()
+	})
+	return d.partial.validator
+}
+
+// ValidateAt validates the value of the field at the dotted path against the subschema of the field, without the rest
+// of the document. The field must be an object or an array, the items of an array are referred to by their index, like
+// "orders.0.details". The errors report the paths of the fields in the whole document.
+func (d *DefaultCollection) ValidateAt(path string, value interface{}) error {
+	subschema, location, err := d.subschemaAt(path)
+	if err != nil {
+		return err
+	}
+
+	keys := strings.Split(path, ObjFlattenDelimiter)
+	if err = d.rejectComputedValues(nestAt(keys, value)); err != nil {
+		return err
+	}
+	pointer := strings.Join(keys, "/")
+	if err = validateValues(pointer, value, len(keys)+1); err != nil {
+		return err
+	}
+
+	return d.validateSchemaAt(subschema, pointer, location, value, ValidationErrorVerbosity)
+}
+
+// subschemaAt returns the compiled subschema of the field at the dotted path and the location of the subschema in the
+// schema of the collection.
+func (d *DefaultCollection) subschemaAt(path string) (*jsonschema.Schema, string, error) {
+	if len(path) == 0 {
+		return nil, "", errors.InvalidArgument("path of the field to validate is empty")
+	}
+
+	s, location := d.Validator, ""
+	for _, key := range strings.Split(path, ObjFlattenDelimiter) {
+		if property, ok := s.Properties[key]; ok {
+			s, location = property, location+"/properties/"+key
+			continue
+		}
+		items, ok := s.Items.(*jsonschema.Schema)
+		if _, err := strconv.ParseUint(key, 10, 32); !ok || err != nil {
+			return nil, "", errors.InvalidArgument("field '%s' is not present in the collection", path)
+		}
+		s, location = items, location+"/items"
+	}
+
+	for _, t := range s.Types {
+		if t == jsonSpecObject || t == jsonSpecArray {
+			return s, location, nil
+		}
+	}
+	return nil, "", errors.InvalidArgument("field '%s' is not an object or an array", path)
+}
+
+// nestAt returns the value nested in the objects of the keys, like {"a": {"b": value}} for the keys "a" and "b".
+func nestAt(keys []string, value interface{}) map[string]interface{} {
+	doc := map[string]interface{}{keys[len(keys)-1]: value}
+	for i := len(keys) - 2; i >= 0; i-- {
+		doc = map[string]interface{}{keys[i]: doc}
+	}
+	return doc
+}
+
+func (d *DefaultCollection) validateSchema(document interface{}, verbosity ErrorVerbosity) error {
+	return d.validateSchemaWith(d.Validator, document, verbosity)
+}
+
+func (d *DefaultCollection) validateSchemaWith(validator *jsonschema.Schema, document interface{}, verbosity ErrorVerbosity) error {
+	return d.validateSchemaAt(validator, "", "", document, verbosity)
+}
+
+// validateSchemaAt validates the value of the field at the pointer with the subschema at the location of the schema,
+// the locations of the errors are relative to the field. The error has all the violations of the value up to
+// MaxViolations, the details of the verbose errors are the ones of the first violation.
+func (d *DefaultCollection) validateSchemaAt(validator *jsonschema.Schema, pointer string, location string, value interface{}, verbosity ErrorVerbosity) error {
+	// one more violation than reported tells if the violations are truncated
+	limit := 0
+	if MaxViolations > 0 {
+		limit = MaxViolations + 1
+	}
+
+	violations, err := d.collectViolations(validator, pointer, location, value, nil, limit)
+	if err != nil || len(violations) == 0 {
+		return err
+	}
+	truncated := MaxViolations > 0 && len(violations) > MaxViolations
+	if truncated {
+		violations = violations[:MaxViolations]
+	}
+
+	details := ""
+	if verbosity == VerboseErrors {
+		details = fmt.Sprintf(" schema path '%s' constraint '%s'", violations[0].schemaPath, violations[0].Constraint)
+	}
+	reported := make([]Violation, 0, len(violations))
+	for _, v := range violations {
+		reported = append(reported, v.Violation)
+	}
+	return newValidationErrors(reported, truncated, details)
+}
+
+// violation is a violation with the location of the keyword in the schema.
+type violation struct {
+	Violation
+
+	schemaPath string
+}
+
+// collectViolations appends the violations of the value to the violations until there are limit violations, zero
+// doesn't limit them. The violations reported by the validator are sorted by field, the large arrays of the value are
+// validated item by item once the rest of the value is validated.
+func (d *DefaultCollection) collectViolations(validator *jsonschema.Schema, pointer string, location string, value interface{}, violations []violation, limit int) ([]violation, error) {
+	var arrays []largeArray
+	if LargeArrayThreshold > 0 {
+		value, _ = splitLargeArrays(validator, pointer, location, value, &arrays)
+	}
+
+	if err := validator.Validate(value); err != nil {
+		v, ok := err.(*jsonschema.ValidationError)
+		if !ok {
+			return nil, errors.InvalidArgument(err.Error())
+		}
+
+		var found []violation
+		for _, cause := range leafCauses(v, nil) {
+			found = append(found, d.newViolation(cause, pointer, location))
+		}
+		sort.SliceStable(found, func(i, j int) bool { return comparePointers(found[i].Field, found[j].Field) < 0 })
+		violations = append(violations, found...)
 	}
 
-	if v, ok := err.(*jsonschema.ValidationError); ok {
-		if len(v.Causes) == 1 {
-			field := v.Causes[0].InstanceLocation
-			if len(field) > 0 && field[0] == '/' {
-				field = field[1:]
+	return d.largeArrayViolations(arrays, violations, limit)
+}
+
+// leafCauses appends the causes of the error that don't have causes to the causes, they are the keywords the value
+// violates. The causes of "contains", with its bounds, "anyOf" and "oneOf" are the subschemas the value doesn't match,
+// the keywords themselves are the violations.
+func leafCauses(err *jsonschema.ValidationError, causes []*jsonschema.ValidationError) []*jsonschema.ValidationError {
+	keyword := err.KeywordLocation[strings.LastIndex(err.KeywordLocation, "/")+1:]
+	switch keyword {
+	case "contains", "minContains", "maxContains", "anyOf", "oneOf":
+		return append(causes, err)
+	}
+	if len(err.Causes) == 0 {
+		return append(causes, err)
+	}
+	for _, cause := range err.Causes {
+		causes = leafCauses(cause, causes)
+	}
+	return causes
+}
+
+// newViolation returns the violation of the cause of a validation error of the value of the field at the pointer.
+func (d *DefaultCollection) newViolation(cause *jsonschema.ValidationError, pointer string, location string) violation {
+	field := cause.InstanceLocation
+	if len(field) > 0 && field[0] == '/' {
+		field = field[1:]
+	}
+	if len(field) == 0 {
+		field = pointer
+	} else {
+		field = joinPointer(pointer, field)
+	}
+	keywordLocation := location + cause.KeywordLocation
+	reason := cause.Message
+	if strings.HasSuffix(keywordLocation, "/multipleOf") {
+		// the validator formats the value as a float, it is reported as it is written in the schema
+		if value, _, _, err := jsonparser.Get(d.expandedSchema, schemaKeys(keywordLocation)...); err == nil {
+			reason = fmt.Sprintf("not a multiple of %s", value)
+		}
+	}
+	if strings.HasSuffix(keywordLocation, "/minContains") || strings.HasSuffix(keywordLocation, "/maxContains") {
+		reason = containsReason(keywordLocation, cause.Message)
+	}
+
+	return violation{
+		Violation:  Violation{Field: field, Reason: reason, Constraint: d.keywordConstraint(keywordLocation)},
+		schemaPath: keywordLocation,
+	}
+}
+
+// comparePointers compares the paths of two fields level by level, the indexes of the arrays are compared as numbers.
+func comparePointers(a string, b string) int {
+	keysA, keysB := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(keysA) && i < len(keysB); i++ {
+		if keysA[i] == keysB[i] {
+			continue
+		}
+		indexA, errA := strconv.Atoi(keysA[i])
+		indexB, errB := strconv.Atoi(keysB[i])
+		if errA == nil && errB == nil {
+			return indexA - indexB
+		}
+		return strings.Compare(keysA[i], keysB[i])
+	}
+	return len(keysA) - len(keysB)
+}
+
+// containsReason rewrites the message of the validator for the number of the items of an array matching "contains".
+func containsReason(location string, message string) string {
+	var bound, matched int
+	if strings.HasSuffix(location, "/minContains") {
+		if _, err := fmt.Sscanf(message, "valid must be >= %d, but got %d", &bound, &matched); err != nil {
+			return message
+		}
+		if matched == 0 {
+			return "no items match contains"
+		}
+		return fmt.Sprintf("%d items match contains, expected at least %d", matched, bound)
+	}
+
+	if _, err := fmt.Sscanf(message, "valid must be <= %d, but got %d", &bound, &matched); err != nil {
+		return message
+	}
+	return fmt.Sprintf("%d items match contains, expected at most %d", matched, bound)
+}
+
+// keywordConstraint returns the keyword at the location of the schema with its value, like `maxLength: 5`. The
+// keywords set by the server, like "additionalProperties", are not in the schema and only the keyword is returned.
+func (d *DefaultCollection) keywordConstraint(location string) string {
+	keys := schemaKeys(location)
+	keyword := keys[len(keys)-1]
+
+	value, dataType, _, err := jsonparser.Get(d.expandedSchema, keys...)
+	if err != nil {
+		return keyword
+	}
+	if dataType == jsonparser.String {
+		return fmt.Sprintf("%s: %q", keyword, value)
+	}
+
+	var compact bytes.Buffer
+	if json.Compact(&compact, value) != nil {
+		return fmt.Sprintf("%s: %s", keyword, value)
+	}
+	return fmt.Sprintf("%s: %s", keyword, compact.String())
+}
+
+// schemaKeys returns the keys of the JSON pointer of a location of the schema.
+func schemaKeys(location string) []string {
+	var keys []string
+	for _, token := range strings.Split(strings.TrimPrefix(location, "/"), "/") {
+		keys = append(keys, strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~"))
+	}
+	return keys
+}
+
+// validateValues rejects the documents nested deeper than MaxNestingDepth and the numbers of the document that are not
+// valid JSON numbers. The decoder keeps the numbers as they are sent when it is using UseNumber, and it accepts some
+// malformed numbers, like "0+", that the validator can't parse. NaN and Infinity are rejected whether they are sent as
+// tokens, as the numbers overflowing a double or as the floats of a decoded document.
+func validateValues(path string, value interface{}, depth int) error {
+	switch v := value.(type) {
+	case json.Number:
+		if isNonFiniteToken(string(v)) {
+			return NewValidationError(path, fmt.Sprintf("%s is not a valid number, NaN and Infinity are not supported", v))
+		}
+		if !isJSONNumber(string(v)) {
+			return NewValidationError(path, fmt.Sprintf("invalid number %s", v))
+		}
+		if overflowsDouble(string(v)) {
+			return NewValidationError(path, fmt.Sprintf("number %s is out of the range of a double, Infinity is not supported", v))
+		}
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return NewValidationError(path, fmt.Sprintf("%v is not a valid number, NaN and Infinity are not supported", v))
+		}
+	case float32:
+		if f := float64(v); math.IsNaN(f) || math.IsInf(f, 0) {
+			return NewValidationError(path, fmt.Sprintf("%v is not a valid number, NaN and Infinity are not supported", v))
+		}
+	case map[string]interface{}:
+		if MaxNestingDepth > 0 && depth > MaxNestingDepth {
+			return newNestingDepthError(path, MaxNestingDepth)
+		}
+		for key, nested := range v {
+			if err := validateValues(joinPointer(path, key), nested, depth+1); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if MaxNestingDepth > 0 && depth > MaxNestingDepth {
+			return newNestingDepthError(path, MaxNestingDepth)
+		}
+		for i, nested := range v {
+			if err := validateValues(joinPointer(path, strconv.Itoa(i)), nested, depth+1); err != nil {
+				return err
 			}
-			return errors.InvalidArgument("json schema validation failed for field '%s' reason '%s'", field, v.Causes[0].Message)
 		}
 	}
 
-	return errors.InvalidArgument(err.Error())
+	return nil
+}
+
+func joinPointer(path string, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+	return path + "/" + key
+}
+
+// isNonFiniteToken returns true if s is one of the NaN and Infinity tokens some encoders emit, like "NaN", "-Infinity"
+// or "inf".
+func isNonFiniteToken(s string) bool {
+	s = strings.TrimLeft(s, "+-")
+	return strings.EqualFold(s, "nan") || strings.EqualFold(s, "inf") || strings.EqualFold(s, "infinity")
+}
+
+// overflowsDouble returns true if the JSON number s is too large to be a double, it would be stored as Infinity. Only
+// the numbers with an exponent or with more digits than the largest double can overflow.
+func overflowsDouble(s string) bool {
+	if !strings.ContainsAny(s, "eE") && len(s) <= 309 {
+		return false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return err != nil && math.IsInf(f, 0)
+}
+
+// isJSONNumber returns true if s follows the JSON number grammar, -?(0|[1-9][0-9]*)(.[0-9]+)?([eE][+-]?[0-9]+)?.
+func isJSONNumber(s string) bool {
+	digits := func(i int) int {
+		start := i
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		return i - start
+	}
+
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	switch {
+	case i < len(s) && s[i] == '0':
+		i++
+	case i < len(s) && s[i] >= '1' && s[i] <= '9':
+		i += digits(i)
+	default:
+		return false
+	}
+	if i < len(s) && s[i] == '.' {
+		n := digits(i + 1)
+		if n == 0 {
+			return false
+		}
+		i += n + 1
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		n := digits(i)
+		if n == 0 {
+			return false
+		}
+		i += n
+	}
+
+	return i == len(s)
 }
 
 func (d *DefaultCollection) SearchCollectionName() string {
@@ -210,6 +791,45 @@ func (d *DefaultCollection) setInt64Fields(parent string, fields []*Field) {
 	}
 }
 
+func (d *DefaultCollection) setSearchHiddenFields(parent string, fields []*Field) {
+	for _, f := range fields {
+		if !f.IsSearchReturned() {
+			d.SearchHiddenFields = append(d.SearchHiddenFields, buildPath(parent, f.FieldName))
+			continue
+		}
+
+		if f.DataType == ObjectType && len(f.Fields) > 0 {
+			d.setSearchHiddenFields(buildPath(parent, f.FieldName), f.Fields)
+		}
+	}
+}
+
+func (d *DefaultCollection) setImmutableFields(parent string, fields []*Field) {
+	for _, f := range fields {
+		if f.IsImmutable() {
+			d.ImmutableFields = append(d.ImmutableFields, buildPath(parent, f.FieldName))
+			continue
+		}
+
+		if f.DataType == ObjectType && len(f.Fields) > 0 {
+			d.setImmutableFields(buildPath(parent, f.FieldName), f.Fields)
+		}
+	}
+}
+
+// GetImmutableField returns the immutable field changed by an update of the field path. It is either the field
+// itself, or one of its parent objects, or one of its nested fields as setting an object replaces all of its fields.
+func (d *DefaultCollection) GetImmutableField(path string) (string, bool) {
+	for _, immutable := range d.ImmutableFields {
+		if immutable == path || strings.HasPrefix(path, immutable+ObjFlattenDelimiter) ||
+			strings.HasPrefix(immutable, path+ObjFlattenDelimiter) {
+			return immutable, true
+		}
+	}
+
+	return "", false
+}
+
 func buildPath(parent string, field string) string {
 	if len(parent) > 0 {
 		if len(field) > 0 {
@@ -282,6 +902,17 @@ func buildSearchSchema(name string, queryableFields []*QueryableField) *tsApi.Co
 				Optional: &ptrTrue,
 			})
 		}
+		// Index the number of elements of the arrays to sort on it
+		if !s.IsReserved() && s.DataType == ArrayType {
+			tsFields = append(tsFields, tsApi.Field{
+				Name:     ToSearchArrayLengthKey(s.Name()),
+				Type:     toSearchFieldType(Int32Type, UnknownType),
+				Facet:    &ptrFalse,
+				Index:    &ptrTrue,
+				Sort:     &ptrTrue,
+				Optional: &ptrTrue,
+			})
+		}
 	}
 
 	return &tsApi.CollectionSchema{
@@ -290,26 +921,13 @@ func buildSearchSchema(name string, queryableFields []*QueryableField) *tsApi.Co
 	}
 }
 
-func init() {
-	jsonschema.Formats[FieldNames[ByteType]] = func(i interface{}) bool {
-		if v, ok := i.(string); ok {
-			_, err := base64.StdEncoding.DecodeString(v)
-			return err == nil
-		}
+// isStrictDateTime returns true if the date-time has an explicit timezone offset and is parsable with DateTimeFormat.
+func isStrictDateTime(v string) bool {
+	if strings.HasSuffix(v, "-00:00") {
 		return false
 	}
-	jsonschema.Formats[FieldNames[Int32Type]] = func(i interface{}) bool {
-		val, err := parseInt(i)
-		if err != nil {
-			return false
-		}
-
-		return !(val < math.MinInt32 || val > math.MaxInt32)
-	}
-	jsonschema.Formats[FieldNames[Int64Type]] = func(i interface{}) bool {
-		_, err := parseInt(i)
-		return err == nil
-	}
+	_, err := time.Parse(DateTimeFormat, v)
+	return err == nil
 }
 
 func parseInt(i interface{}) (int64, error) {
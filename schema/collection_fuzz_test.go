@@ -0,0 +1,83 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/lib/json"
+)
+
+func FuzzCollection_Validate(f *testing.F) {
+	reqSchema := []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": { "type": "integer" },
+		"id_32": { "type": "integer", "format": "int32" },
+		"price": { "type": "number" },
+		"name": { "type": "string", "maxLength": 10 },
+		"bytes": { "type": "string", "format": "byte" },
+		"ts": { "type": "string", "format": "date-time" },
+		"uuid": { "type": "string", "format": "uuid" },
+		"flag": { "type": "boolean" },
+		"ints": { "type": "array", "items": { "type": "integer" } },
+		"nested_arr": { "type": "array", "items": { "type": "array", "items": { "type": "number" } } },
+		"obj": {
+			"type": "object",
+			"properties": {
+				"a": { "type": "integer" },
+				"inner": { "type": "object", "properties": { "b": { "type": "string" } } }
+			}
+		},
+		"any_obj": { "type": "object" }
+	},
+	"primary_key": ["id"]
+}`)
+	factory, err := Build("t1", reqSchema, false)
+	require.NoError(f, err)
+	coll := NewDefaultCollection("t1", 1, 1, factory.CollectionType, factory, "t1", nil)
+
+	for _, seed := range []string{
+		`{"id": 1, "price": 1.5, "name": "a", "flag": true, "ints": [1, 2], "obj": {"a": 1, "inner": {"b": "c"}}}`,
+		`{"id": 1e400, "price": -1e-400, "id_32": 2147483648}`,
+		`{"id": 123456789012345678901234567890, "ints": [9223372036854775808]}`,
+		`{"id": NaN, "price": Infinity}`,
+		`{"price": -0, "id_32": 1.0, "id": 1E2}`,
+		`{"ts": "2022-13-45T99:99:99Z", "uuid": "not-a-uuid", "bytes": "%%%"}`,
+		`{"any_obj": ` + strings.Repeat(`{"a":`, 100) + `1` + strings.Repeat(`}`, 100) + `}`,
+		`{"nested_arr": ` + strings.Repeat(`[`, 100) + strings.Repeat(`]`, 100) + `}`,
+		// close to the maximum depth of the decoder
+		`{"any_obj": ` + strings.Repeat(`{"a":[`, 4000) + `0+` + strings.Repeat(`]}`, 4000) + `}`,
+		`{"obj": {"a": {"b": [1, {"c": null}]}}}`,
+		`{"name": "\ud800", "obj": null}`,
+		`null`,
+		`[]`,
+		`{"id": 1`,
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, doc []byte) {
+		decoded, err := json.Decode(doc)
+		if err != nil {
+			return
+		}
+
+		// an invalid document has to be rejected with an error, it must never panic
+		_ = coll.Validate(decoded)
+	})
+}
@@ -18,11 +18,16 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
+	"math/big"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
 )
 
 func TestCollection_SchemaValidate(t *testing.T) {
@@ -61,6 +66,22 @@ func TestCollection_SchemaValidate(t *testing.T) {
 				"type": "string",
 				"format": "date-time"
 			},
+			"email": {
+				"type": "string",
+				"format": "email"
+			},
+			"homepage": {
+				"type": "string",
+				"format": "uri"
+			},
+			"birthday": {
+				"type": "string",
+				"format": "date"
+			},
+			"opens_at": {
+				"type": "string",
+				"format": "time"
+			},
 			"price": {
 				"type": "number"
 			},
@@ -168,6 +189,110 @@ func TestCollection_SchemaValidate(t *testing.T) {
 			document: []byte(`{"id": 1, "ts": "2016-02-15"}`),
 			expError: "field 'ts' reason ''2016-02-15' is not valid 'date-time'",
 		},
+		{
+			document: []byte(`{"id": 1, "email": "john.doe+tigris@example.com"}`),
+			expError: "",
+		},
+		{
+			document: []byte(`{"id": 1, "email": "john@mail.example.co.uk"}`),
+			expError: "",
+		},
+		{
+			document: []byte(`{"id": 1, "email": "x"}`),
+			expError: "field 'email' reason ''x' is not valid 'email'",
+		},
+		{
+			document: []byte(`{"id": 1, "email": "john@"}`),
+			expError: "field 'email' reason ''john@' is not valid 'email'",
+		},
+		{
+			document: []byte(`{"id": 1, "email": "@example.com"}`),
+			expError: "field 'email' reason ''@example.com' is not valid 'email'",
+		},
+		{
+			document: []byte(`{"id": 1, "email": "john doe@example.com"}`),
+			expError: "field 'email' reason ''john doe@example.com' is not valid 'email'",
+		},
+		{
+			document: []byte(`{"id": 1, "email": 1}`),
+			expError: "expected string, but got number",
+		},
+		{
+			document: []byte(`{"id": 1, "homepage": "https://www.tigrisdata.com/docs?page=1#intro"}`),
+			expError: "",
+		},
+		{
+			document: []byte(`{"id": 1, "homepage": "urn:isbn:0451450523"}`),
+			expError: "",
+		},
+		{
+			document: []byte(`{"id": 1, "homepage": "www.tigrisdata.com"}`),
+			expError: "field 'homepage' reason ''www.tigrisdata.com' is not valid 'uri'",
+		},
+		{
+			document: []byte(`{"id": 1, "homepage": "http://[::1"}`),
+			expError: "field 'homepage' reason ''http://[::1' is not valid 'uri'",
+		},
+		{
+			document: []byte(`{"id": 1, "birthday": "2016-02-15"}`),
+			expError: "",
+		},
+		{
+			document: []byte(`{"id": 1, "birthday": "2016-02-29"}`),
+			expError: "",
+		},
+		{
+			document: []byte(`{"id": 1, "birthday": "2017-02-29"}`),
+			expError: "field 'birthday' reason ''2017-02-29' is not valid 'date'",
+		},
+		{
+			document: []byte(`{"id": 1, "birthday": "2016-02-15T00:00:00Z"}`),
+			expError: "field 'birthday' reason ''2016-02-15T00:00:00Z' is not valid 'date'",
+		},
+		{
+			document: []byte(`{"id": 1, "birthday": "15-02-2016"}`),
+			expError: "field 'birthday' reason ''15-02-2016' is not valid 'date'",
+		},
+		{
+			document: []byte(`{"id": 1, "opens_at": "09:30:00"}`),
+			expError: "",
+		},
+		{
+			document: []byte(`{"id": 1, "opens_at": "23:59:60.123"}`),
+			expError: "",
+		},
+		{
+			document: []byte(`{"id": 1, "opens_at": "09:30:00Z"}`),
+			expError: "",
+		},
+		{
+			document: []byte(`{"id": 1, "opens_at": "09:30:00.5+05:30"}`),
+			expError: "",
+		},
+		{
+			document: []byte(`{"id": 1, "opens_at": "9:30:00"}`),
+			expError: "field 'opens_at' reason ''9:30:00' is not valid 'time'",
+		},
+		{
+			document: []byte(`{"id": 1, "opens_at": "24:00:00"}`),
+			expError: "field 'opens_at' reason ''24:00:00' is not valid 'time'",
+		},
+		{
+			document: []byte(`{"id": 1, "opens_at": "09:30"}`),
+			expError: "field 'opens_at' reason ''09:30' is not valid 'time'",
+		},
+		{
+			document: []byte(`{"id": 1, "opens_at": "09:30:00+5:30"}`),
+			expError: "field 'opens_at' reason ''09:30:00+5:30' is not valid 'time'",
+		},
+		{
+			document: []byte(`{"id": 1, "opens_at": "09:30:00."}`),
+			expError: "field 'opens_at' reason ''09:30:00.' is not valid 'time'",
+		},
+		{
+			document: []byte(`{"id": 1, "opens_at": "2016-02-15T09:30:00Z"}`),
+			expError: "field 'opens_at' reason ''2016-02-15T09:30:00Z' is not valid 'time'",
+		},
 		{
 			document: []byte(`{"id": 1, "random_binary": 1}`),
 			expError: "expected string, but got number",
@@ -199,7 +324,7 @@ func TestCollection_SchemaValidate(t *testing.T) {
 		},
 	}
 	for _, c := range cases {
-		schFactory, err := Build("t1", reqSchema)
+		schFactory, err := Build("t1", reqSchema, false)
 		require.NoError(t, err)
 
 		coll := NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
@@ -299,13 +424,15 @@ func TestCollection_SearchSchema(t *testing.T) {
 	"primary_key": ["id"]
 }`)
 
-	schFactory, err := Build("t1", reqSchema)
+	schFactory, err := Build("t1", reqSchema, false)
 	require.NoError(t, err)
 
 	expFlattenedFields := []string{
-		"id", "_tigris_id", "id_32", "product", "id_uuid", "ts", ToSearchDateKey("ts"), "price", "simple_items", "simple_object.name",
-		"simple_object.phone", "simple_object.address.street", "simple_object.details.nested_id", "simple_object.details.nested_obj.id",
-		"simple_object.details.nested_obj.name", "simple_object.details.nested_array", "simple_object.details.nested_string",
+		"id", "_tigris_id", "id_32", "product", "id_uuid", "ts", ToSearchDateKey("ts"), "price", "simple_items",
+		ToSearchArrayLengthKey("simple_items"), "simple_object.name", "simple_object.phone", "simple_object.address.street",
+		"simple_object.details.nested_id", "simple_object.details.nested_obj.id", "simple_object.details.nested_obj.name",
+		"simple_object.details.nested_array", ToSearchArrayLengthKey("simple_object.details.nested_array"),
+		"simple_object.details.nested_string",
 		"created_at", "updated_at",
 	}
 
@@ -360,7 +487,7 @@ func TestCollection_AdditionalProperties(t *testing.T) {
 		},
 	}
 	for _, c := range cases {
-		schFactory, err := Build("t1", reqSchema)
+		schFactory, err := Build("t1", reqSchema, false)
 		require.NoError(t, err)
 		coll := NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
 
@@ -372,6 +499,228 @@ func TestCollection_AdditionalProperties(t *testing.T) {
 	}
 }
 
+func TestCollection_MalformedNumbers(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"price": { "type": "number" },
+			"any_obj": { "type": "object" }
+		},
+		"primary_key": ["id"]
+	}`)
+	schFactory, err := Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	coll := NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
+
+	cases := []struct {
+		document []byte
+		expError *ValidationError
+	}{
+		{
+			document: []byte(`{"id": 0+}`),
+			expError: NewValidationError("id", "invalid number 0+"),
+		}, {
+			document: []byte(`{"id": 1, "price": 1.5e}`),
+			expError: NewValidationError("price", "invalid number 1.5e"),
+		}, {
+			document: []byte(`{"id": 1, "any_obj": {"a": [1, {"b": --1}]}}`),
+			expError: NewValidationError("any_obj/a/1/b", "invalid number --1"),
+		},
+	}
+	for _, c := range cases {
+		dec := jsoniter.NewDecoder(bytes.NewReader(c.document))
+		dec.UseNumber()
+		var v interface{}
+		require.NoError(t, dec.Decode(&v))
+		err := coll.Validate(v)
+		require.Equal(t, c.expError, err)
+		require.Equal(t, fmt.Sprintf("json schema validation failed for field '%s' reason '%s'",
+			c.expError.Field, c.expError.Reason), err.Error())
+	}
+
+	for _, n := range []string{"0", "-0", "12", "-1.25", "1e400", "1E+2", "2.5e-3"} {
+		require.True(t, isJSONNumber(n), n)
+	}
+	for _, n := range []string{"", "-", "+1", "01", "1.", ".5", "1e", "1e+", "0+", "1.5.2", "NaN", "Infinity", "0x10"} {
+		require.False(t, isJSONNumber(n), n)
+	}
+}
+
+func TestCollection_NonFiniteNumbers(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"price": { "type": "number" },
+			"prices": { "type": "array", "items": { "type": "number" } },
+			"any_obj": { "type": "object" }
+		},
+		"primary_key": ["id"]
+	}`)
+	schFactory, err := Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	coll := NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
+
+	cases := []struct {
+		document map[string]interface{}
+		expError *ValidationError
+	}{
+		{
+			document: map[string]interface{}{"id": json.Number("1"), "price": json.Number("NaN")},
+			expError: NewValidationError("price", "NaN is not a valid number, NaN and Infinity are not supported"),
+		}, {
+			document: map[string]interface{}{"id": json.Number("-Infinity")},
+			expError: NewValidationError("id", "-Infinity is not a valid number, NaN and Infinity are not supported"),
+		}, {
+			document: map[string]interface{}{"id": json.Number("1"), "prices": []interface{}{json.Number("1.5"), json.Number("inf")}},
+			expError: NewValidationError("prices/1", "inf is not a valid number, NaN and Infinity are not supported"),
+		}, {
+			document: map[string]interface{}{"id": json.Number("1"), "price": math.NaN()},
+			expError: NewValidationError("price", "NaN is not a valid number, NaN and Infinity are not supported"),
+		}, {
+			document: map[string]interface{}{"id": json.Number("1"), "any_obj": map[string]interface{}{"a": math.Inf(1)}},
+			expError: NewValidationError("any_obj/a", "+Inf is not a valid number, NaN and Infinity are not supported"),
+		}, {
+			document: map[string]interface{}{"id": json.Number("1"), "price": float32(math.Inf(-1))},
+			expError: NewValidationError("price", "-Inf is not a valid number, NaN and Infinity are not supported"),
+		}, {
+			document: map[string]interface{}{"id": json.Number("1"), "price": json.Number("-1e400")},
+			expError: NewValidationError("price", "number -1e400 is out of the range of a double, Infinity is not supported"),
+		},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.expError, coll.Validate(c.document))
+		require.Equal(t, c.expError, coll.ValidatePartial(c.document))
+	}
+
+	// the numbers that are finite once parsed are valid
+	for _, n := range []string{"1.7976931348623157e308", "-1e-400", "0.5"} {
+		require.NoError(t, coll.Validate(map[string]interface{}{"id": json.Number("1"), "price": json.Number(n)}), n)
+	}
+
+	// the decoder rejects the NaN and Infinity tokens before they can be validated
+	for _, doc := range []string{`{"id": 1, "price": NaN}`, `{"id": 1, "price": Infinity}`, `{"id": 1, "price": -Infinity}`} {
+		dec := jsoniter.NewDecoder(bytes.NewReader([]byte(doc)))
+		dec.UseNumber()
+		var v interface{}
+		require.Error(t, dec.Decode(&v), doc)
+	}
+}
+
+func TestCollection_MaxNestingDepth(t *testing.T) {
+	defer func() { MaxNestingDepth = DefaultMaxNestingDepth }()
+
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"any_obj": { "type": "object" },
+			"arr": { "type": "array", "items": { "type": "array" } }
+		},
+		"primary_key": ["id"]
+	}`)
+	schFactory, err := Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	coll := NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
+
+	decode := func(document string) interface{} {
+		dec := jsoniter.NewDecoder(bytes.NewReader([]byte(document)))
+		dec.UseNumber()
+		var v interface{}
+		require.NoError(t, dec.Decode(&v))
+		return v
+	}
+
+	MaxNestingDepth = 3
+	require.NoError(t, coll.Validate(decode(`{"id": 1, "any_obj": {"a": {"b": 1}}}`)))
+	require.NoError(t, coll.Validate(decode(`{"id": 1, "arr": [[1]]}`)))
+
+	err = coll.Validate(decode(`{"id": 1, "any_obj": {"a": {"b": {"c": 1}}}}`))
+	var depthErr *NestingDepthError
+	require.ErrorAs(t, err, &depthErr)
+	require.Equal(t, "any_obj/a/b", depthErr.Field)
+	require.Equal(t, 3, depthErr.MaxDepth)
+	require.Equal(t, "json schema validation failed for field 'any_obj/a/b' reason 'document exceeds the maximum nesting depth of 3'", err.Error())
+
+	var tigrisErr *api.TigrisError
+	require.ErrorAs(t, err, &tigrisErr)
+	require.Equal(t, api.Code_INVALID_ARGUMENT, tigrisErr.Code)
+
+	require.ErrorAs(t, coll.Validate(decode(`{"id": 1, "arr": [[[1]]]}`)), &depthErr)
+	require.Equal(t, "arr/0/0", depthErr.Field)
+
+	// the default limit protects the validator from the pathological documents
+	MaxNestingDepth = DefaultMaxNestingDepth
+	deep := `{"id": 1, "any_obj": ` + strings.Repeat(`{"a":`, 5000) + `1` + strings.Repeat(`}`, 5000) + `}`
+	require.ErrorAs(t, coll.Validate(decode(deep)), &depthErr)
+	require.Equal(t, DefaultMaxNestingDepth, depthErr.MaxDepth)
+
+	MaxNestingDepth = 0
+	require.NoError(t, coll.Validate(decode(`{"id": 1, "any_obj": {"a": {"b": {"c": 1}}}}`)))
+}
+
+func TestCollection_Refs(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"definitions": {
+			"address": {
+				"type": "object",
+				"properties": {
+					"city": { "type": "string" },
+					"zip": { "type": "integer", "format": "int32" }
+				}
+			}
+		},
+		"properties": {
+			"id": { "type": "integer" },
+			"billing": { "$ref": "#/definitions/address" },
+			"previous": { "type": "array", "items": { "$ref": "#/definitions/address" } }
+		},
+		"primary_key": ["id"]
+	}`)
+
+	schFactory, err := Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	// the schema is stored with its references
+	require.Equal(t, reqSchema, []byte(schFactory.Schema))
+	coll := NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
+
+	// the referenced fields are fields of the collection like the inline ones
+	f, err := coll.GetQueryableField("billing.zip")
+	require.NoError(t, err)
+	require.Equal(t, Int32Type, f.DataType)
+
+	cases := []struct {
+		document []byte
+		expError string
+	}{
+		{
+			document: []byte(`{"id": 1, "billing": {"city": "Paris", "zip": 75001}, "previous": [{"city": "Lyon"}]}`),
+		}, {
+			document: []byte(`{"id": 1, "billing": {"city": 1}}`),
+			expError: "json schema validation failed for field 'billing/city' reason 'expected string, but got number'",
+		}, {
+			document: []byte(`{"id": 1, "billing": {"city": "Paris", "country": "FR"}}`),
+			expError: "json schema validation failed for field 'billing' reason 'additionalProperties 'country' not allowed'",
+		}, {
+			document: []byte(`{"id": 1, "previous": [{"zip": 2147483648}]}`),
+			expError: "json schema validation failed for field 'previous/0/zip' reason ",
+		},
+	}
+	for _, c := range cases {
+		dec := jsoniter.NewDecoder(bytes.NewReader(c.document))
+		dec.UseNumber()
+		var v interface{}
+		require.NoError(t, dec.Decode(&v))
+		if len(c.expError) > 0 {
+			require.Contains(t, coll.Validate(v).Error(), c.expError)
+		} else {
+			require.NoError(t, coll.Validate(v))
+		}
+	}
+}
+
 func TestCollection_Object(t *testing.T) {
 	reqSchema := []byte(`{
 		"title": "t1",
@@ -396,7 +745,7 @@ func TestCollection_Object(t *testing.T) {
 		},
 	}
 	for _, c := range cases {
-		schFactory, err := Build("t1", reqSchema)
+		schFactory, err := Build("t1", reqSchema, false)
 		require.NoError(t, err)
 		coll := NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
 
@@ -454,7 +803,7 @@ func TestCollection_Int64(t *testing.T) {
 		"primary_key": ["id"]
 	}`)
 
-	schFactory, err := Build("t1", reqSchema)
+	schFactory, err := Build("t1", reqSchema, false)
 	require.NoError(t, err)
 	coll := NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
 	require.Equal(t, 4, len(coll.Int64FieldsPath))
@@ -467,3 +816,471 @@ func TestCollection_Int64(t *testing.T) {
 	_, ok = coll.Int64FieldsPath["array_simple_items"]
 	require.True(t, ok)
 }
+
+func TestCollection_SearchHiddenFields(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"score": { "type": "integer", "searchReturn": false },
+			"name": { "type": "string", "searchReturn": true },
+			"nested_object": {
+				"type": "object",
+				"properties": {
+					"name": { "type": "string" },
+					"secret": { "type": "string", "searchReturn": false }
+				}
+			},
+			"scoring": {
+				"type": "object",
+				"searchReturn": false,
+				"properties": {
+					"rank": { "type": "integer" }
+				}
+			}
+		},
+		"primary_key": ["id"]
+	}`)
+
+	schFactory, err := Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	coll := NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
+	require.ElementsMatch(t, []string{"score", "nested_object.secret", "scoring"}, coll.SearchHiddenFields)
+
+	// hidden fields are still indexed
+	for _, name := range []string{"score", "nested_object.secret", "scoring.rank"} {
+		f, err := coll.GetQueryableField(name)
+		require.NoError(t, err)
+		require.True(t, f.Indexed)
+	}
+}
+
+func TestCollection_ImmutableFields(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"tenant_id": { "type": "string", "x-tigris-immutable": true },
+			"name": { "type": "string", "x-tigris-immutable": false },
+			"nested_object": {
+				"type": "object",
+				"properties": {
+					"name": { "type": "string" },
+					"created": { "type": "string", "format": "date-time", "x-tigris-immutable": true }
+				}
+			},
+			"origin": {
+				"type": "object",
+				"x-tigris-immutable": true,
+				"properties": {
+					"source": { "type": "string" }
+				}
+			},
+			"tags": { "type": "array", "items": { "type": "string" }, "x-tigris-immutable": true }
+		},
+		"primary_key": ["id"]
+	}`)
+
+	schFactory, err := Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	coll := NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
+	require.ElementsMatch(t, []string{"tenant_id", "nested_object.created", "origin", "tags"}, coll.ImmutableFields)
+
+	for path, immutable := range map[string]string{
+		"tenant_id":             "tenant_id",
+		"nested_object.created": "nested_object.created",
+		"nested_object":         "nested_object.created",
+		"origin":                "origin",
+		"origin.source":         "origin",
+		"tags":                  "tags",
+	} {
+		f, ok := coll.GetImmutableField(path)
+		require.True(t, ok, path)
+		require.Equal(t, immutable, f)
+	}
+	for _, path := range []string{"id", "name", "nested_object.name", "tenant", "tenant_id_2", "originals"} {
+		_, ok := coll.GetImmutableField(path)
+		require.False(t, ok, path)
+	}
+
+	// the items of an array can't be immutable
+	for _, items := range []string{
+		`{ "type": "string", "x-tigris-immutable": true }`,
+		`{ "type": "object", "properties": { "a": { "type": "string", "x-tigris-immutable": true } } }`,
+	} {
+		_, err = Build("t1", []byte(fmt.Sprintf(`{
+			"title": "t1",
+			"properties": { "id": { "type": "integer" }, "arr": { "type": "array", "items": %s } },
+			"primary_key": ["id"]
+		}`, items)), false)
+		require.Error(t, err, items)
+	}
+}
+
+func TestCollection_StrictDateTime(t *testing.T) {
+	defer func() { StrictDateTime = false }()
+
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"ts": { "type": "string", "format": "date-time" }
+		},
+		"primary_key": ["id"]
+	}`)
+	schFactory, err := Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	coll := NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
+
+	validate := func(ts string) error {
+		return coll.Validate(map[string]interface{}{"id": json.Number("1"), "ts": ts})
+	}
+
+	cases := []struct {
+		ts      string
+		lenient bool
+		strict  bool
+	}{
+		{ts: "2015-12-21T17:42:34Z", lenient: true, strict: true},
+		{ts: "2015-12-21T17:42:34.123+05:30", lenient: true, strict: true},
+		{ts: "2015-12-21T17:42:34-08:00", lenient: true, strict: true},
+		{ts: "2015-12-21T17:42:34-00:00", lenient: true, strict: false},
+		{ts: "2015-12-21t17:42:34z", lenient: true, strict: false},
+		{ts: "2015-12-21T17:42:34", lenient: false, strict: false},
+		{ts: "2015-12-21T17:42:34.123", lenient: false, strict: false},
+	}
+	for _, c := range cases {
+		for _, strict := range []bool{false, true} {
+			StrictDateTime = strict
+			err := validate(c.ts)
+			if valid := (!strict && c.lenient) || (strict && c.strict); valid {
+				require.NoError(t, err, "%s strict %v", c.ts, strict)
+				continue
+			}
+			require.Error(t, err, "%s strict %v", c.ts, strict)
+			require.Equal(t, fmt.Sprintf("json schema validation failed for field 'ts' reason ''%s' is not valid 'date-time''", c.ts), err.Error())
+		}
+	}
+}
+
+func TestCollection_ErrorVerbosity(t *testing.T) {
+	defer func() { ValidationErrorVerbosity = TerseErrors }()
+
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"obj": {
+				"type": "object",
+				"properties": {
+					"name": { "type": "string", "maxLength": 3 },
+					"tags": { "type": "array", "items": { "type": "string" } }
+				}
+			}
+		},
+		"primary_key": ["id"]
+	}`)
+	schFactory, err := Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	coll := NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
+
+	cases := []struct {
+		document map[string]interface{}
+		terse    string
+		verbose  string
+	}{
+		{
+			map[string]interface{}{"id": json.Number("1"), "obj": map[string]interface{}{"name": "abcd"}},
+			"json schema validation failed for field 'obj/name' reason 'length must be <= 3, but got 4'",
+			"json schema validation failed for field 'obj/name' reason 'length must be <= 3, but got 4' schema path '/properties/obj/properties/name/maxLength' constraint 'maxLength: 3'",
+		}, {
+			map[string]interface{}{"id": json.Number("1"), "obj": map[string]interface{}{"tags": []interface{}{json.Number("1")}}},
+			"json schema validation failed for field 'obj/tags/0' reason 'expected string, but got number'",
+			"json schema validation failed for field 'obj/tags/0' reason 'expected string, but got number' schema path '/properties/obj/properties/tags/items/type' constraint 'type: \"string\"'",
+		}, {
+			map[string]interface{}{"id": json.Number("1"), "obj": map[string]interface{}{"other": "a"}},
+			"json schema validation failed for field 'obj' reason 'additionalProperties 'other' not allowed'",
+			"json schema validation failed for field 'obj' reason 'additionalProperties 'other' not allowed' schema path '/properties/obj/additionalProperties' constraint 'additionalProperties'",
+		},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.terse, coll.Validate(c.document).Error())
+		require.Equal(t, c.terse, coll.ValidateWithVerbosity(c.document, TerseErrors).Error())
+		require.Equal(t, c.verbose, coll.ValidateWithVerbosity(c.document, VerboseErrors).Error())
+
+		ValidationErrorVerbosity = VerboseErrors
+		require.Equal(t, c.verbose, coll.Validate(c.document).Error())
+		ValidationErrorVerbosity = TerseErrors
+	}
+
+	for name, exp := range map[string]ErrorVerbosity{"": TerseErrors, "terse": TerseErrors, "verbose": VerboseErrors} {
+		verbosity, err := ParseErrorVerbosity(name)
+		require.NoError(t, err)
+		require.Equal(t, exp, verbosity)
+	}
+	_, err = ParseErrorVerbosity("debug")
+	require.Equal(t, errors.InvalidArgument("unknown validation error verbosity 'debug'"), err)
+}
+
+func TestCollection_MultipleOf(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer", "multipleOf": 2 },
+			"price": { "type": "number", "multipleOf": 0.01 },
+			"amounts": { "type": "array", "items": { "type": "number", "multipleOf": 0.1 } }
+		},
+		"primary_key": ["id"]
+	}`)
+	schFactory, err := Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	coll := NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
+	require.Equal(t, big.NewRat(1, 100), coll.GetField("price").MultipleOf)
+
+	// the values that are not exact as floats, like 0.07 or 19.99, are multiples of 0.01
+	for _, price := range []interface{}{json.Number("0.07"), json.Number("19.99"), json.Number("1e2"), json.Number("-3.3"), 0.07, 0.3} {
+		require.NoError(t, coll.Validate(map[string]interface{}{"id": json.Number("4"), "price": price}), price)
+	}
+	require.NoError(t, coll.Validate(map[string]interface{}{
+		"id": json.Number("2"), "amounts": []interface{}{json.Number("0.3"), json.Number("1.1")},
+	}))
+
+	cases := []struct {
+		document map[string]interface{}
+		expError string
+	}{
+		{
+			map[string]interface{}{"id": json.Number("2"), "price": json.Number("0.001")},
+			"json schema validation failed for field 'price' reason 'not a multiple of 0.01'",
+		}, {
+			map[string]interface{}{"id": json.Number("2"), "price": json.Number("10.015")},
+			"json schema validation failed for field 'price' reason 'not a multiple of 0.01'",
+		}, {
+			map[string]interface{}{"id": json.Number("3")},
+			"json schema validation failed for field 'id' reason 'not a multiple of 2'",
+		}, {
+			map[string]interface{}{"id": json.Number("2"), "amounts": []interface{}{json.Number("0.3"), json.Number("0.35")}},
+			"json schema validation failed for field 'amounts/1' reason 'not a multiple of 0.1'",
+		},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.expError, coll.Validate(c.document).Error())
+	}
+	require.Equal(t, "json schema validation failed for field 'price' reason 'not a multiple of 0.01' schema path '/properties/price/multipleOf' constraint 'multipleOf: 0.01'",
+		coll.ValidateWithVerbosity(map[string]interface{}{"id": json.Number("2"), "price": json.Number("0.001")}, VerboseErrors).Error())
+
+	t.Run("build", func(t *testing.T) {
+		for prop, expError := range map[string]string{
+			`"type": "string", "multipleOf": 2`:  "multipleOf is only supported by the number and the integer fields, field 'a'",
+			`"type": "number", "multipleOf": 0`:  "multipleOf of the field 'a' must be a number greater than 0",
+			`"type": "number", "multipleOf": -1`: "multipleOf of the field 'a' must be a number greater than 0",
+		} {
+			_, err := Build("t1", []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}, "a": {`+prop+`}}, "primary_key": ["id"]}`), false)
+			require.EqualError(t, err, expError, prop)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		update := func(multipleOf string) error {
+			f, err := Build("t1", []byte(`{"title": "t1", "properties": {"id": {"type": "integer", "multipleOf": 2}, "price": {"type": "number", "multipleOf": `+multipleOf+`}, "amounts": {"type": "array", "items": {"type": "number"}}}, "primary_key": ["id"]}`), false)
+			require.NoError(t, err)
+			return ApplySchemaRules(coll, f)
+		}
+		require.NoError(t, update("0.01"))
+		require.NoError(t, update("0.005"))
+		require.EqualError(t, update("0.02"), `changing multipleOf of an existing field to a value that doesn't divide it is not allowed "price"`)
+	})
+}
+
+func TestCollection_Contains(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"tags": {
+				"type": "array",
+				"items": { "type": "object", "properties": { "kind": { "type": "string" }, "name": { "type": "string" } } },
+				"contains": { "properties": { "kind": { "const": "featured" } }, "required": ["kind"] }
+			},
+			"scores": { "type": "array", "items": { "type": "integer" }, "contains": { "minimum": 90 }, "minContains": 2, "maxContains": 3 }
+		},
+		"primary_key": ["id"]
+	}`)
+	schFactory, err := Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	coll := NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
+
+	featured := map[string]interface{}{"kind": "featured", "name": "a"}
+	other := map[string]interface{}{"kind": "other", "name": "b"}
+	for _, doc := range []map[string]interface{}{
+		{"id": 1},
+		{"id": 1, "tags": []interface{}{other, featured}},
+		{"id": 1, "scores": []interface{}{90, 10, 95}},
+		{"id": 1, "scores": []interface{}{90, 91, 92}},
+	} {
+		require.NoError(t, coll.Validate(doc), doc)
+	}
+
+	cases := []struct {
+		document map[string]interface{}
+		expError string
+	}{
+		{
+			map[string]interface{}{"id": 1, "tags": []interface{}{other}},
+			"json schema validation failed for field 'tags' reason 'no items match contains'",
+		}, {
+			map[string]interface{}{"id": 1, "tags": []interface{}{}},
+			"json schema validation failed for field 'tags' reason 'no items match contains'",
+		}, {
+			map[string]interface{}{"id": 1, "scores": []interface{}{90, 10}},
+			"json schema validation failed for field 'scores' reason '1 items match contains, expected at least 2'",
+		}, {
+			map[string]interface{}{"id": 1, "scores": []interface{}{90, 91, 92, 93}},
+			"json schema validation failed for field 'scores' reason '4 items match contains, expected at most 3'",
+		},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.expError, coll.Validate(c.document).Error())
+		// the partial documents of the updates are also validated
+		require.Equal(t, c.expError, coll.ValidatePartial(c.document).Error())
+	}
+
+	t.Run("build", func(t *testing.T) {
+		for prop, expError := range map[string]string{
+			`"type": "string", "contains": {"const": "a"}`:                                                     "contains is only supported by the array fields, field 'a'",
+			`"type": "array", "items": {"type": "string"}, "minContains": 1`:                                   "minContains and maxContains of the field 'a' require contains",
+			`"type": "array", "items": {"type": "string"}, "contains": {}, "maxContains": -1`:                  "minContains and maxContains of the field 'a' can't be negative",
+			`"type": "array", "items": {"type": "string"}, "contains": {}, "minContains": 3, "maxContains": 2`: "minContains of the field 'a' is greater than its maxContains",
+			`"type": "array", "items": {"type": "string"}, "contains": {"type": 5}`:                            "contains of the field 'a' is not a valid schema",
+		} {
+			_, err := Build("t1", []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}, "a": {`+prop+`}}, "primary_key": ["id"]}`), false)
+			require.EqualError(t, err, expError, prop)
+		}
+	})
+}
+
+func TestCollection_ValidatePartial(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"name": { "type": "string", "maxLength": 5 },
+			"address": {
+				"type": "object",
+				"properties": {
+					"city": { "type": "string" },
+					"zip": { "type": "string" }
+				}
+			}
+		},
+		"required": ["id", "name"],
+		"primary_key": ["id"]
+	}`)
+	schFactory, err := Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	coll := NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
+
+	decode := func(doc string) interface{} {
+		dec := jsoniter.NewDecoder(strings.NewReader(doc))
+		dec.UseNumber()
+		var v interface{}
+		require.NoError(t, dec.Decode(&v))
+		return v
+	}
+
+	// the required fields are only checked in the full mode
+	for _, doc := range []string{`{"name": "alice"}`, `{"address": {"zip": "94016"}}`, `{}`} {
+		require.NoError(t, coll.ValidatePartial(decode(doc)), doc)
+		require.Error(t, coll.Validate(decode(doc)), doc)
+	}
+	require.NoError(t, coll.Validate(decode(`{"id": 1, "name": "alice", "address": {"city": "SF", "zip": "94016"}}`)))
+
+	// the fields that are present are validated
+	require.Equal(t, "json schema validation failed for field 'name' reason 'length must be <= 5, but got 7'",
+		coll.ValidatePartial(decode(`{"name": "charlie"}`)).Error())
+	require.Equal(t, "json schema validation failed for field 'address/city' reason 'expected string, but got number'",
+		coll.ValidatePartial(decode(`{"address": {"city": 1}}`)).Error())
+	require.Equal(t, "json schema validation failed for field 'address' reason 'additionalProperties 'state' not allowed'",
+		coll.ValidatePartial(decode(`{"address": {"state": "CA"}}`)).Error())
+
+	// the validator of the full mode is left as it is
+	require.Error(t, coll.Validate(decode(`{"name": "alice"}`)))
+}
+
+func TestCollection_ValidateAt(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"simple_object": {
+				"type": "object",
+				"properties": {
+					"name": { "type": "string" },
+					"details": {
+						"type": "object",
+						"properties": {
+							"nested_id": { "type": "integer" },
+							"nested_obj": {
+								"type": "object",
+								"properties": {
+									"name": { "type": "string", "maxLength": 3 }
+								}
+							}
+						}
+					}
+				}
+			},
+			"orders": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"properties": { "sku": { "type": "string" } }
+				}
+			}
+		},
+		"primary_key": ["id"]
+	}`)
+	schFactory, err := Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	coll := NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
+
+	decode := func(doc string) interface{} {
+		dec := jsoniter.NewDecoder(strings.NewReader(doc))
+		dec.UseNumber()
+		var v interface{}
+		require.NoError(t, dec.Decode(&v))
+		return v
+	}
+
+	// the subtree is validated on its own
+	require.NoError(t, coll.ValidateAt("simple_object.details", decode(`{"nested_id": 1, "nested_obj": {"name": "abc"}}`)))
+	require.NoError(t, coll.ValidateAt("simple_object.details.nested_obj", decode(`{"name": "abc"}`)))
+	require.NoError(t, coll.ValidateAt("orders", decode(`[{"sku": "a"}, {"sku": "b"}]`)))
+	require.NoError(t, coll.ValidateAt("orders.1", decode(`{"sku": "b"}`)))
+
+	// the errors have the paths of the fields in the document
+	require.Equal(t, NewValidationErrors(Violation{"simple_object/details/nested_obj/name", "length must be <= 3, but got 4", "maxLength: 3"}),
+		coll.ValidateAt("simple_object.details", decode(`{"nested_id": 1, "nested_obj": {"name": "abcd"}}`)))
+	require.Equal(t, NewValidationErrors(Violation{"simple_object/details", "additionalProperties 'other' not allowed", "additionalProperties"}),
+		coll.ValidateAt("simple_object.details", decode(`{"nested_id": 1, "other": 1}`)))
+	require.Equal(t, NewValidationErrors(Violation{"orders/1/sku", "expected string, but got number", `type: "string"`}),
+		coll.ValidateAt("orders", decode(`[{"sku": "a"}, {"sku": 2}]`)))
+	require.Equal(t, NewValidationErrors(Violation{"orders/0", "expected object, but got string", `type: "object"`}),
+		coll.ValidateAt("orders.0", decode(`"a"`)))
+
+	ValidationErrorVerbosity = VerboseErrors
+	err = coll.ValidateAt("simple_object.details.nested_obj", decode(`{"name": "abcd"}`))
+	ValidationErrorVerbosity = TerseErrors
+	require.Equal(t, "json schema validation failed for field 'simple_object/details/nested_obj/name' reason 'length must be <= 3, "+
+		"but got 4' schema path '/properties/simple_object/properties/details/properties/nested_obj/properties/name/maxLength' "+
+		"constraint 'maxLength: 3'", err.Error())
+
+	// the path must be an object or an array of the schema
+	require.Equal(t, errors.InvalidArgument("field 'simple_object.name' is not an object or an array"),
+		coll.ValidateAt("simple_object.name", "alice"))
+	require.Equal(t, errors.InvalidArgument("field 'simple_object.missing' is not present in the collection"),
+		coll.ValidateAt("simple_object.missing", decode(`{}`)))
+	require.Equal(t, errors.InvalidArgument("field 'orders.first' is not present in the collection"),
+		coll.ValidateAt("orders.first", decode(`{}`)))
+	require.Equal(t, errors.InvalidArgument("path of the field to validate is empty"), coll.ValidateAt("", decode(`{}`)))
+}
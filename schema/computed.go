@@ -0,0 +1,204 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tigrisdata/tigris/errors"
+)
+
+// ComputeHook derives the value of a computed field from the document. It returns nil if the value can't be derived,
+// the field is then left unset.
+type ComputeHook func(document map[string]interface{}) (interface{}, error)
+
+// computeHooks is the registry of the hooks the fields annotated with "x-tigris-computed" can reference.
+var computeHooks = struct {
+	sync.RWMutex
+
+	byName map[string]ComputeHook
+}{byName: make(map[string]ComputeHook)}
+
+// RegisterComputeHook adds a hook to the registry. A field annotated with "x-tigris-computed": "<name>" is then
+// populated by the hook when the documents are normalized. The hook must be registered before the collections using
+// it are built.
+func RegisterComputeHook(name string, hook ComputeHook) error {
+	if len(name) == 0 {
+		return errors.InvalidArgument("compute hook name is empty")
+	}
+	if hook == nil {
+		return errors.InvalidArgument("compute hook '%s' has no function", name)
+	}
+
+	computeHooks.Lock()
+	defer computeHooks.Unlock()
+	if _, ok := computeHooks.byName[name]; ok {
+		return errors.AlreadyExists("compute hook '%s' is already registered", name)
+	}
+	computeHooks.byName[name] = hook
+
+	return nil
+}
+
+// RegisteredComputeHooks returns the names of the registered compute hooks, sorted by name.
+func RegisteredComputeHooks() []string {
+	computeHooks.RLock()
+	defer computeHooks.RUnlock()
+
+	names := make([]string, 0, len(computeHooks.byName))
+	for name := range computeHooks.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func getComputeHook(name string) (ComputeHook, bool) {
+	computeHooks.RLock()
+	defer computeHooks.RUnlock()
+
+	hook, ok := computeHooks.byName[name]
+	return hook, ok
+}
+
+// ComputedField is a field annotated with "x-tigris-computed", its value is derived by the hook and can't be set by
+// the clients.
+type ComputedField struct {
+	// Path is the dotted path of the field.
+	Path string
+	// Hook is the name of the hook deriving the value.
+	Hook string
+}
+
+func (d *DefaultCollection) setComputedFields(parent string, fields []*Field) {
+	for _, f := range fields {
+		if f.IsComputed() {
+			d.ComputedFields = append(d.ComputedFields, ComputedField{Path: buildPath(parent, f.FieldName), Hook: f.Computed})
+			continue
+		}
+		if f.DataType == ObjectType {
+			d.setComputedFields(buildPath(parent, f.FieldName), f.Fields)
+		}
+	}
+}
+
+// rejectComputedValues returns an error if the document sets a computed field, these fields are only populated by
+// their hooks.
+func (d *DefaultCollection) rejectComputedValues(document interface{}) error {
+	doc, ok := document.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	for _, computed := range d.ComputedFields {
+		if _, ok := lookupPath(doc, strings.Split(computed.Path, ".")); ok {
+			return errors.InvalidArgument("field '%s' is computed and can't be set", computed.Path)
+		}
+	}
+	return nil
+}
+
+// RemoveComputedValues removes the computed fields set in the document, like in the documents exported from the
+// collection, so that they are populated again by their hooks. It returns true if a field is removed.
+func (d *DefaultCollection) RemoveComputedValues(document map[string]interface{}) bool {
+	removed := false
+	for _, computed := range d.ComputedFields {
+		if deletePath(document, strings.Split(computed.Path, ".")) {
+			removed = true
+		}
+	}
+	return removed
+}
+
+// Compute populates the computed fields of the document with the values derived by their hooks, the values set in the
+// document are replaced. The document is then validated against the schema, so that a derived value of the wrong type
+// is rejected. It returns true if a field is populated.
+func (d *DefaultCollection) Compute(document map[string]interface{}) (bool, error) {
+	if len(d.ComputedFields) == 0 {
+		return false, nil
+	}
+
+	computed := false
+	for _, field := range d.ComputedFields {
+		hook, ok := getComputeHook(field.Hook)
+		if !ok {
+			return false, errors.Internal("compute hook '%s' of the field '%s' is not registered", field.Hook, field.Path)
+		}
+
+		value, err := hook(document)
+		if err != nil {
+			return false, errors.InvalidArgument("failed to compute the field '%s': %s", field.Path, err.Error())
+		}
+		if value == nil {
+			continue
+		}
+		if setPath(document, strings.Split(field.Path, "."), value) {
+			computed = true
+		}
+	}
+
+	if err := d.validateSchema(document, ValidationErrorVerbosity); err != nil {
+		return false, err
+	}
+	return computed, nil
+}
+
+func lookupPath(doc map[string]interface{}, keys []string) (interface{}, bool) {
+	value, ok := doc[keys[0]]
+	if !ok || len(keys) == 1 {
+		return value, ok
+	}
+	nested, isObject := value.(map[string]interface{})
+	if !isObject {
+		return nil, false
+	}
+	return lookupPath(nested, keys[1:])
+}
+
+// deletePath removes the field, it returns false if the field is not set.
+func deletePath(doc map[string]interface{}, keys []string) bool {
+	if len(keys) == 1 {
+		_, ok := doc[keys[0]]
+		delete(doc, keys[0])
+		return ok
+	}
+
+	nested, ok := doc[keys[0]].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	return deletePath(nested, keys[1:])
+}
+
+// setPath sets the value of the field, the missing parent objects are created. It returns false if a parent is not an
+// object.
+func setPath(doc map[string]interface{}, keys []string, value interface{}) bool {
+	if len(keys) == 1 {
+		doc[keys[0]] = value
+		return true
+	}
+
+	nested, ok := doc[keys[0]].(map[string]interface{})
+	if !ok {
+		if doc[keys[0]] != nil {
+			return false
+		}
+		nested = make(map[string]interface{})
+		doc[keys[0]] = nested
+	}
+	return setPath(nested, keys[1:], value)
+}
@@ -0,0 +1,173 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+func TestComputedFields(t *testing.T) {
+	require.NoError(t, RegisterComputeHook("test-full-name", func(doc map[string]interface{}) (interface{}, error) {
+		first, okFirst := doc["first"].(string)
+		last, okLast := doc["last"].(string)
+		if !okFirst || !okLast {
+			return nil, nil
+		}
+		return first + " " + last, nil
+	}))
+	require.NoError(t, RegisterComputeHook("test-initials", func(doc map[string]interface{}) (interface{}, error) {
+		first, _ := doc["first"].(string)
+		if len(first) == 0 {
+			return nil, fmt.Errorf("first is empty")
+		}
+		return first[:1], nil
+	}))
+	require.NoError(t, RegisterComputeHook("test-number", func(map[string]interface{}) (interface{}, error) {
+		return json.Number("1"), nil
+	}))
+
+	t.Run("invalid registrations", func(t *testing.T) {
+		require.Contains(t, RegisteredComputeHooks(), "test-full-name")
+		require.Equal(t, errors.AlreadyExists("compute hook 'test-full-name' is already registered"),
+			RegisterComputeHook("test-full-name", func(map[string]interface{}) (interface{}, error) { return nil, nil }))
+		require.Equal(t, errors.InvalidArgument("compute hook name is empty"),
+			RegisterComputeHook("", func(map[string]interface{}) (interface{}, error) { return nil, nil }))
+		require.Equal(t, errors.InvalidArgument("compute hook 'test-nil' has no function"), RegisterComputeHook("test-nil", nil))
+	})
+
+	reqSchema := []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": { "type": "integer" },
+		"first": { "type": "string" },
+		"last": { "type": "string" },
+		"full_name": { "type": "string", "x-tigris-computed": "test-full-name" },
+		"meta": {
+			"type": "object",
+			"properties": {
+				"initials": { "type": "string", "x-tigris-computed": "test-initials" }
+			}
+		}
+	},
+	"primary_key": ["id"]
+}`)
+	schFactory, err := Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	coll := NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
+	require.Equal(t, []ComputedField{
+		{Path: "full_name", Hook: "test-full-name"},
+		{Path: "meta.initials", Hook: "test-initials"},
+	}, coll.ComputedFields)
+
+	t.Run("rejection", func(t *testing.T) {
+		require.Equal(t, errors.InvalidArgument("field 'full_name' is computed and can't be set"),
+			coll.Validate(map[string]interface{}{"id": json.Number("1"), "full_name": "a b"}))
+		require.Equal(t, errors.InvalidArgument("field 'meta.initials' is computed and can't be set"),
+			coll.Validate(map[string]interface{}{"id": json.Number("1"), "meta": map[string]interface{}{"initials": "a"}}))
+		require.NoError(t, coll.Validate(map[string]interface{}{"id": json.Number("1"), "first": "a", "meta": map[string]interface{}{}}))
+	})
+
+	t.Run("removal", func(t *testing.T) {
+		doc := map[string]interface{}{"id": json.Number("1"), "full_name": "a b", "meta": map[string]interface{}{"initials": "a"}}
+		require.True(t, coll.RemoveComputedValues(doc))
+		require.Equal(t, map[string]interface{}{"id": json.Number("1"), "meta": map[string]interface{}{}}, doc)
+		require.NoError(t, coll.Validate(doc))
+
+		require.False(t, coll.RemoveComputedValues(map[string]interface{}{"id": json.Number("1"), "meta": "a"}))
+	})
+
+	t.Run("derivation", func(t *testing.T) {
+		doc := map[string]interface{}{"id": json.Number("1"), "first": "John", "last": "Doe"}
+		computed, err := coll.Compute(doc)
+		require.NoError(t, err)
+		require.True(t, computed)
+		require.Equal(t, map[string]interface{}{
+			"id":        json.Number("1"),
+			"first":     "John",
+			"last":      "Doe",
+			"full_name": "John Doe",
+			"meta":      map[string]interface{}{"initials": "J"},
+		}, doc)
+
+		// the derived values replace the previous ones
+		doc["first"] = "Jane"
+		_, err = coll.Compute(doc)
+		require.NoError(t, err)
+		require.Equal(t, "Jane Doe", doc["full_name"])
+		require.Equal(t, map[string]interface{}{"initials": "J"}, doc["meta"])
+
+		// a field that can't be derived is left unset
+		doc = map[string]interface{}{"id": json.Number("1"), "first": "John"}
+		_, err = coll.Compute(doc)
+		require.NoError(t, err)
+		require.NotContains(t, doc, "full_name")
+
+		_, err = coll.Compute(map[string]interface{}{"id": json.Number("1")})
+		require.Equal(t, errors.InvalidArgument("failed to compute the field 'meta.initials': first is empty"), err)
+	})
+
+	t.Run("derived value is validated", func(t *testing.T) {
+		schFactory, err := Build("t2", []byte(`{
+	"title": "t2",
+	"properties": {
+		"id": { "type": "integer" },
+		"name": { "type": "string", "x-tigris-computed": "test-number" }
+	},
+	"primary_key": ["id"]
+}`), false)
+		require.NoError(t, err)
+		coll := NewDefaultCollection("t2", 1, 1, schFactory.CollectionType, schFactory, "t2", nil)
+
+		_, err = coll.Compute(map[string]interface{}{"id": json.Number("1")})
+		require.Equal(t, "json schema validation failed for field 'name' reason 'expected string, but got number'", err.Error())
+	})
+
+	t.Run("invalid schemas", func(t *testing.T) {
+		for _, c := range []struct {
+			properties string
+			err        error
+		}{
+			{
+				`"name": { "type": "string", "x-tigris-computed": "test-unknown" }`,
+				errors.InvalidArgument("unknown compute hook 'test-unknown' of the field 'name'"),
+			}, {
+				`"names": { "type": "array", "items": { "type": "string", "x-tigris-computed": "test-full-name" } }`,
+				errors.InvalidArgument("the items of an array can't be computed"),
+			}, {
+				`"names": { "type": "array", "items": { "type": "object", "properties": { "name": { "type": "string", "x-tigris-computed": "test-full-name" } } } }`,
+				errors.InvalidArgument("the items of an array can't be computed, array field 'names'"),
+			},
+		} {
+			_, err := Build("t1", []byte(fmt.Sprintf(`{
+	"title": "t1",
+	"properties": { "id": { "type": "integer" }, %s },
+	"primary_key": ["id"]
+}`, c.properties)), false)
+			require.Equal(t, c.err, err, c.properties)
+		}
+
+		_, err := Build("t1", []byte(`{
+	"title": "t1",
+	"properties": { "id": { "type": "string", "x-tigris-computed": "test-full-name" } },
+	"primary_key": ["id"]
+}`), false)
+		require.Equal(t, errors.InvalidArgument("primary key field 'id' can't be computed"), err)
+	})
+}
@@ -0,0 +1,133 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"bytes"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+// DefaultField is a field annotated with "default", the value is set in the documents inserted without the field.
+type DefaultField struct {
+	// Path is the dotted path of the field.
+	Path string
+	// Value is the JSON of the default value.
+	Value jsoniter.RawMessage
+}
+
+// FieldAlias is an alternate name of a field annotated with "x-tigris-aliases".
+type FieldAlias struct {
+	// Parent is the dotted path of the object the field belongs to, it is empty for the top level fields.
+	Parent string
+	// Alias is the alternate name of the field.
+	Alias string
+	// Name is the name of the field.
+	Name string
+}
+
+func (d *DefaultCollection) setDefaultFields(parent string, fields []*Field) {
+	for _, f := range fields {
+		path := buildPath(parent, f.FieldName)
+		if f.Default != nil {
+			d.DefaultFields = append(d.DefaultFields, DefaultField{Path: path, Value: f.Default})
+		}
+		if f.DataType == ObjectType {
+			d.setDefaultFields(path, f.Fields)
+		}
+	}
+}
+
+func (d *DefaultCollection) setFieldAliases(parent string, fields []*Field) {
+	for _, f := range fields {
+		for _, alias := range f.Aliases {
+			d.FieldAliases = append(d.FieldAliases, FieldAlias{Parent: parent, Alias: alias, Name: f.FieldName})
+		}
+		if f.DataType == ObjectType {
+			d.setFieldAliases(buildPath(parent, f.FieldName), f.Fields)
+		}
+	}
+}
+
+// ResolveAliases renames the fields of the document set with one of their aliases to the name of the field. A field
+// can't be set along with one of its aliases. It returns true if a field is renamed.
+func (d *DefaultCollection) ResolveAliases(document map[string]interface{}) (bool, error) {
+	resolved := false
+	for _, a := range d.FieldAliases {
+		parent := document
+		if len(a.Parent) > 0 {
+			nested, ok := lookupPath(document, strings.Split(a.Parent, "."))
+			if parent, ok = nested.(map[string]interface{}); !ok {
+				continue
+			}
+		}
+
+		value, ok := parent[a.Alias]
+		if !ok {
+			continue
+		}
+		if _, ok = parent[a.Name]; ok {
+			return false, errors.InvalidArgument("field '%s' is set along with its alias '%s'",
+				buildPath(a.Parent, a.Name), buildPath(a.Parent, a.Alias))
+		}
+		parent[a.Name] = value
+		delete(parent, a.Alias)
+		resolved = true
+	}
+	return resolved, nil
+}
+
+// ApplyDefaults sets the default value of the fields missing in the document, the fields set to null are left as is.
+// The defaults of the nested fields are only set if their object is set. It returns true if a field is set.
+func (d *DefaultCollection) ApplyDefaults(document map[string]interface{}) (bool, error) {
+	applied := false
+	for _, f := range d.DefaultFields {
+		keys := strings.Split(f.Path, ".")
+		parent := document
+		if len(keys) > 1 {
+			nested, _ := lookupPath(document, keys[:len(keys)-1])
+			var ok bool
+			if parent, ok = nested.(map[string]interface{}); !ok {
+				continue
+			}
+		}
+		if _, ok := parent[keys[len(keys)-1]]; ok {
+			continue
+		}
+
+		// decoded for every document, so that the documents don't share the objects and the arrays of the defaults
+		value, err := decodeDefault(f.Value)
+		if err != nil {
+			return false, errors.Internal("failed to decode the default of the field '%s'", f.Path)
+		}
+		parent[keys[len(keys)-1]] = value
+		applied = true
+	}
+	return applied, nil
+}
+
+// decodeDefault decodes the default value like the values of the documents, the numbers are kept as json.Number.
+func decodeDefault(value jsoniter.RawMessage) (interface{}, error) {
+	var decoded interface{}
+
+	decoder := jsoniter.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+func TestDefaultsAndAliases(t *testing.T) {
+	build := func(properties string) (*DefaultCollection, error) {
+		factory, err := Build("t1", []byte(`{
+	"title": "t1",
+	"properties": { "id": { "type": "integer" }, `+properties+` },
+	"primary_key": ["id"]
+}`), false)
+		if err != nil {
+			return nil, err
+		}
+		return NewDefaultCollection("t1", 1, 1, factory.CollectionType, factory, "t1", nil), nil
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		for properties, expected := range map[string]string{
+			`"a": { "type": "integer", "default": "x" }`:                                                                 "default of the field 'a' doesn't match the schema of the field",
+			`"a": { "type": "array", "items": { "type": "string", "default": "x" } }`:                                    "the items of an array can't have a default, set it on the array field instead",
+			`"a": { "type": "string", "x-tigris-aliases": ["a"] }`:                                                       "alias 'a' of the field 'a' is the name of the field",
+			`"a": { "type": "string", "x-tigris-aliases": ["1a"] }`:                                                      "alias '1a' of the field 'a' is not a valid field name",
+			`"a": { "type": "string", "x-tigris-aliases": ["b"] }, "b": { "type": "string" }`:                            "alias 'b' of the field 'a' is already used by the field 'b'",
+			`"a": { "type": "string", "x-tigris-aliases": ["c"] }, "b": { "type": "string", "x-tigris-aliases": ["c"] }`: "alias 'c' of the field 'b' is already used by the field 'a'",
+		} {
+			_, err := build(properties)
+			require.Equal(t, errors.InvalidArgument(expected), err, properties)
+		}
+
+		_, err := build(`"a": { "type": "array", "items": { "type": "object", "properties": { "b": { "type": "string", "default": "x" } } } }`)
+		require.Equal(t, errors.InvalidArgument("the items of an array can't have defaults or aliases, array field 'a'"), err)
+	})
+
+	coll, err := build(`
+		"count": { "type": "integer", "default": 0 },
+		"user": { "type": "object", "x-tigris-aliases": ["owner"], "properties": {
+			"name": { "type": "string", "x-tigris-aliases": ["username"] },
+			"roles": { "type": "array", "items": { "type": "string" }, "default": ["reader"] }
+		} }`)
+	require.NoError(t, err)
+	require.Equal(t, []DefaultField{
+		{Path: "count", Value: []byte(`0`)},
+		{Path: "user.roles", Value: []byte(`["reader"]`)},
+	}, coll.DefaultFields)
+	require.Equal(t, []FieldAlias{
+		{Alias: "owner", Name: "user"},
+		{Parent: "user", Alias: "username", Name: "name"},
+	}, coll.FieldAliases)
+
+	t.Run("resolve aliases", func(t *testing.T) {
+		doc := map[string]interface{}{"id": 1, "owner": map[string]interface{}{"username": "a"}}
+		resolved, err := coll.ResolveAliases(doc)
+		require.NoError(t, err)
+		require.True(t, resolved)
+		require.Equal(t, map[string]interface{}{"id": 1, "user": map[string]interface{}{"name": "a"}}, doc)
+
+		resolved, err = coll.ResolveAliases(doc)
+		require.NoError(t, err)
+		require.False(t, resolved)
+
+		_, err = coll.ResolveAliases(map[string]interface{}{"user": map[string]interface{}{"name": "a", "username": "b"}})
+		require.Equal(t, errors.InvalidArgument("field 'user.name' is set along with its alias 'user.username'"), err)
+	})
+
+	t.Run("apply defaults", func(t *testing.T) {
+		doc := map[string]interface{}{"id": 1}
+		applied, err := coll.ApplyDefaults(doc)
+		require.NoError(t, err)
+		require.True(t, applied)
+		require.Equal(t, map[string]interface{}{"id": 1, "count": json.Number("0")}, doc)
+
+		// the documents don't share the values of the defaults
+		first, second := map[string]interface{}{"user": map[string]interface{}{}}, map[string]interface{}{"user": map[string]interface{}{}}
+		_, err = coll.ApplyDefaults(first)
+		require.NoError(t, err)
+		_, err = coll.ApplyDefaults(second)
+		require.NoError(t, err)
+		first["user"].(map[string]interface{})["roles"].([]interface{})[0] = "writer"
+		require.Equal(t, []interface{}{"reader"}, second["user"].(map[string]interface{})["roles"])
+
+		// the fields set to null are kept
+		doc = map[string]interface{}{"count": nil, "user": map[string]interface{}{"roles": nil}}
+		applied, err = coll.ApplyDefaults(doc)
+		require.NoError(t, err)
+		require.False(t, applied)
+	})
+}
@@ -0,0 +1,127 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	goerrors "errors"
+	"fmt"
+	"strings"
+
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+// DefaultMaxViolations is the default maximum number of violations reported by a validation error.
+const DefaultMaxViolations = 100
+
+// MaxViolations is the maximum number of violations of a document collected by Validate, the validation stops once
+// it is reached. Zero doesn't limit the number of violations.
+var MaxViolations = DefaultMaxViolations
+
+// Violation is a field of a document failing the validation.
+type Violation struct {
+	// Field is the path of the field in the document, the levels are separated by "/" and the items of the arrays
+	// are referred to by their index.
+	Field  string
+	Reason string
+	// Constraint is the keyword of the schema violated by the field with its value, like `maxLength: 5`, it is empty
+	// if the field doesn't violate a keyword of the schema.
+	Constraint string
+}
+
+// ValidationError is returned by Validate when the fields of a document don't match the schema, it has all the
+// violations of the document up to MaxViolations.
+type ValidationError struct {
+	*api.TigrisError
+
+	// Field and Reason are the ones of the first violation.
+	Field      string
+	Reason     string
+	Violations []Violation
+}
+
+// NewValidationError returns the error of the field of a document failing the validation for the reason.
+func NewValidationError(field string, reason string) *ValidationError {
+	return newValidationError(field, reason, "")
+}
+
+// NewValidationErrors returns the error of the violations of a document.
+func NewValidationErrors(violations ...Violation) *ValidationError {
+	return newValidationErrors(violations, false, "")
+}
+
+// newValidationError returns the error of the field with the details of the failure appended to the message.
+func newValidationError(field string, reason string, details string) *ValidationError {
+	return newValidationErrors([]Violation{{Field: field, Reason: reason}}, false, details)
+}
+
+// newValidationErrors returns the error of the violations with the details of the first violation appended to the
+// message, the message has the number of the violations if there are several of them. The number is a lower bound
+// if the violations are truncated.
+func newValidationErrors(violations []Violation, truncated bool, details string) *ValidationError {
+	first := violations[0]
+
+	var tigrisErr *api.TigrisError
+	switch {
+	case len(violations) == 1 && !truncated:
+		tigrisErr = api.Errorf(api.Code_INVALID_ARGUMENT, "json schema validation failed for field '%s' reason '%s'%s",
+			first.Field, first.Reason, details)
+	case truncated:
+		tigrisErr = api.Errorf(api.Code_INVALID_ARGUMENT, "json schema validation failed with more than %d violations, "+
+			"first for field '%s' reason '%s'%s", len(violations), first.Field, first.Reason, details)
+	default:
+		tigrisErr = api.Errorf(api.Code_INVALID_ARGUMENT, "json schema validation failed with %d violations, "+
+			"first for field '%s' reason '%s'%s", len(violations), first.Field, first.Reason, details)
+	}
+
+	return &ValidationError{
+		TigrisError: tigrisErr,
+		Field:       first.Field,
+		Reason:      first.Reason,
+		Violations:  violations,
+	}
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.TigrisError
+}
+
+// ToAPIError returns the validation errors as API errors with the fields failing the validation attached as BadRequest
+// field violations with their constraints, so that the clients can map the error to the fields. The paths of the
+// fields use "." to separate the levels. The other errors are returned as they are.
+func ToAPIError(err error) error {
+	var (
+		violations    []Violation
+		validationErr *ValidationError
+		depthErr      *NestingDepthError
+	)
+	switch {
+	case goerrors.As(err, &validationErr):
+		violations = validationErr.Violations
+	case goerrors.As(err, &depthErr):
+		violations = []Violation{{
+			Field:  depthErr.Field,
+			Reason: fmt.Sprintf("document exceeds the maximum nesting depth of %d", depthErr.MaxDepth),
+		}}
+	default:
+		return err
+	}
+
+	apiErr := api.Errorf(api.Code_INVALID_ARGUMENT, "%s", err.Error()).WithSubsystem(errors.SubsystemValidation)
+	for _, v := range violations {
+		apiErr = apiErr.WithValidationViolation(strings.ReplaceAll(v.Field, "/", ObjFlattenDelimiter), v.Reason, v.Constraint)
+	}
+	return apiErr
+}
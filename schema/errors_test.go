@@ -0,0 +1,106 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+)
+
+func fieldViolations(err *api.TigrisError) []string {
+	var violations []string
+	for _, v := range err.FieldViolations() {
+		violations = append(violations, v.GetField()+": "+v.GetDescription())
+	}
+	return violations
+}
+
+func TestToAPIError(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"address": { "type": "object", "properties": { "city": { "type": "string", "maxLength": 5 } } }
+		},
+		"primary_key": ["id"]
+	}`)
+	schFactory, err := Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	coll := NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
+
+	validationErr := coll.Validate(map[string]interface{}{"id": 1, "address": map[string]interface{}{"city": "San Francisco"}})
+	require.Equal(t, &ValidationError{
+		TigrisError: api.Errorf(api.Code_INVALID_ARGUMENT,
+			"json schema validation failed for field 'address/city' reason 'length must be <= 5, but got 13'"),
+		Field:      "address/city",
+		Reason:     "length must be <= 5, but got 13",
+		Violations: []Violation{{Field: "address/city", Reason: "length must be <= 5, but got 13", Constraint: "maxLength: 5"}},
+	}, validationErr)
+
+	err = ToAPIError(validationErr)
+	tigrisErr, ok := err.(*api.TigrisError)
+	require.True(t, ok)
+	require.Equal(t, api.Code_INVALID_ARGUMENT, tigrisErr.Code)
+	require.Equal(t, validationErr.Error(), tigrisErr.Message)
+	expViolations := []string{"address.city: length must be <= 5, but got 13"}
+	require.Equal(t, expViolations, fieldViolations(tigrisErr))
+
+	// the field violations are in the details of the GRPC status and in the HTTP errors
+	require.Equal(t, expViolations, fieldViolations(api.FromStatusError(tigrisErr)))
+	body, err := api.MarshalStatus(tigrisErr.GRPCStatus().Proto())
+	require.NoError(t, err)
+	require.JSONEq(t, `{"error": {
+		"code": "INVALID_ARGUMENT",
+		"message": "json schema validation failed for field 'address/city' reason 'length must be <= 5, but got 13'",
+		"field_violations": [{"field": "address.city", "description": "length must be <= 5, but got 13", "constraint": "maxLength: 5"}],
+		"subsystem": "validation"
+	}}`, string(body))
+	require.Equal(t, expViolations, fieldViolations(api.UnmarshalStatus(body)))
+
+	// all the violations of the document are attached with their constraints, sorted by field
+	validationErr = coll.Validate(map[string]interface{}{"id": "1", "address": map[string]interface{}{"city": 1, "zip": 1}})
+	require.Equal(t, "json schema validation failed with 3 violations, first for field 'address' reason "+
+		"'additionalProperties 'zip' not allowed'", validationErr.Error())
+	expDetails := []api.ValidationViolation{
+		{FieldPath: "address", Reason: "additionalProperties 'zip' not allowed", Constraint: "additionalProperties"},
+		{FieldPath: "address.city", Reason: "expected string, but got number", Constraint: `type: "string"`},
+		{FieldPath: "id", Reason: "expected integer, but got string", Constraint: `type: "integer"`},
+	}
+	tigrisErr = ToAPIError(validationErr).(*api.TigrisError)
+	require.Equal(t, expDetails, tigrisErr.ValidationViolations())
+	require.Equal(t, expDetails, api.FromStatusError(tigrisErr).ValidationViolations())
+	body, err = api.MarshalStatus(tigrisErr.GRPCStatus().Proto())
+	require.NoError(t, err)
+	require.Equal(t, expDetails, api.UnmarshalStatus(body).ValidationViolations())
+
+	// the number of the violations is limited
+	defer func() { MaxViolations = DefaultMaxViolations }()
+	MaxViolations = 2
+	validationErr = coll.Validate(map[string]interface{}{"id": "1", "address": map[string]interface{}{"city": 1, "zip": 1}})
+	require.Equal(t, "json schema validation failed with more than 2 violations, first for field 'address' reason "+
+		"'additionalProperties 'zip' not allowed'", validationErr.Error())
+	require.Equal(t, expDetails[:2], ToAPIError(validationErr).(*api.TigrisError).ValidationViolations())
+
+	// the nesting depth errors have the first field above the limit
+	depthErr := ToAPIError(newNestingDepthError("a/b", 2)).(*api.TigrisError)
+	require.Equal(t, []string{"a.b: document exceeds the maximum nesting depth of 2"}, fieldViolations(depthErr))
+
+	// the other errors are returned as they are
+	other := fmt.Errorf("other")
+	require.Equal(t, other, ToAPIError(other))
+}
@@ -15,10 +15,14 @@
 package schema
 
 import (
+	"bytes"
+	"encoding/json"
+	"math/big"
 	"regexp"
 	"strings"
 
 	jsoniter "github.com/json-iterator/go"
+	"github.com/santhosh-tekuri/jsonschema/v5"
 	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/lib/container"
 	"github.com/tigrisdata/tigris/util"
@@ -85,6 +89,10 @@ const (
 	jsonSpecFormatByte     = "byte"
 	jsonSpecFormatInt32    = "int32"
 	jsonSpecFormatInt64    = "int64"
+	jsonSpecFormatEmail    = "email"
+	jsonSpecFormatURI      = "uri"
+	jsonSpecFormatDate     = "date"
+	jsonSpecFormatTime     = "time"
 )
 
 func ToFieldType(jsonType string, encoding string, format string) FieldType {
@@ -128,7 +136,18 @@ func ToFieldType(jsonType string, encoding string, format string) FieldType {
 			return DateTimeType
 		case jsonSpecFormatByte:
 			return ByteType
+		case jsonSpecFormatEmail, jsonSpecFormatURI, jsonSpecFormatTime:
+			// stored as a plain string, the format is enforced by the validator
+			return StringType
+		case jsonSpecFormatDate:
+			// unlike date-time, a full-date (YYYY-MM-DD) sorts and compares lexicographically in the same order as the
+			// dates, so it is indexed as a plain string without a shadow key in the search backend
+			return StringType
 		default:
+			if IsCustomFormat(format) {
+				// stored as a plain string, the format is enforced by its registered validator
+				return StringType
+			}
 			if len(format) > 0 {
 				return UnknownType
 			}
@@ -228,11 +247,22 @@ var SupportedFieldProperties = container.NewHashSet(
 	"format",
 	"items",
 	"maxLength",
+	"multipleOf",
 	"description",
 	"contentEncoding",
 	"properties",
 	"autoGenerate",
 	"sorted",
+	"searchReturn",
+	"primaryKey",
+	"x-tigris-immutable",
+	"x-tigris-computed",
+	"x-tigris-deprecated",
+	"x-tigris-aliases",
+	"default",
+	"contains",
+	"minContains",
+	"maxContains",
 )
 
 // Indexes is to wrap different index that a collection can have.
@@ -283,19 +313,30 @@ func (i *Index) IsCompatible(i1 *Index) error {
 }
 
 type FieldBuilder struct {
-	FieldName   string
-	Description string              `json:"description,omitempty"`
-	Type        string              `json:"type,omitempty"`
-	Format      string              `json:"format,omitempty"`
-	Encoding    string              `json:"contentEncoding,omitempty"`
-	MaxLength   *int32              `json:"maxLength,omitempty"`
-	Auto        *bool               `json:"autoGenerate,omitempty"`
-	Sorted      *bool               `json:"sorted,omitempty"`
-	Items       *FieldBuilder       `json:"items,omitempty"`
-	Properties  jsoniter.RawMessage `json:"properties,omitempty"`
-	Primary     *bool
-	Partition   *bool
-	Fields      []*Field
+	FieldName    string
+	Description  string              `json:"description,omitempty"`
+	Type         string              `json:"type,omitempty"`
+	Format       string              `json:"format,omitempty"`
+	Encoding     string              `json:"contentEncoding,omitempty"`
+	MaxLength    *int32              `json:"maxLength,omitempty"`
+	MultipleOf   *json.Number        `json:"multipleOf,omitempty"`
+	Auto         *bool               `json:"autoGenerate,omitempty"`
+	Sorted       *bool               `json:"sorted,omitempty"`
+	SearchReturn *bool               `json:"searchReturn,omitempty"`
+	Immutable    *bool               `json:"x-tigris-immutable,omitempty"`
+	Computed     string              `json:"x-tigris-computed,omitempty"`
+	Deprecated   *bool               `json:"x-tigris-deprecated,omitempty"`
+	Aliases      []string            `json:"x-tigris-aliases,omitempty"`
+	Default      jsoniter.RawMessage `json:"default,omitempty"`
+	Items        *FieldBuilder       `json:"items,omitempty"`
+	Contains     jsoniter.RawMessage `json:"contains,omitempty"`
+	MinContains  *int32              `json:"minContains,omitempty"`
+	MaxContains  *int32              `json:"maxContains,omitempty"`
+	Properties   jsoniter.RawMessage `json:"properties,omitempty"`
+	PrimaryOrder *int32              `json:"primaryKey,omitempty"`
+	Primary      *bool
+	Partition    *bool
+	Fields       []*Field
 }
 
 func (f *FieldBuilder) Validate(v []byte) error {
@@ -346,31 +387,191 @@ func (f *FieldBuilder) Build(isArrayElement bool) (*Field, error) {
 			return nil, errors.InvalidArgument("unsupported primary key type detected '%s'", f.Type)
 		}
 	}
+	if f.PrimaryOrder != nil && *f.PrimaryOrder < 1 {
+		return nil, errors.InvalidArgument("primary key order of the field '%s' must be greater than 0", f.FieldName)
+	}
 	if f.Primary == nil && f.Auto != nil && *f.Auto {
 		return nil, errors.InvalidArgument("only primary fields can be set as auto-generated '%s'", f.FieldName)
 	}
+	if isArrayElement && f.Immutable != nil && *f.Immutable {
+		return nil, errors.InvalidArgument("the items of an array can't be immutable, set it on the array field instead")
+	}
+	if len(f.Computed) > 0 {
+		if isArrayElement {
+			return nil, errors.InvalidArgument("the items of an array can't be computed")
+		}
+		if f.Primary != nil && *f.Primary {
+			return nil, errors.InvalidArgument("primary key field '%s' can't be computed", f.FieldName)
+		}
+		if _, ok := getComputeHook(f.Computed); !ok {
+			return nil, errors.InvalidArgument("unknown compute hook '%s' of the field '%s'", f.Computed, f.FieldName)
+		}
+	}
+
+	if err := f.validateAliases(isArrayElement); err != nil {
+		return nil, err
+	}
+	if f.Default != nil {
+		if isArrayElement {
+			return nil, errors.InvalidArgument("the items of an array can't have a default, set it on the array field instead")
+		}
+		if len(f.Computed) > 0 || (f.Auto != nil && *f.Auto) {
+			return nil, errors.InvalidArgument("the computed and the auto-generated field '%s' can't have a default", f.FieldName)
+		}
+	}
+
+	multipleOf, err := f.buildMultipleOf(fieldType)
+	if err != nil {
+		return nil, err
+	}
+	if err = f.validateContains(fieldType); err != nil {
+		return nil, err
+	}
 
 	field := &Field{}
 	field.FieldName = f.FieldName
 	field.MaxLength = f.MaxLength
+	field.MultipleOf = multipleOf
 	field.DataType = fieldType
 	field.PrimaryKeyField = f.Primary
+	if f.PrimaryOrder != nil {
+		field.PrimaryKeyOrder = int(*f.PrimaryOrder)
+	}
 	field.PartitionKeyField = f.Partition
 	field.Fields = f.Fields
 	field.AutoGenerated = f.Auto
 	field.Sorted = f.Sorted
+	field.SearchReturn = f.SearchReturn
+	field.Immutable = f.Immutable
+	field.Computed = f.Computed
+	field.Deprecated = f.Deprecated
+	field.Aliases = f.Aliases
+	field.Default = f.Default
 	return field, nil
 }
 
+// validateAliases validates the alternate names of the field annotated with "x-tigris-aliases", they follow the rules
+// of the field names.
+func (f *FieldBuilder) validateAliases(isArrayElement bool) error {
+	if len(f.Aliases) == 0 {
+		return nil
+	}
+	if isArrayElement {
+		return errors.InvalidArgument("the items of an array can't have aliases")
+	}
+	if f.Primary != nil && *f.Primary {
+		return errors.InvalidArgument("primary key field '%s' can't have aliases", f.FieldName)
+	}
+
+	for _, alias := range f.Aliases {
+		if alias == f.FieldName {
+			return errors.InvalidArgument("alias '%s' of the field '%s' is the name of the field", alias, f.FieldName)
+		}
+		if IsReservedField(alias) || !ValidFieldNamePattern.MatchString(alias) {
+			return errors.InvalidArgument("alias '%s' of the field '%s' is not a valid field name", alias, f.FieldName)
+		}
+	}
+	return nil
+}
+
+// validateDefault validates the default value of the field against the schema of the field, the raw definition of the
+// field, so that the documents are not rejected for a value they didn't set.
+func (f *FieldBuilder) validateDefault(field []byte) error {
+	if f.Default == nil {
+		return nil
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft7
+	if err := compiler.AddResource("default.json", bytes.NewReader(field)); err != nil {
+		return errors.InvalidArgument("invalid schema of the field '%s'", f.FieldName)
+	}
+	compiled, err := compiler.Compile("default.json")
+	if err != nil {
+		return errors.InvalidArgument("invalid schema of the field '%s'", f.FieldName)
+	}
+
+	value, err := decodeDefault(f.Default)
+	if err != nil {
+		return errors.InvalidArgument("default of the field '%s' is not a valid JSON value", f.FieldName)
+	}
+	if err = compiled.Validate(value); err != nil {
+		return errors.InvalidArgument("default of the field '%s' doesn't match the schema of the field", f.FieldName)
+	}
+	return nil
+}
+
+// buildMultipleOf returns the exact value of "multipleOf", it is only supported by the numeric fields and it must be
+// greater than zero.
+func (f *FieldBuilder) buildMultipleOf(fieldType FieldType) (*big.Rat, error) {
+	if f.MultipleOf == nil {
+		return nil, nil
+	}
+	if fieldType != Int32Type && fieldType != Int64Type && fieldType != DoubleType {
+		return nil, errors.InvalidArgument("multipleOf is only supported by the number and the integer fields, field '%s'", f.FieldName)
+	}
+
+	multipleOf, ok := new(big.Rat).SetString(f.MultipleOf.String())
+	if !ok || multipleOf.Sign() <= 0 {
+		return nil, errors.InvalidArgument("multipleOf of the field '%s' must be a number greater than 0", f.FieldName)
+	}
+	return multipleOf, nil
+}
+
+// validateContains validates "contains" of an array field, the optional "minContains" and "maxContains" bound the
+// number of items matching it.
+func (f *FieldBuilder) validateContains(fieldType FieldType) error {
+	if f.Contains == nil {
+		if f.MinContains != nil || f.MaxContains != nil {
+			return errors.InvalidArgument("minContains and maxContains of the field '%s' require contains", f.FieldName)
+		}
+		return nil
+	}
+	if fieldType != ArrayType {
+		return errors.InvalidArgument("contains is only supported by the array fields, field '%s'", f.FieldName)
+	}
+	if (f.MinContains != nil && *f.MinContains < 0) || (f.MaxContains != nil && *f.MaxContains < 0) {
+		return errors.InvalidArgument("minContains and maxContains of the field '%s' can't be negative", f.FieldName)
+	}
+	if f.MinContains != nil && f.MaxContains != nil && *f.MinContains > *f.MaxContains {
+		return errors.InvalidArgument("minContains of the field '%s' is greater than its maxContains", f.FieldName)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft7
+	if err := compiler.AddResource("contains.json", bytes.NewReader(f.Contains)); err != nil {
+		return errors.InvalidArgument("contains of the field '%s' must be a schema", f.FieldName)
+	}
+	if _, err := compiler.Compile("contains.json"); err != nil {
+		return errors.InvalidArgument("contains of the field '%s' is not a valid schema", f.FieldName)
+	}
+
+	return nil
+}
+
 type Field struct {
-	FieldName         string
-	DataType          FieldType
-	MaxLength         *int32
+	FieldName string
+	DataType  FieldType
+	MaxLength *int32
+	// MultipleOf is the exact value the values of the field must be a multiple of.
+	MultipleOf        *big.Rat
 	UniqueKeyField    *bool
 	PrimaryKeyField   *bool
+	PrimaryKeyOrder   int
 	PartitionKeyField *bool
 	AutoGenerated     *bool
 	Sorted            *bool
+	SearchReturn      *bool
+	Immutable         *bool
+	// Computed is the name of the hook deriving the value of the field, see RegisterComputeHook.
+	Computed string
+	// Deprecated is set if the field is being phased out, the generated code marks it as deprecated.
+	Deprecated *bool
+	// Aliases are the alternate names of the field annotated with "x-tigris-aliases", the documents are normalized to
+	// the name of the field.
+	Aliases []string
+	// Default is the value of the field annotated with "default", it is set in the documents inserted without it.
+	Default jsoniter.RawMessage
 	// Nested fields are the fields where we know the schema of nested attributes like if properties are
 
 	Fields []*Field
@@ -400,6 +601,28 @@ func (f *Field) IsSorted() bool {
 	return f.Sorted != nil && *f.Sorted
 }
 
+// IsSearchReturned returns false if the field is only indexed for matching and must be removed from the search hits.
+func (f *Field) IsSearchReturned() bool {
+	return f.SearchReturn == nil || *f.SearchReturn
+}
+
+// IsImmutable returns true if the field is annotated with "x-tigris-immutable", it can't be changed by an update.
+func (f *Field) IsImmutable() bool {
+	return f.Immutable != nil && *f.Immutable
+}
+
+// IsComputed returns true if the field is annotated with "x-tigris-computed", its value is derived and can't be set by
+// the clients.
+func (f *Field) IsComputed() bool {
+	return len(f.Computed) > 0
+}
+
+// IsDeprecated returns true if the field is annotated with "x-tigris-deprecated", it is only kept for the existing
+// documents and the clients.
+func (f *Field) IsDeprecated() bool {
+	return f.Deprecated != nil && *f.Deprecated
+}
+
 func (f *Field) IsCompatible(f1 *Field) error {
 	if f.DataType != f1.DataType {
 		return errors.InvalidArgument("data type mismatch for field %q", f.FieldName)
@@ -415,6 +638,13 @@ func (f *Field) IsCompatible(f1 *Field) error {
 		}
 	}
 
+	// the existing values must still be valid, the new value must divide the existing one
+	if f.MultipleOf != nil && f1.MultipleOf != nil {
+		if !new(big.Rat).Quo(f.MultipleOf, f1.MultipleOf).IsInt() {
+			return errors.InvalidArgument("changing multipleOf of an existing field to a value that doesn't divide it is not allowed %q", f.FieldName)
+		}
+	}
+
 	return nil
 }
 
@@ -29,6 +29,10 @@ func TestFieldBuilder_Build(t *testing.T) {
 		require.Equal(t, ByteType, ToFieldType("string", jsonSpecEncodingB64, ""))
 		require.Equal(t, UUIDType, ToFieldType("string", "", jsonSpecFormatUUID))
 		require.Equal(t, DateTimeType, ToFieldType("string", "", jsonSpecFormatDateTime))
+		require.Equal(t, StringType, ToFieldType("string", "", jsonSpecFormatEmail))
+		require.Equal(t, StringType, ToFieldType("string", "", jsonSpecFormatURI))
+		require.Equal(t, StringType, ToFieldType("string", "", jsonSpecFormatDate))
+		require.Equal(t, StringType, ToFieldType("string", "", jsonSpecFormatTime))
 		require.Equal(t, UnknownType, ToFieldType("string", "random", ""))
 	})
 	t.Run("test supported types", func(t *testing.T) {
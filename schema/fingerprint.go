@@ -0,0 +1,57 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+// Fingerprint returns the hash of the schema of the factory. The schemas that only differ in the formatting of their
+// JSON, the whitespace and the order of the keys, have the same fingerprint.
+func Fingerprint(factory *Factory) (string, error) {
+	return FingerprintSchema(factory.Schema)
+}
+
+// FingerprintSchema returns the fingerprint of a JSON schema, see Fingerprint.
+func FingerprintSchema(sch jsoniter.RawMessage) (string, error) {
+	normalized, err := normalizeSchema(sch)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(normalized)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// normalizeSchema returns the schema re-encoded without whitespace and with the keys of its objects sorted. The
+// numbers are decoded, so that 1 and 1.0 are the same.
+func normalizeSchema(sch jsoniter.RawMessage) ([]byte, error) {
+	var decoded interface{}
+	if err := jsoniter.Unmarshal(sch, &decoded); err != nil {
+		return nil, errors.InvalidArgument("invalid schema: %s", err.Error())
+	}
+
+	// the keys of the maps are sorted by encoding/json
+	normalized, err := json.Marshal(decoded)
+	if err != nil {
+		return nil, errors.Internal("normalizing the schema failed: %s", err.Error())
+	}
+	return normalized, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+)
+
+func TestFingerprint(t *testing.T) {
+	fingerprint := func(reqSchema string) string {
+		factory, err := Build("t1", []byte(reqSchema), false)
+		require.NoError(t, err)
+		f, err := Fingerprint(factory)
+		require.NoError(t, err)
+		return f
+	}
+
+	base := fingerprint(`{"title":"t1","properties":{"id":{"type":"integer"},"name":{"type":"string","maxLength":100}},"primary_key":["id"]}`)
+	require.Len(t, base, 64)
+
+	// the whitespace and the order of the keys don't change the fingerprint
+	require.Equal(t, base, fingerprint(`{
+	"primary_key": ["id"],
+	"title": "t1",
+	"properties": {
+		"name": { "maxLength": 100, "type": "string" },
+		"id":   { "type": "integer" }
+	}
+}`))
+
+	// the semantic changes do
+	require.NotEqual(t, base, fingerprint(`{"title":"t1","properties":{"id":{"type":"integer"},"name":{"type":"number"}},"primary_key":["id"]}`))
+	require.NotEqual(t, base, fingerprint(`{"title":"t1","properties":{"id":{"type":"integer"},"name":{"type":"string","maxLength":50}},"primary_key":["id"]}`))
+	require.NotEqual(t, base, fingerprint(`{"title":"t1","properties":{"id":{"type":"integer"},"name":{"type":"string"}},"primary_key":["id"]}`))
+
+	// neither does the representation of the numbers
+	f1, err := FingerprintSchema([]byte(`{"maximum": 1}`))
+	require.NoError(t, err)
+	f2, err := FingerprintSchema([]byte(`{"maximum": 1.0}`))
+	require.NoError(t, err)
+	require.Equal(t, f1, f2)
+
+	_, err = FingerprintSchema([]byte(`{"title":`))
+	require.Equal(t, api.Code_INVALID_ARGUMENT, err.(*api.TigrisError).Code)
+}
@@ -0,0 +1,173 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"encoding/base64"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/buger/jsonparser"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+// FormatValidator returns true if the value is valid for the format. It is called with the decoded values of the
+// document, the values of a type the format doesn't apply to are expected to be accepted.
+type FormatValidator func(value interface{}) bool
+
+type format struct {
+	validator FormatValidator
+	builtin   bool
+}
+
+// formats is the registry of the formats a schema can use, the formats not in the registry are rejected by Build.
+var formats = struct {
+	sync.RWMutex
+
+	byName map[string]format
+}{byName: make(map[string]format)}
+
+// RegisterFormat adds a format to the registry. The fields of type string can then use it in their "format" and the
+// validator is called when the documents are validated. The format must be registered before the collections using
+// it are built, typically from an init function, the validators of the built collections are not updated.
+func RegisterFormat(name string, validator FormatValidator) error {
+	return registerFormat(name, validator, false)
+}
+
+func registerFormat(name string, validator FormatValidator, builtin bool) error {
+	if len(name) == 0 {
+		return errors.InvalidArgument("format name is empty")
+	}
+	if validator == nil {
+		return errors.InvalidArgument("format '%s' has no validator", name)
+	}
+
+	formats.Lock()
+	defer formats.Unlock()
+	if _, ok := formats.byName[name]; ok {
+		return errors.AlreadyExists("format '%s' is already registered", name)
+	}
+	formats.byName[name] = format{validator: validator, builtin: builtin}
+	jsonschema.Formats[name] = validator
+
+	return nil
+}
+
+// IsCustomFormat returns true if the format is registered with RegisterFormat.
+func IsCustomFormat(name string) bool {
+	formats.RLock()
+	defer formats.RUnlock()
+
+	f, ok := formats.byName[name]
+	return ok && !f.builtin
+}
+
+// RegisteredFormats returns the names of the registered formats, built-in ones included, sorted by name.
+func RegisteredFormats() []string {
+	formats.RLock()
+	defer formats.RUnlock()
+
+	names := make([]string, 0, len(formats.byName))
+	for name := range formats.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isRegisteredFormat(name string) bool {
+	formats.RLock()
+	defer formats.RUnlock()
+
+	_, ok := formats.byName[name]
+	return ok
+}
+
+// checkFormats returns an error for the first field of the properties, nested fields and array items included, with a
+// format that is not registered. An empty format is no format.
+func checkFormats(parent string, properties []byte) error {
+	return jsonparser.ObjectEach(properties, func(key []byte, v []byte, dataType jsonparser.ValueType, _ int) error {
+		if dataType != jsonparser.Object {
+			return nil
+		}
+		return checkFieldFormats(buildPath(parent, string(key)), v)
+	})
+}
+
+func checkFieldFormats(path string, field []byte) error {
+	if format, err := jsonparser.GetString(field, "format"); err == nil && len(format) > 0 && !isRegisteredFormat(format) {
+		return errors.InvalidArgument("unknown format '%s' of the field '%s'", format, path)
+	}
+	if properties, dt, _, _ := jsonparser.Get(field, "properties"); dt == jsonparser.Object {
+		if err := checkFormats(path, properties); err != nil {
+			return err
+		}
+	}
+	if items, dt, _, _ := jsonparser.Get(field, "items"); dt == jsonparser.Object {
+		return checkFieldFormats(path, items)
+	}
+	return nil
+}
+
+func mustRegisterBuiltinFormat(name string, validator FormatValidator) {
+	if err := registerFormat(name, validator, true); err != nil {
+		panic(err)
+	}
+}
+
+func init() {
+	// the formats of the validator library that Tigris supports
+	for _, name := range []string{jsonSpecFormatUUID, jsonSpecFormatEmail, jsonSpecFormatURI, jsonSpecFormatDate} {
+		mustRegisterBuiltinFormat(name, jsonschema.Formats[name])
+	}
+
+	isDateTime := jsonschema.Formats[jsonSpecFormatDateTime]
+	mustRegisterBuiltinFormat(jsonSpecFormatDateTime, func(i interface{}) bool {
+		if !isDateTime(i) {
+			return false
+		}
+		if v, ok := i.(string); ok && StrictDateTime {
+			return isStrictDateTime(v)
+		}
+		return true
+	})
+	mustRegisterBuiltinFormat(jsonSpecFormatTime, func(i interface{}) bool {
+		if v, ok := i.(string); ok {
+			return timeOfDay.MatchString(v)
+		}
+		return false
+	})
+	mustRegisterBuiltinFormat(jsonSpecFormatByte, func(i interface{}) bool {
+		if v, ok := i.(string); ok {
+			_, err := base64.StdEncoding.DecodeString(v)
+			return err == nil
+		}
+		return false
+	})
+	mustRegisterBuiltinFormat(jsonSpecFormatInt32, func(i interface{}) bool {
+		val, err := parseInt(i)
+		if err != nil {
+			return false
+		}
+
+		return !(val < math.MinInt32 || val > math.MaxInt32)
+	})
+	mustRegisterBuiltinFormat(jsonSpecFormatInt64, func(i interface{}) bool {
+		_, err := parseInt(i)
+		return err == nil
+	})
+}
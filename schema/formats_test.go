@@ -0,0 +1,87 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+func TestRegisterFormat(t *testing.T) {
+	isbn := regexp.MustCompile(`^(97[89])?[0-9]{9}[0-9X]$`)
+	require.NoError(t, RegisterFormat("test-isbn", func(i interface{}) bool {
+		if v, ok := i.(string); ok {
+			return isbn.MatchString(v)
+		}
+		return true
+	}))
+
+	t.Run("registry", func(t *testing.T) {
+		require.True(t, IsCustomFormat("test-isbn"))
+		require.False(t, IsCustomFormat(jsonSpecFormatDateTime))
+		require.False(t, IsCustomFormat("isbn"))
+		require.Contains(t, RegisteredFormats(), "test-isbn")
+		for _, name := range []string{jsonSpecFormatUUID, jsonSpecFormatDateTime} {
+			require.Contains(t, RegisteredFormats(), name)
+		}
+		require.Equal(t, StringType, ToFieldType("string", "", "test-isbn"))
+	})
+
+	t.Run("invalid registrations", func(t *testing.T) {
+		require.Equal(t, errors.AlreadyExists("format 'test-isbn' is already registered"),
+			RegisterFormat("test-isbn", func(interface{}) bool { return true }))
+		require.Equal(t, errors.AlreadyExists("format 'uuid' is already registered"),
+			RegisterFormat(jsonSpecFormatUUID, func(interface{}) bool { return true }))
+		require.Equal(t, errors.InvalidArgument("format name is empty"),
+			RegisterFormat("", func(interface{}) bool { return true }))
+		require.Equal(t, errors.InvalidArgument("format 'test-nil' has no validator"), RegisterFormat("test-nil", nil))
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"isbn": { "type": "string", "format": "test-isbn" }
+		},
+		"primary_key": ["id"]
+	}`)
+		schFactory, err := Build("t1", reqSchema, false)
+		require.NoError(t, err)
+		require.Equal(t, StringType, schFactory.Fields[1].DataType)
+		coll := NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
+
+		require.NoError(t, coll.Validate(map[string]interface{}{"id": json.Number("1"), "isbn": "9780306406157"}))
+		require.NoError(t, coll.Validate(map[string]interface{}{"id": json.Number("1"), "isbn": "030640615X"}))
+		require.Equal(t, "json schema validation failed for field 'isbn' reason ''978-0306406157' is not valid 'test-isbn''",
+			coll.Validate(map[string]interface{}{"id": json.Number("1"), "isbn": "978-0306406157"}).Error())
+	})
+
+	t.Run("unregistered format", func(t *testing.T) {
+		_, err := Build("t1", []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"phone": { "type": "string", "format": "test-phone" }
+		},
+		"primary_key": ["id"]
+	}`), false)
+		require.Equal(t, errors.InvalidArgument("unsupported format 'test-phone'"), err)
+	})
+}
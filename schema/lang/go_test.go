@@ -86,12 +86,40 @@ type Product struct {
 	// Subtype sub type description
 	Subtype Subtype ` + "`" + `json:"subtype"` + "`" + `
 }
+`},
+		{"ref", refTest, `
+// Address postal address
+type Address struct {
+	City string ` + "`" + `json:"city"` + "`" + `
+	Zip int32 ` + "`" + `json:"zip"` + "`" + `
+}
+
+type Order struct {
+	Billing Address ` + "`" + `json:"billing"` + "`" + `
+	Item string ` + "`" + `json:"item"` + "`" + `
+	Previous []Address ` + "`" + `json:"previous"` + "`" + `
+	// Shipping shipping address
+	Shipping Address ` + "`" + `json:"shipping"` + "`" + `
+}
 `},
 		{
 			"no_tag", noGoTagSchema, `
 type Product struct {
 	Name string
 }
+`,
+		},
+		{
+			"deprecated", deprecatedTest, `
+type Product struct {
+	Id int32 ` + "`" + `json:"id" tigris:"primaryKey:1"` + "`" + `
+	// Deprecated: Name is deprecated.
+	Name string ` + "`" + `json:"name"` + "`" + `
+	// Price field description
+	//
+	// Deprecated: Price is deprecated.
+	Price float64 ` + "`" + `json:"price"` + "`" + `
+}
 `,
 		},
 	}
@@ -108,3 +136,12 @@ type Product struct {
 		})
 	}
 }
+
+func TestGoSchemaGenerator_UndefinedRef(t *testing.T) {
+	buf := bytes.Buffer{}
+	w := bufio.NewWriter(&buf)
+	var hasTime, hasUUID bool
+	err := genCollectionSchema(w, []byte(`{"title": "orders", "properties": {"billing": { "$ref": "#/definitions/address" }}}`),
+		&JSONToGo{}, &hasTime, &hasUUID)
+	require.ErrorIs(t, err, ErrUndefinedRef)
+}
@@ -571,6 +571,85 @@ class Product implements TigrisDocumentCollectionType {
     }
 }
 `},
+		{
+			"deprecated", deprecatedTest, `
+@TigrisCollection(value = "products")
+class Product implements TigrisDocumentCollectionType {
+    @TigrisPrimaryKey(order = 1)
+    private int id;
+    @Deprecated
+    private String name;
+    @TigrisField(description = "field description")
+    @Deprecated
+    private double price;
+
+    public int getId() {
+        return id;
+    }
+
+    public void setId(int id) {
+        this.id = id;
+    }
+
+    @Deprecated
+    public String getName() {
+        return name;
+    }
+
+    @Deprecated
+    public void setName(String name) {
+        this.name = name;
+    }
+
+    @Deprecated
+    public double getPrice() {
+        return price;
+    }
+
+    @Deprecated
+    public void setPrice(double price) {
+        this.price = price;
+    }
+
+    public Product() {};
+
+    public Product(
+        int id,
+        String name,
+        double price
+    ) {
+        this.id = id;
+        this.name = name;
+        this.price = price;
+    };
+
+    @Override
+    public boolean equals(Object o) {
+        if (this == o) {
+            return true;
+        }
+        if (o == null || getClass() != o.getClass()) {
+            return false;
+        }
+
+        Product other = (Product) o;
+        return
+            id == other.id &&
+            name == other.name &&
+            price == other.price;
+    }
+
+    @Override
+    public int hashCode() {
+        return Objects.hash(
+            id,
+            name,
+            price
+        );
+    }
+}
+`,
+		},
 	}
 
 	for _, v := range cases {
@@ -25,12 +25,14 @@ import (
 
 	"github.com/gertd/go-pluralize"
 	"github.com/iancoleman/strcase"
+	"github.com/pkg/errors"
 	"github.com/tigrisdata/tigris/util"
 )
 
 var (
 	ErrUnsupportedFormat = fmt.Errorf("unsupported format. supported formats are: JSON, TypeScripts, Go, Java")
 	ErrEmptyObjectName   = fmt.Errorf("object name should be non-zero length")
+	ErrUndefinedRef      = fmt.Errorf("reference to an undefined definition")
 
 	plural = pluralize.NewClient()
 )
@@ -65,8 +67,10 @@ type Field struct {
 	Desc   string            `json:"description,omitempty"`
 	Fields map[string]*Field `json:"properties,omitempty"`
 	Items  *Field            `json:"items,omitempty"`
+	Ref    string            `json:"$ref,omitempty"`
 
 	AutoGenerate bool `json:"autoGenerate,omitempty"`
+	Deprecated   bool `json:"x-tigris-deprecated,omitempty"`
 }
 
 // Schema is top level JSON schema object.
@@ -76,9 +80,39 @@ type Schema struct {
 	Fields     map[string]*Field `json:"properties,omitempty"`
 	PrimaryKey []string          `json:"primary_key,omitempty"`
 
+	Definitions map[string]*Field `json:"definitions,omitempty"`
+	Defs        map[string]*Field `json:"$defs,omitempty"`
+
 	CollectionType string `json:"collection_type,omitempty"`
 }
 
+// definitions are the shared types of a schema, referenced with "$ref". The object definitions are generated once, the
+// first time they are referenced.
+type definitions struct {
+	fields    map[string]*Field
+	generated map[string]bool
+}
+
+func newDefinitions(sch *Schema) *definitions {
+	defs := &definitions{fields: make(map[string]*Field), generated: make(map[string]bool)}
+	for name, f := range sch.Definitions {
+		defs.fields["#/definitions/"+name] = f
+	}
+	for name, f := range sch.Defs {
+		defs.fields["#/$defs/"+name] = f
+	}
+	return defs
+}
+
+// resolve returns the name and the definition referenced by ref.
+func (defs *definitions) resolve(ref string) (string, *Field, error) {
+	f, ok := defs.fields[ref]
+	if !ok {
+		return "", nil, errors.Wrapf(ErrUndefinedRef, "$ref=%s", ref)
+	}
+	return ref[strings.LastIndex(ref, "/")+1:], f, nil
+}
+
 type JSONToLangType interface {
 	GetType(string, string) (string, error)
 	GetObjectTemplate() string
@@ -109,6 +143,7 @@ type FieldGen struct {
 	ArrayDimensions int
 
 	Description string
+	Deprecated  bool
 }
 
 type Object struct {
@@ -127,11 +162,11 @@ type Object struct {
 }
 
 func genField(w io.Writer, n string, v *Field, pk []string, c JSONToLangType,
-	hasTime *bool, hasUUID *bool,
+	defs *definitions, hasTime *bool, hasUUID *bool,
 ) (*FieldGen, error) {
 	var err error
 
-	f := FieldGen{AutoGenerate: v.AutoGenerate}
+	f := FieldGen{AutoGenerate: v.AutoGenerate, Deprecated: v.Deprecated}
 
 	f.NameJSON = n
 	f.JSONCap = strings.ToUpper(n[0:1]) + n[1:]
@@ -146,15 +181,38 @@ func genField(w io.Writer, n string, v *Field, pk []string, c JSONToLangType,
 	f.NameSnake = strcase.ToSnake(n)
 	f.Description = v.Desc
 
-	for v.Type == typeArray {
+	var defName string
+	for {
+		if len(v.Ref) > 0 {
+			if defName, v, err = defs.resolve(v.Ref); err != nil {
+				return nil, err
+			}
+		}
+		if v.Type != typeArray {
+			break
+		}
+
 		v = v.Items
+		defName = ""
 		f.ArrayDimensions++
 	}
 
 	f.IsArray = f.ArrayDimensions > 0
 
-	if v.Type == typeObject {
-		if err := genSchema(w, n, v.Desc, v.Fields, nil, c, hasTime, hasUUID); err != nil {
+	if v.Type == typeObject && len(defName) > 0 {
+		// the shared type is named after its definition and only generated once
+		if !defs.generated[defName] {
+			defs.generated[defName] = true
+			if err := genSchema(w, defName, v.Desc, v.Fields, nil, c, defs, hasTime, hasUUID); err != nil {
+				return nil, err
+			}
+		}
+
+		f.Type = strcase.ToCamel(plural.Singular(defName))
+		f.TypeDecap = strings.ToLower(f.Type[0:1]) + f.Type[1:]
+		f.IsObject = true
+	} else if v.Type == typeObject {
+		if err := genSchema(w, n, v.Desc, v.Fields, nil, c, defs, hasTime, hasUUID); err != nil {
 			return nil, err
 		}
 
@@ -183,7 +241,7 @@ func genField(w io.Writer, n string, v *Field, pk []string, c JSONToLangType,
 }
 
 func genSchema(w io.Writer, name string, desc string, field map[string]*Field,
-	pk []string, c JSONToLangType, hasTime *bool, hasUUID *bool,
+	pk []string, c JSONToLangType, defs *definitions, hasTime *bool, hasUUID *bool,
 ) error {
 	var obj Object
 
@@ -214,7 +272,7 @@ func genSchema(w io.Writer, name string, desc string, field map[string]*Field,
 			return ErrEmptyObjectName
 		}
 
-		f, err := genField(w, n, v, pk, c, hasTime, hasUUID)
+		f, err := genField(w, n, v, pk, c, defs, hasTime, hasUUID)
 		if err != nil {
 			return err
 		}
@@ -236,7 +294,7 @@ func genCollectionSchema(w io.Writer, rawSchema []byte, c JSONToLangType, hasTim
 		return err
 	}
 
-	if err := genSchema(w, sch.Name, sch.Desc, sch.Fields, sch.PrimaryKey, c, hasTime, hasUUID); err != nil {
+	if err := genSchema(w, sch.Name, sch.Desc, sch.Fields, sch.PrimaryKey, c, newDefinitions(&sch), hasTime, hasUUID); err != nil {
 		return err
 	}
 
@@ -83,6 +83,37 @@ var (
           }
         }`
 
+	deprecatedTest = `{
+		"title": "products",
+		"properties": {
+			"id": { "type": "integer", "format": "int32" },
+			"name": { "type": "string", "x-tigris-deprecated": true },
+			"price": { "type": "number", "description": "field description", "x-tigris-deprecated": true }
+		},
+		"primary_key": ["id"]
+	}`
+
+	refTest = `{
+          "title": "orders",
+          "definitions": {
+            "address": {
+              "type": "object",
+              "description": "postal address",
+              "properties": {
+                "city": { "type": "string" },
+                "zip": { "type": "integer", "format": "int32" }
+              }
+            },
+            "sku": { "type": "string" }
+          },
+          "properties": {
+            "billing": { "$ref": "#/definitions/address" },
+            "shipping": { "$ref": "#/definitions/address", "description": "shipping address" },
+            "previous": { "type": "array", "items": { "$ref": "#/definitions/address" } },
+            "item": { "$ref": "#/definitions/sku" }
+          }
+        }`
+
 	noGoTagSchema = `{
         "title": "products",
         "properties": {
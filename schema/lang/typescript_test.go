@@ -213,6 +213,33 @@ export const productSchema: TigrisSchema<Product> = {
     type: subtypeSchema,
   },
 };
+`,
+		},
+		{
+			"deprecated", deprecatedTest, `
+export interface Product extends TigrisCollectionType {
+  id: number;
+  /** @deprecated */
+  name: string;
+  // price field description
+  /** @deprecated */
+  price: number;
+}
+
+export const productSchema: TigrisSchema<Product> = {
+  id: {
+    type: TigrisDataTypes.INT32,
+    primary_key: {
+      order: 1,
+    },
+  },
+  name: {
+    type: TigrisDataTypes.STRING,
+  },
+  price: {
+    type: TigrisDataTypes.NUMBER,
+  },
+};
 `,
 		},
 	}
@@ -0,0 +1,146 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"strconv"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// DefaultLargeArrayThreshold is the default number of items above which an array is a large array.
+const DefaultLargeArrayThreshold = 1000
+
+// LargeArrayThreshold is the number of items above which the arrays of a document are validated item by item, in the
+// order of the items, instead of by the validator of the collection. The validation of a large array stops once
+// MaxViolations are collected, the validator collects the errors of all the items before it fails. Zero disables it.
+var LargeArrayThreshold = DefaultLargeArrayThreshold
+
+// largeArray is an array of a document that is validated item by item.
+type largeArray struct {
+	// pointer is the path of the array in the document and location the location of its schema.
+	pointer  string
+	location string
+	items    *jsonschema.Schema
+	values   []interface{}
+}
+
+// largeArrayViolations appends the violations of the items of the arrays to the violations, in the order of the
+// items, until there are limit violations.
+func (d *DefaultCollection) largeArrayViolations(arrays []largeArray, violations []violation, limit int) ([]violation, error) {
+	for _, array := range arrays {
+		for i, value := range array.values {
+			if limit > 0 && len(violations) >= limit {
+				return violations, nil
+			}
+
+			var err error
+			violations, err = d.collectViolations(array.items, joinPointer(array.pointer, strconv.Itoa(i)), array.location+"/items", value, violations, limit)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return violations, nil
+}
+
+// splitLargeArrays returns the value with the large arrays that can be validated item by item replaced by their
+// MinItems first items, so that the validator still checks the array itself, and appends the arrays to arrays. The
+// objects on the path to a large array are copied and the value is left unchanged, the returned bool is false if
+// there is no large array. Only the arrays nested in objects are split, the items of a large array are split when
+// they are validated.
+func splitLargeArrays(s *jsonschema.Schema, pointer string, location string, value interface{}, arrays *[]largeArray) (interface{}, bool) {
+	doc, ok := value.(map[string]interface{})
+	if !ok || !isPlainSchema(s) {
+		return value, false
+	}
+
+	var split map[string]interface{}
+	for key, nested := range doc {
+		property, ok := s.Properties[key]
+		if !ok {
+			continue
+		}
+
+		var (
+			replaced interface{}
+			changed  bool
+		)
+		switch v := nested.(type) {
+		case []interface{}:
+			if items := largeArrayItems(property, len(v)); items != nil {
+				*arrays = append(*arrays, largeArray{
+					pointer:  joinPointer(pointer, key),
+					location: location + "/properties/" + key,
+					items:    items,
+					values:   v,
+				})
+				minItems := 0
+				if property.MinItems > 0 {
+					minItems = property.MinItems
+				}
+				replaced, changed = v[:minItems], true
+			}
+		case map[string]interface{}:
+			replaced, changed = splitLargeArrays(property, joinPointer(pointer, key), location+"/properties/"+key, v, arrays)
+		}
+		if !changed {
+			continue
+		}
+
+		if split == nil {
+			split = make(map[string]interface{}, len(doc))
+			for k, v := range doc {
+				split[k] = v
+			}
+		}
+		split[key] = replaced
+	}
+
+	if split == nil {
+		return value, false
+	}
+	return split, true
+}
+
+// largeArrayItems returns the schema of the items of the array of the schema if the array is large and its items can
+// be validated one by one, the arrays with keywords that apply to several items, like "uniqueItems" and "contains",
+// are left to the validator.
+func largeArrayItems(s *jsonschema.Schema, length int) *jsonschema.Schema {
+	if LargeArrayThreshold <= 0 || length <= LargeArrayThreshold || !isPlainSchema(s) {
+		return nil
+	}
+	if s.UniqueItems || s.Contains != nil || s.AdditionalItems != nil || len(s.PrefixItems) > 0 || s.Items2020 != nil {
+		return nil
+	}
+	// the validator reports the bounds on the number of items
+	if length < s.MinItems || (s.MaxItems != -1 && length > s.MaxItems) {
+		return nil
+	}
+
+	items, _ := s.Items.(*jsonschema.Schema)
+	return items
+}
+
+// isPlainSchema returns true if the schema doesn't have keywords that validate the value against other schemas, or
+// the value as a whole, so that the fields of the value can be validated separately.
+func isPlainSchema(s *jsonschema.Schema) bool {
+	return s.Always == nil && s.Ref == nil && s.RecursiveRef == nil && s.DynamicRef == nil &&
+		s.Not == nil && s.If == nil && len(s.AllOf) == 0 && len(s.AnyOf) == 0 && len(s.OneOf) == 0 &&
+		len(s.Constant) == 0 && len(s.Enum) == 0 && len(s.Extensions) == 0 &&
+		s.PropertyNames == nil && len(s.PatternProperties) == 0 && len(s.Dependencies) == 0 &&
+		len(s.DependentRequired) == 0 && len(s.DependentSchemas) == 0 &&
+		s.UnevaluatedProperties == nil && s.UnevaluatedItems == nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var largeArraySchema = []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": { "type": "integer" },
+		"values": { "type": "array", "items": { "type": "integer" } },
+		"names": { "type": "array", "items": { "type": "string", "maxLength": 3 } },
+		"tagged": { "type": "array", "items": { "type": "integer" }, "contains": { "type": "integer", "multipleOf": 1000 }, "maxContains": 2 },
+		"nested": {
+			"type": "object",
+			"properties": {
+				"points": {
+					"type": "array",
+					"items": { "type": "object", "properties": { "x": { "type": "integer" } } }
+				}
+			}
+		}
+	},
+	"primary_key": ["id"]
+}`)
+
+func largeArrayCollection(t testing.TB) *DefaultCollection {
+	schFactory, err := Build("t1", largeArraySchema, false)
+	require.NoError(t, err)
+	return NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
+}
+
+func intArray(n int, invalid map[int]interface{}) []interface{} {
+	values := make([]interface{}, n)
+	for i := range values {
+		values[i] = float64(i % 100)
+		if v, ok := invalid[i]; ok {
+			values[i] = v
+		}
+	}
+	return values
+}
+
+func TestCollection_LargeArrays(t *testing.T) {
+	defer func() { LargeArrayThreshold = DefaultLargeArrayThreshold }()
+	coll := largeArrayCollection(t)
+
+	for _, threshold := range []int{DefaultLargeArrayThreshold, 0} {
+		LargeArrayThreshold = threshold
+
+		require.NoError(t, coll.Validate(map[string]interface{}{"id": float64(1), "values": intArray(100000, nil)}))
+
+		// the error near the end of the array is reported the same way the validator reports it
+		doc := map[string]interface{}{"id": float64(1), "values": intArray(100000, map[int]interface{}{99998: "x"})}
+		require.Equal(t, NewValidationErrors(Violation{"values/99998", "expected integer, but got string", `type: "integer"`}), coll.Validate(doc), threshold)
+		names := make([]interface{}, 100000)
+		for i := range names {
+			names[i] = "abc"
+		}
+		names[99999] = "abcd"
+		doc = map[string]interface{}{"id": float64(1), "names": names}
+		require.Equal(t, NewValidationErrors(Violation{"names/99999", "length must be <= 3, but got 4", "maxLength: 3"}), coll.Validate(doc), threshold)
+
+		// the nested arrays of objects
+		points := make([]interface{}, 5000)
+		for i := range points {
+			points[i] = map[string]interface{}{"x": float64(i)}
+		}
+		points[4990] = map[string]interface{}{"x": "y"}
+		doc = map[string]interface{}{"id": float64(1), "nested": map[string]interface{}{"points": points}}
+		require.Equal(t, NewValidationErrors(Violation{"nested/points/4990/x", "expected integer, but got string", `type: "integer"`}), coll.Validate(doc), threshold)
+
+		// the arrays with keywords on several items are left to the validator
+		err := coll.Validate(map[string]interface{}{"id": float64(1), "tagged": intArray(1200, nil)})
+		require.Equal(t, NewValidationErrors(Violation{"tagged", "12 items match contains, expected at most 2", "maxContains: 2"}), err, threshold)
+	}
+
+	// the value is not modified
+	values := intArray(2000, nil)
+	doc := map[string]interface{}{"id": float64(1), "values": values, "nested": map[string]interface{}{"points": []interface{}{}}}
+	LargeArrayThreshold = DefaultLargeArrayThreshold
+	require.NoError(t, coll.Validate(doc))
+	require.Len(t, doc["values"], 2000)
+
+	// the rest of the document is validated before the large arrays, in the order of the items
+	err := coll.Validate(map[string]interface{}{"id": "1", "values": intArray(2000, map[int]interface{}{1500: "x", 1900: "y"})})
+	require.Equal(t, NewValidationErrors(
+		Violation{"id", "expected integer, but got string", `type: "integer"`},
+		Violation{"values/1500", "expected integer, but got string", `type: "integer"`},
+		Violation{"values/1900", "expected integer, but got string", `type: "integer"`},
+	), err)
+
+	// the validation of the items stops once the maximum number of violations is reached
+	defer func() { MaxViolations = DefaultMaxViolations }()
+	MaxViolations = 2
+	invalid := make(map[int]interface{})
+	for i := 0; i < 2000; i++ {
+		invalid[i] = "x"
+	}
+	err = coll.Validate(map[string]interface{}{"id": float64(1), "values": intArray(2000, invalid)})
+	require.Equal(t, "json schema validation failed with more than 2 violations, first for field 'values/0' reason "+
+		"'expected integer, but got string'", err.Error())
+	require.Len(t, err.(*ValidationError).Violations, 2)
+}
+
+func BenchmarkValidateLargeArray(b *testing.B) {
+	defer func() { LargeArrayThreshold = DefaultLargeArrayThreshold }()
+	coll := largeArrayCollection(b)
+	valid := map[string]interface{}{"id": float64(1), "values": intArray(100000, nil)}
+	invalid := map[string]interface{}{"id": float64(1), "values": intArray(100000, map[int]interface{}{10: "x"})}
+
+	for _, threshold := range []int{DefaultLargeArrayThreshold, 0} {
+		LargeArrayThreshold = threshold
+		b.Run(fmt.Sprintf("valid/threshold=%d", threshold), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = coll.Validate(valid)
+			}
+		})
+		b.Run(fmt.Sprintf("invalid/threshold=%d", threshold), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = coll.Validate(invalid)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/buger/jsonparser"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+const refKey = "$ref"
+
+// refResolver expands the "$ref" of a schema with the shared definitions of the schema, found either in
+// "definitions" or "$defs".
+type refResolver struct {
+	definitions map[string][]byte
+}
+
+// ExpandRefs returns the schema with every "$ref" replaced by the definition it references, and without the
+// definitions. The keywords set next to a "$ref", like "description", are kept and take precedence over the ones of
+// the definition. Only the local references, "#/definitions/<name>" and "#/$defs/<name>", are supported and a
+// definition can't reference itself. The order of the properties is preserved.
+func ExpandRefs(reqSchema jsoniter.RawMessage) (jsoniter.RawMessage, error) {
+	if !bytes.Contains(reqSchema, []byte(`"`+refKey+`"`)) {
+		return reqSchema, nil
+	}
+
+	r, err := newRefResolver(reqSchema)
+	if err != nil {
+		return nil, err
+	}
+	// Delete changes the schema in place, the schema of the caller is kept with its definitions
+	withoutDefs := append([]byte(nil), reqSchema...)
+	for _, key := range definitionKeywords {
+		withoutDefs = jsonparser.Delete(withoutDefs, key)
+	}
+
+	expanded, err := r.expand(withoutDefs, jsonparser.Object, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return expanded, nil
+}
+
+// newRefResolver returns the resolver of the definitions of the schema.
+func newRefResolver(reqSchema jsoniter.RawMessage) (*refResolver, error) {
+	r := &refResolver{definitions: make(map[string][]byte)}
+	for _, key := range definitionKeywords {
+		defs, dataType, _, err := jsonparser.Get(reqSchema, key)
+		if dataType == jsonparser.NotExist {
+			continue
+		}
+		if err != nil || dataType != jsonparser.Object {
+			return nil, errors.InvalidArgument("'%s' of the schema must be an object", key)
+		}
+
+		err = jsonparser.ObjectEach(defs, func(name []byte, def []byte, dataType jsonparser.ValueType, _ int) error {
+			if dataType != jsonparser.Object {
+				return errors.InvalidArgument("definition '%s' must be an object", name)
+			}
+			r.definitions["#/"+key+"/"+string(name)] = def
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// RefName returns the name of the definition a local "$ref" references.
+func RefName(ref string) (string, bool) {
+	for _, key := range definitionKeywords {
+		if name := strings.TrimPrefix(ref, "#/"+key+"/"); name != ref && len(name) > 0 {
+			return strings.ReplaceAll(strings.ReplaceAll(name, "~1", "/"), "~0", "~"), true
+		}
+	}
+	return "", false
+}
+
+func (r *refResolver) expand(value []byte, dataType jsonparser.ValueType, stack []string) ([]byte, error) {
+	switch dataType {
+	case jsonparser.Object:
+		if ref, refType, _, _ := jsonparser.Get(value, refKey); refType != jsonparser.NotExist {
+			if refType != jsonparser.String {
+				return nil, errors.InvalidArgument("'%s' must be a string", refKey)
+			}
+			return r.expandRef(value, string(ref), stack)
+		}
+
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		err := jsonparser.ObjectEach(value, func(key []byte, nested []byte, nestedType jsonparser.ValueType, _ int) error {
+			expanded, err := r.expand(nested, nestedType, stack)
+			if err != nil {
+				return err
+			}
+			if buf.Len() > 1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('"')
+			buf.Write(key)
+			buf.WriteString(`":`)
+			buf.Write(expanded)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte('}')
+		return buf.Bytes(), nil
+	case jsonparser.Array:
+		var (
+			buf bytes.Buffer
+			err error
+		)
+		buf.WriteByte('[')
+		_, arrErr := jsonparser.ArrayEach(value, func(item []byte, itemType jsonparser.ValueType, _ int, _ error) {
+			if err != nil {
+				return
+			}
+			var expanded []byte
+			if expanded, err = r.expand(item, itemType, stack); err != nil {
+				return
+			}
+			if buf.Len() > 1 {
+				buf.WriteByte(',')
+			}
+			buf.Write(expanded)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if arrErr != nil {
+			return nil, errors.InvalidArgument("invalid array in the schema")
+		}
+		buf.WriteByte(']')
+		return buf.Bytes(), nil
+	case jsonparser.String:
+		// the strings are returned without their quotes
+		return append(append([]byte{'"'}, value...), '"'), nil
+	default:
+		return value, nil
+	}
+}
+
+func (r *refResolver) expandRef(value []byte, ref string, stack []string) ([]byte, error) {
+	if _, ok := RefName(ref); !ok {
+		return nil, errors.InvalidArgument("unsupported $ref '%s', only the local definitions are supported", ref)
+	}
+	def, ok := r.definitions[ref]
+	if !ok {
+		return nil, errors.InvalidArgument("$ref '%s' is not defined", ref)
+	}
+	for _, s := range stack {
+		if s == ref {
+			return nil, errors.InvalidArgument("recursive $ref '%s' is not supported", ref)
+		}
+	}
+
+	expanded, err := r.expand(def, jsonparser.Object, append(stack, ref))
+	if err != nil {
+		return nil, err
+	}
+
+	// the keywords next to the reference override the ones of the definition
+	err = jsonparser.ObjectEach(value, func(key []byte, nested []byte, nestedType jsonparser.ValueType, _ int) error {
+		if string(key) == refKey {
+			return nil
+		}
+		sibling, err := r.expand(nested, nestedType, stack)
+		if err != nil {
+			return err
+		}
+		expanded, err = jsonparser.Set(expanded, sibling, string(key))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return expanded, nil
+}
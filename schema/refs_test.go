@@ -0,0 +1,117 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+func TestExpandRefs(t *testing.T) {
+	t.Run("expanded", func(t *testing.T) {
+		reqSchema := []byte(`{
+	"title": "t1",
+	"definitions": {
+		"address": { "type": "object", "properties": { "city": { "type": "string" }, "geo": { "$ref": "#/$defs/geo" } } }
+	},
+	"$defs": {
+		"geo": { "type": "object", "properties": { "lat": { "type": "number" } } }
+	},
+	"properties": {
+		"id": { "type": "integer" },
+		"billing": { "$ref": "#/definitions/address", "description": "billing address" },
+		"previous": { "type": "array", "items": { "$ref": "#/definitions/address" } }
+	},
+	"primary_key": ["id"]
+}`)
+		saved := string(reqSchema)
+
+		expanded, err := ExpandRefs(reqSchema)
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+	"title": "t1",
+	"properties": {
+		"id": { "type": "integer" },
+		"billing": {
+			"type": "object",
+			"properties": { "city": { "type": "string" }, "geo": { "type": "object", "properties": { "lat": { "type": "number" } } } },
+			"description": "billing address"
+		},
+		"previous": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"properties": { "city": { "type": "string" }, "geo": { "type": "object", "properties": { "lat": { "type": "number" } } } }
+			}
+		}
+	},
+	"primary_key": ["id"]
+}`, string(expanded))
+		// the schema of the caller is left as it is
+		require.Equal(t, saved, string(reqSchema))
+	})
+
+	t.Run("no_refs", func(t *testing.T) {
+		reqSchema := []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}}}`)
+		expanded, err := ExpandRefs(reqSchema)
+		require.NoError(t, err)
+		require.Equal(t, reqSchema, []byte(expanded))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		cases := []struct {
+			schema string
+			err    error
+		}{
+			{
+				`{"properties": {"a": {"$ref": "#/definitions/missing"}}}`,
+				errors.InvalidArgument("$ref '#/definitions/missing' is not defined"),
+			}, {
+				`{"properties": {"a": {"$ref": "other.json#/definitions/a"}}}`,
+				errors.InvalidArgument("unsupported $ref 'other.json#/definitions/a', only the local definitions are supported"),
+			}, {
+				`{"definitions": {"a": {"type": "object", "properties": {"b": {"$ref": "#/definitions/a"}}}}, "properties": {"a": {"$ref": "#/definitions/a"}}}`,
+				errors.InvalidArgument("recursive $ref '#/definitions/a' is not supported"),
+			}, {
+				`{"properties": {"a": {"$ref": 1}}}`,
+				errors.InvalidArgument("'$ref' must be a string"),
+			}, {
+				`{"definitions": [], "properties": {"a": {"$ref": "#/definitions/a"}}}`,
+				errors.InvalidArgument("'definitions' of the schema must be an object"),
+			},
+		}
+		for _, c := range cases {
+			_, err := ExpandRefs([]byte(c.schema))
+			require.Equal(t, c.err, err, c.schema)
+		}
+	})
+}
+
+func TestRefName(t *testing.T) {
+	name, ok := RefName("#/definitions/address")
+	require.True(t, ok)
+	require.Equal(t, "address", name)
+
+	name, ok = RefName("#/$defs/a~1b~0c")
+	require.True(t, ok)
+	require.Equal(t, "a/b~c", name)
+
+	for _, ref := range []string{"#/definitions/", "#/properties/a", "other.json"} {
+		_, ok = RefName(ref)
+		require.False(t, ok, ref)
+	}
+}
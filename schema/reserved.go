@@ -14,6 +14,12 @@
 
 package schema
 
+import (
+	"strings"
+
+	"github.com/tigrisdata/tigris/errors"
+)
+
 const (
 	SearchId = "id"
 )
@@ -26,16 +32,24 @@ const (
 	Metadata
 	IdToSearchKey
 	DateSearchKeyPrefix
+	SourceCollection
+	ArrayLengthSearchKeyPrefix
 )
 
 var ReservedFields = [...]string{
-	CreatedAt:           "created_at",
-	UpdatedAt:           "updated_at",
-	Metadata:            "metadata",
-	IdToSearchKey:       "_tigris_id",
-	DateSearchKeyPrefix: "_tigris_date_",
+	CreatedAt:                  "created_at",
+	UpdatedAt:                  "updated_at",
+	Metadata:                   "metadata",
+	IdToSearchKey:              "_tigris_id",
+	DateSearchKeyPrefix:        "_tigris_date_",
+	SourceCollection:           "_tigris_collection",
+	ArrayLengthSearchKeyPrefix: "_tigris_len_",
 }
 
+// BannedFields are the names of the fields the schemas of the new collections can't have, at any nesting level, on
+// top of the reserved fields. The names are compared case-insensitively.
+var BannedFields []string
+
 func IsReservedField(name string) bool {
 	for _, r := range ReservedFields {
 		if r == name {
@@ -46,6 +60,43 @@ func IsReservedField(name string) bool {
 	return false
 }
 
+// IsBannedField returns true if the name is one of the BannedFields.
+func IsBannedField(name string) bool {
+	for _, b := range BannedFields {
+		if strings.EqualFold(b, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckBannedFields rejects the schema of a new collection with a field named after one of the BannedFields, the
+// nested objects and the items of the arrays included. The schemas of the existing collections are not checked, so
+// the collections created before a field is banned can still be updated.
+func CheckBannedFields(factory *Factory) error {
+	if len(BannedFields) == 0 {
+		return nil
+	}
+	return checkBannedFields("", factory.Fields)
+}
+
+func checkBannedFields(parent string, fields []*Field) error {
+	for _, f := range fields {
+		path := buildPath(parent, f.FieldName)
+		if IsBannedField(f.FieldName) {
+			if path == f.FieldName {
+				return errors.InvalidArgument("field '%s' is banned", path)
+			}
+			return errors.InvalidArgument("field name '%s' of the field '%s' is banned", f.FieldName, path)
+		}
+		if err := checkBannedFields(path, f.Fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func IsSearchID(name string) bool {
 	return name == SearchId
 }
@@ -55,3 +106,9 @@ func IsSearchID(name string) bool {
 func ToSearchDateKey(key string) string {
 	return ReservedFields[DateSearchKeyPrefix] + key
 }
+
+// ToSearchArrayLengthKey returns the field of the search backend the number of elements of an array field is indexed
+// under.
+func ToSearchArrayLengthKey(key string) string {
+	return ReservedFields[ArrayLengthSearchKeyPrefix] + key
+}
@@ -18,6 +18,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
 )
 
 func TestIsReservedField(t *testing.T) {
@@ -25,3 +26,37 @@ func TestIsReservedField(t *testing.T) {
 	require.True(t, IsReservedField("updated_at"))
 	require.False(t, IsReservedField("id"))
 }
+
+func TestBannedFields(t *testing.T) {
+	defer func() { BannedFields = nil }()
+	build := func(properties string) error {
+		factory, err := Build("t1", []byte(`{
+	"title": "t1",
+	"properties": { "id": { "type": "integer" }, `+properties+` },
+	"primary_key": ["id"]
+}`), false)
+		require.NoError(t, err)
+		return CheckBannedFields(factory)
+	}
+
+	// nothing is banned by default
+	require.NoError(t, build(`"password": { "type": "string" }`))
+
+	BannedFields = []string{"password", "ssn"}
+	for _, properties := range []string{
+		`"name": { "type": "string" }`,
+		`"password_hash": { "type": "string" }`,
+		`"user": { "type": "object", "properties": { "name": { "type": "string" } } }`,
+	} {
+		require.NoError(t, build(properties), properties)
+	}
+
+	for properties, expected := range map[string]string{
+		`"password": { "type": "string" }`: "field 'password' is banned",
+		`"SSN": { "type": "string" }`:      "field 'SSN' is banned",
+		`"user": { "type": "object", "properties": { "password": { "type": "string" } } }`:                           "field name 'password' of the field 'user.password' is banned",
+		`"users": { "type": "array", "items": { "type": "object", "properties": { "ssn": { "type": "string" } } } }`: "field name 'ssn' of the field 'users.ssn' is banned",
+	} {
+		require.Equal(t, errors.InvalidArgument(expected), build(properties), properties)
+	}
+}
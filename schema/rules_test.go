@@ -65,9 +65,9 @@ func TestApplySchemaRules(t *testing.T) {
 		},
 	}
 	for _, c := range cases {
-		f1, err := Build("t1", c.existing)
+		f1, err := Build("t1", c.existing, false)
 		require.NoError(t, err)
-		f2, err := Build("t1", c.incoming)
+		f2, err := Build("t1", c.incoming, false)
 		require.NoError(t, err)
 
 		existingC := NewDefaultCollection(f1.Name, 1, 1, f1.CollectionType, f1, "f", nil)
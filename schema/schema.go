@@ -23,6 +23,7 @@ import (
 	"github.com/buger/jsonparser"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
 	"github.com/tigrisdata/tigris/lib/container"
 	langSchema "github.com/tigrisdata/tigris/schema/lang"
 	ulog "github.com/tigrisdata/tigris/util/log"
@@ -89,6 +90,9 @@ type JSONSchema struct {
 	PartitionKeys   []string            `json:"key,omitempty"`
 	CollectionType  string              `json:"collection_type,omitempty"`
 	IndexingVersion string              `json:"indexing_version,omitempty"`
+	PreImages       bool                `json:"pre_images,omitempty"`
+	AppendOnly      bool                `json:"x-tigris-append-only,omitempty"`
+	Compression     string              `json:"compression,omitempty"`
 }
 
 // Factory is used as an intermediate step so that collection can be initialized with properly encoded values.
@@ -106,6 +110,12 @@ type Factory struct {
 	// CollectionType is the type of the collection. Only two types of collections are supported "messages" and "documents"
 	CollectionType  CollectionType
 	IndexingVersion string
+	// PreImages stores the documents before the change in the change stream of the collection.
+	PreImages bool
+	// AppendOnly only allows the documents to be inserted in the collection, they can't be updated or deleted.
+	AppendOnly bool
+	// Compression is the codec compressing the documents of the collection at rest.
+	Compression internal.Compression
 }
 
 func RemoveIndexingVersion(schema jsoniter.RawMessage) jsoniter.RawMessage {
@@ -146,8 +156,15 @@ func GetCollectionType(reqSchema jsoniter.RawMessage) (CollectionType, error) {
 	return "", err
 }
 
-// Build is used to deserialize the user json schema into a schema factory.
-func Build(collection string, reqSchema jsoniter.RawMessage) (*Factory, error) {
+// Build is used to deserialize the user json schema into a schema factory. With strictFormats, a format that is not
+// registered fails the build on the fields of any type, otherwise the formats of the fields of the types that don't
+// have formats are ignored. The subschemas of "allOf" are merged, the schema is stored merged.
+func Build(collection string, reqSchema jsoniter.RawMessage, strictFormats bool) (*Factory, error) {
+	reqSchema, err := MergeAllOf(reqSchema)
+	if err != nil {
+		return nil, err
+	}
+
 	cType, err := GetCollectionType(reqSchema)
 	if err != nil {
 		return nil, err
@@ -159,8 +176,14 @@ func Build(collection string, reqSchema jsoniter.RawMessage) (*Factory, error) {
 		}
 	}
 
+	// the fields are built from the expanded schema, the schema is stored with its references
+	expanded, err := ExpandRefs(reqSchema)
+	if err != nil {
+		return nil, err
+	}
+
 	schema := &JSONSchema{}
-	if err = jsoniter.Unmarshal(reqSchema, schema); err != nil {
+	if err = jsoniter.Unmarshal(expanded, schema); err != nil {
 		return nil, errors.Internal(fmt.Errorf("unmarshalling failed %w", err).Error())
 	}
 	if collection != schema.Name {
@@ -176,6 +199,17 @@ func Build(collection string, reqSchema jsoniter.RawMessage) (*Factory, error) {
 		return nil, errors.InvalidArgument("setting primary key is not supported for messages collection")
 	}
 
+	compression, err := internal.ParseCompression(schema.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	if strictFormats {
+		if err = checkFormats("", schema.Properties); err != nil {
+			return nil, err
+		}
+	}
+
 	primaryKeysSet := container.NewHashSet(schema.PrimaryKeys...)
 	partitionKeysSet := container.NewHashSet(schema.PartitionKeys...)
 	fields, err := deserializeProperties(schema.Properties, primaryKeysSet, partitionKeysSet)
@@ -185,10 +219,15 @@ func Build(collection string, reqSchema jsoniter.RawMessage) (*Factory, error) {
 
 	// ordering needs to same as in schema
 	var primaryKeyFields []*Field
-	for _, pkeyField := range schema.PrimaryKeys {
+	for i, pkeyField := range schema.PrimaryKeys {
 		found := false
 		for _, f := range fields {
 			if f.FieldName == pkeyField {
+				// an explicit order on the field must match its position in the primary key
+				if f.PrimaryKeyOrder != 0 && f.PrimaryKeyOrder != i+1 {
+					return nil, errors.InvalidArgument("primary key field '%s' has the order %d, but it is at the position %d of the primary key", pkeyField, f.PrimaryKeyOrder, i+1)
+				}
+				f.PrimaryKeyOrder = i + 1
 				primaryKeyFields = append(primaryKeyFields, f)
 				found = true
 			}
@@ -197,6 +236,11 @@ func Build(collection string, reqSchema jsoniter.RawMessage) (*Factory, error) {
 			return nil, errors.InvalidArgument("missing primary key '%s' field in schema", pkeyField)
 		}
 	}
+	for _, f := range fields {
+		if f.PrimaryKeyOrder != 0 && !f.IsPrimaryKey() {
+			return nil, errors.InvalidArgument("field '%s' has a primary key order, but it is not part of the primary key", f.FieldName)
+		}
+	}
 
 	return &Factory{
 		Fields: fields,
@@ -210,6 +254,9 @@ func Build(collection string, reqSchema jsoniter.RawMessage) (*Factory, error) {
 		Schema:          reqSchema,
 		CollectionType:  cType,
 		IndexingVersion: schema.IndexingVersion,
+		PreImages:       schema.PreImages,
+		AppendOnly:      schema.AppendOnly,
+		Compression:     compression,
 	}, nil
 }
 
@@ -243,6 +290,51 @@ func setPrimaryKey(reqSchema jsoniter.RawMessage, format string, ifMissing bool)
 	return jsoniter.Marshal(schema)
 }
 
+func hasImmutableField(fields []*Field) bool {
+	for _, f := range fields {
+		if f.IsImmutable() || hasImmutableField(f.Fields) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasComputedField(fields []*Field) bool {
+	for _, f := range fields {
+		if f.IsComputed() || hasComputedField(f.Fields) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasDefaultOrAlias returns true if one of the fields, the nested fields included, has a default or aliases.
+func hasDefaultOrAlias(fields []*Field) bool {
+	for _, f := range fields {
+		if f.Default != nil || len(f.Aliases) > 0 || hasDefaultOrAlias(f.Fields) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAliases rejects the aliases matching the name or an alias of another field of the same object.
+func checkAliases(fields []*Field) error {
+	names := make(map[string]string)
+	for _, f := range fields {
+		names[f.FieldName] = f.FieldName
+	}
+	for _, f := range fields {
+		for _, alias := range f.Aliases {
+			if other, ok := names[alias]; ok {
+				return errors.InvalidArgument("alias '%s' of the field '%s' is already used by the field '%s'", alias, f.FieldName, other)
+			}
+			names[alias] = f.FieldName
+		}
+	}
+	return nil
+}
+
 func deserializeProperties(properties jsoniter.RawMessage, primaryKeysSet container.HashSet, partitionKeysSet container.HashSet) ([]*Field, error) {
 	var fields []*Field
 	var err error
@@ -265,6 +357,9 @@ func deserializeProperties(properties jsoniter.RawMessage, primaryKeysSet contai
 		if builder.Type == jsonSpecArray && builder.Items == nil {
 			return errors.InvalidArgument("missing items for array field")
 		}
+		if err = builder.validateDefault(v); err != nil {
+			return err
+		}
 
 		if builder.Items != nil {
 			// for arrays, items must be set, and it is possible that item type is object in that case deserialize those
@@ -279,6 +374,15 @@ func deserializeProperties(properties jsoniter.RawMessage, primaryKeysSet contai
 				if nestedFields, err = deserializeProperties(builder.Items.Properties, primaryKeysSet, partitionKeysSet); err != nil {
 					return err
 				}
+				if hasImmutableField(nestedFields) {
+					return errors.InvalidArgument("the items of an array can't be immutable, set it on the array field '%s' instead", builder.FieldName)
+				}
+				if hasComputedField(nestedFields) {
+					return errors.InvalidArgument("the items of an array can't be computed, array field '%s'", builder.FieldName)
+				}
+				if hasDefaultOrAlias(nestedFields) {
+					return errors.InvalidArgument("the items of an array can't have defaults or aliases, array field '%s'", builder.FieldName)
+				}
 				builder.Fields[0].Fields = nestedFields
 			} else {
 				var current *Field
@@ -329,6 +433,9 @@ func deserializeProperties(properties jsoniter.RawMessage, primaryKeysSet contai
 	if err != nil {
 		return nil, err
 	}
+	if err = checkAliases(fields); err != nil {
+		return nil, err
+	}
 
 	return fields, nil
 }
@@ -15,17 +15,19 @@
 package schema
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
 )
 
 func TestCreateCollectionFromSchema(t *testing.T) {
 	t.Run("test_create_success", func(t *testing.T) {
 		reqSchema := []byte(`{"title":"t1", "description":"This document records the details of an order","properties":{"order_id":{"description":"A unique identifier for an order","type":"integer"},"cust_id":{"description":"A unique identifier for a customer","type":"integer"},"product":{"description":"name of the product","type":"string","maxLength":100},"quantity":{"description":"number of products ordered","type":"integer"},"price":{"description":"price of the product","type":"number"}},"primary_key":["cust_id","order_id"]}`)
-		schF, err := Build("t1", reqSchema)
+		schF, err := Build("t1", reqSchema, false)
 		require.NoError(t, err)
 		c := NewDefaultCollection("t1", 1, 1, schF.CollectionType, schF, "t1", nil)
 		require.Equal(t, c.Name, "t1")
@@ -34,7 +36,7 @@ func TestCreateCollectionFromSchema(t *testing.T) {
 	})
 	t.Run("test_create_failure", func(t *testing.T) {
 		reqSchema := []byte(`{"title":"Record of an order","properties":{"order_id":{"description":"A unique identifier for an order","type":"integer"},"cust_id":{"description":"A unique identifier for a customer","type":"integer"},"product":{"description":"name of the product","type":"string","maxLength":100},"quantity":{"description":"number of products ordered","type":"integer"},"price":{"description":"price of the product","type":"number"}},"primary_key":["cust_id","order_id"]}`)
-		_, err := Build("t1", reqSchema)
+		_, err := Build("t1", reqSchema, false)
 		require.Equal(t, "collection name is not same as schema name 't1' 'Record of an order'", err.(*api.TigrisError).Error())
 	})
 	t.Run("test_supported_types", func(t *testing.T) {
@@ -68,7 +70,7 @@ func TestCreateCollectionFromSchema(t *testing.T) {
 	},
 	"primary_key": ["K1", "K2"]
 }`)
-		sch, err := Build("t1", schema)
+		sch, err := Build("t1", schema, false)
 		require.NoError(t, err)
 		c := NewDefaultCollection("t1", 1, 1, sch.CollectionType, sch, "t1", nil)
 		fields := c.GetFields()
@@ -105,7 +107,7 @@ func TestCreateCollectionFromSchema(t *testing.T) {
 	},
 	"primary_key": ["K1", "K2", "K3", "K4", "K5"]
 }`)
-		sch, err := Build("t1", schema)
+		sch, err := Build("t1", schema, false)
 		require.NoError(t, err)
 		c := NewDefaultCollection("t1", 1, 1, sch.CollectionType, sch, "t1", nil)
 		require.NoError(t, err)
@@ -128,7 +130,7 @@ func TestCreateCollectionFromSchema(t *testing.T) {
 		},
 		"primary_key": ["K1"]
 	}`)
-		_, err := Build("t1", schema)
+		_, err := Build("t1", schema, false)
 		require.Equal(t, "unsupported primary key type detected 'number'", err.(*api.TigrisError).Error())
 	})
 	t.Run("test_complex_types", func(t *testing.T) {
@@ -185,7 +187,7 @@ func TestCreateCollectionFromSchema(t *testing.T) {
 	},
 	"primary_key": ["id"]
 }`)
-		sch, err := Build("t1", schema)
+		sch, err := Build("t1", schema, false)
 		require.NoError(t, err)
 		coll := NewDefaultCollection("t1", 1, 1, sch.CollectionType, sch, "t1", nil)
 		require.Equal(t, "simple_items", coll.Fields[4].FieldName)
@@ -221,7 +223,7 @@ func TestCreateCollectionFromSchema(t *testing.T) {
 	},
 	"primary_key": ["id"]
 }`)
-		_, err := Build("t1", schema)
+		_, err := Build("t1", schema, false)
 		require.Equal(t, errors.InvalidArgument("missing items for array field"), err)
 	})
 	t.Run("test_object_missing_properties_error", func(t *testing.T) {
@@ -237,7 +239,7 @@ func TestCreateCollectionFromSchema(t *testing.T) {
 	},
 	"primary_key": ["id"]
 }`)
-		sch, err := Build("t1", schema)
+		sch, err := Build("t1", schema, false)
 		require.NoError(t, err)
 		c := NewDefaultCollection("t1", 1, 1, sch.CollectionType, sch, "t1", nil)
 		fields := c.GetFields()
@@ -266,7 +268,7 @@ func TestCreateCollectionFromSchema(t *testing.T) {
 	},
 	"primary_key": ["K1", "K2"]
 }`)
-		sch, err := Build("t1", schema)
+		sch, err := Build("t1", schema, false)
 		require.NoError(t, err)
 		c := NewDefaultCollection("t1", 1, 1, sch.CollectionType, sch, "t1", nil)
 		fields := c.GetFields()
@@ -288,7 +290,7 @@ func TestCreateCollectionFromSchema(t *testing.T) {
 		}
 	}
 }`)
-		sch, err := Build("t1", schema)
+		sch, err := Build("t1", schema, false)
 		require.NoError(t, err)
 		c := NewDefaultCollection("t1", 1, 1, sch.CollectionType, sch, "t1", nil)
 		fields := c.GetFields()
@@ -319,7 +321,7 @@ func TestCreateCollectionFromSchema(t *testing.T) {
 		}
 	}
 }`)
-		sch, err := Build("t1", schema)
+		sch, err := Build("t1", schema, false)
 		require.NoError(t, err)
 		c := NewDefaultCollection("t1", 1, 1, sch.CollectionType, sch, "t1", nil)
 		fields := c.GetFields()
@@ -356,7 +358,7 @@ func TestCreateCollectionFromSchema(t *testing.T) {
 		}
 	}
 }`)
-		sch, err := Build("t1", schema)
+		sch, err := Build("t1", schema, false)
 		require.NoError(t, err)
 		c := NewDefaultCollection("t1", 1, 1, sch.CollectionType, sch, "t1", nil)
 		fields := c.GetFields()
@@ -405,3 +407,177 @@ func TestGetCollectionType(t *testing.T) {
 	require.Equal(t, TopicType, ty)
 	require.NoError(t, err)
 }
+
+func TestPreImages(t *testing.T) {
+	reqSchema := []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": {
+			"type": "integer"
+		}
+	},
+	"primary_key": ["id"],
+	"pre_images": true
+}`)
+
+	factory, err := Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	require.True(t, factory.PreImages)
+	require.True(t, NewDefaultCollection("t1", 1, 1, DocumentsType, factory, "t1", nil).PreImages)
+
+	factory, err = Build("t1", []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}}, "primary_key": ["id"]}`), false)
+	require.NoError(t, err)
+	require.False(t, NewDefaultCollection("t1", 1, 1, DocumentsType, factory, "t1", nil).PreImages)
+}
+
+func TestCompression(t *testing.T) {
+	reqSchema := []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": {
+			"type": "integer"
+		}
+	},
+	"primary_key": ["id"],
+	"compression": "zstd"
+}`)
+
+	factory, err := Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	require.Equal(t, internal.ZstdCompression, factory.Compression)
+	require.Equal(t, internal.ZstdCompression, NewDefaultCollection("t1", 1, 1, DocumentsType, factory, "t1", nil).Compression)
+
+	factory, err = Build("t1", []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}}, "primary_key": ["id"]}`), false)
+	require.NoError(t, err)
+	require.Equal(t, internal.NoCompression, NewDefaultCollection("t1", 1, 1, DocumentsType, factory, "t1", nil).Compression)
+
+	_, err = Build("t1", []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}}, "primary_key": ["id"], "compression": "lz4"}`), false)
+	require.Equal(t, errors.InvalidArgument("unsupported compression 'lz4', supported are 'none', 'zstd' and 'snappy'"), err)
+}
+
+func TestAppendOnly(t *testing.T) {
+	reqSchema := []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": {
+			"type": "integer"
+		}
+	},
+	"primary_key": ["id"],
+	"x-tigris-append-only": true
+}`)
+
+	factory, err := Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	require.True(t, factory.AppendOnly)
+	require.True(t, NewDefaultCollection("t1", 1, 1, DocumentsType, factory, "t1", nil).AppendOnly)
+
+	factory, err = Build("t1", []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}}, "primary_key": ["id"]}`), false)
+	require.NoError(t, err)
+	require.False(t, NewDefaultCollection("t1", 1, 1, DocumentsType, factory, "t1", nil).AppendOnly)
+}
+
+func TestDeprecated(t *testing.T) {
+	reqSchema := []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": { "type": "integer" },
+		"name": { "type": "string", "x-tigris-deprecated": true },
+		"address": {
+			"type": "object",
+			"properties": {
+				"city": { "type": "string" },
+				"zip": { "type": "string", "x-tigris-deprecated": true }
+			}
+		}
+	},
+	"primary_key": ["id"]
+}`)
+
+	factory, err := Build("t1", reqSchema, false)
+	require.NoError(t, err)
+
+	deprecated := map[string]bool{}
+	for _, f := range factory.Fields {
+		deprecated[f.FieldName] = f.IsDeprecated()
+		for _, nested := range f.Fields {
+			deprecated[f.FieldName+"."+nested.FieldName] = nested.IsDeprecated()
+		}
+	}
+	require.Equal(t, map[string]bool{
+		"id":           false,
+		"name":         true,
+		"address":      false,
+		"address.city": false,
+		"address.zip":  true,
+	}, deprecated)
+}
+
+func TestStrictFormats(t *testing.T) {
+	build := func(properties string, strict bool) error {
+		_, err := Build("t1", []byte(fmt.Sprintf(`{
+	"title": "t1",
+	"properties": { "id": { "type": "integer" }, %s },
+	"primary_key": ["id"]
+}`, properties)), strict)
+		return err
+	}
+
+	for _, properties := range []string{
+		`"random_binary": { "type": "string", "format": "" }`,
+		`"uuid": { "type": "string", "format": "uuid" }`,
+		`"created": { "type": "string", "format": "date-time" }`,
+		`"day": { "type": "string", "format": "date" }`,
+		`"email": { "type": "string", "format": "email" }`,
+		`"small": { "type": "integer", "format": "int32" }`,
+		`"data": { "type": "string", "format": "byte" }`,
+		`"ids": { "type": "array", "items": { "type": "string", "format": "uuid" } }`,
+	} {
+		require.NoError(t, build(properties, true), properties)
+	}
+
+	for properties, expected := range map[string]string{
+		`"uuid": { "type": "string", "format": "uuidd" }`:                                         "unknown format 'uuidd' of the field 'uuid'",
+		`"price": { "type": "number", "format": "money" }`:                                        "unknown format 'money' of the field 'price'",
+		`"ids": { "type": "array", "items": { "type": "string", "format": "uid" } }`:              "unknown format 'uid' of the field 'ids'",
+		`"obj": { "type": "object", "properties": { "a": { "type": "number", "format": "x" } } }`: "unknown format 'x' of the field 'obj.a'",
+	} {
+		require.Equal(t, errors.InvalidArgument(expected), build(properties, true), properties)
+	}
+
+	// the formats of the fields of the types that don't have formats are ignored in the lenient mode
+	require.NoError(t, build(`"price": { "type": "number", "format": "money" }`, false))
+	require.Equal(t, errors.InvalidArgument("unsupported format 'uuidd'"), build(`"uuid": { "type": "string", "format": "uuidd" }`, false))
+}
+
+func TestPrimaryKeyOrder(t *testing.T) {
+	t.Run("implicit", func(t *testing.T) {
+		factory, err := Build("t1", []byte(`{"title": "t1", "properties": {"int_field": {"type": "integer"}, "string_field": {"type": "string"}}, "primary_key": ["int_field"]}`), false)
+		require.NoError(t, err)
+		require.Len(t, factory.Indexes.PrimaryKey.Fields, 1)
+		require.Equal(t, "int_field", factory.Indexes.PrimaryKey.Fields[0].FieldName)
+		require.Equal(t, 1, factory.Indexes.PrimaryKey.Fields[0].PrimaryKeyOrder)
+	})
+	t.Run("consistent", func(t *testing.T) {
+		factory, err := Build("t1", []byte(`{"title": "t1", "properties": {"int_field": {"type": "integer", "primaryKey": 1}, "string_field": {"type": "string"}}, "primary_key": ["int_field"]}`), false)
+		require.NoError(t, err)
+		require.Equal(t, 1, factory.Indexes.PrimaryKey.Fields[0].PrimaryKeyOrder)
+
+		factory, err = Build("t1", []byte(`{"title": "t1", "properties": {"int_field": {"type": "integer", "primaryKey": 2}, "string_field": {"type": "string", "primaryKey": 1}}, "primary_key": ["string_field", "int_field"]}`), false)
+		require.NoError(t, err)
+		require.Equal(t, "string_field", factory.Indexes.PrimaryKey.Fields[0].FieldName)
+		require.Equal(t, 1, factory.Indexes.PrimaryKey.Fields[0].PrimaryKeyOrder)
+		require.Equal(t, "int_field", factory.Indexes.PrimaryKey.Fields[1].FieldName)
+		require.Equal(t, 2, factory.Indexes.PrimaryKey.Fields[1].PrimaryKeyOrder)
+	})
+	t.Run("conflicting", func(t *testing.T) {
+		_, err := Build("t1", []byte(`{"title": "t1", "properties": {"int_field": {"type": "integer", "primaryKey": 2}, "string_field": {"type": "string"}}, "primary_key": ["int_field"]}`), false)
+		require.Equal(t, errors.InvalidArgument("primary key field 'int_field' has the order 2, but it is at the position 1 of the primary key"), err)
+
+		_, err = Build("t1", []byte(`{"title": "t1", "properties": {"int_field": {"type": "integer"}, "string_field": {"type": "string", "primaryKey": 1}}, "primary_key": ["int_field"]}`), false)
+		require.Equal(t, errors.InvalidArgument("field 'string_field' has a primary key order, but it is not part of the primary key"), err)
+
+		_, err = Build("t1", []byte(`{"title": "t1", "properties": {"int_field": {"type": "integer", "primaryKey": 0}}, "primary_key": ["int_field"]}`), false)
+		require.Equal(t, errors.InvalidArgument("primary key order of the field 'int_field' must be greater than 0"), err)
+	})
+}
@@ -0,0 +1,133 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/buger/jsonparser"
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	// WarningNoDescription is reported for a field without a description.
+	WarningNoDescription = "field has no description"
+	// WarningEmptyFormat is reported for a field with the format set to an empty string.
+	WarningEmptyFormat = "format is an empty string"
+	// WarningDeprecatedFormat is reported for a field using a format that is only kept for backward compatibility.
+	WarningDeprecatedFormat = "format '%s' is deprecated, use %s"
+	// WarningUnusedDefinition is reported for a definition that is not referenced in the schema.
+	WarningUnusedDefinition = "definition is not used"
+)
+
+// deprecatedFormats are the formats that are still accepted, mapped to their replacement.
+var deprecatedFormats = map[string]string{
+	jsonSpecFormatByte: `"contentEncoding": "base64"`,
+}
+
+// definitionKeywords are the keywords that hold the definitions of a schema.
+var definitionKeywords = []string{"$defs", "definitions"}
+
+// Warning is a non-fatal issue found in the user schema. Warnings don't stop the schema from being built, they are
+// only meant for linting.
+type Warning struct {
+	// Path is the dotted path of the field or the definition the warning is about.
+	Path string
+	// Message describes the issue.
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s", w.Path, w.Message)
+}
+
+// BuildWithWarnings is same as Build but additionally returns the non-fatal warnings found in the schema. The warnings
+// are only returned if the schema is valid.
+func BuildWithWarnings(collection string, reqSchema jsoniter.RawMessage) (*Factory, []Warning, error) {
+	factory, err := Build(collection, reqSchema, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the user schema is linted, not the one from the factory, so that the fields added by the server are skipped
+	var warnings []Warning
+	if properties, dt, _, _ := jsonparser.Get(reqSchema, "properties"); dt == jsonparser.Object {
+		warnings = lintProperties("", properties, warnings)
+	}
+	warnings = lintDefinitions(reqSchema, warnings)
+
+	return factory, warnings, nil
+}
+
+func lintProperties(parent string, properties []byte, warnings []Warning) []Warning {
+	_ = jsonparser.ObjectEach(properties, func(key []byte, v []byte, dataType jsonparser.ValueType, _ int) error {
+		if dataType != jsonparser.Object {
+			return nil
+		}
+
+		warnings = lintField(buildPath(parent, string(key)), v, warnings)
+		return nil
+	})
+
+	return warnings
+}
+
+func lintField(path string, field []byte, warnings []Warning) []Warning {
+	if description, _ := jsonparser.GetString(field, "description"); len(description) == 0 {
+		warnings = append(warnings, Warning{Path: path, Message: WarningNoDescription})
+	}
+
+	if format, dt, _, err := jsonparser.Get(field, "format"); err == nil && dt == jsonparser.String {
+		if len(format) == 0 {
+			warnings = append(warnings, Warning{Path: path, Message: WarningEmptyFormat})
+		} else if replacement, ok := deprecatedFormats[string(format)]; ok {
+			warnings = append(warnings, Warning{Path: path, Message: fmt.Sprintf(WarningDeprecatedFormat, format, replacement)})
+		}
+	}
+
+	if properties, dt, _, _ := jsonparser.Get(field, "properties"); dt == jsonparser.Object {
+		warnings = lintProperties(path, properties, warnings)
+	}
+
+	// items of an array don't have a name, for objects the nested fields have the path of the array as parent and the
+	// description is only expected on the array itself
+	if items, dt, _, _ := jsonparser.Get(field, "items"); dt == jsonparser.Object {
+		if properties, dt, _, _ := jsonparser.Get(items, "properties"); dt == jsonparser.Object {
+			warnings = lintProperties(path, properties, warnings)
+		}
+	}
+
+	return warnings
+}
+
+func lintDefinitions(reqSchema []byte, warnings []Warning) []Warning {
+	for _, keyword := range definitionKeywords {
+		definitions, dt, _, _ := jsonparser.Get(reqSchema, keyword)
+		if dt != jsonparser.Object {
+			continue
+		}
+
+		_ = jsonparser.ObjectEach(definitions, func(key []byte, _ []byte, _ jsonparser.ValueType, _ int) error {
+			ref := fmt.Sprintf(`"#/%s/%s"`, keyword, key)
+			if !bytes.Contains(reqSchema, []byte(ref)) {
+				warnings = append(warnings, Warning{Path: keyword + "." + string(key), Message: WarningUnusedDefinition})
+			}
+			return nil
+		})
+	}
+
+	return warnings
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildWithWarnings(t *testing.T) {
+	t.Run("no_warnings", func(t *testing.T) {
+		reqSchema := []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": { "type": "integer", "description": "identifier" },
+		"name": { "type": "string", "description": "name of the item" }
+	},
+	"primary_key": ["id"]
+}`)
+		factory, warnings, err := BuildWithWarnings("t1", reqSchema)
+		require.NoError(t, err)
+		require.NotNil(t, factory)
+		require.Empty(t, warnings)
+	})
+
+	t.Run("missing_descriptions", func(t *testing.T) {
+		reqSchema := []byte(`{
+	"title": "t1",
+	"properties": {
+		"name": { "type": "string" },
+		"address": {
+			"type": "object",
+			"description": "postal address",
+			"properties": {
+				"city": { "type": "string" }
+			}
+		},
+		"tags": {
+			"type": "array",
+			"description": "list of tags",
+			"items": {
+				"type": "object",
+				"properties": {
+					"label": { "type": "string", "description": "tag label" },
+					"weight": { "type": "integer" }
+				}
+			}
+		}
+	}
+}`)
+		factory, warnings, err := BuildWithWarnings("t1", reqSchema)
+		require.NoError(t, err)
+		require.NotNil(t, factory)
+		// the auto-generated primary key is not part of the warnings
+		require.Equal(t, []Warning{
+			{Path: "name", Message: WarningNoDescription},
+			{Path: "address.city", Message: WarningNoDescription},
+			{Path: "tags.weight", Message: WarningNoDescription},
+		}, warnings)
+	})
+
+	t.Run("formats", func(t *testing.T) {
+		reqSchema := []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": { "type": "integer", "description": "identifier" },
+		"random_binary": { "type": "string", "format": "", "description": "random bytes" },
+		"raw": { "type": "string", "format": "byte", "description": "raw bytes" }
+	},
+	"primary_key": ["id"]
+}`)
+		_, warnings, err := BuildWithWarnings("t1", reqSchema)
+		require.NoError(t, err)
+		require.Equal(t, []Warning{
+			{Path: "random_binary", Message: WarningEmptyFormat},
+			{Path: "raw", Message: `format 'byte' is deprecated, use "contentEncoding": "base64"`},
+		}, warnings)
+		require.Equal(t, "random_binary: format is an empty string", warnings[0].String())
+	})
+
+	t.Run("unused_definitions", func(t *testing.T) {
+		reqSchema := []byte(`{
+	"title": "t1",
+	"$defs": {
+		"address": { "type": "object" }
+	},
+	"definitions": {
+		"tag": { "type": "string" }
+	},
+	"properties": {
+		"id": { "type": "integer", "description": "identifier" }
+	},
+	"primary_key": ["id"]
+}`)
+		_, warnings, err := BuildWithWarnings("t1", reqSchema)
+		require.NoError(t, err)
+		require.Equal(t, []Warning{
+			{Path: "$defs.address", Message: WarningUnusedDefinition},
+			{Path: "definitions.tag", Message: WarningUnusedDefinition},
+		}, warnings)
+
+		// referenced definitions are not reported
+		warnings = lintDefinitions([]byte(`{
+	"$defs": { "address": { "type": "object" }, "phone": { "type": "string" } },
+	"properties": { "home": { "$ref": "#/$defs/address" } }
+}`), nil)
+		require.Equal(t, []Warning{{Path: "$defs.phone", Message: WarningUnusedDefinition}}, warnings)
+	})
+
+	t.Run("invalid_schema", func(t *testing.T) {
+		factory, warnings, err := BuildWithWarnings("t1", []byte(`{"title": "t1"}`))
+		require.Error(t, err)
+		require.Nil(t, factory)
+		require.Nil(t, warnings)
+	})
+}
@@ -0,0 +1,299 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"bytes"
+	"context"
+	"path"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/tigrisdata/tigris/server/request"
+	"github.com/tigrisdata/tigris/server/transaction"
+	ulog "github.com/tigrisdata/tigris/util/log"
+)
+
+// Principal is an authenticated caller, the role of its token is only used if no role is assigned to it in its
+// namespace.
+type Principal struct {
+	Namespace string
+	Sub       string
+	Role      string
+}
+
+// Manager authorizes the calls of the principals. The roles assigned in the namespaces are cached, so that a call
+// doesn't read the metadata. The version of the role assignments is checked in the background, the cached roles are
+// dropped once it changes, so that a role changed on another server is enforced within the refresh interval.
+type Manager struct {
+	cfg       *config.AuthzConfig
+	logOnly   bool
+	tenantMgr *metadata.TenantManager
+	txMgr     *transaction.Manager
+	store     *Store
+	cache     *lru.Cache
+	now       func() time.Time
+	// readRole reads the role assigned to the principal with the version of the role assignments it is read at, the
+	// role is only cached if it is read from the namespace
+	readRole func(ctx context.Context, principal *Principal) (Role, metadata.Version, bool, error)
+	// readVersion reads the current version of the role assignments
+	readVersion func(ctx context.Context) (metadata.Version, error)
+
+	sync.RWMutex
+	version metadata.Version
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type cachedRole struct {
+	role     Role
+	version  metadata.Version
+	expireAt time.Time
+}
+
+var mgr *Manager
+
+func NewManager(tm *metadata.TenantManager, txMgr *transaction.Manager, store *Store, cfg *config.Config) (*Manager, error) {
+	if _, err := ParseRole(cfg.Auth.Authz.DefaultRole); err != nil {
+		return nil, err
+	}
+	cache, err := lru.New(cfg.Auth.Authz.CacheSize)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		cfg:       &cfg.Auth.Authz,
+		logOnly:   cfg.Auth.LogOnly,
+		tenantMgr: tm,
+		txMgr:     txMgr,
+		store:     store,
+		cache:     cache,
+		now:       time.Now,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	m.readRole = m.readNamespaceRole
+	m.readVersion = m.readStoreVersion
+	return m, nil
+}
+
+// Init enables the authorization of the calls, it is only enforced when the authentication is enabled as well.
+func Init(tm *metadata.TenantManager, txMgr *transaction.Manager, cfg *config.Config) error {
+	if !cfg.Auth.Enabled || !cfg.Auth.Authz.Enabled {
+		mgr = nil
+		return nil
+	}
+
+	m, err := NewManager(tm, txMgr, NewStore(metadata.NewUserStore(&metadata.DefaultMDNameRegistry{})), cfg)
+	if err != nil {
+		return err
+	}
+	mgr = m
+	mgr.Start()
+	return nil
+}
+
+// Cleanup stops the background refresh of the authorization.
+func Cleanup() {
+	if mgr != nil {
+		mgr.Stop()
+	}
+}
+
+// Authorize checks that the principal of the call has the role required by the method. The calls without a principal
+// are not authorized here, they are rejected by the authentication.
+func Authorize(ctx context.Context, method string) error {
+	if mgr == nil {
+		return nil
+	}
+
+	token, err := request.GetAccessToken(ctx)
+	if err != nil {
+		return nil
+	}
+	return mgr.Authorize(ctx, method, &Principal{Namespace: token.Namespace, Sub: token.Sub, Role: token.Role})
+}
+
+// Invalidate drops the cached role of the principal once its role assignment changed on this server, the other servers
+// drop it once they see the new version of the role assignments.
+func Invalidate(namespace string, sub string) {
+	if mgr != nil {
+		mgr.Invalidate(namespace, sub)
+	}
+}
+
+func (m *Manager) Authorize(ctx context.Context, method string, principal *Principal) error {
+	required := RequiredRole(method)
+	role, cached, err := m.role(ctx, principal)
+	if err != nil {
+		return err
+	}
+
+	allowed := role.Allows(required)
+	metrics.CountAuthzDecision(method, string(role), allowed, cached)
+	if allowed {
+		return nil
+	}
+
+	log.Debug().Str("namespace", principal.Namespace).Str("sub", principal.Sub).Str("method", method).
+		Str("role", string(role)).Str("required_role", string(required)).Msg("call not authorized")
+	if m.logOnly {
+		return nil
+	}
+	return errors.PermissionDenied("the '%s' role is required for %s, the role of the caller is '%s'",
+		required, path.Base(method), role)
+}
+
+func (m *Manager) Invalidate(namespace string, sub string) {
+	m.cache.Remove(cacheKey(namespace, sub))
+}
+
+// Start starts the background refresh of the version of the role assignments.
+func (m *Manager) Start() {
+	m.wg.Add(1)
+	go m.refreshLoop()
+}
+
+// Stop stops the background refresh and waits for the refresh in progress to return.
+func (m *Manager) Stop() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+func (m *Manager) refreshLoop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		m.refresh(m.ctx)
+
+		select {
+		case <-ticker.C:
+		case <-m.ctx.Done():
+			return
+		}
+	}
+}
+
+// refresh reads the version of the role assignments and drops the cached roles if it changed since the last refresh.
+func (m *Manager) refresh(ctx context.Context) {
+	version, err := m.readVersion(ctx)
+	if ulog.E(err) {
+		return
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	if !bytes.Equal(m.version, version) {
+		m.version = version
+		m.cache.Purge()
+	}
+}
+
+func (m *Manager) currentVersion() metadata.Version {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.version
+}
+
+// role returns the role of the principal, and whether the role assigned in the namespace was cached.
+func (m *Manager) role(ctx context.Context, principal *Principal) (Role, bool, error) {
+	assigned, cached, err := m.assignedRole(ctx, principal)
+	if err != nil {
+		return "", false, err
+	}
+	if assigned != "" {
+		return assigned, cached, nil
+	}
+	if principal.Role != "" {
+		if role, err := ParseRole(principal.Role); err == nil {
+			return role, cached, nil
+		}
+		log.Warn().Str("namespace", principal.Namespace).Str("role", principal.Role).Msg("unknown role in the token")
+	}
+	return Role(m.cfg.DefaultRole), cached, nil
+}
+
+func (m *Manager) assignedRole(ctx context.Context, principal *Principal) (Role, bool, error) {
+	key := cacheKey(principal.Namespace, principal.Sub)
+	if entry, ok := m.cache.Get(key); ok {
+		// a role read before the last refresh saw the version change may have been cached after the cache was purged
+		if c := entry.(*cachedRole); m.now().Before(c.expireAt) && bytes.Equal(c.version, m.currentVersion()) {
+			return c.role, true, nil
+		}
+	}
+
+	role, version, cacheable, err := m.readRole(ctx, principal)
+	if err != nil {
+		return "", false, err
+	}
+	if cacheable {
+		m.cache.Add(key, &cachedRole{role: role, version: version, expireAt: m.now().Add(m.cfg.CacheTTL)})
+	}
+	return role, false, nil
+}
+
+func (m *Manager) readNamespaceRole(ctx context.Context, principal *Principal) (Role, metadata.Version, bool, error) {
+	tenant, err := m.tenantMgr.GetTenant(ctx, principal.Namespace)
+	if errors.Is(err, metadata.ErrNamespaceNotFound) {
+		// no role is assigned in a namespace that doesn't exist, it is not cached to be read once it is created
+		return "", nil, false, nil
+	}
+	if err != nil {
+		return "", nil, false, err
+	}
+
+	tx, err := m.txMgr.StartTx(ctx)
+	if err != nil {
+		return "", nil, false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// the version is read in the transaction of the role, so that the role is cached with the version it is valid for
+	version, err := m.store.Version(ctx, tx)
+	if err != nil {
+		return "", nil, false, err
+	}
+	role, err := m.store.GetRole(ctx, tx, tenant.GetNamespace().Id(), principal.Sub)
+	return role, version, err == nil, err
+}
+
+func (m *Manager) readStoreVersion(ctx context.Context) (metadata.Version, error) {
+	tx, err := m.txMgr.StartTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	return m.store.Version(ctx, tx)
+}
+
+func cacheKey(namespace string, sub string) string {
+	return namespace + "/" + sub
+}
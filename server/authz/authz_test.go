@@ -0,0 +1,131 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/uber-go/tally"
+)
+
+func TestManagerAuthorize(t *testing.T) {
+	defer func() { metrics.AuthzCount = nil }()
+	testScope := tally.NewTestScope("", nil)
+	metrics.AuthzCount = testScope
+
+	cfg := config.DefaultConfig
+	cfg.Auth.LogOnly = false
+	m, err := NewManager(nil, nil, nil, &cfg)
+	require.NoError(t, err)
+
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	assigned, reads := map[string]Role{"ns1/editor": RoleEditor}, 0
+	m.readRole = func(_ context.Context, principal *Principal) (Role, metadata.Version, bool, error) {
+		reads++
+		return assigned[cacheKey(principal.Namespace, principal.Sub)], nil, true, nil
+	}
+	ctx := context.Background()
+
+	editor := &Principal{Namespace: "ns1", Sub: "editor"}
+	require.NoError(t, m.Authorize(ctx, api.InsertMethodName, editor))
+	err = m.Authorize(ctx, api.DropDatabaseMethodName, editor)
+	require.Equal(t, api.Code_PERMISSION_DENIED, err.(*api.TigrisError).Code)
+	require.Equal(t, "the 'admin' role is required for DropDatabase, the role of the caller is 'editor'", err.Error())
+	require.Equal(t, 1, reads)
+
+	// the role assigned in the namespace takes precedence over the role of the token
+	require.Error(t, m.Authorize(ctx, api.DropDatabaseMethodName, &Principal{Namespace: "ns1", Sub: "editor", Role: "admin"}))
+	require.NoError(t, m.Authorize(ctx, api.DropDatabaseMethodName, &Principal{Namespace: "ns1", Sub: "other", Role: "admin"}))
+
+	// the default role applies without an assignment or a role in the token
+	reader := &Principal{Namespace: "ns1", Sub: "reader"}
+	require.NoError(t, m.Authorize(ctx, api.ReadMethodName, reader))
+	require.Equal(t, "the 'editor' role is required for Insert, the role of the caller is 'reader'",
+		m.Authorize(ctx, api.InsertMethodName, reader).Error())
+
+	// the cached roles are read again once invalidated or expired
+	reads = 0
+	assigned["ns1/reader"] = RoleAdmin
+	require.Error(t, m.Authorize(ctx, api.InsertMethodName, reader))
+	m.Invalidate("ns1", "reader")
+	require.NoError(t, m.Authorize(ctx, api.InsertMethodName, reader))
+	require.Equal(t, 1, reads)
+	assigned["ns1/reader"] = RoleReader
+	now = now.Add(cfg.Auth.Authz.CacheTTL)
+	require.Error(t, m.Authorize(ctx, api.InsertMethodName, reader))
+	require.Equal(t, 2, reads)
+
+	// the denied calls are only logged in the log only mode
+	m.logOnly = true
+	require.NoError(t, m.Authorize(ctx, api.InsertMethodName, reader))
+
+	counters := testScope.Snapshot().Counters()
+	require.Equal(t, int64(1), counters["allowed+cached=false,grpc_method=/tigrisdata.v1.Tigris/Insert,role=editor"].Value())
+	require.Equal(t, int64(2), counters["denied+cached=true,grpc_method=/tigrisdata.v1.Tigris/DropDatabase,role=editor"].Value())
+	require.Equal(t, int64(1), counters["allowed+cached=false,grpc_method=/tigrisdata.v1.Tigris/Insert,role=admin"].Value())
+}
+
+func TestManagerRefresh(t *testing.T) {
+	cfg := config.DefaultConfig
+	cfg.Auth.LogOnly = false
+	m, err := NewManager(nil, nil, nil, &cfg)
+	require.NoError(t, err)
+
+	var version metadata.Version
+	assigned, reads := map[string]Role{}, 0
+	m.readRole = func(_ context.Context, principal *Principal) (Role, metadata.Version, bool, error) {
+		reads++
+		return assigned[cacheKey(principal.Namespace, principal.Sub)], version, true, nil
+	}
+	m.readVersion = func(context.Context) (metadata.Version, error) { return version, nil }
+	ctx := context.Background()
+
+	// the cached roles are read again once the version of the role assignments changed on another server
+	reader := &Principal{Namespace: "ns1", Sub: "reader"}
+	require.Error(t, m.Authorize(ctx, api.InsertMethodName, reader))
+	assigned["ns1/reader"] = RoleEditor
+	version = metadata.Version("v1")
+	require.Error(t, m.Authorize(ctx, api.InsertMethodName, reader))
+	m.refresh(ctx)
+	require.NoError(t, m.Authorize(ctx, api.InsertMethodName, reader))
+	require.Equal(t, 2, reads)
+
+	// the roles read at another version than the last refresh saw are not used once cached
+	version = metadata.Version("v2")
+	m.Invalidate("ns1", "reader")
+	require.NoError(t, m.Authorize(ctx, api.InsertMethodName, reader))
+	require.NoError(t, m.Authorize(ctx, api.InsertMethodName, reader))
+	require.Equal(t, 4, reads)
+	m.refresh(ctx)
+	require.NoError(t, m.Authorize(ctx, api.InsertMethodName, reader))
+	require.Equal(t, 5, reads)
+	require.NoError(t, m.Authorize(ctx, api.InsertMethodName, reader))
+	require.Equal(t, 5, reads)
+}
+
+func TestNewManagerDefaultRole(t *testing.T) {
+	cfg := config.DefaultConfig
+	cfg.Auth.Authz.DefaultRole = "owner"
+	_, err := NewManager(nil, nil, nil, &cfg)
+	require.Error(t, err)
+}
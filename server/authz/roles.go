@@ -0,0 +1,104 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"strings"
+
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+// Role is the set of the operations allowed to a principal in its namespace. A role grants the operations of the
+// roles below it: an admin is also an editor and an editor is also a reader.
+type Role string
+
+const (
+	// RoleReader reads and searches the documents and describes the databases and the collections.
+	RoleReader Role = "reader"
+	// RoleEditor writes the documents as well.
+	RoleEditor Role = "editor"
+	// RoleAdmin creates, updates and drops the databases and the collections as well.
+	RoleAdmin Role = "admin"
+)
+
+var roleLevels = map[Role]int{
+	RoleReader: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// methodRoles are the roles required by the methods, the methods that are not listed require the admin role.
+var methodRoles = map[string]Role{
+	api.ReadMethodName:                RoleReader,
+	api.SearchMethodName:              RoleReader,
+	api.SubscribeMethodName:           RoleReader,
+	api.EventsMethodName:              RoleReader,
+	api.ListDatabasesMethodName:       RoleReader,
+	api.ListCollectionsMethodName:     RoleReader,
+	api.DescribeDatabaseMethodName:    RoleReader,
+	api.DescribeCollectionMethodName:  RoleReader,
+	api.BeginTransactionMethodName:    RoleReader,
+	api.CommitTransactionMethodName:   RoleReader,
+	api.RollbackTransactionMethodName: RoleReader,
+
+	api.ManagementMethodPrefix + "GetUserMetadata":    RoleReader,
+	api.ManagementMethodPrefix + "InsertUserMetadata": RoleReader,
+	api.ManagementMethodPrefix + "UpdateUserMetadata": RoleReader,
+
+	api.InsertMethodName:  RoleEditor,
+	api.ReplaceMethodName: RoleEditor,
+	api.UpdateMethodName:  RoleEditor,
+	api.DeleteMethodName:  RoleEditor,
+	api.PublishMethodName: RoleEditor,
+
+	api.CreateDatabaseMethodName:           RoleAdmin,
+	api.DropDatabaseMethodName:             RoleAdmin,
+	api.CreateOrUpdateCollectionMethodName: RoleAdmin,
+	api.DropCollectionMethodName:           RoleAdmin,
+
+	api.CreateNamespaceMethodName:    RoleAdmin,
+	api.ListNamespaceMethodName:      RoleAdmin,
+	api.DescribeNamespacesMethodName: RoleAdmin,
+	api.DescribeNamespaceMethodName:  RoleAdmin,
+	api.DeleteNamespaceMethodName:    RoleAdmin,
+}
+
+// ParseRole returns the role of the name.
+func ParseRole(name string) (Role, error) {
+	role := Role(strings.ToLower(name))
+	if _, ok := roleLevels[role]; !ok {
+		return "", errors.InvalidArgument("unknown role '%s', the roles are 'admin', 'editor' and 'reader'", name)
+	}
+	return role, nil
+}
+
+// Allows returns true if the role grants the operations of the required role.
+func (r Role) Allows(required Role) bool {
+	level, ok := roleLevels[r]
+	return ok && level >= roleLevels[required]
+}
+
+// RequiredRole returns the role required to call the method. The observability methods only read, and the methods
+// that are not known require the admin role.
+func RequiredRole(method string) Role {
+	if role, ok := methodRoles[method]; ok {
+		return role
+	}
+	if strings.HasPrefix(method, api.ObservabilityMethodPrefix) {
+		return RoleReader
+	}
+	return RoleAdmin
+}
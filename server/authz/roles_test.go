@@ -0,0 +1,46 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+)
+
+func TestRoles(t *testing.T) {
+	for _, name := range []string{"admin", "Editor", "READER"} {
+		_, err := ParseRole(name)
+		require.NoError(t, err)
+	}
+	_, err := ParseRole("owner")
+	require.Equal(t, "unknown role 'owner', the roles are 'admin', 'editor' and 'reader'", err.Error())
+
+	require.True(t, RoleAdmin.Allows(RoleEditor))
+	require.True(t, RoleEditor.Allows(RoleEditor))
+	require.True(t, RoleEditor.Allows(RoleReader))
+	require.False(t, RoleReader.Allows(RoleEditor))
+	require.False(t, RoleEditor.Allows(RoleAdmin))
+	require.False(t, Role("").Allows(RoleReader))
+
+	require.Equal(t, RoleReader, RequiredRole(api.SearchMethodName))
+	require.Equal(t, RoleReader, RequiredRole(api.ObservabilityMethodPrefix+"QuotaUsage"))
+	require.Equal(t, RoleEditor, RequiredRole(api.InsertMethodName))
+	require.Equal(t, RoleAdmin, RequiredRole(api.DropDatabaseMethodName))
+	require.Equal(t, RoleAdmin, RequiredRole(api.CreateNamespaceMethodName))
+	require.Equal(t, RoleAdmin, RequiredRole(api.DeleteNamespaceMethodName))
+	require.Equal(t, RoleAdmin, RequiredRole("/tigrisdata.v1.Tigris/Unknown"))
+}
@@ -0,0 +1,89 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"context"
+
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/transaction"
+)
+
+// roleMetadataKey is the key of the reserved user metadata storing the role of a principal in its namespace.
+const roleMetadataKey = "role"
+
+// versionKey is the key of the version of the role assignments, every change of a role assignment sets it to the
+// version of its transaction so that the servers drop the roles they cached.
+var versionKey = []byte("authz_roles_version")
+
+// Store keeps the roles assigned to the principals of the namespaces in the reserved user metadata, the principals
+// can't write it through the user metadata API.
+type Store struct {
+	users *metadata.UserSubspace
+}
+
+func NewStore(users *metadata.UserSubspace) *Store {
+	return &Store{
+		users: users,
+	}
+}
+
+// GetRole returns the role assigned to the principal in the namespace, the role is empty if none is assigned.
+func (s *Store) GetRole(ctx context.Context, tx transaction.Tx, namespaceId uint32, sub string) (Role, error) {
+	payload, err := s.users.GetUserMetadata(ctx, tx, namespaceId, metadata.Reserved, sub, roleMetadataKey)
+	if err != nil || payload == nil {
+		return "", err
+	}
+	return ParseRole(string(payload))
+}
+
+// SetRole assigns the role to the principal in the namespace, it replaces the role assigned before.
+func (s *Store) SetRole(ctx context.Context, tx transaction.Tx, namespaceId uint32, sub string, role Role) error {
+	current, err := s.GetRole(ctx, tx, namespaceId, sub)
+	if err != nil {
+		return err
+	}
+	if current == "" {
+		err = s.users.InsertUserMetadata(ctx, tx, namespaceId, metadata.Reserved, sub, roleMetadataKey, []byte(role))
+	} else {
+		err = s.users.UpdateUserMetadata(ctx, tx, namespaceId, metadata.Reserved, sub, roleMetadataKey, []byte(role))
+	}
+	if err != nil {
+		return err
+	}
+	return s.incrementVersion(ctx, tx)
+}
+
+// DeleteRole removes the role assigned to the principal in the namespace.
+func (s *Store) DeleteRole(ctx context.Context, tx transaction.Tx, namespaceId uint32, sub string) error {
+	if err := s.users.DeleteUserMetadata(ctx, tx, namespaceId, metadata.Reserved, sub, roleMetadataKey); err != nil {
+		return err
+	}
+	return s.incrementVersion(ctx, tx)
+}
+
+// Version returns the version of the role assignments, it is nil until a role is assigned. It can't be read after a
+// role assignment is changed in the same transaction.
+func (s *Store) Version(ctx context.Context, tx transaction.Tx) (metadata.Version, error) {
+	f, err := tx.Get(ctx, versionKey, false)
+	if err != nil {
+		return nil, err
+	}
+	return f.Get()
+}
+
+func (s *Store) incrementVersion(ctx context.Context, tx transaction.Tx) error {
+	return tx.SetVersionstampedValue(ctx, versionKey, metadata.VersionValue)
+}
@@ -0,0 +1,376 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"bytes"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"github.com/google/uuid"
+	jsoniter "github.com/json-iterator/go"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/metrics"
+)
+
+// groupStore persists the state of the consumer groups, the state is shared by all the servers.
+type groupStore interface {
+	load(key fdb.Key) (*groupState, error)
+	// update applies the change to the state in a transaction and returns the updated state. The change is applied
+	// again to the state read by the next attempt if the transaction is retried.
+	update(key fdb.Key, change func(state *groupState) error) (*groupState, error)
+}
+
+// groupState is the state of a consumer group. The members are the active members of the group across all the
+// servers, a member is removed once it hasn't been refreshed for the member timeout.
+type groupState struct {
+	Position   []byte                  `json:"position,omitempty"`
+	Generation uint64                  `json:"generation"`
+	Members    map[string]*memberState `json:"members,omitempty"`
+}
+
+type memberState struct {
+	Committed  []byte `json:"committed,omitempty"`
+	CommitTime int64  `json:"commit_time,omitempty"`
+	// Heartbeat is the last time the member was refreshed by its server, in unix nanoseconds.
+	Heartbeat int64 `json:"heartbeat"`
+}
+
+// rebalance starts a new generation, the members restart from the committed position of the group.
+func (s *groupState) rebalance() {
+	for _, m := range s.Members {
+		m.Committed, m.CommitTime = nil, 0
+	}
+	s.Generation++
+}
+
+// removeExpired removes the members that haven't been refreshed for the timeout and rebalances the group if any is.
+func (s *groupState) removeExpired(now time.Time, timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+
+	expired := false
+	for id, m := range s.Members {
+		if now.Sub(time.Unix(0, m.Heartbeat)) > timeout {
+			delete(s.Members, id)
+			expired = true
+		}
+	}
+	if expired {
+		s.rebalance()
+	}
+}
+
+type fdbGroupStore struct {
+	db fdb.Database
+}
+
+func (f *fdbGroupStore) load(key fdb.Key) (*groupState, error) {
+	value, err := f.db.ReadTransact(func(rtx fdb.ReadTransaction) (interface{}, error) {
+		return rtx.Get(key).Get()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeGroupState(value.([]byte))
+}
+
+func (f *fdbGroupStore) update(key fdb.Key, change func(state *groupState) error) (*groupState, error) {
+	state, err := f.db.Transact(func(tx fdb.Transaction) (interface{}, error) {
+		value, err := tx.Get(key).Get()
+		if err != nil {
+			return nil, err
+		}
+
+		state, err := decodeGroupState(value)
+		if err != nil {
+			return nil, err
+		}
+		if err = change(state); err != nil {
+			return nil, err
+		}
+
+		encoded, err := jsoniter.Marshal(state)
+		if err != nil {
+			return nil, err
+		}
+		tx.Set(key, encoded)
+		return state, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return state.(*groupState), nil
+}
+
+func decodeGroupState(value []byte) (*groupState, error) {
+	state := &groupState{}
+	if len(value) > 0 {
+		if err := jsoniter.Unmarshal(value, state); err != nil {
+			return nil, err
+		}
+	}
+	if state.Members == nil {
+		state.Members = make(map[string]*memberState)
+	}
+	return state, nil
+}
+
+func groupKey(db string, collection string, group string) fdb.Key {
+	return subspace.FromBytes([]byte("cdc_groups_" + db)).Pack(tuple.Tuple{collection, group})
+}
+
+// Group is a named consumer group of the change stream of a collection. The events are spread across the active
+// members of the group by the hash of their key, so that every event is delivered to exactly one member and the events
+// of a document are always delivered in order to the same member as long as the members don't change.
+//
+// The members, the generation and the committed position of the group are stored in the database, so that the members
+// of a group can stream from any server. A server refreshes the state of the group every time its members join, leave,
+// commit or send a heartbeat, the members that are not refreshed for the member timeout are removed from the group.
+//
+// The group has a single committed position, it is the smallest position committed by the members, so that no event
+// is skipped when the group is resumed. Any change to the members, or a reset of the position, starts a new generation
+// and the members restart from the committed position, which means that the events delivered after the committed
+// position may be delivered again after a rebalance.
+type Group struct {
+	sync.Mutex
+
+	db         string
+	collection string
+	name       string
+
+	store   groupStore
+	key     fdb.Key
+	timeout time.Duration
+	state   *groupState
+	order   []string
+}
+
+// Member is an active member of a consumer group.
+type Member struct {
+	Id string
+}
+
+func newGroup(store groupStore, db string, collection string, name string, timeout time.Duration) (*Group, error) {
+	g := &Group{
+		db:         db,
+		collection: collection,
+		name:       name,
+		store:      store,
+		key:        groupKey(db, collection, name),
+		timeout:    timeout,
+	}
+
+	state, err := store.load(g.key)
+	if err != nil {
+		return nil, err
+	}
+	g.setState(state)
+	return g, nil
+}
+
+// update applies the change to the stored state of the group and caches the updated state.
+func (g *Group) update(change func(state *groupState, now time.Time) error) error {
+	state, err := g.store.update(g.key, func(state *groupState) error {
+		return change(state, time.Now())
+	})
+	if err != nil {
+		return err
+	}
+
+	g.setState(state)
+	return nil
+}
+
+func (g *Group) setState(state *groupState) {
+	g.state = state
+	g.order = g.order[:0]
+	for id := range state.Members {
+		g.order = append(g.order, id)
+	}
+	sort.Strings(g.order)
+
+	metrics.UpdateCdcGroupMembers(g.db, g.collection, g.name, len(g.order))
+}
+
+func (g *Group) notActive(id string) error {
+	return errors.NotFound("member '%s' is not active in the group '%s'", id, g.name)
+}
+
+// IsNotActive returns true if the member has been removed from the group, because it has left or hasn't been
+// refreshed for the member timeout.
+func IsNotActive(err error) bool {
+	e, ok := err.(*api.TigrisError)
+	return ok && e.Code == api.Code_NOT_FOUND
+}
+
+// Join adds the member to the group and rebalances the events across the members. A new id is generated if it is not
+// set.
+func (g *Group) Join(id string) (*Member, error) {
+	if len(id) == 0 {
+		id = uuid.New().String()
+	}
+
+	g.Lock()
+	defer g.Unlock()
+
+	err := g.update(func(state *groupState, now time.Time) error {
+		state.removeExpired(now, g.timeout)
+		if _, ok := state.Members[id]; ok {
+			return errors.AlreadyExists("member '%s' is already active in the group '%s'", id, g.name)
+		}
+
+		state.Members[id] = &memberState{Heartbeat: now.UnixNano()}
+		state.rebalance()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Member{Id: id}, nil
+}
+
+// Leave removes the member from the group and rebalances the events across the remaining members.
+func (g *Group) Leave(m *Member) error {
+	g.Lock()
+	defer g.Unlock()
+
+	return g.update(func(state *groupState, now time.Time) error {
+		state.removeExpired(now, g.timeout)
+		if _, ok := state.Members[m.Id]; ok {
+			delete(state.Members, m.Id)
+			state.rebalance()
+		}
+		return nil
+	})
+}
+
+// Heartbeat refreshes the member, so that it is not removed from the group, and the state of the group, so that the
+// changes made from the other servers are seen by the member.
+func (g *Group) Heartbeat(m *Member) error {
+	g.Lock()
+	defer g.Unlock()
+
+	return g.update(func(state *groupState, now time.Time) error {
+		state.removeExpired(now, g.timeout)
+		member, ok := state.Members[m.Id]
+		if !ok {
+			return g.notActive(m.Id)
+		}
+
+		member.Heartbeat = now.UnixNano()
+		return nil
+	})
+}
+
+// Generation changes every time the members of the group change or the position is reset.
+func (g *Group) Generation() uint64 {
+	g.Lock()
+	defer g.Unlock()
+
+	return g.state.Generation
+}
+
+// Position is the committed position of the group, it is nil if the group starts from the latest change.
+func (g *Group) Position() []byte {
+	g.Lock()
+	defer g.Unlock()
+
+	return g.state.Position
+}
+
+// Owns returns true if the event with the key is delivered to the member.
+func (g *Group) Owns(m *Member, key []byte) bool {
+	g.Lock()
+	defer g.Unlock()
+
+	idx := sort.SearchStrings(g.order, m.Id)
+	if idx == len(g.order) || g.order[idx] != m.Id {
+		return false
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write(key)
+	return int(h.Sum32()%uint32(len(g.order))) == idx
+}
+
+// Commit records that the member has processed all its events up to the position, written at commitTime, and
+// refreshes the member like a heartbeat. The position of the group moves forward once every member has committed past
+// it. The commits of the events delivered in a previous generation are ignored.
+func (g *Group) Commit(m *Member, generation uint64, position []byte, commitTime int64) error {
+	g.Lock()
+	defer g.Unlock()
+
+	var slowest *memberState
+	err := g.update(func(state *groupState, now time.Time) error {
+		slowest = nil
+		state.removeExpired(now, g.timeout)
+		member, ok := state.Members[m.Id]
+		if !ok {
+			return g.notActive(m.Id)
+		}
+		member.Heartbeat = now.UnixNano()
+		if generation != state.Generation || bytes.Compare(position, member.Committed) <= 0 {
+			return nil
+		}
+		member.Committed, member.CommitTime = position, commitTime
+
+		for _, ms := range state.Members {
+			if ms.Committed == nil {
+				// the member hasn't processed anything since the committed position of the group
+				return nil
+			}
+			if slowest == nil || bytes.Compare(ms.Committed, slowest.Committed) < 0 {
+				slowest = ms
+			}
+		}
+		if bytes.Compare(slowest.Committed, state.Position) > 0 {
+			state.Position = slowest.Committed
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if slowest != nil {
+		metrics.UpdateCdcGroupLag(g.db, g.collection, g.name, time.Since(time.Unix(0, slowest.CommitTime)))
+	}
+	return nil
+}
+
+// Reset moves the committed position of the group, nil means the latest change. The active members restart from the
+// new position.
+func (g *Group) Reset(position []byte) error {
+	g.Lock()
+	defer g.Unlock()
+
+	return g.update(func(state *groupState, now time.Time) error {
+		state.removeExpired(now, g.timeout)
+		state.Position = position
+		state.rebalance()
+		return nil
+	})
+}
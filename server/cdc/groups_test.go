@@ -0,0 +1,226 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+type memGroupStore struct {
+	states map[string][]byte
+}
+
+func (m *memGroupStore) load(key fdb.Key) (*groupState, error) {
+	return decodeGroupState(m.states[string(key)])
+}
+
+func (m *memGroupStore) update(key fdb.Key, change func(state *groupState) error) (*groupState, error) {
+	state, err := m.load(key)
+	if err != nil {
+		return nil, err
+	}
+	if err = change(state); err != nil {
+		return nil, err
+	}
+
+	if m.states[string(key)], err = jsoniter.Marshal(state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (m *memGroupStore) position(g *Group) []byte {
+	state, _ := m.load(g.key)
+	return state.Position
+}
+
+func newTestGroup(t *testing.T) (*Group, *memGroupStore) {
+	store := &memGroupStore{states: make(map[string][]byte)}
+	g, err := newGroup(store, "db1", "coll1", "g1", time.Minute)
+	require.NoError(t, err)
+	return g, store
+}
+
+func TestGroup(t *testing.T) {
+	t.Run("join", func(t *testing.T) {
+		g, _ := newTestGroup(t)
+
+		m1, err := g.Join("m1")
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), g.Generation())
+
+		_, err = g.Join("m1")
+		require.Equal(t, errors.AlreadyExists("member 'm1' is already active in the group 'g1'"), err)
+
+		m2, err := g.Join("")
+		require.NoError(t, err)
+		require.NotEmpty(t, m2.Id)
+		require.Equal(t, uint64(2), g.Generation())
+
+		require.NoError(t, g.Leave(m1))
+		require.Equal(t, uint64(3), g.Generation())
+		require.False(t, g.Owns(m1, []byte("k1")))
+		require.True(t, g.Owns(m2, []byte("k1")))
+	})
+
+	t.Run("owns", func(t *testing.T) {
+		g, _ := newTestGroup(t)
+
+		var members []*Member
+		for i := 0; i < 3; i++ {
+			m, err := g.Join(fmt.Sprintf("m%d", i))
+			require.NoError(t, err)
+			members = append(members, m)
+		}
+
+		owned := make(map[string]int)
+		for i := 0; i < 100; i++ {
+			key := []byte(fmt.Sprintf("key%d", i))
+			owners := 0
+			for _, m := range members {
+				if g.Owns(m, key) {
+					owners++
+					owned[m.Id]++
+				}
+			}
+			require.Equal(t, 1, owners)
+		}
+		require.Len(t, owned, 3)
+	})
+
+	t.Run("commit", func(t *testing.T) {
+		g, store := newTestGroup(t)
+
+		m1, err := g.Join("m1")
+		require.NoError(t, err)
+		m2, err := g.Join("m2")
+		require.NoError(t, err)
+		generation := g.Generation()
+
+		// the group doesn't move until every member has committed
+		require.NoError(t, g.Commit(m1, generation, []byte{0x02}, 0))
+		require.Nil(t, g.Position())
+
+		require.NoError(t, g.Commit(m2, generation, []byte{0x01}, 0))
+		require.Equal(t, []byte{0x01}, g.Position())
+		require.Equal(t, []byte{0x01}, store.position(g))
+
+		require.NoError(t, g.Commit(m2, generation, []byte{0x03}, 0))
+		require.Equal(t, []byte{0x02}, g.Position())
+
+		// the commits of a previous generation are ignored
+		m3, err := g.Join("m3")
+		require.NoError(t, err)
+		require.NoError(t, g.Commit(m1, generation, []byte{0x05}, 0))
+		require.NoError(t, g.Commit(m2, generation, []byte{0x05}, 0))
+		require.NoError(t, g.Commit(m3, g.Generation(), []byte{0x05}, 0))
+		require.Equal(t, []byte{0x02}, g.Position())
+
+		require.NoError(t, g.Leave(m3))
+		err = g.Commit(m3, g.Generation(), []byte{0x06}, 0)
+		require.Equal(t, errors.NotFound("member 'm3' is not active in the group 'g1'"), err)
+
+		// the position is loaded when the group is created again
+		g2, err := newGroup(store, "db1", "coll1", "g1", time.Minute)
+		require.NoError(t, err)
+		require.Equal(t, []byte{0x02}, g2.Position())
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		g, store := newTestGroup(t)
+
+		m1, err := g.Join("m1")
+		require.NoError(t, err)
+		require.NoError(t, g.Commit(m1, g.Generation(), []byte{0x05}, 0))
+		require.Equal(t, []byte{0x05}, g.Position())
+
+		generation := g.Generation()
+		require.NoError(t, g.Reset([]byte{0x01}))
+		require.Equal(t, []byte{0x01}, g.Position())
+		require.Equal(t, []byte{0x01}, store.position(g))
+		require.NotEqual(t, generation, g.Generation())
+
+		require.NoError(t, g.Reset(nil))
+		require.Nil(t, g.Position())
+	})
+
+	t.Run("servers", func(t *testing.T) {
+		// the groups of two servers share the store
+		g1, store := newTestGroup(t)
+		g2, err := newGroup(store, "db1", "coll1", "g1", time.Minute)
+		require.NoError(t, err)
+
+		m1, err := g1.Join("m1")
+		require.NoError(t, err)
+		m2, err := g2.Join("m2")
+		require.NoError(t, err)
+		_, err = g2.Join("m1")
+		require.Equal(t, errors.AlreadyExists("member 'm1' is already active in the group 'g1'"), err)
+
+		// the server of m1 sees m2 once it syncs with the group
+		require.True(t, g1.Owns(m1, []byte("k1")))
+		require.NoError(t, g1.Heartbeat(m1))
+		require.Equal(t, g2.Generation(), g1.Generation())
+		for i := 0; i < 10; i++ {
+			key := []byte(fmt.Sprintf("key%d", i))
+			require.NotEqual(t, g1.Owns(m1, key), g2.Owns(m2, key))
+		}
+
+		// the group moves once the members of both servers have committed
+		generation := g1.Generation()
+		require.NoError(t, g1.Commit(m1, generation, []byte{0x02}, 0))
+		require.NoError(t, g2.Commit(m2, generation, []byte{0x01}, 0))
+		require.Equal(t, []byte{0x01}, g2.Position())
+
+		// a reset from one server is seen by the members of the other
+		require.NoError(t, g2.Reset(nil))
+		require.NoError(t, g1.Heartbeat(m1))
+		require.Nil(t, g1.Position())
+		require.NotEqual(t, generation, g1.Generation())
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		g, store := newTestGroup(t)
+
+		m1, err := g.Join("m1")
+		require.NoError(t, err)
+		m2, err := g.Join("m2")
+		require.NoError(t, err)
+
+		// the server of m1 stopped refreshing it
+		_, err = store.update(g.key, func(state *groupState) error {
+			state.Members["m1"].Heartbeat = time.Now().Add(-2 * time.Minute).UnixNano()
+			return nil
+		})
+		require.NoError(t, err)
+
+		generation := g.Generation()
+		require.NoError(t, g.Heartbeat(m2))
+		require.NotEqual(t, generation, g.Generation())
+		require.True(t, g.Owns(m2, []byte("k1")))
+		require.True(t, IsNotActive(g.Heartbeat(m1)))
+
+		// the member can join again
+		_, err = g.Join("m1")
+		require.NoError(t, err)
+	})
+}
@@ -16,16 +16,24 @@ package cdc
 
 import (
 	"context"
+	"time"
 
 	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/internal"
 	"github.com/tigrisdata/tigris/server/transaction"
 	"github.com/tigrisdata/tigris/store/kv"
 )
 
+// maxTxSize is the limit of the size of the changes of a transaction, they are written to the log as a single value
+// which is limited in size by FoundationDB.
+const maxTxSize = 100000
+
 type Tx struct {
 	Id  []byte
 	Ops []*kv.Event
+	// CommitTime is the time in nanoseconds the change is written to the log, it is used to enforce the retention.
+	CommitTime int64 `json:",omitempty"`
 }
 
 func (p *Publisher) OnCommit(ctx context.Context, tx transaction.Tx, listener kv.EventListener) error {
@@ -35,19 +43,24 @@ func (p *Publisher) OnCommit(ctx context.Context, tx transaction.Tx, listener kv
 	}
 
 	json, err := jsoniter.Marshal(&Tx{
-		Ops: events,
+		Ops:        events,
+		CommitTime: time.Now().UnixNano(),
 	})
 	if err != nil {
 		return err
 	}
 
-	key, err := p.keySpace.getNextKey()
+	td := internal.NewTableDataWithEncoding(json, internal.JsonEncoding)
+	enc, err := internal.Encode(td)
 	if err != nil {
 		return err
 	}
+	// the size includes both the new and the old documents of the changes
+	if len(enc) > maxTxSize {
+		return errors.InvalidArgument("the changes of the transaction are %d bytes, exceeding the limit of %d bytes of the change stream", len(enc), maxTxSize)
+	}
 
-	td := internal.NewTableDataWithEncoding(json, internal.JsonEncoding)
-	enc, err := internal.Encode(td)
+	key, err := p.keySpace.getNextKey()
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,38 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+func TestOnCommitSize(t *testing.T) {
+	doc := bytes.Repeat([]byte("a"), maxTxSize/2)
+	listener := &kv.DefaultListener{}
+	listener.OnUpdate(kv.UpdateEvent, internal.UserTableKeyPrefix, []byte("k1"), doc, doc)
+
+	// either document alone fits in the log, the old and the new document together don't
+	err := NewPublisher("db1").OnCommit(context.TODO(), nil, listener)
+	// the size of the encoded creation time of the changes varies
+	require.Equal(t, api.Code_INVALID_ARGUMENT, err.(*api.TigrisError).Code)
+	require.Regexp(t, `^the changes of the transaction are 1335\d\d bytes, exceeding the limit of 100000 bytes of the change stream$`, err.Error())
+}
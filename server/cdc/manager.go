@@ -17,7 +17,11 @@ package cdc
 import (
 	"context"
 	"sync"
+	"time"
 
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/metadata"
 	"github.com/tigrisdata/tigris/server/transaction"
 	"github.com/tigrisdata/tigris/store/kv"
@@ -28,15 +32,42 @@ type DatabaseNameCtxKey struct{}
 type Manager struct {
 	sync.RWMutex
 
-	pubs map[string]*Publisher
+	pubs   map[string]*Publisher
+	groups map[string]*Group
 }
 
 func NewManager() *Manager {
 	return &Manager{
-		pubs: make(map[string]*Publisher),
+		pubs:   make(map[string]*Publisher),
+		groups: make(map[string]*Group),
 	}
 }
 
+// GetGroup returns the consumer group of the change stream of the collection, its state is loaded from the database
+// the first time the group is used on this server.
+func (m *Manager) GetGroup(kvStore kv.KeyValueStore, dbName string, collection string, name string) (*Group, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	key := string(groupKey(dbName, collection, name))
+	if g, ok := m.groups[key]; ok {
+		return g, nil
+	}
+
+	intDb, err := kvStore.GetInternalDatabase()
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := newGroup(&fdbGroupStore{db: intDb.(fdb.Database)}, dbName, collection, name, config.DefaultConfig.Cdc.GroupMemberTimeout)
+	if err != nil {
+		return nil, err
+	}
+	m.groups[key] = g
+
+	return g, nil
+}
+
 func (m *Manager) GetPublisher(dbName string) *Publisher {
 	m.Lock()
 	defer m.Unlock()
@@ -47,6 +78,27 @@ func (m *Manager) GetPublisher(dbName string) *Publisher {
 	return m.pubs[dbName]
 }
 
+// StartTrimming removes the changes older than the retention from the change logs of the databases in the background,
+// whether a stream of the database is open or not. The databases are listed again every time the logs are trimmed.
+func (m *Manager) StartTrimming(kvStore kv.KeyValueStore, databases func() []string) {
+	go func() {
+		ticker := time.NewTicker(trimInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			m.trim(kvStore, databases())
+		}
+	}()
+}
+
+func (m *Manager) trim(kvStore kv.KeyValueStore, databases []string) {
+	for _, dbName := range databases {
+		if err := m.GetPublisher(dbName).Trim(kvStore, config.DefaultConfig.Cdc); err != nil {
+			log.Err(err).Str("db", dbName).Msg("trimming the change log failed")
+		}
+	}
+}
+
 func (m *Manager) WrapContext(ctx context.Context, dbName string) context.Context {
 	if len(dbName) == 0 {
 		return ctx
@@ -15,6 +15,9 @@
 package cdc
 
 import (
+	"bytes"
+	"time"
+
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
 	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
@@ -23,6 +26,9 @@ import (
 	ulog "github.com/tigrisdata/tigris/util/log"
 )
 
+// trimInterval is how often the changes that are older than the retention are removed from the logs.
+const trimInterval = time.Minute
+
 type Publisher struct {
 	keySpace *PublisherKeySpace
 }
@@ -50,6 +56,11 @@ func getKey(cdcBytes []byte, tv [10]byte) fdb.Key {
 	return k
 }
 
+// contains returns true if the key is a change of this keyspace.
+func (p *PublisherKeySpace) contains(key fdb.Key) bool {
+	return bytes.Compare(key, p.beginKey) >= 0 && bytes.Compare(key, p.endKey) < 0
+}
+
 func (p *PublisherKeySpace) getNextKey() (fdb.Key, error) {
 	s := subspace.FromBytes(p.cdcBytes)
 	v := tuple.IncompleteVersionstamp(0)
@@ -63,7 +74,96 @@ func NewPublisher(dbName string) *Publisher {
 	}
 }
 
-func (p *Publisher) NewStreamer(kvStore kv.KeyValueStore) (*Streamer, error) {
+// Position returns the position of the latest change, the streams resumed from it start with the changes logged after
+// it. It is the position of the empty log if there is no change yet.
+func (p *Publisher) Position(kvStore kv.KeyValueStore) ([]byte, error) {
+	intDb, err := kvStore.GetInternalDatabase()
+	if ulog.E(err) {
+		return nil, err
+	}
+
+	key, err := intDb.(fdb.Database).ReadTransact(func(rtx fdb.ReadTransaction) (interface{}, error) {
+		kr := fdb.KeyRange{Begin: p.keySpace.beginKey, End: p.keySpace.endKey}
+		kvs, err := rtx.GetRange(kr, fdb.RangeOptions{Limit: 1, Reverse: true}).GetSliceWithError()
+		if err != nil {
+			return nil, err
+		}
+		if len(kvs) == 0 {
+			return p.keySpace.beginKey, nil
+		}
+		return kvs[0].Key, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return key.(fdb.Key), nil
+}
+
+// Trim removes the changes older than the retention from the log of the database, StreamBatch changes are removed
+// with a transaction until there is no expired change left.
+func (p *Publisher) Trim(kvStore kv.KeyValueStore, cfg config.CdcConfig) error {
+	if cfg.Retention <= 0 {
+		return nil
+	}
+
+	intDb, err := kvStore.GetInternalDatabase()
+	if ulog.E(err) {
+		return err
+	}
+
+	for {
+		removed, err := p.trim(intDb.(fdb.Database), cfg)
+		if err != nil {
+			return err
+		}
+		if removed == 0 || removed < cfg.StreamBatch {
+			return nil
+		}
+	}
+}
+
+// trim removes at most StreamBatch changes older than the retention from the log and returns the number removed.
+func (p *Publisher) trim(db fdb.Database, cfg config.CdcConfig) (int, error) {
+	removed, err := db.Transact(func(tx fdb.Transaction) (interface{}, error) {
+		kr := fdb.KeyRange{Begin: p.keySpace.beginKey, End: p.keySpace.endKey}
+		r := tx.GetRange(kr, fdb.RangeOptions{Limit: cfg.StreamBatch})
+
+		var end fdb.Key
+		removed := 0
+		now := time.Now()
+		i := r.Iterator()
+		for i.Advance() {
+			kv, err := i.Get()
+			if err != nil {
+				return nil, err
+			}
+
+			change, err := decodeTx(kv.Value)
+			if err != nil {
+				return nil, err
+			}
+			if !isExpired(change.CommitTime, cfg.Retention, now) {
+				break
+			}
+			end = kv.Key
+			removed++
+		}
+
+		if end != nil {
+			tx.ClearRange(fdb.KeyRange{Begin: p.keySpace.beginKey, End: append(append(fdb.Key{}, end...), 0x00)})
+		}
+		return removed, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return removed.(int), nil
+}
+
+// NewStreamer returns the streamer of the changes, starting from the latest change or right after the change of the
+// resume token if it is set.
+func (p *Publisher) NewStreamer(kvStore kv.KeyValueStore, resumeToken []byte) (*Streamer, error) {
 	intDb, err := kvStore.GetInternalDatabase()
 	if ulog.E(err) {
 		return nil, err
@@ -74,7 +174,7 @@ func (p *Publisher) NewStreamer(kvStore kv.KeyValueStore) (*Streamer, error) {
 		cfg:      config.DefaultConfig.Cdc,
 	}
 
-	if err = s.start(); err != nil {
+	if err = s.start(resumeToken); err != nil {
 		return nil, err
 	}
 
@@ -21,10 +21,15 @@ import (
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/internal"
 	"github.com/tigrisdata/tigris/server/config"
 )
 
+// HeartbeatEvent is the op of the events sent on an idle stream, the id of the event is the position of the stream.
+const HeartbeatEvent = "heartbeat"
+
 type Streamer struct {
 	db       fdb.Database
 	lastKey  fdb.Key
@@ -34,8 +39,12 @@ type Streamer struct {
 	Txs      chan Tx
 }
 
-func (s *Streamer) start() error {
+func (s *Streamer) start(resumeToken []byte) error {
 	key, err := s.db.ReadTransact(func(rtx fdb.ReadTransaction) (interface{}, error) {
+		if len(resumeToken) > 0 {
+			return s.resumeKey(rtx, resumeToken)
+		}
+
 		kr := fdb.KeyRange{Begin: s.keySpace.beginKey, End: s.keySpace.endKey}
 		r := rtx.GetRange(kr, fdb.RangeOptions{Limit: 1, Reverse: true})
 
@@ -69,6 +78,67 @@ func (s *Streamer) start() error {
 	return nil
 }
 
+// resumeKey validates the resume token, which is the id of the last event received by the client, and returns the key
+// the stream starts after. A token that is no longer in the log or older than the retention can't be resumed from.
+func (s *Streamer) resumeKey(rtx fdb.ReadTransaction, resumeToken []byte) (fdb.Key, error) {
+	key := fdb.Key(resumeToken)
+	if !s.keySpace.contains(key) {
+		return nil, errors.InvalidArgument("invalid resume token")
+	}
+	if bytes.Equal(key, s.keySpace.beginKey) {
+		// the position of an empty log, the stream starts with the first change
+		return key, nil
+	}
+
+	value, err := rtx.Get(key).Get()
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, s.expired()
+	}
+
+	tx, err := decodeTx(value)
+	if err != nil {
+		return nil, err
+	}
+	if isExpired(tx.CommitTime, s.cfg.Retention, time.Now()) {
+		return nil, s.expired()
+	}
+
+	return key, nil
+}
+
+// IsResumeTokenExpired returns true if the stream can't be resumed because the changes after the resume token are no
+// longer retained, it is the only failed precondition of starting a stream.
+func IsResumeTokenExpired(err error) bool {
+	e, ok := err.(*api.TigrisError)
+	return ok && e.Code == api.Code_FAILED_PRECONDITION
+}
+
+func (s *Streamer) expired() error {
+	return errors.FailedPrecondition("resume token has expired, the changes are only retained for %s", s.cfg.Retention)
+}
+
+// isExpired returns true if the change committed at commitTime is older than the retention. The changes logged
+// without a commit time are older than any change with one.
+func isExpired(commitTime int64, retention time.Duration, now time.Time) bool {
+	return retention > 0 && now.Sub(time.Unix(0, commitTime)) > retention
+}
+
+func decodeTx(value []byte) (*Tx, error) {
+	data, err := internal.Decode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := &Tx{}
+	if err = jsoniter.Unmarshal(data.RawData, tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
 func (s *Streamer) read() error {
 	_, err := s.db.ReadTransact(func(rtx fdb.ReadTransaction) (interface{}, error) {
 		kr := fdb.KeyRange{Begin: s.lastKey, End: s.keySpace.endKey}
@@ -85,13 +155,7 @@ func (s *Streamer) read() error {
 				continue
 			}
 
-			data, err := internal.Decode(kv.Value)
-			if err != nil {
-				return nil, err
-			}
-
-			tx := Tx{}
-			err = jsoniter.Unmarshal(data.RawData, &tx)
+			tx, err := decodeTx(kv.Value)
 			if err != nil {
 				return nil, err
 			}
@@ -100,7 +164,7 @@ func (s *Streamer) read() error {
 
 			if len(s.Txs) < cap(s.Txs) {
 				s.lastKey = kv.Key
-				s.Txs <- tx
+				s.Txs <- *tx
 			} else {
 				// buffer overflow
 				close(s.Txs)
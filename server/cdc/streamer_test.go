@@ -0,0 +1,79 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+func TestIsExpired(t *testing.T) {
+	now := time.Now()
+
+	require.False(t, isExpired(now.Add(-time.Hour).UnixNano(), 2*time.Hour, now))
+	require.True(t, isExpired(now.Add(-3*time.Hour).UnixNano(), 2*time.Hour, now))
+	// changes logged without a commit time
+	require.True(t, isExpired(0, 2*time.Hour, now))
+	// no retention
+	require.False(t, isExpired(0, 0, now))
+}
+
+func TestPublisherKeySpace(t *testing.T) {
+	ks := NewPublisherKeySpace("db1")
+
+	key := getKey([]byte("cdc_db1"), [10]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x02, 0x03, 0x00, 0x01})
+	require.True(t, ks.contains(key))
+	require.True(t, ks.contains(ks.beginKey))
+	require.False(t, ks.contains(ks.endKey))
+
+	other := getKey([]byte("cdc_db2"), [10]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x02, 0x03, 0x00, 0x01})
+	require.False(t, ks.contains(other))
+	require.False(t, ks.contains(fdb.Key("random")))
+}
+
+func TestIsResumeTokenExpired(t *testing.T) {
+	s := &Streamer{cfg: config.CdcConfig{Retention: time.Hour}}
+	require.True(t, IsResumeTokenExpired(s.expired()))
+	require.False(t, IsResumeTokenExpired(errors.InvalidArgument("invalid resume token")))
+	require.False(t, IsResumeTokenExpired(fmt.Errorf("read failed")))
+}
+
+func TestDecodeTx(t *testing.T) {
+	commitTime := time.Now().UnixNano()
+	json, err := jsoniter.Marshal(&Tx{
+		Ops:        []*kv.Event{{Op: kv.UpdateEvent, Key: []byte("k1"), Data: []byte("v2"), Before: []byte("v1")}},
+		CommitTime: commitTime,
+	})
+	require.NoError(t, err)
+
+	enc, err := internal.Encode(internal.NewTableDataWithEncoding(json, internal.JsonEncoding))
+	require.NoError(t, err)
+
+	tx, err := decodeTx(enc)
+	require.NoError(t, err)
+	require.Equal(t, commitTime, tx.CommitTime)
+	require.Len(t, tx.Ops, 1)
+	require.Equal(t, []byte("v1"), tx.Ops[0].Before)
+	require.Equal(t, []byte("v2"), tx.Ops[0].Data)
+}
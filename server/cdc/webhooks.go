@@ -0,0 +1,265 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+const (
+	// WebhookActive is the state of a webhook that is delivering the changes.
+	WebhookActive = "active"
+	// WebhookDeadLetter is the state of a webhook whose delivery failed after the maximum number of retries, the
+	// delivery is stopped until the webhook is resumed.
+	WebhookDeadLetter = "dead_letter"
+)
+
+// Webhook is a sink that delivers the changes of a collection to a URL, the events are POSTed in batches.
+type Webhook struct {
+	Id         string `json:"id"`
+	Namespace  string `json:"namespace"`
+	Db         string `json:"db"`
+	Collection string `json:"collection"`
+	URL        string `json:"url"`
+	// Secret signs the deliveries, see SignWebhook.
+	Secret        string              `json:"secret,omitempty"`
+	Filter        jsoniter.RawMessage `json:"filter,omitempty"`
+	IncludeBefore bool                `json:"include_before,omitempty"`
+	// BatchSize is the maximum number of events of a delivery.
+	BatchSize int `json:"batch_size,omitempty"`
+	// BatchIntervalMs is how long, in milliseconds, the events are buffered before a delivery.
+	BatchIntervalMs int64 `json:"batch_interval_ms,omitempty"`
+	// MaxRetries is the number of retries of a delivery before the webhook is dead-lettered.
+	MaxRetries int `json:"max_retries,omitempty"`
+}
+
+// WebhookStatus is the delivery state of a webhook.
+type WebhookStatus struct {
+	State string `json:"state"`
+	// Position is the id of the last change delivered, the delivery resumes right after it.
+	Position []byte `json:"position,omitempty"`
+	// Delivered is the number of events delivered.
+	Delivered int64 `json:"delivered"`
+	// Retries is the number of failed attempts of the current delivery.
+	Retries     int       `json:"retries"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastAttempt time.Time `json:"last_attempt,omitempty"`
+	// DeadLetter is the number of events of the delivery that failed after the maximum number of retries.
+	DeadLetter int `json:"dead_letter,omitempty"`
+	// Expired is set once the changes after the position are no longer retained, the webhook is dead-lettered and
+	// resumed from the latest change.
+	Expired bool `json:"expired,omitempty"`
+}
+
+// WebhookStore persists the webhooks and their status.
+type WebhookStore interface {
+	List() ([]*Webhook, error)
+	Get(id string) (*Webhook, *WebhookStatus, error)
+	Save(webhook *Webhook, status *WebhookStatus) error
+	SaveStatus(id string, status *WebhookStatus) error
+	Delete(id string) error
+}
+
+// NewWebhookStore returns the store of the webhooks in the database.
+func NewWebhookStore(db fdb.Database) WebhookStore {
+	return &fdbWebhookStore{db: db, sub: subspace.FromBytes([]byte("cdc_webhooks"))}
+}
+
+type fdbWebhookStore struct {
+	db  fdb.Database
+	sub subspace.Subspace
+}
+
+func (f *fdbWebhookStore) webhookKey(id string) fdb.Key {
+	return f.sub.Pack(tuple.Tuple{"webhook", id})
+}
+
+func (f *fdbWebhookStore) statusKey(id string) fdb.Key {
+	return f.sub.Pack(tuple.Tuple{"status", id})
+}
+
+func (f *fdbWebhookStore) List() ([]*Webhook, error) {
+	values, err := f.db.ReadTransact(func(rtx fdb.ReadTransaction) (interface{}, error) {
+		return rtx.GetRange(f.sub.Sub("webhook"), fdb.RangeOptions{}).GetSliceWithError()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var webhooks []*Webhook
+	for _, kv := range values.([]fdb.KeyValue) {
+		webhook := &Webhook{}
+		if err = jsoniter.Unmarshal(kv.Value, webhook); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, nil
+}
+
+func (f *fdbWebhookStore) Get(id string) (*Webhook, *WebhookStatus, error) {
+	values, err := f.db.ReadTransact(func(rtx fdb.ReadTransaction) (interface{}, error) {
+		webhook, err := rtx.Get(f.webhookKey(id)).Get()
+		if err != nil {
+			return nil, err
+		}
+		status, err := rtx.Get(f.statusKey(id)).Get()
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{webhook, status}, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raw := values.([][]byte)
+	if raw[0] == nil {
+		return nil, nil, errors.NotFound("webhook '%s' doesn't exist", id)
+	}
+
+	webhook, status := &Webhook{}, &WebhookStatus{State: WebhookActive}
+	if err = jsoniter.Unmarshal(raw[0], webhook); err != nil {
+		return nil, nil, err
+	}
+	if raw[1] != nil {
+		if err = jsoniter.Unmarshal(raw[1], status); err != nil {
+			return nil, nil, err
+		}
+	}
+	return webhook, status, nil
+}
+
+func (f *fdbWebhookStore) Save(webhook *Webhook, status *WebhookStatus) error {
+	webhookData, err := jsoniter.Marshal(webhook)
+	if err != nil {
+		return err
+	}
+	statusData, err := jsoniter.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.db.Transact(func(tx fdb.Transaction) (interface{}, error) {
+		tx.Set(f.webhookKey(webhook.Id), webhookData)
+		tx.Set(f.statusKey(webhook.Id), statusData)
+		return nil, nil
+	})
+	return err
+}
+
+func (f *fdbWebhookStore) SaveStatus(id string, status *WebhookStatus) error {
+	statusData, err := jsoniter.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.db.Transact(func(tx fdb.Transaction) (interface{}, error) {
+		// the webhook may have been deleted while it was delivering
+		webhook, err := tx.Get(f.webhookKey(id)).Get()
+		if err != nil || webhook == nil {
+			return nil, err
+		}
+		tx.Set(f.statusKey(id), statusData)
+		return nil, nil
+	})
+	return err
+}
+
+func (f *fdbWebhookStore) Delete(id string) error {
+	_, err := f.db.Transact(func(tx fdb.Transaction) (interface{}, error) {
+		tx.Clear(f.webhookKey(id))
+		tx.Clear(f.statusKey(id))
+		return nil, nil
+	})
+	return err
+}
+
+// WebhookBackoff returns how long to wait before the retry of a delivery, the delay is doubled on every attempt
+// starting at initial and capped at max.
+func WebhookBackoff(retry int, initial time.Duration, max time.Duration) time.Duration {
+	delay := initial
+	for i := 1; i < retry && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+// SignWebhook returns the signature of a delivery, sent in the "Tigris-Webhook-Signature" header as
+//
+//	t=<timestamp>,v1=<signature>
+//
+// where the timestamp is the unix time of the delivery attempt in seconds and the signature is the hex encoded
+// HMAC-SHA256, keyed with the secret of the webhook, of the timestamp and the body of the request joined with a dot.
+// The receiver verifies a delivery by computing the same HMAC over the raw body, comparing it in constant time, and
+// rejecting the timestamps that are too old to prevent replays, see VerifyWebhookSignature.
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", ts, webhookHMAC(secret, ts, body))
+}
+
+// VerifyWebhookSignature checks the signature header of a delivery received at now, the deliveries signed more than
+// tolerance before are rejected.
+func VerifyWebhookSignature(secret string, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var ts, signature string
+	for _, part := range strings.Split(header, ",") {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			switch k {
+			case "t":
+				ts = v
+			case "v1":
+				signature = v
+			}
+		}
+	}
+	if len(ts) == 0 || len(signature) == 0 {
+		return errors.InvalidArgument("malformed webhook signature")
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.InvalidArgument("malformed webhook signature")
+	}
+	if tolerance > 0 && now.Sub(time.Unix(unix, 0)) > tolerance {
+		return errors.InvalidArgument("webhook signature has expired")
+	}
+	if !hmac.Equal([]byte(signature), []byte(webhookHMAC(secret, ts, body))) {
+		return errors.InvalidArgument("webhook signature doesn't match")
+	}
+	return nil
+}
+
+func webhookHMAC(secret string, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(ts))
+	_, _ = mac.Write([]byte("."))
+	_, _ = mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,45 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	now := time.Unix(1667000000, 0)
+	body := []byte(`{"events":[{"op":"insert"}]}`)
+	signature := SignWebhook("secret", now, body)
+
+	require.Regexp(t, `^t=1667000000,v1=[0-9a-f]{64}$`, signature)
+	require.NoError(t, VerifyWebhookSignature("secret", signature, body, time.Minute, now.Add(time.Second)))
+
+	require.Equal(t, "webhook signature doesn't match", VerifyWebhookSignature("other", signature, body, time.Minute, now).Error())
+	require.Equal(t, "webhook signature doesn't match", VerifyWebhookSignature("secret", signature, []byte(`{}`), time.Minute, now).Error())
+	require.Equal(t, "webhook signature has expired", VerifyWebhookSignature("secret", signature, body, time.Minute, now.Add(2*time.Minute)).Error())
+	require.Equal(t, "malformed webhook signature", VerifyWebhookSignature("secret", "v1=abc", body, time.Minute, now).Error())
+	require.Equal(t, "malformed webhook signature", VerifyWebhookSignature("secret", "t=abc,v1=abc", body, time.Minute, now).Error())
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	require.Equal(t, time.Second, WebhookBackoff(1, time.Second, time.Minute))
+	require.Equal(t, 2*time.Second, WebhookBackoff(2, time.Second, time.Minute))
+	require.Equal(t, 8*time.Second, WebhookBackoff(4, time.Second, time.Minute))
+	require.Equal(t, time.Minute, WebhookBackoff(10, time.Second, time.Minute))
+	require.Equal(t, time.Minute, WebhookBackoff(100, time.Second, time.Minute))
+}
@@ -21,9 +21,124 @@ import (
 )
 
 type ServerConfig struct {
-	Host        string
-	Port        int16
-	FDBHardDrop bool `mapstructure:"fdb_hard_drop" yaml:"fdb_hard_drop" json:"fdb_hard_drop"`
+	Host           string
+	Port           int16
+	FDBHardDrop    bool `mapstructure:"fdb_hard_drop" yaml:"fdb_hard_drop" json:"fdb_hard_drop"`
+	MaxHeaderBytes int  `mapstructure:"max_header_bytes" yaml:"max_header_bytes" json:"max_header_bytes"`
+	// AdminRoutes enables the HTTP routes of the operators. With the auth enabled, they are only served to the tokens of
+	// the admin namespaces with the role the route requires, the admin role by default.
+	AdminRoutes bool `mapstructure:"admin_routes" yaml:"admin_routes" json:"admin_routes"`
+	// MuxMatchers is the order the protocols served on the port, "http" and "grpc", are matched in. A connection is
+	// served by the first protocol that matches it, the protocols missing from the list are matched last.
+	MuxMatchers []string `mapstructure:"mux_matchers" yaml:"mux_matchers" json:"mux_matchers"`
+	// MuxReadTimeout is how long a new connection has to send the bytes its protocol is matched on, the connection is
+	// closed once it is reached. Zero disables the timeout.
+	MuxReadTimeout time.Duration `mapstructure:"mux_read_timeout" yaml:"mux_read_timeout" json:"mux_read_timeout"`
+	// HealthCheckTimeout bounds the checks of the backends done by the health and readiness probes. Zero disables the
+	// timeout.
+	HealthCheckTimeout time.Duration `mapstructure:"health_check_timeout" yaml:"health_check_timeout" json:"health_check_timeout"`
+	// ShutdownDelay is how long the server keeps serving once it is asked to stop. The readiness probe fails meanwhile,
+	// so that the load balancers stop routing new requests to the server before it exits.
+	ShutdownDelay time.Duration `mapstructure:"shutdown_delay" yaml:"shutdown_delay" json:"shutdown_delay"`
+	// ShutdownTimeout is how long the calls in flight are waited for once the server stops serving, the calls still
+	// running then are canceled. Zero cancels them right away.
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout" yaml:"shutdown_timeout" json:"shutdown_timeout"`
+	// TLS terminates TLS on the port, for both the HTTP and the gRPC connections.
+	TLS TLSConfig `mapstructure:"tls" yaml:"tls" json:"tls"`
+	// GRPCWeb serves the gRPC-Web requests of the browsers on the HTTP connections.
+	GRPCWeb bool `mapstructure:"grpc_web" yaml:"grpc_web" json:"grpc_web"`
+	// CORS is the policy of the cross-origin requests of the HTTP connections.
+	CORS CORSConfig `mapstructure:"cors" yaml:"cors" json:"cors"`
+	// GRPC are the limits of the gRPC connections and the services of the gRPC server.
+	GRPC GRPCConfig `mapstructure:"grpc" yaml:"grpc" json:"grpc"`
+	// HTTP are the limits of the requests of the HTTP connections.
+	HTTP HTTPConfig `mapstructure:"http" yaml:"http" json:"http"`
+	// Concurrency bounds the number of requests handled at once.
+	Concurrency ConcurrencyConfig `mapstructure:"concurrency" yaml:"concurrency" json:"concurrency"`
+	// PanicDetails adds the message of the panics of the handlers to the errors returned to the clients. It is ignored
+	// in production.
+	PanicDetails bool `mapstructure:"panic_details" yaml:"panic_details" json:"panic_details"`
+}
+
+// ConcurrencyConfig bounds the number of requests handled at once by the server, the requests above a limit are
+// rejected with RESOURCE_EXHAUSTED. A zero value disables the limit.
+type ConcurrencyConfig struct {
+	// MaxInFlight is the maximum number of requests handled at once, all the methods together.
+	MaxInFlight int `mapstructure:"max_in_flight" yaml:"max_in_flight" json:"max_in_flight"`
+	// Methods are the maximum numbers of requests of the methods handled at once. The methods are either the full
+	// gRPC method names, like "/tigrisdata.v1.Tigris/Search", or the method names, like "Search".
+	Methods map[string]int `mapstructure:"methods" yaml:"methods" json:"methods"`
+}
+
+// HTTPConfig are the limits of the HTTP requests. The document routes, that read and write the documents of the
+// collections, have their own limits, the other routes manage the metadata. A zero value disables the limit.
+type HTTPConfig struct {
+	// MaxBodySize is the maximum size in bytes of the body of a request of the metadata routes.
+	MaxBodySize int64 `mapstructure:"max_body_size" yaml:"max_body_size" json:"max_body_size"`
+	// MaxDocumentBodySize is the maximum size in bytes of the body of a request of the document routes.
+	MaxDocumentBodySize int64 `mapstructure:"max_document_body_size" yaml:"max_document_body_size" json:"max_document_body_size"`
+	// Timeout is how long a request of the metadata routes is handled, its context is then cancelled.
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout" json:"timeout"`
+	// DocumentTimeout is how long a request of the document routes is handled, its context is then cancelled.
+	DocumentTimeout time.Duration `mapstructure:"document_timeout" yaml:"document_timeout" json:"document_timeout"`
+}
+
+// GRPCConfig are the limits of the gRPC server, the zero values keep the defaults of gRPC.
+type GRPCConfig struct {
+	// MaxRecvMsgSize is the maximum size in bytes of a message received by the server.
+	MaxRecvMsgSize int `mapstructure:"max_recv_msg_size" yaml:"max_recv_msg_size" json:"max_recv_msg_size"`
+	// MaxSendMsgSize is the maximum size in bytes of a message sent by the server.
+	MaxSendMsgSize int `mapstructure:"max_send_msg_size" yaml:"max_send_msg_size" json:"max_send_msg_size"`
+	// MaxConcurrentStreams is the maximum number of concurrent calls of a connection.
+	MaxConcurrentStreams uint32 `mapstructure:"max_concurrent_streams" yaml:"max_concurrent_streams" json:"max_concurrent_streams"`
+	// KeepaliveMinTime is the minimum interval of the keepalive pings of the clients, the connections of the clients
+	// pinging more often are closed.
+	KeepaliveMinTime time.Duration `mapstructure:"keepalive_min_time" yaml:"keepalive_min_time" json:"keepalive_min_time"`
+	// KeepalivePermitWithoutStream allows the keepalive pings of the connections without active calls.
+	KeepalivePermitWithoutStream bool `mapstructure:"keepalive_permit_without_stream" yaml:"keepalive_permit_without_stream" json:"keepalive_permit_without_stream"`
+	// MaxConnectionIdle is how long a connection without active calls is kept open.
+	MaxConnectionIdle time.Duration `mapstructure:"max_connection_idle" yaml:"max_connection_idle" json:"max_connection_idle"`
+	// MaxConnectionAge is how long a connection is kept open, the clients then reconnect, possibly to another server.
+	MaxConnectionAge time.Duration `mapstructure:"max_connection_age" yaml:"max_connection_age" json:"max_connection_age"`
+	// MaxConnectionAgeGrace is how long the calls of a connection that reached its maximum age have to complete
+	// before the connection is closed.
+	MaxConnectionAgeGrace time.Duration `mapstructure:"max_connection_age_grace" yaml:"max_connection_age_grace" json:"max_connection_age_grace"`
+	// Reflection registers the server reflection service, so that the tools like grpcurl can list and call the
+	// services without their proto files. It is meant for debugging and is disabled by default.
+	Reflection bool `mapstructure:"reflection" yaml:"reflection" json:"reflection"`
+}
+
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to call the server, "*" allows all of them. An origin can contain one
+	// wildcard, like "https://*.example.com".
+	AllowedOrigins []string `mapstructure:"allowed_origins" yaml:"allowed_origins" json:"allowed_origins"`
+	// AllowedHeaders are the headers the requests can carry, "*" allows all of them.
+	AllowedHeaders []string `mapstructure:"allowed_headers" yaml:"allowed_headers" json:"allowed_headers"`
+	// MaxAge is how long the browsers can cache the result of a preflight request. Zero doesn't send the header.
+	MaxAge time.Duration `mapstructure:"max_age" yaml:"max_age" json:"max_age"`
+	// AllowCredentials allows the requests carrying cookies or the client certificates of the browser.
+	AllowCredentials bool `mapstructure:"allow_credentials" yaml:"allow_credentials" json:"allow_credentials"`
+}
+
+// The modes of verification of the client certificates.
+const (
+	ClientAuthNone          = "none"
+	ClientAuthVerifyIfGiven = "verify-if-given"
+	ClientAuthRequire       = "require"
+)
+
+type TLSConfig struct {
+	Enabled  bool   `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+	CertFile string `mapstructure:"cert_file" yaml:"cert_file" json:"cert_file"`
+	KeyFile  string `mapstructure:"key_file" yaml:"key_file" json:"key_file"`
+	// ClientCAFile is the bundle of the CAs the client certificates are verified with, it is required unless the
+	// client auth is "none".
+	ClientCAFile string `mapstructure:"client_ca_file" yaml:"client_ca_file" json:"client_ca_file"`
+	// ClientAuth is one of "none", "verify-if-given" and "require".
+	ClientAuth string `mapstructure:"client_auth" yaml:"client_auth" json:"client_auth"`
+	// ReloadInterval is how often the files are checked for changes, the certificates are also reloaded on SIGHUP.
+	// Zero disables the checks.
+	ReloadInterval time.Duration `mapstructure:"reload_interval" yaml:"reload_interval" json:"reload_interval"`
 }
 
 type Config struct {
@@ -39,6 +154,30 @@ type Config struct {
 	Quota         QuotaConfig
 	Observability ObservabilityConfig `yaml:"observability" json:"observability"`
 	Management    ManagementConfig    `yaml:"management" json:"management"`
+	Snapshot      SnapshotConfig      `yaml:"snapshot" json:"snapshot"`
+	Schema        SchemaConfig        `yaml:"schema" json:"schema"`
+}
+
+type SchemaConfig struct {
+	// MaxNestingDepth is the maximum nesting depth of the documents, zero disables the limit.
+	MaxNestingDepth int `mapstructure:"max_nesting_depth" yaml:"max_nesting_depth" json:"max_nesting_depth"`
+	// StrictDateTime rejects the date-time values without an explicit timezone offset.
+	StrictDateTime bool `mapstructure:"strict_date_time" yaml:"strict_date_time" json:"strict_date_time"`
+	// StrictFormats rejects the schemas with a format that is not registered on the fields of any type, the formats
+	// of the fields of the types that don't have formats are ignored otherwise. The stored schemas are not affected.
+	StrictFormats bool `mapstructure:"strict_formats" yaml:"strict_formats" json:"strict_formats"`
+	// ErrorVerbosity is the verbosity of the validation errors, "terse" only reports the field and the reason and
+	// "verbose" also reports the path of the keyword in the schema and its constraint.
+	ErrorVerbosity string `mapstructure:"error_verbosity" yaml:"error_verbosity" json:"error_verbosity"`
+	// LargeArrayThreshold is the number of items above which the arrays of the documents are validated item by item,
+	// stopping once MaxViolations are found. Zero validates them with the rest of the document.
+	LargeArrayThreshold int `mapstructure:"large_array_threshold" yaml:"large_array_threshold" json:"large_array_threshold"`
+	// MaxViolations is the maximum number of violations reported by the validation errors of a document, zero reports
+	// all of them.
+	MaxViolations int `mapstructure:"max_violations" yaml:"max_violations" json:"max_violations"`
+	// BannedFields are the field names, compared case-insensitively, that the schemas of the new collections can't
+	// use at any nesting level. The existing collections are not affected.
+	BannedFields []string `mapstructure:"banned_fields" yaml:"banned_fields" json:"banned_fields"`
 }
 
 type AuthConfig struct {
@@ -58,6 +197,36 @@ type AuthConfig struct {
 	ManagementClientId        string        `mapstructure:"management_client_id" yaml:"management_client_id" json:"management_client_id"`
 	ManagementClientSecret    string        `mapstructure:"management_client_secret" yaml:"management_client_secret" json:"management_client_secret"`
 	TokenClockSkewDurationSec int           `mapstructure:"token_clock_skew_duration_sec" yaml:"token_clock_skew_duration_sec" json:"token_clock_skew_duration_sec"`
+	// Issuers are the issuers of the access tokens, the single issuer of the issuer_url and the audience is used when
+	// it is empty. The keys of the issuers are refreshed every jwks_cache_timeout in the background.
+	Issuers []IssuerConfig `mapstructure:"issuers" yaml:"issuers" json:"issuers"`
+	// Authz enforces the roles of the authenticated principals.
+	Authz AuthzConfig `mapstructure:"authz" yaml:"authz" json:"authz"`
+}
+
+// IssuerConfig is an issuer of the access tokens accepted by the server.
+type IssuerConfig struct {
+	URL       string   `mapstructure:"url" yaml:"url" json:"url"`
+	Audiences []string `mapstructure:"audiences" yaml:"audiences" json:"audiences"`
+	// JWKSURL is the URL of the keys of the issuer, it is discovered from the issuer when empty.
+	JWKSURL string `mapstructure:"jwks_url" yaml:"jwks_url" json:"jwks_url"`
+	// NamespaceClaim is the claim of the namespace of the callers, either the namespace or an object with its "code".
+	// It is "https://tigris/n" when empty.
+	NamespaceClaim string `mapstructure:"namespace_claim" yaml:"namespace_claim" json:"namespace_claim"`
+}
+
+// AuthzConfig configures the role-based authorization, the roles are "admin", "editor" and "reader".
+type AuthzConfig struct {
+	Enabled bool `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+	// DefaultRole is the role of the principals without a role assigned in their namespace or in their token.
+	DefaultRole string `mapstructure:"default_role" yaml:"default_role" json:"default_role"`
+	// CacheSize is the number of principals whose role is cached.
+	CacheSize int `mapstructure:"cache_size" yaml:"cache_size" json:"cache_size"`
+	// CacheTTL is how long a role is cached at most.
+	CacheTTL time.Duration `mapstructure:"cache_ttl" yaml:"cache_ttl" json:"cache_ttl"`
+	// RefreshInterval is how often the version of the role assignments is checked, a role changed on another server
+	// is enforced within the interval.
+	RefreshInterval time.Duration `mapstructure:"refresh_interval" yaml:"refresh_interval" json:"refresh_interval"`
 }
 
 type CdcConfig struct {
@@ -65,6 +234,37 @@ type CdcConfig struct {
 	StreamInterval time.Duration
 	StreamBatch    int
 	StreamBuffer   int
+	// Retention is how long the changes are kept in the change log, a stream can't be resumed from an older position.
+	Retention time.Duration `mapstructure:"retention" yaml:"retention" json:"retention"`
+	// HeartbeatInterval is how often an idle stream sends a heartbeat event, zero disables the heartbeats.
+	HeartbeatInterval time.Duration `mapstructure:"heartbeat_interval" yaml:"heartbeat_interval" json:"heartbeat_interval"`
+	// GroupCommitInterval is how often the position of the events delivered to the members of a consumer group is
+	// committed.
+	GroupCommitInterval time.Duration `mapstructure:"group_commit_interval" yaml:"group_commit_interval" json:"group_commit_interval"`
+	// GroupMemberTimeout is how long a member of a consumer group stays in the group without being refreshed by its
+	// server, the members are refreshed every GroupCommitInterval, or a third of the timeout if the commits are off.
+	GroupMemberTimeout time.Duration `mapstructure:"group_member_timeout" yaml:"group_member_timeout" json:"group_member_timeout"`
+	// Webhooks configures the delivery of the changes to the webhooks.
+	Webhooks WebhooksConfig `mapstructure:"webhooks" yaml:"webhooks" json:"webhooks"`
+}
+
+// WebhooksConfig configures the delivery of the changes to the webhooks. The delivery must only be enabled on a single
+// server of the cluster, otherwise every server delivers the changes.
+type WebhooksConfig struct {
+	Enabled bool `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+	// Timeout is the timeout of a delivery request.
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout" json:"timeout"`
+	// BatchSize and BatchInterval are the defaults of the webhooks that don't set them.
+	BatchSize     int           `mapstructure:"batch_size" yaml:"batch_size" json:"batch_size"`
+	BatchInterval time.Duration `mapstructure:"batch_interval" yaml:"batch_interval" json:"batch_interval"`
+	// MaxRetries is the default number of retries of a delivery before the webhook is dead-lettered.
+	MaxRetries int `mapstructure:"max_retries" yaml:"max_retries" json:"max_retries"`
+	// InitialBackoff is the delay of the first retry, it is doubled on every retry up to MaxBackoff.
+	InitialBackoff time.Duration `mapstructure:"initial_backoff" yaml:"initial_backoff" json:"initial_backoff"`
+	MaxBackoff     time.Duration `mapstructure:"max_backoff" yaml:"max_backoff" json:"max_backoff"`
+	// AllowPrivateAddresses allows the webhooks on the loopback, private and link-local addresses, they are rejected
+	// by default so that the webhooks can't reach the internal services of the cluster.
+	AllowPrivateAddresses bool `mapstructure:"allow_private_addresses" yaml:"allow_private_addresses" json:"allow_private_addresses"`
 }
 
 type TracingConfig struct {
@@ -75,6 +275,23 @@ type TracingConfig struct {
 	WithUDS             string  `mapstructure:"agent_socket" yaml:"agent_socket" json:"agent_socket"`
 	WithAgentAddr       string  `mapstructure:"agent_addr" yaml:"agent_addr" json:"agent_addr"`
 	WithDogStatsdAddr   string  `mapstructure:"dogstatsd_addr" yaml:"dogstatsd_addr" json:"dogstatsd_addr"`
+	// DatadogEnabled exports the spans to the Datadog agent, it can be enabled along with Otlp while migrating from
+	// one backend to the other.
+	DatadogEnabled bool       `mapstructure:"datadog_enabled" yaml:"datadog_enabled" json:"datadog_enabled"`
+	Otlp           OtlpConfig `mapstructure:"otlp" yaml:"otlp" json:"otlp"`
+	// NoMeasurementMethods are the full names of the methods that are neither traced nor counted in the metrics.
+	NoMeasurementMethods []string `mapstructure:"no_measurement_methods" yaml:"no_measurement_methods" json:"no_measurement_methods"`
+	// MethodSampleRates are the rates the requests of the methods are traced at, keyed by the full method name or the
+	// method name. The decision replaces the sampling of the tracer and it is made on the request id, the requests of
+	// the other methods are sampled by the tracer at SampleRate. The requests are counted in the metrics regardless.
+	MethodSampleRates map[string]float64 `mapstructure:"method_sample_rates" yaml:"method_sample_rates" json:"method_sample_rates"`
+}
+
+// OtlpConfig exports the spans as OpenTelemetry spans to an OTLP collector over gRPC.
+type OtlpConfig struct {
+	Enabled  bool   `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+	Endpoint string `mapstructure:"endpoint" yaml:"endpoint" json:"endpoint"`
+	Insecure bool   `mapstructure:"insecure" yaml:"insecure" json:"insecure"`
 }
 
 type MetricsConfig struct {
@@ -87,11 +304,20 @@ type MetricsConfig struct {
 	Size           SizeMetricGroupConfig     `mapstructure:"size" yaml:"size" json:"size"`
 	Network        NetworkMetricGroupConfig  `mapstructure:"network" yaml:"network" json:"network"`
 	Auth           AuthMetricsConfig         `mapstructure:"auth" yaml:"auth" json:"auth"`
+	Cdc            CdcMetricsConfig          `mapstructure:"cdc" yaml:"cdc" json:"cdc"`
+	SlowQuery      SlowQueryConfig           `mapstructure:"slow_query" yaml:"slow_query" json:"slow_query"`
+	TagCardinality TagCardinalityConfig      `mapstructure:"tag_cardinality" yaml:"tag_cardinality" json:"tag_cardinality"`
+	Usage          UsageMetricsConfig        `mapstructure:"usage" yaml:"usage" json:"usage"`
 }
 
 type TimerConfig struct {
 	TimerEnabled     bool `mapstructure:"timer_enabled" yaml:"timer_enabled" json:"timer_enabled"`
 	HistogramEnabled bool `mapstructure:"histogram_enabled" yaml:"histogram_enabled" json:"histogram_enabled"`
+	// HistogramBuckets are the upper bounds of the buckets of the histogram, they must be strictly increasing. The
+	// default buckets of tally are used when empty.
+	HistogramBuckets []time.Duration `mapstructure:"histogram_buckets" yaml:"histogram_buckets" json:"histogram_buckets"`
+	// Quantiles are the quantiles reported by the timer, the quantiles of the metrics config are used when empty.
+	Quantiles []float64 `mapstructure:"quantiles" yaml:"quantiles" json:"quantiles"`
 }
 
 type CounterConfig struct {
@@ -111,6 +337,9 @@ type FdbMetricGroupConfig struct {
 	Counter      CounterConfig `mapstructure:"counter" yaml:"counter" json:"counter"`
 	Timer        TimerConfig   `mapstructure:"timer" yaml:"timer" json:"timer"`
 	FilteredTags []string      `mapstructure:"filtered_tags" yaml:"filtered_tags" json:"filtered_tags"`
+	// Operations are the names of the operations reported in the fdb_method tag in addition to the operations of the
+	// store, the other names are reported as unknown.
+	Operations []string `mapstructure:"operations" yaml:"operations" json:"operations"`
 }
 
 type SearchMetricGroupConfig struct {
@@ -133,6 +362,35 @@ type SizeMetricGroupConfig struct {
 	Db           bool     `mapstructure:"db" yaml:"db" json:"db"`
 	Collection   bool     `mapstructure:"collection" yaml:"collection" json:"collection"`
 	FilteredTags []string `mapstructure:"filtered_tags" yaml:"filtered_tags" json:"filtered_tags"`
+	// Reporter periodically reports the size and the number of documents of every collection.
+	Reporter SizeReporterConfig `mapstructure:"reporter" yaml:"reporter" json:"reporter"`
+}
+
+type SizeReporterConfig struct {
+	Enabled  bool          `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+	Interval time.Duration `mapstructure:"interval" yaml:"interval" json:"interval"`
+	// Concurrency is the number of collections measured at the same time.
+	Concurrency int `mapstructure:"concurrency" yaml:"concurrency" json:"concurrency"`
+	// MaxExactCount is the number of documents above which the documents of a collection are not counted, as an
+	// exact count has to read all of them.
+	MaxExactCount int64 `mapstructure:"max_exact_count" yaml:"max_exact_count" json:"max_exact_count"`
+}
+
+// SlowQueryConfig logs the unary requests that take longer than the threshold of their method.
+type SlowQueryConfig struct {
+	Enabled   bool          `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+	Threshold time.Duration `mapstructure:"threshold" yaml:"threshold" json:"threshold"`
+	// MethodThresholds overrides the threshold of the methods, keyed by the full method name or the method name.
+	MethodThresholds map[string]time.Duration `mapstructure:"method_thresholds" yaml:"method_thresholds" json:"method_thresholds"`
+	// CountEnabled counts the slow requests in a dedicated metric.
+	CountEnabled bool `mapstructure:"count_enabled" yaml:"count_enabled" json:"count_enabled"`
+}
+
+type TagCardinalityConfig struct {
+	Enabled bool `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+	// Limits is the number of distinct values reported for a tag key, the values seen once the limit is reached are
+	// reported as "__other__". The tag keys without a limit are not guarded.
+	Limits map[string]int `mapstructure:"limits" yaml:"limits" json:"limits"`
 }
 
 type NetworkMetricGroupConfig struct {
@@ -145,6 +403,16 @@ type AuthMetricsConfig struct {
 	FilteredTags []string `mapstructure:"filtered_tags" yaml:"filtered_tags" json:"filtered_tags"`
 }
 
+type CdcMetricsConfig struct {
+	Enabled bool `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+}
+
+// UsageMetricsConfig enables the counters of the work done for the namespaces. The totals of the namespaces are kept
+// regardless, they are returned by the usage route of the admin API.
+type UsageMetricsConfig struct {
+	Enabled bool `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+}
+
 type ProfilingConfig struct {
 	Enabled         bool `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
 	EnableCPU       bool `mapstructure:"enable_cpu" yaml:"enable_cpu" json:"enable_cpu"`
@@ -158,6 +426,12 @@ type ManagementConfig struct {
 	Enabled bool `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
 }
 
+// SnapshotConfig is the object store of the database snapshots, the snapshots are disabled if the path is empty.
+type SnapshotConfig struct {
+	// Path is the directory of the local filesystem the snapshots are written to.
+	Path string `mapstructure:"path" yaml:"path" json:"path"`
+}
+
 type ObservabilityConfig struct {
 	Provider    string `mapstructure:"provider" yaml:"provider" json:"provider"`
 	Enabled     bool   `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
@@ -177,9 +451,30 @@ var DefaultConfig = Config{
 		SampleRate: 0.01,
 	},
 	Server: ServerConfig{
-		Host:        "0.0.0.0",
-		Port:        8081,
-		FDBHardDrop: false,
+		Host:               "0.0.0.0",
+		Port:               8081,
+		FDBHardDrop:        false,
+		MaxHeaderBytes:     1 << 20, // same as http.DefaultMaxHeaderBytes
+		MuxMatchers:        []string{"http", "grpc"},
+		MuxReadTimeout:     10 * time.Second,
+		HealthCheckTimeout: 2 * time.Second,
+		ShutdownDelay:      5 * time.Second,
+		ShutdownTimeout:    30 * time.Second,
+		TLS: TLSConfig{
+			ClientAuth:     ClientAuthNone,
+			ReloadInterval: time.Minute,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"*"},
+			AllowedHeaders: []string{"*"},
+		},
+		GRPC: GRPCConfig{
+			MaxRecvMsgSize: 4 << 20, // same as the default of gRPC
+		},
+		HTTP: HTTPConfig{
+			MaxBodySize:         1 << 20,
+			MaxDocumentBodySize: 16 << 20,
+		},
 	},
 	Auth: AuthConfig{
 		Enabled:          false,
@@ -188,24 +483,64 @@ var DefaultConfig = Config{
 		JWKSCacheTimeout: 5 * time.Minute,
 		LogOnly:          true,
 		AdminNamespaces:  []string{"tigris-admin"},
+		Authz: AuthzConfig{
+			Enabled:         false,
+			DefaultRole:     "reader",
+			CacheSize:       10000,
+			CacheTTL:        time.Minute,
+			RefreshInterval: time.Second,
+		},
 	},
 	Cdc: CdcConfig{
-		Enabled:        false,
-		StreamInterval: 500 * time.Millisecond,
-		StreamBatch:    100,
-		StreamBuffer:   200,
+		Enabled:             false,
+		StreamInterval:      500 * time.Millisecond,
+		StreamBatch:         100,
+		StreamBuffer:        200,
+		Retention:           24 * time.Hour,
+		HeartbeatInterval:   5 * time.Second,
+		GroupCommitInterval: time.Second,
+		GroupMemberTimeout:  30 * time.Second,
+		Webhooks: WebhooksConfig{
+			Enabled:        false,
+			Timeout:        10 * time.Second,
+			BatchSize:      100,
+			BatchInterval:  time.Second,
+			MaxRetries:     10,
+			InitialBackoff: time.Second,
+			MaxBackoff:     5 * time.Minute,
+		},
 	},
 	Search: SearchConfig{
-		Host:         "localhost",
-		Port:         8108,
-		ReadEnabled:  true,
-		WriteEnabled: true,
+		Host:                       "localhost",
+		Port:                       8108,
+		ReadEnabled:                true,
+		WriteEnabled:               true,
+		MultiCollectionConcurrency: 4,
+		MaxConnections:             64,
+		Timeout:                    5 * time.Second,
+		MaxRetries:                 2,
+		RetryBackoff:               50 * time.Millisecond,
+		CircuitBreaker: SearchCircuitBreakerConfig{
+			ConsecutiveFailures: 5,
+			OpenTimeout:         10 * time.Second,
+			HalfOpenRequests:    1,
+		},
+		IndexBatch: SearchIndexBatchConfig{
+			Enabled:       false,
+			MaxDocuments:  100,
+			FlushInterval: 5 * time.Millisecond,
+		},
 	},
 	Tracing: TracingConfig{
 		Enabled:             false,
 		SampleRate:          0.01,
 		CodeHotspotsEnabled: true,
 		EndpointsEnabled:    true,
+		DatadogEnabled:      true,
+		Otlp: OtlpConfig{
+			Endpoint: "localhost:4317",
+		},
+		NoMeasurementMethods: []string{"/HealthAPI/Health"},
 	},
 	Metrics: MetricsConfig{
 		Enabled:        true,
@@ -264,6 +599,11 @@ var DefaultConfig = Config{
 			Db:           true,
 			Collection:   true,
 			FilteredTags: nil,
+			Reporter: SizeReporterConfig{
+				Interval:      5 * time.Minute,
+				Concurrency:   4,
+				MaxExactCount: 100000,
+			},
 		},
 		Network: NetworkMetricGroupConfig{
 			Enabled:      true,
@@ -273,6 +613,26 @@ var DefaultConfig = Config{
 			Enabled:      true,
 			FilteredTags: nil,
 		},
+		Cdc: CdcMetricsConfig{
+			Enabled: true,
+		},
+		SlowQuery: SlowQueryConfig{
+			Enabled:   true,
+			Threshold: time.Second,
+		},
+		TagCardinality: TagCardinalityConfig{
+			Enabled: true,
+			Limits: map[string]int{
+				"db":                 1000,
+				"collection":         10000,
+				"tigris_tenant":      10000,
+				"tigris_tenant_name": 10000,
+				"field":              10000,
+			},
+		},
+		Usage: UsageMetricsConfig{
+			Enabled: true,
+		},
 	},
 	Profiling: ProfilingConfig{
 		Enabled:    false,
@@ -311,6 +671,16 @@ var DefaultConfig = Config{
 			DataSizeLimit:   100 * 1024 * 1024,
 			RefreshInterval: 60 * time.Second,
 		},
+		Requests: RequestRateConfig{
+			Enabled: false,
+			Default: RequestRateLimits{
+				Read:  1000,
+				Write: 500,
+				DDL:   10,
+			},
+			RefreshInterval: 60 * time.Second,
+			IdleTimeout:     10 * time.Minute,
+		},
 	},
 	Observability: ObservabilityConfig{
 		Enabled:     false,
@@ -320,11 +690,34 @@ var DefaultConfig = Config{
 	Management: ManagementConfig{
 		Enabled: true,
 	},
+	Schema: SchemaConfig{
+		MaxNestingDepth:     100,
+		ErrorVerbosity:      "terse",
+		LargeArrayThreshold: 1000,
+		MaxViolations:       100,
+	},
 }
 
 // FoundationDBConfig keeps FoundationDB configuration parameters.
 type FoundationDBConfig struct {
 	ClusterFile string `mapstructure:"cluster_file" json:"cluster_file" yaml:"cluster_file"`
+	// MaxRetries is the maximum number of times an operation of the store is retried on a transient error, the
+	// default is used when zero.
+	MaxRetries int `mapstructure:"max_retries" json:"max_retries" yaml:"max_retries"`
+	// MinBackoff and MaxBackoff bound the exponential backoff between the retries, the delay FoundationDB waits
+	// before its own retries is also capped by MaxBackoff. The defaults are used when zero.
+	MinBackoff time.Duration `mapstructure:"min_backoff" json:"min_backoff" yaml:"min_backoff"`
+	MaxBackoff time.Duration `mapstructure:"max_backoff" json:"max_backoff" yaml:"max_backoff"`
+	// ScanParallelism is the maximum number of shards of a range read concurrently by the parallel scans, the scans
+	// are serial when it is zero or one.
+	ScanParallelism int `mapstructure:"scan_parallelism" json:"scan_parallelism" yaml:"scan_parallelism"`
+	// ScanShardBuffer is the maximum number of values buffered for every shard read by a parallel scan, the default
+	// is used when zero.
+	ScanShardBuffer int `mapstructure:"scan_shard_buffer" json:"scan_shard_buffer" yaml:"scan_shard_buffer"`
+	// ReportConflicts reports the conflicting keys of the interactive transactions aborted by a conflict, so that the
+	// errors can tell the collections and the keys the transactions collided on. The reporting makes the commits of the
+	// interactive transactions slower.
+	ReportConflicts bool `mapstructure:"report_conflicts" json:"report_conflicts" yaml:"report_conflicts"`
 }
 
 type SearchConfig struct {
@@ -333,6 +726,42 @@ type SearchConfig struct {
 	AuthKey      string `mapstructure:"auth_key" json:"auth_key" yaml:"auth_key"`
 	ReadEnabled  bool   `mapstructure:"read_enabled" yaml:"read_enabled" json:"read_enabled"`
 	WriteEnabled bool   `mapstructure:"write_enabled" yaml:"write_enabled" json:"write_enabled"`
+	// MultiCollectionConcurrency is the maximum number of collections queried in parallel by a search that
+	// targets multiple collections of a database.
+	MultiCollectionConcurrency int `mapstructure:"multi_collection_concurrency" yaml:"multi_collection_concurrency" json:"multi_collection_concurrency"`
+	// MaxConnections is the maximum number of connections opened to the search backend.
+	MaxConnections int `mapstructure:"max_connections" yaml:"max_connections" json:"max_connections"`
+	// Timeout is the maximum time a single call to the search backend can take.
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout" json:"timeout"`
+	// MaxRetries is the number of times an idempotent call to the search backend is retried on a transient failure.
+	MaxRetries int `mapstructure:"max_retries" yaml:"max_retries" json:"max_retries"`
+	// RetryBackoff is the delay before the first retry, it is doubled on every subsequent retry.
+	RetryBackoff time.Duration `mapstructure:"retry_backoff" yaml:"retry_backoff" json:"retry_backoff"`
+	// DefaultSortByPrimaryKey orders the searches that have neither a query nor a sort, the listings of the documents,
+	// by the primary key ascending instead of the order of the search backend.
+	DefaultSortByPrimaryKey bool                       `mapstructure:"default_sort_by_primary_key" yaml:"default_sort_by_primary_key" json:"default_sort_by_primary_key"`
+	CircuitBreaker          SearchCircuitBreakerConfig `mapstructure:"circuit_breaker" yaml:"circuit_breaker" json:"circuit_breaker"`
+	IndexBatch              SearchIndexBatchConfig     `mapstructure:"index_batch" yaml:"index_batch" json:"index_batch"`
+}
+
+// SearchCircuitBreakerConfig controls when the calls to the search backend fail fast without reaching the backend.
+type SearchCircuitBreakerConfig struct {
+	// ConsecutiveFailures is the number of consecutive failed calls that open the breaker.
+	ConsecutiveFailures uint32 `mapstructure:"consecutive_failures" yaml:"consecutive_failures" json:"consecutive_failures"`
+	// OpenTimeout is how long the breaker stays open before the trial calls are let through to the backend.
+	OpenTimeout time.Duration `mapstructure:"open_timeout" yaml:"open_timeout" json:"open_timeout"`
+	// HalfOpenRequests is the number of trial calls allowed while the breaker is half-open.
+	HalfOpenRequests uint32 `mapstructure:"half_open_requests" yaml:"half_open_requests" json:"half_open_requests"`
+}
+
+// SearchIndexBatchConfig controls the buffering of the index operations, so that the documents written to a collection
+// are sent to the search backend in bulk instead of one call per document.
+type SearchIndexBatchConfig struct {
+	Enabled bool `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+	// MaxDocuments is the number of buffered operations of a collection that triggers a flush.
+	MaxDocuments int `mapstructure:"max_documents" yaml:"max_documents" json:"max_documents"`
+	// FlushInterval is the maximum time an operation stays in the buffer before it is flushed.
+	FlushInterval time.Duration `mapstructure:"flush_interval" yaml:"flush_interval" json:"flush_interval"`
 }
 
 type LimitsConfig struct {
@@ -372,6 +801,23 @@ type NamespaceStorageLimitsConfig struct {
 	Size int64
 }
 
+// RequestRateConfig limits the number of requests per second of the namespaces on a node, the reads, the writes and
+// the DDL requests have their own limits. The limits set for a namespace with the admin API are read again every
+// refresh interval. The state of the namespaces without a request for the idle timeout is dropped.
+type RequestRateConfig struct {
+	Enabled         bool
+	Default         RequestRateLimits
+	RefreshInterval time.Duration `mapstructure:"refresh_interval" yaml:"refresh_interval" json:"refresh_interval"`
+	IdleTimeout     time.Duration `mapstructure:"idle_timeout" yaml:"idle_timeout" json:"idle_timeout"`
+}
+
+// RequestRateLimits are the requests per second allowed, zero doesn't limit the requests.
+type RequestRateLimits struct {
+	Read  int `mapstructure:"read" yaml:"read" json:"read"`
+	Write int `mapstructure:"write" yaml:"write" json:"write"`
+	DDL   int `mapstructure:"ddl" yaml:"ddl" json:"ddl"`
+}
+
 type StorageLimitsConfig struct {
 	Enabled         bool
 	DataSizeLimit   int64         `mapstructure:"data_size_limit" yaml:"data_size_limit" json:"data_size_limit"`
@@ -403,6 +849,7 @@ type QuotaConfig struct {
 	Node      LimitsConfig          // maximum rates per node. protects the node from overloading
 	Namespace NamespaceLimitsConfig // user quota across all the nodes
 	Storage   StorageLimitsConfig
+	Requests  RequestRateConfig // requests per second per namespace on this node
 
 	WriteUnitSize int
 	ReadUnitSize  int
@@ -15,16 +15,24 @@
 package main
 
 import (
+	"context"
 	"os"
+	"os/signal"
 	"runtime"
+	"syscall"
+	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/authz"
 	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/metadata"
 	"github.com/tigrisdata/tigris/server/metrics"
 	"github.com/tigrisdata/tigris/server/muxer"
 	"github.com/tigrisdata/tigris/server/quota"
+	"github.com/tigrisdata/tigris/server/ratelimit"
 	"github.com/tigrisdata/tigris/server/request"
+	v1 "github.com/tigrisdata/tigris/server/services/v1"
 	"github.com/tigrisdata/tigris/server/tracing"
 	"github.com/tigrisdata/tigris/server/transaction"
 	"github.com/tigrisdata/tigris/store/kv"
@@ -50,6 +58,15 @@ func mainWithCode() int {
 	}
 	defer closerFunc()
 
+	if err = metrics.ValidateTimerConfig(&config.DefaultConfig.Metrics); err != nil {
+		log.Error().Err(err).Msg("invalid metrics config")
+		return 1
+	}
+	if err = metrics.ValidateFdbOperations(&config.DefaultConfig.Metrics); err != nil {
+		log.Error().Err(err).Msg("invalid metrics config")
+		return 1
+	}
+
 	// Initialize metrics once
 	cleanup := metrics.InitializeMetrics()
 	defer cleanup()
@@ -79,6 +96,14 @@ func mainWithCode() int {
 		return 1
 	}
 
+	var batchingStore *search.BatchingStore
+	if config.DefaultConfig.Search.IndexBatch.Enabled {
+		batchingStore = search.NewBatchingStore(searchStore, &config.DefaultConfig.Search.IndexBatch)
+		defer batchingStore.Close()
+		searchStore = batchingStore
+		log.Info().Msg("initialized search index batching")
+	}
+
 	txMgr := transaction.NewManager(kvStore)
 	log.Info().Msg("initialized transaction manager")
 
@@ -90,12 +115,36 @@ func mainWithCode() int {
 		return 1
 	}
 
+	schema.MaxNestingDepth = config.DefaultConfig.Schema.MaxNestingDepth
+	schema.LargeArrayThreshold = config.DefaultConfig.Schema.LargeArrayThreshold
+	schema.MaxViolations = config.DefaultConfig.Schema.MaxViolations
+	schema.BannedFields = config.DefaultConfig.Schema.BannedFields
+	schema.StrictDateTime = config.DefaultConfig.Schema.StrictDateTime
+	if schema.ValidationErrorVerbosity, err = schema.ParseErrorVerbosity(config.DefaultConfig.Schema.ErrorVerbosity); err != nil {
+		log.Error().Err(err).Msg("invalid schema config")
+		return 1
+	}
+
 	request.Init(tenantMgr)
-	_ = quota.Init(tenantMgr, &config.DefaultConfig)
+	_ = quota.Init(tenantMgr, txMgr, &config.DefaultConfig)
 	defer quota.Cleanup()
 
+	if err = authz.Init(tenantMgr, txMgr, &config.DefaultConfig); err != nil {
+		log.Error().Err(err).Msg("error initializing authorization")
+		return 1
+	}
+	defer authz.Cleanup()
+	ratelimit.Init(tenantMgr, txMgr, &config.DefaultConfig)
+
+	if cfg := &config.DefaultConfig.Metrics.Size; config.DefaultConfig.Metrics.Enabled && cfg.Enabled && cfg.Reporter.Enabled {
+		reporter := metrics.NewSizeReporter(&cfg.Reporter, metadata.NewCollectionStats(tenantMgr))
+		reporter.Start()
+		defer reporter.Stop()
+	}
+
 	mx := muxer.NewMuxer(&config.DefaultConfig)
 	mx.RegisterServices(kvStore, searchStore, tenantMgr, txMgr)
+	shutdownOnSignal(&config.DefaultConfig.Server, mx)
 
 	if err := mx.Start(config.DefaultConfig.Server.Host, config.DefaultConfig.Server.Port); err != nil {
 		log.Error().Err(err).Msgf("error starting server")
@@ -105,3 +154,22 @@ func mainWithCode() int {
 	log.Info().Msg("Shutdown")
 	return 0
 }
+
+// shutdownOnSignal stops the server when it is asked to. The readiness probe fails for the shutdown delay first, so
+// that the load balancers stop routing new requests to the server. The server then stops accepting connections and
+// waits for the calls in flight, once they are done Start returns and the deferred cleanups of main run, like the
+// flush of the buffered index operations whose documents are already committed to the database.
+func shutdownOnSignal(cfg *config.ServerConfig, mx *muxer.Muxer) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		log.Info().Str("signal", sig.String()).Dur("delay", cfg.ShutdownDelay).Msg("Shutting down")
+		v1.SetShuttingDown()
+		time.Sleep(cfg.ShutdownDelay)
+
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+		mx.Stop(ctx)
+	}()
+}
@@ -0,0 +1,141 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/tigrisdata/tigris/store/kv"
+	ulog "github.com/tigrisdata/tigris/util/log"
+)
+
+// CountDocuments counts the documents of the collection, it stops once more than limit documents are read and returns
+// limit+1 in that case.
+func (tenant *Tenant) CountDocuments(ctx context.Context, db *Database, coll *schema.DefaultCollection, limit int64) (int64, error) {
+	tenant.Lock()
+	table, err := tenant.Encoder.EncodeTableName(tenant.namespace, db, coll)
+	tenant.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	// the reads of the shards are stopped once the limit is reached
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// the documents are only counted, so the shards are read in parallel in any order
+	it, err := tenant.kvStore.ReadRangeParallel(ctx, table, nil, nil, true, false)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		count int64
+		v     kv.KeyValue
+	)
+	for count <= limit && it.Next(&v) {
+		count++
+	}
+
+	return count, it.Err()
+}
+
+// CollectionStats exposes the size and the number of documents of the collections of all the tenants to the
+// metrics.SizeReporter.
+type CollectionStats struct {
+	tenantMgr *TenantManager
+}
+
+var _ metrics.CollectionStatsSource = &CollectionStats{}
+
+func NewCollectionStats(tenantMgr *TenantManager) *CollectionStats {
+	return &CollectionStats{tenantMgr: tenantMgr}
+}
+
+func (s *CollectionStats) ListCollections(ctx context.Context) ([]metrics.CollectionRef, error) {
+	var colls []metrics.CollectionRef
+	for _, namespace := range s.tenantMgr.GetNamespaceNames() {
+		tenant, err := s.tenantMgr.GetTenant(ctx, namespace)
+		if ulog.E(err) {
+			continue
+		}
+		tenantName := tenant.GetNamespace().Metadata().Name
+
+		for _, dbName := range tenant.ListDatabases(ctx) {
+			db, err := tenant.GetDatabase(ctx, dbName)
+			if err != nil {
+				return nil, err
+			}
+			if db == nil {
+				// the database is dropped in between
+				continue
+			}
+
+			for _, coll := range db.ListCollection() {
+				colls = append(colls, metrics.CollectionRef{
+					Namespace:     namespace,
+					NamespaceName: tenantName,
+					Db:            dbName,
+					Collection:    coll.Name,
+				})
+			}
+		}
+	}
+
+	return colls, nil
+}
+
+func (s *CollectionStats) CollectionSize(ctx context.Context, ref metrics.CollectionRef) (int64, error) {
+	tenant, db, coll, err := s.resolve(ctx, ref)
+	if err != nil {
+		return 0, err
+	}
+
+	return tenant.CollectionSize(ctx, db, coll)
+}
+
+func (s *CollectionStats) CountDocuments(ctx context.Context, ref metrics.CollectionRef, limit int64) (int64, error) {
+	tenant, db, coll, err := s.resolve(ctx, ref)
+	if err != nil {
+		return 0, err
+	}
+
+	return tenant.CountDocuments(ctx, db, coll, limit)
+}
+
+func (s *CollectionStats) resolve(ctx context.Context, ref metrics.CollectionRef) (*Tenant, *Database, *schema.DefaultCollection, error) {
+	tenant, err := s.tenantMgr.GetTenant(ctx, ref.Namespace)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	db, err := tenant.GetDatabase(ctx, ref.Db)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if db == nil {
+		return nil, nil, nil, errors.NotFound("database doesn't exist '%s'", ref.Db)
+	}
+
+	coll := db.GetCollection(ref.Collection)
+	if coll == nil {
+		return nil, nil, nil, errors.NotFound("collection doesn't exist '%s'", ref.Collection)
+	}
+
+	return tenant, db, coll, nil
+}
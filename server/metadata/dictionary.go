@@ -39,6 +39,12 @@ import (
 //   where,
 //     "reserved", "namespace", and "created" are keywords and "namespace1" is a namespace.
 //
+// A deleted namespace is moved to a tombstone, so that its id is never assigned again and the data left under the id
+// is never visible to another namespace,
+//   [“reserved”, "dropped_namespace", 0x05, "dropped"] = x
+//   where,
+//     "reserved", "dropped_namespace", and "dropped" are keywords and 0x05 is the id of the deleted namespace.
+//
 // The second subspace is the "encoding" which is used to assign dictionary encoded values for the database, collection
 // and any index names. Values assigned are monotonically incremental counter and are local to this cluster and doesn't
 // need to be unique across the Tigris ecosystem.
@@ -75,13 +81,14 @@ import (
 //
 //	["encoding", 0x01, x, 0x01, 0x03, "index", "pkey", "dropped"] = 0x04
 const (
-	namespaceKey  = "namespace"
-	dbKey         = "db"
-	collectionKey = "coll"
-	counterKey    = "counter"
-	indexKey      = "index"
-	keyEnd        = "created"
-	keyDroppedEnd = "dropped"
+	namespaceKey        = "namespace"
+	droppedNamespaceKey = "dropped_namespace"
+	dbKey               = "db"
+	collectionKey       = "coll"
+	counterKey          = "counter"
+	indexKey            = "index"
+	keyEnd              = "created"
+	keyDroppedEnd       = "dropped"
 )
 
 const (
@@ -104,6 +111,8 @@ type reservedSubspace struct {
 
 	idToNamespaceStruct    map[uint32]NamespaceMetadata
 	strIdToNamespaceStruct map[string]NamespaceMetadata
+	// droppedIdToNamespace are the namespaces that are deleted, their ids are never assigned again.
+	droppedIdToNamespace map[uint32]NamespaceMetadata
 }
 
 func newReservedSubspace(mdNameRegistry MDNameRegistry) *reservedSubspace {
@@ -112,6 +121,7 @@ func newReservedSubspace(mdNameRegistry MDNameRegistry) *reservedSubspace {
 
 		idToNamespaceStruct:    make(map[uint32]NamespaceMetadata),
 		strIdToNamespaceStruct: make(map[string]NamespaceMetadata),
+		droppedIdToNamespace:   make(map[uint32]NamespaceMetadata),
 	}
 }
 
@@ -128,6 +138,10 @@ func (r *reservedSubspace) reload(ctx context.Context, tx transaction.Tx) error
 	r.Lock()
 	defer r.Unlock()
 
+	// reset, the namespaces deleted since the last reload are not in the subspace anymore
+	r.idToNamespaceStruct = make(map[uint32]NamespaceMetadata)
+	r.strIdToNamespaceStruct = make(map[string]NamespaceMetadata)
+
 	key := keys.NewKey(r.ReservedSubspaceName(), namespaceKey)
 	it, err := tx.Read(ctx, key)
 	if err != nil {
@@ -159,10 +173,97 @@ func (r *reservedSubspace) reload(ctx context.Context, tx transaction.Tx) error
 		r.idToNamespaceStruct[namespaceMetadata.Id] = namespaceMetadata
 		r.strIdToNamespaceStruct[namespaceMetadata.StrId] = namespaceMetadata
 	}
+	if err = it.Err(); err != nil {
+		return err
+	}
+
+	return r.reloadDropped(ctx, tx)
+}
+
+func (r *reservedSubspace) reloadDropped(ctx context.Context, tx transaction.Tx) error {
+	r.droppedIdToNamespace = make(map[uint32]NamespaceMetadata)
+
+	it, err := tx.Read(ctx, keys.NewKey(r.ReservedSubspaceName(), droppedNamespaceKey))
+	if err != nil {
+		return err
+	}
+
+	var row kv.KeyValue
+	for it.Next(&row) {
+		var namespaceMetadata NamespaceMetadata
+		if err = json.Unmarshal(row.Data.RawData, &namespaceMetadata); err != nil {
+			return errors.Internal("unable to read the dropped namespace for the key %v", row.Key)
+		}
+		r.droppedIdToNamespace[namespaceMetadata.Id] = namespaceMetadata
+	}
 
 	return it.Err()
 }
 
+// nextNamespaceId returns the id following the ids assigned to the existing and the deleted namespaces.
+func (r *reservedSubspace) nextNamespaceId() uint32 {
+	r.RLock()
+	defer r.RUnlock()
+
+	maxId := InvalidId
+	for id := range r.idToNamespaceStruct {
+		if id > maxId {
+			maxId = id
+		}
+	}
+	for id := range r.droppedIdToNamespace {
+		if id > maxId {
+			maxId = id
+		}
+	}
+	return maxId + 1
+}
+
+// dropNamespace removes the reservation of the namespace and keeps a tombstone of its id.
+func (r *reservedSubspace) dropNamespace(ctx context.Context, tx transaction.Tx, namespaceId string) error {
+	if err := r.reload(ctx, tx); ulog.E(err) {
+		return err
+	}
+
+	r.RLock()
+	namespaceMetadata, ok := r.strIdToNamespaceStruct[namespaceId]
+	r.RUnlock()
+	if !ok {
+		return errors.NotFound("namespace '%s' doesn't exist", namespaceId)
+	}
+
+	key := keys.NewKey(r.ReservedSubspaceName(), namespaceKey, namespaceId, keyEnd)
+	if err := tx.Delete(ctx, key); err != nil {
+		log.Debug().Str("key", key.String()).Err(err).Msg("dropping namespace failed")
+		return err
+	}
+
+	namespaceMetadataBytes, err := json.Marshal(namespaceMetadata)
+	if err != nil {
+		return err
+	}
+	droppedKey := keys.NewKey(r.ReservedSubspaceName(), droppedNamespaceKey, UInt32ToByte(namespaceMetadata.Id), keyDroppedEnd)
+	if err := tx.Insert(ctx, droppedKey, internal.NewTableDataWithEncoding(namespaceMetadataBytes, namespaceJsonEncoding)); err != nil {
+		log.Debug().Str("key", droppedKey.String()).Uint32("value", namespaceMetadata.Id).Err(err).Msg("dropping namespace failed")
+		return err
+	}
+
+	log.Debug().Str("key", droppedKey.String()).Uint32("value", namespaceMetadata.Id).Msg("dropping namespace succeed")
+	return nil
+}
+
+// isDropped returns true if the id was assigned to a namespace that is deleted.
+func (r *reservedSubspace) isDropped(ctx context.Context, tx transaction.Tx, id uint32) (bool, error) {
+	it, err := tx.Read(ctx, keys.NewKey(r.ReservedSubspaceName(), droppedNamespaceKey, UInt32ToByte(id), keyDroppedEnd))
+	if err != nil {
+		return false, err
+	}
+
+	var row kv.KeyValue
+	dropped := it.Next(&row)
+	return dropped, it.Err()
+}
+
 func (r *reservedSubspace) reserveNamespace(ctx context.Context, tx transaction.Tx, namespaceId string, namespaceMetadata NamespaceMetadata) error {
 	if len(namespaceId) == 0 {
 		return errors.InvalidArgument("namespaceId is empty")
@@ -186,6 +287,9 @@ func (r *reservedSubspace) reserveNamespace(ctx context.Context, tx transaction.
 			}
 		}
 	}
+	if dropped, ok := r.droppedIdToNamespace[namespaceMetadata.Id]; ok {
+		return errors.AlreadyExists("id was assigned to the deleted namespace '%s', the ids are never reused", dropped.StrId)
+	}
 
 	key := keys.NewKey(r.ReservedSubspaceName(), namespaceKey, namespaceId, keyEnd)
 	// now do an insert to fail if namespace already exists.
@@ -258,6 +362,21 @@ func (k *MetadataDictionary) GetNamespaces(ctx context.Context, tx transaction.T
 	return k.reservedSb.getNamespaces(), nil
 }
 
+// DropNamespace removes the reservation of the namespace. The id of the namespace is never assigned again, so that
+// the data left under it is never visible to another namespace.
+func (k *MetadataDictionary) DropNamespace(ctx context.Context, tx transaction.Tx, namespaceId string) error {
+	return k.reservedSb.dropNamespace(ctx, tx, namespaceId)
+}
+
+// NextNamespaceId returns an id that was never assigned to a namespace.
+func (k *MetadataDictionary) NextNamespaceId(ctx context.Context, tx transaction.Tx) (uint32, error) {
+	if err := k.reservedSb.reload(ctx, tx); err != nil {
+		return InvalidId, err
+	}
+
+	return k.reservedSb.nextNamespaceId(), nil
+}
+
 func (k *MetadataDictionary) CreateDatabase(ctx context.Context, tx transaction.Tx, dbName string, namespaceId uint32) (uint32, error) {
 	if err := k.validNamespaceId(namespaceId); err != nil {
 		return InvalidId, err
@@ -265,6 +384,14 @@ func (k *MetadataDictionary) CreateDatabase(ctx context.Context, tx transaction.
 	if len(dbName) == 0 {
 		return InvalidId, errors.InvalidArgument("database name is empty")
 	}
+	// the tenant may be cached by this server after the namespace was deleted by another one
+	dropped, err := k.reservedSb.isDropped(ctx, tx, namespaceId)
+	if err != nil {
+		return InvalidId, err
+	}
+	if dropped {
+		return InvalidId, errors.NotFound("namespace with id '%d' is deleted", namespaceId)
+	}
 
 	key := keys.NewKey(k.EncodingSubspaceName(), encVersion, UInt32ToByte(namespaceId), dbKey, dbName, keyEnd)
 	return k.allocateAndSave(ctx, tx, key, dbKey)
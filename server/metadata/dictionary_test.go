@@ -433,6 +433,49 @@ func TestReservedNamespace(t *testing.T) {
 	require.Equal(t, expError, r.reserveNamespace(context.TODO(), tx, "p2-o2", NewNamespaceMetadata(123, "p2-o2", "p2-o2-display_name")))
 }
 
+func TestDroppedNamespace(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	r := newReservedSubspace(&TestMDNameRegistry{
+		ReserveSB:  "test_reserved",
+		EncodingSB: "test_encoding",
+	})
+
+	_ = kvStore.DropTable(ctx, r.EncodingSubspaceName())
+	_ = kvStore.DropTable(ctx, r.ReservedSubspaceName())
+
+	tm := transaction.NewManager(kvStore)
+
+	tx, err := tm.StartTx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, r.reserveNamespace(ctx, tx, "p1-o1", NewNamespaceMetadata(123, "p1-o1", "p1-o1-display_name")))
+	require.NoError(t, r.reserveNamespace(ctx, tx, "p2-o2", NewNamespaceMetadata(12, "p2-o2", "p2-o2-display_name")))
+	require.NoError(t, r.dropNamespace(ctx, tx, "p1-o1"))
+	require.NoError(t, tx.Commit(ctx))
+
+	tx, err = tm.StartTx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, r.reload(ctx, tx))
+	require.Equal(t, map[string]NamespaceMetadata{"p2-o2": NewNamespaceMetadata(12, "p2-o2", "p2-o2-display_name")}, r.getNamespaces())
+	// the id of the dropped namespace is never assigned again
+	require.Equal(t, uint32(124), r.nextNamespaceId())
+	dropped, err := r.isDropped(ctx, tx, 123)
+	require.NoError(t, err)
+	require.True(t, dropped)
+	dropped, err = r.isDropped(ctx, tx, 12)
+	require.NoError(t, err)
+	require.False(t, dropped)
+
+	expError := errors.AlreadyExists("id was assigned to the deleted namespace 'p1-o1', the ids are never reused")
+	require.Equal(t, expError, r.reserveNamespace(ctx, tx, "p3-o3", NewNamespaceMetadata(123, "p3-o3", "p3-o3-display_name")))
+	require.Equal(t, errors.NotFound("namespace 'p1-o1' doesn't exist"), r.dropNamespace(ctx, tx, "p1-o1"))
+
+	// the name of the dropped namespace can be reused with a new id
+	require.NoError(t, r.reserveNamespace(ctx, tx, "p1-o1", NewNamespaceMetadata(124, "p1-o1", "p1-o1-display_name")))
+	require.NoError(t, tx.Commit(ctx))
+}
+
 func TestDecode(t *testing.T) {
 	k := kv.BuildKey(encVersion, UInt32ToByte(1234), dbKey, "db-1", keyEnd)
 	mp, err := NewMetadataDictionary(&TestMDNameRegistry{
@@ -32,9 +32,16 @@ const (
 // NamespaceSubspace is used to store metadata about Tigris namespaces.
 type NamespaceSubspace struct {
 	MDNameRegistry
+
+	reserved bool
 }
 
-var namespaceVersion = []byte{0x01}
+var (
+	namespaceVersion = []byte{0x01}
+	// reservedNamespaceMetadata prefixes the keys of the reserved namespace metadata, the keys written through the
+	// namespace metadata API have a single part so they never match these keys.
+	reservedNamespaceMetadata = []byte("reserved")
+)
 
 func NewNamespaceStore(mdNameRegistry MDNameRegistry) *NamespaceSubspace {
 	return &NamespaceSubspace{
@@ -42,11 +49,28 @@ func NewNamespaceStore(mdNameRegistry MDNameRegistry) *NamespaceSubspace {
 	}
 }
 
+// NewReservedNamespaceStore returns the store of the metadata only the server writes for the namespaces, like the
+// limits set by the operators. It is not reachable through the namespace metadata API and is deleted with the
+// namespace.
+func NewReservedNamespaceStore(mdNameRegistry MDNameRegistry) *NamespaceSubspace {
+	return &NamespaceSubspace{
+		MDNameRegistry: mdNameRegistry,
+		reserved:       true,
+	}
+}
+
+func (n *NamespaceSubspace) metadataKey(namespaceId uint32, metadataKey string) keys.Key {
+	if n.reserved {
+		return keys.NewKey(n.NamespaceSubspaceName(), namespaceVersion, UInt32ToByte(namespaceId), reservedNamespaceMetadata, []byte(metadataKey))
+	}
+	return keys.NewKey(n.NamespaceSubspaceName(), namespaceVersion, UInt32ToByte(namespaceId), []byte(metadataKey))
+}
+
 func (n *NamespaceSubspace) InsertNamespaceMetadata(ctx context.Context, tx transaction.Tx, namespaceId uint32, metadataKey string, payload []byte) error {
 	if err := validateNamespaceArgs(namespaceId, metadataKey, payload); err != nil {
 		return err
 	}
-	key := keys.NewKey(n.NamespaceSubspaceName(), namespaceVersion, UInt32ToByte(namespaceId), []byte(metadataKey))
+	key := n.metadataKey(namespaceId, metadataKey)
 	if err := tx.Insert(ctx, key, internal.NewTableData(payload)); err != nil {
 		log.Debug().Str("key", key.String()).Str("value", string(payload)).Err(err).Msg("storing namespace metadata failed")
 		return err
@@ -61,7 +85,7 @@ func (n *NamespaceSubspace) GetNamespaceMetadata(ctx context.Context, tx transac
 	if err := validateNamespaceArgsPartial1(namespaceId, metadataKey); err != nil {
 		return nil, err
 	}
-	key := keys.NewKey(n.NamespaceSubspaceName(), namespaceVersion, UInt32ToByte(namespaceId), []byte(metadataKey))
+	key := n.metadataKey(namespaceId, metadataKey)
 	it, err := tx.Read(ctx, key)
 	if err != nil {
 		return nil, err
@@ -79,7 +103,7 @@ func (n *NamespaceSubspace) UpdateNamespaceMetadata(ctx context.Context, tx tran
 	if err := validateNamespaceArgs(namespaceId, metadataKey, payload); err != nil {
 		return err
 	}
-	key := keys.NewKey(n.NamespaceSubspaceName(), namespaceVersion, UInt32ToByte(namespaceId), []byte(metadataKey))
+	key := n.metadataKey(namespaceId, metadataKey)
 
 	_, err := tx.Update(ctx, key, func(data *internal.TableData) (*internal.TableData, error) {
 		return internal.NewTableData(payload), nil
@@ -95,7 +119,7 @@ func (n *NamespaceSubspace) DeleteNamespaceMetadata(ctx context.Context, tx tran
 	if err := validateNamespaceArgsPartial1(namespaceId, metadataKey); err != nil {
 		return err
 	}
-	key := keys.NewKey(n.NamespaceSubspaceName(), namespaceVersion, UInt32ToByte(namespaceId), []byte(metadataKey))
+	key := n.metadataKey(namespaceId, metadataKey)
 	err := tx.Delete(ctx, key)
 	if err != nil {
 		log.Debug().Str("key", key.String()).Err(err).Msg("Delete namespace metadata failed")
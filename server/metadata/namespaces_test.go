@@ -206,4 +206,38 @@ func TestNamespacesSubspace(t *testing.T) {
 
 		_ = kvStore.DropTable(ctx, n.NamespaceSubspaceName())
 	})
+
+	t.Run("reserved", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		registry := &TestMDNameRegistry{
+			NamespaceSB: "test_namespace",
+		}
+		n := NewNamespaceStore(registry)
+		reserved := NewReservedNamespaceStore(registry)
+		_ = kvStore.DropTable(ctx, n.NamespaceSubspaceName())
+
+		tm := transaction.NewManager(kvStore)
+		tx, err := tm.StartTx(ctx)
+		require.NoError(t, err)
+		require.NoError(t, reserved.InsertNamespaceMetadata(ctx, tx, 1, "storage_quota", []byte(`{"size":1}`)))
+
+		// the namespace metadata doesn't see the reserved metadata, writing the same key doesn't change it
+		value, err := n.GetNamespaceMetadata(ctx, tx, 1, "storage_quota")
+		require.NoError(t, err)
+		require.Nil(t, value)
+		require.NoError(t, n.InsertNamespaceMetadata(ctx, tx, 1, "storage_quota", []byte(`{"size":100}`)))
+		value, err = reserved.GetNamespaceMetadata(ctx, tx, 1, "storage_quota")
+		require.NoError(t, err)
+		require.Equal(t, []byte(`{"size":1}`), value)
+
+		// the reserved metadata is deleted with the namespace
+		require.NoError(t, n.DeleteNamespace(ctx, tx, 1))
+		value, err = reserved.GetNamespaceMetadata(ctx, tx, 1, "storage_quota")
+		require.NoError(t, err)
+		require.Nil(t, value)
+
+		_ = kvStore.DropTable(ctx, n.NamespaceSubspaceName())
+	})
 }
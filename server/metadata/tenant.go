@@ -17,13 +17,12 @@ package metadata
 import (
 	"bytes"
 	"context"
+	goerrors "errors"
 	"fmt"
 	"math/rand"
-	"reflect"
 	"sync"
 	"time"
 
-	jsoniter "github.com/json-iterator/go"
 	"github.com/rs/zerolog/log"
 	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/schema"
@@ -38,6 +37,9 @@ import (
 
 type NamespaceType string
 
+// ErrNamespaceNotFound is returned when the namespace of a tenant doesn't exist.
+var ErrNamespaceNotFound = goerrors.New("namespace not found")
+
 const (
 	baseSchemaVersion = 1
 )
@@ -245,6 +247,109 @@ func (m *TenantManager) CreateTenant(ctx context.Context, tx transaction.Tx, nam
 	return namespace, nil
 }
 
+// NextNamespaceId returns an id for a new namespace, the ids of the deleted namespaces are never returned.
+func (m *TenantManager) NextNamespaceId(ctx context.Context, tx transaction.Tx) (uint32, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.metaStore.NextNamespaceId(ctx, tx)
+}
+
+// DeleteTenant deletes the namespace. A namespace with databases is only deleted with force, then its databases are
+// dropped along with their collections in the search store. The id of the namespace is never assigned again and the
+// data stored under it is cleared once the deletion is committed. It returns the namespace that was deleted.
+func (m *TenantManager) DeleteTenant(ctx context.Context, namespaceId string, force bool) (namespace Namespace, err error) {
+	if namespaceId == defaults.DefaultNamespaceName {
+		return nil, errors.InvalidArgument("the default namespace can't be deleted")
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	collectionsInSearch, err := m.searchStore.AllCollections(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := m.txMgr.StartTx(ctx)
+	if ulog.E(err) {
+		return nil, err
+	}
+
+	defer func() {
+		if err == nil {
+			if err = tx.Commit(ctx); err == nil {
+				delete(m.tenants, namespace.StrId())
+				delete(m.idToTenantMap, namespace.Id())
+				// the id is never reused so the data left if clearing fails is never visible to another namespace
+				ulog.E(m.clearTenantData(ctx, namespace))
+			}
+		} else {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	return m.deleteTenantInternal(ctx, tx, namespaceId, force, collectionsInSearch)
+}
+
+func (m *TenantManager) deleteTenantInternal(ctx context.Context, tx transaction.Tx, namespaceId string, force bool, collectionsInSearch map[string]*tsApi.CollectionResponse) (Namespace, error) {
+	namespaces, err := m.metaStore.GetNamespaces(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	metadata, ok := namespaces[namespaceId]
+	if !ok {
+		return nil, errors.NotFound("namespace '%s' doesn't exist", namespaceId)
+	}
+
+	currentVersion, err := m.versionH.Read(ctx, tx, false)
+	if ulog.E(err) {
+		return nil, err
+	}
+
+	// the databases are read in the transaction instead of the cache to not miss the ones created by other servers
+	namespace := NewTenantNamespace(namespaceId, metadata)
+	tenant := NewTenant(namespace, m.kvStore, m.searchStore, m.metaStore, m.schemaStore, m.encoder, m.versionH, currentVersion, m.tableKeyGenerator)
+	tenant.Lock()
+	err = tenant.reload(ctx, tx, currentVersion, collectionsInSearch)
+	tenant.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	databases := tenant.ListDatabases(ctx)
+	if len(databases) > 0 && !force {
+		return nil, errors.FailedPrecondition("namespace '%s' is not empty, it has %d databases", namespaceId, len(databases))
+	}
+	for _, db := range databases {
+		if _, err = tenant.DropDatabase(ctx, tx, db); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = NewNamespaceStore(m.mdNameRegistry).DeleteNamespace(ctx, tx, namespace.Id()); err != nil {
+		return nil, err
+	}
+	if err = m.metaStore.DropNamespace(ctx, tx, namespaceId); ulog.E(err) {
+		return nil, err
+	}
+	if err = m.versionH.IncrementNamespace(ctx, tx, namespace.Id()); ulog.E(err) {
+		return nil, err
+	}
+
+	return namespace, nil
+}
+
+// clearTenantData clears the data stored under the id of a deleted namespace.
+func (m *TenantManager) clearTenantData(ctx context.Context, namespace Namespace) error {
+	nsName, err := m.encoder.EncodeTableName(namespace, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	return m.kvStore.DropTable(ctx, nsName)
+}
+
 func (m *TenantManager) getTenantFromCache(namespaceName string) (tenant *Tenant) {
 	m.RLock()
 	defer m.RUnlock()
@@ -255,6 +360,9 @@ func (m *TenantManager) getTenantFromCache(namespaceName string) (tenant *Tenant
 }
 
 func (m *TenantManager) GetNamespaceNames() []string {
+	m.RLock()
+	defer m.RUnlock()
+
 	res := make([]string, 0, len(m.tenants))
 	for name := range m.tenants {
 		res = append(res, name)
@@ -314,7 +422,7 @@ func (m *TenantManager) GetTenant(ctx context.Context, namespaceName string) (te
 	}
 	metadata, ok := namespaces[namespaceName]
 	if !ok {
-		return nil, fmt.Errorf("namespace not found: %s", namespaceName)
+		return nil, fmt.Errorf("%w: %s", ErrNamespaceNotFound, namespaceName)
 	}
 
 	currentVersion, err := m.versionH.Read(ctx, tx, false)
@@ -472,6 +580,27 @@ func (m *TenantManager) Reload(ctx context.Context, tx transaction.Tx, collectio
 	return err
 }
 
+// CachedVersion returns the most recent metadata version loaded in the cache and false while the metadata is not loaded
+// yet. The tenants are reloaded lazily by the requests that detect a change, so this is the version of the most recently
+// reloaded tenant.
+func (m *TenantManager) CachedVersion() (Version, bool) {
+	m.RLock()
+	defer m.RUnlock()
+
+	if m.version == nil {
+		return nil, false
+	}
+	latest := m.version
+	for _, tenant := range m.tenants {
+		tenant.RLock()
+		if bytes.Compare(tenant.version, latest) > 0 {
+			latest = tenant.version
+		}
+		tenant.RUnlock()
+	}
+	return latest, true
+}
+
 func (m *TenantManager) reload(ctx context.Context, tx transaction.Tx, currentVersion Version, collectionsInSearch map[string]*tsApi.CollectionResponse) error {
 	namespaces, err := m.metaStore.GetNamespaces(ctx, tx)
 	if err != nil {
@@ -479,6 +608,14 @@ func (m *TenantManager) reload(ctx context.Context, tx transaction.Tx, currentVe
 	}
 	log.Debug().Interface("ns", namespaces).Msg("existing reserved namespaces")
 
+	for namespace, tenant := range m.tenants {
+		if _, ok := namespaces[namespace]; !ok {
+			// the namespace was deleted
+			delete(m.tenants, namespace)
+			delete(m.idToTenantMap, tenant.namespace.Id())
+		}
+	}
+
 	for namespace, metadata := range namespaces {
 		if _, ok := m.tenants[namespace]; !ok {
 			m.tenants[namespace] = NewTenant(NewTenantNamespace(namespace, metadata), m.kvStore, m.searchStore, m.metaStore, m.schemaStore, m.encoder, m.versionH, currentVersion, m.tableKeyGenerator)
@@ -648,8 +785,8 @@ func (tenant *Tenant) DropDatabase(ctx context.Context, tx transaction.Tx, dbNam
 // As reloading of tenant state is happening at the session manager layer so GetDatabase calls assume that the caller
 // just needs the state from the cache.
 func (tenant *Tenant) GetDatabase(_ context.Context, dbName string) (*Database, error) {
-	tenant.Lock()
-	defer tenant.Unlock()
+	tenant.RLock()
+	defer tenant.RUnlock()
 
 	return tenant.databases[dbName], nil
 }
@@ -736,13 +873,17 @@ func (tenant *Tenant) CreateCollection(ctx context.Context, tx transaction.Tx, d
 
 	// first check if we need to run update collection
 	if c, ok := database.collections[schFactory.Name]; ok {
-		if eq, err := isSchemaEq(c.collection.Schema, schFactory.Schema); eq || err != nil {
+		if eq, err := isSchemaEq(c.collection.Schema, schFactory); eq || err != nil {
 			// shortcut to just check if schema is eq then return early
 			return err
 		}
 		return tenant.updateCollection(ctx, tx, database, c, schFactory)
 	}
 
+	if err := schema.CheckBannedFields(schFactory); err != nil {
+		return err
+	}
+
 	// add indexing version here in the name, because this is a fresh create collection request
 	if err := schema.SetIndexingVersion(schFactory); err != nil {
 		return err
@@ -944,6 +1085,11 @@ func (tenant *Tenant) DatabaseSize(ctx context.Context, db *Database) (int64, er
 	return tenant.kvStore.TableSize(ctx, nsName)
 }
 
+// GetCollectionSchemas returns all the revisions of the schema of the collection, in the order of the revisions.
+func (tenant *Tenant) GetCollectionSchemas(ctx context.Context, tx transaction.Tx, db *Database, coll *schema.DefaultCollection) ([][]byte, []int, error) {
+	return tenant.schemaStore.Get(ctx, tx, tenant.namespace.Id(), db.id, coll.Id)
+}
+
 // CollectionSize returns approximate data size on disk for all the collections for the database provided by the caller.
 func (tenant *Tenant) CollectionSize(ctx context.Context, db *Database, coll *schema.DefaultCollection) (int64, error) {
 	tenant.Lock()
@@ -954,6 +1100,48 @@ func (tenant *Tenant) CollectionSize(ctx context.Context, db *Database, coll *sc
 	return tenant.kvStore.TableSize(ctx, nsName)
 }
 
+// DatabaseSizeExact returns the data size on disk of the collections of the database computed by reading their
+// documents, at most rowLimit of them in total.
+func (tenant *Tenant) DatabaseSizeExact(ctx context.Context, db *Database, rowLimit int64) (int64, error) {
+	var size, rows int64
+	for _, coll := range db.ListCollection() {
+		collSize, collRows, err := tenant.collectionSizeExact(ctx, db, coll, rowLimit-rows)
+		if err != nil {
+			return 0, err
+		}
+		size, rows = size+collSize, rows+collRows
+	}
+
+	return size, nil
+}
+
+// CollectionSizeExact returns the data size on disk of the collection computed by reading its documents, at most
+// rowLimit of them.
+func (tenant *Tenant) CollectionSizeExact(ctx context.Context, db *Database, coll *schema.DefaultCollection, rowLimit int64) (int64, error) {
+	size, _, err := tenant.collectionSizeExact(ctx, db, coll, rowLimit)
+	return size, err
+}
+
+// collectionSizeExact reads the documents of the collection, the keys of its primary key index, and returns their size
+// and their number.
+func (tenant *Tenant) collectionSizeExact(ctx context.Context, db *Database, coll *schema.DefaultCollection, rowLimit int64) (int64, int64, error) {
+	tenant.Lock()
+	nsName, _ := tenant.Encoder.EncodeTableName(tenant.namespace, db, coll)
+	idxName := tenant.Encoder.EncodeIndexName(coll.Indexes.PrimaryKey)
+	tenant.Unlock()
+
+	return tenant.kvStore.TableSizeExact(ctx, nsName, kv.BuildKey(idxName), rowLimit)
+}
+
+// CollectionRangeSizes returns the approximate data sizes on disk of at most buckets sub-ranges of the collection.
+func (tenant *Tenant) CollectionRangeSizes(ctx context.Context, db *Database, coll *schema.DefaultCollection, buckets int) ([]kv.RangeSize, error) {
+	tenant.Lock()
+	nsName, _ := tenant.Encoder.EncodeTableName(tenant.namespace, db, coll)
+	tenant.Unlock()
+
+	return tenant.kvStore.TableRangeSizes(ctx, nsName, buckets)
+}
+
 // Database is to manage the collections for this database. Check the Clone method before changing this struct.
 type Database struct {
 	sync.RWMutex
@@ -1093,7 +1281,7 @@ func (c *collectionHolder) get() *schema.DefaultCollection {
 }
 
 func createCollection(id uint32, schVer int, name string, revision []byte, idxNameToId map[string]uint32, searchCollectionName string, fieldsInSearch []tsApi.Field) (*schema.DefaultCollection, error) {
-	schFactory, err := schema.Build(name, revision)
+	schFactory, err := schema.Build(name, revision, false)
 	if err != nil {
 		return nil, err
 	}
@@ -1112,15 +1300,16 @@ func createCollection(id uint32, schVer int, name string, revision []byte, idxNa
 	return schema.NewDefaultCollection(name, id, schVer, schFactory.CollectionType, schFactory, searchCollectionName, fieldsInSearch), nil
 }
 
-func isSchemaEq(s1, s2 []byte) (bool, error) {
-	var j, j2 interface{}
-	if err := jsoniter.Unmarshal(s1, &j); err != nil {
+func isSchemaEq(existing []byte, schFactory *schema.Factory) (bool, error) {
+	f1, err := schema.FingerprintSchema(existing)
+	if err != nil {
 		return false, err
 	}
-	if err := jsoniter.Unmarshal(s2, &j2); err != nil {
+	f2, err := schema.Fingerprint(schFactory)
+	if err != nil {
 		return false, err
 	}
-	return reflect.DeepEqual(j2, j), nil
+	return f1 == f2, nil
 }
 
 // NewTestTenantMgr creates new TenantManager for tests.
@@ -1128,9 +1317,10 @@ func NewTestTenantMgr(kvStore kv.KeyValueStore) (*TenantManager, context.Context
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 
 	m := newTenantManager(kvStore, &search.NoopStore{}, &TestMDNameRegistry{
-		ReserveSB:  fmt.Sprintf("test_tenant_reserve_%x", rand.Uint64()),  //nolint:golint,gosec
-		EncodingSB: fmt.Sprintf("test_tenant_encoding_%x", rand.Uint64()), //nolint:golint,gosec
-		SchemaSB:   fmt.Sprintf("test_tenant_schema_%x", rand.Uint64()),   //nolint:golint,gosec
+		ReserveSB:   fmt.Sprintf("test_tenant_reserve_%x", rand.Uint64()),   //nolint:golint,gosec
+		EncodingSB:  fmt.Sprintf("test_tenant_encoding_%x", rand.Uint64()),  //nolint:golint,gosec
+		SchemaSB:    fmt.Sprintf("test_tenant_schema_%x", rand.Uint64()),    //nolint:golint,gosec
+		NamespaceSB: fmt.Sprintf("test_tenant_namespace_%x", rand.Uint64()), //nolint:golint,gosec
 	},
 		transaction.NewManager(kvStore),
 	)
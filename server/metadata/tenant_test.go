@@ -20,12 +20,15 @@ import (
 	"os"
 	"testing"
 
+	jsoniter "github.com/json-iterator/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/internal"
 	"github.com/tigrisdata/tigris/schema"
 	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/defaults"
 	"github.com/tigrisdata/tigris/server/transaction"
 	"github.com/tigrisdata/tigris/store/kv"
 	ulog "github.com/tigrisdata/tigris/util/log"
@@ -175,6 +178,136 @@ func TestTenantManager_CreateTenant(t *testing.T) {
 	})
 }
 
+func TestTenantManager_DeleteTenant(t *testing.T) {
+	tm := transaction.NewManager(kvStore)
+
+	// createTenant creates the namespace of the id with the databases
+	createTenant := func(t *testing.T, m *TenantManager, ctx context.Context, id uint32, databases ...string) *Tenant {
+		name := fmt.Sprintf("ns-test%d", id)
+		tx, err := tm.StartTx(ctx)
+		require.NoError(t, err)
+		_, err = m.CreateTenant(ctx, tx, &TenantNamespace{name, id, NewNamespaceMetadata(id, name, name+"-display_name")})
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit(ctx))
+
+		tenant, err := m.GetTenant(ctx, name)
+		require.NoError(t, err)
+		for _, db := range databases {
+			tx, err = tm.StartTx(ctx)
+			require.NoError(t, err)
+			_, err = tenant.CreateDatabase(ctx, tx, db)
+			require.NoError(t, err)
+			require.NoError(t, tx.Commit(ctx))
+		}
+		return tenant
+	}
+	cleanup := func(m *TenantManager, ctx context.Context) {
+		_ = kvStore.DropTable(ctx, m.mdNameRegistry.ReservedSubspaceName())
+		_ = kvStore.DropTable(ctx, m.mdNameRegistry.EncodingSubspaceName())
+		_ = kvStore.DropTable(ctx, m.mdNameRegistry.NamespaceSubspaceName())
+	}
+
+	t.Run("non_force", func(t *testing.T) {
+		m, ctx, cancel := NewTestTenantMgr(kvStore)
+		defer cancel()
+		defer cleanup(m, ctx)
+
+		createTenant(t, m, ctx, 2, "db1")
+		createTenant(t, m, ctx, 3)
+
+		// the namespace with a database is only deleted with force, it is kept
+		_, err := m.DeleteTenant(ctx, "ns-test2", false)
+		require.Equal(t, errors.FailedPrecondition("namespace 'ns-test2' is not empty, it has 1 databases"), err)
+		tenant, err := m.GetTenant(ctx, "ns-test2")
+		require.NoError(t, err)
+		require.Equal(t, []string{"db1"}, tenant.ListDatabases(ctx))
+
+		// the empty namespace is deleted without force
+		namespace, err := m.DeleteTenant(ctx, "ns-test3", false)
+		require.NoError(t, err)
+		require.Equal(t, uint32(3), namespace.Id())
+		require.Nil(t, m.getTenantFromCache("ns-test3"))
+
+		_, err = m.DeleteTenant(ctx, "ns-test3", false)
+		require.Equal(t, errors.NotFound("namespace 'ns-test3' doesn't exist"), err)
+		_, err = m.DeleteTenant(ctx, defaults.DefaultNamespaceName, false)
+		require.Equal(t, errors.InvalidArgument("the default namespace can't be deleted"), err)
+	})
+
+	t.Run("force", func(t *testing.T) {
+		m, ctx, cancel := NewTestTenantMgr(kvStore)
+		defer cancel()
+		defer cleanup(m, ctx)
+
+		tenant := createTenant(t, m, ctx, 2, "db1", "db2")
+		tx, err := tm.StartTx(ctx)
+		require.NoError(t, err)
+		require.NoError(t, NewNamespaceStore(m.mdNameRegistry).InsertNamespaceMetadata(ctx, tx, 2, "meta-key-1", []byte(`{}`)))
+		require.NoError(t, NewReservedNamespaceStore(m.mdNameRegistry).InsertNamespaceMetadata(ctx, tx, 2, "meta-key-1", []byte(`{}`)))
+		require.NoError(t, tx.Commit(ctx))
+
+		namespace, err := m.DeleteTenant(ctx, "ns-test2", true)
+		require.NoError(t, err)
+		require.Equal(t, uint32(2), namespace.Id())
+		require.Nil(t, m.getTenantFromCache("ns-test2"))
+
+		// the databases and the metadata of the namespace are deleted with it
+		tx, err = tm.StartTx(ctx)
+		require.NoError(t, err)
+		dbs, err := m.metaStore.GetDatabases(ctx, tx, 2)
+		require.NoError(t, err)
+		require.Empty(t, dbs)
+		value, err := NewNamespaceStore(m.mdNameRegistry).GetNamespaceMetadata(ctx, tx, 2, "meta-key-1")
+		require.NoError(t, err)
+		require.Nil(t, value)
+		value, err = NewReservedNamespaceStore(m.mdNameRegistry).GetNamespaceMetadata(ctx, tx, 2, "meta-key-1")
+		require.NoError(t, err)
+		require.Nil(t, value)
+
+		// the databases can't be created in a deleted namespace that is still cached
+		_, err = tenant.CreateDatabase(ctx, tx, "db3")
+		require.Equal(t, errors.NotFound("namespace with id '2' is deleted"), err)
+		_ = tx.Rollback(ctx)
+	})
+
+	t.Run("id_not_reused", func(t *testing.T) {
+		m, ctx, cancel := NewTestTenantMgr(kvStore)
+		defer cancel()
+		defer cleanup(m, ctx)
+
+		createTenant(t, m, ctx, 2)
+		createTenant(t, m, ctx, 3, "db1")
+		_, err := m.DeleteTenant(ctx, "ns-test2", false)
+		require.NoError(t, err)
+		_, err = m.DeleteTenant(ctx, "ns-test3", true)
+		require.NoError(t, err)
+
+		// the ids of the deleted namespaces, the last one included, are never assigned or accepted again
+		tx, err := tm.StartTx(ctx)
+		require.NoError(t, err)
+		id, err := m.NextNamespaceId(ctx, tx)
+		require.NoError(t, err)
+		require.Equal(t, uint32(4), id)
+		_, err = m.CreateTenant(ctx, tx, &TenantNamespace{"ns-test4", 2, NewNamespaceMetadata(2, "ns-test4", "ns-test4-display_name")})
+		require.Error(t, err)
+		_, err = m.CreateTenant(ctx, tx, &TenantNamespace{"ns-test4", 3, NewNamespaceMetadata(3, "ns-test4", "ns-test4-display_name")})
+		require.Error(t, err)
+		_ = tx.Rollback(ctx)
+
+		// the name of a deleted namespace can be used again with a new id
+		tx, err = tm.StartTx(ctx)
+		require.NoError(t, err)
+		id, err = m.NextNamespaceId(ctx, tx)
+		require.NoError(t, err)
+		_, err = m.CreateTenant(ctx, tx, &TenantNamespace{"ns-test2", id, NewNamespaceMetadata(id, "ns-test2", "ns-test2-display_name")})
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit(ctx))
+		tenant, err := m.GetTenant(ctx, "ns-test2")
+		require.NoError(t, err)
+		require.Equal(t, uint32(4), tenant.GetNamespace().Id())
+	})
+}
+
 func TestTenantManager_CreateDatabases(t *testing.T) {
 	tm := transaction.NewManager(kvStore)
 	t.Run("create_databases", func(t *testing.T) {
@@ -261,7 +394,7 @@ func TestTenantManager_CreateCollections(t *testing.T) {
 		"primary_key": ["K1", "K2"]
 	}`)
 
-		factory, err := schema.Build("test_collection", jsSchema)
+		factory, err := schema.Build("test_collection", jsSchema, false)
 		require.NoError(t, err)
 		require.NoError(t, tenant.CreateCollection(ctx, tx, db2, factory))
 
@@ -323,7 +456,7 @@ func TestTenantManager_DropCollection(t *testing.T) {
 		"primary_key": ["K1"]
 	}`)
 
-		factory, err := schema.Build("test_collection", jsSchema)
+		factory, err := schema.Build("test_collection", jsSchema, false)
 		require.NoError(t, err)
 		require.NoError(t, tenant.CreateCollection(ctx, tx, db2, factory))
 		require.NoError(t, tenant.reload(ctx, tx, nil, nil))
@@ -352,6 +485,56 @@ func TestTenantManager_DropCollection(t *testing.T) {
 	})
 }
 
+func TestTenantManager_CollectionSchemas(t *testing.T) {
+	tm := transaction.NewManager(kvStore)
+	m, ctx, cancel := NewTestTenantMgr(kvStore)
+	defer cancel()
+
+	_, err := m.CreateOrGetTenant(ctx, &TenantNamespace{"ns-test1", 2, NewNamespaceMetadata(2, "ns-test1", "ns-test1-display_name")})
+	require.NoError(t, err)
+	tenant := m.tenants["ns-test1"]
+
+	tx, err := tm.StartTx(ctx)
+	require.NoError(t, err)
+	_, err = tenant.CreateDatabase(ctx, tx, "tenant_db1")
+	require.NoError(t, err)
+	require.NoError(t, tenant.reload(ctx, tx, nil, nil))
+	db, err := tenant.GetDatabase(ctx, "tenant_db1")
+	require.NoError(t, err)
+
+	v1 := []byte(`{"title":"test_collection","properties":{"K1":{"type":"string"}},"primary_key":["K1"]}`)
+	v2 := []byte(`{"title":"test_collection","properties":{"K1":{"type":"string"},"K2":{"type":"integer"}},"primary_key":["K1"]}`)
+	for _, sch := range [][]byte{v1, v2} {
+		factory, err := schema.Build("test_collection", sch, false)
+		require.NoError(t, err)
+		require.NoError(t, tenant.CreateCollection(ctx, tx, db, factory))
+		require.NoError(t, tenant.reload(ctx, tx, nil, nil))
+		db, err = tenant.GetDatabase(ctx, "tenant_db1")
+		require.NoError(t, err)
+	}
+	require.NoError(t, tx.Commit(ctx))
+
+	coll := db.GetCollection("test_collection")
+	require.Equal(t, int32(2), coll.GetVersion())
+
+	// every version of the schema is returned, in the order of the versions, as it is stored
+	tx, err = tm.StartTx(ctx)
+	require.NoError(t, err)
+	schemas, versions, err := tenant.GetCollectionSchemas(ctx, tx, db, coll)
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback(ctx))
+	require.Equal(t, []int{1, 2}, versions)
+	require.Len(t, schemas, 2)
+	for i, sch := range [][]byte{v1, v2} {
+		require.JSONEq(t, string(sch), string(schema.RemoveIndexingVersion(schemas[i])))
+		require.Equal(t, schema.DefaultIndexingSchemaVersion, jsoniter.Get(schemas[i], schema.IndexingSchemaVersionKey).ToString())
+	}
+
+	_ = kvStore.DropTable(ctx, m.mdNameRegistry.ReservedSubspaceName())
+	_ = kvStore.DropTable(ctx, m.mdNameRegistry.EncodingSubspaceName())
+	_ = kvStore.DropTable(ctx, m.mdNameRegistry.SchemaSubspaceName())
+}
+
 func TestTenantManager_DataSize(t *testing.T) {
 	tm := transaction.NewManager(kvStore)
 	m, ctx, cancel := NewTestTenantMgr(kvStore)
@@ -399,7 +582,7 @@ func TestTenantManager_DataSize(t *testing.T) {
 		  "primary_key": ["K1", "K2"]
 	    }`)
 
-	factory, err := schema.Build("test_collection", jsSchema)
+	factory, err := schema.Build("test_collection", jsSchema, false)
 	require.NoError(t, err)
 
 	db1, err := tenant.GetDatabase(ctx, "tenant_db1")
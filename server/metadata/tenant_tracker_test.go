@@ -0,0 +1,111 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+func TestCacheTracker_DroppedCollection(t *testing.T) {
+	tm := transaction.NewManager(kvStore)
+	versionH := &VersionHandler{}
+	m, ctx, cancel := NewTestTenantMgr(kvStore)
+	defer cancel()
+
+	_, err := m.CreateOrGetTenant(ctx, &TenantNamespace{"ns-tracker", 5, NewNamespaceMetadata(5, "ns-tracker", "ns-tracker-display_name")})
+	require.NoError(t, err)
+	tenant := m.tenants["ns-tracker"]
+
+	tx, err := tm.StartTx(ctx)
+	require.NoError(t, err)
+	_, err = tenant.CreateDatabase(ctx, tx, "tracker_db")
+	require.NoError(t, err)
+	require.NoError(t, tenant.reload(ctx, tx, nil, nil))
+	db, err := tenant.GetDatabase(ctx, "tracker_db")
+	require.NoError(t, err)
+
+	factory, err := schema.Build("tracker_coll", []byte(`{
+		"title": "tracker_coll",
+		"properties": { "K1": { "type": "string" } },
+		"primary_key": ["K1"]
+	}`), false)
+	require.NoError(t, err)
+	require.NoError(t, tenant.CreateCollection(ctx, tx, db, factory))
+	require.NoError(t, tenant.reload(ctx, tx, nil, nil))
+	require.NoError(t, tx.Commit(ctx))
+
+	cacheTracker := NewCacheTracker(m, tm)
+
+	// the session resolves the collection from the cache once the version is checked
+	tx1, err := tm.StartTx(WithVersionConflict(ctx, 5))
+	require.NoError(t, err)
+	tracker, err := cacheTracker.DeferredTracking(ctx, tx1, tenant)
+	require.NoError(t, err)
+	_, err = tracker.Stop(ctx)
+	require.NoError(t, err)
+	coll := tenant.GetCollection("tracker_db", "tracker_coll")
+	require.NotNil(t, coll)
+	table, err := tenant.Encoder.EncodeTableName(tenant.namespace, db, coll)
+	require.NoError(t, err)
+	key, err := tenant.Encoder.EncodeKey(table, coll.Indexes.PrimaryKey, []interface{}{"a"})
+	require.NoError(t, err)
+
+	// a concurrent DDL drops the collection before the session writes into it
+	tx2, err := tm.StartTx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, tenant.DropCollection(ctx, tx2, db, "tracker_coll"))
+	require.NoError(t, versionH.IncrementNamespace(ctx, tx2, 5))
+	require.NoError(t, tx2.Commit(ctx))
+
+	// the write into the keyspace of the dropped collection is not committed
+	require.NoError(t, tx1.Replace(ctx, key, internal.NewTableData([]byte(`{"K1": "a"}`)), false))
+	require.ErrorIs(t, tx1.Commit(ctx), kv.ErrConflictingTransaction)
+
+	// the retry sees the new version, the collection is not found
+	tx3, err := tm.StartTx(WithVersionConflict(ctx, 5))
+	require.NoError(t, err)
+	tracker, err = cacheTracker.DeferredTracking(ctx, tx3, tenant)
+	require.NoError(t, err)
+	_, err = tracker.Stop(ctx)
+	require.NoError(t, err)
+	require.Nil(t, tenant.GetCollection("tracker_db", "tracker_coll"))
+	require.NoError(t, tx3.Rollback(ctx))
+
+	// the DDLs of the other namespaces don't abort the sessions of the namespace
+	tx4, err := tm.StartTx(WithVersionConflict(ctx, 5))
+	require.NoError(t, err)
+	tx5, err := tm.StartTx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, versionH.IncrementNamespace(ctx, tx5, 6))
+	require.NoError(t, tx5.Commit(ctx))
+	require.NoError(t, tx4.Replace(ctx, key, internal.NewTableData([]byte(`{"K1": "a"}`)), false))
+	require.NoError(t, tx4.Delete(ctx, key))
+	require.NoError(t, tx4.Commit(ctx))
+
+	it, err := kvStore.Read(ctx, table, kv.BuildKey(key.IndexParts()...))
+	require.NoError(t, err)
+	var v kv.KeyValue
+	require.False(t, it.Next(&v))
+
+	_ = kvStore.DropTable(ctx, m.mdNameRegistry.ReservedSubspaceName())
+	_ = kvStore.DropTable(ctx, m.mdNameRegistry.EncodingSubspaceName())
+	_ = kvStore.DropTable(ctx, m.mdNameRegistry.SchemaSubspaceName())
+}
@@ -41,6 +41,9 @@ type UserType uint32
 const (
 	User        UserType = 0
 	Application UserType = 1
+	// Reserved is the metadata of the users that only the server writes, like the roles assigned to them. The user
+	// metadata API only reaches the metadata of the User type.
+	Reserved UserType = 2
 )
 
 func NewUserStore(mdNameRegistry MDNameRegistry) *UserSubspace {
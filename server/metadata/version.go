@@ -27,6 +27,8 @@ var (
 	VersionKey = []byte{0xff, '/', 'm', 'e', 't', 'a', 'd', 'a', 't', 'a', 'V', 'e', 'r', 's', 'i', 'o', 'n'}
 	// VersionValue is the value set when calling setVersionstampedValue, any value other than this is rejected.
 	VersionValue = []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+
+	namespaceVersionPrefix = []byte("namespace_version/")
 )
 
 type (
@@ -44,6 +46,21 @@ func (m *VersionHandler) Increment(ctx context.Context, tx transaction.Tx) error
 	return tx.SetVersionstampedValue(ctx, VersionKey, VersionValue)
 }
 
+// IncrementNamespace increments the metadata version and the metadata version of the namespace, it is used by the
+// changes of the metadata of a namespace.
+func (m *VersionHandler) IncrementNamespace(ctx context.Context, tx transaction.Tx, namespaceId uint32) error {
+	if err := m.Increment(ctx, tx); err != nil {
+		return err
+	}
+	return tx.SetVersionstampedValue(ctx, NamespaceVersionKey(namespaceId), VersionValue)
+}
+
+// NamespaceVersionKey is the key of the metadata version of the namespace, only the changes of the metadata of the
+// namespace increment it.
+func NamespaceVersionKey(namespaceId uint32) []byte {
+	return append(append(make([]byte, 0, len(namespaceVersionPrefix)+4), namespaceVersionPrefix...), UInt32ToByte(namespaceId)...)
+}
+
 // Read is blocking and returns the latest metadata version.
 func (m *VersionHandler) Read(ctx context.Context, tx transaction.Tx, isSnapshot bool) (Version, error) {
 	vf, err := m.ReadFuture(ctx, tx, isSnapshot)
@@ -59,6 +76,14 @@ func (m *VersionHandler) ReadFuture(ctx context.Context, tx transaction.Tx, isSn
 	return tx.Get(ctx, VersionKey, isSnapshot)
 }
 
+// WithVersionConflict returns the context of a transaction that fails to commit with a conflict if the metadata version
+// of the namespace is incremented while it runs. The transactions using the cached metadata then can't write into the
+// collections that a concurrent DDL of the namespace dropped or changed, they are retried once the cache is reloaded.
+// The DDLs of the other namespaces don't abort them.
+func WithVersionConflict(ctx context.Context, namespaceId uint32) context.Context {
+	return kv.WithReadConflictKeys(ctx, NamespaceVersionKey(namespaceId))
+}
+
 // ReadInOwnTxn creates a transaction and then reads the version. This is useful when a transaction is also changing
 // the metadata then it is better to read the metadata version in its own transaction as the read-write-read or write-read
 // metadata version is not allowed in a transaction.
@@ -71,4 +71,33 @@ func TestMetaVersion(t *testing.T) {
 		require.NoError(t, tx.Commit(ctx))
 		require.NotEqual(t, first, second)
 	})
+	t.Run("bump namespace", func(t *testing.T) {
+		m := &VersionHandler{}
+		ctx := context.TODO()
+		tm := transaction.NewManager(kv)
+		tx, err := tm.StartTx(ctx)
+		require.NoError(t, err)
+		first, err := m.Read(ctx, tx, true)
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit(ctx))
+
+		// the namespace version is written along with the metadata version
+		tx, err = tm.StartTx(ctx)
+		require.NoError(t, err)
+		require.NoError(t, m.IncrementNamespace(ctx, tx, 7))
+		require.NoError(t, tx.Commit(ctx))
+
+		tx, err = tm.StartTx(ctx)
+		require.NoError(t, err)
+		second, err := m.Read(ctx, tx, true)
+		require.NoError(t, err)
+		nsVersion, err := tx.Get(ctx, NamespaceVersionKey(7), true)
+		require.NoError(t, err)
+		nsValue, err := nsVersion.Get()
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit(ctx))
+		require.NotEqual(t, first, second)
+		require.Len(t, nsValue, 10)
+		require.NotEqual(t, NamespaceVersionKey(7), NamespaceVersionKey(8))
+	})
 }
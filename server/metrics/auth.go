@@ -16,15 +16,23 @@ package metrics
 
 import (
 	"context"
+	"errors"
+	"strconv"
 
 	"github.com/uber-go/tally"
 )
 
+// AuthFailureError is implemented by the authentication errors to report why the access token was rejected.
+type AuthFailureError interface {
+	AuthFailure() string
+}
+
 var (
 	AuthOkCount       tally.Scope
 	AuthErrorCount    tally.Scope
 	AuthRespTime      tally.Scope
 	AuthErrorRespTime tally.Scope
+	AuthzCount        tally.Scope
 )
 
 func getAuthOkTagKeys() []string {
@@ -48,7 +56,17 @@ func getAuthErrorTagKeys() []string {
 		"tigris_tenant_name",
 		"error_source",
 		"error_value",
+		"auth_failure",
+	}
+}
+
+// getAuthFailureTags returns the reason the access token was rejected, if the error reports it.
+func getAuthFailureTags(err error) map[string]string {
+	var failure AuthFailureError
+	if errors.As(err, &failure) {
+		return map[string]string{"auth_failure": failure.AuthFailure()}
 	}
+	return map[string]string{}
 }
 
 func GetAuthBaseTags(ctx context.Context) map[string]string {
@@ -60,4 +78,23 @@ func initializeAuthScopes() {
 	AuthErrorCount = AuthMetrics.SubScope("count")
 	AuthRespTime = AuthMetrics.SubScope("response")
 	AuthErrorRespTime = AuthMetrics.SubScope("error_response")
+	AuthzCount = AuthMetrics.SubScope("authz")
+}
+
+// CountAuthzDecision counts the authorization of a call by the method and the role of the caller, cached is true if
+// the role of the caller was cached.
+func CountAuthzDecision(method string, role string, allowed bool, cached bool) {
+	if AuthzCount == nil {
+		return
+	}
+
+	decision := "denied"
+	if allowed {
+		decision = "allowed"
+	}
+	AuthzCount.Tagged(map[string]string{
+		"grpc_method": method,
+		"role":        role,
+		"cached":      strconv.FormatBool(cached),
+	}).Counter(decision).Inc(1)
 }
@@ -0,0 +1,129 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/defaults"
+	"github.com/uber-go/tally"
+)
+
+// OtherTagValue replaces the values of a tag key once the cardinality limit of the key is reached.
+const OtherTagValue = "__other__"
+
+// TagCardinalityMetrics counts the tag values replaced by OtherTagValue.
+var TagCardinalityMetrics tally.Scope
+
+// tagCardinality tracks the distinct values of the guarded tag keys. The values seen before the limit was reached
+// keep being reported.
+type tagCardinality struct {
+	sync.RWMutex
+
+	limits map[string]int
+	values map[string]map[string]struct{}
+	warned map[string]bool
+}
+
+var tagValues *tagCardinality
+
+func initializeTagCardinality(cfg *config.TagCardinalityConfig) {
+	if !cfg.Enabled || len(cfg.Limits) == 0 {
+		tagValues = nil
+		return
+	}
+
+	c := &tagCardinality{
+		limits: make(map[string]int, len(cfg.Limits)),
+		values: make(map[string]map[string]struct{}, len(cfg.Limits)),
+		warned: make(map[string]bool),
+	}
+	for key, limit := range cfg.Limits {
+		c.limits[key] = limit
+		c.values[key] = make(map[string]struct{})
+	}
+	tagValues = c
+}
+
+func (c *tagCardinality) known(key string, value string) (bool, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	values, guarded := c.values[key]
+	if !guarded {
+		return false, false
+	}
+	_, ok := values[value]
+	return true, ok
+}
+
+// value returns the value to report for the tag key, it is the value itself until the limit of the key is reached.
+func (c *tagCardinality) value(key string, value string) string {
+	if value == defaults.UnknownValue || value == OtherTagValue {
+		return value
+	}
+	if guarded, known := c.known(key, value); !guarded || known {
+		return value
+	}
+
+	c.Lock()
+	values := c.values[key]
+	if _, ok := values[value]; ok {
+		c.Unlock()
+		return value
+	}
+	if len(values) < c.limits[key] {
+		values[value] = struct{}{}
+		c.Unlock()
+		return value
+	}
+	warn := !c.warned[key]
+	c.warned[key] = true
+	c.Unlock()
+
+	if warn {
+		log.Warn().Str("tag_key", key).Int("limit", c.limits[key]).
+			Msgf("Tag cardinality limit reached, the new values are reported as %s", OtherTagValue)
+	}
+	if TagCardinalityMetrics != nil {
+		TagCardinalityMetrics.Tagged(map[string]string{"tag_key": key}).Counter("dropped_values").Inc(1)
+	}
+	return OtherTagValue
+}
+
+// limitTagCardinality returns the tags with the values above the cardinality limit of their key replaced by
+// OtherTagValue. The tags are copied when a value is replaced.
+func limitTagCardinality(tags map[string]string) map[string]string {
+	c := tagValues
+	if c == nil {
+		return tags
+	}
+
+	res, copied := tags, false
+	for key, value := range tags {
+		if limited := c.value(key, value); limited != value {
+			if !copied {
+				res, copied = make(map[string]string, len(tags)), true
+				for k, v := range tags {
+					res[k] = v
+				}
+			}
+			res[key] = limited
+		}
+	}
+	return res
+}
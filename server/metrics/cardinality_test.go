@@ -0,0 +1,88 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/defaults"
+	"github.com/uber-go/tally"
+)
+
+func TestLimitTagCardinality(t *testing.T) {
+	defer func() {
+		TagCardinalityMetrics = nil
+		initializeTagCardinality(&config.DefaultConfig.Metrics.TagCardinality)
+	}()
+
+	testScope := tally.NewTestScope("", nil)
+	TagCardinalityMetrics = testScope
+	initializeTagCardinality(&config.TagCardinalityConfig{
+		Enabled: true,
+		Limits:  map[string]int{"collection": 2, "db": 1},
+	})
+
+	for i := 0; i < 2; i++ {
+		tags := map[string]string{"db": "db1", "collection": fmt.Sprintf("coll%d", i)}
+		require.Equal(t, tags, limitTagCardinality(tags))
+	}
+
+	tags := map[string]string{"db": "db2", "collection": "coll2", "grpc_method": "Read"}
+	require.Equal(t, map[string]string{"db": OtherTagValue, "collection": OtherTagValue, "grpc_method": "Read"}, limitTagCardinality(tags))
+	// the tags of the caller are not changed
+	require.Equal(t, "coll2", tags["collection"])
+
+	// the known values keep reporting, and the unknown value doesn't count towards the limit
+	require.Equal(t, map[string]string{"db": "db1", "collection": "coll1"}, limitTagCardinality(map[string]string{"db": "db1", "collection": "coll1"}))
+	require.Equal(t, map[string]string{"collection": defaults.UnknownValue}, limitTagCardinality(map[string]string{"collection": defaults.UnknownValue}))
+	require.Equal(t, map[string]string{"collection": OtherTagValue}, limitTagCardinality(map[string]string{"collection": "coll3"}))
+
+	counters := testScope.Snapshot().Counters()
+	require.Equal(t, int64(2), counters["dropped_values+tag_key=collection"].Value())
+	require.Equal(t, int64(1), counters["dropped_values+tag_key=db"].Value())
+
+	// the measurement tags are limited as well
+	measurement := NewMeasurement("test.service.name", "TestResource", "rpc", map[string]string{"db": "db3", "collection": "coll0"})
+	okTags := measurement.GetRequestOkTags()
+	require.Equal(t, OtherTagValue, okTags["db"])
+	require.Equal(t, "coll0", okTags["collection"])
+	require.Equal(t, "db3", measurement.GetTags()["db"])
+
+	initializeTagCardinality(&config.TagCardinalityConfig{Enabled: false, Limits: map[string]int{"collection": 1}})
+	require.Equal(t, map[string]string{"collection": "coll5"}, limitTagCardinality(map[string]string{"collection": "coll5"}))
+}
+
+func TestDbCollTagsForReqCardinality(t *testing.T) {
+	defer initializeTagCardinality(&config.DefaultConfig.Metrics.TagCardinality)
+
+	initializeTagCardinality(&config.TagCardinalityConfig{
+		Enabled: true,
+		Limits:  map[string]int{"collection": 3, "db": 3},
+	})
+
+	reported := make(map[string]int)
+	for i := 0; i < 10; i++ {
+		req := &api.ReadRequest{Db: "db1", Collection: fmt.Sprintf("coll%d", i)}
+		measurement := NewMeasurement("test.service.name", "Read", "rpc", GetDbCollTagsForReq(req))
+		reported[measurement.GetRequestOkTags()["collection"]]++
+	}
+
+	// the collections beyond the limit collapse into a single value
+	require.Equal(t, map[string]int{"coll0": 1, "coll1": 1, "coll2": 1, OtherTagValue: 7}, reported)
+}
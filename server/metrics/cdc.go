@@ -0,0 +1,102 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/uber-go/tally"
+)
+
+var (
+	// CdcGroups tracks the consumer groups of the change streams.
+	CdcGroups tally.Scope
+	// CdcWebhooks tracks the deliveries to the webhooks.
+	CdcWebhooks tally.Scope
+)
+
+func initializeCdcScopes() {
+	CdcGroups = CdcMetrics.SubScope("group")
+	CdcWebhooks = CdcMetrics.SubScope("webhook")
+}
+
+func getCdcGroupTags(db string, collection string, group string) map[string]string {
+	return limitTagCardinality(map[string]string{
+		"env":        config.GetEnvironment(),
+		"db":         db,
+		"collection": collection,
+		"group":      group,
+	})
+}
+
+// UpdateCdcGroupLag reports how far behind the committed position of a consumer group is, as the time elapsed since
+// the change at the committed position was written.
+func UpdateCdcGroupLag(db string, collection string, group string, lag time.Duration) {
+	if CdcGroups == nil {
+		return
+	}
+
+	CdcGroups.Tagged(getCdcGroupTags(db, collection, group)).Gauge("lag_seconds").Update(lag.Seconds())
+}
+
+// UpdateCdcGroupMembers reports the number of active members of a consumer group.
+func UpdateCdcGroupMembers(db string, collection string, group string, members int) {
+	if CdcGroups == nil {
+		return
+	}
+
+	CdcGroups.Tagged(getCdcGroupTags(db, collection, group)).Gauge("members").Update(float64(members))
+}
+
+func getCdcWebhookTags(db string, collection string, webhook string) map[string]string {
+	return limitTagCardinality(map[string]string{
+		"env":        config.GetEnvironment(),
+		"db":         db,
+		"collection": collection,
+		"webhook":    webhook,
+	})
+}
+
+// UpdateCdcWebhookDelivered reports a successful delivery of events to a webhook and how long the delivery took,
+// including the retries.
+func UpdateCdcWebhookDelivered(db string, collection string, webhook string, events int, latency time.Duration) {
+	if CdcWebhooks == nil {
+		return
+	}
+
+	scope := CdcWebhooks.Tagged(getCdcWebhookTags(db, collection, webhook))
+	scope.Counter("delivered").Inc(1)
+	scope.Counter("events").Inc(int64(events))
+	scope.Timer("latency").Record(latency)
+}
+
+// UpdateCdcWebhookRetry reports a failed attempt of a delivery to a webhook.
+func UpdateCdcWebhookRetry(db string, collection string, webhook string) {
+	if CdcWebhooks == nil {
+		return
+	}
+
+	CdcWebhooks.Tagged(getCdcWebhookTags(db, collection, webhook)).Counter("retries").Inc(1)
+}
+
+// UpdateCdcWebhookDeadLetter reports a webhook dead-lettered after the maximum number of retries.
+func UpdateCdcWebhookDeadLetter(db string, collection string, webhook string) {
+	if CdcWebhooks == nil {
+		return
+	}
+
+	CdcWebhooks.Tagged(getCdcWebhookTags(db, collection, webhook)).Counter("dead_letters").Inc(1)
+}
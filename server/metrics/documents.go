@@ -0,0 +1,61 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"github.com/uber-go/tally"
+)
+
+var (
+	// DocumentMetrics are the sizes of the documents written to the collections.
+	DocumentMetrics tally.Scope
+
+	// documentSizeBuckets goes from 64 bytes to 16MB.
+	documentSizeBuckets = tally.MustMakeExponentialValueBuckets(64, 4, 10)
+	// compressionRatioBuckets goes from 5% to 100% of the uncompressed size.
+	compressionRatioBuckets = tally.MustMakeLinearValueBuckets(0.05, 0.05, 20)
+)
+
+func getDocumentSizeTags(db string, collection string) map[string]string {
+	return limitTagCardinality(map[string]string{
+		"db":         db,
+		"collection": collection,
+	})
+}
+
+// RecordDocumentSize records the size of a document written to the collection, it is the size of the serialized
+// document as it is stored.
+func RecordDocumentSize(db string, collection string, size int) {
+	if DocumentMetrics == nil {
+		return
+	}
+
+	DocumentMetrics.Tagged(getDocumentSizeTags(db, collection)).Histogram("size", documentSizeBuckets).RecordValue(float64(size))
+}
+
+// RecordDocumentCompression records the size of a document written to the collection before and after it is
+// compressed with the codec. The ratio is the size after the compression over the size before.
+func RecordDocumentCompression(db string, collection string, codec string, size int, compressed int) {
+	if DocumentMetrics == nil || size == 0 {
+		return
+	}
+
+	tags := getDocumentSizeTags(db, collection)
+	tags["codec"] = codec
+	scope := DocumentMetrics.Tagged(tags)
+	scope.Counter("uncompressed_bytes").Inc(int64(size))
+	scope.Counter("compressed_bytes").Inc(int64(compressed))
+	scope.Histogram("compression_ratio", compressionRatioBuckets).RecordValue(float64(compressed) / float64(size))
+}
@@ -0,0 +1,56 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+)
+
+func TestRecordDocumentSize(t *testing.T) {
+	scope := tally.NewTestScope("", nil)
+	DocumentMetrics = scope
+	defer func() { DocumentMetrics = nil }()
+
+	RecordDocumentSize("db1", "orders", 100)
+	RecordDocumentSize("db1", "orders", 200)
+	RecordDocumentSize("db1", "orders", 5000)
+
+	histogram := scope.Snapshot().Histograms()["size+collection=orders,db=db1"]
+	require.NotNil(t, histogram)
+	// the buckets are 64, 256, 1024, 4096, 16384 bytes...
+	require.Equal(t, int64(2), histogram.Values()[256])
+	require.Equal(t, int64(1), histogram.Values()[16384])
+	require.Equal(t, int64(0), histogram.Values()[1024])
+}
+
+func TestRecordDocumentCompression(t *testing.T) {
+	scope := tally.NewTestScope("", nil)
+	DocumentMetrics = scope
+	defer func() { DocumentMetrics = nil }()
+
+	RecordDocumentCompression("db1", "orders", "zstd", 1000, 200)
+	RecordDocumentCompression("db1", "orders", "zstd", 1000, 100)
+
+	snapshot := scope.Snapshot()
+	require.Equal(t, int64(2000), snapshot.Counters()["uncompressed_bytes+codec=zstd,collection=orders,db=db1"].Value())
+	require.Equal(t, int64(300), snapshot.Counters()["compressed_bytes+codec=zstd,collection=orders,db=db1"].Value())
+	histogram := snapshot.Histograms()["compression_ratio+codec=zstd,collection=orders,db=db1"]
+	require.NotNil(t, histogram)
+	require.Equal(t, int64(1), histogram.Values()[0.1])
+	require.Equal(t, int64(1), histogram.Values()[0.2])
+}
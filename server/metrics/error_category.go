@@ -0,0 +1,222 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/sony/gobreaker"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/uber-go/tally"
+	"google.golang.org/grpc/status"
+)
+
+// The categories of the errors, they are a small and stable set of tag values the dashboards can rely on.
+const (
+	ErrorCategoryNone               = "none"
+	ErrorCategoryConflict           = "conflict"
+	ErrorCategoryTimeout            = "timeout"
+	ErrorCategoryNotFound           = "not_found"
+	ErrorCategoryValidation         = "validation"
+	ErrorCategoryQuota              = "quota"
+	ErrorCategoryBackendUnavailable = "backend_unavailable"
+	ErrorCategoryInternal           = "internal"
+)
+
+// ErrorMetrics counts the errors that are not mapped to a category.
+var ErrorMetrics tally.Scope
+
+// CategorizedError is implemented by the errors of the stores, that the metrics can't import, to report their
+// category.
+type CategorizedError interface {
+	ErrorCategory() string
+}
+
+// fdbErrorCategories maps the FoundationDB error codes, see https://apple.github.io/foundationdb/api-error-codes.html.
+var fdbErrorCategories = map[int]string{
+	1004: ErrorCategoryTimeout,            // timed_out
+	1007: ErrorCategoryTimeout,            // transaction_too_old
+	1009: ErrorCategoryBackendUnavailable, // future_version
+	1020: ErrorCategoryConflict,           // not_committed
+	1021: ErrorCategoryBackendUnavailable, // commit_unknown_result
+	1031: ErrorCategoryTimeout,            // transaction_timed_out
+	1037: ErrorCategoryBackendUnavailable, // process_behind
+	1038: ErrorCategoryBackendUnavailable, // database_locked
+	1039: ErrorCategoryBackendUnavailable, // cluster_version_changed
+	1213: ErrorCategoryBackendUnavailable, // tag_throttled
+	2101: ErrorCategoryValidation,         // transaction_too_large
+	2102: ErrorCategoryValidation,         // key_too_large
+	2103: ErrorCategoryValidation,         // value_too_large
+}
+
+var codeErrorCategories = map[api.Code]string{
+	api.Code_OK:                  ErrorCategoryNone,
+	api.Code_CANCELLED:           ErrorCategoryTimeout,
+	api.Code_UNKNOWN:             ErrorCategoryInternal,
+	api.Code_INVALID_ARGUMENT:    ErrorCategoryValidation,
+	api.Code_DEADLINE_EXCEEDED:   ErrorCategoryTimeout,
+	api.Code_NOT_FOUND:           ErrorCategoryNotFound,
+	api.Code_ALREADY_EXISTS:      ErrorCategoryConflict,
+	api.Code_PERMISSION_DENIED:   ErrorCategoryValidation,
+	api.Code_RESOURCE_EXHAUSTED:  ErrorCategoryQuota,
+	api.Code_FAILED_PRECONDITION: ErrorCategoryValidation,
+	api.Code_ABORTED:             ErrorCategoryConflict,
+	api.Code_OUT_OF_RANGE:        ErrorCategoryValidation,
+	api.Code_UNIMPLEMENTED:       ErrorCategoryValidation,
+	api.Code_INTERNAL:            ErrorCategoryInternal,
+	api.Code_UNAVAILABLE:         ErrorCategoryBackendUnavailable,
+	api.Code_DATA_LOSS:           ErrorCategoryInternal,
+	api.Code_UNAUTHENTICATED:     ErrorCategoryValidation,
+	api.Code_CONFLICT:            ErrorCategoryConflict,
+	api.Code_BAD_GATEWAY:         ErrorCategoryBackendUnavailable,
+	api.Code_METHOD_NOT_ALLOWED:  ErrorCategoryValidation,
+}
+
+// getErrorCategory returns the category of the error and whether the error is mapped. The errors that are not mapped
+// are categorized as internal.
+func getErrorCategory(err error) (string, bool) {
+	if err == nil {
+		return ErrorCategoryNone, true
+	}
+
+	var categorized CategorizedError
+	if goerrors.As(err, &categorized) {
+		return categorized.ErrorCategory(), true
+	}
+
+	var fdbErr fdb.Error
+	if goerrors.As(err, &fdbErr) {
+		category, ok := fdbErrorCategories[fdbErr.Code]
+		if !ok {
+			return ErrorCategoryInternal, false
+		}
+		return category, true
+	}
+
+	var tigrisErr *api.TigrisError
+	if goerrors.As(err, &tigrisErr) {
+		return getCodeCategory(tigrisErr.Code)
+	}
+	if s, ok := status.FromError(err); ok {
+		return getCodeCategory(api.ToTigrisCode(s.Code()))
+	}
+
+	var netErr net.Error
+	switch {
+	case goerrors.Is(err, context.DeadlineExceeded), goerrors.Is(err, context.Canceled):
+		return ErrorCategoryTimeout, true
+	case goerrors.Is(err, gobreaker.ErrOpenState), goerrors.Is(err, gobreaker.ErrTooManyRequests):
+		return ErrorCategoryBackendUnavailable, true
+	case goerrors.As(err, &netErr):
+		if netErr.Timeout() {
+			return ErrorCategoryTimeout, true
+		}
+		return ErrorCategoryBackendUnavailable, true
+	}
+
+	return ErrorCategoryInternal, false
+}
+
+func getCodeCategory(code api.Code) (string, bool) {
+	category, ok := codeErrorCategories[code]
+	if !ok {
+		return ErrorCategoryInternal, false
+	}
+	return category, true
+}
+
+// HTTPErrorCategory returns the category of an error response of an HTTP backend.
+func HTTPErrorCategory(httpCode int) string {
+	switch {
+	case httpCode < http.StatusBadRequest:
+		return ErrorCategoryNone
+	case httpCode == http.StatusNotFound:
+		return ErrorCategoryNotFound
+	case httpCode == http.StatusConflict:
+		return ErrorCategoryConflict
+	case httpCode == http.StatusRequestTimeout, httpCode == http.StatusGatewayTimeout:
+		return ErrorCategoryTimeout
+	case httpCode == http.StatusTooManyRequests:
+		return ErrorCategoryQuota
+	case httpCode == http.StatusBadGateway, httpCode == http.StatusServiceUnavailable:
+		return ErrorCategoryBackendUnavailable
+	case httpCode < http.StatusInternalServerError:
+		return ErrorCategoryValidation
+	default:
+		return ErrorCategoryInternal
+	}
+}
+
+// getErrorTags returns the tags of the error reported by the metrics, the error value of the generic errors is the
+// type of the error instead of its message to keep the number of values low.
+func getErrorTags(err error, source string) map[string]string {
+	tags := getTagsForError(err, source)
+	_, isFdbError := getFdbError(err)
+	_, isTigrisError := getTigrisError(err)
+	if err != nil && !isFdbError && !isTigrisError {
+		tags["error_value"] = errorTypeName(err)
+	}
+
+	category, _ := getErrorCategory(err)
+	tags["error_category"] = category
+	tags["error_retryable"] = strconv.FormatBool(errors.IsRetryable(err))
+
+	return mergeTags(tags, getErrorCodeTags(err))
+}
+
+// countUnmappedError counts the errors without a category by the type of the error, so that the mapping can be
+// extended.
+func countUnmappedError(source string, err error) {
+	if ErrorMetrics == nil || err == nil {
+		return
+	}
+	if _, mapped := getErrorCategory(err); mapped {
+		return
+	}
+
+	ErrorMetrics.Tagged(map[string]string{
+		"error_source": source,
+		"error_type":   errorTypeName(err),
+	}).Counter("unmapped_error").Inc(1)
+}
+
+// CountPanic counts the panics recovered in the handlers of the method.
+func CountPanic(method string) {
+	if ErrorMetrics == nil {
+		return
+	}
+
+	ErrorMetrics.Tagged(limitTagCardinality(map[string]string{
+		"grpc_method": method,
+	})).Counter("panic").Inc(1)
+}
+
+// errorTypeName returns the type of the error, the wrapping errors of fmt.Errorf are unwrapped.
+func errorTypeName(err error) string {
+	for {
+		unwrapped := goerrors.Unwrap(err)
+		if unwrapped == nil {
+			return fmt.Sprintf("%T", err)
+		}
+		err = unwrapped
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/uber-go/tally"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type testCategorizedError struct{}
+
+func (testCategorizedError) Error() string { return "categorized" }
+
+func (testCategorizedError) ErrorCategory() string { return ErrorCategoryQuota }
+
+type testUnmappedError struct{}
+
+func (*testUnmappedError) Error() string { return "unmapped" }
+
+func TestGetErrorCategory(t *testing.T) {
+	cases := []struct {
+		err      error
+		category string
+		mapped   bool
+	}{
+		{nil, ErrorCategoryNone, true},
+		{fdb.Error{Code: 1020}, ErrorCategoryConflict, true},
+		{fdb.Error{Code: 1031}, ErrorCategoryTimeout, true},
+		{fdb.Error{Code: 1037}, ErrorCategoryBackendUnavailable, true},
+		{fdb.Error{Code: 1}, ErrorCategoryInternal, false},
+		{errors.InvalidArgument("invalid"), ErrorCategoryValidation, true},
+		{errors.NotFound("missing"), ErrorCategoryNotFound, true},
+		{errors.AlreadyExists("exists"), ErrorCategoryConflict, true},
+		{errors.ResourceExhausted("quota"), ErrorCategoryQuota, true},
+		{errors.Internal("internal"), ErrorCategoryInternal, true},
+		{status.Error(codes.Unavailable, "unavailable"), ErrorCategoryBackendUnavailable, true},
+		{context.DeadlineExceeded, ErrorCategoryTimeout, true},
+		{gobreaker.ErrOpenState, ErrorCategoryBackendUnavailable, true},
+		{testCategorizedError{}, ErrorCategoryQuota, true},
+		{&testUnmappedError{}, ErrorCategoryInternal, false},
+		{fmt.Errorf("generic"), ErrorCategoryInternal, false},
+	}
+	for _, c := range cases {
+		category, mapped := getErrorCategory(c.err)
+		require.Equal(t, c.category, category, "%v", c.err)
+		require.Equal(t, c.mapped, mapped, "%v", c.err)
+	}
+}
+
+func TestHTTPErrorCategory(t *testing.T) {
+	require.Equal(t, ErrorCategoryNotFound, HTTPErrorCategory(http.StatusNotFound))
+	require.Equal(t, ErrorCategoryConflict, HTTPErrorCategory(http.StatusConflict))
+	require.Equal(t, ErrorCategoryValidation, HTTPErrorCategory(http.StatusBadRequest))
+	require.Equal(t, ErrorCategoryQuota, HTTPErrorCategory(http.StatusTooManyRequests))
+	require.Equal(t, ErrorCategoryBackendUnavailable, HTTPErrorCategory(http.StatusServiceUnavailable))
+	require.Equal(t, ErrorCategoryTimeout, HTTPErrorCategory(http.StatusGatewayTimeout))
+	require.Equal(t, ErrorCategoryInternal, HTTPErrorCategory(http.StatusInternalServerError))
+}
+
+func TestErrorTags(t *testing.T) {
+	measurement := NewMeasurement("test.service.name", "TestResource", "rpc", GetGlobalTags())
+
+	tags := measurement.GetRequestErrorTags(fmt.Errorf("failed to read key %d: %w", 42, &testUnmappedError{}))
+	require.Equal(t, "*metrics.testUnmappedError", tags["error_value"])
+	require.Equal(t, ErrorCategoryInternal, tags["error_category"])
+	require.Equal(t, "UNKNOWN", tags["error_code"])
+
+	tags = measurement.GetRequestErrorTags(errors.NotFound("collection doesn't exist"))
+	require.Equal(t, "NOT_FOUND", tags["error_value"])
+	require.Equal(t, ErrorCategoryNotFound, tags["error_category"])
+	require.Equal(t, "NOT_FOUND", tags["error_code"])
+	require.Equal(t, "false", tags["error_retryable"])
+
+	tags = measurement.GetRequestErrorTags(errors.Unavailable("search is unavailable"))
+	require.Equal(t, ErrorCategoryBackendUnavailable, tags["error_category"])
+	require.Equal(t, "true", tags["error_retryable"])
+
+	tags = measurement.GetFdbErrorTags(fdb.Error{Code: 1020})
+	require.Equal(t, "1020", tags["error_value"])
+	require.Equal(t, ErrorCategoryConflict, tags["error_category"])
+	require.Equal(t, "UNKNOWN", tags["error_code"])
+
+	tags = measurement.GetSearchErrorTags(testCategorizedError{})
+	require.Equal(t, "search", tags["error_source"])
+	require.Equal(t, "metrics.testCategorizedError", tags["error_value"])
+	require.Equal(t, ErrorCategoryQuota, tags["error_category"])
+}
+
+func TestCountUnmappedError(t *testing.T) {
+	defer func() { ErrorMetrics = nil }()
+
+	testScope := tally.NewTestScope("", nil)
+	ErrorMetrics = testScope
+
+	countUnmappedError("request", fmt.Errorf("wrapped: %w", &testUnmappedError{}))
+	countUnmappedError("request", fmt.Errorf("generic"))
+	countUnmappedError("fdb", fdb.Error{Code: 1})
+	countUnmappedError("request", errors.InvalidArgument("invalid"))
+	countUnmappedError("request", nil)
+
+	counters := testScope.Snapshot().Counters()
+	require.Len(t, counters, 3)
+	require.Equal(t, int64(1), counters["unmapped_error+error_source=request,error_type=*metrics.testUnmappedError"].Value())
+	require.Equal(t, int64(1), counters["unmapped_error+error_source=request,error_type=*errors.errorString"].Value())
+	require.Equal(t, int64(1), counters["unmapped_error+error_source=fdb,error_type=fdb.Error"].Value())
+}
@@ -15,6 +15,14 @@
 package metrics
 
 import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/defaults"
 	"github.com/uber-go/tally"
 )
 
@@ -23,8 +31,97 @@ var (
 	FdbErrorCount    tally.Scope
 	FdbRespTime      tally.Scope
 	FdbErrorRespTime tally.Scope
+	// FdbRetryCount counts the retries of the operations of the store by the FoundationDB error code.
+	FdbRetryCount tally.Scope
+)
+
+// fdbOperationPattern is the pattern of the names of the operations reported in the fdb_method tag.
+var fdbOperationPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// fdbOperations are the operations reported in the fdb_method tag. The operations of the store are registered
+// upfront and the wrappers of the transactions register theirs with RegisterFdbOperations.
+var fdbOperations = newFdbOperationRegistry(
+	"BeginTx", "Commit", "Rollback",
+	"CreateTable", "DropTable",
+	"Insert", "Replace", "Delete", "DeleteRange", "Update", "UpdateRange",
+	"Read", "ReadRange", "ReadRangeParallel", "Get",
+	"SetVersionstampedKey", "SetVersionstampedValue",
+	"TableSize", "TableSizeExact", "TableRangeSizes",
 )
 
+type fdbOperationRegistry struct {
+	sync.RWMutex
+
+	names map[string]struct{}
+	// flagged are the unregistered names that were already logged.
+	flagged map[string]struct{}
+}
+
+func newFdbOperationRegistry(names ...string) *fdbOperationRegistry {
+	r := &fdbOperationRegistry{
+		names:   make(map[string]struct{}, len(names)),
+		flagged: make(map[string]struct{}),
+	}
+	for _, name := range names {
+		r.names[name] = struct{}{}
+	}
+	return r
+}
+
+func (r *fdbOperationRegistry) register(names ...string) error {
+	for _, name := range names {
+		if !fdbOperationPattern.MatchString(name) {
+			return fmt.Errorf("invalid fdb operation name '%s', it must match %s", name, fdbOperationPattern)
+		}
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	for _, name := range names {
+		r.names[name] = struct{}{}
+	}
+	return nil
+}
+
+func (r *fdbOperationRegistry) registered(name string) bool {
+	r.RLock()
+	defer r.RUnlock()
+
+	_, ok := r.names[name]
+	return ok
+}
+
+// tag returns the value of the fdb_method tag of the operation, the unregistered operations are reported as unknown
+// and logged the first time they are seen.
+func (r *fdbOperationRegistry) tag(name string) string {
+	if r.registered(name) {
+		return name
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.flagged[name]; !ok {
+		r.flagged[name] = struct{}{}
+		log.Warn().Str("fdb_method", name).Msg("fdb operation is not registered, it is reported as unknown")
+	}
+	return defaults.UnknownValue
+}
+
+// RegisterFdbOperations registers the names of the operations reported in the fdb_method tag.
+func RegisterFdbOperations(names ...string) error {
+	return fdbOperations.register(names...)
+}
+
+// IsFdbOperationRegistered returns true if the operation is reported in the fdb_method tag.
+func IsFdbOperationRegistered(name string) bool {
+	return fdbOperations.registered(name)
+}
+
+// ValidateFdbOperations registers the operations of the config, it fails on the names that can't be reported.
+func ValidateFdbOperations(cfg *config.MetricsConfig) error {
+	return RegisterFdbOperations(cfg.Fdb.Operations...)
+}
+
 func getFdbOkTagKeys() []string {
 	return []string{
 		"grpc_method",
@@ -48,18 +145,20 @@ func getFdbErrorTagKeys() []string {
 		"fdb_method",
 		"error_source",
 		"error_value",
+		"error_code",
+		"error_category",
 	}
 }
 
 func GetFdbOkTags(reqMethodName string) map[string]string {
 	return map[string]string{
-		"fdb_method": reqMethodName,
+		"fdb_method": fdbOperations.tag(reqMethodName),
 	}
 }
 
 func GetFdbErrorTags(reqMethodName string, code string) map[string]string {
 	return map[string]string{
-		"fdb_method":   reqMethodName,
+		"fdb_method":   fdbOperations.tag(reqMethodName),
 		"error_source": "fdb",
 		"error_value":  code,
 	}
@@ -74,4 +173,37 @@ func initializeFdbScopes() {
 	FdbErrorCount = FdbMetrics.SubScope("count")
 	FdbRespTime = FdbMetrics.SubScope("response")
 	FdbErrorRespTime = FdbMetrics.SubScope("error_response")
+	FdbRetryCount = FdbMetrics.SubScope("retry")
+}
+
+func getFdbRetryTags(code int) map[string]string {
+	category, ok := fdbErrorCategories[code]
+	if !ok {
+		category = ErrorCategoryInternal
+	}
+
+	return map[string]string{
+		"error_source":   "fdb",
+		"error_value":    strconv.Itoa(code),
+		"error_category": category,
+	}
+}
+
+// CountFdbRetry counts a retry of an operation of the store on the FoundationDB error with the code.
+func CountFdbRetry(code int) {
+	if FdbRetryCount == nil {
+		return
+	}
+
+	FdbRetryCount.Tagged(getFdbRetryTags(code)).Counter("retries").Inc(1)
+}
+
+// CountFdbRetriesExhausted counts the operations of the store that failed with the FoundationDB error with the code
+// once they were retried the maximum number of times.
+func CountFdbRetriesExhausted(code int) {
+	if FdbRetryCount == nil {
+		return
+	}
+
+	FdbRetryCount.Tagged(getFdbRetryTags(code)).Counter("exhausted").Inc(1)
 }
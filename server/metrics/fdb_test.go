@@ -18,7 +18,9 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/defaults"
 )
 
 func TestFdbMetrics(t *testing.T) {
@@ -59,3 +61,30 @@ func TestFdbMetrics(t *testing.T) {
 		defer FdbErrorRespTime.Tagged(testTimerTags).Timer("time").Start().Stop()
 	})
 }
+
+func TestFdbOperations(t *testing.T) {
+	save := fdbOperations
+	t.Cleanup(func() { fdbOperations = save })
+	fdbOperations = newFdbOperationRegistry("Commit")
+
+	// the registered operations are reported in the fdb_method tag
+	require.True(t, IsFdbOperationRegistered("Commit"))
+	require.Equal(t, map[string]string{"fdb_method": "Commit"}, GetFdbOkTags("Commit"))
+
+	// the unregistered operations are flagged and reported as unknown
+	require.False(t, IsFdbOperationRegistered("ReadWithRetry"))
+	require.Equal(t, map[string]string{"fdb_method": defaults.UnknownValue}, GetFdbOkTags("ReadWithRetry"))
+	require.Equal(t, defaults.UnknownValue, GetFdbErrorTags("ReadWithRetry", "1020")["fdb_method"])
+	require.Contains(t, fdbOperations.flagged, "ReadWithRetry")
+
+	cfg := config.MetricsConfig{Fdb: config.FdbMetricGroupConfig{Operations: []string{"ReadWithRetry"}}}
+	require.NoError(t, ValidateFdbOperations(&cfg))
+	require.True(t, IsFdbOperationRegistered("ReadWithRetry"))
+	require.Equal(t, map[string]string{"fdb_method": "ReadWithRetry"}, GetFdbOkTags("ReadWithRetry"))
+
+	for _, name := range []string{"", "read with retry", "1Read"} {
+		cfg.Fdb.Operations = []string{name}
+		require.Error(t, ValidateFdbOperations(&cfg), name)
+		require.False(t, IsFdbOperationRegistered(name))
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"github.com/uber-go/tally"
+)
+
+// FieldMetrics counts the writes of the fields of the collections, to find the fields that are updated the most.
+var FieldMetrics tally.Scope
+
+func getFieldWriteTags(db string, collection string, field string) map[string]string {
+	return limitTagCardinality(map[string]string{
+		"db":         db,
+		"collection": collection,
+		"field":      field,
+	})
+}
+
+// CountFieldWrites counts the writes of the fields of the collection, the fields are the dotted paths changed by the
+// field operators of an update. Each field is counted once per document written.
+func CountFieldWrites(db string, collection string, fields []string, documents int64) {
+	if FieldMetrics == nil || documents == 0 {
+		return
+	}
+
+	counted := make(map[string]struct{}, len(fields))
+	for _, field := range fields {
+		if _, ok := counted[field]; ok {
+			continue
+		}
+		counted[field] = struct{}{}
+		FieldMetrics.Tagged(getFieldWriteTags(db, collection, field)).Counter("writes").Inc(documents)
+	}
+}
@@ -23,9 +23,15 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/defaults"
+	"github.com/tigrisdata/tigris/server/tracing"
 	ulog "github.com/tigrisdata/tigris/util/log"
 	"github.com/uber-go/tally"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc/status"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 )
 
@@ -46,19 +52,42 @@ type Measurement struct {
 	spanType     string
 	tags         map[string]string
 	span         tracer.Span
+	otelSpan     trace.Span
+	otelParent   trace.Span
 	parent       *Measurement
 	started      bool
 	stopped      bool
 	startedAt    time.Time
 	stoppedAt    time.Time
+	// stats is the breakdown of the request, it is only set on the top level measurement
+	stats *requestStats
+	// sampling is the sampling decision of the trace, the child measurements inherit the decision of their parent
+	sampling TraceSampling
 }
 
+// TraceSampling is the sampling decision of a request made before its measurement is started.
+type TraceSampling uint8
+
+const (
+	// TraceSamplingDefault leaves the decision to the sampler of the tracer.
+	TraceSamplingDefault TraceSampling = iota
+	// TraceSamplingKeep keeps the trace regardless of the sampler of the tracer.
+	TraceSamplingKeep
+	// TraceSamplingDrop doesn't create the spans of the request, the request is still measured in the metrics.
+	TraceSamplingDrop
+)
+
 type MeasurementCtxKey struct{}
 
 func NewMeasurement(serviceName string, resourceName string, spanType string, tags map[string]string) *Measurement {
 	return &Measurement{serviceName: serviceName, resourceName: resourceName, spanType: spanType, tags: tags}
 }
 
+// SetTraceSampling sets the sampling decision of the trace, it must be called before StartTracing.
+func (m *Measurement) SetTraceSampling(sampling TraceSampling) {
+	m.sampling = sampling
+}
+
 func MeasurementFromContext(ctx context.Context) (*Measurement, bool) {
 	s, ok := ctx.Value(MeasurementCtxKey{}).(*Measurement)
 	return s, ok
@@ -105,7 +134,7 @@ func (m *Measurement) GetRequestOkTags() map[string]string {
 }
 
 func (m *Measurement) GetRequestErrorTags(err error) map[string]string {
-	return filterTags(standardizeTags(mergeTags(m.tags, getTagsForError(err, "request")), getRequestErrorTagKeys()), config.DefaultConfig.Metrics.Requests.FilteredTags)
+	return filterTags(standardizeTags(mergeTags(m.tags, getErrorTags(err, "request")), getRequestErrorTagKeys()), config.DefaultConfig.Metrics.Requests.FilteredTags)
 }
 
 func (m *Measurement) GetFdbOkTags() map[string]string {
@@ -113,7 +142,7 @@ func (m *Measurement) GetFdbOkTags() map[string]string {
 }
 
 func (m *Measurement) GetFdbErrorTags(err error) map[string]string {
-	return filterTags(standardizeTags(mergeTags(m.tags, getTagsForError(err, "fdb")), getFdbErrorTagKeys()), config.DefaultConfig.Metrics.Fdb.FilteredTags)
+	return filterTags(standardizeTags(mergeTags(m.tags, getErrorTags(err, "fdb")), getFdbErrorTagKeys()), config.DefaultConfig.Metrics.Fdb.FilteredTags)
 }
 
 func (m *Measurement) GetSearchOkTags() map[string]string {
@@ -121,7 +150,7 @@ func (m *Measurement) GetSearchOkTags() map[string]string {
 }
 
 func (m *Measurement) GetSearchErrorTags(err error) map[string]string {
-	return filterTags(standardizeTags(mergeTags(m.tags, getTagsForError(err, "search")), getSearchErrorTagKeys()), config.DefaultConfig.Metrics.Search.FilteredTags)
+	return filterTags(standardizeTags(mergeTags(m.tags, getErrorTags(err, "search")), getSearchErrorTagKeys()), config.DefaultConfig.Metrics.Search.FilteredTags)
 }
 
 func (m *Measurement) GetSessionOkTags() map[string]string {
@@ -153,11 +182,12 @@ func (m *Measurement) GetAuthOkTags() map[string]string {
 }
 
 func (m *Measurement) GetAuthErrorTags(err error) map[string]string {
-	return filterTags(standardizeTags(mergeTags(m.tags, getTagsForError(err, "auth")), getAuthErrorTagKeys()), config.DefaultConfig.Metrics.Auth.FilteredTags)
+	tags := mergeTags(m.tags, getTagsForError(err, "auth"), getAuthFailureTags(err))
+	return filterTags(standardizeTags(tags, getAuthErrorTagKeys()), config.DefaultConfig.Metrics.Auth.FilteredTags)
 }
 
 func (m *Measurement) SaveMeasurementToContext(ctx context.Context) (context.Context, error) {
-	if m.span == nil {
+	if m.span == nil && m.sampling != TraceSamplingDrop {
 		return nil, fmt.Errorf("parent span was not created")
 	}
 	ctx = context.WithValue(ctx, MeasurementCtxKey{}, m)
@@ -181,6 +211,9 @@ func (m *Measurement) AddTags(tags map[string]string) {
 				// The span already exists, set the tag there as well
 				m.span.SetTag(k, v)
 			}
+			if m.otelSpan != nil {
+				m.otelSpan.SetAttributes(attribute.String(k, v))
+			}
 		}
 	}
 }
@@ -205,7 +238,10 @@ func (m *Measurement) StartTracing(ctx context.Context, childOnly bool) context.
 	spanOpts := m.GetSpanOptions()
 	if parentMeasurement, parentExists := MeasurementFromContext(ctx); parentExists {
 		// This is a child span, parents need to be marked
-		spanOpts = append(spanOpts, tracer.ChildOf(parentMeasurement.span.Context()))
+		m.sampling = parentMeasurement.sampling
+		if parentMeasurement.span != nil {
+			spanOpts = append(spanOpts, tracer.ChildOf(parentMeasurement.span.Context()))
+		}
 		m.parent = parentMeasurement
 		// Copy the tags from the parent span
 		m.AddTags(parentMeasurement.GetTags())
@@ -215,9 +251,19 @@ func (m *Measurement) StartTracing(ctx context.Context, childOnly bool) context.
 		return ctx
 	}
 
-	m.span = tracer.StartSpan(TraceServiceName, spanOpts...)
-	for k, v := range m.tags {
-		m.span.SetTag(k, v)
+	if m.parent == nil {
+		m.stats = &requestStats{}
+	}
+
+	if m.sampling != TraceSamplingDrop {
+		if m.sampling == TraceSamplingKeep && m.parent == nil {
+			spanOpts = append(spanOpts, tracer.Tag(ext.ManualKeep, true))
+		}
+		m.span = tracer.StartSpan(TraceServiceName, spanOpts...)
+		for k, v := range m.tags {
+			m.span.SetTag(k, v)
+		}
+		ctx = m.startOtelSpan(ctx)
 	}
 
 	ctx, err := m.SaveMeasurementToContext(ctx)
@@ -227,6 +273,60 @@ func (m *Measurement) StartTracing(ctx context.Context, childOnly bool) context.
 	return ctx
 }
 
+func (m *Measurement) getOtelSpanKind() trace.SpanKind {
+	switch {
+	case m.spanType == GrpcSpanType && m.parent == nil:
+		return trace.SpanKindServer
+	case m.spanType == FdbSpanType || m.spanType == SearchSpanType:
+		return trace.SpanKindClient
+	default:
+		return trace.SpanKindInternal
+	}
+}
+
+// startOtelSpan starts the OpenTelemetry span of the measurement when the OTLP export is enabled. Its parent is the
+// span of the context, either the span of the parent measurement or the remote span of the upstream caller.
+func (m *Measurement) startOtelSpan(ctx context.Context) context.Context {
+	if !config.DefaultConfig.Tracing.Enabled || !config.DefaultConfig.Tracing.Otlp.Enabled {
+		return ctx
+	}
+
+	attrs := make([]attribute.KeyValue, 0, len(m.tags)+3)
+	attrs = append(attrs, attribute.String("service", m.serviceName), attribute.String("span.type", m.spanType))
+	if m.sampling == TraceSamplingKeep && m.parent == nil {
+		attrs = append(attrs, tracing.KeepAttribute.Bool(true))
+	}
+	for k, v := range m.tags {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+
+	m.otelParent = trace.SpanFromContext(ctx)
+	ctx, m.otelSpan = otel.Tracer(TraceServiceName).Start(ctx, m.resourceName,
+		trace.WithSpanKind(m.getOtelSpanKind()),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx
+}
+
+// finishOtelSpan ends the OpenTelemetry span of the measurement and restores the span of the parent in the context.
+func (m *Measurement) finishOtelSpan(ctx context.Context, source string, err error) context.Context {
+	if m.otelSpan == nil {
+		return ctx
+	}
+
+	if err != nil {
+		m.otelSpan.SetAttributes(attribute.String("grpc.code", status.Code(err).String()))
+		for k, v := range getTagsForError(err, source) {
+			m.otelSpan.SetAttributes(attribute.String(k, v))
+		}
+		m.otelSpan.RecordError(err)
+		m.otelSpan.SetStatus(otelcodes.Error, err.Error())
+	}
+	m.otelSpan.End()
+
+	return trace.ContextWithSpan(ctx, m.otelParent)
+}
+
 func (m *Measurement) FinishTracing(ctx context.Context) context.Context {
 	if !m.started {
 		log.Error().Str("service_name", m.serviceName).Str("resource_name", m.resourceName).Msg("Finish tracing called before starting the trace")
@@ -235,12 +335,14 @@ func (m *Measurement) FinishTracing(ctx context.Context) context.Context {
 
 	m.stopped = true
 	m.stoppedAt = time.Now()
+	m.recordChildTime()
 
 	log.Debug().Str("started", strconv.FormatBool(m.started)).Str("stopped", strconv.FormatBool(m.stopped)).Str("span_type", m.spanType).Msg("FinishingTracing start")
 
 	if m.span != nil {
 		m.span.Finish()
 	}
+	ctx = m.finishOtelSpan(ctx, "", nil)
 
 	if m.parent != nil {
 		var err error
@@ -257,6 +359,7 @@ func (m *Measurement) FinishTracing(ctx context.Context) context.Context {
 
 func (m *Measurement) RecordDuration(scope tally.Scope, tags map[string]string) {
 	var timerEnabled, histogramEnabled bool
+	var buckets tally.Buckets
 	cfg := config.DefaultConfig.Metrics
 	switch scope {
 	case AuthRespTime, AuthErrorRespTime:
@@ -264,21 +367,25 @@ func (m *Measurement) RecordDuration(scope tally.Scope, tags map[string]string)
 	case RequestsRespTime, RequestsErrorRespTime:
 		timerEnabled = cfg.Requests.Timer.TimerEnabled
 		histogramEnabled = cfg.Requests.Timer.HistogramEnabled
+		buckets = requestsHistogramBuckets
 	case FdbRespTime, FdbErrorRespTime:
 		timerEnabled = cfg.Fdb.Timer.TimerEnabled
 		histogramEnabled = cfg.Fdb.Timer.HistogramEnabled
+		buckets = fdbHistogramBuckets
 	case SessionRespTime, SessionErrorRespTime:
 		timerEnabled = cfg.Session.Timer.TimerEnabled
 		histogramEnabled = cfg.Session.Timer.HistogramEnabled
+		buckets = sessionHistogramBuckets
 	case SearchRespTime, SearchErrorRespTime:
 		timerEnabled = cfg.Search.Timer.TimerEnabled
 		histogramEnabled = cfg.Search.Timer.HistogramEnabled
+		buckets = searchHistogramBuckets
 	}
 	if scope != nil && timerEnabled {
 		m.recordTimerDuration(scope, tags)
 	}
 	if scope != nil && histogramEnabled {
-		m.recordHistogramDuration(scope, tags)
+		m.recordHistogramDuration(scope, tags, buckets)
 	}
 }
 
@@ -295,7 +402,7 @@ func (m *Measurement) recordTimerDuration(scope tally.Scope, tags map[string]str
 	scope.Tagged(tags).Timer("time").Record(m.stoppedAt.Sub(m.startedAt))
 }
 
-func (m *Measurement) recordHistogramDuration(scope tally.Scope, tags map[string]string) {
+func (m *Measurement) recordHistogramDuration(scope tally.Scope, tags map[string]string, buckets tally.Buckets) {
 	if !m.started {
 		log.Error().Str("service_name", m.serviceName).Str("resource_name", m.resourceName).Str("span_type", m.spanType).Msg("recordHistogramDuration was called on a span that was not started")
 		return
@@ -304,7 +411,7 @@ func (m *Measurement) recordHistogramDuration(scope tally.Scope, tags map[string
 		log.Error().Str("service_name", m.serviceName).Str("resource_name", m.resourceName).Str("span_type", m.spanType).Msg("recordHistogramDuration was called on a span that was not stopped")
 		return
 	}
-	scope.Tagged(tags).Histogram("histogram", tally.DefaultBuckets).RecordDuration(m.stoppedAt.Sub(m.startedAt))
+	scope.Tagged(tags).Histogram("histogram", buckets).RecordDuration(m.stoppedAt.Sub(m.startedAt))
 }
 
 func (m *Measurement) FinishWithError(ctx context.Context, source string, err error) context.Context {
@@ -315,22 +422,23 @@ func (m *Measurement) FinishWithError(ctx context.Context, source string, err er
 
 	m.stopped = true
 	m.stoppedAt = time.Now()
+	m.recordChildTime()
+	countUnmappedError(source, err)
 
-	if m.span == nil {
+	if m.span == nil && m.sampling != TraceSamplingDrop {
 		log.Debug().Msg("FinishWithError end: no tracing span found to finish, returning")
 		return ctx
 	}
-	errCode := status.Code(err)
-	m.span.SetTag("grpc.code", errCode.String())
-	errTags := getTagsForError(err, source)
-	for k, v := range errTags {
-		m.span.SetTag(k, v)
-	}
-	finishOptions := []tracer.FinishOption{tracer.WithError(err)}
-
 	if m.span != nil {
-		m.span.Finish(finishOptions...)
+		errCode := status.Code(err)
+		m.span.SetTag("grpc.code", errCode.String())
+		errTags := getTagsForError(err, source)
+		for k, v := range errTags {
+			m.span.SetTag(k, v)
+		}
+		m.span.Finish(tracer.WithError(err))
 	}
+	ctx = m.finishOtelSpan(ctx, source, err)
 
 	if m.parent != nil {
 		var err error
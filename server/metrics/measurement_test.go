@@ -21,6 +21,14 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/defaults"
+	"github.com/tigrisdata/tigris/server/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type FakeError struct{}
@@ -29,6 +37,18 @@ func (f *FakeError) Error() string {
 	return "fake error for testing tags"
 }
 
+type fakeAuthError struct {
+	reason string
+}
+
+func (f *fakeAuthError) Error() string {
+	return "fake auth error"
+}
+
+func (f *fakeAuthError) AuthFailure() string {
+	return f.reason
+}
+
 func TestTracing(t *testing.T) {
 	t.Run("Test NewMeasurement", func(t *testing.T) {
 		tags := GetGlobalTags()
@@ -220,4 +240,127 @@ func TestTracing(t *testing.T) {
 		}
 		assert.Equal(t, len(getNetworkTagKeys()), len(networkTags))
 	})
+
+	t.Run("Test auth error tags", func(t *testing.T) {
+		config.DefaultConfig.Metrics.Enabled = true
+		InitializeMetrics()
+		measurement := NewMeasurement("test.service.name", "TestResource", "auth", GetGlobalTags())
+
+		authTags := measurement.GetAuthErrorTags(&fakeAuthError{reason: "expired"})
+		assert.Equal(t, "expired", authTags["auth_failure"])
+		assert.Equal(t, len(getAuthErrorTagKeys()), len(authTags))
+
+		authTags = measurement.GetAuthErrorTags(fmt.Errorf("wrapped: %w", &fakeAuthError{reason: "wrong_audience"}))
+		assert.Equal(t, "wrong_audience", authTags["auth_failure"])
+
+		authTags = measurement.GetAuthErrorTags(&FakeError{})
+		assert.Equal(t, defaults.UnknownValue, authTags["auth_failure"])
+	})
+
+	t.Run("Test otel spans", func(t *testing.T) {
+		config.DefaultConfig.Tracing.Enabled = true
+		config.DefaultConfig.Metrics.Enabled = true
+		config.DefaultConfig.Tracing.Otlp.Enabled = true
+		defer func() { config.DefaultConfig.Tracing.Otlp.Enabled = false }()
+
+		recorder := tracetest.NewSpanRecorder()
+		prev := otel.GetTracerProvider()
+		defer otel.SetTracerProvider(prev)
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+		remote := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{1},
+			SpanID:     trace.SpanID{2},
+			TraceFlags: trace.FlagsSampled,
+			Remote:     true,
+		})
+		ctx := trace.ContextWithRemoteSpanContext(context.Background(), remote)
+
+		parentMeasurement := NewMeasurement("parent.service", "parent.resource", GrpcSpanType, map[string]string{"db": "db1"})
+		ctx = parentMeasurement.StartTracing(ctx, false)
+		childMeasurement := NewMeasurement("child.service", "child.resource", FdbSpanType, map[string]string{})
+		ctx = childMeasurement.StartTracing(ctx, true)
+		childMeasurement.AddTags(map[string]string{"collection": "coll1"})
+		ctx = childMeasurement.FinishWithError(ctx, "fdb", fmt.Errorf("child error"))
+		// the span of the parent is restored in the context
+		assert.Equal(t, recorder.Started()[0].SpanContext(), trace.SpanContextFromContext(ctx))
+		ctx = parentMeasurement.FinishTracing(ctx)
+		assert.Equal(t, remote, trace.SpanContextFromContext(ctx))
+
+		spans := recorder.Ended()
+		assert.Len(t, spans, 2)
+		child, parent := spans[0], spans[1]
+
+		assert.Equal(t, "parent.resource", parent.Name())
+		assert.Equal(t, trace.SpanKindServer, parent.SpanKind())
+		assert.Equal(t, remote, parent.Parent())
+		assert.Equal(t, remote.TraceID(), parent.SpanContext().TraceID())
+		assert.Equal(t, codes.Unset, parent.Status().Code)
+		assert.Contains(t, parent.Attributes(), attribute.String("db", "db1"))
+		assert.Contains(t, parent.Attributes(), attribute.String("service", "parent.service"))
+
+		assert.Equal(t, "child.resource", child.Name())
+		assert.Equal(t, trace.SpanKindClient, child.SpanKind())
+		assert.Equal(t, parent.SpanContext(), child.Parent())
+		assert.Equal(t, codes.Error, child.Status().Code)
+		assert.Equal(t, "child error", child.Status().Description)
+		// the tags of the parent and the ones added after the start are on the span
+		assert.Contains(t, child.Attributes(), attribute.String("db", "db1"))
+		assert.Contains(t, child.Attributes(), attribute.String("collection", "coll1"))
+		assert.Contains(t, child.Attributes(), attribute.String("error_source", "fdb"))
+		assert.Len(t, child.Events(), 1)
+	})
+
+	t.Run("Test trace sampling", func(t *testing.T) {
+		config.DefaultConfig.Tracing.Enabled = true
+		config.DefaultConfig.Metrics.Enabled = true
+		config.DefaultConfig.Tracing.Otlp.Enabled = true
+		defer func() { config.DefaultConfig.Tracing.Otlp.Enabled = false }()
+
+		recorder := tracetest.NewSpanRecorder()
+		prev := otel.GetTracerProvider()
+		defer otel.SetTracerProvider(prev)
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+		// the spans of a dropped request are not created, the child still finds its parent
+		parentMeasurement := NewMeasurement("parent.service", "parent.resource", GrpcSpanType, map[string]string{"db": "db1"})
+		parentMeasurement.SetTraceSampling(TraceSamplingDrop)
+		ctx := parentMeasurement.StartTracing(context.Background(), false)
+		loaded, ok := MeasurementFromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, parentMeasurement, loaded)
+
+		childMeasurement := NewMeasurement("child.service", "child.resource", FdbSpanType, map[string]string{})
+		ctx = childMeasurement.StartTracing(ctx, true)
+		assert.Equal(t, parentMeasurement, childMeasurement.parent)
+		assert.Equal(t, TraceSamplingDrop, childMeasurement.sampling)
+		assert.Equal(t, "db1", childMeasurement.GetTags()["db"])
+		ctx = childMeasurement.FinishWithError(ctx, "fdb", fmt.Errorf("child error"))
+		loaded, ok = MeasurementFromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, parentMeasurement, loaded)
+		ctx = parentMeasurement.FinishTracing(ctx)
+		_, ok = MeasurementFromContext(ctx)
+		assert.False(t, ok)
+
+		assert.Nil(t, parentMeasurement.span)
+		assert.Nil(t, childMeasurement.span)
+		assert.Empty(t, recorder.Started())
+		// the time of the child is still part of the breakdown of the request
+		assert.Equal(t, childMeasurement.stoppedAt.Sub(childMeasurement.startedAt), parentMeasurement.stats.fdbTime)
+
+		// a kept request is marked on its root span only
+		parentMeasurement = NewMeasurement("parent.service", "parent.resource", GrpcSpanType, map[string]string{})
+		parentMeasurement.SetTraceSampling(TraceSamplingKeep)
+		ctx = parentMeasurement.StartTracing(context.Background(), false)
+		childMeasurement = NewMeasurement("child.service", "child.resource", FdbSpanType, map[string]string{})
+		ctx = childMeasurement.StartTracing(ctx, true)
+		ctx = childMeasurement.FinishTracing(ctx)
+		_ = parentMeasurement.FinishTracing(ctx)
+
+		spans := recorder.Ended()
+		assert.Len(t, spans, 2)
+		assert.NotContains(t, spans[0].Attributes(), tracing.KeepAttribute.Bool(true))
+		assert.Contains(t, spans[1].Attributes(), tracing.KeepAttribute.Bool(true))
+	})
 }
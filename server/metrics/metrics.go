@@ -16,6 +16,7 @@ package metrics
 
 import (
 	"io"
+	"math"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -37,6 +38,7 @@ var (
 	QuotaMetrics   tally.Scope
 	NetworkMetrics tally.Scope
 	AuthMetrics    tally.Scope
+	CdcMetrics     tally.Scope
 )
 
 func getVersion() string {
@@ -65,25 +67,31 @@ func getTigrisDefaultSummaryObjectives() map[float64]float64 {
 	}
 }
 
-func getTimerSummaryObjectives() map[float64]float64 {
+// getTimerSummaryObjectives returns the objectives of the quantiles, the allowed error of the quantiles without a
+// default objective is a tenth of the distance to 1, so that the p99.99 is not merged with the p99.9.
+func getTimerSummaryObjectives(quantiles []float64) map[float64]float64 {
+	defaults := getTigrisDefaultSummaryObjectives()
 	res := make(map[float64]float64)
-	for confQuantile, objective := range getTigrisDefaultSummaryObjectives() {
-		for _, wantedQuantile := range config.DefaultConfig.Metrics.TimerQuantiles {
-			if confQuantile == wantedQuantile {
-				res[confQuantile] = objective
-			}
+	for _, wantedQuantile := range quantiles {
+		if objective, ok := defaults[wantedQuantile]; ok {
+			res[wantedQuantile] = objective
+		} else {
+			res[wantedQuantile] = math.Min(0.01, (1-wantedQuantile)/10)
 		}
 	}
 	return res
 }
 
 func InitializeMetrics() func() {
+	return initializeMetrics(promreporter.Options{})
+}
+
+func initializeMetrics(reporterOpts promreporter.Options) func() {
 	var closer io.Closer
 	if cfg := config.DefaultConfig.Metrics; cfg.Enabled {
 		log.Debug().Msg("Initializing metrics")
-		Reporter = promreporter.NewReporter(promreporter.Options{
-			DefaultSummaryObjectives: getTimerSummaryObjectives(),
-		})
+		Reporter = newReporter(&cfg, reporterOpts)
+		initializeHistogramBuckets(&cfg)
 		root, closer = tally.NewRootScope(tally.ScopeOptions{
 			Tags:           GetGlobalTags(),
 			CachedReporter: Reporter,
@@ -126,11 +134,41 @@ func InitializeMetrics() func() {
 			AuthMetrics = root.SubScope("auth")
 			initializeAuthScopes()
 		}
+		if cfg.Cdc.Enabled {
+			// Change stream metrics
+			CdcMetrics = root.SubScope("cdc")
+			initializeCdcScopes()
+		}
+
+		if cfg.Usage.Enabled {
+			// Namespace usage metrics
+			UsageMetrics = root.SubScope("usage")
+		}
+		// Error mapping metrics
+		ErrorMetrics = root.SubScope("errors")
+		// Field write metrics
+		FieldMetrics = root.SubScope("fields")
+		// Document size metrics
+		DocumentMetrics = root.SubScope("documents")
 
 		if config.DefaultConfig.Quota.Namespace.Enabled {
 			initializeQuotaScopes()
 		}
+		if config.DefaultConfig.Quota.Requests.Enabled {
+			// Request rate limits metrics
+			RequestRateMetrics = root.SubScope("request_rate")
+		}
+		if cfg.TagCardinality.Enabled {
+			// Tag cardinality metrics
+			TagCardinalityMetrics = root.SubScope("tags")
+		}
+		initializeTagCardinality(&cfg.TagCardinality)
+		if cfg.SlowQuery.Enabled && cfg.SlowQuery.CountEnabled {
+			// Slow query metrics
+			SlowQueries = root.SubScope("slow_query")
+		}
 	}
+	initializeSlowQueryThresholds(&config.DefaultConfig.Metrics.SlowQuery)
 
 	return func() {
 		if closer != nil {
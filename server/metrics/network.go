@@ -37,6 +37,7 @@ func getNetworkTagKeys() []string {
 func initializeNetworkScopes() {
 	BytesReceived = NetworkMetrics.SubScope("bytes")
 	BytesSent = NetworkMetrics.SubScope("bytes")
+	initializeStreamScopes()
 }
 
 func (m *Measurement) CountSentBytes(scope tally.Scope, tags map[string]string, size int) {
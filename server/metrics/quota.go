@@ -23,6 +23,7 @@ var (
 	QuotaThrottled tally.Scope
 	QuotaSet       tally.Scope
 	QuotaCurRates  tally.Scope
+	QuotaStorage   tally.Scope
 )
 
 func initializeQuotaScopes() {
@@ -31,6 +32,7 @@ func initializeQuotaScopes() {
 	QuotaThrottled = QuotaMetrics.SubScope("throttled")
 	QuotaSet = QuotaMetrics.SubScope("set_node")
 	QuotaCurRates = QuotaMetrics.SubScope("cur_rates")
+	QuotaStorage = QuotaMetrics.SubScope("storage")
 }
 
 func getQuotaUsageTags(namespaceName string) map[string]string {
@@ -98,3 +100,14 @@ func UpdateQuotaCurrentRatesReceivedLimit(namespaceName string, value int, isWri
 
 	QuotaCurRates.Tagged(getQuotaUsageTags(namespaceName)).Gauge(counter).Update(float64(value))
 }
+
+// UpdateQuotaStorage reports the data size of the namespace tracked by the storage quota and its limit.
+func UpdateQuotaStorage(namespaceName string, usage int64, limit int64) {
+	if QuotaStorage == nil {
+		return
+	}
+
+	scope := QuotaStorage.Tagged(getQuotaUsageTags(namespaceName))
+	scope.Gauge("usage_bytes").Update(float64(usage))
+	scope.Gauge("limit_bytes").Update(float64(limit))
+}
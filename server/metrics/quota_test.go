@@ -17,7 +17,9 @@ package metrics
 import (
 	"testing"
 
+	"github.com/stretchr/testify/require"
 	"github.com/tigrisdata/tigris/server/config"
+	"github.com/uber-go/tally"
 )
 
 func TestQuotaMetrics(t *testing.T) {
@@ -59,3 +61,21 @@ func TestQuotaMetrics(t *testing.T) {
 		UpdateQuotaCurrentNodeLimit(testNamespace, testSize, true)
 	})
 }
+
+func TestUpdateQuotaStorage(t *testing.T) {
+	save := QuotaStorage
+	defer func() { QuotaStorage = save }()
+
+	scope := tally.NewTestScope("", nil)
+	QuotaStorage = scope
+
+	UpdateQuotaStorage("ns1", 100, 1000)
+	UpdateQuotaStorage("ns1", 200, 1000)
+
+	gauges := scope.Snapshot().Gauges()
+	require.Equal(t, float64(200), gauges["usage_bytes+tigris_tenant=ns1"].Value())
+	require.Equal(t, float64(1000), gauges["limit_bytes+tigris_tenant=ns1"].Value())
+
+	QuotaStorage = nil
+	UpdateQuotaStorage("ns1", 300, 1000)
+}
@@ -0,0 +1,57 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"github.com/uber-go/tally"
+)
+
+// RequestRateMetrics reports the request rates of the namespaces and their limits, it is only set when the request
+// rates are limited.
+var RequestRateMetrics tally.Scope
+
+func getRequestRateTags(namespace string, kind string) map[string]string {
+	return limitTagCardinality(map[string]string{
+		"tigris_tenant": namespace,
+		"request_kind":  kind,
+	})
+}
+
+// UpdateRequestRate reports the requests per second of the namespace of the kind, read, write or ddl.
+func UpdateRequestRate(namespace string, kind string, rate int) {
+	if RequestRateMetrics == nil {
+		return
+	}
+
+	RequestRateMetrics.Tagged(getRequestRateTags(namespace, kind)).Gauge("rate").Update(float64(rate))
+}
+
+// UpdateRequestRateLimit reports the requests per second allowed for the namespace, zero is no limit.
+func UpdateRequestRateLimit(namespace string, kind string, limit int) {
+	if RequestRateMetrics == nil {
+		return
+	}
+
+	RequestRateMetrics.Tagged(getRequestRateTags(namespace, kind)).Gauge("limit").Update(float64(limit))
+}
+
+// CountRequestRateThrottled counts the requests of the namespace rejected because they are over the limit.
+func CountRequestRateThrottled(namespace string, kind string) {
+	if RequestRateMetrics == nil {
+		return
+	}
+
+	RequestRateMetrics.Tagged(getRequestRateTags(namespace, kind)).Counter("throttled").Inc(1)
+}
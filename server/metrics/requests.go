@@ -15,6 +15,7 @@
 package metrics
 
 import (
+	"github.com/tigrisdata/tigris/server/config"
 	"github.com/uber-go/tally"
 )
 
@@ -23,6 +24,8 @@ var (
 	RequestsErrorCount    tally.Scope
 	RequestsRespTime      tally.Scope
 	RequestsErrorRespTime tally.Scope
+	// RequestsUpdateOperators tracks the field operators used by the update requests.
+	RequestsUpdateOperators tally.Scope
 )
 
 func getRequestOkTagKeys() []string {
@@ -50,6 +53,9 @@ func getRequestErrorTagKeys() []string {
 		"collection",
 		"error_source",
 		"error_value",
+		"error_code",
+		"error_category",
+		"error_retryable",
 		"read_type",
 		"search_type",
 		"write_type",
@@ -62,4 +68,27 @@ func initializeRequestScopes() {
 	RequestsErrorCount = Requests.SubScope("count")
 	RequestsRespTime = Requests.SubScope("response")
 	RequestsErrorRespTime = Requests.SubScope("error_response")
+	RequestsUpdateOperators = Requests.SubScope("update")
+}
+
+func getUpdateOperatorTags(namespace string, db string, collection string, operator string) map[string]string {
+	return limitTagCardinality(map[string]string{
+		"env":           config.GetEnvironment(),
+		"tigris_tenant": namespace,
+		"db":            db,
+		"collection":    collection,
+		"operator":      operator,
+	})
+}
+
+// UpdateOperatorsUsed counts the field operators of an update request, every operator is counted once per request
+// regardless of the number of fields it updates or the number of documents the request updates.
+func UpdateOperatorsUsed(namespace string, db string, collection string, operators []string) {
+	if RequestsUpdateOperators == nil {
+		return
+	}
+
+	for _, op := range operators {
+		RequestsUpdateOperators.Tagged(getUpdateOperatorTags(namespace, db, collection, op)).Counter("operators").Inc(1)
+	}
 }
@@ -0,0 +1,52 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+)
+
+func TestUpdateOperatorsUsed(t *testing.T) {
+	t.Run("enabled", func(t *testing.T) {
+		save := RequestsUpdateOperators
+		t.Cleanup(func() { RequestsUpdateOperators = save })
+
+		scope := tally.NewTestScope("", nil)
+		RequestsUpdateOperators = scope
+
+		// a request combining the operators, followed by a request with only $set
+		UpdateOperatorsUsed("ns1", "db1", "coll1", []string{"$bit", "$set", "$unset"})
+		UpdateOperatorsUsed("ns1", "db1", "coll1", []string{"$set"})
+
+		counts := map[string]int64{}
+		for _, c := range scope.Snapshot().Counters() {
+			require.Equal(t, "operators", c.Name())
+			require.Equal(t, "coll1", c.Tags()["collection"])
+			counts[c.Tags()["operator"]] += c.Value()
+		}
+		require.Equal(t, map[string]int64{"$bit": 1, "$set": 2, "$unset": 1}, counts)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		save := RequestsUpdateOperators
+		t.Cleanup(func() { RequestsUpdateOperators = save })
+
+		RequestsUpdateOperators = nil
+		UpdateOperatorsUsed("ns1", "db1", "coll1", []string{"$set"})
+	})
+}
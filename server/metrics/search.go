@@ -15,6 +15,7 @@
 package metrics
 
 import (
+	"github.com/tigrisdata/tigris/server/config"
 	"github.com/uber-go/tally"
 )
 
@@ -23,6 +24,8 @@ var (
 	SearchErrorCount    tally.Scope
 	SearchRespTime      tally.Scope
 	SearchErrorRespTime tally.Scope
+	// SearchCircuitBreaker tracks the state of the circuit breaker in front of the search backend.
+	SearchCircuitBreaker tally.Scope
 )
 
 func getSearchOkTagKeys() []string {
@@ -47,6 +50,8 @@ func getSearchErrorTagKeys() []string {
 		"collection",
 		"error_source",
 		"error_value",
+		"error_code",
+		"error_category",
 		"search_method",
 	}
 }
@@ -56,6 +61,7 @@ func initializeSearchScopes() {
 	SearchErrorCount = SearchMetrics.SubScope("count")
 	SearchRespTime = SearchMetrics.SubScope("response")
 	SearchErrorRespTime = SearchMetrics.SubScope("error_response")
+	SearchCircuitBreaker = SearchMetrics.SubScope("circuit_breaker")
 }
 
 func GetSearchTags(reqMethodName string) map[string]string {
@@ -63,3 +69,24 @@ func GetSearchTags(reqMethodName string) map[string]string {
 		"search_method": reqMethodName,
 	}
 }
+
+// UpdateSearchCircuitBreakerState counts the transitions of the circuit breaker in front of the search backend and
+// tracks whether it is open.
+func UpdateSearchCircuitBreakerState(from string, to string, open bool) {
+	if SearchCircuitBreaker == nil {
+		return
+	}
+
+	tags := map[string]string{
+		"env":  config.GetEnvironment(),
+		"from": from,
+		"to":   to,
+	}
+	SearchCircuitBreaker.Tagged(tags).Counter("transitions").Inc(1)
+
+	value := 0.0
+	if open {
+		value = 1
+	}
+	SearchCircuitBreaker.Tagged(map[string]string{"env": config.GetEnvironment()}).Gauge("open").Update(value)
+}
@@ -23,6 +23,8 @@ var (
 	SessionErrorCount    tally.Scope
 	SessionRespTime      tally.Scope
 	SessionErrorRespTime tally.Scope
+	// SessionConflicts are the conflicts of the interactive transactions, per collection.
+	SessionConflicts tally.Scope
 )
 
 func getSessionOkTagKeys() []string {
@@ -62,4 +64,18 @@ func initializeSessionScopes() {
 	SessionErrorCount = SessionMetrics.SubScope("count")
 	SessionRespTime = SessionMetrics.SubScope("response")
 	SessionErrorRespTime = SessionMetrics.SubScope("error_response")
+	SessionConflicts = SessionMetrics.SubScope("conflicts")
+}
+
+// CountTransactionConflict counts a conflict of an interactive transaction on a collection.
+func CountTransactionConflict(namespace string, db string, collection string) {
+	if SessionConflicts == nil {
+		return
+	}
+
+	SessionConflicts.Tagged(limitTagCardinality(map[string]string{
+		"tigris_tenant_name": namespace,
+		"db":                 db,
+		"collection":         collection,
+	})).Counter("count").Inc(1)
 }
@@ -17,7 +17,9 @@ package metrics
 import (
 	"testing"
 
+	"github.com/stretchr/testify/require"
 	"github.com/tigrisdata/tigris/server/config"
+	"github.com/uber-go/tally"
 )
 
 func TestSessionMetrics(t *testing.T) {
@@ -45,3 +47,17 @@ func TestSessionMetrics(t *testing.T) {
 		defer SessionRespTime.Tagged(tags).Timer("time").Start().Stop()
 	})
 }
+
+func TestCountTransactionConflict(t *testing.T) {
+	scope := tally.NewTestScope("", nil)
+	SessionConflicts = scope
+	defer func() { SessionConflicts = nil }()
+
+	CountTransactionConflict("ns1", "db1", "orders")
+	CountTransactionConflict("ns1", "db1", "orders")
+	CountTransactionConflict("ns1", "db1", "users")
+
+	counters := scope.Snapshot().Counters()
+	require.Equal(t, int64(2), counters["count+collection=orders,db=db1,tigris_tenant_name=ns1"].Value())
+	require.Equal(t, int64(1), counters["count+collection=users,db=db1,tigris_tenant_name=ns1"].Value())
+}
@@ -22,12 +22,15 @@ var (
 	NamespaceSize  tally.Scope
 	DbSize         tally.Scope
 	CollectionSize tally.Scope
+	// SizeReporterMetrics are the metrics of the SizeReporter runs.
+	SizeReporterMetrics tally.Scope
 )
 
 func initializeSizeScopes() {
 	NamespaceSize = SizeMetrics.SubScope("namespace")
 	DbSize = SizeMetrics.SubScope("db")
 	CollectionSize = SizeMetrics.SubScope("collection")
+	SizeReporterMetrics = SizeMetrics.SubScope("reporter")
 }
 
 func getNameSpaceSizeTagKeys() []string {
@@ -71,20 +74,20 @@ func getNamespaceSizeTags(namespace string, namespaceName string) map[string]str
 }
 
 func getDbSizeTags(namespace string, namespaceName string, dbName string) map[string]string {
-	return map[string]string{
+	return limitTagCardinality(map[string]string{
 		"tigris_tenant":      namespace,
 		"tigris_tenant_name": GetTenantNameTagValue(namespace, namespaceName),
 		"db":                 dbName,
-	}
+	})
 }
 
 func getCollectionSizeTags(namespace string, namespaceName string, dbName string, collectionName string) map[string]string {
-	return map[string]string{
+	return limitTagCardinality(map[string]string{
 		"tigris_tenant":      namespace,
 		"tigris_tenant_name": GetTenantNameTagValue(namespace, namespaceName),
 		"db":                 dbName,
 		"collection":         collectionName,
-	}
+	})
 }
 
 func UpdateNameSpaceSizeMetrics(namespace string, namespaceName string, size int64) {
@@ -104,3 +107,9 @@ func UpdateCollectionSizeMetrics(namespace string, namespaceName string, dbName
 		CollectionSize.Tagged(getCollectionSizeTags(namespace, namespaceName, dbName, collectionName)).Gauge("bytes").Update(float64(size))
 	}
 }
+
+func UpdateCollectionDocumentsMetrics(namespace string, namespaceName string, dbName string, collectionName string, count int64) {
+	if CollectionSize != nil {
+		CollectionSize.Tagged(getCollectionSizeTags(namespace, namespaceName, dbName, collectionName)).Gauge("documents").Update(float64(count))
+	}
+}
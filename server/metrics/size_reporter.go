@@ -0,0 +1,189 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/server/config"
+	ulog "github.com/tigrisdata/tigris/util/log"
+)
+
+// CollectionRef identifies a collection measured by the SizeReporter.
+type CollectionRef struct {
+	Namespace     string
+	NamespaceName string
+	Db            string
+	Collection    string
+}
+
+// CollectionStatsSource lists the collections and measures them for the SizeReporter.
+type CollectionStatsSource interface {
+	// ListCollections returns all the collections of all the namespaces.
+	ListCollections(ctx context.Context) ([]CollectionRef, error)
+	// CollectionSize returns the estimated size of the collection in bytes.
+	CollectionSize(ctx context.Context, coll CollectionRef) (int64, error)
+	// CountDocuments counts the documents of the collection, it stops counting and returns limit+1 once the
+	// collection has more than limit documents.
+	CountDocuments(ctx context.Context, coll CollectionRef, limit int64) (int64, error)
+}
+
+// SizeReporter periodically emits the size and the number of documents of every collection as gauges. The
+// collections are measured concurrently, and the documents of the collections above the count threshold are not
+// counted as it would read all of them.
+type SizeReporter struct {
+	cfg    *config.SizeReporterConfig
+	source CollectionStatsSource
+	// overThreshold is the size of the collections when they were found to be above the count threshold, they are not
+	// counted again until their size goes below it.
+	overThreshold sync.Map
+	// newTicker returns the ticks starting the runs after the first one and the function stopping them
+	newTicker func(interval time.Duration) (<-chan time.Time, func())
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewSizeReporter(cfg *config.SizeReporterConfig, source CollectionStatsSource) *SizeReporter {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &SizeReporter{cfg: cfg, source: source, newTicker: newTimeTicker, ctx: ctx, cancel: cancel}
+}
+
+func newTimeTicker(interval time.Duration) (<-chan time.Time, func()) {
+	t := time.NewTicker(interval)
+	return t.C, t.Stop
+}
+
+// Start starts the background loop of the reporter.
+func (r *SizeReporter) Start() {
+	r.wg.Add(1)
+	go r.reportLoop()
+}
+
+// Stop stops the background loop and waits for the run in progress to return.
+func (r *SizeReporter) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+func (r *SizeReporter) reportLoop() {
+	defer r.wg.Done()
+
+	log.Debug().Dur("interval", r.cfg.Interval).Msg("Initializing collection size reporter")
+
+	ticks, stop := r.newTicker(r.cfg.Interval)
+	defer stop()
+
+	for {
+		// a run doesn't outlast the interval, the next run would otherwise fall further behind
+		ctx, cancel := context.WithTimeout(r.ctx, r.cfg.Interval)
+		r.report(ctx)
+		cancel()
+
+		select {
+		case <-ticks:
+		case <-r.ctx.Done():
+			log.Debug().Msg("Collection size reporter exited")
+			return
+		}
+	}
+}
+
+// report measures all the collections once and records the duration of the run.
+func (r *SizeReporter) report(ctx context.Context) {
+	start := time.Now()
+
+	colls, err := r.source.ListCollections(ctx)
+	if ulog.E(err) {
+		r.countError()
+		return
+	}
+
+	concurrency := r.cfg.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	work := make(chan CollectionRef)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for coll := range work {
+				r.reportCollection(ctx, coll)
+			}
+		}()
+	}
+
+	for _, coll := range colls {
+		if ctx.Err() != nil {
+			break
+		}
+		work <- coll
+	}
+	close(work)
+	wg.Wait()
+
+	if SizeReporterMetrics != nil {
+		SizeReporterMetrics.Gauge("last_run_duration").Update(time.Since(start).Seconds())
+		SizeReporterMetrics.Gauge("last_run_collections").Update(float64(len(colls)))
+	}
+	log.Debug().Dur("duration", time.Since(start)).Int("collections", len(colls)).Msg("Reported collection sizes")
+}
+
+func (r *SizeReporter) reportCollection(ctx context.Context, coll CollectionRef) {
+	size, err := r.source.CollectionSize(ctx, coll)
+	if ulog.E(err) {
+		r.countError()
+		return
+	}
+	UpdateCollectionSizeMetrics(coll.Namespace, coll.NamespaceName, coll.Db, coll.Collection, size)
+
+	if skippedAt, ok := r.overThreshold.Load(coll); ok && size >= skippedAt.(int64) {
+		r.countSkipped(coll)
+		return
+	}
+
+	count, err := r.source.CountDocuments(ctx, coll, r.cfg.MaxExactCount)
+	if ulog.E(err) {
+		r.countError()
+		return
+	}
+	if count > r.cfg.MaxExactCount {
+		r.overThreshold.Store(coll, size)
+		r.countSkipped(coll)
+		return
+	}
+	r.overThreshold.Delete(coll)
+	UpdateCollectionDocumentsMetrics(coll.Namespace, coll.NamespaceName, coll.Db, coll.Collection, count)
+}
+
+func (r *SizeReporter) countSkipped(coll CollectionRef) {
+	if SizeReporterMetrics != nil {
+		SizeReporterMetrics.Tagged(getCollectionSizeTags(coll.Namespace, coll.NamespaceName, coll.Db, coll.Collection)).
+			Counter("count_skipped").Inc(1)
+	}
+}
+
+func (r *SizeReporter) countError() {
+	if SizeReporterMetrics != nil {
+		SizeReporterMetrics.Counter("errors").Inc(1)
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/uber-go/tally"
+)
+
+type testStatsSource struct {
+	sync.Mutex
+
+	colls     []CollectionRef
+	sizes     map[string]int64
+	documents map[string]int64
+	counted   map[string]int
+
+	running    int32
+	maxRunning int32
+}
+
+func (s *testStatsSource) ListCollections(_ context.Context) ([]CollectionRef, error) {
+	return s.colls, nil
+}
+
+func (s *testStatsSource) CollectionSize(_ context.Context, coll CollectionRef) (int64, error) {
+	running := atomic.AddInt32(&s.running, 1)
+	defer atomic.AddInt32(&s.running, -1)
+	for {
+		maxRunning := atomic.LoadInt32(&s.maxRunning)
+		if running <= maxRunning || atomic.CompareAndSwapInt32(&s.maxRunning, maxRunning, running) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	s.Lock()
+	defer s.Unlock()
+	size, ok := s.sizes[coll.Collection]
+	if !ok {
+		return 0, errors.NotFound("collection doesn't exist '%s'", coll.Collection)
+	}
+	return size, nil
+}
+
+func (s *testStatsSource) CountDocuments(_ context.Context, coll CollectionRef, limit int64) (int64, error) {
+	s.Lock()
+	s.counted[coll.Collection]++
+	count := s.documents[coll.Collection]
+	s.Unlock()
+
+	if count <= limit {
+		return count, nil
+	}
+	return limit + 1, nil
+}
+
+func TestSizeReporter(t *testing.T) {
+	saveNamespace, saveCollection, saveReporter := NamespaceSize, CollectionSize, SizeReporterMetrics
+	t.Cleanup(func() {
+		NamespaceSize, CollectionSize, SizeReporterMetrics = saveNamespace, saveCollection, saveReporter
+	})
+
+	scope := tally.NewTestScope("", nil)
+	NamespaceSize = scope.SubScope("namespace")
+	CollectionSize = scope.SubScope("collection")
+	SizeReporterMetrics = scope.SubScope("reporter")
+
+	source := &testStatsSource{
+		sizes:     map[string]int64{},
+		documents: map[string]int64{},
+		counted:   map[string]int{},
+	}
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("coll%d", i)
+		source.colls = append(source.colls, CollectionRef{Namespace: "ns1", NamespaceName: "ns1", Db: "db1", Collection: name})
+		source.sizes[name] = int64(i * 100)
+		source.documents[name] = int64(i)
+	}
+	// the size of the last collection can't be read
+	delete(source.sizes, "coll9")
+	// the third one is above the count threshold
+	source.documents["coll2"] = 1000
+
+	r := NewSizeReporter(&config.SizeReporterConfig{Interval: time.Minute, Concurrency: 3, MaxExactCount: 100}, source)
+	r.report(context.Background())
+
+	require.LessOrEqual(t, source.maxRunning, int32(3))
+
+	gauges := map[string]float64{}
+	for _, g := range scope.Snapshot().Gauges() {
+		key := g.Name()
+		if coll, ok := g.Tags()["collection"]; ok {
+			key = coll + "." + key
+		}
+		gauges[key] = g.Value()
+	}
+	require.Equal(t, float64(300), gauges["coll3.collection.bytes"])
+	require.Equal(t, float64(3), gauges["coll3.collection.documents"])
+	require.Equal(t, float64(200), gauges["coll2.collection.bytes"])
+	require.NotContains(t, gauges, "coll2.collection.documents")
+	require.NotContains(t, gauges, "coll9.collection.bytes")
+	require.Equal(t, float64(10), gauges["reporter.last_run_collections"])
+	require.Contains(t, gauges, "reporter.last_run_duration")
+
+	counters := map[string]int64{}
+	for _, c := range scope.Snapshot().Counters() {
+		counters[c.Name()] += c.Value()
+	}
+	require.Equal(t, int64(1), counters["reporter.errors"])
+	require.Equal(t, int64(1), counters["reporter.count_skipped"])
+
+	// the collection above the threshold is not counted again while its size doesn't go down
+	r.report(context.Background())
+	require.Equal(t, 1, source.counted["coll2"])
+	require.Equal(t, 2, source.counted["coll3"])
+
+	source.sizes["coll2"] = 100
+	source.documents["coll2"] = 10
+	r.report(context.Background())
+	require.Equal(t, 2, source.counted["coll2"])
+
+	for _, g := range scope.Snapshot().Gauges() {
+		if g.Name() == "collection.documents" && g.Tags()["collection"] == "coll2" {
+			require.Equal(t, float64(10), g.Value())
+		}
+	}
+}
+
+func TestSizeReporter_Ticks(t *testing.T) {
+	saveNamespace, saveCollection, saveReporter := NamespaceSize, CollectionSize, SizeReporterMetrics
+	t.Cleanup(func() {
+		NamespaceSize, CollectionSize, SizeReporterMetrics = saveNamespace, saveCollection, saveReporter
+	})
+
+	scope := tally.NewTestScope("", nil)
+	NamespaceSize = scope.SubScope("namespace")
+	CollectionSize = scope.SubScope("collection")
+	SizeReporterMetrics = scope.SubScope("reporter")
+
+	coll := CollectionRef{Namespace: "ns1", NamespaceName: "ns1", Db: "db1", Collection: "coll1"}
+	source := &testStatsSource{
+		colls:     []CollectionRef{coll},
+		sizes:     map[string]int64{"coll1": 100},
+		documents: map[string]int64{"coll1": 1},
+		counted:   map[string]int{},
+	}
+
+	ticks := make(chan time.Time)
+	stopped := make(chan struct{})
+	r := NewSizeReporter(&config.SizeReporterConfig{Interval: time.Hour, Concurrency: 1, MaxExactCount: 100}, source)
+	r.newTicker = func(interval time.Duration) (<-chan time.Time, func()) {
+		require.Equal(t, time.Hour, interval)
+		return ticks, func() { close(stopped) }
+	}
+
+	gauge := func(name string) (float64, bool) {
+		for _, g := range scope.Snapshot().Gauges() {
+			if g.Name() == name && g.Tags()["collection"] == "coll1" {
+				return g.Value(), true
+			}
+		}
+		return 0, false
+	}
+	runs := func() int {
+		source.Lock()
+		defer source.Unlock()
+		return source.counted["coll1"]
+	}
+
+	r.Start()
+	// the collections are measured once when the reporter starts
+	require.Eventually(t, func() bool { return runs() == 1 }, time.Second, time.Millisecond)
+	size, ok := gauge("collection.bytes")
+	require.True(t, ok)
+	require.Equal(t, float64(100), size)
+
+	source.Lock()
+	source.sizes["coll1"], source.documents["coll1"] = 300, 3
+	source.Unlock()
+
+	// and again on every tick of the interval
+	ticks <- time.Now()
+	require.Eventually(t, func() bool { return runs() == 2 }, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool {
+		documents, ok := gauge("collection.documents")
+		return ok && documents == 3
+	}, time.Second, time.Millisecond)
+	size, _ = gauge("collection.bytes")
+	require.Equal(t, float64(300), size)
+
+	r.Stop()
+	<-stopped
+}
@@ -0,0 +1,242 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/buger/jsonparser"
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/uber-go/tally"
+	"google.golang.org/grpc/status"
+)
+
+// SlowQueries counts the requests logged as slow, it is only set when the count is enabled.
+var SlowQueries tally.Scope
+
+// slowQueryThresholds are the thresholds of the slow query log, they are initialized from the config and can be
+// adjusted at runtime.
+var slowQueryThresholds struct {
+	sync.RWMutex
+
+	cfg config.SlowQueryConfig
+}
+
+func initializeSlowQueryThresholds(cfg *config.SlowQueryConfig) {
+	SetSlowQueryConfig(*cfg)
+}
+
+// GetSlowQueryConfig returns the thresholds the slow query log is currently using.
+func GetSlowQueryConfig() config.SlowQueryConfig {
+	slowQueryThresholds.RLock()
+	defer slowQueryThresholds.RUnlock()
+
+	cfg := slowQueryThresholds.cfg
+	cfg.MethodThresholds = make(map[string]time.Duration, len(slowQueryThresholds.cfg.MethodThresholds))
+	for method, threshold := range slowQueryThresholds.cfg.MethodThresholds {
+		cfg.MethodThresholds[method] = threshold
+	}
+	return cfg
+}
+
+// SetSlowQueryConfig replaces the thresholds of the slow query log. Whether the slow requests are counted is only read
+// from the config when the metrics are initialized.
+func SetSlowQueryConfig(cfg config.SlowQueryConfig) {
+	methods := make(map[string]time.Duration, len(cfg.MethodThresholds))
+	for method, threshold := range cfg.MethodThresholds {
+		methods[method] = threshold
+	}
+	cfg.MethodThresholds = methods
+
+	slowQueryThresholds.Lock()
+	defer slowQueryThresholds.Unlock()
+	slowQueryThresholds.cfg = cfg
+}
+
+// getSlowQueryThreshold returns the threshold of the method, the override of its full name takes precedence over the
+// override of its name.
+func getSlowQueryThreshold(fullMethod string) (time.Duration, bool) {
+	slowQueryThresholds.RLock()
+	defer slowQueryThresholds.RUnlock()
+
+	cfg := slowQueryThresholds.cfg
+	if !cfg.Enabled {
+		return 0, false
+	}
+	if threshold, ok := cfg.MethodThresholds[fullMethod]; ok {
+		return threshold, true
+	}
+	if threshold, ok := cfg.MethodThresholds[fullMethod[strings.LastIndex(fullMethod, "/")+1:]]; ok {
+		return threshold, true
+	}
+	return cfg.Threshold, true
+}
+
+// requestStats is the breakdown of a request, the time spent in the child spans and the rows it read.
+type requestStats struct {
+	sync.Mutex
+
+	fdbTime      time.Duration
+	searchTime   time.Duration
+	rowsSet      bool
+	rowsScanned  int64
+	rowsReturned int64
+}
+
+func (m *Measurement) root() *Measurement {
+	root := m
+	for root.parent != nil {
+		root = root.parent
+	}
+	return root
+}
+
+// recordChildTime adds the duration of the FoundationDB and search spans to the breakdown of their request.
+func (m *Measurement) recordChildTime() {
+	if m.parent == nil || (m.spanType != FdbSpanType && m.spanType != SearchSpanType) {
+		return
+	}
+	stats := m.root().stats
+	if stats == nil {
+		return
+	}
+
+	stats.Lock()
+	defer stats.Unlock()
+	if m.spanType == FdbSpanType {
+		stats.fdbTime += m.stoppedAt.Sub(m.startedAt)
+	} else {
+		stats.searchTime += m.stoppedAt.Sub(m.startedAt)
+	}
+}
+
+// SetRowCounts records the number of rows the request read from the storage and the number of rows it returned or
+// modified. A retried transaction overrides the counts of its previous attempt.
+func SetRowCounts(ctx context.Context, scanned int64, returned int64) {
+	measurement, exists := MeasurementFromContext(ctx)
+	if !exists || measurement == nil {
+		return
+	}
+	stats := measurement.root().stats
+	if stats == nil {
+		return
+	}
+
+	stats.Lock()
+	defer stats.Unlock()
+	stats.rowsSet = true
+	stats.rowsScanned, stats.rowsReturned = scanned, returned
+}
+
+// LogSlowQuery logs the request if it took longer than the threshold of its method. It must be called once the
+// measurement of the request is finished.
+func (m *Measurement) LogSlowQuery(req interface{}, err error) {
+	if !m.started || !m.stopped {
+		return
+	}
+	threshold, enabled := getSlowQueryThreshold(m.resourceName)
+	duration := m.stoppedAt.Sub(m.startedAt)
+	if !enabled || duration < threshold {
+		return
+	}
+
+	event := log.Warn().
+		Str("method", m.resourceName).
+		Dur("duration", duration).
+		Dur("threshold", threshold).
+		Str("namespace", m.tags["tigris_tenant"]).
+		Str("db", m.tags["db"]).
+		Str("collection", m.tags["collection"])
+	if r, ok := req.(api.RequestWithFilter); ok && len(r.GetFilter()) > 0 {
+		event.RawJSON("filter", filterShape(r.GetFilter()))
+	}
+	if stats := m.stats; stats != nil {
+		stats.Lock()
+		event.Dur("fdb_time", stats.fdbTime).Dur("search_time", stats.searchTime)
+		if stats.rowsSet {
+			event.Int64("rows_scanned", stats.rowsScanned).Int64("rows_returned", stats.rowsReturned)
+		}
+		stats.Unlock()
+	}
+	if err != nil {
+		event.Str("grpc_code", status.Code(err).String())
+	}
+	event.Msg("slow query")
+
+	if SlowQueries != nil {
+		SlowQueries.Tagged(m.GetRequestOkTags()).Counter("count").Inc(1)
+	}
+}
+
+// filterShape returns the filter with its values redacted, only the field names and the operators are kept. The arrays
+// of values, like the values of "$in", are redacted as a whole.
+func filterShape(filter []byte) []byte {
+	value, dataType, _, err := jsonparser.Get(filter)
+	if err != nil {
+		return []byte(`"?"`)
+	}
+
+	var buf bytes.Buffer
+	writeShape(&buf, value, dataType)
+	return buf.Bytes()
+}
+
+func writeShape(buf *bytes.Buffer, value []byte, dataType jsonparser.ValueType) {
+	switch dataType {
+	case jsonparser.Object:
+		buf.WriteByte('{')
+		first := true
+		_ = jsonparser.ObjectEach(value, func(key []byte, nested []byte, nestedType jsonparser.ValueType, _ int) error {
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			buf.WriteByte('"')
+			buf.Write(key)
+			buf.WriteString(`":`)
+			writeShape(buf, nested, nestedType)
+			return nil
+		})
+		buf.WriteByte('}')
+	case jsonparser.Array:
+		var items [][]byte
+		var types []jsonparser.ValueType
+		_, _ = jsonparser.ArrayEach(value, func(item []byte, itemType jsonparser.ValueType, _ int, _ error) {
+			items, types = append(items, item), append(types, itemType)
+		})
+		for _, itemType := range types {
+			if itemType != jsonparser.Object && itemType != jsonparser.Array {
+				buf.WriteString(`"?"`)
+				return
+			}
+		}
+		buf.WriteByte('[')
+		for i := range items {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeShape(buf, items[i], types[i])
+		}
+		buf.WriteByte(']')
+	default:
+		buf.WriteString(`"?"`)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/uber-go/tally"
+)
+
+func TestFilterShape(t *testing.T) {
+	cases := []struct {
+		filter string
+		shape  string
+	}{
+		{`{"name": "alice"}`, `{"name":"?"}`},
+		{`{"age": {"$gt": 10, "$lt": 20}}`, `{"age":{"$gt":"?","$lt":"?"}}`},
+		{
+			`{"$or": [{"a": 1}, {"$and": [{"b": true}, {"c.d": null}]}]}`,
+			`{"$or":[{"a":"?"},{"$and":[{"b":"?"},{"c.d":"?"}]}]}`,
+		},
+		{`{"tags": {"$in": ["a", "b", "c"]}}`, `{"tags":{"$in":"?"}}`},
+		{`{"obj": {"nested": {"x": "secret"}}}`, `{"obj":{"nested":{"x":"?"}}}`},
+		{`{}`, `{}`},
+		{`not json`, `"?"`},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.shape, string(filterShape([]byte(c.filter))), c.filter)
+	}
+}
+
+func TestGetSlowQueryThreshold(t *testing.T) {
+	save := GetSlowQueryConfig()
+	t.Cleanup(func() { SetSlowQueryConfig(save) })
+
+	SetSlowQueryConfig(config.SlowQueryConfig{
+		Enabled:   true,
+		Threshold: time.Second,
+		MethodThresholds: map[string]time.Duration{
+			"Read":                         5 * time.Second,
+			"/tigrisdata.v1.Tigris/Search": 3 * time.Second,
+			"Search":                       4 * time.Second,
+		},
+	})
+
+	for method, exp := range map[string]time.Duration{
+		"/tigrisdata.v1.Tigris/Update": time.Second,
+		"/tigrisdata.v1.Tigris/Read":   5 * time.Second,
+		"/tigrisdata.v1.Tigris/Search": 3 * time.Second,
+	} {
+		threshold, enabled := getSlowQueryThreshold(method)
+		require.True(t, enabled)
+		require.Equal(t, exp, threshold, method)
+	}
+
+	SetSlowQueryConfig(config.SlowQueryConfig{Threshold: time.Second})
+	_, enabled := getSlowQueryThreshold("/tigrisdata.v1.Tigris/Update")
+	require.False(t, enabled)
+}
+
+func TestLogSlowQuery(t *testing.T) {
+	saveCfg, saveScope := GetSlowQueryConfig(), SlowQueries
+	saveTracing, saveMetrics := config.DefaultConfig.Tracing.Enabled, config.DefaultConfig.Metrics.Enabled
+	t.Cleanup(func() {
+		SetSlowQueryConfig(saveCfg)
+		SlowQueries = saveScope
+		config.DefaultConfig.Tracing.Enabled, config.DefaultConfig.Metrics.Enabled = saveTracing, saveMetrics
+	})
+	config.DefaultConfig.Tracing.Enabled = true
+	config.DefaultConfig.Metrics.Enabled = true
+
+	scope := tally.NewTestScope("", nil)
+	SlowQueries = scope
+	SetSlowQueryConfig(config.SlowQueryConfig{
+		Enabled:          true,
+		Threshold:        time.Hour,
+		MethodThresholds: map[string]time.Duration{"Update": 0},
+	})
+
+	run := func(method string) *Measurement {
+		m := NewMeasurement("tigris.test", method, GrpcSpanType, map[string]string{
+			"tigris_tenant": "ns1", "db": "db1", "collection": "coll1",
+		})
+		ctx := m.StartTracing(context.Background(), false)
+
+		fdb := NewMeasurement(KvTracingServiceName, "ReadRange", FdbSpanType, GetFdbBaseTags("ReadRange"))
+		ctx = fdb.StartTracing(ctx, true)
+		time.Sleep(time.Millisecond)
+		ctx = fdb.FinishTracing(ctx)
+		SetRowCounts(ctx, 10, 2)
+
+		_ = m.FinishTracing(ctx)
+		m.LogSlowQuery(&api.UpdateRequest{Db: "db1", Collection: "coll1", Filter: []byte(`{"a": 1}`)}, nil)
+		return m
+	}
+
+	m := run("/tigrisdata.v1.Tigris/Update")
+	require.GreaterOrEqual(t, m.stats.fdbTime, time.Millisecond)
+	require.Zero(t, m.stats.searchTime)
+	require.True(t, m.stats.rowsSet)
+	require.Equal(t, int64(10), m.stats.rowsScanned)
+	require.Equal(t, int64(2), m.stats.rowsReturned)
+	require.Len(t, scope.Snapshot().Counters(), 1)
+
+	// below the threshold, the request isn't counted
+	run("/tigrisdata.v1.Tigris/Delete")
+	var total int64
+	for _, c := range scope.Snapshot().Counters() {
+		require.Equal(t, "count", c.Name())
+		total += c.Value()
+	}
+	require.Equal(t, int64(1), total)
+}
@@ -0,0 +1,157 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/uber-go/tally"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	StreamOk               = "ok"
+	StreamCanceled         = "canceled"
+	StreamDeadlineExceeded = "deadline_exceeded"
+	StreamError            = "error"
+)
+
+var (
+	// StreamMetrics are the message level metrics of the streams, set along with the network metrics.
+	StreamMetrics tally.Scope
+
+	// messageSizeBuckets goes from 64 bytes to 256MB.
+	messageSizeBuckets = tally.MustMakeExponentialValueBuckets(64, 4, 12)
+	// streamMessagesBuckets goes from 1 to 4M messages.
+	streamMessagesBuckets = tally.MustMakeExponentialValueBuckets(1, 4, 12)
+)
+
+// openStreams is the number of streams currently open per method.
+var openStreams struct {
+	sync.Mutex
+
+	count map[string]int64
+}
+
+func initializeStreamScopes() {
+	StreamMetrics = NetworkMetrics.SubScope("stream")
+}
+
+// StreamStats tracks the messages of a stream. The size of every message is recorded as it is sent or received, and
+// the number of messages and the bytes of the stream are recorded when it finishes.
+type StreamStats struct {
+	method string
+
+	sentMessages     int64
+	sentBytes        int64
+	receivedMessages int64
+	receivedBytes    int64
+	finished         int32
+}
+
+// NewStreamStats returns the stats of a new stream of the method and counts it as open until it finishes.
+func NewStreamStats(fullMethod string) *StreamStats {
+	s := &StreamStats{method: fullMethod[strings.LastIndex(fullMethod, "/")+1:]}
+	s.updateOpenStreams(1)
+	return s
+}
+
+func (s *StreamStats) updateOpenStreams(delta int64) {
+	if StreamMetrics == nil {
+		return
+	}
+
+	openStreams.Lock()
+	if openStreams.count == nil {
+		openStreams.count = make(map[string]int64)
+	}
+	openStreams.count[s.method] += delta
+	open := openStreams.count[s.method]
+	openStreams.Unlock()
+
+	StreamMetrics.Tagged(map[string]string{"grpc_method": s.method}).Gauge("open").Update(float64(open))
+}
+
+func (s *StreamStats) messageTags(direction string) map[string]string {
+	return map[string]string{
+		"grpc_method": s.method,
+		"direction":   direction,
+	}
+}
+
+// MessageSent records a message sent on the stream.
+func (s *StreamStats) MessageSent(size int) {
+	atomic.AddInt64(&s.sentMessages, 1)
+	atomic.AddInt64(&s.sentBytes, int64(size))
+	if StreamMetrics != nil {
+		StreamMetrics.Tagged(s.messageTags("sent")).Histogram("message_size", messageSizeBuckets).RecordValue(float64(size))
+	}
+}
+
+// MessageReceived records a message received on the stream.
+func (s *StreamStats) MessageReceived(size int) {
+	atomic.AddInt64(&s.receivedMessages, 1)
+	atomic.AddInt64(&s.receivedBytes, int64(size))
+	if StreamMetrics != nil {
+		StreamMetrics.Tagged(s.messageTags("received")).Histogram("message_size", messageSizeBuckets).RecordValue(float64(size))
+	}
+}
+
+// Finish records the number of messages of the stream tagged with the reason it terminated, and counts it as closed.
+// Only the first call is recorded.
+func (s *StreamStats) Finish(reason string) {
+	if !atomic.CompareAndSwapInt32(&s.finished, 0, 1) {
+		return
+	}
+	s.updateOpenStreams(-1)
+	if StreamMetrics == nil {
+		return
+	}
+
+	for _, d := range []struct {
+		direction string
+		messages  int64
+		bytes     int64
+	}{
+		{"sent", atomic.LoadInt64(&s.sentMessages), atomic.LoadInt64(&s.sentBytes)},
+		{"received", atomic.LoadInt64(&s.receivedMessages), atomic.LoadInt64(&s.receivedBytes)},
+	} {
+		tags := s.messageTags(d.direction)
+		tags["reason"] = reason
+		scope := StreamMetrics.Tagged(tags)
+		scope.Histogram("messages", streamMessagesBuckets).RecordValue(float64(d.messages))
+		scope.Counter("messages_total").Inc(d.messages)
+		scope.Counter("bytes_total").Inc(d.bytes)
+	}
+}
+
+// StreamTerminationReason returns the reason the stream terminated, a stream can be aborted by the client or by its
+// deadline.
+func StreamTerminationReason(ctx context.Context, err error) string {
+	switch {
+	case err == nil:
+		return StreamOk
+	case ctx.Err() == context.Canceled || status.Code(err) == codes.Canceled:
+		return StreamCanceled
+	case ctx.Err() == context.DeadlineExceeded || status.Code(err) == codes.DeadlineExceeded:
+		return StreamDeadlineExceeded
+	default:
+		return StreamError
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStreamStats(t *testing.T) {
+	prev := StreamMetrics
+	defer func() { StreamMetrics = prev }()
+	testScope := tally.NewTestScope("", nil)
+	StreamMetrics = testScope
+
+	s1 := NewStreamStats("/tigrisdata.v1.Tigris/Read")
+	s2 := NewStreamStats("/tigrisdata.v1.Tigris/Read")
+	require.Equal(t, float64(2), testScope.Snapshot().Gauges()["open+grpc_method=Read"].Value())
+
+	s1.MessageReceived(10)
+	s1.MessageSent(100)
+	s1.MessageSent(1000)
+	s1.Finish(StreamOk)
+	// only the first call is recorded
+	s1.Finish(StreamError)
+	require.Equal(t, float64(1), testScope.Snapshot().Gauges()["open+grpc_method=Read"].Value())
+
+	s2.MessageSent(50)
+	s2.Finish(StreamCanceled)
+
+	snapshot := testScope.Snapshot()
+	require.Equal(t, float64(0), snapshot.Gauges()["open+grpc_method=Read"].Value())
+
+	counters := snapshot.Counters()
+	require.Equal(t, int64(2), counters["messages_total+direction=sent,grpc_method=Read,reason=ok"].Value())
+	require.Equal(t, int64(1100), counters["bytes_total+direction=sent,grpc_method=Read,reason=ok"].Value())
+	require.Equal(t, int64(1), counters["messages_total+direction=received,grpc_method=Read,reason=ok"].Value())
+	require.Equal(t, int64(1), counters["messages_total+direction=sent,grpc_method=Read,reason=canceled"].Value())
+	require.Equal(t, int64(0), counters["messages_total+direction=received,grpc_method=Read,reason=canceled"].Value())
+	require.NotContains(t, counters, "messages_total+direction=sent,grpc_method=Read,reason=error")
+
+	histograms := snapshot.Histograms()
+	sizes := histograms["message_size+direction=sent,grpc_method=Read"].Values()
+	require.Equal(t, int64(1), sizes[64])
+	require.Equal(t, int64(1), sizes[256])
+	require.Equal(t, int64(1), sizes[1024])
+	received := histograms["message_size+direction=received,grpc_method=Read"].Values()
+	require.Equal(t, int64(1), received[64])
+
+	messages := histograms["messages+direction=sent,grpc_method=Read,reason=ok"].Values()
+	require.Equal(t, int64(1), messages[4])
+}
+
+func TestStreamTerminationReason(t *testing.T) {
+	ctx := context.Background()
+	require.Equal(t, StreamOk, StreamTerminationReason(ctx, nil))
+	require.Equal(t, StreamError, StreamTerminationReason(ctx, fmt.Errorf("failed")))
+	require.Equal(t, StreamCanceled, StreamTerminationReason(ctx, status.Error(codes.Canceled, "canceled")))
+	require.Equal(t, StreamDeadlineExceeded, StreamTerminationReason(ctx, status.Error(codes.DeadlineExceeded, "deadline")))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	require.Equal(t, StreamCanceled, StreamTerminationReason(canceled, fmt.Errorf("failed")))
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/tigrisdata/tigris/server/defaults"
 	"github.com/tigrisdata/tigris/util"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
 )
 
 func mergeTags(tagSets ...map[string]string) map[string]string {
@@ -59,6 +60,25 @@ func getTigrisError(err error) (string, bool) {
 	return "", false
 }
 
+// getErrorCodeTags returns the api.Code of the error as a tag, so that the client errors can be told apart from the
+// server errors. The errors that are neither a TigrisError nor a gRPC status error are reported as UNKNOWN.
+func getErrorCodeTags(err error) map[string]string {
+	var code api.Code
+	var tigrisErr *api.TigrisError
+	switch {
+	case err == nil:
+		code = api.Code_OK
+	case errors.As(err, &tigrisErr):
+		code = tigrisErr.Code
+	default:
+		code = api.ToTigrisCode(status.Code(err))
+	}
+
+	return map[string]string{
+		"error_code": api.CodeToString(code),
+	}
+}
+
 func getTagsForError(err error, source string) map[string]string {
 	// The source parameter is only considered when the source cannot be determined from the error itself
 	value, isFdbError := getFdbError(err)
@@ -174,7 +194,7 @@ func standardizeTags(tags map[string]string, stdKeys []string) map[string]string
 			delete(res, k)
 		}
 	}
-	return res
+	return limitTagCardinality(res)
 }
 
 func getGrpcTagsFromContext(ctx context.Context) map[string]string {
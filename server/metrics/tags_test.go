@@ -15,11 +15,15 @@
 package metrics
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/stretchr/testify/assert"
 	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestTagsHelpers(t *testing.T) {
@@ -62,6 +66,22 @@ func TestTagsHelpers(t *testing.T) {
 		assert.Equal(t, "NOT_FOUND", tigrisErrTags["error_value"])
 	})
 
+	t.Run("Test getErrorCodeTags", func(t *testing.T) {
+		assert.Equal(t, map[string]string{"error_code": "OK"}, getErrorCodeTags(nil))
+		assert.Equal(t, map[string]string{"error_code": "ALREADY_EXISTS"}, getErrorCodeTags(errors.AlreadyExists("exists")))
+		assert.Equal(t, map[string]string{"error_code": "NOT_FOUND"}, getErrorCodeTags(fmt.Errorf("wrapped: %w", errors.NotFound("missing"))))
+		assert.Equal(t, map[string]string{"error_code": "DEADLINE_EXCEEDED"}, getErrorCodeTags(status.Error(codes.DeadlineExceeded, "timeout")))
+		assert.Equal(t, map[string]string{"error_code": "UNKNOWN"}, getErrorCodeTags(fdb.Error{Code: 1}))
+		assert.Equal(t, map[string]string{"error_code": "UNKNOWN"}, getErrorCodeTags(fmt.Errorf("generic")))
+	})
+
+	t.Run("Test request error code tag", func(t *testing.T) {
+		measurement := NewMeasurement("test.service.name", "TestResource", "rpc", GetGlobalTags())
+		assert.Equal(t, "INVALID_ARGUMENT", measurement.GetRequestErrorTags(errors.InvalidArgument("invalid"))["error_code"])
+		assert.Equal(t, "INTERNAL", measurement.GetRequestErrorTags(errors.Internal("internal"))["error_code"])
+		assert.Equal(t, "UNKNOWN", measurement.GetRequestErrorTags(fmt.Errorf("generic"))["error_code"])
+	})
+
 	t.Run("Test getDbTags", func(t *testing.T) {
 		assert.Equal(t, map[string]string{"db": "foobar"}, getDbTags("foobar"))
 	})
@@ -0,0 +1,143 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/uber-go/tally"
+	promreporter "github.com/uber-go/tally/prometheus"
+)
+
+// The buckets of the duration histograms of the metric groups, they are set when the scopes are created.
+var (
+	requestsHistogramBuckets tally.Buckets = tally.DefaultBuckets
+	fdbHistogramBuckets      tally.Buckets = tally.DefaultBuckets
+	searchHistogramBuckets   tally.Buckets = tally.DefaultBuckets
+	sessionHistogramBuckets  tally.Buckets = tally.DefaultBuckets
+)
+
+type timerGroup struct {
+	name  string
+	timer *config.TimerConfig
+}
+
+// timerGroups returns the metric groups with a configurable timer, the name of a group is the name of its scope.
+func timerGroups(cfg *config.MetricsConfig) []timerGroup {
+	return []timerGroup{
+		{name: "requests", timer: &cfg.Requests.Timer},
+		{name: "fdb", timer: &cfg.Fdb.Timer},
+		{name: "search", timer: &cfg.Search.Timer},
+		{name: "session", timer: &cfg.Session.Timer},
+	}
+}
+
+// ValidateTimerConfig checks the quantiles and the histogram buckets of the metrics config.
+func ValidateTimerConfig(cfg *config.MetricsConfig) error {
+	if err := validateQuantiles("metrics", cfg.TimerQuantiles); err != nil {
+		return err
+	}
+	for _, group := range timerGroups(cfg) {
+		if err := validateQuantiles(group.name, group.timer.Quantiles); err != nil {
+			return err
+		}
+		for i, bucket := range group.timer.HistogramBuckets {
+			if bucket <= 0 {
+				return fmt.Errorf("histogram bucket %v of the %s metrics must be positive", bucket, group.name)
+			}
+			if i > 0 && bucket <= group.timer.HistogramBuckets[i-1] {
+				return fmt.Errorf("histogram buckets of the %s metrics must be strictly increasing, %v follows %v",
+					group.name, bucket, group.timer.HistogramBuckets[i-1])
+			}
+		}
+	}
+	return nil
+}
+
+func validateQuantiles(group string, quantiles []float64) error {
+	for _, q := range quantiles {
+		if q <= 0 || q >= 1 {
+			return fmt.Errorf("quantile %v of the %s metrics must be between 0 and 1", q, group)
+		}
+	}
+	return nil
+}
+
+// initializeHistogramBuckets sets the buckets of the duration histograms from the config, the default buckets are
+// kept when the config is not valid.
+func initializeHistogramBuckets(cfg *config.MetricsConfig) {
+	requestsHistogramBuckets, fdbHistogramBuckets = tally.DefaultBuckets, tally.DefaultBuckets
+	searchHistogramBuckets, sessionHistogramBuckets = tally.DefaultBuckets, tally.DefaultBuckets
+	if err := ValidateTimerConfig(cfg); err != nil {
+		log.Error().Err(err).Msg("Invalid timer config, using the default histogram buckets")
+		return
+	}
+
+	buckets := func(timer *config.TimerConfig) tally.Buckets {
+		if len(timer.HistogramBuckets) == 0 {
+			return tally.DefaultBuckets
+		}
+		return tally.DurationBuckets(timer.HistogramBuckets)
+	}
+	requestsHistogramBuckets = buckets(&cfg.Requests.Timer)
+	fdbHistogramBuckets = buckets(&cfg.Fdb.Timer)
+	searchHistogramBuckets = buckets(&cfg.Search.Timer)
+	sessionHistogramBuckets = buckets(&cfg.Session.Timer)
+}
+
+// groupTimerReporter allocates the timers of the metric groups with their own quantiles with the reporter of the group.
+type groupTimerReporter struct {
+	promreporter.Reporter
+
+	// groups are the reporters of the groups keyed by the prefix of the names of their metrics
+	groups map[string]promreporter.Reporter
+}
+
+func (r *groupTimerReporter) AllocateTimer(name string, tags map[string]string) tally.CachedTimer {
+	for prefix, reporter := range r.groups {
+		if strings.HasPrefix(name, prefix) {
+			return reporter.AllocateTimer(name, tags)
+		}
+	}
+	return r.Reporter.AllocateTimer(name, tags)
+}
+
+// newReporter returns the prometheus reporter of the metrics, the timers of the groups configured with their own
+// quantiles share the registry of the reporter.
+func newReporter(cfg *config.MetricsConfig, opts promreporter.Options) promreporter.Reporter {
+	opts.DefaultSummaryObjectives = getTimerSummaryObjectives(cfg.TimerQuantiles)
+	reporter := promreporter.NewReporter(opts)
+	if ValidateTimerConfig(cfg) != nil {
+		return reporter
+	}
+
+	groups := make(map[string]promreporter.Reporter)
+	for _, group := range timerGroups(cfg) {
+		if len(group.timer.Quantiles) == 0 {
+			continue
+		}
+		groupOpts := opts
+		groupOpts.DefaultSummaryObjectives = getTimerSummaryObjectives(group.timer.Quantiles)
+		groups[group.name+promreporter.DefaultSeparator] = promreporter.NewReporter(groupOpts)
+	}
+	if len(groups) == 0 {
+		return reporter
+	}
+
+	return &groupTimerReporter{Reporter: reporter, groups: groups}
+}
@@ -0,0 +1,102 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	prom "github.com/m3db/prometheus_client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/server/config"
+	promreporter "github.com/uber-go/tally/prometheus"
+)
+
+func TestValidateTimerConfig(t *testing.T) {
+	cfg := config.DefaultConfig.Metrics
+	require.NoError(t, ValidateTimerConfig(&cfg))
+
+	cfg.Requests.Timer.HistogramBuckets = []time.Duration{time.Millisecond, 10 * time.Millisecond, time.Second}
+	cfg.Fdb.Timer.Quantiles = []float64{0.99, 0.999}
+	require.NoError(t, ValidateTimerConfig(&cfg))
+
+	cfg.Requests.Timer.HistogramBuckets = []time.Duration{time.Millisecond, time.Millisecond}
+	require.EqualError(t, ValidateTimerConfig(&cfg), "histogram buckets of the requests metrics must be strictly increasing, 1ms follows 1ms")
+
+	cfg.Requests.Timer.HistogramBuckets = []time.Duration{time.Second, time.Millisecond}
+	require.EqualError(t, ValidateTimerConfig(&cfg), "histogram buckets of the requests metrics must be strictly increasing, 1ms follows 1s")
+
+	cfg.Requests.Timer.HistogramBuckets = []time.Duration{0, time.Millisecond}
+	require.EqualError(t, ValidateTimerConfig(&cfg), "histogram bucket 0s of the requests metrics must be positive")
+
+	cfg.Requests.Timer.HistogramBuckets = nil
+	cfg.Session.Timer.Quantiles = []float64{1}
+	require.EqualError(t, ValidateTimerConfig(&cfg), "quantile 1 of the session metrics must be between 0 and 1")
+}
+
+func TestGetTimerSummaryObjectives(t *testing.T) {
+	require.Equal(t, map[float64]float64{0.5: 0.01, 0.99: 0.001, 0.999: 0.0001}, getTimerSummaryObjectives([]float64{0.5, 0.99, 0.999}))
+	require.InDelta(t, 0.00001, getTimerSummaryObjectives([]float64{0.9999})[0.9999], 1e-12)
+	require.Empty(t, getTimerSummaryObjectives(nil))
+}
+
+func TestConfiguredTimers(t *testing.T) {
+	defaultCfg := config.DefaultConfig.Metrics
+	defer func() {
+		config.DefaultConfig.Metrics = defaultCfg
+		InitializeMetrics()
+	}()
+
+	config.DefaultConfig.Metrics.Enabled = true
+	config.DefaultConfig.Metrics.Requests.Timer = config.TimerConfig{
+		TimerEnabled:     true,
+		HistogramEnabled: true,
+		HistogramBuckets: []time.Duration{250 * time.Millisecond, 500 * time.Millisecond, 750 * time.Millisecond},
+		Quantiles:        []float64{0.999},
+	}
+	config.DefaultConfig.Metrics.Fdb.Timer = config.TimerConfig{TimerEnabled: true}
+
+	registry := prom.NewRegistry()
+	closer := initializeMetrics(promreporter.Options{Registerer: registry})
+
+	measurement := NewMeasurement("test.service.name", "TestResource", "rpc", GetGlobalTags())
+	measurement.StartTracing(context.Background(), true)
+	measurement.FinishTracing(context.Background())
+	measurement.RecordDuration(RequestsRespTime, measurement.GetRequestOkTags())
+
+	fdbMeasurement := NewMeasurement("fdb", "Get", FdbSpanType, GetGlobalTags())
+	fdbMeasurement.StartTracing(context.Background(), true)
+	fdbMeasurement.FinishTracing(context.Background())
+	fdbMeasurement.RecordDuration(FdbRespTime, fdbMeasurement.GetFdbOkTags())
+	closer()
+
+	rec := httptest.NewRecorder()
+	Reporter.HTTPHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	output := rec.Body.String()
+
+	for _, bucket := range []string{`le="0.25"`, `le="0.5"`, `le="0.75"`, `le="\+Inf"`} {
+		require.Regexp(t, `requests_response_histogram_bucket\{[^}]*`+bucket, output)
+	}
+	require.NotRegexp(t, `requests_response_histogram_bucket\{[^}]*le="0.001"`, output)
+	require.Regexp(t, `requests_response_time\{[^}]*quantile="0.999"`, output)
+	require.NotRegexp(t, `requests_response_time\{[^}]*quantile="0.5"`, output)
+	// the groups without their own quantiles use the quantiles of the metrics config
+	require.Regexp(t, `fdb_response_time\{[^}]*quantile="0.95"`, output)
+	require.NotRegexp(t, `fdb_response_time\{[^}]*quantile="0.999"`, output)
+}
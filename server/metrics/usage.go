@@ -0,0 +1,175 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/uber-go/tally"
+)
+
+// UsageMetrics counts the work done for the namespaces, it is only set when the usage metrics are enabled.
+var UsageMetrics tally.Scope
+
+// Usage is the work done on behalf of a namespace.
+type Usage struct {
+	BytesRead        int64 `json:"bytes_read"`
+	BytesWritten     int64 `json:"bytes_written"`
+	DocumentsRead    int64 `json:"documents_read"`
+	DocumentsWritten int64 `json:"documents_written"`
+	SearchQueries    int64 `json:"search_queries"`
+}
+
+func (u *Usage) add(other Usage) {
+	u.BytesRead += other.BytesRead
+	u.BytesWritten += other.BytesWritten
+	u.DocumentsRead += other.DocumentsRead
+	u.DocumentsWritten += other.DocumentsWritten
+	u.SearchQueries += other.SearchQueries
+}
+
+// NamespaceUsage is the total usage of a namespace since the server started.
+type NamespaceUsage struct {
+	Namespace     string `json:"namespace"`
+	NamespaceName string `json:"namespace_name"`
+	Usage
+}
+
+// usageTotals keeps the exact totals of the namespaces, they are not subject to the cardinality limits of the tags.
+var usageTotals struct {
+	sync.Mutex
+
+	namespaces map[string]*NamespaceUsage
+}
+
+// RecordUsage adds the usage to the counters and to the totals of the namespace.
+func RecordUsage(namespace string, namespaceName string, usage Usage) {
+	if usage == (Usage{}) {
+		return
+	}
+
+	usageTotals.Lock()
+	if usageTotals.namespaces == nil {
+		usageTotals.namespaces = make(map[string]*NamespaceUsage)
+	}
+	total, ok := usageTotals.namespaces[namespace]
+	if !ok {
+		total = &NamespaceUsage{Namespace: namespace}
+		usageTotals.namespaces[namespace] = total
+	}
+	total.NamespaceName = namespaceName
+	total.add(usage)
+	usageTotals.Unlock()
+
+	if UsageMetrics == nil {
+		return
+	}
+	scope := UsageMetrics.Tagged(getUsageTags(namespace, namespaceName))
+	for name, value := range map[string]int64{
+		"bytes_read":        usage.BytesRead,
+		"bytes_written":     usage.BytesWritten,
+		"documents_read":    usage.DocumentsRead,
+		"documents_written": usage.DocumentsWritten,
+		"search_queries":    usage.SearchQueries,
+	} {
+		if value > 0 {
+			scope.Counter(name).Inc(value)
+		}
+	}
+}
+
+// UsageSnapshot returns the totals of the namespaces, sorted by namespace.
+func UsageSnapshot() []NamespaceUsage {
+	usageTotals.Lock()
+	defer usageTotals.Unlock()
+
+	snapshot := make([]NamespaceUsage, 0, len(usageTotals.namespaces))
+	for _, total := range usageTotals.namespaces {
+		snapshot = append(snapshot, *total)
+	}
+	sort.Slice(snapshot, func(i, j int) bool {
+		return snapshot[i].Namespace < snapshot[j].Namespace
+	})
+	return snapshot
+}
+
+func getUsageTags(namespace string, namespaceName string) map[string]string {
+	return limitTagCardinality(map[string]string{
+		"tigris_tenant":      namespace,
+		"tigris_tenant_name": GetTenantNameTagValue(namespace, namespaceName),
+	})
+}
+
+type usageCtxKey struct{}
+
+// pendingUsage is the usage of a transaction, it is only recorded once the transaction is committed so that the
+// attempts of a request that are retried are not metered.
+type pendingUsage struct {
+	sync.Mutex
+
+	namespace     string
+	namespaceName string
+	usage         Usage
+}
+
+// WithPendingUsage attaches the pending usage of a transaction started by the namespace to the context. The reads
+// and the writes done in the transaction are attributed to this namespace.
+func WithPendingUsage(ctx context.Context, namespace string, namespaceName string) context.Context {
+	return context.WithValue(ctx, usageCtxKey{}, &pendingUsage{namespace: namespace, namespaceName: namespaceName})
+}
+
+// RecordWriteUsage records the documents written by a request. The writes done in a transaction with a pending usage
+// are recorded when the transaction is committed.
+func RecordWriteUsage(ctx context.Context, namespace string, namespaceName string, documents int64, bytes int64) {
+	RecordTxUsage(ctx, namespace, namespaceName, Usage{DocumentsWritten: documents, BytesWritten: bytes})
+}
+
+// RecordTxUsage records the usage of a request. The usage of a transaction with a pending usage is recorded when the
+// transaction is committed.
+func RecordTxUsage(ctx context.Context, namespace string, namespaceName string, usage Usage) {
+	if pending, ok := ctx.Value(usageCtxKey{}).(*pendingUsage); ok {
+		pending.Lock()
+		pending.usage.add(usage)
+		pending.Unlock()
+		return
+	}
+	RecordUsage(namespace, namespaceName, usage)
+}
+
+// CommitPendingUsage records the usage of the committed transaction of the context.
+func CommitPendingUsage(ctx context.Context) {
+	pending, ok := ctx.Value(usageCtxKey{}).(*pendingUsage)
+	if !ok {
+		return
+	}
+
+	pending.Lock()
+	usage := pending.usage
+	pending.usage = Usage{}
+	pending.Unlock()
+
+	RecordUsage(pending.namespace, pending.namespaceName, usage)
+}
+
+// DiscardPendingUsage drops the usage of the rolled back transaction of the context.
+func DiscardPendingUsage(ctx context.Context) {
+	if pending, ok := ctx.Value(usageCtxKey{}).(*pendingUsage); ok {
+		pending.Lock()
+		pending.usage = Usage{}
+		pending.Unlock()
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/uber-go/tally"
+)
+
+func TestUsage(t *testing.T) {
+	defer func() {
+		UsageMetrics = nil
+		usageTotals.namespaces = nil
+		initializeTagCardinality(&config.DefaultConfig.Metrics.TagCardinality)
+	}()
+
+	testScope := tally.NewTestScope("", nil)
+	UsageMetrics = testScope
+	usageTotals.namespaces = nil
+	initializeTagCardinality(&config.TagCardinalityConfig{
+		Enabled: true,
+		Limits:  map[string]int{"tigris_tenant": 1},
+	})
+
+	t.Run("reads", func(t *testing.T) {
+		RecordUsage("ns1", "name1", Usage{DocumentsRead: 2, BytesRead: 100})
+		RecordUsage("ns1", "name1", Usage{SearchQueries: 1, DocumentsRead: 1, BytesRead: 10})
+		RecordUsage("ns1", "name1", Usage{})
+
+		counters := testScope.Snapshot().Counters()
+		require.Equal(t, int64(3), counters["documents_read+tigris_tenant=ns1,tigris_tenant_name=name1_ns1"].Value())
+		require.Equal(t, int64(110), counters["bytes_read+tigris_tenant=ns1,tigris_tenant_name=name1_ns1"].Value())
+		require.Equal(t, int64(1), counters["search_queries+tigris_tenant=ns1,tigris_tenant_name=name1_ns1"].Value())
+	})
+
+	t.Run("writes are recorded on commit", func(t *testing.T) {
+		ctx := WithPendingUsage(context.Background(), "ns2", "name2")
+		// the writes are attributed to the namespace that started the transaction
+		RecordWriteUsage(ctx, "ns3", "name3", 2, 50)
+		RecordWriteUsage(ctx, "ns3", "name3", 1, 25)
+		require.Len(t, UsageSnapshot(), 1)
+
+		CommitPendingUsage(ctx)
+		// a second commit doesn't record the writes again
+		CommitPendingUsage(ctx)
+
+		snapshot := UsageSnapshot()
+		require.Len(t, snapshot, 2)
+		require.Equal(t, NamespaceUsage{
+			Namespace:     "ns2",
+			NamespaceName: "name2",
+			Usage:         Usage{DocumentsWritten: 3, BytesWritten: 75},
+		}, snapshot[1])
+
+		// the namespace is beyond the cardinality limit of the counters, its totals are exact
+		counters := testScope.Snapshot().Counters()
+		require.Equal(t, int64(3), counters["documents_written+tigris_tenant=__other__,tigris_tenant_name=name2_ns2"].Value())
+	})
+
+	t.Run("writes are dropped on rollback", func(t *testing.T) {
+		ctx := WithPendingUsage(context.Background(), "ns1", "name1")
+		RecordWriteUsage(ctx, "ns1", "name1", 5, 500)
+		DiscardPendingUsage(ctx)
+		CommitPendingUsage(ctx)
+
+		// without a transaction the writes are recorded right away
+		RecordWriteUsage(context.Background(), "ns1", "name1", 1, 20)
+
+		snapshot := UsageSnapshot()
+		require.Equal(t, NamespaceUsage{
+			Namespace:     "ns1",
+			NamespaceName: "name1",
+			Usage:         Usage{DocumentsRead: 3, BytesRead: 110, SearchQueries: 1, DocumentsWritten: 1, BytesWritten: 20},
+		}, snapshot[0])
+	})
+
+	t.Run("reads of a transaction are recorded on commit", func(t *testing.T) {
+		// the retried attempt is discarded, only the committed one is recorded
+		ctx := WithPendingUsage(context.Background(), "ns4", "name4")
+		RecordTxUsage(ctx, "ns4", "name4", Usage{DocumentsRead: 2, BytesRead: 40})
+		DiscardPendingUsage(ctx)
+		RecordTxUsage(ctx, "ns4", "name4", Usage{DocumentsRead: 2, BytesRead: 40})
+		RecordWriteUsage(ctx, "ns4", "name4", 1, 10)
+		CommitPendingUsage(ctx)
+
+		snapshot := UsageSnapshot()
+		require.Equal(t, NamespaceUsage{
+			Namespace:     "ns4",
+			NamespaceName: "name4",
+			Usage:         Usage{DocumentsRead: 2, BytesRead: 40, DocumentsWritten: 1, BytesWritten: 10},
+		}, snapshot[len(snapshot)-1])
+	})
+}
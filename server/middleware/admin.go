@@ -0,0 +1,114 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/authz"
+	"github.com/tigrisdata/tigris/server/request"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// AdminAuth authenticates and authorizes the requests of the admin HTTP routes like the calls of the admin methods:
+// the token of the request must be issued to one of the admin namespaces and the caller must have the role the method
+// of the route requires, the admin role unless the method is listed with another role. Nothing is checked when the
+// auth is disabled, the same as for the other methods. It is created by Get with the auth function of the interceptors,
+// so that the admin routes share their token validator.
+type AdminAuth struct {
+	authFunc func(ctx context.Context) (context.Context, error)
+}
+
+// Handler returns the middleware of an admin route authorized as the method, the name of the method is prefixed with
+// api.AdminRoutesMethodPrefix. The request is served with the context of the caller, like the calls of the methods.
+func (a *AdminAuth) Handler(method string) func(http.Handler) http.Handler {
+	return a.MethodHandler(api.AdminRoutesMethodPrefix + method)
+}
+
+// MethodHandler returns the middleware of an admin route authorized as the admin method of the full name, for the
+// routes serving the admin methods of the API.
+func (a *AdminAuth) MethodHandler(fullMethod string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if a == nil || a.authFunc == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, err := a.authorize(r, fullMethod)
+			if err != nil {
+				writeHTTPError(w, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func (a *AdminAuth) authorize(r *http.Request, fullMethod string) (context.Context, error) {
+	ctx := metadata.NewIncomingContext(r.Context(), metadata.Pairs(headerAuthorize, r.Header.Get(headerAuthorize)))
+	// the auth reads the method of the call from its transport stream
+	ctx = grpc.NewContextWithServerTransportStream(ctx, &adminTransportStream{method: fullMethod})
+	reqMetadata := request.GetGrpcEndPointMetadataFromFullMethod(ctx, fullMethod, "unary")
+	ctx = reqMetadata.SaveToContext(ctx)
+
+	ctx, err := a.authFunc(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = authz.Authorize(ctx, fullMethod); err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
+// writeHTTPError writes the error in the same format as the errors of the API.
+func writeHTTPError(w http.ResponseWriter, err error) {
+	e := errors.WithRetryInfo(err)
+	data, merr := api.MarshalStatus(e.GRPCStatus().Proto())
+	if merr != nil {
+		log.Err(merr).Msg("failed to marshal the error")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(api.ToHTTPCode(e.Code))
+	_, _ = w.Write(data)
+}
+
+// adminTransportStream is the transport stream of the admin routes, the headers of the calls are not sent.
+type adminTransportStream struct {
+	method string
+}
+
+func (s *adminTransportStream) Method() string {
+	return s.method
+}
+
+func (s *adminTransportStream) SetHeader(metadata.MD) error {
+	return nil
+}
+
+func (s *adminTransportStream) SendHeader(metadata.MD) error {
+	return nil
+}
+
+func (s *adminTransportStream) SetTrailer(metadata.MD) error {
+	return nil
+}
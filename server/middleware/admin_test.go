@@ -0,0 +1,82 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/request"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func TestAdminAuth(t *testing.T) {
+	issuer := newTestIssuer(t)
+	cfg := config.DefaultConfig
+	cfg.Auth.LogOnly = false
+	cfg.Auth.AdminNamespaces = []string{"tigris-admin"}
+	cfg.Auth.TokenCacheSize = 10
+	cfg.Auth.Issuers = []config.IssuerConfig{
+		{URL: "https://issuer.example.com/", Audiences: []string{"https://tigris-api"}, JWKSURL: issuer.server.URL},
+	}
+	v, err := newTokenValidator(&cfg.Auth)
+	require.NoError(t, err)
+	auth := &AdminAuth{authFunc: func(ctx context.Context) (context.Context, error) {
+		return authFunction(ctx, v, &cfg)
+	}}
+
+	var served *request.AccessToken
+	handler := auth.Handler("GetRole")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served, _ = request.GetAccessToken(r.Context())
+	}))
+	call := func(namespace string) int {
+		served = nil
+		r := httptest.NewRequest(http.MethodGet, "/admin/namespaces/ns1/roles/user", nil)
+		if namespace != "" {
+			token := issuer.sign(t, "key-1", jwt.Claims{
+				Issuer:   "https://issuer.example.com/",
+				Subject:  "user",
+				Audience: jwt.Audience{"https://tigris-api"},
+				Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			}, map[string]interface{}{tigrisNamespaceClaim: map[string]string{"code": namespace}})
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// the requests without a token and the tokens of the other namespaces are rejected
+	require.Equal(t, http.StatusUnauthorized, call(""))
+	require.Nil(t, served)
+	require.Equal(t, http.StatusUnauthorized, call("ns1"))
+	require.Nil(t, served)
+
+	require.Equal(t, http.StatusOK, call("tigris-admin"))
+	require.Equal(t, &request.AccessToken{Namespace: "tigris-admin", Sub: "user"}, served)
+
+	// nothing is checked without the auth
+	var disabled *AdminAuth
+	w := httptest.NewRecorder()
+	called := false
+	disabled.Handler("GetRole")(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { called = true })).
+		ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/namespaces/ns1/roles/user", nil))
+	require.True(t, called)
+}
@@ -16,13 +16,8 @@ package middleware
 
 import (
 	"context"
-	"net/url"
 	"strings"
-	"time"
 
-	"github.com/auth0/go-jwt-middleware/v2/jwks"
-	"github.com/auth0/go-jwt-middleware/v2/validator"
-	lru "github.com/hashicorp/golang-lru"
 	"github.com/rs/zerolog/log"
 	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/errors"
@@ -44,26 +39,6 @@ var (
 	)
 )
 
-type Namespace struct {
-	Code string `json:"code"`
-}
-
-type User struct {
-	Email string `json:"email"`
-}
-
-type CustomClaim struct {
-	Namespace Namespace `json:"https://tigris/n"`
-	User      User      `json:"https://tigris/u"`
-}
-
-func (c CustomClaim) Validate(_ context.Context) error {
-	if len(c.Namespace.Code) == 0 {
-		return errors.PermissionDenied("empty namespace code in token")
-	}
-	return nil
-}
-
 func AuthFromMD(ctx context.Context, expectedScheme string) (string, error) {
 	val := api.GetHeader(ctx, headerAuthorize)
 	if val == "" {
@@ -82,32 +57,10 @@ func AuthFromMD(ctx context.Context, expectedScheme string) (string, error) {
 	return splits[1], nil
 }
 
-func GetJWTValidator(config *config.Config) *validator.Validator {
-	issuerURL, _ := url.Parse(config.Auth.IssuerURL)
-	provider := jwks.NewCachingProvider(issuerURL, config.Auth.JWKSCacheTimeout)
-
-	jwtValidator, err := validator.New(
-		provider.KeyFunc,
-		validator.RS256,
-		issuerURL.String(),
-		[]string{config.Auth.Audience},
-		validator.WithAllowedClockSkew(time.Duration(config.Auth.TokenClockSkewDurationSec)*time.Second),
-		validator.WithCustomClaims(
-			func() validator.CustomClaims {
-				return &CustomClaim{}
-			},
-		),
-	)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to configure JWTValidator")
-	}
-	return jwtValidator
-}
-
-func measuredAuthFunction(ctx context.Context, jwtValidator *validator.Validator, config *config.Config, cache *lru.Cache) (ctxResult context.Context, err error) {
+func measuredAuthFunction(ctx context.Context, tokenValidator *tokenValidator, config *config.Config) (ctxResult context.Context, err error) {
 	measurement := metrics.NewMeasurement("auth", "auth", metrics.AuthSpanType, metrics.GetAuthBaseTags(ctx))
 	measurement.StartTracing(ctx, true)
-	ctxResult, err = authFunction(ctx, jwtValidator, config, cache)
+	ctxResult, err = authFunction(ctx, tokenValidator, config)
 	if err != nil {
 		measurement.CountErrorForScope(metrics.AuthErrorCount, measurement.GetAuthErrorTags(err))
 		measurement.FinishWithError(ctxResult, "auth", err)
@@ -120,7 +73,7 @@ func measuredAuthFunction(ctx context.Context, jwtValidator *validator.Validator
 	return
 }
 
-func authFunction(ctx context.Context, jwtValidator *validator.Validator, config *config.Config, cache *lru.Cache) (ctxResult context.Context, err error) {
+func authFunction(ctx context.Context, tokenValidator *tokenValidator, config *config.Config) (ctxResult context.Context, err error) {
 	reqMetadata, err := request.GetRequestMetadataFromContext(ctx)
 	if err != nil {
 		log.Warn().Err(err).Msg("Failed to load request metadata")
@@ -148,54 +101,44 @@ func authFunction(ctx context.Context, jwtValidator *validator.Validator, config
 		return ctx, err
 	}
 
-	validatedToken, found := cache.Get(tkn)
-	if !found {
-		validatedToken, err = jwtValidator.ValidateToken(ctx, tkn)
-		if err != nil {
-			if reqMetadata != nil {
-				log.Debug().Str("error", err.Error()).Str("unauthenticated_namespace", reqMetadata.GetNamespace()).Str("unauthenticated_namespace_name", reqMetadata.GetNamespaceName()).Err(err).Msg("Failed to validate access token")
-			} else {
-				log.Debug().Str("error", err.Error()).Err(err).Msg("Failed to validate access token")
-			}
-			return ctx, errors.Unauthenticated("Failed to validate access token")
+	validatedClaims, err := tokenValidator.validate(ctx, tkn)
+	if err != nil {
+		if reqMetadata != nil {
+			log.Debug().Str("error", err.Error()).Str("unauthenticated_namespace", reqMetadata.GetNamespace()).Str("unauthenticated_namespace_name", reqMetadata.GetNamespaceName()).Err(err).Msg("Failed to validate access token")
+		} else {
+			log.Debug().Str("error", err.Error()).Err(err).Msg("Failed to validate access token")
 		}
-		cache.Add(tkn, validatedToken)
+		return ctx, err
 	}
 
 	// validate custom claims
-	if validatedClaims, ok := validatedToken.(*validator.ValidatedClaims); ok {
-		// validate expiration
-		if validatedClaims.RegisteredClaims.Expiry+int64(config.Auth.TokenClockSkewDurationSec) < time.Now().Unix() {
-			return nil, errors.Unauthenticated("Failed to validate access token")
+	if customClaims, ok := validatedClaims.CustomClaims.(*tokenClaims); ok {
+		// if incoming namespace is empty, set it to unknown for observables and reject request
+		if customClaims.Namespace == "" {
+			log.Warn().Msg("Valid token with empty namespace received")
+			reqMetadata.SetNamespace(ctx, defaults.UnknownValue)
+			return ctx, errors.Unauthenticated("You are not authorized to perform this admin action")
 		}
-
-		if customClaims, ok := validatedClaims.CustomClaims.(*CustomClaim); ok {
-			// if incoming namespace is empty, set it to unknown for observables and reject request
-			if customClaims.Namespace.Code == "" {
-				log.Warn().Msg("Valid token with empty namespace received")
-				reqMetadata.SetNamespace(ctx, defaults.UnknownValue)
+		isAdmin := fullMethodNameFound && request.IsAdminApi(fullMethodName)
+		if isAdmin {
+			// admin api being called, let's check if the user is of admin allowed namespaces
+			if !isAdminNamespace(customClaims.Namespace, config) {
+				log.Warn().
+					Interface("AdminNamespaces", config.Auth.AdminNamespaces).
+					Str("IncomingNamespace", customClaims.Namespace).
+					Msg("Valid token received for admin action - but not allowed to administer from this namespace")
 				return ctx, errors.Unauthenticated("You are not authorized to perform this admin action")
 			}
-			isAdmin := fullMethodNameFound && request.IsAdminApi(fullMethodName)
-			if isAdmin {
-				// admin api being called, let's check if the user is of admin allowed namespaces
-				if !isAdminNamespace(customClaims.Namespace.Code, config) {
-					log.Warn().
-						Interface("AdminNamespaces", config.Auth.AdminNamespaces).
-						Str("IncomingNamespace", customClaims.Namespace.Code).
-						Msg("Valid token received for admin action - but not allowed to administer from this namespace")
-					return ctx, errors.Unauthenticated("You are not authorized to perform this admin action")
-				}
-			}
+		}
 
-			log.Debug().Msg("Valid token received")
-			token := &request.AccessToken{
-				Namespace: customClaims.Namespace.Code,
-				Sub:       validatedClaims.RegisteredClaims.Subject,
-			}
-			reqMetadata.SetAccessToken(token)
-			return ctx, nil
+		log.Debug().Msg("Valid token received")
+		token := &request.AccessToken{
+			Namespace: customClaims.Namespace,
+			Sub:       validatedClaims.RegisteredClaims.Subject,
+			Role:      customClaims.Role,
 		}
+		reqMetadata.SetAccessToken(token)
+		return ctx, nil
 	}
 	// this should never happen.
 	return ctx, errors.Unauthenticated("You are not authorized to perform this action")
@@ -212,21 +155,20 @@ func isAdminNamespace(incomingNamespace string, config *config.Config) bool {
 
 func getAuthFunction(config *config.Config) func(ctx context.Context) (context.Context, error) {
 	if config.Auth.Enabled {
-		jwtValidator := GetJWTValidator(config)
-
-		lruCache, err := lru.New(config.Auth.TokenCacheSize)
+		tokenValidator, err := newTokenValidator(&config.Auth)
 		if err != nil {
-			panic("Failed to setup token cache")
+			log.Fatal().Err(err).Msg("Failed to configure the token validator")
 		}
+		tokenValidator.startRefresh(config.Auth.JWKSCacheTimeout)
 
-		// inline closure to access the state of jwtValidator
+		// inline closure to access the state of the token validator
 		if config.Tracing.Enabled {
 			return func(ctx context.Context) (context.Context, error) {
-				return measuredAuthFunction(ctx, jwtValidator, config, lruCache)
+				return measuredAuthFunction(ctx, tokenValidator, config)
 			}
 		} else {
 			return func(ctx context.Context) (context.Context, error) {
-				return authFunction(ctx, jwtValidator, config, lruCache)
+				return authFunction(ctx, tokenValidator, config)
 			}
 		}
 	}
@@ -19,8 +19,6 @@ import (
 	"os"
 	"testing"
 
-	"github.com/auth0/go-jwt-middleware/v2/validator"
-	lru "github.com/hashicorp/golang-lru"
 	"github.com/stretchr/testify/require"
 	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/server/config"
@@ -35,9 +33,10 @@ func TestAuth(t *testing.T) {
 			Level: "error",
 		},
 		Auth: config.AuthConfig{
-			IssuerURL:                "",
-			Audience:                 "",
+			IssuerURL:                "https://issuer.example.com/",
+			Audience:                 "https://tigris-api",
 			JWKSCacheTimeout:         0,
+			TokenCacheSize:           5,
 			LogOnly:                  false,
 			EnableNamespaceIsolation: false,
 			AdminNamespaces:          []string{"tigris-admin"},
@@ -45,39 +44,38 @@ func TestAuth(t *testing.T) {
 		FoundationDB: config.FoundationDBConfig{},
 	}
 
-	cache, err := lru.New(5)
-	if err != nil {
-		panic("Failed to setup cache")
-	}
+	tokenValidator, err := newTokenValidator(&enforcedAuthConfig.Auth)
+	require.NoError(t, err)
 	t.Run("log_only mode: no token", func(t *testing.T) {
-		ctx, err := authFunction(context.TODO(), &validator.Validator{}, &config.DefaultConfig, cache)
+		ctx, err := authFunction(context.TODO(), tokenValidator, &config.DefaultConfig)
 		require.NotNil(t, ctx)
 		require.Nil(t, err)
 	})
 
 	t.Run("enforcing mode: no token", func(t *testing.T) {
-		_, err := authFunction(context.TODO(), &validator.Validator{}, &enforcedAuthConfig, cache)
+		_, err := authFunction(context.TODO(), tokenValidator, &enforcedAuthConfig)
 		require.NotNil(t, err)
 		require.Equal(t, err, errors.Unauthenticated("request unauthenticated with bearer"))
 	})
 
 	t.Run("enforcing mode: Bad authorization string1", func(t *testing.T) {
 		incomingCtx := metadata.NewIncomingContext(context.TODO(), metadata.Pairs("authorization", "bearer"))
-		_, err := authFunction(incomingCtx, &validator.Validator{}, &enforcedAuthConfig, cache)
+		_, err := authFunction(incomingCtx, tokenValidator, &enforcedAuthConfig)
 		require.NotNil(t, err)
 		require.Equal(t, err, errors.Unauthenticated("bad authorization string"))
 	})
 
 	t.Run("enforcing mode: Bad token", func(t *testing.T) {
 		incomingCtx := metadata.NewIncomingContext(context.TODO(), metadata.Pairs("authorization", "bearer somebadtoken"))
-		_, err := authFunction(incomingCtx, &validator.Validator{}, &enforcedAuthConfig, cache)
+		_, err := authFunction(incomingCtx, tokenValidator, &enforcedAuthConfig)
 		require.NotNil(t, err)
-		require.Equal(t, err, errors.Unauthenticated("Failed to validate access token"))
+		require.Equal(t, err, newTokenError(tokenMalformed))
+		require.Equal(t, "Failed to validate access token: the token is malformed", err.Error())
 	})
 
 	t.Run("enforcing mode: Bad token 2", func(t *testing.T) {
 		incomingCtx := metadata.NewIncomingContext(context.TODO(), metadata.Pairs("authorization", "bearer some.bad.token"))
-		_, err := authFunction(incomingCtx, &validator.Validator{}, &enforcedAuthConfig, cache)
+		_, err := authFunction(incomingCtx, tokenValidator, &enforcedAuthConfig)
 		require.NotNil(t, err)
 		require.Contains(t, err.Error(), "Failed to validate access token")
 	})
@@ -0,0 +1,113 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"path"
+
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/config"
+	"google.golang.org/grpc"
+)
+
+// concurrencyLimiter bounds the number of requests in flight, all the methods together and per method. The requests
+// above a limit are rejected right away rather than queued, so that a spike doesn't pile up requests timing out anyway.
+// The streams are in flight until they are closed.
+type concurrencyLimiter struct {
+	global  chan struct{}
+	methods map[string]chan struct{}
+}
+
+// newConcurrencyLimiter returns the limiter of the configured limits, nil if no limit is configured.
+func newConcurrencyLimiter(cfg *config.ConcurrencyConfig) *concurrencyLimiter {
+	l := &concurrencyLimiter{methods: map[string]chan struct{}{}}
+	if cfg.MaxInFlight > 0 {
+		l.global = make(chan struct{}, cfg.MaxInFlight)
+	}
+	for method, limit := range cfg.Methods {
+		if limit > 0 {
+			l.methods[method] = make(chan struct{}, limit)
+		}
+	}
+	if l.global == nil && len(l.methods) == 0 {
+		return nil
+	}
+
+	return l
+}
+
+// acquire reserves a slot of the method and a global slot, the release function frees them once the request is done.
+// The health checks are not limited.
+func (l *concurrencyLimiter) acquire(method string) (func(), error) {
+	if method == api.HealthMethodName {
+		return func() {}, nil
+	}
+
+	sem, ok := l.methods[method]
+	if !ok {
+		sem = l.methods[path.Base(method)]
+	}
+	if sem != nil {
+		select {
+		case sem <- struct{}{}:
+		default:
+			return nil, errors.ResourceExhausted("too many concurrent requests of '%s', the limit is %d", path.Base(method), cap(sem)).
+				WithSubsystem(errors.SubsystemConcurrency)
+		}
+	}
+
+	if l.global != nil {
+		select {
+		case l.global <- struct{}{}:
+		default:
+			if sem != nil {
+				<-sem
+			}
+			return nil, errors.ResourceExhausted("too many concurrent requests, the limit is %d", cap(l.global)).
+				WithSubsystem(errors.SubsystemConcurrency)
+		}
+	}
+
+	return func() {
+		if l.global != nil {
+			<-l.global
+		}
+		if sem != nil {
+			<-sem
+		}
+	}, nil
+}
+
+func (l *concurrencyLimiter) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	release, err := l.acquire(info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return handler(ctx, req)
+}
+
+func (l *concurrencyLimiter) stream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	release, err := l.acquire(info.FullMethod)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return handler(srv, stream)
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/config"
+	"google.golang.org/grpc"
+)
+
+const (
+	testInsertMethod = "/tigrisdata.v1.Tigris/Insert"
+	testSearchMethod = "/tigrisdata.v1.Tigris/Search"
+)
+
+// inFlight starts a request of the method that is handled until the returned function is called, the error is the
+// error of the request if it is rejected.
+func inFlight(t *testing.T, l *concurrencyLimiter, method string) (func(), error) {
+	t.Helper()
+
+	started, done := make(chan struct{}), make(chan struct{})
+	errc := make(chan error, 1)
+	go func() {
+		_, err := l.unary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			close(started)
+			<-done
+			return nil, nil
+		})
+		errc <- err
+	}()
+
+	select {
+	case <-started:
+		return func() { close(done); require.NoError(t, <-errc) }, nil
+	case err := <-errc:
+		return nil, err
+	}
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	require.Nil(t, newConcurrencyLimiter(&config.ConcurrencyConfig{}))
+	require.Nil(t, newConcurrencyLimiter(&config.ConcurrencyConfig{Methods: map[string]int{"Search": 0}}))
+
+	t.Run("global", func(t *testing.T) {
+		l := newConcurrencyLimiter(&config.ConcurrencyConfig{MaxInFlight: 2})
+
+		r1, err := inFlight(t, l, testInsertMethod)
+		require.NoError(t, err)
+		r2, err := inFlight(t, l, testSearchMethod)
+		require.NoError(t, err)
+
+		_, err = inFlight(t, l, testInsertMethod)
+		require.Equal(t, errors.ResourceExhausted("too many concurrent requests, the limit is 2").WithSubsystem(errors.SubsystemConcurrency), err)
+		// the health checks are not limited
+		rh, err := inFlight(t, l, api.HealthMethodName)
+		require.NoError(t, err)
+		rh()
+
+		// the requests proceed once the requests in flight are done
+		r1()
+		r3, err := inFlight(t, l, testInsertMethod)
+		require.NoError(t, err)
+		r2()
+		r3()
+	})
+
+	t.Run("method", func(t *testing.T) {
+		l := newConcurrencyLimiter(&config.ConcurrencyConfig{MaxInFlight: 3, Methods: map[string]int{
+			"Search":         1,
+			testInsertMethod: 2,
+		}})
+
+		s1, err := inFlight(t, l, testSearchMethod)
+		require.NoError(t, err)
+		_, err = inFlight(t, l, testSearchMethod)
+		require.Equal(t, errors.ResourceExhausted("too many concurrent requests of 'Search', the limit is 1").WithSubsystem(errors.SubsystemConcurrency), err)
+
+		i1, err := inFlight(t, l, testInsertMethod)
+		require.NoError(t, err)
+		i2, err := inFlight(t, l, testInsertMethod)
+		require.NoError(t, err)
+		// the global limit is reached, a slot of the method is not kept by the rejected request
+		_, err = inFlight(t, l, "/tigrisdata.v1.Tigris/Read")
+		require.Equal(t, errors.ResourceExhausted("too many concurrent requests, the limit is 3").WithSubsystem(errors.SubsystemConcurrency), err)
+		s1()
+		_, err = inFlight(t, l, testInsertMethod)
+		require.Equal(t, errors.ResourceExhausted("too many concurrent requests of 'Insert', the limit is 2").WithSubsystem(errors.SubsystemConcurrency), err)
+
+		i1()
+		i2()
+		s2, err := inFlight(t, l, testSearchMethod)
+		require.NoError(t, err)
+		s2()
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		l := newConcurrencyLimiter(&config.ConcurrencyConfig{MaxInFlight: 1})
+		info := &grpc.StreamServerInfo{FullMethod: testSearchMethod}
+
+		err := l.stream(nil, nil, info, func(srv interface{}, stream grpc.ServerStream) error {
+			// the stream is in flight until its handler returns
+			_, err := inFlight(t, l, testInsertMethod)
+			require.Equal(t, errors.ResourceExhausted("too many concurrent requests, the limit is 1").WithSubsystem(errors.SubsystemConcurrency), err)
+			return nil
+		})
+		require.NoError(t, err)
+
+		r, err := inFlight(t, l, testInsertMethod)
+		require.NoError(t, err)
+		r()
+	})
+}
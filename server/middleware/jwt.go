@@ -0,0 +1,398 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	goerrors "errors"
+	"math/rand"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/auth0/go-jwt-middleware/v2/jwks"
+	"github.com/auth0/go-jwt-middleware/v2/validator"
+	lru "github.com/hashicorp/golang-lru"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/config"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	// tigrisNamespaceClaim is the namespace claim of the tokens issued for Tigris, {"code": "<namespace>"}.
+	tigrisNamespaceClaim = "https://tigris/n"
+	// tigrisRoleClaim is the role of the caller in its namespace.
+	tigrisRoleClaim = "https://tigris/r"
+
+	// jwksMinRefreshInterval is how often the keys of an issuer can be fetched for the tokens signed with a key that
+	// is not known yet.
+	jwksMinRefreshInterval = 30 * time.Second
+	jwksFetchTimeout       = 10 * time.Second
+)
+
+// The reasons the tokens fail to validate, they are reported by the auth metrics.
+const (
+	tokenExpired         = "expired"
+	tokenNotValidYet     = "not_valid_yet"
+	tokenMalformed       = "malformed"
+	tokenBadSignature    = "invalid_signature"
+	tokenWrongAudience   = "wrong_audience"
+	tokenWrongIssuer     = "wrong_issuer"
+	tokenKeysUnavailable = "keys_unavailable"
+)
+
+var tokenFailureMessages = map[string]string{
+	tokenExpired:         "the token is expired",
+	tokenNotValidYet:     "the token is not valid yet",
+	tokenMalformed:       "the token is malformed",
+	tokenBadSignature:    "the signature of the token is invalid",
+	tokenWrongAudience:   "the token is not issued for this audience",
+	tokenWrongIssuer:     "the issuer of the token is not trusted",
+	tokenKeysUnavailable: "the keys of the issuer are unavailable",
+}
+
+// tokenError is the error of an access token that failed to validate.
+type tokenError struct {
+	*api.TigrisError
+
+	reason string
+}
+
+func newTokenError(reason string) error {
+	return &tokenError{
+		TigrisError: api.Errorf(api.Code_UNAUTHENTICATED, "Failed to validate access token: %s", tokenFailureMessages[reason]),
+		reason:      reason,
+	}
+}
+
+func (e *tokenError) Unwrap() error {
+	return e.TigrisError
+}
+
+// AuthFailure is the reason the token failed to validate.
+func (e *tokenError) AuthFailure() string {
+	return e.reason
+}
+
+// tokenFailure returns the reason of the error of the validator.
+func tokenFailure(err error) string {
+	switch {
+	case goerrors.Is(err, jwt.ErrExpired):
+		return tokenExpired
+	case goerrors.Is(err, jwt.ErrNotValidYet), goerrors.Is(err, jwt.ErrIssuedInTheFuture):
+		return tokenNotValidYet
+	case goerrors.Is(err, jwt.ErrInvalidAudience):
+		return tokenWrongAudience
+	case goerrors.Is(err, jwt.ErrInvalidIssuer):
+		return tokenWrongIssuer
+	case goerrors.Is(err, jose.ErrCryptoFailure), goerrors.Is(err, jose.ErrUnsupportedKeyType):
+		return tokenBadSignature
+	default:
+		return tokenMalformed
+	}
+}
+
+// tokenClaims are the claims of the tokens read by Tigris, the namespace is read from the namespace claim of the
+// issuer of the token.
+type tokenClaims struct {
+	namespaceClaim string
+
+	Namespace string
+	Role      string
+}
+
+func (c *tokenClaims) UnmarshalJSON(data []byte) error {
+	var claims map[string]jsoniter.RawMessage
+	if err := jsoniter.Unmarshal(data, &claims); err != nil {
+		return err
+	}
+
+	if raw, ok := claims[c.namespaceClaim]; ok {
+		c.Namespace = namespaceClaimValue(raw)
+	}
+	if raw, ok := claims[tigrisRoleClaim]; ok {
+		_ = jsoniter.Unmarshal(raw, &c.Role)
+	}
+	return nil
+}
+
+func (c *tokenClaims) Validate(_ context.Context) error {
+	if len(c.Namespace) == 0 {
+		return errors.PermissionDenied("empty namespace code in token")
+	}
+	return nil
+}
+
+// namespaceClaimValue returns the namespace of the claim, the claim is either the namespace or an object with its code.
+func namespaceClaimValue(raw jsoniter.RawMessage) string {
+	var namespace string
+	if err := jsoniter.Unmarshal(raw, &namespace); err == nil {
+		return namespace
+	}
+
+	var object struct {
+		Code string `json:"code"`
+	}
+	_ = jsoniter.Unmarshal(raw, &object)
+	return object.Code
+}
+
+// jwksKeys are the keys of an issuer, they are refreshed in the background so that the validation of the tokens
+// doesn't wait for them.
+type jwksKeys struct {
+	sync.RWMutex
+
+	provider  *jwks.Provider
+	keys      *jose.JSONWebKeySet
+	fetchedAt time.Time
+}
+
+// get returns the keys of the issuer, they are only fetched if they were never fetched.
+func (k *jwksKeys) get(ctx context.Context) (*jose.JSONWebKeySet, error) {
+	k.RLock()
+	keys := k.keys
+	k.RUnlock()
+	if keys != nil {
+		return keys, nil
+	}
+
+	return k.fetch(ctx)
+}
+
+// fetch reads the keys of the issuer, the current keys are kept if they can't be read.
+func (k *jwksKeys) fetch(ctx context.Context) (*jose.JSONWebKeySet, error) {
+	keys, err := k.provider.KeyFunc(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	k.Lock()
+	k.keys, k.fetchedAt = keys.(*jose.JSONWebKeySet), time.Now()
+	k.Unlock()
+	return keys.(*jose.JSONWebKeySet), nil
+}
+
+// fetchUnknown fetches the keys for a token signed with a key that is not known, the keys of a rotation are then
+// found before they are refreshed. The keys are fetched at most once per jwksMinRefreshInterval.
+func (k *jwksKeys) fetchUnknown(ctx context.Context) {
+	k.Lock()
+	if time.Since(k.fetchedAt) < jwksMinRefreshInterval {
+		k.Unlock()
+		return
+	}
+	k.fetchedAt = time.Now()
+	k.Unlock()
+
+	if _, err := k.fetch(ctx); err != nil {
+		log.Warn().Err(err).Msg("fetching the keys of the issuer failed")
+	}
+}
+
+// refresh fetches the keys every interval, with a jitter so that the servers don't fetch them all at the same time.
+func (k *jwksKeys) refresh(interval time.Duration) {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+		if _, err := k.fetch(ctx); err != nil {
+			log.Warn().Err(err).Msg("refreshing the keys of the issuer failed")
+		}
+		cancel()
+
+		time.Sleep(withJitter(interval))
+	}
+}
+
+// withJitter returns the interval increased or decreased by up to a tenth.
+func withJitter(interval time.Duration) time.Duration {
+	jitter := int64(interval / 10)
+	if jitter <= 0 {
+		return interval
+	}
+	return interval - time.Duration(jitter) + time.Duration(rand.Int63n(2*jitter+1)) //nolint:gosec
+}
+
+// issuerValidator validates the tokens of an issuer, a token is valid if it is issued for one of the audiences.
+type issuerValidator struct {
+	keys       *jwksKeys
+	validators []*validator.Validator
+}
+
+func (i *issuerValidator) validateToken(ctx context.Context, token string) (interface{}, error) {
+	var err error
+	for _, audienceValidator := range i.validators {
+		var validated interface{}
+		if validated, err = audienceValidator.ValidateToken(ctx, token); !goerrors.Is(err, jwt.ErrInvalidAudience) {
+			return validated, err
+		}
+	}
+	return nil, err
+}
+
+// tokenValidator validates the access tokens of the issuers. A validated token is cached until it expires, keyed by
+// its hash.
+type tokenValidator struct {
+	issuers   map[string]*issuerValidator
+	cache     *lru.Cache
+	clockSkew time.Duration
+	now       func() time.Time
+}
+
+type validatedToken struct {
+	claims   *validator.ValidatedClaims
+	expireAt time.Time
+}
+
+// authIssuers returns the issuers of the config, the issuer_url and the audience are the issuer when none is listed.
+func authIssuers(cfg *config.AuthConfig) []config.IssuerConfig {
+	if len(cfg.Issuers) > 0 {
+		return cfg.Issuers
+	}
+	return []config.IssuerConfig{{URL: cfg.IssuerURL, Audiences: []string{cfg.Audience}}}
+}
+
+func newTokenValidator(cfg *config.AuthConfig) (*tokenValidator, error) {
+	cache, err := lru.New(cfg.TokenCacheSize)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &tokenValidator{
+		issuers:   make(map[string]*issuerValidator),
+		cache:     cache,
+		clockSkew: time.Duration(cfg.TokenClockSkewDurationSec) * time.Second,
+		now:       time.Now,
+	}
+	for _, issuerCfg := range authIssuers(cfg) {
+		issuerURL, err := url.Parse(issuerCfg.URL)
+		if err != nil {
+			return nil, err
+		}
+		var opts []jwks.ProviderOption
+		if len(issuerCfg.JWKSURL) > 0 {
+			jwksURL, err := url.Parse(issuerCfg.JWKSURL)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, jwks.WithCustomJWKSURI(jwksURL))
+		}
+		namespaceClaim := issuerCfg.NamespaceClaim
+		if len(namespaceClaim) == 0 {
+			namespaceClaim = tigrisNamespaceClaim
+		}
+
+		keys := &jwksKeys{provider: jwks.NewProvider(issuerURL, opts...)}
+		issuer := &issuerValidator{keys: keys}
+		// all the expected audiences must be in a token, the token is then validated for each audience
+		for _, audience := range issuerCfg.Audiences {
+			jwtValidator, err := validator.New(
+				func(ctx context.Context) (interface{}, error) {
+					return keys.get(ctx)
+				},
+				validator.RS256,
+				issuerURL.String(),
+				[]string{audience},
+				validator.WithAllowedClockSkew(v.clockSkew),
+				validator.WithCustomClaims(
+					func() validator.CustomClaims {
+						return &tokenClaims{namespaceClaim: namespaceClaim}
+					},
+				),
+			)
+			if err != nil {
+				return nil, err
+			}
+			issuer.validators = append(issuer.validators, jwtValidator)
+		}
+		if len(issuer.validators) == 0 {
+			return nil, errors.InvalidArgument("no audience is configured for the issuer '%s'", issuerCfg.URL)
+		}
+
+		v.issuers[issuerURL.String()] = issuer
+	}
+
+	return v, nil
+}
+
+// startRefresh refreshes the keys of the issuers in the background.
+func (v *tokenValidator) startRefresh(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	for _, issuer := range v.issuers {
+		go issuer.keys.refresh(interval)
+	}
+}
+
+func (v *tokenValidator) validate(ctx context.Context, token string) (*validator.ValidatedClaims, error) {
+	hash := sha256.Sum256([]byte(token))
+	key := string(hash[:])
+	if entry, ok := v.cache.Get(key); ok {
+		cached := entry.(*validatedToken)
+		if v.now().Before(cached.expireAt) {
+			return cached.claims, nil
+		}
+		v.cache.Remove(key)
+		return nil, newTokenError(tokenExpired)
+	}
+
+	claims, err := v.validateToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	// the tokens without an expiration are validated every time
+	if claims.RegisteredClaims.Expiry > 0 {
+		v.cache.Add(key, &validatedToken{
+			claims:   claims,
+			expireAt: time.Unix(claims.RegisteredClaims.Expiry, 0).Add(v.clockSkew),
+		})
+	}
+	return claims, nil
+}
+
+func (v *tokenValidator) validateToken(ctx context.Context, token string) (*validator.ValidatedClaims, error) {
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, newTokenError(tokenMalformed)
+	}
+	// the issuer is verified by the validator of the issuer
+	var unverified jwt.Claims
+	if err = parsed.UnsafeClaimsWithoutVerification(&unverified); err != nil {
+		return nil, newTokenError(tokenMalformed)
+	}
+	issuer, ok := v.issuers[unverified.Issuer]
+	if !ok {
+		return nil, newTokenError(tokenWrongIssuer)
+	}
+
+	keys, err := issuer.keys.get(ctx)
+	if err != nil {
+		log.Warn().Err(err).Str("issuer", unverified.Issuer).Msg("fetching the keys of the issuer failed")
+		return nil, newTokenError(tokenKeysUnavailable)
+	}
+	if kid := parsed.Headers[0].KeyID; len(kid) > 0 && len(keys.Key(kid)) == 0 {
+		issuer.keys.fetchUnknown(ctx)
+	}
+
+	validated, err := issuer.validateToken(ctx, token)
+	if err != nil {
+		log.Debug().Err(err).Str("issuer", unverified.Issuer).Msg("Failed to validate access token")
+		return nil, newTokenError(tokenFailure(err))
+	}
+	return validated.(*validator.ValidatedClaims), nil
+}
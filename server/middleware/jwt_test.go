@@ -0,0 +1,208 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/server/config"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// testIssuer serves the keys of an issuer and signs its tokens.
+type testIssuer struct {
+	sync.Mutex
+
+	keys    jose.JSONWebKeySet
+	signers map[string]jose.Signer
+	fetches int32
+	server  *httptest.Server
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+
+	issuer := &testIssuer{signers: make(map[string]jose.Signer)}
+	issuer.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&issuer.fetches, 1)
+		issuer.Lock()
+		defer issuer.Unlock()
+		_ = jsoniter.NewEncoder(w).Encode(issuer.keys)
+	}))
+	t.Cleanup(issuer.server.Close)
+	issuer.addKey(t, "key-1")
+	return issuer
+}
+
+func (i *testIssuer) addKey(t *testing.T, kid string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", kid))
+	require.NoError(t, err)
+
+	i.Lock()
+	defer i.Unlock()
+	i.keys.Keys = append(i.keys.Keys, jose.JSONWebKey{Key: key.Public(), KeyID: kid, Algorithm: "RS256", Use: "sig"})
+	i.signers[kid] = signer
+}
+
+func (i *testIssuer) sign(t *testing.T, kid string, claims jwt.Claims, custom map[string]interface{}) string {
+	t.Helper()
+
+	i.Lock()
+	signer := i.signers[kid]
+	i.Unlock()
+	token, err := jwt.Signed(signer).Claims(claims).Claims(custom).CompactSerialize()
+	require.NoError(t, err)
+	return token
+}
+
+func TestTokenValidator(t *testing.T) {
+	first, second := newTestIssuer(t), newTestIssuer(t)
+	cfg := &config.AuthConfig{
+		TokenCacheSize:            10,
+		TokenClockSkewDurationSec: 60,
+		Issuers: []config.IssuerConfig{
+			{URL: "https://first.example.com/", Audiences: []string{"https://tigris-api"}, JWKSURL: first.server.URL},
+			{
+				URL:            "https://second.example.com/",
+				Audiences:      []string{"https://tigris-api", "https://other-api"},
+				JWKSURL:        second.server.URL,
+				NamespaceClaim: "org",
+			},
+		},
+	}
+	v, err := newTokenValidator(cfg)
+	require.NoError(t, err)
+
+	now := time.Now()
+	claims := func(issuer string, audience string, expiry time.Time) jwt.Claims {
+		return jwt.Claims{
+			Issuer:   issuer,
+			Subject:  "user",
+			Audience: jwt.Audience{audience},
+			IssuedAt: jwt.NewNumericDate(now.Add(-time.Minute)),
+			Expiry:   jwt.NewNumericDate(expiry),
+		}
+	}
+	namespace := map[string]interface{}{tigrisNamespaceClaim: map[string]string{"code": "ns1"}, tigrisRoleClaim: "editor"}
+	ctx := context.Background()
+
+	t.Run("issuers", func(t *testing.T) {
+		token := first.sign(t, "key-1", claims("https://first.example.com/", "https://tigris-api", now.Add(time.Hour)), namespace)
+		validated, err := v.validate(ctx, token)
+		require.NoError(t, err)
+		require.Equal(t, "ns1", validated.CustomClaims.(*tokenClaims).Namespace)
+		require.Equal(t, "editor", validated.CustomClaims.(*tokenClaims).Role)
+
+		// the namespace claim of the issuer is a string
+		token = second.sign(t, "key-1", claims("https://second.example.com/", "https://other-api", now.Add(time.Hour)),
+			map[string]interface{}{"org": "ns2"})
+		validated, err = v.validate(ctx, token)
+		require.NoError(t, err)
+		require.Equal(t, "ns2", validated.CustomClaims.(*tokenClaims).Namespace)
+
+		// the keys of an issuer don't validate the tokens of the other issuer
+		token = second.sign(t, "key-1", claims("https://first.example.com/", "https://tigris-api", now.Add(time.Hour)), namespace)
+		_, err = v.validate(ctx, token)
+		require.Equal(t, tokenBadSignature, err.(*tokenError).AuthFailure())
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		for _, c := range []struct {
+			name   string
+			claims jwt.Claims
+			reason string
+		}{
+			{"expired", claims("https://first.example.com/", "https://tigris-api", now.Add(-2*time.Minute)), tokenExpired},
+			{"wrong audience", claims("https://first.example.com/", "https://other-api", now.Add(time.Hour)), tokenWrongAudience},
+			{"wrong issuer", claims("https://third.example.com/", "https://tigris-api", now.Add(time.Hour)), tokenWrongIssuer},
+		} {
+			_, err := v.validate(ctx, first.sign(t, "key-1", c.claims, namespace))
+			require.Error(t, err, c.name)
+			require.Equal(t, c.reason, err.(*tokenError).AuthFailure(), c.name)
+			require.Equal(t, "Failed to validate access token: "+tokenFailureMessages[c.reason], err.Error(), c.name)
+		}
+
+		_, err := v.validate(ctx, "not.a.token")
+		require.Equal(t, newTokenError(tokenMalformed), err)
+	})
+
+	t.Run("clock skew", func(t *testing.T) {
+		token := first.sign(t, "key-1", claims("https://first.example.com/", "https://tigris-api", now.Add(-30*time.Second)), namespace)
+		_, err := v.validate(ctx, token)
+		require.NoError(t, err)
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		token := first.sign(t, "key-1", claims("https://first.example.com/", "https://tigris-api", now.Add(time.Minute)), namespace)
+		_, err := v.validate(ctx, token)
+		require.NoError(t, err)
+		fetches := atomic.LoadInt32(&first.fetches)
+
+		_, err = v.validate(ctx, token)
+		require.NoError(t, err)
+		require.Equal(t, fetches, atomic.LoadInt32(&first.fetches))
+
+		// the cached token expires with the token
+		v.now = func() time.Time { return now.Add(3 * time.Minute) }
+		defer func() { v.now = time.Now }()
+		_, err = v.validate(ctx, token)
+		require.Equal(t, newTokenError(tokenExpired), err)
+	})
+
+	t.Run("key rotation", func(t *testing.T) {
+		first.addKey(t, "key-2")
+		keys := v.issuers["https://first.example.com/"].keys
+		keys.Lock()
+		keys.fetchedAt = time.Now().Add(-jwksMinRefreshInterval)
+		keys.Unlock()
+
+		token := first.sign(t, "key-2", claims("https://first.example.com/", "https://tigris-api", now.Add(time.Hour)), namespace)
+		_, err := v.validate(ctx, token)
+		require.NoError(t, err)
+
+		// the keys are not fetched again for the keys that are still unknown
+		fetches := atomic.LoadInt32(&first.fetches)
+		first.addKey(t, "key-3")
+		token = first.sign(t, "key-3", claims("https://first.example.com/", "https://tigris-api", now.Add(time.Hour)), namespace)
+		_, err = v.validate(ctx, token)
+		require.Error(t, err)
+		require.Equal(t, fetches, atomic.LoadInt32(&first.fetches))
+	})
+}
+
+func TestWithJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		interval := withJitter(time.Minute)
+		require.GreaterOrEqual(t, interval, 54*time.Second)
+		require.LessOrEqual(t, interval, 66*time.Second)
+	}
+	require.Equal(t, time.Duration(0), withJitter(0))
+}
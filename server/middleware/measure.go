@@ -16,11 +16,17 @@ package middleware
 
 import (
 	"context"
+	goerrors "errors"
+	"hash/fnv"
+	"math/rand"
+	"strings"
 
 	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
 	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/metrics"
 	"github.com/tigrisdata/tigris/server/request"
+	"github.com/tigrisdata/tigris/server/tracing"
 	"github.com/tigrisdata/tigris/util"
 	ulog "github.com/tigrisdata/tigris/util/log"
 	"google.golang.org/grpc"
@@ -29,17 +35,19 @@ import (
 
 const (
 	TigrisStreamSpan string = "rpcstream"
+
+	// sampleBuckets is the resolution of the sample rates of the methods.
+	sampleBuckets = 1000000
 )
 
 type wrappedStream struct {
 	*middleware.WrappedServerStream
 	measurement *metrics.Measurement
+	stats       *metrics.StreamStats
 }
 
 func getNoMeasurementMethods() []string {
-	return []string{
-		api.HealthMethodName,
-	}
+	return config.DefaultConfig.Tracing.NoMeasurementMethods
 }
 
 func measureMethod(fullMethod string) bool {
@@ -51,6 +59,47 @@ func measureMethod(fullMethod string) bool {
 	return true
 }
 
+// getMethodSampleRate returns the sample rate of the traces of the method, the rate of its full name takes precedence
+// over the rate of its name. The names are compared case-insensitively as the keys of the config are lowercased.
+func getMethodSampleRate(fullMethod string) (float64, bool) {
+	var (
+		rate  float64
+		found bool
+	)
+	name := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	for method, r := range config.DefaultConfig.Tracing.MethodSampleRates {
+		if strings.EqualFold(method, fullMethod) {
+			return r, true
+		}
+		if strings.EqualFold(method, name) {
+			rate, found = r, true
+		}
+	}
+	return rate, found
+}
+
+// getTraceSampling decides whether the request is traced when its method has a sample rate. The decision is made on
+// the request id so that it is the same on every server the request goes through.
+func getTraceSampling(fullMethod string, requestID string) metrics.TraceSampling {
+	rate, ok := getMethodSampleRate(fullMethod)
+	if !ok {
+		return metrics.TraceSamplingDefault
+	}
+
+	var sample float64
+	if len(requestID) > 0 {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(requestID))
+		sample = float64(h.Sum64()%sampleBuckets) / sampleBuckets
+	} else {
+		sample = rand.Float64() //nolint:gosec
+	}
+	if sample < rate {
+		return metrics.TraceSamplingKeep
+	}
+	return metrics.TraceSamplingDrop
+}
+
 func measureUnary() func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		if !measureMethod(info.FullMethod) {
@@ -62,13 +111,15 @@ func measureUnary() func(ctx context.Context, req interface{}, info *grpc.UnaryS
 		tags := reqMetadata.GetInitialTags()
 		measurement := metrics.NewMeasurement(util.Service, info.FullMethod, metrics.GrpcSpanType, tags)
 		measurement.AddTags(metrics.GetDbCollTagsForReq(req))
-		ctx = measurement.StartTracing(ctx, false)
+		measurement.SetTraceSampling(getTraceSampling(info.FullMethod, reqMetadata.GetRequestID()))
+		ctx = measurement.StartTracing(tracing.ExtractIncoming(ctx), false)
 		resp, err := handler(ctx, req)
 		if err != nil {
 			// Request had an error
 			measurement.CountErrorForScope(metrics.RequestsErrorCount, measurement.GetRequestErrorTags(err))
 			_ = measurement.FinishWithError(ctx, "request", err)
 			measurement.RecordDuration(metrics.RequestsErrorRespTime, measurement.GetRequestErrorTags(err))
+			measurement.LogSlowQuery(req, err)
 			return nil, err
 		}
 		// Request was ok
@@ -77,6 +128,7 @@ func measureUnary() func(ctx context.Context, req interface{}, info *grpc.UnaryS
 		measurement.CountSentBytes(metrics.BytesSent, measurement.GetNetworkTags(), proto.Size(resp.(proto.Message)))
 		_ = measurement.FinishTracing(ctx)
 		measurement.RecordDuration(metrics.RequestsRespTime, measurement.GetRequestOkTags())
+		measurement.LogSlowQuery(req, nil)
 		return resp, err
 	}
 }
@@ -95,14 +147,17 @@ func measureStream() grpc.StreamServerInterceptor {
 		}
 		tags := reqMetadata.GetInitialTags()
 		measurement := metrics.NewMeasurement(util.Service, info.FullMethod, metrics.GrpcSpanType, tags)
+		measurement.SetTraceSampling(getTraceSampling(info.FullMethod, reqMetadata.GetRequestID()))
 		wrapped.measurement = measurement
-		wrapped.WrappedContext = measurement.StartTracing(wrapped.WrappedContext, false)
+		wrapped.stats = metrics.NewStreamStats(info.FullMethod)
+		wrapped.WrappedContext = measurement.StartTracing(tracing.ExtractIncoming(wrapped.WrappedContext), false)
 		err = handler(srv, wrapped)
-		if err != nil {
-			measurement.CountErrorForScope(metrics.RequestsErrorCount, measurement.GetRequestErrorTags(err))
-			_ = measurement.FinishWithError(wrapped.WrappedContext, "request", err)
-			measurement.RecordDuration(metrics.RequestsErrorRespTime, measurement.GetRequestErrorTags(err))
-			ulog.E(err)
+		wrapped.stats.Finish(metrics.StreamTerminationReason(stream.Context(), err))
+		if measuredErr := measuredStreamError(stream.Context(), err); measuredErr != nil {
+			measurement.CountErrorForScope(metrics.RequestsErrorCount, measurement.GetRequestErrorTags(measuredErr))
+			_ = measurement.FinishWithError(wrapped.WrappedContext, "request", measuredErr)
+			measurement.RecordDuration(metrics.RequestsErrorRespTime, measurement.GetRequestErrorTags(measuredErr))
+			ulog.E(measuredErr)
 			return err
 		}
 		measurement.CountOkForScope(metrics.RequestsOkCount, measurement.GetRequestOkTags())
@@ -112,6 +167,20 @@ func measureStream() grpc.StreamServerInterceptor {
 	}
 }
 
+// measuredStreamError returns the error the stream is measured with. The handlers don't always report that the client
+// cancelled the stream, the stream is then measured as cancelled whatever the handler returned.
+func measuredStreamError(ctx context.Context, err error) error {
+	if ctx.Err() != context.Canceled {
+		return err
+	}
+
+	var tigrisErr *api.TigrisError
+	if goerrors.As(err, &tigrisErr) && tigrisErr.Code == api.Code_CANCELLED {
+		return err
+	}
+	return api.Errorf(api.Code_CANCELLED, "the stream is cancelled by the client")
+}
+
 func (w *wrappedStream) RecvMsg(m interface{}) error {
 	parentMeasurement := w.measurement
 	if parentMeasurement == nil {
@@ -123,7 +192,11 @@ func (w *wrappedStream) RecvMsg(m interface{}) error {
 	err := w.ServerStream.RecvMsg(m)
 	parentMeasurement.RecursiveAddTags(metrics.GetDbCollTagsForReq(m))
 	childMeasurement.RecursiveAddTags(metrics.GetDbCollTagsForReq(m))
-	parentMeasurement.CountReceivedBytes(metrics.BytesReceived, parentMeasurement.GetNetworkTags(), proto.Size(m.(proto.Message)))
+	size := proto.Size(m.(proto.Message))
+	parentMeasurement.CountReceivedBytes(metrics.BytesReceived, parentMeasurement.GetNetworkTags(), size)
+	if err == nil {
+		w.stats.MessageReceived(size)
+	}
 	w.WrappedContext = childMeasurement.FinishTracing(w.WrappedContext)
 	return err
 }
@@ -139,7 +212,11 @@ func (w *wrappedStream) SendMsg(m interface{}) error {
 	err := w.ServerStream.SendMsg(m)
 	parentMeasurement.RecursiveAddTags(metrics.GetDbCollTagsForReq(m))
 	childMeasurement.RecursiveAddTags(metrics.GetDbCollTagsForReq(m))
-	parentMeasurement.CountSentBytes(metrics.BytesSent, parentMeasurement.GetNetworkTags(), proto.Size(m.(proto.Message)))
+	size := proto.Size(m.(proto.Message))
+	parentMeasurement.CountSentBytes(metrics.BytesSent, parentMeasurement.GetNetworkTags(), size)
+	if err == nil {
+		w.stats.MessageSent(size)
+	}
 	w.WrappedContext = childMeasurement.FinishTracing(w.WrappedContext)
 	return err
 }
@@ -0,0 +1,117 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/tigrisdata/tigris/server/request"
+	"github.com/uber-go/tally"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMeasureMethod(t *testing.T) {
+	defaultMethods := config.DefaultConfig.Tracing.NoMeasurementMethods
+	defer func() { config.DefaultConfig.Tracing.NoMeasurementMethods = defaultMethods }()
+
+	require.False(t, measureMethod(api.HealthMethodName))
+	require.True(t, measureMethod("/tigrisdata.v1.Tigris/Read"))
+
+	config.DefaultConfig.Tracing.NoMeasurementMethods = []string{"/tigrisdata.v1.Tigris/Read"}
+	require.True(t, measureMethod(api.HealthMethodName))
+	require.False(t, measureMethod("/tigrisdata.v1.Tigris/Read"))
+}
+
+func TestTraceSampling(t *testing.T) {
+	defer func() { config.DefaultConfig.Tracing.MethodSampleRates = nil }()
+
+	config.DefaultConfig.Tracing.MethodSampleRates = map[string]float64{
+		"read": 0.01,
+		"/tigrisdata.v1.tigris/createorupdatecollection": 1,
+		"createorupdatecollection":                       0,
+		"/tigrisdata.v1.tigris/dropdatabase":             0,
+	}
+
+	require.Equal(t, metrics.TraceSamplingDefault, getTraceSampling("/tigrisdata.v1.Tigris/Insert", "id"))
+	// the full name takes precedence over the name
+	require.Equal(t, metrics.TraceSamplingKeep, getTraceSampling("/tigrisdata.v1.Tigris/CreateOrUpdateCollection", "id"))
+	require.Equal(t, metrics.TraceSamplingDrop, getTraceSampling("/tigrisdata.v1.Tigris/DropDatabase", "id"))
+	require.Equal(t, metrics.TraceSamplingDrop, getTraceSampling("/tigrisdata.v1.Tigris/DropDatabase", ""))
+
+	kept := 0
+	for i := 0; i < 100000; i++ {
+		id := fmt.Sprintf("request-%d", i)
+		sampling := getTraceSampling("/tigrisdata.v1.Tigris/Read", id)
+		// the decision is the same for the same request id
+		require.Equal(t, sampling, getTraceSampling("/tigrisdata.v1.Tigris/Read", id))
+		if sampling == metrics.TraceSamplingKeep {
+			kept++
+		}
+	}
+	require.InDelta(t, 1000, kept, 200)
+}
+
+func TestMeasureCanceledStream(t *testing.T) {
+	testScope := tally.NewTestScope("", nil)
+	defer func(ok, errs, respTime, errRespTime tally.Scope, timer bool) {
+		metrics.RequestsOkCount, metrics.RequestsErrorCount = ok, errs
+		metrics.RequestsRespTime, metrics.RequestsErrorRespTime = respTime, errRespTime
+		config.DefaultConfig.Metrics.Requests.Timer.TimerEnabled = timer
+	}(metrics.RequestsOkCount, metrics.RequestsErrorCount, metrics.RequestsRespTime, metrics.RequestsErrorRespTime,
+		config.DefaultConfig.Metrics.Requests.Timer.TimerEnabled)
+	metrics.RequestsOkCount, metrics.RequestsErrorCount = testScope.SubScope("count"), testScope.SubScope("count")
+	metrics.RequestsRespTime, metrics.RequestsErrorRespTime = testScope.SubScope("response"), testScope.SubScope("error_response")
+	config.DefaultConfig.Metrics.Requests.Timer.TimerEnabled = true
+
+	info := &grpc.StreamServerInfo{FullMethod: api.ReadMethodName}
+	for _, handlerErr := range []error{nil, context.Canceled, status.Error(codes.Canceled, "context canceled")} {
+		reqMetadata := request.NewRequestEndpointMetadata(context.Background(), "tigrisdata.v1.Tigris", grpc.MethodInfo{Name: "Read"})
+		ctx, cancel := context.WithCancel(reqMetadata.SaveToContext(context.Background()))
+		err := measureStream()(nil, &testServerStream{ctx: ctx}, info, func(interface{}, grpc.ServerStream) error {
+			// the client cancels the stream while it is handled
+			cancel()
+			return handlerErr
+		})
+		require.Equal(t, handlerErr, err)
+	}
+
+	snapshot := testScope.Snapshot()
+	var errs, oks, durations int64
+	for _, c := range snapshot.Counters() {
+		switch c.Name() {
+		case "count.error":
+			require.Equal(t, "CANCELLED", c.Tags()["error_code"])
+			errs += c.Value()
+		case "count.ok":
+			oks += c.Value()
+		}
+	}
+	for _, timer := range snapshot.Timers() {
+		require.Equal(t, "error_response.time", timer.Name())
+		durations += int64(len(timer.Values()))
+	}
+	// every stream is measured once, as a cancelled stream
+	require.Equal(t, int64(3), errs)
+	require.Equal(t, int64(0), oks)
+	require.Equal(t, int64(3), durations)
+}
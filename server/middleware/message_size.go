@@ -0,0 +1,98 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/config"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// messageSizeLimits checks the size of the messages of the calls against the configured limits. The errors of gRPC,
+// like "received message larger than max", are written before the interceptors of the unary calls are invoked, so the
+// transport accepts larger messages and the interceptors return an error explaining the limit instead.
+type messageSizeLimits struct {
+	recv int
+	send int
+}
+
+// MessageSizeInterceptors returns the interceptors enforcing the message size limits of the gRPC server. A limit is
+// not checked if it is zero.
+func MessageSizeInterceptors(cfg *config.GRPCConfig) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	limits := &messageSizeLimits{recv: cfg.MaxRecvMsgSize, send: cfg.MaxSendMsgSize}
+	return limits.unary, limits.stream
+}
+
+func (l *messageSizeLimits) checkRecv(m interface{}) error {
+	if msg, ok := m.(proto.Message); ok && l.recv > 0 {
+		if size := proto.Size(msg); size > l.recv {
+			return errors.NonRetryable(errors.ResourceExhausted("request of %d bytes exceeds the maximum message size of %d bytes",
+				size, l.recv))
+		}
+	}
+	return nil
+}
+
+func (l *messageSizeLimits) checkSend(m interface{}) error {
+	if msg, ok := m.(proto.Message); ok && l.send > 0 {
+		if size := proto.Size(msg); size > l.send {
+			return errors.NonRetryable(errors.ResourceExhausted("response of %d bytes exceeds the maximum message size of %d bytes",
+				size, l.send))
+		}
+	}
+	return nil
+}
+
+func (l *messageSizeLimits) unary(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := l.checkRecv(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err = l.checkSend(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (l *messageSizeLimits) stream(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &messageSizeStream{ServerStream: stream, limits: l})
+}
+
+type messageSizeStream struct {
+	grpc.ServerStream
+
+	limits *messageSizeLimits
+}
+
+func (s *messageSizeStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.limits.checkRecv(m)
+}
+
+func (s *messageSizeStream) SendMsg(m interface{}) error {
+	if err := s.limits.checkSend(m); err != nil {
+		return err
+	}
+	return s.ServerStream.SendMsg(m)
+}
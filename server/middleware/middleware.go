@@ -19,7 +19,6 @@ import (
 	grpc_zerolog "github.com/grpc-ecosystem/go-grpc-middleware/providers/zerolog/v2"
 	grpc_auth "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
 	grpc_logging "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
-	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/tigrisdata/tigris/server/config"
@@ -28,7 +27,9 @@ import (
 	"google.golang.org/grpc"
 )
 
-func Get(config *config.Config) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+// Get returns the interceptors of the servers and the auth of the admin routes. They hold the state of the concurrency
+// limits and of the token validation, so they are created once and shared by the gRPC and the HTTP servers.
+func Get(config *config.Config) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor, *AdminAuth) {
 	authFunc := getAuthFunction(config)
 
 	// adding all the middlewares for the server stream
@@ -44,13 +45,24 @@ func Get(config *config.Config) (grpc.UnaryServerInterceptor, grpc.StreamServerI
 
 	// The order of the interceptors matter with optional elements in them
 	streamInterceptors := []grpc.StreamServerInterceptor{
+		retryInfoStreamServerInterceptor(),
 		metadataExtractorStream(),
+		requestIDStreamServerInterceptor(),
 	}
 
 	if config.Metrics.Enabled || config.Tracing.Enabled {
 		streamInterceptors = append(streamInterceptors, measureStream())
 	}
 
+	// the panics are recovered inside the measurement, so that the requests are counted as errors
+	streamInterceptors = append(streamInterceptors, recoveryStreamServerInterceptor(panicDetails(config)))
+
+	// the requests above the concurrency limits are rejected before any work is done for them
+	limiter := newConcurrencyLimiter(&config.Server.Concurrency)
+	if limiter != nil {
+		streamInterceptors = append(streamInterceptors, limiter.stream)
+	}
+
 	streamInterceptors = append(streamInterceptors, forwarderStreamServerInterceptor())
 
 	if authFunc != nil {
@@ -59,10 +71,11 @@ func Get(config *config.Config) (grpc.UnaryServerInterceptor, grpc.StreamServerI
 
 	streamInterceptors = append(streamInterceptors, []grpc.StreamServerInterceptor{
 		namespaceSetterStreamServerInterceptor(config.Auth.EnableNamespaceIsolation),
+		authzStreamServerInterceptor(),
+		rateLimitStreamServerInterceptor(),
 		quotaStreamServerInterceptor(),
 		grpc_logging.StreamServerInterceptor(grpc_zerolog.InterceptorLogger(sampledTaggedLogger), []grpc_logging.Option{}...),
 		validatorStreamServerInterceptor(),
-		grpc_recovery.StreamServerInterceptor(),
 		headersStreamServerInterceptor(),
 	}...)
 	stream := middleware.ChainStreamServer(streamInterceptors...)
@@ -74,13 +87,21 @@ func Get(config *config.Config) (grpc.UnaryServerInterceptor, grpc.StreamServerI
 
 	// The order of the interceptors matter with optional elements in them
 	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		retryInfoUnaryServerInterceptor(),
 		metadataExtractorUnary(),
+		requestIDUnaryServerInterceptor(),
 	}
 
 	if config.Metrics.Enabled || config.Tracing.Enabled {
 		unaryInterceptors = append(unaryInterceptors, measureUnary())
 	}
 
+	unaryInterceptors = append(unaryInterceptors, recoveryUnaryServerInterceptor(panicDetails(config)))
+
+	if limiter != nil {
+		unaryInterceptors = append(unaryInterceptors, limiter.unary)
+	}
+
 	unaryInterceptors = append(unaryInterceptors, forwarderUnaryServerInterceptor())
 
 	if authFunc != nil {
@@ -89,15 +110,16 @@ func Get(config *config.Config) (grpc.UnaryServerInterceptor, grpc.StreamServerI
 
 	unaryInterceptors = append(unaryInterceptors, []grpc.UnaryServerInterceptor{
 		namespaceSetterUnaryServerInterceptor(config.Auth.EnableNamespaceIsolation),
+		authzUnaryServerInterceptor(),
 		pprofUnaryServerInterceptor(),
+		rateLimitUnaryServerInterceptor(),
 		quotaUnaryServerInterceptor(),
 		grpc_logging.UnaryServerInterceptor(grpc_zerolog.InterceptorLogger(sampledTaggedLogger)),
 		validatorUnaryServerInterceptor(),
 		timeoutUnaryServerInterceptor(DefaultTimeout),
-		grpc_recovery.UnaryServerInterceptor(),
 		headersUnaryServerInterceptor(),
 	}...)
 	unary := middleware.ChainUnaryServer(unaryInterceptors...)
 
-	return unary, stream
+	return unary, stream, &AdminAuth{authFunc: authFunc}
 }
@@ -0,0 +1,52 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/server/ratelimit"
+	"github.com/tigrisdata/tigris/server/request"
+	"google.golang.org/grpc"
+)
+
+func rateLimitUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if m := info.FullMethod; m != api.HealthMethodName && !request.IsAdminApi(m) {
+			ns, _ := request.GetNamespace(ctx)
+			if err := ratelimit.Allow(ctx, ns, m); err != nil {
+				return nil, err
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// rateLimitStreamServerInterceptor limits the streams when they are opened, the messages of a stream are limited by
+// the quota.
+func rateLimitStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if m := info.FullMethod; m != api.HealthMethodName && !request.IsAdminApi(m) {
+			ns, _ := request.GetNamespace(stream.Context())
+			if err := ratelimit.Allow(stream.Context(), ns, m); err != nil {
+				return err
+			}
+		}
+
+		return handler(srv, stream)
+	}
+}
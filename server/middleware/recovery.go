@@ -0,0 +1,78 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/tigrisdata/tigris/server/request"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+)
+
+// panicDetails returns true if the message of the panics is returned to the clients, it never is in production.
+func panicDetails(cfg *config.Config) bool {
+	return cfg.Server.PanicDetails && config.GetEnvironment() != config.EnvProduction
+}
+
+// recoveryUnaryServerInterceptor turns the panics of the handlers into internal errors, instead of closing the
+// connection of the client.
+func recoveryUnaryServerInterceptor(details bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				resp, err = nil, recoverPanic(ctx, info.FullMethod, p, details)
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+func recoveryStreamServerInterceptor(details bool) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recoverPanic(stream.Context(), info.FullMethod, p, details)
+			}
+		}()
+
+		return handler(srv, stream)
+	}
+}
+
+// recoverPanic logs the panic with its stack and returns the error of the request, the request id is in the details
+// of the error so that the logs of the panic can be found.
+func recoverPanic(ctx context.Context, method string, p interface{}, details bool) error {
+	var requestID string
+	if reqMetadata, err := request.GetRequestMetadataFromContext(ctx); err == nil {
+		requestID = reqMetadata.GetRequestID()
+	}
+
+	log.Error().Str("request_id", requestID).Str("method", method).Interface("panic", p).
+		Str("stack", string(debug.Stack())).Msg("recovered from a panic in the handler")
+	metrics.CountPanic(method)
+
+	err := api.Errorf(api.Code_INTERNAL, "internal server error")
+	if details {
+		err = api.Errorf(api.Code_INTERNAL, "internal server error: %v", p)
+	}
+	return err.WithDetails(&errdetails.RequestInfo{RequestId: requestID})
+}
@@ -0,0 +1,106 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/tigrisdata/tigris/server/request"
+	"github.com/uber-go/tally"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type testServerStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestRecovery(t *testing.T) {
+	defer func() { metrics.ErrorMetrics = nil }()
+	testScope := tally.NewTestScope("", nil)
+	metrics.ErrorMetrics = testScope
+
+	reqMetadata := &request.Metadata{}
+	reqMetadata.SetRequestID("req-1")
+	ctx := reqMetadata.SaveToContext(context.Background())
+
+	requireInternal := func(t *testing.T, err error, message string) {
+		t.Helper()
+
+		st := status.Convert(err)
+		require.Equal(t, codes.Internal, st.Code())
+		require.Equal(t, message, st.Message())
+		var requestID string
+		for _, d := range st.Details() {
+			if info, ok := d.(*errdetails.RequestInfo); ok {
+				requestID = info.RequestId
+			}
+		}
+		require.Equal(t, "req-1", requestID)
+	}
+
+	t.Run("unary", func(t *testing.T) {
+		info := &grpc.UnaryServerInfo{FullMethod: "/tigrisdata.v1.Tigris/Insert"}
+		resp, err := recoveryUnaryServerInterceptor(false)(ctx, &api.InsertRequest{}, info, func(context.Context, interface{}) (interface{}, error) {
+			panic("nil map")
+		})
+		require.Nil(t, resp)
+		requireInternal(t, err, "internal server error")
+
+		_, err = recoveryUnaryServerInterceptor(true)(ctx, &api.InsertRequest{}, info, func(context.Context, interface{}) (interface{}, error) {
+			panic("nil map")
+		})
+		requireInternal(t, err, "internal server error: nil map")
+
+		resp, err = recoveryUnaryServerInterceptor(false)(ctx, &api.InsertRequest{}, info, func(context.Context, interface{}) (interface{}, error) {
+			return &api.InsertResponse{}, nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, &api.InsertResponse{}, resp)
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		info := &grpc.StreamServerInfo{FullMethod: "/tigrisdata.v1.Tigris/Read"}
+		err := recoveryStreamServerInterceptor(true)(nil, &testServerStream{ctx: ctx}, info, func(interface{}, grpc.ServerStream) error {
+			panic("stream closed")
+		})
+		requireInternal(t, err, "internal server error: stream closed")
+	})
+
+	counters := testScope.Snapshot().Counters()
+	require.Equal(t, int64(2), counters["panic+grpc_method=/tigrisdata.v1.Tigris/Insert"].Value())
+	require.Equal(t, int64(1), counters["panic+grpc_method=/tigrisdata.v1.Tigris/Read"].Value())
+}
+
+func TestPanicDetails(t *testing.T) {
+	cfg := &config.Config{}
+	require.False(t, panicDetails(cfg))
+
+	cfg.Server.PanicDetails = true
+	require.Equal(t, config.GetEnvironment() != config.EnvProduction, panicDetails(cfg))
+}
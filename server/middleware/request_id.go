@@ -0,0 +1,63 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/lib/uuid"
+	"github.com/tigrisdata/tigris/server/request"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// requestIDUnaryServerInterceptor tags the request with the request id passed by the client in the X-Request-Id header
+// or generates a new one if it is absent. The id is saved in the request metadata so that it is part of the
+// measurement tags and is echoed back to the client in the response headers.
+func requestIDUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		id := setRequestID(ctx)
+		if err := grpc.SetHeader(ctx, metadata.Pairs(api.HeaderRequestId, id)); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func requestIDStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		id := setRequestID(stream.Context())
+		if err := stream.SetHeader(metadata.Pairs(api.HeaderRequestId, id)); err != nil {
+			return err
+		}
+
+		return handler(srv, stream)
+	}
+}
+
+func setRequestID(ctx context.Context) string {
+	id := api.GetHeader(ctx, api.HeaderRequestId)
+	if len(id) == 0 {
+		id = uuid.NewUUIDAsString()
+	}
+
+	if reqMetadata, err := request.GetRequestMetadataFromContext(ctx); err == nil {
+		reqMetadata.SetRequestID(id)
+	}
+
+	return id
+}
@@ -0,0 +1,116 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/server/request"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type mockTransportStream struct {
+	header metadata.MD
+}
+
+func (m *mockTransportStream) Method() string { return api.ReadMethodName }
+
+func (m *mockTransportStream) SetHeader(md metadata.MD) error {
+	m.header = metadata.Join(m.header, md)
+	return nil
+}
+
+func (m *mockTransportStream) SendHeader(md metadata.MD) error { return m.SetHeader(md) }
+
+func (m *mockTransportStream) SetTrailer(_ metadata.MD) error { return nil }
+
+type mockServerStream struct {
+	grpc.ServerStream
+
+	ctx    context.Context
+	header metadata.MD
+}
+
+func (m *mockServerStream) Context() context.Context { return m.ctx }
+
+func (m *mockServerStream) SetHeader(md metadata.MD) error {
+	m.header = metadata.Join(m.header, md)
+	return nil
+}
+
+func requestIDTestContext(id string) context.Context {
+	ctx := context.Background()
+	if len(id) > 0 {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(api.HeaderRequestId, id))
+	}
+
+	reqMetadata := request.GetGrpcEndPointMetadataFromFullMethod(ctx, api.ReadMethodName, "unary")
+	return reqMetadata.SaveToContext(ctx)
+}
+
+func TestRequestID(t *testing.T) {
+	t.Run("unary", func(t *testing.T) {
+		for _, id := range []string{"test-request-id", ""} {
+			transport := &mockTransportStream{}
+			ctx := grpc.NewContextWithServerTransportStream(requestIDTestContext(id), transport)
+
+			var tags map[string]string
+			_, err := requestIDUnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: api.ReadMethodName},
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					reqMetadata, err := request.GetRequestMetadataFromContext(ctx)
+					require.NoError(t, err)
+					tags = reqMetadata.GetInitialTags()
+					return nil, nil
+				})
+			require.NoError(t, err)
+
+			header := transport.header.Get(api.HeaderRequestId)
+			require.Len(t, header, 1)
+			require.NotEmpty(t, header[0])
+			if len(id) > 0 {
+				require.Equal(t, id, header[0])
+			}
+			require.Equal(t, header[0], tags["request_id"])
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		for _, id := range []string{"test-request-id", ""} {
+			stream := &mockServerStream{ctx: requestIDTestContext(id)}
+
+			var tags map[string]string
+			err := requestIDStreamServerInterceptor()(nil, stream, &grpc.StreamServerInfo{FullMethod: api.ReadMethodName},
+				func(srv interface{}, stream grpc.ServerStream) error {
+					reqMetadata, err := request.GetRequestMetadataFromContext(stream.Context())
+					require.NoError(t, err)
+					tags = reqMetadata.GetInitialTags()
+					return nil
+				})
+			require.NoError(t, err)
+
+			header := stream.header.Get(api.HeaderRequestId)
+			require.Len(t, header, 1)
+			require.NotEmpty(t, header[0])
+			if len(id) > 0 {
+				require.Equal(t, id, header[0])
+			}
+			require.Equal(t, header[0], tags["request_id"])
+		}
+	})
+}
@@ -0,0 +1,74 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+func TestRetryInfo(t *testing.T) {
+	requireRetryInfo := func(t *testing.T, err error, metadata map[string]string) {
+		t.Helper()
+
+		var info *errdetails.ErrorInfo
+		for _, d := range status.Convert(err).Details() {
+			if i, ok := d.(*errdetails.ErrorInfo); ok {
+				info = i
+			}
+		}
+		require.NotNil(t, info)
+		require.Equal(t, metadata, info.Metadata)
+	}
+
+	t.Run("unary", func(t *testing.T) {
+		info := &grpc.UnaryServerInfo{FullMethod: "/tigrisdata.v1.Tigris/Insert"}
+		_, err := retryInfoUnaryServerInterceptor()(context.Background(), &api.InsertRequest{}, info, func(context.Context, interface{}) (interface{}, error) {
+			return nil, errors.Aborted("transaction not committed due to conflict with another transaction")
+		})
+		requireRetryInfo(t, err, map[string]string{"retryable": "true", "subsystem": errors.SubsystemKV})
+
+		_, err = retryInfoUnaryServerInterceptor()(context.Background(), &api.InsertRequest{}, info, func(context.Context, interface{}) (interface{}, error) {
+			return nil, api.Errorf(api.Code_INVALID_ARGUMENT, "invalid document").WithSubsystem(errors.SubsystemValidation)
+		})
+		requireRetryInfo(t, err, map[string]string{"subsystem": errors.SubsystemValidation})
+
+		resp, err := retryInfoUnaryServerInterceptor()(context.Background(), &api.InsertRequest{}, info, func(context.Context, interface{}) (interface{}, error) {
+			return &api.InsertResponse{}, nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, &api.InsertResponse{}, resp)
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		info := &grpc.StreamServerInfo{FullMethod: "/tigrisdata.v1.Tigris/Read"}
+		stream := &testServerStream{ctx: context.Background()}
+		err := retryInfoStreamServerInterceptor()(nil, stream, info, func(interface{}, grpc.ServerStream) error {
+			return errors.Unavailable("search is unavailable")
+		})
+		requireRetryInfo(t, err, map[string]string{"retryable": "true"})
+
+		require.NoError(t, retryInfoStreamServerInterceptor()(nil, stream, info, func(interface{}, grpc.ServerStream) error {
+			return nil
+		}))
+	})
+}
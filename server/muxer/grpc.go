@@ -15,11 +15,15 @@
 package muxer
 
 import (
+	"context"
+	"math"
+
 	"github.com/rs/zerolog/log"
 	"github.com/soheilhy/cmux"
 	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/middleware"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -27,21 +31,87 @@ type GRPCServer struct {
 	*grpc.Server
 }
 
-func NewGRPCServer(cfg *config.Config) *GRPCServer {
+// NewGRPCServer returns the gRPC server of the interceptors, they are shared with the HTTP server so that the limits
+// they enforce apply to the requests of both servers together.
+func NewGRPCServer(cfg *config.Config, unary grpc.UnaryServerInterceptor, stream grpc.StreamServerInterceptor) *GRPCServer {
 	s := &GRPCServer{}
 
-	unary, stream := middleware.Get(cfg)
-	s.Server = grpc.NewServer(grpc.StreamInterceptor(stream), grpc.UnaryInterceptor(unary))
-	reflection.Register(s)
+	opts := []grpc.ServerOption{grpc.StreamInterceptor(stream), grpc.UnaryInterceptor(unary)}
+	if cfg.Server.TLS.Enabled {
+		opts = append(opts, grpc.Creds(muxTLSCredentials{}))
+	}
+	opts = append(opts, grpcLimitOptions(&cfg.Server.GRPC)...)
+	s.Server = grpc.NewServer(opts...)
+	if cfg.Server.GRPC.Reflection {
+		reflection.Register(s)
+	}
 	return s
 }
 
+// grpcLimitOptions returns the options of the configured limits of the gRPC server. The message size limits are
+// checked by the interceptors, after the others, as the transport accepts larger messages.
+func grpcLimitOptions(cfg *config.GRPCConfig) []grpc.ServerOption {
+	unary, stream := middleware.MessageSizeInterceptors(cfg)
+	opts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(unary), grpc.ChainStreamInterceptor(stream)}
+	if cfg.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(transportMessageSize(cfg.MaxRecvMsgSize)))
+	}
+	if cfg.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(transportMessageSize(cfg.MaxSendMsgSize)))
+	}
+	if cfg.MaxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(cfg.MaxConcurrentStreams))
+	}
+	if cfg.KeepaliveMinTime > 0 || cfg.KeepalivePermitWithoutStream {
+		opts = append(opts, grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             cfg.KeepaliveMinTime,
+			PermitWithoutStream: cfg.KeepalivePermitWithoutStream,
+		}))
+	}
+	if cfg.MaxConnectionIdle > 0 || cfg.MaxConnectionAge > 0 || cfg.MaxConnectionAgeGrace > 0 {
+		opts = append(opts, grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionIdle:     cfg.MaxConnectionIdle,
+			MaxConnectionAge:      cfg.MaxConnectionAge,
+			MaxConnectionAgeGrace: cfg.MaxConnectionAgeGrace,
+		}))
+	}
+	return opts
+}
+
+// transportMessageSize is the message size limit of the transport for a configured limit. The messages up to twice the
+// limit are read so that the interceptors can explain the limit, the larger ones are rejected by gRPC.
+func transportMessageSize(limit int) int {
+	if limit > math.MaxInt32/2 {
+		return math.MaxInt32
+	}
+	return 2 * limit
+}
+
 func (s *GRPCServer) Start(mux cmux.CMux) error {
 	// MatchWithWriters is needed as it needs SETTINGS frame from the server otherwise the client will block
 	match := mux.MatchWithWriters(cmux.HTTP2MatchHeaderFieldSendSettings("content-type", "application/grpc"))
 	go func() {
-		err := s.Serve(match)
-		log.Fatal().Err(err).Msg("start http server")
+		if err := s.Serve(match); !isClosed(err) {
+			log.Fatal().Err(err).Msg("start grpc server")
+		}
 	}()
 	return nil
 }
+
+// Stop waits for the calls in flight, the calls still running once the context is done, like the change streams, are
+// canceled.
+func (s *GRPCServer) Stop(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Warn().Msg("canceling the grpc calls still running")
+		s.Server.Stop()
+		<-done
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package muxer
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/server/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func newLimitsTestClient(t *testing.T, cfg *config.GRPCConfig) healthpb.HealthClient {
+	t.Helper()
+
+	server := grpc.NewServer(grpcLimitOptions(cfg)...)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = server.Serve(l) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestGRPCLimits(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("receive", func(t *testing.T) {
+		client := newLimitsTestClient(t, &config.GRPCConfig{MaxRecvMsgSize: 1024})
+
+		_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+		require.NoError(t, err)
+
+		// the request is a 3 bytes header followed by the service name
+		_, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: strings.Repeat("a", 1100)})
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+		require.Equal(t, "request of 1103 bytes exceeds the maximum message size of 1024 bytes", status.Convert(err).Message())
+
+		watch, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: strings.Repeat("a", 1100)})
+		require.NoError(t, err)
+		_, err = watch.Recv()
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+		require.Equal(t, "request of 1103 bytes exceeds the maximum message size of 1024 bytes", status.Convert(err).Message())
+
+		// the messages larger than twice the limit are rejected by the transport
+		_, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: strings.Repeat("a", 2100)})
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+		require.Contains(t, status.Convert(err).Message(), "received message larger than max")
+	})
+
+	t.Run("send", func(t *testing.T) {
+		client := newLimitsTestClient(t, &config.GRPCConfig{MaxSendMsgSize: 1})
+
+		_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+		require.Equal(t, "response of 2 bytes exceeds the maximum message size of 1 bytes", status.Convert(err).Message())
+	})
+
+	t.Run("no limits", func(t *testing.T) {
+		client := newLimitsTestClient(t, &config.GRPCConfig{})
+
+		_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: strings.Repeat("a", 1<<20)})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestGRPCLimitOptions(t *testing.T) {
+	// the interceptors are always set, the other options only when they are configured
+	require.Len(t, grpcLimitOptions(&config.GRPCConfig{}), 2)
+	require.Len(t, grpcLimitOptions(&config.GRPCConfig{
+		MaxRecvMsgSize:               1,
+		MaxSendMsgSize:               1,
+		MaxConcurrentStreams:         10,
+		KeepaliveMinTime:             time.Minute,
+		KeepalivePermitWithoutStream: true,
+		MaxConnectionAge:             time.Hour,
+		MaxConnectionAgeGrace:        time.Minute,
+	}), 7)
+
+	require.Equal(t, 2048, transportMessageSize(1024))
+	require.Equal(t, 1<<31-1, transportMessageSize(1<<30))
+}
+
+func TestGRPCReflection(t *testing.T) {
+	const reflectionService = "grpc.reflection.v1alpha.ServerReflection"
+
+	cfg := config.DefaultConfig
+	require.False(t, cfg.Server.GRPC.Reflection)
+	_, ok := NewGRPCServer(&cfg, nil, nil).GetServiceInfo()[reflectionService]
+	require.False(t, ok)
+
+	cfg.Server.GRPC.Reflection = true
+	_, ok = NewGRPCServer(&cfg, nil, nil).GetServiceInfo()[reflectionService]
+	require.True(t, ok)
+}
@@ -0,0 +1,177 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package muxer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/tigrisdata/tigris/server/request"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+)
+
+const (
+	grpcWebContentType     = "application/grpc-web"
+	grpcWebTextContentType = "application/grpc-web-text"
+
+	// grpcWebTrailerFlag is the flag of the frame carrying the trailers at the end of the body of a response.
+	grpcWebTrailerFlag = 0x80
+)
+
+// grpcWebExposedHeaders are the headers of the responses the browsers must expose to the gRPC-Web clients.
+var grpcWebExposedHeaders = []string{"Grpc-Status", "Grpc-Message", "Grpc-Status-Details-Bin"}
+
+// grpcWebHandler translates the gRPC-Web requests of the browsers, sent over HTTP/1.1, to the gRPC server. The
+// trailers of the gRPC responses are sent at the end of the body, as the browsers can't read the HTTP trailers, and
+// the "-text" requests and responses are base64 encoded.
+type grpcWebHandler struct {
+	server *grpc.Server
+}
+
+// isGRPCWebRequest returns true if the request is a gRPC-Web call.
+func isGRPCWebRequest(r *http.Request) bool {
+	return r.Method == http.MethodPost && strings.HasPrefix(r.Header.Get("Content-Type"), grpcWebContentType)
+}
+
+func (h *grpcWebHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	text := strings.HasPrefix(contentType, grpcWebTextContentType)
+
+	// the gRPC server only accepts HTTP/2 requests, the body and the headers are the same apart from the content type
+	req := r.Clone(r.Context())
+	req.ProtoMajor, req.ProtoMinor, req.Proto = 2, 0, "HTTP/2"
+	req.Header.Set("Content-Type", "application/grpc"+strings.TrimPrefix(strings.TrimPrefix(contentType, grpcWebTextContentType), grpcWebContentType))
+	req.Header.Del("Content-Length")
+	req.ContentLength = -1
+	if text {
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{Reader: base64.NewDecoder(base64.StdEncoding, r.Body), Closer: r.Body}
+	}
+	// the TLS state of the connections behind the muxer is read from the context, the peer of the call is then set
+	if state, ok := request.TLSConnectionStateFromContext(r.Context()); ok && req.TLS == nil {
+		req.TLS = state
+	}
+
+	resp := &grpcWebResponse{w: w, header: make(http.Header), contentType: contentType, text: text}
+	h.server.ServeHTTP(resp, req)
+	resp.finish()
+}
+
+// grpcWebResponse is the response writer of the gRPC server, the headers set once the response is started are the
+// trailers.
+type grpcWebResponse struct {
+	w           http.ResponseWriter
+	header      http.Header
+	contentType string
+	text        bool
+	wroteHeader bool
+}
+
+func (r *grpcWebResponse) Header() http.Header {
+	return r.header
+}
+
+func (r *grpcWebResponse) WriteHeader(code int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+
+	declared := r.declaredTrailers()
+	for key, values := range r.header {
+		if _, ok := declared[key]; ok || key == "Trailer" || strings.HasPrefix(key, http2.TrailerPrefix) {
+			continue
+		}
+		r.w.Header()[key] = values
+	}
+	r.w.Header().Set("Content-Type", r.contentType)
+	r.w.WriteHeader(code)
+}
+
+func (r *grpcWebResponse) Write(data []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	if r.text {
+		// every chunk is encoded on its own, the clients decode the padded chunks one after the other
+		if _, err := r.w.Write([]byte(base64.StdEncoding.EncodeToString(data))); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	return r.w.Write(data)
+}
+
+func (r *grpcWebResponse) Flush() {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	if f, ok := r.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *grpcWebResponse) declaredTrailers() map[string]struct{} {
+	declared := make(map[string]struct{})
+	for _, values := range r.header["Trailer"] {
+		for _, key := range strings.Split(values, ",") {
+			declared[http.CanonicalHeaderKey(strings.TrimSpace(key))] = struct{}{}
+		}
+	}
+	return declared
+}
+
+// finish writes the trailers of the gRPC response as the last frame of the body.
+func (r *grpcWebResponse) finish() {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+
+	declared := r.declaredTrailers()
+	var lines []string
+	for key, values := range r.header {
+		name := key
+		if strings.HasPrefix(key, http2.TrailerPrefix) {
+			name = strings.TrimPrefix(key, http2.TrailerPrefix)
+		} else if _, ok := declared[key]; !ok {
+			continue
+		}
+		for _, v := range values {
+			lines = append(lines, fmt.Sprintf("%s: %s\r\n", strings.ToLower(name), v))
+		}
+	}
+	sort.Strings(lines)
+
+	var trailers bytes.Buffer
+	for _, line := range lines {
+		trailers.WriteString(line)
+	}
+	frame := make([]byte, 5, 5+trailers.Len())
+	frame[0] = grpcWebTrailerFlag
+	binary.BigEndian.PutUint32(frame[1:], uint32(trailers.Len()))
+	frame = append(frame, trailers.Bytes()...)
+
+	_, _ = r.Write(frame)
+	r.Flush()
+}
@@ -0,0 +1,237 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package muxer
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/server/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func newGRPCWebTestServer(t *testing.T, corsCfg config.CORSConfig) *httptest.Server {
+	t.Helper()
+
+	cfg := config.DefaultConfig
+	cfg.Server.CORS = corsCfg
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+
+	s := NewHTTPServer(&cfg, nil, nil)
+	s.Router.Get("/ping", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("pong"))
+	})
+	s.EnableGRPCWeb(grpcServer)
+
+	srv := httptest.NewServer(s.handler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func grpcWebFrame(t *testing.T, msg proto.Message) []byte {
+	t.Helper()
+
+	data, err := proto.Marshal(msg)
+	require.NoError(t, err)
+	frame := make([]byte, 5, 5+len(data))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+	return append(frame, data...)
+}
+
+// readGRPCWebFrame returns the flag and the payload of the next frame of the body.
+func readGRPCWebFrame(t *testing.T, r io.Reader) (byte, []byte) {
+	t.Helper()
+
+	header := make([]byte, 5)
+	_, err := io.ReadFull(r, header)
+	require.NoError(t, err)
+	payload := make([]byte, binary.BigEndian.Uint32(header[1:]))
+	_, err = io.ReadFull(r, payload)
+	require.NoError(t, err)
+	return header[0], payload
+}
+
+func postGRPCWeb(t *testing.T, ctx context.Context, url string, contentType string, body []byte) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("X-Grpc-Web", "1")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestCORSPreflight(t *testing.T) {
+	srv := newGRPCWebTestServer(t, config.CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedHeaders:   []string{"Content-Type", "X-Grpc-Web", "Authorization"},
+		MaxAge:           10 * time.Minute,
+		AllowCredentials: true,
+	})
+
+	preflight := func(origin string, headers string) *http.Response {
+		req, err := http.NewRequest(http.MethodOptions, srv.URL+"/grpc.health.v1.Health/Check", nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", headers)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		return resp
+	}
+
+	resp := preflight("https://app.example.com", "content-type,x-grpc-web")
+	require.Equal(t, "https://app.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "POST", resp.Header.Get("Access-Control-Allow-Methods"))
+	require.Equal(t, "Content-Type, X-Grpc-Web", resp.Header.Get("Access-Control-Allow-Headers"))
+	require.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+	require.Equal(t, "600", resp.Header.Get("Access-Control-Max-Age"))
+
+	// the origins and the headers that are not allowed
+	require.Empty(t, preflight("https://other.example.com", "content-type").Header.Get("Access-Control-Allow-Origin"))
+	require.Empty(t, preflight("https://app.example.com", "x-other").Header.Get("Access-Control-Allow-Origin"))
+
+	// the actual requests carry the policy as well
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/ping", nil)
+	require.NoError(t, err)
+	req.Header.Set("Origin", "https://app.example.com")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "https://app.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+}
+
+func TestCORSDefaultConfig(t *testing.T) {
+	srv := newGRPCWebTestServer(t, config.DefaultConfig.Server.CORS)
+
+	req, err := http.NewRequest(http.MethodOptions, srv.URL+"/ping", nil)
+	require.NoError(t, err)
+	req.Header.Set("Origin", "https://any.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	req.Header.Set("Access-Control-Request-Headers", "x-anything")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, "*", resp.Header.Get("Access-Control-Allow-Origin"))
+	require.Empty(t, resp.Header.Get("Access-Control-Allow-Credentials"))
+	require.Empty(t, resp.Header.Get("Access-Control-Max-Age"))
+}
+
+func TestGRPCWeb(t *testing.T) {
+	srv := newGRPCWebTestServer(t, config.CORSConfig{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"*"}})
+	ctx := context.Background()
+
+	t.Run("unary", func(t *testing.T) {
+		resp := postGRPCWeb(t, ctx, srv.URL+"/grpc.health.v1.Health/Check", "application/grpc-web+proto",
+			grpcWebFrame(t, &healthpb.HealthCheckRequest{}))
+		defer func() { _ = resp.Body.Close() }()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "application/grpc-web+proto", resp.Header.Get("Content-Type"))
+		require.Equal(t, "*", resp.Header.Get("Access-Control-Allow-Origin"))
+		require.Equal(t, "Grpc-Status, Grpc-Message, Grpc-Status-Details-Bin", resp.Header.Get("Access-Control-Expose-Headers"))
+		require.Empty(t, resp.Header.Get("Grpc-Status"))
+
+		flag, payload := readGRPCWebFrame(t, resp.Body)
+		require.Equal(t, byte(0), flag)
+		var msg healthpb.HealthCheckResponse
+		require.NoError(t, proto.Unmarshal(payload, &msg))
+		require.Equal(t, healthpb.HealthCheckResponse_SERVING, msg.Status)
+
+		flag, payload = readGRPCWebFrame(t, resp.Body)
+		require.Equal(t, byte(grpcWebTrailerFlag), flag)
+		require.Equal(t, "grpc-status: 0\r\n", string(payload))
+	})
+
+	t.Run("text", func(t *testing.T) {
+		body := base64.StdEncoding.EncodeToString(grpcWebFrame(t, &healthpb.HealthCheckRequest{}))
+		resp := postGRPCWeb(t, ctx, srv.URL+"/grpc.health.v1.Health/Check", "application/grpc-web-text", []byte(body))
+		defer func() { _ = resp.Body.Close() }()
+		require.Equal(t, "application/grpc-web-text", resp.Header.Get("Content-Type"))
+
+		encoded, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		// the frames are encoded one by one, each of them is padded
+		var decoded []byte
+		for i := 0; i < len(encoded); i += 4 {
+			part, err := base64.StdEncoding.DecodeString(string(encoded[i : i+4]))
+			require.NoError(t, err)
+			decoded = append(decoded, part...)
+		}
+
+		r := bytes.NewReader(decoded)
+		flag, payload := readGRPCWebFrame(t, r)
+		require.Equal(t, byte(0), flag)
+		var msg healthpb.HealthCheckResponse
+		require.NoError(t, proto.Unmarshal(payload, &msg))
+		require.Equal(t, healthpb.HealthCheckResponse_SERVING, msg.Status)
+		flag, payload = readGRPCWebFrame(t, r)
+		require.Equal(t, byte(grpcWebTrailerFlag), flag)
+		require.Equal(t, "grpc-status: 0\r\n", string(payload))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		resp := postGRPCWeb(t, ctx, srv.URL+"/grpc.health.v1.Health/Check", "application/grpc-web",
+			grpcWebFrame(t, &healthpb.HealthCheckRequest{Service: "unknown"}))
+		defer func() { _ = resp.Body.Close() }()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		flag, payload := readGRPCWebFrame(t, resp.Body)
+		require.Equal(t, byte(grpcWebTrailerFlag), flag)
+		require.Equal(t, "grpc-message: unknown service\r\ngrpc-status: 5\r\n", string(payload))
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		resp := postGRPCWeb(t, ctx, srv.URL+"/grpc.health.v1.Health/Watch", "application/grpc-web+proto",
+			grpcWebFrame(t, &healthpb.HealthCheckRequest{}))
+		defer func() { _ = resp.Body.Close() }()
+
+		// the first message is received while the stream is still open
+		flag, payload := readGRPCWebFrame(t, resp.Body)
+		require.Equal(t, byte(0), flag)
+		var msg healthpb.HealthCheckResponse
+		require.NoError(t, proto.Unmarshal(payload, &msg))
+		require.Equal(t, healthpb.HealthCheckResponse_SERVING, msg.Status)
+	})
+
+	t.Run("http routes", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/ping")
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "pong", string(body))
+	})
+}
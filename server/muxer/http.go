@@ -15,6 +15,8 @@
 package muxer
 
 import (
+	"context"
+	"net"
 	"net/http"
 	"time"
 
@@ -25,7 +27,8 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/soheilhy/cmux"
 	"github.com/tigrisdata/tigris/server/config"
-	"github.com/tigrisdata/tigris/server/middleware"
+	"github.com/tigrisdata/tigris/server/request"
+	"google.golang.org/grpc"
 )
 
 const readHeaderTimeout = 5 * time.Second
@@ -33,29 +36,111 @@ const readHeaderTimeout = 5 * time.Second
 type HTTPServer struct {
 	Router chi.Router
 	Inproc *inprocgrpc.Channel
+
+	cfg *config.Config
+	srv *http.Server
+	// grpcWeb serves the gRPC-Web requests, they are routed to the router when it is not set
+	grpcWeb http.Handler
 }
 
-func NewHTTPServer(cfg *config.Config) *HTTPServer {
+// NewHTTPServer returns the HTTP server calling the services through the interceptors of the gRPC server.
+func NewHTTPServer(cfg *config.Config, unary grpc.UnaryServerInterceptor, stream grpc.StreamServerInterceptor) *HTTPServer {
 	r := chi.NewRouter()
+	r.Use(limitRequests(&cfg.Server.HTTP))
 
-	r.Use(cors.AllowAll().Handler)
 	r.Mount("/debug", chi_middleware.Profiler())
 
-	unary, stream := middleware.Get(cfg)
-
 	inproc := &inprocgrpc.Channel{}
 	inproc.WithServerStreamInterceptor(stream)
 	inproc.WithServerUnaryInterceptor(unary)
 
-	return &HTTPServer{Inproc: inproc, Router: r}
+	return &HTTPServer{Inproc: inproc, Router: r, cfg: cfg}
+}
+
+func (s *HTTPServer) newServer() *http.Server {
+	maxHeaderBytes := s.cfg.Server.MaxHeaderBytes
+	if maxHeaderBytes <= 0 {
+		maxHeaderBytes = http.DefaultMaxHeaderBytes
+	}
+
+	return &http.Server{
+		Handler:           s.handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+		MaxHeaderBytes:    maxHeaderBytes,
+		ConnContext:       connContext,
+	}
+}
+
+// EnableGRPCWeb serves the gRPC-Web requests of the browsers with the gRPC server.
+func (s *HTTPServer) EnableGRPCWeb(server *grpc.Server) {
+	s.grpcWeb = &grpcWebHandler{server: server}
+}
+
+// handler applies the CORS policy to the requests, both the gRPC-Web and the HTTP ones.
+func (s *HTTPServer) handler() http.Handler {
+	var h http.Handler = s.Router
+	if grpcWeb := s.grpcWeb; grpcWeb != nil {
+		h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isGRPCWebRequest(r) {
+				grpcWeb.ServeHTTP(w, r)
+				return
+			}
+			s.Router.ServeHTTP(w, r)
+		})
+	}
+
+	return newCORS(&s.cfg.Server.CORS).Handler(h)
+}
+
+// newCORS returns the CORS policy of the config. The gRPC-Web clients read the status of the calls from the headers of
+// the responses, these headers are always exposed.
+func newCORS(cfg *config.CORSConfig) *cors.Cors {
+	return cors.New(cors.Options{
+		AllowedOrigins: cfg.AllowedOrigins,
+		AllowedMethods: []string{
+			http.MethodHead,
+			http.MethodGet,
+			http.MethodPost,
+			http.MethodPut,
+			http.MethodPatch,
+			http.MethodDelete,
+		},
+		AllowedHeaders:   cfg.AllowedHeaders,
+		ExposedHeaders:   grpcWebExposedHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           int(cfg.MaxAge.Seconds()),
+	})
+}
+
+// connContext attaches the state of the TLS connection to the context of its requests, the server doesn't see the TLS
+// connections behind the connections of the muxer.
+func connContext(ctx context.Context, conn net.Conn) context.Context {
+	if state, ok := tlsConnectionState(conn); ok {
+		return request.WithTLSConnectionState(ctx, state)
+	}
+	return ctx
 }
 
 func (s *HTTPServer) Start(mux cmux.CMux) error {
 	match := mux.Match(cmux.HTTP1Fast())
+	s.srv = s.newServer()
 	go func() {
-		srv := &http.Server{Handler: s.Router, ReadHeaderTimeout: readHeaderTimeout}
-		err := srv.Serve(match)
-		log.Fatal().Err(err).Msg("start http server")
+		if err := s.srv.Serve(match); !isClosed(err) {
+			log.Fatal().Err(err).Msg("start http server")
+		}
 	}()
 	return nil
 }
+
+// Stop closes the idle connections and waits for the requests in flight, the connections still active once the
+// context is done are closed.
+func (s *HTTPServer) Stop(ctx context.Context) {
+	if s.srv == nil {
+		return
+	}
+
+	if err := s.srv.Shutdown(ctx); err != nil {
+		log.Warn().Err(err).Msg("closing the http connections still active")
+		_ = s.srv.Close()
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package muxer
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/server/config"
+)
+
+func TestHTTPServerMaxHeaderBytes(t *testing.T) {
+	cfg := config.DefaultConfig
+	srv := NewHTTPServer(&cfg, nil, nil).newServer()
+	require.Equal(t, config.DefaultConfig.Server.MaxHeaderBytes, srv.MaxHeaderBytes)
+	require.Equal(t, readHeaderTimeout, srv.ReadHeaderTimeout)
+
+	cfg.Server.MaxHeaderBytes = 64 * 1024
+	srv = NewHTTPServer(&cfg, nil, nil).newServer()
+	require.Equal(t, 64*1024, srv.MaxHeaderBytes)
+
+	cfg.Server.MaxHeaderBytes = 0
+	srv = NewHTTPServer(&cfg, nil, nil).newServer()
+	require.Equal(t, http.DefaultMaxHeaderBytes, srv.MaxHeaderBytes)
+}
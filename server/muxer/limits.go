@@ -0,0 +1,170 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package muxer
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/config"
+)
+
+// errBodyTooLarge is not retryable, the request fails again as long as its body is the same.
+var errBodyTooLarge = errors.NonRetryable(errors.ResourceExhausted("request body too large"))
+
+// isDocumentRoute returns true for the routes of the documents of the collections, like
+// "/v1/databases/{db}/collections/{collection}/documents/insert", and for the imports and the exports of the documents.
+func isDocumentRoute(path string) bool {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 0; i+2 < len(segments); i++ {
+		if segments[i] == "collections" && isDocumentSegment(segments[i+2]) {
+			return true
+		}
+	}
+	return false
+}
+
+func isDocumentSegment(segment string) bool {
+	return segment == "documents" || segment == "import" || segment == "export"
+}
+
+// limitRequests bounds the size of the bodies of the requests and how long they are handled. The requests with a
+// larger body are answered with a 413 and the context of the requests is cancelled once the timeout is reached, which
+// stops the transactions of the requests.
+func limitRequests(cfg *config.HTTPConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			maxBodySize, timeout := cfg.MaxBodySize, cfg.Timeout
+			if isDocumentRoute(r.URL.Path) {
+				maxBodySize, timeout = cfg.MaxDocumentBodySize, cfg.DocumentTimeout
+			}
+
+			if timeout > 0 {
+				ctx, cancel := context.WithTimeout(r.Context(), timeout)
+				defer cancel()
+				r = r.WithContext(ctx)
+			}
+			if maxBodySize <= 0 || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if r.ContentLength > maxBodySize {
+				writeBodyTooLarge(w, maxBodySize)
+				return
+			}
+
+			// the bodies without a length are only known to be too large once they are read, the handler fails then
+			// and its response is replaced
+			body := &limitedBody{ReadCloser: r.Body, remaining: maxBodySize}
+			lw := &bodyLimitWriter{ResponseWriter: w, body: body, limit: maxBodySize}
+			r.Body = body
+			next.ServeHTTP(lw, r)
+			if body.exceeded && !lw.replaced {
+				lw.replace()
+			}
+		})
+	}
+}
+
+func writeBodyTooLarge(w http.ResponseWriter, limit int64) {
+	err := errors.NonRetryable(errors.ResourceExhausted("request body exceeds the limit of %d bytes", limit))
+	data, merr := api.MarshalStatus(errors.WithRetryInfo(err).GRPCStatus().Proto())
+	if merr != nil {
+		log.Err(merr).Msg("failed to marshal the error")
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Connection", "close")
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	_, _ = w.Write(data)
+}
+
+// limitedBody fails the reads past the limit.
+type limitedBody struct {
+	io.ReadCloser
+
+	remaining int64
+	exceeded  bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, errBodyTooLarge
+	}
+	// one more byte than the limit is read to know if the body is larger
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		b.exceeded = true
+		n = int(b.remaining)
+		b.remaining = 0
+		return n, errBodyTooLarge
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// bodyLimitWriter replaces the response of a handler that read a body larger than the limit.
+type bodyLimitWriter struct {
+	http.ResponseWriter
+
+	body        *limitedBody
+	limit       int64
+	wroteHeader bool
+	replaced    bool
+}
+
+func (w *bodyLimitWriter) replace() {
+	w.replaced = true
+	writeBodyTooLarge(w.ResponseWriter, w.limit)
+}
+
+func (w *bodyLimitWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if w.body.exceeded {
+		w.replace()
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *bodyLimitWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.replaced {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyLimitWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && !w.replaced {
+		f.Flush()
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package muxer
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/server/config"
+)
+
+func TestIsDocumentRoute(t *testing.T) {
+	require.True(t, isDocumentRoute("/v1/databases/db1/collections/c1/documents/insert"))
+	require.True(t, isDocumentRoute("/admin/namespaces/ns1/databases/db1/collections/c1/import/stream"))
+	require.True(t, isDocumentRoute("/admin/namespaces/ns1/databases/db1/collections/c1/export"))
+	require.False(t, isDocumentRoute("/v1/databases/db1/collections/c1/createOrUpdate"))
+	require.False(t, isDocumentRoute("/v1/databases/db1/collections/documents"))
+	require.False(t, isDocumentRoute("/v1/databases/db1/create"))
+	require.False(t, isDocumentRoute("/"))
+}
+
+func TestLimitRequests(t *testing.T) {
+	cfg := &config.HTTPConfig{MaxBodySize: 8, MaxDocumentBodySize: 16, Timeout: time.Minute, DocumentTimeout: time.Hour}
+
+	var deadline time.Time
+	handler := limitRequests(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, _ = r.Context().Deadline()
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	serve := func(path string, body io.Reader) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, body))
+		return w
+	}
+	// the readers of the requests without a length, like the chunked ones
+	chunked := func(body string) io.Reader {
+		return io.MultiReader(strings.NewReader(body))
+	}
+
+	metadataPath, documentPath := "/v1/databases/db1/create", "/v1/databases/db1/collections/c1/documents/insert"
+
+	w := serve(metadataPath, strings.NewReader("12345678"))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "12345678", w.Body.String())
+	require.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+	w = serve(documentPath, strings.NewReader("1234567890"))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "1234567890", w.Body.String())
+	require.WithinDuration(t, time.Now().Add(time.Hour), deadline, time.Second)
+
+	for _, body := range []io.Reader{strings.NewReader("123456789"), chunked("123456789")} {
+		w = serve(metadataPath, body)
+		require.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		require.JSONEq(t, `{"error":{"code":"RESOURCE_EXHAUSTED","message":"request body exceeds the limit of 8 bytes"}}`, w.Body.String())
+	}
+
+	w = serve(documentPath, chunked(strings.Repeat("1", 16)))
+	require.Equal(t, http.StatusOK, w.Code)
+	w = serve(documentPath, chunked(strings.Repeat("1", 17)))
+	require.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	require.JSONEq(t, `{"error":{"code":"RESOURCE_EXHAUSTED","message":"request body exceeds the limit of 16 bytes"}}`, w.Body.String())
+
+	t.Run("timeout", func(t *testing.T) {
+		handler := limitRequests(&config.HTTPConfig{Timeout: 50 * time.Millisecond})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			http.Error(w, r.Context().Err().Error(), http.StatusGatewayTimeout)
+		}))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, metadataPath, nil))
+		require.Equal(t, http.StatusGatewayTimeout, w.Code)
+		require.Equal(t, "context deadline exceeded\n", w.Body.String())
+	})
+
+	t.Run("no limits", func(t *testing.T) {
+		handler := limitRequests(&config.HTTPConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, ok := r.Context().Deadline()
+			require.False(t, ok)
+			n, err := io.Copy(io.Discard, r.Body)
+			require.NoError(t, err)
+			require.Equal(t, int64(1<<20), n)
+		}))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, metadataPath, chunked(strings.Repeat("1", 1<<20))))
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+}
@@ -15,13 +15,21 @@
 package muxer
 
 import (
+	"context"
+	"crypto/tls"
+	goerrors "errors"
 	"fmt"
 	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/soheilhy/cmux"
 	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/middleware"
 	v1 "github.com/tigrisdata/tigris/server/services/v1"
 	"github.com/tigrisdata/tigris/server/transaction"
 	"github.com/tigrisdata/tigris/store/kv"
@@ -33,16 +41,58 @@ type Server interface {
 	Start(mux cmux.CMux) error
 }
 
+// stopper is a server that can be stopped gracefully, see Muxer.Stop.
+type stopper interface {
+	Stop(ctx context.Context)
+}
+
+const (
+	// MatcherHTTP matches the HTTP/1.x connections.
+	MatcherHTTP = "http"
+	// MatcherGRPC matches the gRPC connections.
+	MatcherGRPC = "grpc"
+)
+
+// defaultMatchers is the order the protocols missing from the configuration are matched in.
+var defaultMatchers = []string{MatcherHTTP, MatcherGRPC}
+
 type Muxer struct {
-	servers []Server
+	servers     map[string]Server
+	matchers    []string
+	readTimeout time.Duration
+	tls         *config.TLSConfig
+
+	mu       sync.Mutex
+	cm       cmux.CMux
+	stopping bool
+	stopped  chan struct{}
+
+	adminAuth *middleware.AdminAuth
 }
 
 func NewMuxer(cfg *config.Config) *Muxer {
-	return &Muxer{servers: []Server{NewHTTPServer(cfg), NewGRPCServer(cfg)}}
+	// the interceptors are created once, the concurrency limits are for all the requests of the server
+	unary, stream, adminAuth := middleware.Get(cfg)
+	httpServer, grpcServer := NewHTTPServer(cfg, unary, stream), NewGRPCServer(cfg, unary, stream)
+	if cfg.Server.GRPCWeb {
+		httpServer.EnableGRPCWeb(grpcServer.Server)
+	}
+
+	return &Muxer{
+		servers: map[string]Server{
+			MatcherHTTP: httpServer,
+			MatcherGRPC: grpcServer,
+		},
+		matchers:    cfg.Server.MuxMatchers,
+		readTimeout: cfg.Server.MuxReadTimeout,
+		tls:         &cfg.Server.TLS,
+		stopped:     make(chan struct{}),
+		adminAuth:   adminAuth,
+	}
 }
 
 func (m *Muxer) RegisterServices(kvStore kv.KeyValueStore, searchStore search.Store, tenantMgr *metadata.TenantManager, txMgr *transaction.Manager) {
-	services := v1.GetRegisteredServices(kvStore, searchStore, tenantMgr, txMgr)
+	services := v1.GetRegisteredServices(kvStore, searchStore, tenantMgr, txMgr, m.adminAuth)
 	for _, r := range services {
 		for _, v := range m.servers {
 			if s, ok := v.(*GRPCServer); ok {
@@ -61,15 +111,123 @@ func (m *Muxer) RegisterServices(kvStore kv.KeyValueStore, searchStore search.St
 func (m *Muxer) Start(host string, port int16) error {
 	log.Info().Int16("port", port).Msg("initializing server")
 
+	order, err := matcherOrder(m.matchers)
+	if err != nil {
+		return err
+	}
+
 	l, err := net.Listen("tcp", fmt.Sprintf("%s:%d", host, port))
 	if err != nil {
 		log.Fatal().Err(err).Msg("listening failed ")
 	}
+	if m.tls != nil && m.tls.Enabled {
+		reloader, err := newCertReloader(m.tls)
+		if err != nil {
+			return err
+		}
+		reloader.watch()
+		// the connections are matched on their decrypted bytes, both servers are served over TLS
+		l = tls.NewListener(l, reloader.tlsConfig())
+		log.Info().Str("client_auth", m.tls.ClientAuth).Msg("tls enabled")
+	}
 
-	cm := cmux.New(l)
+	return m.run(l, order)
+}
+
+// run serves the connections of the listener until the muxer is stopped.
+func (m *Muxer) run(l net.Listener, order []string) error {
+	cm := m.serve(l, order)
+	m.mu.Lock()
+	if m.stopping {
+		m.mu.Unlock()
+		cm.Close()
+		return nil
+	}
+	m.cm = cm
+	m.mu.Unlock()
+
+	log.Info().Strs("matchers", order).Msg("server started, servicing requests")
+	err := cm.Serve()
+
+	m.mu.Lock()
+	stopping := m.stopping
+	m.mu.Unlock()
+	if stopping {
+		// the listener is closed by the stop, which returns once the calls in flight are done
+		<-m.stopped
+		return nil
+	}
+	return err
+}
+
+// Stop stops accepting connections and waits for the calls in flight to finish, the calls that are still running once
+// the context is done are canceled. Start returns once the servers are stopped.
+func (m *Muxer) Stop(ctx context.Context) {
+	m.mu.Lock()
+	if m.stopping {
+		m.mu.Unlock()
+		return
+	}
+	m.stopping = true
+	cm := m.cm
+	m.mu.Unlock()
+	defer close(m.stopped)
+
+	if cm == nil {
+		return
+	}
+
+	var wg sync.WaitGroup
 	for _, s := range m.servers {
-		_ = s.Start(cm)
+		if st, ok := s.(stopper); ok {
+			wg.Add(1)
+			go func(st stopper) {
+				defer wg.Done()
+				st.Stop(ctx)
+			}(st)
+		}
+	}
+	wg.Wait()
+	cm.Close()
+}
+
+// isClosed returns true if a server stopped serving because the listener of the muxer is closed, either by a stop or
+// because it failed, in which case Start returns the error.
+func isClosed(err error) bool {
+	return err == nil || goerrors.Is(err, http.ErrServerClosed) || goerrors.Is(err, cmux.ErrServerClosed) ||
+		goerrors.Is(err, cmux.ErrListenerClosed) || goerrors.Is(err, net.ErrClosed)
+}
+
+// serve registers the matchers of the servers in order on the listener.
+func (m *Muxer) serve(l net.Listener, order []string) cmux.CMux {
+	cm := cmux.New(l)
+	cm.SetReadTimeout(m.readTimeout)
+	for _, name := range order {
+		_ = m.servers[name].Start(cm)
+	}
+	return cm
+}
+
+// matcherOrder returns the order of the configured matchers followed by the missing ones in their default order.
+func matcherOrder(configured []string) ([]string, error) {
+	seen := make(map[string]struct{}, len(defaultMatchers))
+	order := make([]string, 0, len(defaultMatchers))
+	for _, name := range configured {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != MatcherHTTP && name != MatcherGRPC {
+			return nil, fmt.Errorf("unknown mux matcher '%s'", name)
+		}
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("duplicate mux matcher '%s'", name)
+		}
+		seen[name] = struct{}{}
+		order = append(order, name)
+	}
+
+	for _, name := range defaultMatchers {
+		if _, ok := seen[name]; !ok {
+			order = append(order, name)
+		}
 	}
-	log.Info().Msg("server started, servicing requests")
-	return cm.Serve()
+	return order, nil
 }
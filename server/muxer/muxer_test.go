@@ -0,0 +1,188 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package muxer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/soheilhy/cmux"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/server/config"
+)
+
+// testServer answers the HTTP requests of the connections its matcher matches with its name.
+type testServer struct {
+	name    string
+	matcher cmux.Matcher
+}
+
+func (t *testServer) Start(mux cmux.CMux) error {
+	l := mux.Match(t.matcher)
+	go func() {
+		_ = http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(t.name))
+		}))
+	}()
+	return nil
+}
+
+func startTestMuxer(t *testing.T, m *Muxer, order []string) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	cm := m.serve(l, order)
+	go func() { _ = cm.Serve() }()
+	t.Cleanup(func() { _ = l.Close() })
+
+	return l.Addr().String()
+}
+
+func get(t *testing.T, addr string) string {
+	t.Helper()
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/", addr))
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestMatcherOrder(t *testing.T) {
+	order, err := matcherOrder(nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{MatcherHTTP, MatcherGRPC}, order)
+
+	order, err = matcherOrder([]string{" GRPC"})
+	require.NoError(t, err)
+	require.Equal(t, []string{MatcherGRPC, MatcherHTTP}, order)
+
+	order, err = matcherOrder([]string{"grpc", "http"})
+	require.NoError(t, err)
+	require.Equal(t, []string{MatcherGRPC, MatcherHTTP}, order)
+
+	_, err = matcherOrder([]string{"http", "h2c"})
+	require.EqualError(t, err, "unknown mux matcher 'h2c'")
+
+	_, err = matcherOrder([]string{"http", "http"})
+	require.EqualError(t, err, "duplicate mux matcher 'http'")
+}
+
+func TestMuxer(t *testing.T) {
+	t.Run("order", func(t *testing.T) {
+		m := &Muxer{servers: map[string]Server{
+			MatcherHTTP: &testServer{name: MatcherHTTP, matcher: cmux.Any()},
+			MatcherGRPC: &testServer{name: MatcherGRPC, matcher: cmux.Any()},
+		}}
+
+		// both servers match any connection, the first one in the order serves it
+		require.Equal(t, MatcherHTTP, get(t, startTestMuxer(t, m, []string{MatcherHTTP, MatcherGRPC})))
+		require.Equal(t, MatcherGRPC, get(t, startTestMuxer(t, m, []string{MatcherGRPC, MatcherHTTP})))
+	})
+
+	t.Run("read_timeout", func(t *testing.T) {
+		timeout := 200 * time.Millisecond
+		m := &Muxer{
+			servers:     map[string]Server{MatcherHTTP: &testServer{name: MatcherHTTP, matcher: cmux.HTTP1Fast()}},
+			readTimeout: timeout,
+		}
+		addr := startTestMuxer(t, m, []string{MatcherHTTP})
+
+		// a connection that never sends its first bytes is closed once the timeout is reached
+		conn, err := net.Dial("tcp", addr)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(10*timeout)))
+
+		start := time.Now()
+		_, err = conn.Read(make([]byte, 1))
+		require.Equal(t, io.EOF, err)
+		require.GreaterOrEqual(t, time.Since(start), timeout/2)
+		require.Less(t, time.Since(start), 5*timeout)
+
+		// the connections that match are not bound by the timeout once they are matched
+		conn, err = net.Dial("tcp", addr)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+		_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\n\r\n"))
+		require.NoError(t, err)
+		time.Sleep(2 * timeout)
+
+		reader := bufio.NewReader(conn)
+		for i := 0; i < 2; i++ {
+			resp, err := http.ReadResponse(reader, nil)
+			require.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, MatcherHTTP, string(body))
+
+			// the connection is kept alive past the timeout
+			time.Sleep(2 * timeout)
+			_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\n\r\n"))
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		cfg := config.DefaultConfig
+		httpServer := NewHTTPServer(&cfg, nil, nil)
+		inFlight, release := make(chan struct{}), make(chan struct{})
+		httpServer.Router.Get("/slow", func(w http.ResponseWriter, _ *http.Request) {
+			close(inFlight)
+			<-release
+			_, _ = w.Write([]byte("done"))
+		})
+		m := &Muxer{servers: map[string]Server{MatcherHTTP: httpServer}, stopped: make(chan struct{})}
+
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		served := make(chan error, 1)
+		go func() { served <- m.run(l, []string{MatcherHTTP}) }()
+
+		responses := make(chan string, 1)
+		go func() {
+			resp, err := http.Get(fmt.Sprintf("http://%s/slow", l.Addr().String()))
+			if err != nil {
+				responses <- err.Error()
+				return
+			}
+			defer func() { _ = resp.Body.Close() }()
+			body, _ := io.ReadAll(resp.Body)
+			responses <- string(body)
+		}()
+		<-inFlight
+
+		// the muxer keeps running until the request in flight is done
+		go m.Stop(context.Background())
+		select {
+		case err = <-served:
+			require.Fail(t, "stopped with a request in flight", err)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		close(release)
+		require.Equal(t, "done", <-responses)
+		require.NoError(t, <-served)
+	})
+}
@@ -0,0 +1,243 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package muxer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/soheilhy/cmux"
+	"github.com/tigrisdata/tigris/server/config"
+	"google.golang.org/grpc/credentials"
+)
+
+// certReloader serves the certificate of the server and verifies the client certificates with the CAs of the bundle.
+// They are reloaded when their files change or the server receives SIGHUP, the connections established before a
+// reload keep their certificates.
+type certReloader struct {
+	sync.RWMutex
+
+	cfg        *config.TLSConfig
+	clientAuth tls.ClientAuthType
+	cert       *tls.Certificate
+	clientCAs  *x509.CertPool
+	modTimes   []time.Time
+}
+
+func newCertReloader(cfg *config.TLSConfig) (*certReloader, error) {
+	clientAuth, err := clientAuthType(cfg.ClientAuth)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.CertFile) == 0 || len(cfg.KeyFile) == 0 {
+		return nil, fmt.Errorf("tls requires a cert_file and a key_file")
+	}
+	if clientAuth != tls.NoClientCert && len(cfg.ClientCAFile) == 0 {
+		return nil, fmt.Errorf("tls client auth '%s' requires a client_ca_file", cfg.ClientAuth)
+	}
+
+	r := &certReloader{cfg: cfg, clientAuth: clientAuth}
+	if err = r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func clientAuthType(mode string) (tls.ClientAuthType, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", config.ClientAuthNone:
+		return tls.NoClientCert, nil
+	case config.ClientAuthVerifyIfGiven:
+		return tls.VerifyClientCertIfGiven, nil
+	case config.ClientAuthRequire:
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return tls.NoClientCert, fmt.Errorf("unknown tls client auth '%s'", mode)
+	}
+}
+
+func (r *certReloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if len(r.cfg.ClientCAFile) > 0 {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
+
+func (r *certReloader) readModTimes() ([]time.Time, error) {
+	modTimes := make([]time.Time, 0, 3)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		modTimes = append(modTimes, info.ModTime())
+	}
+	return modTimes, nil
+}
+
+// reload loads the files, the previous certificates are kept if any of them can't be loaded.
+func (r *certReloader) reload() error {
+	modTimes, err := r.readModTimes()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return err
+	}
+
+	var clientCAs *x509.CertPool
+	if len(r.cfg.ClientCAFile) > 0 {
+		bundle, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(bundle) {
+			return fmt.Errorf("no certificate found in the client CA file '%s'", r.cfg.ClientCAFile)
+		}
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.cert, r.clientCAs, r.modTimes = &cert, clientCAs, modTimes
+	return nil
+}
+
+// reloadIfChanged reloads the files if any of them is modified since they were loaded.
+func (r *certReloader) reloadIfChanged() error {
+	modTimes, err := r.readModTimes()
+	if err != nil {
+		return err
+	}
+
+	r.RLock()
+	changed := len(modTimes) != len(r.modTimes)
+	for i := 0; !changed && i < len(modTimes); i++ {
+		changed = !modTimes[i].Equal(r.modTimes[i])
+	}
+	r.RUnlock()
+
+	if !changed {
+		return nil
+	}
+	return r.reload()
+}
+
+// watch reloads the files on SIGHUP and when they change, for the lifetime of the server.
+func (r *certReloader) watch() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP)
+
+	var ticks <-chan time.Time
+	if r.cfg.ReloadInterval > 0 {
+		ticks = time.NewTicker(r.cfg.ReloadInterval).C
+	}
+
+	go func() {
+		for {
+			var err error
+			select {
+			case <-sigs:
+				err = r.reload()
+			case <-ticks:
+				err = r.reloadIfChanged()
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to reload the tls certificates, the previous ones are kept")
+			}
+		}
+	}()
+}
+
+// tlsConfig returns the config of the listener, every handshake uses the certificates loaded last.
+func (r *certReloader) tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.RLock()
+			defer r.RUnlock()
+
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+				ClientAuth:   r.clientAuth,
+				ClientCAs:    r.clientCAs,
+				// the server preference wins, so that the HTTP clients also offering h2 negotiate HTTP/1.1 and are
+				// matched by the HTTP matcher. The gRPC clients only offer h2.
+				NextProtos: []string{"http/1.1", "h2"},
+			}, nil
+		},
+	}
+}
+
+// tlsConnectionState returns the state of the TLS connection matched by the muxer. The handshake is done when the
+// muxer reads the first bytes of the connection to match it.
+func tlsConnectionState(conn net.Conn) (*tls.ConnectionState, bool) {
+	if muxConn, ok := conn.(*cmux.MuxConn); ok {
+		conn = muxConn.Conn
+	}
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return nil, false
+	}
+
+	state := tlsConn.ConnectionState()
+	return &state, true
+}
+
+// muxTLSCredentials exposes the state of the TLS connections to the gRPC server, the TLS is terminated by the listener
+// of the muxer.
+type muxTLSCredentials struct{}
+
+func (muxTLSCredentials) ClientHandshake(_ context.Context, _ string, _ net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, fmt.Errorf("the muxer tls credentials are only used by the server")
+}
+
+func (muxTLSCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	state, ok := tlsConnectionState(conn)
+	if !ok {
+		return nil, nil, fmt.Errorf("not a tls connection")
+	}
+
+	return conn, credentials.TLSInfo{
+		State:          *state,
+		CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.PrivacyAndIntegrity},
+	}, nil
+}
+
+func (muxTLSCredentials) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: "tls"}
+}
+
+func (c muxTLSCredentials) Clone() credentials.TransportCredentials {
+	return c
+}
+
+func (muxTLSCredentials) OverrideServerName(string) error {
+	return nil
+}
@@ -0,0 +1,293 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package muxer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/soheilhy/cmux"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/request"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the certificate and the key, PEM encoded, of the subject signed by the CA.
+func (ca *testCA) issue(t *testing.T, serial int64, commonName string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"tigris"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func writeFile(t *testing.T, dir string, name string, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+// newTestTLSConfig writes the server certificate and the CA bundle, and returns the config using them.
+func newTestTLSConfig(t *testing.T, ca *testCA, clientAuth string) *config.TLSConfig {
+	t.Helper()
+
+	dir := t.TempDir()
+	cert, key := ca.issue(t, 2, "server", x509.ExtKeyUsageServerAuth)
+	return &config.TLSConfig{
+		Enabled:      true,
+		CertFile:     writeFile(t, dir, "server.crt", cert),
+		KeyFile:      writeFile(t, dir, "server.key", key),
+		ClientCAFile: writeFile(t, dir, "ca.crt", ca.pem),
+		ClientAuth:   clientAuth,
+	}
+}
+
+func servedCertificate(t *testing.T, r *certReloader) *x509.Certificate {
+	t.Helper()
+
+	cfg, err := r.tlsConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+	require.NoError(t, err)
+	return cert
+}
+
+func TestCertReloader(t *testing.T) {
+	ca := newTestCA(t)
+
+	t.Run("config", func(t *testing.T) {
+		cfg := newTestTLSConfig(t, ca, config.ClientAuthRequire)
+		r, err := newCertReloader(cfg)
+		require.NoError(t, err)
+		require.Equal(t, tls.RequireAndVerifyClientCert, r.clientAuth)
+		require.NotNil(t, r.clientCAs)
+
+		cfg.ClientAuth = "optional"
+		_, err = newCertReloader(cfg)
+		require.EqualError(t, err, "unknown tls client auth 'optional'")
+
+		cfg.ClientAuth, cfg.ClientCAFile = config.ClientAuthVerifyIfGiven, ""
+		_, err = newCertReloader(cfg)
+		require.EqualError(t, err, "tls client auth 'verify-if-given' requires a client_ca_file")
+
+		cfg.ClientAuth = config.ClientAuthNone
+		r, err = newCertReloader(cfg)
+		require.NoError(t, err)
+		require.Equal(t, tls.NoClientCert, r.clientAuth)
+		require.Nil(t, r.clientCAs)
+
+		cfg.KeyFile = ""
+		_, err = newCertReloader(cfg)
+		require.EqualError(t, err, "tls requires a cert_file and a key_file")
+
+		cfg.KeyFile = filepath.Join(t.TempDir(), "missing.key")
+		_, err = newCertReloader(cfg)
+		require.Error(t, err)
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		cfg := newTestTLSConfig(t, ca, config.ClientAuthNone)
+		r, err := newCertReloader(cfg)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), servedCertificate(t, r).SerialNumber.Int64())
+
+		// nothing changed
+		require.NoError(t, r.reloadIfChanged())
+		require.Equal(t, int64(2), servedCertificate(t, r).SerialNumber.Int64())
+
+		cert, key := ca.issue(t, 3, "server", x509.ExtKeyUsageServerAuth)
+		require.NoError(t, os.WriteFile(cfg.CertFile, cert, 0o600))
+		require.NoError(t, os.WriteFile(cfg.KeyFile, key, 0o600))
+		later := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(cfg.CertFile, later, later))
+		require.NoError(t, r.reloadIfChanged())
+		require.Equal(t, int64(3), servedCertificate(t, r).SerialNumber.Int64())
+
+		// an invalid certificate is not loaded, the previous one is kept
+		require.NoError(t, os.WriteFile(cfg.CertFile, []byte("invalid"), 0o600))
+		require.Error(t, r.reload())
+		require.Equal(t, int64(3), servedCertificate(t, r).SerialNumber.Int64())
+	})
+}
+
+// subjectServer serves the HTTP requests with the subject of the client certificate of their connection, and the gRPC
+// health checks.
+type subjectServer struct {
+	grpcSubjects chan string
+}
+
+func (s *subjectServer) Start(mux cmux.CMux) error {
+	grpcL := mux.MatchWithWriters(cmux.HTTP2MatchHeaderFieldSendSettings("content-type", "application/grpc"))
+	httpL := mux.Match(cmux.HTTP1Fast())
+
+	srv := grpc.NewServer(grpc.Creds(muxTLSCredentials{}), grpc.UnaryInterceptor(
+		func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			p, _ := peer.FromContext(ctx)
+			info := p.AuthInfo.(credentials.TLSInfo)
+			s.grpcSubjects <- info.State.PeerCertificates[0].Subject.CommonName
+			return handler(ctx, req)
+		}))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go func() { _ = srv.Serve(grpcL) }()
+
+	go func() {
+		_ = (&http.Server{
+			ReadHeaderTimeout: readHeaderTimeout,
+			ConnContext:       connContext,
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				state, ok := request.TLSConnectionStateFromContext(r.Context())
+				if !ok || len(state.PeerCertificates) == 0 {
+					_, _ = w.Write([]byte("none"))
+					return
+				}
+				_, _ = w.Write([]byte(state.PeerCertificates[0].Subject.CommonName))
+			}),
+		}).Serve(httpL)
+	}()
+	return nil
+}
+
+func TestTLSMuxer(t *testing.T) {
+	ca := newTestCA(t)
+	r, err := newCertReloader(newTestTLSConfig(t, ca, config.ClientAuthVerifyIfGiven))
+	require.NoError(t, err)
+
+	server := &subjectServer{grpcSubjects: make(chan string, 1)}
+	m := &Muxer{servers: map[string]Server{MatcherHTTP: server}}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	cm := m.serve(tls.NewListener(l, r.tlsConfig()), []string{MatcherHTTP})
+	go func() { _ = cm.Serve() }()
+	t.Cleanup(func() { _ = l.Close() })
+	addr := l.Addr().String()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	certPEM, keyPEM := ca.issue(t, 10, "client-1", x509.ExtKeyUsageClientAuth)
+	clientCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	httpGet := func(clientCfg *tls.Config) (string, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientCfg, ForceAttemptHTTP2: true}}
+		resp, err := client.Get("https://" + addr + "/")
+		if err != nil {
+			return "", err
+		}
+		defer func() { _ = resp.Body.Close() }()
+		require.Equal(t, "HTTP/1.1", resp.Proto)
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	t.Run("http", func(t *testing.T) {
+		// the HTTP clients offering h2 are served over HTTP/1.1
+		subject, err := httpGet(&tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12})
+		require.NoError(t, err)
+		require.Equal(t, "none", subject)
+
+		subject, err = httpGet(&tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{clientCert}})
+		require.NoError(t, err)
+		require.Equal(t, "client-1", subject)
+
+		// the certificates not issued by the CA are rejected
+		otherCA := newTestCA(t)
+		certPEM, keyPEM := otherCA.issue(t, 11, "client-2", x509.ExtKeyUsageClientAuth)
+		otherCert, err := tls.X509KeyPair(certPEM, keyPEM)
+		require.NoError(t, err)
+		_, err = httpGet(&tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{otherCert}})
+		require.Error(t, err)
+	})
+
+	t.Run("grpc", func(t *testing.T) {
+		conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+			RootCAs:      roots,
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{clientCert},
+		})))
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+		require.NoError(t, err)
+		require.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+		require.Equal(t, "client-1", <-server.grpcSubjects)
+	})
+}
@@ -18,19 +18,53 @@ import (
 	"context"
 
 	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/metadata"
 	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/tigrisdata/tigris/server/transaction"
 )
 
 var (
-	ErrReadUnitsExceeded      = errors.ResourceExhausted("request read rate exceeded")
-	ErrWriteUnitsExceeded     = errors.ResourceExhausted("request write rate exceeded")
-	ErrStorageSizeExceeded    = errors.ResourceExhausted("data size limit exceeded")
-	ErrMaxRequestSizeExceeded = errors.ResourceExhausted("maximum request size limit exceeded")
+	ErrReadUnitsExceeded   = errors.ResourceExhausted("request read rate exceeded").WithSubsystem(errors.SubsystemQuota)
+	ErrWriteUnitsExceeded  = errors.ResourceExhausted("request write rate exceeded").WithSubsystem(errors.SubsystemQuota)
+	ErrStorageSizeExceeded = errors.ResourceExhausted("data size limit exceeded").WithSubsystem(errors.SubsystemQuota)
+	// ErrMaxRequestSizeExceeded is not retryable, the request fails again whatever the usage of the namespace.
+	ErrMaxRequestSizeExceeded = errors.NonRetryable(
+		errors.ResourceExhausted("maximum request size limit exceeded").WithSubsystem(errors.SubsystemQuota))
 )
 
+// StorageSizeExceededError is ErrStorageSizeExceeded with the data size of the namespace and its limit.
+type StorageSizeExceededError struct {
+	*api.TigrisError
+
+	Usage int64
+	Limit int64
+}
+
+func newStorageSizeExceededError(usage int64, limit int64) *StorageSizeExceededError {
+	return &StorageSizeExceededError{
+		TigrisError: errors.ResourceExhausted("data size limit exceeded, the namespace is using %d bytes of its %d bytes limit",
+			usage, limit).WithSubsystem(errors.SubsystemQuota),
+		Usage: usage,
+		Limit: limit,
+	}
+}
+
+func (e *StorageSizeExceededError) Is(target error) bool {
+	return target == ErrStorageSizeExceeded
+}
+
+func (e *StorageSizeExceededError) Unwrap() error {
+	return e.TigrisError
+}
+
+// ErrorRetryable returns false, the writes fail until the data size of the namespace is reduced or its quota is raised.
+func (e *StorageSizeExceededError) ErrorRetryable() bool {
+	return false
+}
+
 type Quota interface {
 	Allow(ctx context.Context, namespace string, size int, isWrite bool) error
 	Wait(ctx context.Context, namespace string, size int, isWrite bool) error
@@ -60,13 +94,19 @@ func (s *State) Wait(ctx context.Context, size int, isWrite bool) error {
 
 type Manager struct {
 	quota []Quota
+
+	// storage is the storage quota, it is nil if neither the storage quota nor the size metrics are enabled.
+	storage *storage
 }
 
 var mgr Manager
 
 // this is extracted from Init for tests.
-func initManager(tm *metadata.TenantManager, cfg *config.Config) *Manager {
-	var q []Quota
+func initManager(tm *metadata.TenantManager, txMgr *transaction.Manager, cfg *config.Config) *Manager {
+	var (
+		q  []Quota
+		st *storage
+	)
 
 	if cfg.Quota.ReadUnitSize != 0 {
 		config.ReadUnitSize = cfg.Quota.ReadUnitSize
@@ -81,7 +121,8 @@ func initManager(tm *metadata.TenantManager, cfg *config.Config) *Manager {
 	// metrics calculation is piggybacked to storage quota, so initialize
 	// storage quota manager even when quota is disabled, but metrics are enabled
 	if cfg.Quota.Storage.Enabled || cfg.Metrics.Size.Enabled {
-		q = append(q, initStorage(tm, &cfg.Quota))
+		st = initStorage(tm, txMgr, &cfg.Quota)
+		q = append(q, st)
 	}
 
 	if cfg.Quota.Node.Enabled {
@@ -100,11 +141,11 @@ func initManager(tm *metadata.TenantManager, cfg *config.Config) *Manager {
 		}
 	}
 
-	return &Manager{quota: q}
+	return &Manager{quota: q, storage: st}
 }
 
-func Init(tm *metadata.TenantManager, cfg *config.Config) error {
-	mgr = *initManager(tm, cfg)
+func Init(tm *metadata.TenantManager, txMgr *transaction.Manager, cfg *config.Config) error {
+	mgr = *initManager(tm, txMgr, cfg)
 
 	return nil
 }
@@ -170,3 +211,21 @@ func Wait(ctx context.Context, namespace string, size int, isWrite bool) error {
 
 	return nil
 }
+
+// StorageUsage returns the data size of the namespace and its limit, ok is false if the storage quota is disabled.
+func StorageUsage(namespace string) (usage int64, limit int64, ok bool) {
+	if mgr.storage == nil || !mgr.storage.cfg.Storage.Enabled {
+		return 0, 0, false
+	}
+
+	ss := mgr.storage.getState(namespace)
+	return ss.Size.Load(), mgr.storage.limit(namespace, ss), true
+}
+
+// SetStorageQuota applies the storage quota set for the namespace with the admin API on this node, nil removes it.
+// The other nodes apply it once they refresh the data size of the namespace.
+func SetStorageQuota(namespace string, q *StorageQuota) {
+	if mgr.storage != nil {
+		mgr.storage.setQuota(namespace, q)
+	}
+}
@@ -16,6 +16,7 @@ package quota
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"os"
@@ -64,7 +65,7 @@ func TestQuota(t *testing.T) {
 		  "primary_key": ["K1", "K2"]
 	    }`)
 
-	factory, err := schema.Build("test_collection", jsSchema)
+	factory, err := schema.Build("test_collection", jsSchema, false)
 	require.NoError(t, err)
 
 	err = tenant.Reload(ctx, tx, []byte("aaa"))
@@ -81,7 +82,7 @@ func TestQuota(t *testing.T) {
 	table, err := metadata.NewEncoder().EncodeTableName(tenant.GetNamespace(), db1, coll1)
 	require.NoError(t, err)
 
-	err = Init(tenants, &config.Config{
+	err = Init(tenants, txMgr, &config.Config{
 		Quota: config.QuotaConfig{
 			Namespace: config.NamespaceLimitsConfig{
 				Enabled: true,
@@ -123,9 +124,9 @@ func TestQuota(t *testing.T) {
 
 	time.Sleep(100 * time.Millisecond)
 
-	require.Equal(t, ErrStorageSizeExceeded, Allow(ctx, ns, 1, true))
+	require.ErrorIs(t, Allow(ctx, ns, 1, true), ErrStorageSizeExceeded)
 	require.NoError(t, Allow(ctx, ns, 0, false))
-	require.Equal(t, ErrStorageSizeExceeded, Wait(ctx, ns, 1, true))
+	require.ErrorIs(t, Wait(ctx, ns, 1, true), ErrStorageSizeExceeded)
 	require.NoError(t, Wait(ctx, ns, 0, false))
 
 	i := 0
@@ -135,7 +136,7 @@ func TestQuota(t *testing.T) {
 	assert.Equal(t, 10, i)
 
 	i = 0
-	for ; err != ErrWriteUnitsExceeded && err != ErrStorageSizeExceeded && i < 10; i++ {
+	for ; err != ErrWriteUnitsExceeded && !errors.Is(err, ErrStorageSizeExceeded) && i < 10; i++ {
 		err = Allow(ctx, ns, 512, true) // < 1024 = 1 unit
 	}
 	assert.Equal(t, 1, i)
@@ -26,6 +26,7 @@ import (
 	"github.com/tigrisdata/tigris/server/metadata"
 	"github.com/tigrisdata/tigris/server/metrics"
 	"github.com/tigrisdata/tigris/server/request"
+	"github.com/tigrisdata/tigris/server/transaction"
 	ulog "github.com/tigrisdata/tigris/util/log"
 	"go.uber.org/atomic"
 )
@@ -34,6 +35,8 @@ type storage struct {
 	tenantQuota sync.Map
 	cfg         *config.QuotaConfig
 	tenantMgr   *metadata.TenantManager
+	txMgr       *transaction.Manager
+	store       *Store
 
 	wg     sync.WaitGroup
 	ctx    context.Context
@@ -41,15 +44,25 @@ type storage struct {
 }
 
 type storageState struct {
+	// Size is the data size of the namespace, it is updated by the committed writes and reconciled with the size of
+	// the ranges of the namespace every refresh interval.
 	Size atomic.Int64
+	// Quota is the storage quota set for the namespace with the admin API, it is zero if none is set.
+	Quota atomic.Int64
 }
 
+// skipStorageCheck returns true for the requests that don't grow the data, the deletes and the DDL are always allowed
+// so that the namespaces over their quota can get back under it.
 func skipStorageCheck(name string) bool {
 	switch name {
 	case api.DropCollectionMethodName, api.DropDatabaseMethodName:
 		return true
+	case api.CreateOrUpdateCollectionMethodName, api.CreateDatabaseMethodName:
+		return true
 	case api.DeleteMethodName:
 		return true
+	case api.BeginTransactionMethodName, api.CommitTransactionMethodName, api.RollbackTransactionMethodName:
+		return true
 	}
 
 	return false
@@ -60,10 +73,10 @@ func (s *storage) Allow(ctx context.Context, namespace string, size int, isWrite
 
 	var method string
 	if md, _ := request.GetRequestMetadataFromContext(ctx); md != nil {
-		method = md.GetMethodName()
+		method = md.GetFullMethod()
 	}
 
-	if !isWrite || skipStorageCheck(method) {
+	if !s.cfg.Storage.Enabled || !isWrite || skipStorageCheck(method) {
 		return nil
 	}
 
@@ -73,7 +86,7 @@ func (s *storage) Allow(ctx context.Context, namespace string, size int, isWrite
 func (s *storage) Wait(_ context.Context, namespace string, size int, isWrite bool) error {
 	l := s.getState(namespace)
 
-	if !isWrite {
+	if !s.cfg.Storage.Enabled || !isWrite {
 		return nil
 	}
 
@@ -93,26 +106,78 @@ func (s *storage) getState(namespace string) *storageState {
 
 func (s *storage) checkStorage(namespace string, ss *storageState, size int) error {
 	sz := ss.Size.Load()
+	limit := s.limit(namespace, ss)
 
-	sizeLimit := s.cfg.Storage.DataSizeLimit
-	nsLimit, ok := s.cfg.Storage.Namespaces[namespace]
-	if ok {
-		sizeLimit = nsLimit.Size
+	if sz+int64(size) >= limit {
+		return newStorageSizeExceededError(sz, limit)
 	}
 
-	if sz+int64(size) >= sizeLimit {
-		return ErrStorageSizeExceeded
+	return nil
+}
+
+// limit returns the quota set for the namespace, the namespaces without a quota have the limits of the config.
+func (s *storage) limit(namespace string, ss *storageState) int64 {
+	if q := ss.Quota.Load(); q > 0 {
+		return q
 	}
 
-	return nil
+	return s.cfg.Storage.NamespaceLimits(namespace)
+}
+
+// add applies the change of the data size of the namespace by the committed writes, the size is reconciled with the
+// size of the ranges of the namespace by the refresh loop.
+func (s *storage) add(namespace string, delta int64) {
+	if !s.cfg.Storage.Enabled || delta == 0 {
+		return
+	}
+
+	ss := s.getState(namespace)
+	if ss.Size.Add(delta) < 0 {
+		ss.Size.Store(0)
+	}
+}
+
+func (s *storage) setQuota(namespace string, q *StorageQuota) {
+	ss := s.getState(namespace)
+	if q == nil {
+		ss.Quota.Store(0)
+	} else {
+		ss.Quota.Store(q.Size)
+	}
+}
+
+// readQuota reads the quota set for the namespace, it is kept as it is if it can't be read.
+func (s *storage) readQuota(ctx context.Context, namespace string, namespaceId uint32) {
+	if s.txMgr == nil {
+		return
+	}
+
+	tx, err := s.txMgr.StartTx(ctx)
+	if ulog.E(err) {
+		return
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q, err := s.store.GetStorageQuota(ctx, tx, namespaceId)
+	if ulog.E(err) {
+		return
+	}
+	s.setQuota(namespace, q)
 }
 
-func initStorage(tm *metadata.TenantManager, cfg *config.QuotaConfig) *storage {
+func initStorage(tm *metadata.TenantManager, txMgr *transaction.Manager, cfg *config.QuotaConfig) *storage {
 	log.Debug().Msg("Initializing storage quota manager")
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	s := &storage{tenantMgr: tm, ctx: ctx, cancel: cancel, cfg: cfg}
+	s := &storage{
+		tenantMgr: tm,
+		txMgr:     txMgr,
+		store:     NewStore(metadata.NewReservedNamespaceStore(&metadata.DefaultMDNameRegistry{})),
+		ctx:       ctx,
+		cancel:    cancel,
+		cfg:       cfg,
+	}
 
 	s.wg.Add(1)
 
@@ -183,6 +248,9 @@ func (s *storage) updateMetricsForNamespace(ctx context.Context, namespace strin
 	if s.cfg.Storage.Enabled {
 		ss := s.getState(namespace)
 		ss.Size.Store(dsz)
+		s.readQuota(ctx, namespace, tenant.GetNamespace().Id())
+
+		metrics.UpdateQuotaStorage(namespace, dsz, s.limit(namespace, ss))
 	}
 }
 
@@ -217,3 +285,62 @@ func (s *storage) refreshLoop() {
 		}
 	}
 }
+
+type storageCtxKey struct{}
+
+// pendingStorage is the change of the data size of a namespace by the writes of a transaction, it is only applied once
+// the transaction is committed.
+type pendingStorage struct {
+	sync.Mutex
+
+	namespace string
+	delta     int64
+}
+
+// WithPendingStorage attaches the pending change of the data size of a transaction started by the namespace to the
+// context.
+func WithPendingStorage(ctx context.Context, namespace string) context.Context {
+	return context.WithValue(ctx, storageCtxKey{}, &pendingStorage{namespace: namespace})
+}
+
+// RecordStorage records the change of the data size of the namespace by a write. The changes done in a transaction
+// with a pending change are applied when the transaction is committed.
+func RecordStorage(ctx context.Context, namespace string, delta int64) {
+	if pending, ok := ctx.Value(storageCtxKey{}).(*pendingStorage); ok {
+		pending.Lock()
+		pending.delta += delta
+		pending.Unlock()
+		return
+	}
+	addStorage(namespace, delta)
+}
+
+// CommitPendingStorage applies the change of the data size by the committed transaction of the context.
+func CommitPendingStorage(ctx context.Context) {
+	pending, ok := ctx.Value(storageCtxKey{}).(*pendingStorage)
+	if !ok {
+		return
+	}
+
+	pending.Lock()
+	delta := pending.delta
+	pending.delta = 0
+	pending.Unlock()
+
+	addStorage(pending.namespace, delta)
+}
+
+// DiscardPendingStorage drops the change of the data size by the rolled back transaction of the context.
+func DiscardPendingStorage(ctx context.Context) {
+	if pending, ok := ctx.Value(storageCtxKey{}).(*pendingStorage); ok {
+		pending.Lock()
+		pending.delta = 0
+		pending.Unlock()
+	}
+}
+
+func addStorage(namespace string, delta int64) {
+	if mgr.storage != nil {
+		mgr.storage.add(namespace, delta)
+	}
+}
@@ -22,10 +22,12 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/internal"
 	"github.com/tigrisdata/tigris/schema"
 	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/request"
 	"github.com/tigrisdata/tigris/server/transaction"
 	"github.com/tigrisdata/tigris/store/kv"
 )
@@ -58,7 +60,7 @@ func TestStorageQuota(t *testing.T) {
 		  "primary_key": ["K1", "K2"]
 	    }`)
 
-	factory, err := schema.Build("test_collection", jsSchema)
+	factory, err := schema.Build("test_collection", jsSchema, false)
 	require.NoError(t, err)
 
 	err = tenant.Reload(ctx, tx, []byte("aaa"))
@@ -75,7 +77,7 @@ func TestStorageQuota(t *testing.T) {
 	table, err := metadata.NewEncoder().EncodeTableName(tenant.GetNamespace(), db1, coll1)
 	require.NoError(t, err)
 
-	m := initStorage(tenants, &config.QuotaConfig{
+	m := initStorage(tenants, txMgr, &config.QuotaConfig{
 		Storage: config.StorageLimitsConfig{
 			Enabled:         true,
 			RefreshInterval: 50 * time.Millisecond,
@@ -96,11 +98,119 @@ func TestStorageQuota(t *testing.T) {
 
 	time.Sleep(100 * time.Millisecond)
 
-	require.Equal(t, ErrStorageSizeExceeded, m.Allow(ctx, ns, 0, true))
+	require.ErrorIs(t, m.Allow(ctx, ns, 0, true), ErrStorageSizeExceeded)
 	require.NoError(t, m.Allow(ctx, ns, 0, false))
-	require.Equal(t, ErrStorageSizeExceeded, m.Wait(ctx, ns, 0, true))
+	require.ErrorIs(t, m.Wait(ctx, ns, 0, true), ErrStorageSizeExceeded)
 	require.NoError(t, m.Wait(ctx, ns, 0, false))
 
+	// the quota set for the namespace is read by the refresh loop
+	store := NewStore(metadata.NewReservedNamespaceStore(&metadata.DefaultMDNameRegistry{}))
+	tx, err = txMgr.StartTx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, store.SetStorageQuota(ctx, tx, id, &StorageQuota{Size: 1024 * 1024}))
+	require.NoError(t, tx.Commit(ctx))
+
+	time.Sleep(100 * time.Millisecond)
+
+	require.NoError(t, m.Allow(ctx, ns, 0, true))
+
+	tx, err = txMgr.StartTx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, store.DeleteStorageQuota(ctx, tx, id))
+	require.NoError(t, tx.Commit(ctx))
+
 	m.Cleanup()
 	require.NoError(t, kvStore.DropTable(ctx, table))
 }
+
+func withMethod(ctx context.Context, method string) context.Context {
+	md := request.GetGrpcEndPointMetadataFromFullMethod(ctx, method, "unary")
+	return md.SaveToContext(ctx)
+}
+
+func TestStorageQuotaLimits(t *testing.T) {
+	s := &storage{cfg: &config.QuotaConfig{
+		Storage: config.StorageLimitsConfig{
+			Enabled:       true,
+			DataSizeLimit: 100,
+			Namespaces:    map[string]config.NamespaceStorageLimitsConfig{"ns2": {Size: 200}},
+		},
+	}}
+	ctx := withMethod(context.Background(), api.InsertMethodName)
+
+	// the namespaces without a quota have the limits of the config
+	s.add("ns1", 90)
+	s.add("ns2", 150)
+	require.NoError(t, s.Allow(ctx, "ns1", 5, true))
+	require.NoError(t, s.Allow(ctx, "ns2", 10, true))
+
+	err := s.Allow(ctx, "ns1", 10, true)
+	require.ErrorIs(t, err, ErrStorageSizeExceeded)
+	var sizeErr *StorageSizeExceededError
+	require.ErrorAs(t, err, &sizeErr)
+	require.Equal(t, int64(90), sizeErr.Usage)
+	require.Equal(t, int64(100), sizeErr.Limit)
+	require.Equal(t, api.Code_RESOURCE_EXHAUSTED, sizeErr.Code)
+	require.Equal(t, "data size limit exceeded, the namespace is using 90 bytes of its 100 bytes limit", err.Error())
+
+	// the quota set for the namespace overrides the config
+	s.setQuota("ns1", &StorageQuota{Size: 1000})
+	s.setQuota("ns2", &StorageQuota{Size: 100})
+	require.NoError(t, s.Allow(ctx, "ns1", 10, true))
+	require.ErrorIs(t, s.Allow(ctx, "ns2", 10, true), ErrStorageSizeExceeded)
+	s.setQuota("ns2", nil)
+	require.NoError(t, s.Allow(ctx, "ns2", 10, true))
+
+	// the deletes and the DDL are allowed over the quota
+	s.add("ns1", 1000)
+	for _, method := range []string{api.InsertMethodName, api.ReplaceMethodName, api.UpdateMethodName} {
+		require.ErrorIs(t, s.Allow(withMethod(ctx, method), "ns1", 1, true), ErrStorageSizeExceeded)
+	}
+	for _, method := range []string{api.DeleteMethodName, api.DropCollectionMethodName, api.CreateOrUpdateCollectionMethodName} {
+		require.NoError(t, s.Allow(withMethod(ctx, method), "ns1", 1, true))
+	}
+
+	// the usage doesn't go below zero
+	s.add("ns1", -5000)
+	require.Equal(t, int64(0), s.getState("ns1").Size.Load())
+
+	// nothing is limited once the storage quota is disabled
+	s.cfg.Storage.Enabled = false
+	s.add("ns1", 5000)
+	require.NoError(t, s.Allow(ctx, "ns1", 1, true))
+}
+
+func TestPendingStorage(t *testing.T) {
+	save := mgr
+	defer func() { mgr = save }()
+
+	s := &storage{cfg: &config.QuotaConfig{Storage: config.StorageLimitsConfig{Enabled: true, DataSizeLimit: 100}}}
+	mgr = Manager{quota: []Quota{s}, storage: s}
+
+	// the writes without a transaction are applied right away
+	RecordStorage(context.Background(), "ns1", 10)
+	usage, limit, ok := StorageUsage("ns1")
+	require.True(t, ok)
+	require.Equal(t, int64(10), usage)
+	require.Equal(t, int64(100), limit)
+
+	// the writes of a transaction are applied once it is committed
+	ctx := WithPendingStorage(context.Background(), "ns1")
+	RecordStorage(ctx, "ns1", 30)
+	RecordStorage(ctx, "ns1", -5)
+	usage, _, _ = StorageUsage("ns1")
+	require.Equal(t, int64(10), usage)
+	CommitPendingStorage(ctx)
+	usage, _, _ = StorageUsage("ns1")
+	require.Equal(t, int64(35), usage)
+
+	RecordStorage(ctx, "ns1", 50)
+	DiscardPendingStorage(ctx)
+	CommitPendingStorage(ctx)
+	usage, _, _ = StorageUsage("ns1")
+	require.Equal(t, int64(35), usage)
+
+	SetStorageQuota("ns1", &StorageQuota{Size: 500})
+	_, limit, _ = StorageUsage("ns1")
+	require.Equal(t, int64(500), limit)
+}
@@ -0,0 +1,88 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"context"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/transaction"
+)
+
+// storageQuotaMetadataKey is the key of the reserved namespace metadata storing the storage quota set for the namespace.
+const storageQuotaMetadataKey = "storage_quota"
+
+// StorageQuota is the storage quota set for a namespace, it overrides the data size limits of the config.
+type StorageQuota struct {
+	// Size is the maximum data size of the namespace in bytes.
+	Size int64 `json:"size"`
+}
+
+func (q *StorageQuota) Validate() error {
+	if q.Size <= 0 {
+		return errors.InvalidArgument("the storage quota must be greater than 0, received %d", q.Size)
+	}
+	return nil
+}
+
+// Store keeps the storage quotas set for the namespaces in the reserved namespace metadata, so the tenants can't
+// change their quota through the namespace metadata API.
+type Store struct {
+	namespaces *metadata.NamespaceSubspace
+}
+
+func NewStore(namespaces *metadata.NamespaceSubspace) *Store {
+	return &Store{
+		namespaces: namespaces,
+	}
+}
+
+// GetStorageQuota returns the quota set for the namespace, it is nil if none is set.
+func (s *Store) GetStorageQuota(ctx context.Context, tx transaction.Tx, namespaceId uint32) (*StorageQuota, error) {
+	payload, err := s.namespaces.GetNamespaceMetadata(ctx, tx, namespaceId, storageQuotaMetadataKey)
+	if err != nil || payload == nil {
+		return nil, err
+	}
+
+	var q StorageQuota
+	if err = jsoniter.Unmarshal(payload, &q); err != nil {
+		return nil, errors.Internal("failed to read the storage quota of the namespace: %s", err.Error())
+	}
+	return &q, nil
+}
+
+// SetStorageQuota sets the quota of the namespace, it replaces the quota set before.
+func (s *Store) SetStorageQuota(ctx context.Context, tx transaction.Tx, namespaceId uint32, q *StorageQuota) error {
+	payload, err := jsoniter.Marshal(q)
+	if err != nil {
+		return err
+	}
+
+	current, err := s.GetStorageQuota(ctx, tx, namespaceId)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return s.namespaces.InsertNamespaceMetadata(ctx, tx, namespaceId, storageQuotaMetadataKey, payload)
+	}
+	return s.namespaces.UpdateNamespaceMetadata(ctx, tx, namespaceId, storageQuotaMetadataKey, payload)
+}
+
+// DeleteStorageQuota removes the quota set for the namespace, the namespace then has the limits of the config.
+func (s *Store) DeleteStorageQuota(ctx context.Context, tx transaction.Tx, namespaceId uint32) error {
+	return s.namespaces.DeleteNamespaceMetadata(ctx, tx, namespaceId, storageQuotaMetadataKey)
+}
@@ -0,0 +1,301 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/lib/container"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/tigrisdata/tigris/server/request"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"golang.org/x/time/rate"
+)
+
+// Kind is the kind of request a limit applies to.
+type Kind int
+
+const (
+	Read Kind = iota
+	Write
+	DDL
+
+	kindCount = 3
+)
+
+var kindNames = [kindCount]string{"read", "write", "ddl"}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// ddlMethods are the methods creating or dropping the databases and the collections.
+var ddlMethods = container.NewHashSet(
+	api.CreateDatabaseMethodName,
+	api.DropDatabaseMethodName,
+	api.CreateOrUpdateCollectionMethodName,
+	api.DropCollectionMethodName,
+)
+
+// RequestKind returns the kind of the requests of the method.
+func RequestKind(method string) Kind {
+	switch {
+	case ddlMethods.Contains(method):
+		return DDL
+	case request.IsReadMethod(method):
+		return Read
+	default:
+		return Write
+	}
+}
+
+// Manager limits the requests per second of the namespaces with a token bucket per namespace and kind of request. The
+// bucket holds a second of requests, so that a namespace can't exceed its limit in any second. The namespaces without
+// a request for the idle timeout are evicted, their limits are read again on their next request.
+type Manager struct {
+	cfg        *config.RequestRateConfig
+	tenantMgr  *metadata.TenantManager
+	txMgr      *transaction.Manager
+	store      *Store
+	namespaces sync.Map
+	now        func() time.Time
+
+	evictMu   sync.Mutex
+	evictedAt time.Time
+	// readLimits reads the limits set for the namespace, they are nil if none is set
+	readLimits func(ctx context.Context, namespace string) (*Limits, error)
+}
+
+// namespaceLimiter is the state of a namespace, the buckets of the kinds without a limit are nil.
+type namespaceLimiter struct {
+	sync.Mutex
+
+	limits   config.RequestRateLimits
+	buckets  [kindCount]*rate.Limiter
+	loadedAt time.Time
+	usedAt   time.Time
+
+	// the requests received in the current second, they are reported as the rate once the second is over
+	window time.Time
+	counts [kindCount]int
+}
+
+var mgr *Manager
+
+func NewManager(tm *metadata.TenantManager, txMgr *transaction.Manager, store *Store, cfg *config.Config) *Manager {
+	m := &Manager{
+		cfg:       &cfg.Quota.Requests,
+		tenantMgr: tm,
+		txMgr:     txMgr,
+		store:     store,
+		now:       time.Now,
+	}
+	m.evictedAt = m.now()
+	m.readLimits = m.readNamespaceLimits
+	return m
+}
+
+// Init enables the request rate limits of the namespaces.
+func Init(tm *metadata.TenantManager, txMgr *transaction.Manager, cfg *config.Config) {
+	if !cfg.Quota.Requests.Enabled {
+		mgr = nil
+		return
+	}
+
+	mgr = NewManager(tm, txMgr, NewStore(metadata.NewReservedNamespaceStore(&metadata.DefaultMDNameRegistry{})), cfg)
+}
+
+// Allow returns a ResourceExhausted error, with the delay after which the request can be retried, if the namespace
+// is over its limit for the requests of the method.
+func Allow(ctx context.Context, namespace string, method string) error {
+	if mgr == nil {
+		return nil
+	}
+
+	return mgr.Allow(ctx, namespace, RequestKind(method))
+}
+
+// Invalidate reads the limits of the namespace again on its next request, once they are set with the admin API.
+func Invalidate(namespace string) {
+	if mgr != nil {
+		mgr.Invalidate(namespace)
+	}
+}
+
+func (m *Manager) Allow(ctx context.Context, namespace string, kind Kind) error {
+	l := m.limiter(ctx, namespace)
+	now := m.now()
+	m.evictIdle(now)
+
+	l.Lock()
+	defer l.Unlock()
+
+	l.usedAt = now
+	l.count(namespace, kind, now)
+	bucket := l.buckets[kind]
+	if bucket == nil {
+		return nil
+	}
+
+	r := bucket.ReserveN(now, 1)
+	if delay := r.DelayFrom(now); !r.OK() || delay > 0 {
+		r.CancelAt(now)
+		metrics.CountRequestRateThrottled(namespace, kind.String())
+		return errors.ResourceExhausted("the %s requests of the namespace '%s' are limited to %d per second", kind,
+			namespace, l.limit(kind)).WithRetry(delay).WithSubsystem(errors.SubsystemQuota)
+	}
+	return nil
+}
+
+func (m *Manager) Invalidate(namespace string) {
+	if v, ok := m.namespaces.Load(namespace); ok {
+		l := v.(*namespaceLimiter)
+		l.Lock()
+		l.loadedAt = time.Time{}
+		l.Unlock()
+	}
+}
+
+// evictIdle drops the state of the namespaces without a request for the idle timeout, the namespaces are checked
+// once per idle timeout.
+func (m *Manager) evictIdle(now time.Time) {
+	if m.cfg.IdleTimeout <= 0 {
+		return
+	}
+
+	m.evictMu.Lock()
+	if now.Sub(m.evictedAt) < m.cfg.IdleTimeout {
+		m.evictMu.Unlock()
+		return
+	}
+	m.evictedAt = now
+	m.evictMu.Unlock()
+
+	m.namespaces.Range(func(key, value interface{}) bool {
+		l := value.(*namespaceLimiter)
+		l.Lock()
+		idle := now.Sub(l.usedAt) >= m.cfg.IdleTimeout
+		l.Unlock()
+		if idle {
+			m.namespaces.Delete(key)
+		}
+		return true
+	})
+}
+
+// limiter returns the state of the namespace, the limits of the namespace are read again once they are older than
+// the refresh interval. The requests received while they are read use the previous limits.
+func (m *Manager) limiter(ctx context.Context, namespace string) *namespaceLimiter {
+	if v, ok := m.namespaces.Load(namespace); ok {
+		l := v.(*namespaceLimiter)
+		if l.claimRefresh(m.now(), m.cfg.RefreshInterval) {
+			if limits, ok := m.limits(ctx, namespace); ok {
+				l.Lock()
+				l.setLimits(namespace, limits, m.now())
+				l.Unlock()
+			}
+		}
+		return l
+	}
+
+	// the limits of a namespace are read before its first request, the namespace has the default limits if they
+	// can't be read
+	l := &namespaceLimiter{loadedAt: m.now()}
+	limits, _ := m.limits(ctx, namespace)
+	l.setLimits(namespace, limits, m.now())
+	v, _ := m.namespaces.LoadOrStore(namespace, l)
+	return v.(*namespaceLimiter)
+}
+
+// limits returns the limits of the namespace, they are the default limits and false if they can't be read.
+func (m *Manager) limits(ctx context.Context, namespace string) (config.RequestRateLimits, bool) {
+	override, err := m.readLimits(ctx, namespace)
+	if err != nil {
+		log.Warn().Err(err).Str("namespace", namespace).Msg("reading the request rate limits failed")
+		return m.cfg.Default, false
+	}
+	return override.Apply(m.cfg.Default), true
+}
+
+func (m *Manager) readNamespaceLimits(ctx context.Context, namespace string) (*Limits, error) {
+	tenant, err := m.tenantMgr.GetTenant(ctx, namespace)
+	if err != nil {
+		// the namespaces that can't be loaded have the default limits
+		return nil, nil
+	}
+
+	tx, err := m.txMgr.StartTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	return m.store.GetLimits(ctx, tx, tenant.GetNamespace().Id())
+}
+
+// claimRefresh returns true if the limits must be read again, the caller reads them and the other requests don't.
+func (l *namespaceLimiter) claimRefresh(now time.Time, interval time.Duration) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	if !l.loadedAt.IsZero() && now.Sub(l.loadedAt) < interval {
+		return false
+	}
+	l.loadedAt = now
+	return true
+}
+
+func (l *namespaceLimiter) limit(kind Kind) int {
+	return []int{Read: l.limits.Read, Write: l.limits.Write, DDL: l.limits.DDL}[kind]
+}
+
+// setLimits updates the buckets of the namespace, the tokens of the buckets are kept when their limit changes.
+func (l *namespaceLimiter) setLimits(namespace string, limits config.RequestRateLimits, now time.Time) {
+	l.limits = limits
+	for kind := Kind(0); kind < kindCount; kind++ {
+		limit := l.limit(kind)
+		metrics.UpdateRequestRateLimit(namespace, kind.String(), limit)
+
+		switch {
+		case limit <= 0:
+			l.buckets[kind] = nil
+		case l.buckets[kind] == nil:
+			l.buckets[kind] = rate.NewLimiter(rate.Limit(limit), limit)
+		default:
+			l.buckets[kind].SetLimitAt(now, rate.Limit(limit))
+			l.buckets[kind].SetBurstAt(now, limit)
+		}
+	}
+}
+
+// count counts the request in the current second, the counts of the previous second are reported as the rates.
+func (l *namespaceLimiter) count(namespace string, kind Kind, now time.Time) {
+	if now.Sub(l.window) >= time.Second {
+		if !l.window.IsZero() {
+			for k := Kind(0); k < kindCount; k++ {
+				metrics.UpdateRequestRate(namespace, k.String(), l.counts[k])
+			}
+		}
+		l.window, l.counts = now, [kindCount]int{}
+	}
+	l.counts[kind]++
+}
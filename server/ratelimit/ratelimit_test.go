@@ -0,0 +1,193 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/uber-go/tally"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func newTestManager(t *testing.T, overrides map[string]*Limits) (*Manager, *time.Time, *int) {
+	t.Helper()
+
+	cfg := config.DefaultConfig
+	cfg.Quota.Requests = config.RequestRateConfig{
+		Enabled:         true,
+		Default:         config.RequestRateLimits{Read: 4, Write: 2, DDL: 1},
+		RefreshInterval: time.Minute,
+		IdleTimeout:     10 * time.Minute,
+	}
+
+	now, reads := time.Now(), 0
+	m := NewManager(nil, nil, nil, &cfg)
+	m.now = func() time.Time { return now }
+	m.readLimits = func(_ context.Context, namespace string) (*Limits, error) {
+		reads++
+		if namespace == "failing" {
+			return nil, fmt.Errorf("backend unavailable")
+		}
+		return overrides[namespace], nil
+	}
+	return m, &now, &reads
+}
+
+// allowed returns the number of requests allowed out of the requests sent at once.
+func allowed(m *Manager, namespace string, kind Kind, requests int) int {
+	n := 0
+	for i := 0; i < requests; i++ {
+		if m.Allow(context.Background(), namespace, kind) == nil {
+			n++
+		}
+	}
+	return n
+}
+
+func TestRequestKind(t *testing.T) {
+	require.Equal(t, Read, RequestKind(api.ReadMethodName))
+	require.Equal(t, Read, RequestKind(api.SearchMethodName))
+	require.Equal(t, Write, RequestKind(api.InsertMethodName))
+	require.Equal(t, Write, RequestKind(api.DeleteMethodName))
+	require.Equal(t, DDL, RequestKind(api.CreateOrUpdateCollectionMethodName))
+	require.Equal(t, DDL, RequestKind(api.DropDatabaseMethodName))
+}
+
+func TestAllow(t *testing.T) {
+	t.Run("limits per kind", func(t *testing.T) {
+		m, now, _ := newTestManager(t, nil)
+
+		require.Equal(t, 4, allowed(m, "ns1", Read, 10))
+		require.Equal(t, 2, allowed(m, "ns1", Write, 10))
+		require.Equal(t, 1, allowed(m, "ns1", DDL, 10))
+		// the namespaces have their own buckets
+		require.Equal(t, 2, allowed(m, "ns2", Write, 10))
+
+		// the bucket is refilled at the rate of the limit
+		*now = now.Add(500 * time.Millisecond)
+		require.Equal(t, 2, allowed(m, "ns1", Read, 10))
+		require.Equal(t, 1, allowed(m, "ns1", Write, 10))
+		require.Equal(t, 0, allowed(m, "ns1", DDL, 10))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		m, _, _ := newTestManager(t, nil)
+
+		require.NoError(t, m.Allow(context.Background(), "ns1", DDL))
+		err := m.Allow(context.Background(), "ns1", DDL)
+		require.Equal(t, api.Code_RESOURCE_EXHAUSTED, err.(*api.TigrisError).Code)
+		require.Equal(t, "the ddl requests of the namespace 'ns1' are limited to 1 per second", err.Error())
+		require.Equal(t, time.Second, err.(*api.TigrisError).RetryDelay())
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		overrides := map[string]*Limits{"ns1": {Read: intPtr(0), Write: intPtr(5)}}
+		m, now, reads := newTestManager(t, overrides)
+
+		// zero doesn't limit the requests, the limits that are not set are the default limits
+		require.Equal(t, 100, allowed(m, "ns1", Read, 100))
+		require.Equal(t, 5, allowed(m, "ns1", Write, 10))
+		require.Equal(t, 1, allowed(m, "ns1", DDL, 10))
+		require.Equal(t, 1, *reads)
+
+		// the limits are read again after the refresh interval
+		overrides["ns1"] = &Limits{Write: intPtr(1)}
+		*now = now.Add(30 * time.Second)
+		require.Equal(t, 5, allowed(m, "ns1", Write, 10))
+		*now = now.Add(30 * time.Second)
+		require.Equal(t, 1, allowed(m, "ns1", Write, 10))
+		require.Equal(t, 4, allowed(m, "ns1", Read, 10))
+		require.Equal(t, 2, *reads)
+
+		// or once they are invalidated
+		overrides["ns1"] = nil
+		m.Invalidate("ns1")
+		*now = now.Add(time.Second)
+		// the tokens of the bucket are kept when its limit changes
+		require.Equal(t, 1, allowed(m, "ns1", Write, 10))
+		require.Equal(t, 3, *reads)
+		*now = now.Add(time.Second)
+		require.Equal(t, 2, allowed(m, "ns1", Write, 10))
+	})
+
+	t.Run("idle eviction", func(t *testing.T) {
+		m, now, reads := newTestManager(t, nil)
+
+		require.Equal(t, 2, allowed(m, "ns1", Write, 10))
+		*now = now.Add(5 * time.Minute)
+		require.Equal(t, 2, allowed(m, "ns2", Write, 10))
+		require.Equal(t, 2, *reads)
+
+		// ns1 is evicted once it is idle for the idle timeout, ns2 is kept
+		*now = now.Add(6 * time.Minute)
+		require.NoError(t, m.Allow(context.Background(), "ns2", Read))
+		_, ok := m.namespaces.Load("ns1")
+		require.False(t, ok)
+		_, ok = m.namespaces.Load("ns2")
+		require.True(t, ok)
+
+		// its limits are read again on its next request
+		*reads = 0
+		require.Equal(t, 2, allowed(m, "ns1", Write, 10))
+		require.Equal(t, 1, *reads)
+	})
+
+	t.Run("read failure", func(t *testing.T) {
+		m, _, _ := newTestManager(t, nil)
+
+		require.Equal(t, 2, allowed(m, "failing", Write, 10))
+	})
+}
+
+func TestRequestRateMetrics(t *testing.T) {
+	scope := tally.NewTestScope("", nil)
+	metrics.RequestRateMetrics = scope
+	defer func() { metrics.RequestRateMetrics = nil }()
+
+	m, now, _ := newTestManager(t, nil)
+	require.Equal(t, 2, allowed(m, "ns1", Write, 3))
+	*now = now.Add(time.Second)
+	require.Equal(t, 1, allowed(m, "ns1", Write, 1))
+
+	snapshot := scope.Snapshot()
+	require.Equal(t, float64(3), snapshot.Gauges()["rate+request_kind=write,tigris_tenant=ns1"].Value())
+	require.Equal(t, float64(2), snapshot.Gauges()["limit+request_kind=write,tigris_tenant=ns1"].Value())
+	require.Equal(t, float64(1), snapshot.Gauges()["limit+request_kind=ddl,tigris_tenant=ns1"].Value())
+	require.Equal(t, int64(1), snapshot.Counters()["throttled+request_kind=write,tigris_tenant=ns1"].Value())
+}
+
+func TestLimits(t *testing.T) {
+	defaults := config.RequestRateLimits{Read: 4, Write: 2, DDL: 1}
+
+	var none *Limits
+	require.Equal(t, defaults, none.Apply(defaults))
+	require.Equal(t, config.RequestRateLimits{Read: 4, Write: 0, DDL: 3},
+		(&Limits{Write: intPtr(0), DDL: intPtr(3)}).Apply(defaults))
+
+	require.NoError(t, (&Limits{Read: intPtr(0)}).Validate())
+	require.Equal(t, errors.InvalidArgument("the write request rate limit can't be negative"),
+		(&Limits{Write: intPtr(-1)}).Validate())
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/transaction"
+)
+
+// limitsMetadataKey is the key of the reserved namespace metadata storing the request rate limits set for the namespace.
+const limitsMetadataKey = "request_rate_limits"
+
+// Limits are the request rate limits set for a namespace, the limits that are not set are the default limits.
+type Limits struct {
+	Read  *int `json:"read,omitempty"`
+	Write *int `json:"write,omitempty"`
+	DDL   *int `json:"ddl,omitempty"`
+}
+
+// Apply returns the default limits overridden by the limits that are set.
+func (l *Limits) Apply(defaults config.RequestRateLimits) config.RequestRateLimits {
+	if l == nil {
+		return defaults
+	}
+	if l.Read != nil {
+		defaults.Read = *l.Read
+	}
+	if l.Write != nil {
+		defaults.Write = *l.Write
+	}
+	if l.DDL != nil {
+		defaults.DDL = *l.DDL
+	}
+	return defaults
+}
+
+func (l *Limits) Validate() error {
+	for kind, limit := range []*int{Read: l.Read, Write: l.Write, DDL: l.DDL} {
+		if limit != nil && *limit < 0 {
+			return errors.InvalidArgument("the %s request rate limit can't be negative", Kind(kind))
+		}
+	}
+	return nil
+}
+
+// Store keeps the request rate limits set for the namespaces in the reserved namespace metadata, so the tenants can't
+// change their limits through the namespace metadata API.
+type Store struct {
+	namespaces *metadata.NamespaceSubspace
+}
+
+func NewStore(namespaces *metadata.NamespaceSubspace) *Store {
+	return &Store{
+		namespaces: namespaces,
+	}
+}
+
+// GetLimits returns the limits set for the namespace, they are nil if none is set.
+func (s *Store) GetLimits(ctx context.Context, tx transaction.Tx, namespaceId uint32) (*Limits, error) {
+	payload, err := s.namespaces.GetNamespaceMetadata(ctx, tx, namespaceId, limitsMetadataKey)
+	if err != nil || payload == nil {
+		return nil, err
+	}
+
+	var limits Limits
+	if err = jsoniter.Unmarshal(payload, &limits); err != nil {
+		return nil, errors.Internal("failed to read the request rate limits of the namespace: %s", err.Error())
+	}
+	return &limits, nil
+}
+
+// SetLimits sets the limits of the namespace, they replace the limits set before.
+func (s *Store) SetLimits(ctx context.Context, tx transaction.Tx, namespaceId uint32, limits *Limits) error {
+	payload, err := jsoniter.Marshal(limits)
+	if err != nil {
+		return err
+	}
+
+	current, err := s.GetLimits(ctx, tx, namespaceId)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return s.namespaces.InsertNamespaceMetadata(ctx, tx, namespaceId, limitsMetadataKey, payload)
+	}
+	return s.namespaces.UpdateNamespaceMetadata(ctx, tx, namespaceId, limitsMetadataKey, payload)
+}
+
+// DeleteLimits removes the limits set for the namespace, the namespace then has the default limits.
+func (s *Store) DeleteLimits(ctx context.Context, tx transaction.Tx, namespaceId uint32) error {
+	return s.namespaces.DeleteNamespaceMetadata(ctx, tx, namespaceId, limitsMetadataKey)
+}
@@ -16,11 +16,13 @@ package request
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/base64"
 	"fmt"
 	"strings"
 
 	"github.com/buger/jsonparser"
+	"github.com/fullstorydev/grpchan/inprocgrpc"
 	"github.com/rs/zerolog/log"
 	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/errors"
@@ -31,10 +33,13 @@ import (
 	"github.com/tigrisdata/tigris/server/metrics"
 	ulog "github.com/tigrisdata/tigris/util/log"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
 )
 
 var (
-	adminMethods = container.NewHashSet(api.CreateNamespaceMethodName, api.ListNamespaceMethodName, api.DescribeNamespacesMethodName)
+	adminMethods = container.NewHashSet(api.CreateNamespaceMethodName, api.ListNamespaceMethodName, api.DescribeNamespacesMethodName,
+		api.DescribeNamespaceMethodName, api.DeleteNamespaceMethodName)
 	tenantGetter metadata.TenantGetter
 )
 
@@ -43,6 +48,8 @@ type MetadataCtxKey struct{}
 type AccessToken struct {
 	Namespace string
 	Sub       string
+	// Role is the role claimed by the token, the role assigned in the namespace takes precedence.
+	Role string
 }
 
 type Metadata struct {
@@ -54,7 +61,11 @@ type Metadata struct {
 	namespace string
 	// human readable namespace name
 	namespaceName string
-	IsHuman       bool
+	// requestID is used to correlate the request, it is either passed by the client or generated by the server
+	requestID string
+	// clientCertSubject is the subject of the verified certificate of the client, when the client presented one
+	clientCertSubject string
+	IsHuman           bool
 }
 
 func Init(tg metadata.TenantGetter) {
@@ -63,7 +74,7 @@ func Init(tg metadata.TenantGetter) {
 
 func NewRequestEndpointMetadata(ctx context.Context, serviceName string, methodInfo grpc.MethodInfo) Metadata {
 	ns, utype := GetMetadataFromHeader(ctx)
-	md := Metadata{serviceName: serviceName, methodInfo: methodInfo, IsHuman: utype}
+	md := Metadata{serviceName: serviceName, methodInfo: methodInfo, IsHuman: utype, clientCertSubject: getClientCertSubject(ctx)}
 	md.SetNamespace(ctx, ns)
 	return md
 }
@@ -125,8 +136,22 @@ func (m *Metadata) GetMethodInfo() grpc.MethodInfo {
 	return m.methodInfo
 }
 
+func (m *Metadata) SetRequestID(id string) {
+	m.requestID = id
+}
+
+func (m *Metadata) GetRequestID() string {
+	return m.requestID
+}
+
+// GetClientCertSubject returns the subject of the verified certificate of the client, it is empty when the client didn't
+// present a certificate.
+func (m *Metadata) GetClientCertSubject() string {
+	return m.clientCertSubject
+}
+
 func (m *Metadata) GetInitialTags() map[string]string {
-	return map[string]string{
+	tags := map[string]string{
 		"grpc_method":        m.methodInfo.Name,
 		"tigris_tenant":      m.namespace,
 		"tigris_tenant_name": m.GetTigrisNamespaceNameTag(),
@@ -134,6 +159,12 @@ func (m *Metadata) GetInitialTags() map[string]string {
 		"db":                 defaults.UnknownValue,
 		"collection":         defaults.UnknownValue,
 	}
+	if len(m.requestID) > 0 {
+		// only ends up in the tracing spans, the metric tags are filtered by the scope specific tag keys
+		tags["request_id"] = m.requestID
+	}
+
+	return tags
 }
 
 func (m *Metadata) GetFullMethod() string {
@@ -168,6 +199,39 @@ func (m *Metadata) GetTigrisNamespaceNameTag() string {
 	return metrics.GetTenantNameTagValue(m.namespace, m.namespaceName)
 }
 
+type tlsStateCtxKey struct{}
+
+// WithTLSConnectionState attaches the state of the TLS connection of the HTTP requests to their context.
+func WithTLSConnectionState(ctx context.Context, state *tls.ConnectionState) context.Context {
+	return context.WithValue(ctx, tlsStateCtxKey{}, state)
+}
+
+// TLSConnectionStateFromContext returns the state of the TLS connection attached by WithTLSConnectionState.
+func TLSConnectionStateFromContext(ctx context.Context) (*tls.ConnectionState, bool) {
+	state, ok := ctx.Value(tlsStateCtxKey{}).(*tls.ConnectionState)
+	return state, ok
+}
+
+// getClientCertSubject returns the subject of the verified client certificate of the connection of the request. The
+// gRPC requests carry the state of their connection in their peer, and the HTTP requests, served in process, in the
+// context of the HTTP request.
+func getClientCertSubject(ctx context.Context) string {
+	var state *tls.ConnectionState
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			state = &info.State
+		}
+	}
+	if clientCtx := inprocgrpc.ClientContext(ctx); state == nil && clientCtx != nil {
+		state, _ = TLSConnectionStateFromContext(clientCtx)
+	}
+
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.PeerCertificates) == 0 {
+		return ""
+	}
+	return state.PeerCertificates[0].Subject.String()
+}
+
 // NamespaceExtractor - extract the namespace from context.
 type NamespaceExtractor interface {
 	Extract(ctx context.Context) (string, error)
@@ -232,7 +296,7 @@ func (tokenNamespaceExtractor *AccessTokenNamespaceExtractor) Extract(ctx contex
 }
 
 func IsAdminApi(fullMethodName string) bool {
-	return adminMethods.Contains(fullMethodName)
+	return adminMethods.Contains(fullMethodName) || strings.HasPrefix(fullMethodName, api.AdminRoutesMethodPrefix)
 }
 
 func getTokenFromHeader(header string) (string, error) {
@@ -306,6 +370,11 @@ func isWrite(name string) bool {
 	return !isRead(name)
 }
 
+// IsReadMethod returns true if the method, by its full name, only reads.
+func IsReadMethod(fullMethod string) bool {
+	return isRead(fullMethod)
+}
+
 func IsRead(ctx context.Context) bool {
 	m, _ := grpc.Method(ctx)
 	return isRead(m)
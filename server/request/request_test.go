@@ -16,11 +16,16 @@ package request
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"testing"
 
 	"github.com/bmizerany/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/tigrisdata/tigris/errors"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
 )
 
 func TestRequestMetadata(t *testing.T) {
@@ -70,6 +75,8 @@ func TestRequestMetadata(t *testing.T) {
 	t.Run("isAdmin test", func(t *testing.T) {
 		require.True(t, IsAdminApi("/tigrisdata.management.v1.Management/CreateNamespace"))
 		require.True(t, IsAdminApi("/tigrisdata.management.v1.Management/ListNamespaces"))
+		require.True(t, IsAdminApi("/tigrisdata.management.v1.Management/DeleteNamespace"))
+		require.True(t, IsAdminApi("/tigrisdata.admin.v1.AdminRoutes/SetRole"))
 		require.False(t, IsAdminApi("/.HealthAPI/Health"))
 		require.False(t, IsAdminApi("some-random"))
 	})
@@ -98,3 +105,25 @@ func TestRequestMetadata(t *testing.T) {
 		assert.Equal(t, false, utype)
 	})
 }
+
+func TestGetClientCertSubject(t *testing.T) {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "client-1", Organization: []string{"tigris"}}}
+	withState := func(state tls.ConnectionState) context.Context {
+		return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: state}})
+	}
+
+	require.Equal(t, "", getClientCertSubject(context.Background()))
+	require.Equal(t, "", getClientCertSubject(peer.NewContext(context.Background(), &peer.Peer{})))
+	// the certificates that are not verified are ignored
+	require.Equal(t, "", getClientCertSubject(withState(tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}})))
+	require.Equal(t, "CN=client-1,O=tigris", getClientCertSubject(withState(tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert}},
+	})))
+
+	state, ok := TLSConnectionStateFromContext(WithTLSConnectionState(context.Background(), &tls.ConnectionState{ServerName: "tigris"}))
+	require.True(t, ok)
+	require.Equal(t, "tigris", state.ServerName)
+	_, ok = TLSConnectionStateFromContext(context.Background())
+	require.False(t, ok)
+}
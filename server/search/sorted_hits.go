@@ -39,6 +39,36 @@ func (sh *Hit) isFieldMissingOrNil(f string) bool {
 	}
 }
 
+// placement of a missing or nil value relative to the non-nil values of a field.
+const (
+	placeFirst = -1
+	placeValue = 0
+	placeLast  = 1
+)
+
+// placement returns where the value of the field is sorted for this order, missing and nil values are only distinguished
+// when the order has an explicit nulls policy.
+func (sh *Hit) placement(order sort.SortField) int {
+	v, ok := sh.Document[order.Name]
+	if ok && v != nil {
+		return placeValue
+	}
+
+	if ok {
+		switch order.Nulls {
+		case sort.NullsFirst:
+			return placeFirst
+		case sort.NullsLast:
+			return placeLast
+		}
+	}
+
+	if order.MissingValuesFirst {
+		return placeFirst
+	}
+	return placeLast
+}
+
 func NewSearchHit(tsHit *tsApi.SearchResultHit) *Hit {
 	if tsHit == nil || tsHit.Document == nil {
 		return nil
@@ -117,20 +147,20 @@ func hitsComparator(this, that *Hit, sortingOrder *sort.Ordering) int {
 		}
 		order := (*sortingOrder)[i]
 
-		thisIsNil, thatIsNil := this.isFieldMissingOrNil(order.Name), that.isFieldMissingOrNil(order.Name)
-
-		// if both hits are missing/nil field, continue
-		if thisIsNil && thatIsNil {
-			continue
-		}
+		thisPlace, thatPlace := this.placement(order), that.placement(order)
 
-		// only one of the document is missing the field
-		if (thisIsNil && order.MissingValuesFirst) || (thatIsNil && !order.MissingValuesFirst) {
+		// only one of the document is missing the field or the field is nil
+		if thisPlace < thatPlace {
 			return This
-		} else if (thatIsNil && order.MissingValuesFirst) || (thisIsNil && !order.MissingValuesFirst) {
+		} else if thisPlace > thatPlace {
 			return That
 		}
 
+		// if both hits are missing/nil field, continue
+		if thisPlace != placeValue {
+			continue
+		}
+
 		// extract values to perform actual comparison
 		thisVal, thatVal := this.Document[order.Name], that.Document[order.Name]
 		var thisV, thatV float64
@@ -81,6 +81,29 @@ func Test_hitsComparator(t *testing.T) {
 		assert.Equal(t, That, hitsComparator(complete, missingField, sortOrder))
 	})
 
+	t.Run("use nulls policy when one document has nil field", func(t *testing.T) {
+		missing := &Hit{Document: map[string]interface{}{}}
+		null := &Hit{Document: map[string]interface{}{"balance": nil}}
+		value := &Hit{Document: map[string]interface{}{"balance": json.Number("10")}}
+
+		// nulls are treated as missing by default
+		sortOrder := &sort.Ordering{{Name: "balance", Ascending: true}}
+		assert.Equal(t, This, hitsComparator(value, null, sortOrder))
+		assert.Equal(t, Equal, hitsComparator(missing, null, sortOrder))
+
+		// nulls to front, missing values to end
+		sortOrder = &sort.Ordering{{Name: "balance", Ascending: true, Nulls: sort.NullsFirst}}
+		assert.Equal(t, This, hitsComparator(null, value, sortOrder))
+		assert.Equal(t, This, hitsComparator(null, missing, sortOrder))
+		assert.Equal(t, This, hitsComparator(value, missing, sortOrder))
+
+		// nulls to end, missing values to front
+		sortOrder = &sort.Ordering{{Name: "balance", Ascending: true, MissingValuesFirst: true, Nulls: sort.NullsLast}}
+		assert.Equal(t, That, hitsComparator(null, value, sortOrder))
+		assert.Equal(t, That, hitsComparator(null, missing, sortOrder))
+		assert.Equal(t, That, hitsComparator(value, missing, sortOrder))
+	})
+
 	t.Run("when comparing on int values", func(t *testing.T) {
 		tsHits := generateTsHits(documents["complete_document"], documents["missing_balance_2"])
 		highPriority, lowPriority := NewSearchHit(&tsHits[0]), NewSearchHit(&tsHits[1])
@@ -0,0 +1,206 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metrics"
+	tsApi "github.com/typesense/typesense-go/typesense/api"
+)
+
+const (
+	adminPath = "/admin"
+
+	// searchFieldsPath returns the fields of the search collection of a collection.
+	searchFieldsPath = adminPath + "/namespaces/{namespace}/databases/{db}/collections/{collection}/search/fields"
+	// slowQueryPath reads and adjusts the thresholds of the slow query log.
+	slowQueryPath = adminPath + "/slow_query"
+)
+
+// searchFieldsResponse is the mapping of a collection in the search backend.
+type searchFieldsResponse struct {
+	Collection string        `json:"collection"`
+	Fields     []tsApi.Field `json:"fields"`
+}
+
+// slowQueryConfig is the config of the slow query log, the thresholds are durations like "500ms". In an update, the
+// missing fields are left unchanged and a method with an empty threshold is removed from the overrides.
+type slowQueryConfig struct {
+	Enabled          *bool             `json:"enabled,omitempty"`
+	Threshold        string            `json:"threshold,omitempty"`
+	MethodThresholds map[string]string `json:"method_thresholds,omitempty"`
+}
+
+func (s *apiService) registerAdminRoutes(router chi.Router) {
+	s.adminRoute(router, http.MethodGet, searchFieldsPath, "GetSearchFields", s.searchFields)
+	s.adminRoute(router, http.MethodPost, searchBackfillPath, "BackfillSearchFields", s.backfillSearchFields)
+	s.adminRoute(router, http.MethodGet, slowQueryPath, "GetSlowQuery", s.getSlowQuery)
+	s.adminRoute(router, http.MethodGet, usagePath, "GetUsage", s.getUsage)
+	s.adminRoute(router, http.MethodPut, slowQueryPath, "UpdateSlowQuery", s.updateSlowQuery)
+	s.adminRoute(router, http.MethodGet, exportPath, "ExportDocuments", s.exportDocuments)
+	s.adminRoute(router, http.MethodPost, importPath, "ImportDocuments", s.importDocuments)
+	s.adminRoute(router, http.MethodPost, importStreamPath, "ImportStream", s.importStream)
+	s.adminRoute(router, http.MethodPost, normalizePath, "NormalizeDocument", s.normalizeDocument)
+	s.adminRoute(router, http.MethodPost, describeCollectionsPath, "DescribeCollections", s.describeCollections)
+	s.adminRoute(router, http.MethodGet, schemaHistoryPath, "GetSchemaHistory", s.getSchemaHistory)
+	s.adminRoute(router, http.MethodGet, rangeSizesPath, "GetRangeSizes", s.getRangeSizes)
+	s.registerRoleRoutes(router)
+	s.registerRateLimitRoutes(router)
+	s.registerStorageQuotaRoutes(router)
+	s.registerNamespaceRoutes(router)
+	if s.webhooks != nil {
+		s.registerWebhookRoutes(router)
+		s.adminRoute(router, http.MethodPost, groupResetPath, "ResetCdcGroup", s.resetGroup)
+	}
+	if s.snapshots != nil {
+		s.registerSnapshotRoutes(router)
+	}
+}
+
+// adminRoute registers the handler of an admin route, the route is authenticated and authorized as the admin method of
+// the name.
+func (s *apiService) adminRoute(router chi.Router, method string, pattern string, name string, handler http.HandlerFunc) {
+	router.With(s.adminAuth.Handler(name)).Method(method, pattern, handler)
+}
+
+// adminMethodRoute registers the handler of an admin route serving an admin method of the API, the route is
+// authorized as the method.
+func (s *apiService) adminMethodRoute(router chi.Router, method string, pattern string, fullMethod string, handler http.HandlerFunc) {
+	router.With(s.adminAuth.MethodHandler(fullMethod)).Method(method, pattern, handler)
+}
+
+// searchFields dumps the flattened fields of a collection exactly as they are sent to the search backend.
+func (s *apiService) searchFields(w http.ResponseWriter, r *http.Request) {
+	namespace, db, collection := chi.URLParam(r, "namespace"), chi.URLParam(r, "db"), chi.URLParam(r, "collection")
+
+	tenant, err := s.tenantMgr.GetTenant(r.Context(), namespace)
+	if err != nil {
+		writeAdminError(w, errors.NotFound("namespace '%s' doesn't exist", namespace))
+		return
+	}
+
+	coll := tenant.GetCollection(db, collection)
+	if coll == nil {
+		writeAdminError(w, errors.NotFound("collection '%s' doesn't exist in the database '%s'", collection, db))
+		return
+	}
+
+	writeSearchFields(w, coll)
+}
+
+func (s *apiService) getSlowQuery(w http.ResponseWriter, _ *http.Request) {
+	writeSlowQueryConfig(w, metrics.GetSlowQueryConfig())
+}
+
+// updateSlowQuery adjusts the thresholds of the slow query log at runtime, they are reset to the config on restart.
+func (s *apiService) updateSlowQuery(w http.ResponseWriter, r *http.Request) {
+	req := &slowQueryConfig{}
+	if err := jsoniter.NewDecoder(r.Body).Decode(req); err != nil {
+		writeAdminError(w, errors.InvalidArgument("invalid slow query config: %s", err.Error()))
+		return
+	}
+
+	cfg := metrics.GetSlowQueryConfig()
+	if req.Enabled != nil {
+		cfg.Enabled = *req.Enabled
+	}
+	if len(req.Threshold) > 0 {
+		threshold, err := time.ParseDuration(req.Threshold)
+		if err != nil || threshold < 0 {
+			writeAdminError(w, errors.InvalidArgument("invalid threshold '%s'", req.Threshold))
+			return
+		}
+		cfg.Threshold = threshold
+	}
+	for method, value := range req.MethodThresholds {
+		if len(value) == 0 {
+			delete(cfg.MethodThresholds, method)
+			continue
+		}
+		threshold, err := time.ParseDuration(value)
+		if err != nil || threshold < 0 {
+			writeAdminError(w, errors.InvalidArgument("invalid threshold '%s' of the method '%s'", value, method))
+			return
+		}
+		cfg.MethodThresholds[method] = threshold
+	}
+
+	metrics.SetSlowQueryConfig(cfg)
+	writeSlowQueryConfig(w, cfg)
+}
+
+func writeSlowQueryConfig(w http.ResponseWriter, cfg config.SlowQueryConfig) {
+	resp := &slowQueryConfig{
+		Enabled:          &cfg.Enabled,
+		Threshold:        cfg.Threshold.String(),
+		MethodThresholds: make(map[string]string, len(cfg.MethodThresholds)),
+	}
+	for method, threshold := range cfg.MethodThresholds {
+		resp.MethodThresholds[method] = threshold.String()
+	}
+
+	writeAdminJSON(w, resp)
+}
+
+func writeSearchFields(w http.ResponseWriter, coll *schema.DefaultCollection) {
+	writeAdminJSON(w, &searchFieldsResponse{
+		Collection: coll.Search.Name,
+		Fields:     coll.Search.Fields,
+	})
+}
+
+func writeAdminJSON(w http.ResponseWriter, resp interface{}) {
+	data, err := jsoniter.Marshal(resp)
+	if err != nil {
+		writeAdminError(w, errors.Internal("failed to marshal the response"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// adminErrorDetails returns the error in the same format as the errors of the API, to be embedded in a response
+// whose status is already sent.
+func adminErrorDetails(err error) jsoniter.RawMessage {
+	data, merr := api.MarshalStatus(errors.WithRetryInfo(err).GRPCStatus().Proto())
+	if merr != nil {
+		log.Err(merr).Msg("failed to marshal the error")
+		return nil
+	}
+	return []byte(jsoniter.Get(data, "error").ToString())
+}
+
+// writeAdminError writes the error in the same format as the errors of the API.
+func writeAdminError(w http.ResponseWriter, err error) {
+	e := errors.WithRetryInfo(err)
+	data, merr := api.MarshalStatus(e.GRPCStatus().Proto())
+	if merr != nil {
+		log.Err(merr).Msg("failed to marshal the error")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(api.ToHTTPCode(e.Code))
+	_, _ = w.Write(data)
+}
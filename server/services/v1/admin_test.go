@@ -0,0 +1,149 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metrics"
+)
+
+func TestWriteSearchFields(t *testing.T) {
+	reqSchema := []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": { "type": "integer" },
+		"id_32": { "type": "integer", "format": "int32" },
+		"product": { "type": "string", "maxLength": 100 },
+		"id_uuid": { "type": "string", "format": "uuid" },
+		"ts": { "type": "string", "format": "date-time" },
+		"price": { "type": "number" },
+		"simple_items": { "type": "array", "items": { "type": "integer" } },
+		"simple_object": {
+			"type": "object",
+			"properties": {
+				"name": { "type": "string" },
+				"phone": { "type": "string" },
+				"address": { "type": "object", "properties": { "street": { "type": "string" } } },
+				"details": {
+					"type": "object",
+					"properties": {
+						"nested_id": { "type": "integer" },
+						"nested_obj": {
+							"type": "object",
+							"properties": { "id": { "type": "integer" }, "name": { "type": "string" } }
+						},
+						"nested_array": { "type": "array", "items": { "type": "integer" } },
+						"nested_string": { "type": "string" }
+					}
+				}
+			}
+		}
+	},
+	"primary_key": ["id"]
+}`)
+
+	factory, err := schema.Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	coll := schema.NewDefaultCollection("t1", 1, 1, factory.CollectionType, factory, "search_t1", nil)
+
+	w := httptest.NewRecorder()
+	writeSearchFields(w, coll)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var resp struct {
+		Collection string
+		Fields     []struct {
+			Name string
+			Type string
+		}
+	}
+	require.NoError(t, jsoniter.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, "search_t1", resp.Collection)
+
+	// same as the flattened fields of TestCollection_SearchSchema
+	expFlattenedFields := []string{
+		"id", "_tigris_id", "id_32", "product", "id_uuid", "ts", schema.ToSearchDateKey("ts"), "price", "simple_items",
+		schema.ToSearchArrayLengthKey("simple_items"), "simple_object.name", "simple_object.phone", "simple_object.address.street",
+		"simple_object.details.nested_id", "simple_object.details.nested_obj.id", "simple_object.details.nested_obj.name",
+		"simple_object.details.nested_array", schema.ToSearchArrayLengthKey("simple_object.details.nested_array"),
+		"simple_object.details.nested_string",
+		"created_at", "updated_at",
+	}
+	require.Len(t, resp.Fields, len(expFlattenedFields))
+	for i, f := range resp.Fields {
+		require.Equal(t, expFlattenedFields[i], f.Name)
+		require.Equal(t, coll.Search.Fields[i].Type, f.Type)
+	}
+	require.Equal(t, "int64", resp.Fields[0].Type)
+	require.Equal(t, "string", resp.Fields[6].Type)
+}
+
+func TestWriteAdminError(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeAdminError(w, errors.NotFound("collection 't1' doesn't exist in the database 'db1'"))
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.JSONEq(t, `{"error":{"code":"NOT_FOUND","message":"collection 't1' doesn't exist in the database 'db1'"}}`, w.Body.String())
+}
+
+func TestUpdateSlowQuery(t *testing.T) {
+	save := metrics.GetSlowQueryConfig()
+	t.Cleanup(func() { metrics.SetSlowQueryConfig(save) })
+	metrics.SetSlowQueryConfig(config.SlowQueryConfig{
+		Enabled:          true,
+		Threshold:        time.Second,
+		MethodThresholds: map[string]time.Duration{"Read": 5 * time.Second},
+	})
+
+	s := &apiService{}
+	update := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.updateSlowQuery(w, httptest.NewRequest(http.MethodPut, slowQueryPath, strings.NewReader(body)))
+		return w
+	}
+
+	w := update(`{"threshold": "250ms", "method_thresholds": {"Update": "2s", "Read": ""}}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"enabled": true, "threshold": "250ms", "method_thresholds": {"Update": "2s"}}`, w.Body.String())
+	require.Equal(t, config.SlowQueryConfig{
+		Enabled:          true,
+		Threshold:        250 * time.Millisecond,
+		MethodThresholds: map[string]time.Duration{"Update": 2 * time.Second},
+	}, metrics.GetSlowQueryConfig())
+
+	w = update(`{"enabled": false}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"enabled": false, "threshold": "250ms", "method_thresholds": {"Update": "2s"}}`, w.Body.String())
+
+	for _, body := range []string{`{"threshold": "fast"}`, `{"method_thresholds": {"Update": "-1s"}}`, `[]`} {
+		w = update(body)
+		require.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	require.Equal(t, 250*time.Millisecond, metrics.GetSlowQueryConfig().Threshold)
+
+	w = httptest.NewRecorder()
+	s.getSlowQuery(w, httptest.NewRequest(http.MethodGet, slowQueryPath, nil))
+	require.JSONEq(t, `{"enabled": false, "threshold": "250ms", "method_thresholds": {"Update": "2s"}}`, w.Body.String())
+}
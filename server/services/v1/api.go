@@ -18,19 +18,26 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/fullstorydev/grpchan/inprocgrpc"
 	"github.com/go-chi/chi/v5"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/rs/zerolog/log"
 	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/errors"
-	"github.com/tigrisdata/tigris/internal"
 	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/authz"
 	"github.com/tigrisdata/tigris/server/cdc"
 	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/defaults"
 	"github.com/tigrisdata/tigris/server/metadata"
 	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/tigrisdata/tigris/server/middleware"
+	"github.com/tigrisdata/tigris/server/quota"
+	"github.com/tigrisdata/tigris/server/ratelimit"
+	"github.com/tigrisdata/tigris/server/snapshot"
 	"github.com/tigrisdata/tigris/server/transaction"
 	"github.com/tigrisdata/tigris/store/kv"
 	"github.com/tigrisdata/tigris/store/search"
@@ -63,16 +70,28 @@ type apiService struct {
 	runnerFactory *QueryRunnerFactory
 	versionH      *metadata.VersionHandler
 	searchStore   search.Store
+	webhooks      *webhookDispatcher
+	snapshots     snapshot.Store
+	snapshotJobs  *snapshotJobs
+	roles         *authz.Store
+	rateLimits    *ratelimit.Store
+	storageQuotas *quota.Store
+	adminAuth     *middleware.AdminAuth
 }
 
-func newApiService(kv kv.KeyValueStore, searchStore search.Store, tenantMgr *metadata.TenantManager, txMgr *transaction.Manager) *apiService {
+func newApiService(kv kv.KeyValueStore, searchStore search.Store, tenantMgr *metadata.TenantManager, txMgr *transaction.Manager, adminAuth *middleware.AdminAuth) *apiService {
 	u := &apiService{
-		kvStore:     kv,
-		txMgr:       txMgr,
-		versionH:    &metadata.VersionHandler{},
-		searchStore: searchStore,
-		cdcMgr:      cdc.NewManager(),
-		tenantMgr:   tenantMgr,
+		kvStore:       kv,
+		txMgr:         txMgr,
+		versionH:      &metadata.VersionHandler{},
+		searchStore:   searchStore,
+		cdcMgr:        cdc.NewManager(),
+		tenantMgr:     tenantMgr,
+		roles:         authz.NewStore(metadata.NewUserStore(&metadata.DefaultMDNameRegistry{})),
+		rateLimits:    ratelimit.NewStore(metadata.NewReservedNamespaceStore(&metadata.DefaultMDNameRegistry{})),
+		storageQuotas: quota.NewStore(metadata.NewReservedNamespaceStore(&metadata.DefaultMDNameRegistry{})),
+		snapshotJobs:  newSnapshotJobs(),
+		adminAuth:     adminAuth,
 	}
 
 	collectionsInSearch, err := u.searchStore.AllCollections(context.TODO())
@@ -108,9 +127,47 @@ func newApiService(kv kv.KeyValueStore, searchStore search.Store, tenantMgr *met
 	}
 	u.runnerFactory = NewQueryRunnerFactory(u.txMgr, u.cdcMgr, u.searchStore)
 
+	if config.DefaultConfig.Cdc.Enabled {
+		intDb, err := u.kvStore.GetInternalDatabase()
+		if err != nil {
+			log.Fatal().Err(err).Msgf("error starting server: loading webhooks failed")
+		}
+		u.webhooks = newWebhookDispatcher(&config.DefaultConfig.Cdc.Webhooks, cdc.NewWebhookStore(intDb.(fdb.Database)), u.kvStore, u.tenantMgr, u.cdcMgr)
+		if config.DefaultConfig.Cdc.Webhooks.Enabled {
+			u.webhooks.start()
+		}
+		u.cdcMgr.StartTrimming(u.kvStore, u.changeLogDatabases)
+	}
+
+	if len(config.DefaultConfig.Snapshot.Path) > 0 {
+		u.snapshots = snapshot.NewFileStore(config.DefaultConfig.Snapshot.Path)
+	}
+
 	return u
 }
 
+// changeLogDatabases returns the names of the databases of all the namespaces, the databases of the same name share
+// their change log.
+func (s *apiService) changeLogDatabases() []string {
+	ctx := context.TODO()
+
+	seen := make(map[string]struct{})
+	var databases []string
+	for _, namespace := range s.tenantMgr.GetNamespaceNames() {
+		tenant, err := s.tenantMgr.GetTenant(ctx, namespace)
+		if ulog.E(err) {
+			continue
+		}
+		for _, dbName := range tenant.ListDatabases(ctx) {
+			if _, ok := seen[dbName]; !ok {
+				seen[dbName] = struct{}{}
+				databases = append(databases, dbName)
+			}
+		}
+	}
+	return databases
+}
+
 func (s *apiService) RegisterHTTP(router chi.Router, inproc *inprocgrpc.Channel) error {
 	mux := runtime.NewServeMux(
 		runtime.WithMarshalerOption(runtime.MIMEWildcard, &api.CustomMarshaler{JSONBuiltin: &runtime.JSONBuiltin{}}),
@@ -136,10 +193,14 @@ func (s *apiService) RegisterHTTP(router chi.Router, inproc *inprocgrpc.Channel)
 	router.HandleFunc(apiPathPrefix+infoPath, func(w http.ResponseWriter, r *http.Request) {
 		mux.ServeHTTP(w, r)
 	})
+	router.Get(apiPathPrefix+limitsPath, writeServerLimits)
 
 	if config.DefaultConfig.Metrics.Enabled {
 		router.Handle(metricsPath, metrics.Reporter.HTTPHandler())
 	}
+	if config.DefaultConfig.Server.AdminRoutes {
+		s.registerAdminRoutes(router)
+	}
 
 	return nil
 }
@@ -174,7 +235,7 @@ func (s *apiService) CommitTransaction(ctx context.Context, _ *api.CommitTransac
 
 	err := session.Commit(s.versionH, session.tx.Context().GetStagedDatabase() != nil, nil)
 	if err != nil {
-		return nil, err
+		return nil, conflictError(s.tenantMgr, session.tenant, err)
 	}
 
 	return &api.CommitTransactionResponse{}, nil
@@ -284,8 +345,22 @@ func (s *apiService) Read(r *api.ReadRequest, stream api.Tigris_ReadServer) erro
 }
 
 func (s *apiService) Search(r *api.SearchRequest, stream api.Tigris_SearchServer) error {
+	if api.GetTransaction(stream.Context()) != nil {
+		// search is served by the indexing store which only sees committed data, so running it inside an
+		// interactive transaction would silently ignore the writes pending in the transaction.
+		return errors.FailedPrecondition("search is not supported inside an interactive transaction, " +
+			"uncommitted writes of the transaction are not visible to search")
+	}
+
 	queryMetrics := metrics.SearchQueryMetrics{}
-	_, err := s.sessions.ReadOnlyExecute(stream.Context(), s.runnerFactory.GetSearchQueryRunner(r, stream, &queryMetrics), &ReqOptions{
+	var runner ReadOnlyQueryRunner
+	if r.IsMultiCollection() {
+		runner = s.runnerFactory.GetMultiCollectionSearchQueryRunner(r, stream, &queryMetrics)
+	} else {
+		runner = s.runnerFactory.GetSearchQueryRunner(r, stream, &queryMetrics)
+	}
+
+	_, err := s.sessions.ReadOnlyExecute(stream.Context(), runner, &ReqOptions{
 		instantVerTracking: true,
 	})
 	if err != nil {
@@ -431,66 +506,175 @@ func (s *apiService) Events(r *api.EventsRequest, stream api.Tigris_EventsServer
 		return errors.InvalidArgument("collection name is missing")
 	}
 
+	ctx := stream.Context()
+	var resumeToken []byte
+	if token := api.GetHeader(ctx, api.HeaderCdcResumeToken); len(token) > 0 {
+		var err error
+		if resumeToken, err = decodeResumeToken(token); err != nil {
+			return err
+		}
+	}
+	includeBefore := api.GetHeader(ctx, api.HeaderCdcIncludeBefore) == "true"
+	reqFilter := []byte(api.GetHeader(ctx, api.HeaderCdcFilter))
+	reqFields := []byte(api.GetHeader(ctx, api.HeaderCdcFields))
+
+	// the collection may not exist yet, in which case the filter is validated once the collection is created
+	selector, err := s.getEventSelector(ctx, r, reqFilter, reqFields, includeBefore)
+	if err != nil {
+		return err
+	}
+
+	consumer, err := s.joinEventsGroup(stream, r)
+	if err != nil {
+		return err
+	}
+	if consumer != nil {
+		if len(resumeToken) > 0 {
+			consumer.leave()
+			return errors.InvalidArgument("resume token can't be used with a consumer group, the group resumes from its committed position")
+		}
+		defer consumer.leave()
+		resumeToken = consumer.group.Position()
+	}
+
 	publisher := s.cdcMgr.GetPublisher(r.GetDb())
-	streamer, err := publisher.NewStreamer(s.kvStore)
+	streamer, err := publisher.NewStreamer(s.kvStore, resumeToken)
 	if err != nil {
 		return err
 	}
-	defer streamer.Close()
+	defer func() { streamer.Close() }()
 
+	// the heartbeats let the client detect a dead stream when there are no changes, they carry the position of the
+	// last change so that the client can resume from it
+	var heartbeats <-chan time.Time
+	if interval := config.DefaultConfig.Cdc.HeartbeatInterval; interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		heartbeats = ticker.C
+	}
+
+	// the members of a group are synced with the group, which also commits their events unless the commits are off
+	var syncs <-chan time.Time
+	interval, autoCommit := syncInterval(&config.DefaultConfig.Cdc)
+	if consumer != nil && interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		syncs = ticker.C
+	}
+
+	position := resumeToken
 	reqDatabaseId, reqCollectionId := uint32(0), uint32(0)
-	for tx := range streamer.Txs {
-		for _, op := range tx.Ops {
-			if reqDatabaseId == 0 || reqCollectionId == 0 {
-				if reqDatabaseId, reqCollectionId = s.tenantMgr.GetDatabaseAndCollectionId(r.GetDb(), r.Collection); reqDatabaseId == 0 || reqCollectionId == 0 {
-					// neither is ready yet
-					continue
-				}
+	for {
+		if consumer.rebalanced() {
+			// the members of the group changed or the group was reset, the member restarts from the position of the group
+			streamer.Close()
+			position = consumer.restart()
+			if streamer, err = publisher.NewStreamer(s.kvStore, position); err != nil {
+				return err
 			}
+		}
 
-			_, dbId, cId, ok := s.tenantMgr.GetEncoder().DecodeTableName(op.Table)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-syncs:
+			if err := consumer.sync(autoCommit); err != nil {
+				return err
+			}
+		case <-heartbeats:
+			response := &api.EventsResponse{
+				Event: &api.StreamEvent{
+					TxId:       position,
+					Collection: r.Collection,
+					Op:         cdc.HeartbeatEvent,
+				},
+			}
+			if err := stream.Send(response); ulog.E(err) {
+				return err
+			}
+		case tx, ok := <-streamer.Txs:
 			if !ok {
-				log.Err(err).Str("table", string(op.Table)).Msg("unexpected key in event streams")
-				return errors.Internal("unexpected key in event streams")
+				return nil
 			}
 
-			if dbId != reqDatabaseId || cId != reqCollectionId {
-				//  the event is no for the collection we are listening to
-				continue
-			}
+			for _, op := range tx.Ops {
+				if reqDatabaseId == 0 || reqCollectionId == 0 {
+					if reqDatabaseId, reqCollectionId = s.tenantMgr.GetDatabaseAndCollectionId(r.GetDb(), r.Collection); reqDatabaseId == 0 || reqCollectionId == 0 {
+						// neither is ready yet
+						continue
+					}
+				}
+				if selector == nil {
+					if selector, err = s.getEventSelector(ctx, r, reqFilter, reqFields, includeBefore); err != nil {
+						return err
+					}
+					if selector == nil {
+						continue
+					}
+				}
+
+				_, dbId, cId, ok := s.tenantMgr.GetEncoder().DecodeTableName(op.Table)
+				if !ok {
+					log.Err(err).Str("table", string(op.Table)).Msg("unexpected key in event streams")
+					return errors.Internal("unexpected key in event streams")
+				}
+
+				if dbId != reqDatabaseId || cId != reqCollectionId {
+					//  the event is no for the collection we are listening to
+					continue
+				}
+
+				if !consumer.owns(op.Key) {
+					// the event is delivered to another member of the group
+					continue
+				}
 
-			var data []byte
-			if op.Op != kv.DeleteEvent && op.Op != kv.DeleteRangeEvent {
-				td, err := internal.Decode(op.Data)
+				data, matched, err := selector.eventData(op)
 				if err != nil {
-					log.Err(err).Str("data", string(op.Data)).Msg("failed to decode data")
-					return errors.Internal("failed to decode data")
+					return err
+				}
+				if !matched {
+					continue
 				}
-				data = td.RawData
-			}
 
-			event := &api.StreamEvent{
-				TxId:       tx.Id,
-				Collection: r.Collection,
-				Op:         op.Op,
-				Key:        op.Key,
-				Lkey:       op.LKey,
-				Rkey:       op.RKey,
-				Data:       data,
-				Last:       op.Last,
-			}
+				event := &api.StreamEvent{
+					TxId:       tx.Id,
+					Collection: r.Collection,
+					Op:         op.Op,
+					Key:        op.Key,
+					Lkey:       op.LKey,
+					Rkey:       op.RKey,
+					Data:       data,
+					Last:       op.Last,
+				}
 
-			response := &api.EventsResponse{
-				Event: event,
-			}
+				response := &api.EventsResponse{
+					Event: event,
+				}
 
-			if err := stream.Send(response); ulog.E(err) {
-				return err
+				if err := stream.Send(response); ulog.E(err) {
+					return err
+				}
 			}
+			position = tx.Id
+			consumer.delivered(tx.Id, tx.CommitTime)
 		}
 	}
+}
 
-	return nil
+// getEventSelector returns the selector of the events of the collection, or nil if the collection doesn't exist yet.
+func (s *apiService) getEventSelector(ctx context.Context, r *api.EventsRequest, reqFilter []byte, reqFields []byte, includeBefore bool) (*eventSelector, error) {
+	tenant, err := s.tenantMgr.GetTenant(ctx, defaults.DefaultNamespaceName)
+	if err != nil {
+		return nil, err
+	}
+
+	coll := tenant.GetCollection(r.GetDb(), r.GetCollection())
+	if coll == nil {
+		return nil, nil
+	}
+
+	return newEventSelector(coll, reqFilter, reqFields, includeBefore)
 }
 
 func (s *apiService) Publish(ctx context.Context, r *api.PublishRequest) (*api.PublishResponse, error) {
@@ -0,0 +1,155 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	goerrors "errors"
+	"fmt"
+	"strings"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+// maxConflictHints bounds the number of conflicting ranges described in the error of a conflict.
+const maxConflictHints = 10
+
+// conflictError returns the conflict of an interactive transaction as an aborted error. The conflicting ranges reported
+// by the store are mapped back to the collections and the primary keys they are in, the collections and the keys are
+// attached to the error and the conflicts are counted per collection. The other errors are returned as they are.
+func conflictError(tenantMgr *metadata.TenantManager, tenant *metadata.Tenant, err error) error {
+	if !goerrors.Is(err, kv.ErrConflictingTransaction) {
+		return err
+	}
+
+	apiErr := api.Errorf(api.Code_ABORTED, "%s", err.Error())
+	var conflict *kv.ConflictError
+	if !goerrors.As(err, &conflict) {
+		return apiErr
+	}
+
+	counted, hints := map[string]struct{}{}, map[string]struct{}{}
+	for _, r := range conflict.Ranges {
+		db, coll, table := conflictCollection(tenantMgr, tenant, r.Begin)
+		if coll == nil {
+			continue
+		}
+
+		if _, ok := counted[db+"."+coll.Name]; !ok {
+			counted[db+"."+coll.Name] = struct{}{}
+			metrics.CountTransactionConflict(tenant.GetNamespace().Metadata().Name, db, coll.Name)
+		}
+
+		hint := conflictHint(coll, table, r.Begin)
+		if _, ok := hints[hint]; !ok && len(hints) < maxConflictHints {
+			hints[hint] = struct{}{}
+			apiErr = apiErr.WithConflict(coll.Name, hint)
+		}
+	}
+
+	return apiErr
+}
+
+// conflictCollection returns the database, the collection of the tenant and the table the key is in, the collection is
+// nil if the key is not in a collection of the tenant.
+func conflictCollection(tenantMgr *metadata.TenantManager, tenant *metadata.Tenant, key []byte) (string, *schema.DefaultCollection, []byte) {
+	nsId, dbId, collId, ok := tenant.Encoder.DecodeTableName(key)
+	if !ok || nsId != tenant.GetNamespace().Id() {
+		return "", nil, nil
+	}
+
+	_, dbName, collName, ok := tenantMgr.GetTableNameFromIds(nsId, dbId, collId)
+	if !ok {
+		return "", nil, nil
+	}
+	db, _ := tenant.GetDatabase(context.Background(), dbName)
+	coll := tenant.GetCollection(dbName, collName)
+	if db == nil || coll == nil {
+		return "", nil, nil
+	}
+
+	var table []byte
+	if bytes.HasPrefix(key, internal.PartitionKeyPrefix) {
+		table, _ = tenant.Encoder.EncodePartitionTableName(tenant.GetNamespace(), db, coll)
+	} else {
+		table, _ = tenant.Encoder.EncodeTableName(tenant.GetNamespace(), db, coll)
+	}
+
+	return dbName, coll, table
+}
+
+// conflictHint describes the conflict on the key of the table of the collection with the values of the primary key the
+// key starts with, like "conflict on collection 'orders' keys near id=12345".
+func conflictHint(coll *schema.DefaultCollection, table []byte, key []byte) string {
+	hint := fmt.Sprintf("conflict on collection '%s'", coll.Name)
+
+	values := conflictKeyValues(table, key)
+	var fields []string
+	for i, f := range coll.Indexes.PrimaryKey.Fields {
+		if i >= len(values) || values[i] == nil {
+			break
+		}
+		fields = append(fields, fmt.Sprintf("%s=%s", f.Name(), formatKeyValue(values[i])))
+	}
+	if len(fields) == 0 {
+		return hint
+	}
+
+	return hint + " keys near " + strings.Join(fields, ", ")
+}
+
+// conflictKeyValues returns the values of the index the key of the table starts with, without the identifier of the
+// index and the partition of the partitioned tables. The end of a conflicting range of a single key is the key followed
+// by a zero byte, which is dropped if the key is not decoded otherwise.
+func conflictKeyValues(table []byte, key []byte) []interface{} {
+	if !bytes.HasPrefix(key, table) {
+		return nil
+	}
+
+	k, err := keys.FromBinary(table, key)
+	if err != nil && bytes.HasSuffix(key, []byte{0}) {
+		k, err = keys.FromBinary(table, key[:len(key)-1])
+	}
+	if err != nil {
+		return nil
+	}
+
+	parts := k.IndexParts()
+	skip := 1
+	if bytes.HasPrefix(table, internal.PartitionKeyPrefix) {
+		skip = 2
+	}
+	if len(parts) <= skip {
+		return nil
+	}
+
+	return parts[skip:]
+}
+
+func formatKeyValue(value interface{}) string {
+	if b, ok := value.([]byte); ok {
+		return fdb.Printable(b)
+	}
+
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+func TestConflictHint(t *testing.T) {
+	factory, err := schema.Build("orders", []byte(`{
+		"title": "orders",
+		"properties": {
+			"id": { "type": "integer" },
+			"region": { "type": "string" },
+			"total": { "type": "number" }
+		},
+		"primary_key": ["region", "id"]
+	}`), false)
+	require.NoError(t, err)
+	coll := schema.NewDefaultCollection("orders", 3, 1, factory.CollectionType, factory, "orders", nil)
+
+	table := []byte("data\x00\x00\x00\x01\x00\x00\x00\x02\x00\x00\x00\x03")
+	idx := []byte{0, 0, 0, 1}
+	key := keys.NewKey(table, idx, "us", int64(12345)).SerializeToBytes()
+
+	require.Equal(t, "conflict on collection 'orders' keys near region=us, id=12345", conflictHint(coll, table, key))
+	// the range of a single key ends with the key followed by a zero byte
+	require.Equal(t, "conflict on collection 'orders' keys near region=us, id=12345",
+		conflictHint(coll, table, append(key, 0)))
+	// the ranges of the prefixes of the keys have the values of the prefix
+	require.Equal(t, "conflict on collection 'orders' keys near region=us",
+		conflictHint(coll, table, keys.NewKey(table, idx, "us").SerializeToBytes()))
+	require.Equal(t, "conflict on collection 'orders'", conflictHint(coll, table, table))
+	require.Equal(t, "conflict on collection 'orders'", conflictHint(coll, table, []byte("other")))
+
+	// the keys of the partitioned tables have the partition after the index
+	partTable := []byte("part\x00\x00\x00\x01\x00\x00\x00\x02\x00\x00\x00\x03")
+	require.Equal(t, "conflict on collection 'orders' keys near region=us, id=12345",
+		conflictHint(coll, partTable, keys.NewKey(partTable, idx, []byte{0, 1}, "us", int64(12345)).SerializeToBytes()))
+}
+
+func TestConflictError(t *testing.T) {
+	// the other errors are returned as they are
+	other := fmt.Errorf("other")
+	require.Equal(t, other, conflictError(nil, nil, other))
+
+	// the conflicts without the conflicting keys are aborted
+	err := conflictError(nil, nil, kv.ErrConflictingTransaction)
+	require.Equal(t, api.Errorf(api.Code_ABORTED, "%s", kv.ErrConflictingTransaction.Error()), err)
+	err = conflictError(nil, nil, &kv.ConflictError{})
+	require.Equal(t, api.Errorf(api.Code_ABORTED, "%s", kv.ErrConflictingTransaction.Error()), err)
+	require.Empty(t, err.(*api.TigrisError).Conflicts())
+}
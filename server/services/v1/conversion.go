@@ -18,7 +18,6 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/schema"
 )
 
@@ -64,7 +63,7 @@ func (p *payloadMutator) traverse(parentMap map[string]any, value any, keys []st
 	if parentField.Type() == schema.Int64Type {
 		if conv, ok := value.(string); ok {
 			if parentMap[parentField.FieldName], err = strconv.ParseInt(conv, 10, 64); err != nil {
-				return errors.InvalidArgument("json schema validation failed for field '%s' reason 'expected integer, but got string'", parentField.FieldName)
+				return schema.NewValidationError(parentField.FieldName, "expected integer, but got string")
 			}
 
 			p.mutated = true
@@ -94,7 +93,7 @@ func (p *payloadMutator) traverse(parentMap map[string]any, value any, keys []st
 			for idx := range converted {
 				if conv, ok := converted[idx].(string); ok {
 					if converted[idx], err = strconv.ParseInt(conv, 10, 64); err != nil {
-						return errors.InvalidArgument("json schema validation failed for field '%s' reason 'expected integer, but got string'", field.FieldName)
+						return schema.NewValidationError(field.FieldName, "expected integer, but got string")
 					}
 					p.mutated = true
 				}
@@ -70,7 +70,7 @@ func TestMutatePayload(t *testing.T) {
 		"primary_key": ["id"]
 	}`)
 
-	schFactory, err := schema.Build("t1", reqSchema)
+	schFactory, err := schema.Build("t1", reqSchema, false)
 	require.NoError(t, err)
 	coll := schema.NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
 	require.Equal(t, 4, len(coll.Int64FieldsPath))
@@ -185,7 +185,7 @@ func BenchmarkStringToInteger(b *testing.B) {
 		"primary_key": ["id"]
 	}`)
 
-	schFactory, err := schema.Build("t1", reqSchema)
+	schFactory, err := schema.Build("t1", reqSchema, false)
 	require.NoError(b, err)
 	coll := schema.NewDefaultCollection("t1", 1, 1, schFactory.CollectionType, schFactory, "t1", nil)
 	require.Equal(b, 4, len(coll.Int64FieldsPath))
@@ -0,0 +1,326 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/tigrisdata/tigris/store/kv"
+	"google.golang.org/grpc"
+	gmetadata "google.golang.org/grpc/metadata"
+)
+
+const (
+	// describeCollectionsPath describes several collections of a database in one call.
+	describeCollectionsPath = adminPath + "/namespaces/{namespace}/databases/{db}/collections/describe"
+
+	// describeCollectionsMaxCount is the maximum number of collections of a batch describe.
+	describeCollectionsMaxCount = 1000
+
+	// describeExactSizeDefaultRowLimit and describeExactSizeMaxRowLimit bound the documents read to compute the exact
+	// sizes.
+	describeExactSizeDefaultRowLimit = 100000
+	describeExactSizeMaxRowLimit     = 1000000
+)
+
+// sizeOptions are the options of the sizes of the descriptions. The sizes are the estimates of the storage unless
+// exact is set, the exact sizes are computed by reading the documents and fail if there are more than the row limit.
+type sizeOptions struct {
+	Exact    bool  `json:"exact"`
+	RowLimit int64 `json:"row_limit"`
+}
+
+// sizeOptionsFromHeaders returns the size options of DescribeDatabase and DescribeCollection, they are passed in the
+// headers of the calls.
+func sizeOptionsFromHeaders(ctx context.Context) (*sizeOptions, error) {
+	opts := &sizeOptions{Exact: api.GetHeader(ctx, api.HeaderSizeExact) == "true"}
+	if value := api.GetHeader(ctx, api.HeaderSizeRowLimit); len(value) > 0 {
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, errors.InvalidArgument("invalid row limit '%s'", value)
+		}
+		opts.RowLimit = limit
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+func (opts *sizeOptions) validate() error {
+	if opts.RowLimit < 0 || opts.RowLimit > describeExactSizeMaxRowLimit {
+		return errors.InvalidArgument("row limit must be between 1 and %d", describeExactSizeMaxRowLimit)
+	}
+	if opts.RowLimit == 0 {
+		opts.RowLimit = describeExactSizeDefaultRowLimit
+	}
+	return nil
+}
+
+// setSizeEstimatedHeader tells the caller of DescribeDatabase and DescribeCollection whether the sizes are estimated.
+func setSizeEstimatedHeader(ctx context.Context, opts *sizeOptions) {
+	if err := grpc.SetHeader(ctx, gmetadata.Pairs(api.HeaderSizeEstimated, strconv.FormatBool(!opts.Exact))); err != nil {
+		log.Debug().Err(err).Msg("failed to set the size estimated header")
+	}
+}
+
+// collectionMetadata is the metadata of a described collection.
+type collectionMetadata struct {
+	// Version is the version of the schema of the collection, it is incremented by every update of the schema.
+	Version     int32  `json:"version"`
+	Type        string `json:"type"`
+	PreImages   bool   `json:"pre_images,omitempty"`
+	AppendOnly  bool   `json:"append_only,omitempty"`
+	Compression string `json:"compression,omitempty"`
+}
+
+func describeMetadata(coll *schema.DefaultCollection) *collectionMetadata {
+	md := &collectionMetadata{
+		Version:    coll.GetVersion(),
+		Type:       string(coll.Type()),
+		PreImages:  coll.PreImages,
+		AppendOnly: coll.AppendOnly,
+	}
+	if coll.Compression != internal.NoCompression {
+		md.Compression = coll.Compression.String()
+	}
+	return md
+}
+
+// setCollectionsMetadataHeader returns the metadata of the collections described by DescribeDatabase and
+// DescribeCollection in a header, the metadata messages of their responses have no fields.
+func setCollectionsMetadataHeader(ctx context.Context, collections []*schema.DefaultCollection) {
+	metadataByName := make(map[string]*collectionMetadata, len(collections))
+	for _, coll := range collections {
+		metadataByName[coll.GetName()] = describeMetadata(coll)
+	}
+	data, err := jsoniter.Marshal(metadataByName)
+	if err != nil {
+		log.Debug().Err(err).Msg("failed to marshal the collections metadata")
+		return
+	}
+	if err = grpc.SetHeader(ctx, gmetadata.Pairs(api.HeaderCollectionsMetadata, string(data))); err != nil {
+		log.Debug().Err(err).Msg("failed to set the collections metadata header")
+	}
+}
+
+// describedCollections returns the collections DescribeDatabase describes, the ones listed in the header of the call
+// or all the collections of the database.
+func describedCollections(ctx context.Context, db *metadata.Database) ([]*schema.DefaultCollection, error) {
+	names, err := describeCollectionNames(ctx)
+	if err != nil || names == nil {
+		return db.ListCollection(), err
+	}
+
+	collections := make([]*schema.DefaultCollection, 0, len(names))
+	for _, name := range names {
+		coll := db.GetCollection(name)
+		if coll == nil {
+			return nil, errors.NotFound("collection doesn't exist '%s'", name)
+		}
+		collections = append(collections, coll)
+	}
+	return collections, nil
+}
+
+// describeCollectionNames returns the names of the collections listed in the header of DescribeDatabase, it is nil
+// if the header is not set.
+func describeCollectionNames(ctx context.Context) ([]string, error) {
+	value := api.GetHeader(ctx, api.HeaderDescribeCollections)
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	names := strings.Split(value, ",")
+	if len(names) > describeCollectionsMaxCount {
+		return nil, errors.InvalidArgument("at most %d collections can be described in one call", describeCollectionsMaxCount)
+	}
+	for i := range names {
+		if names[i] = strings.TrimSpace(names[i]); len(names[i]) == 0 {
+			return nil, errors.InvalidArgument("invalid list of collections to describe '%s'", value)
+		}
+	}
+	return names, nil
+}
+
+// describeCollectionsRequest lists the collections to describe, all the collections of the database are described
+// when the list is empty. The schema format and the size options apply to all of them.
+type describeCollectionsRequest struct {
+	sizeOptions
+
+	Collections  []string `json:"collections"`
+	SchemaFormat string   `json:"schema_format"`
+}
+
+type describeCollectionsResponse struct {
+	Db          string                   `json:"db"`
+	Size        *int64                   `json:"size,omitempty"`
+	Estimated   bool                     `json:"estimated"`
+	Collections []*collectionDescription `json:"collections"`
+}
+
+// collectionDescription is the description of a collection as returned by DescribeCollection, or the error of the
+// collection if it can't be described.
+type collectionDescription struct {
+	Collection string              `json:"collection"`
+	Metadata   *collectionMetadata `json:"metadata,omitempty"`
+	Schema     jsoniter.RawMessage `json:"schema,omitempty"`
+	Size       *int64              `json:"size,omitempty"`
+	Estimated  *bool               `json:"estimated,omitempty"`
+	Error      jsoniter.RawMessage `json:"error,omitempty"`
+}
+
+// describeCollections describes the collections of a database in one call. A collection that doesn't exist, or can't be
+// described, reports its error in its item instead of failing the whole call.
+func (s *apiService) describeCollections(w http.ResponseWriter, r *http.Request) {
+	namespace, dbName := chi.URLParam(r, "namespace"), chi.URLParam(r, "db")
+
+	req := &describeCollectionsRequest{}
+	if err := jsoniter.NewDecoder(r.Body).Decode(req); err != nil {
+		writeAdminError(w, errors.InvalidArgument("invalid describe request: %s", err.Error()))
+		return
+	}
+	if len(req.Collections) > describeCollectionsMaxCount {
+		writeAdminError(w, errors.InvalidArgument("at most %d collections can be described in one call", describeCollectionsMaxCount))
+		return
+	}
+	if err := req.validate(); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	tenant, err := s.tenantMgr.GetTenant(r.Context(), namespace)
+	if err != nil {
+		writeAdminError(w, errors.NotFound("namespace '%s' doesn't exist", namespace))
+		return
+	}
+	db, err := tenant.GetDatabase(r.Context(), dbName)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if db == nil {
+		writeAdminError(w, errors.NotFound("database doesn't exist '%s'", dbName))
+		return
+	}
+
+	names := req.Collections
+	if len(names) == 0 {
+		for _, coll := range db.ListCollection() {
+			names = append(names, coll.GetName())
+		}
+	}
+
+	dbSize, err := describeSize(db.Name(), &req.sizeOptions, func() (int64, error) {
+		return tenant.DatabaseSize(r.Context(), db)
+	}, func() (int64, error) {
+		return tenant.DatabaseSizeExact(r.Context(), db, req.RowLimit)
+	})
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	tenantName := tenant.GetNamespace().Metadata().Name
+	descriptions := describeCollectionList(names, req.SchemaFormat, db.GetCollection,
+		func(coll *schema.DefaultCollection) (int64, error) {
+			size, err := describeSize(coll.GetName(), &req.sizeOptions, func() (int64, error) {
+				return tenant.CollectionSize(r.Context(), db, coll)
+			}, func() (int64, error) {
+				return tenant.CollectionSizeExact(r.Context(), db, coll, req.RowLimit)
+			})
+			if err == nil && !req.Exact {
+				metrics.UpdateCollectionSizeMetrics(namespace, tenantName, db.Name(), coll.GetName(), size)
+			}
+			return size, err
+		}, !req.Exact)
+
+	writeAdminJSON(w, &describeCollectionsResponse{
+		Db:          db.Name(),
+		Size:        &dbSize,
+		Estimated:   !req.Exact,
+		Collections: descriptions,
+	})
+}
+
+// describeSize returns the estimated size, or the exact size if it is requested. The exact size fails with an
+// invalid argument once there are more documents than the row limit of the request.
+func describeSize(name string, opts *sizeOptions, estimated func() (int64, error), exact func() (int64, error)) (int64, error) {
+	if !opts.Exact {
+		return estimated()
+	}
+
+	size, err := exact()
+	if err == kv.ErrRowLimitExceeded {
+		return 0, errors.InvalidArgument("'%s' has more than %d documents, its exact size is not computed", name, opts.RowLimit)
+	}
+	return size, err
+}
+
+// describeCollectionList describes the collections in the order of their names.
+func describeCollectionList(names []string, schemaFormat string, getCollection func(string) *schema.DefaultCollection,
+	collectionSize func(*schema.DefaultCollection) (int64, error), estimated bool,
+) []*collectionDescription {
+	descriptions := make([]*collectionDescription, 0, len(names))
+	for _, name := range names {
+		desc := &collectionDescription{Collection: name}
+		descriptions = append(descriptions, desc)
+
+		coll := getCollection(name)
+		if coll == nil {
+			desc.Error = adminErrorDetails(errors.NotFound("collection doesn't exist '%s'", name))
+			continue
+		}
+
+		size, err := collectionSize(coll)
+		if err != nil {
+			desc.Error = adminErrorDetails(err)
+			continue
+		}
+		sch, err := describeSchema(coll, schemaFormat)
+		if err != nil {
+			desc.Error = adminErrorDetails(err)
+			continue
+		}
+
+		desc.Metadata, desc.Schema, desc.Size, desc.Estimated = describeMetadata(coll), sch, &size, &estimated
+	}
+
+	return descriptions
+}
+
+// describeSchema returns the schema of the collection as it is described, in the requested formats if any.
+func describeSchema(coll *schema.DefaultCollection, schemaFormat string) ([]byte, error) {
+	// remove indexing version from the schema before returning the response
+	sch := schema.RemoveIndexingVersion(coll.Schema)
+
+	// Generate schema in the requested language format
+	if schemaFormat != "" {
+		return schema.Generate(sch, schemaFormat)
+	}
+	return sch, nil
+}
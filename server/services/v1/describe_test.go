@@ -0,0 +1,122 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/schema"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestDescribeCollectionList(t *testing.T) {
+	collections := make(map[string]*schema.DefaultCollection)
+	for _, name := range []string{"orders", "users"} {
+		factory, err := schema.Build(name, []byte(`{
+			"title": "`+name+`",
+			"properties": { "id": { "type": "integer" }, "name": { "type": "string" } },
+			"primary_key": ["id"]
+		}`), false)
+		require.NoError(t, err)
+		collections[name] = schema.NewDefaultCollection(name, 1, 1, factory.CollectionType, factory, name, nil)
+	}
+	getCollection := func(name string) *schema.DefaultCollection {
+		return collections[name]
+	}
+	collectionSize := func(coll *schema.DefaultCollection) (int64, error) {
+		if coll.Name == "orders" {
+			return 0, errors.Internal("size failed")
+		}
+		return 42, nil
+	}
+
+	marshal := func(descriptions []*collectionDescription) string {
+		data, err := jsoniter.Marshal(descriptions)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	t.Run("mixed", func(t *testing.T) {
+		descriptions := describeCollectionList([]string{"users", "missing", "orders"}, "", getCollection, collectionSize, true)
+		require.JSONEq(t, `[
+			{
+				"collection": "users",
+				"metadata": {"version": 1, "type": "documents"},
+				"schema": {"title":"users","properties":{"id":{"type":"integer"},"name":{"type":"string"}},"primary_key":["id"]},
+				"size": 42,
+				"estimated": true
+			},
+			{"collection": "missing", "error": {"code": "NOT_FOUND", "message": "collection doesn't exist 'missing'"}},
+			{"collection": "orders", "error": {"code": "INTERNAL", "message": "size failed"}}
+		]`, marshal(descriptions))
+	})
+
+	t.Run("schema format", func(t *testing.T) {
+		descriptions := describeCollectionList([]string{"users", "missing"}, "go,typescript", getCollection, collectionSize, true)
+		require.Len(t, descriptions, 2)
+		require.Nil(t, descriptions[0].Error)
+
+		expected, err := schema.Generate(schema.RemoveIndexingVersion(collections["users"].Schema), "go,typescript")
+		require.NoError(t, err)
+		require.JSONEq(t, string(expected), string(descriptions[0].Schema))
+		require.Contains(t, jsoniter.Get(descriptions[0].Schema, "go").ToString(), "type User struct")
+		require.Equal(t, "NOT_FOUND", jsoniter.Get(descriptions[1].Error, "code").ToString())
+	})
+
+	t.Run("unknown schema format", func(t *testing.T) {
+		descriptions := describeCollectionList([]string{"users"}, "cobol", getCollection, collectionSize, true)
+		require.Len(t, descriptions, 1)
+		require.Nil(t, descriptions[0].Schema)
+		require.NotEmpty(t, jsoniter.Get(descriptions[0].Error, "message").ToString())
+	})
+}
+
+func TestDescribeMetadata(t *testing.T) {
+	factory, err := schema.Build("t1", []byte(`{
+		"title": "t1",
+		"properties": { "id": { "type": "integer" } },
+		"primary_key": ["id"],
+		"pre_images": true,
+		"compression": "zstd"
+	}`), false)
+	require.NoError(t, err)
+	coll := schema.NewDefaultCollection("t1", 1, 3, factory.CollectionType, factory, "t1", nil)
+	require.Equal(t, internal.ZstdCompression, coll.Compression)
+
+	require.Equal(t, &collectionMetadata{Version: 3, Type: "documents", PreImages: true, Compression: "zstd"}, describeMetadata(coll))
+}
+
+func TestDescribeCollectionNames(t *testing.T) {
+	headers := func(value string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(api.HeaderDescribeCollections, value))
+	}
+
+	names, err := describeCollectionNames(context.Background())
+	require.NoError(t, err)
+	require.Nil(t, names)
+
+	names, err = describeCollectionNames(headers("users, orders"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"users", "orders"}, names)
+
+	_, err = describeCollectionNames(headers("users,,orders"))
+	require.Equal(t, errors.InvalidArgument("invalid list of collections to describe 'users,,orders'"), err)
+}
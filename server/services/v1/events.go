@@ -0,0 +1,325 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/query/filter"
+	"github.com/tigrisdata/tigris/query/read"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/cdc"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/store/kv"
+	ulog "github.com/tigrisdata/tigris/util/log"
+	gmetadata "google.golang.org/grpc/metadata"
+)
+
+const (
+	// latestPosition resets a consumer group to the latest change.
+	latestPosition = "latest"
+
+	// groupResetPath moves the committed position of a consumer group of the change stream of a collection, the
+	// members of the group restart from the new position on any server.
+	groupResetPath = adminPath + "/namespaces/{namespace}/databases/{db}/collections/{collection}/groups/{group}/reset"
+)
+
+// groupResetRequest is the body of a reset, the position is a resume token or "latest", the default.
+type groupResetRequest struct {
+	Position string `json:"position,omitempty"`
+}
+
+// groupResetResponse is the committed position of the group after a reset, it is empty for the latest change.
+type groupResetResponse struct {
+	Group    string `json:"group"`
+	Position []byte `json:"position,omitempty"`
+}
+
+// decodeGroupPosition returns the position of the reset value, nil is the latest change.
+func decodeGroupPosition(reset string) ([]byte, error) {
+	if len(reset) == 0 || reset == latestPosition {
+		return nil, nil
+	}
+	return decodeResumeToken(reset)
+}
+
+func decodeResumeToken(token string) ([]byte, error) {
+	decoded, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.InvalidArgument("invalid resume token")
+	}
+	return decoded, nil
+}
+
+// eventSelector filters the events of a change stream and projects the documents that are emitted. The filter uses
+// the same grammar as the reads and is matched on the new document of an event. The delete events don't have a new
+// document, they are matched on the old document if the log has it, otherwise they are always emitted. The fields
+// projection applies to both the new and the old document.
+type eventSelector struct {
+	includeBefore bool
+	filter        *filter.WrappedFilter
+	fields        *read.FieldFactory
+}
+
+func newEventSelector(coll *schema.DefaultCollection, reqFilter []byte, reqFields []byte, includeBefore bool) (*eventSelector, error) {
+	selector := &eventSelector{
+		includeBefore: includeBefore,
+	}
+
+	var err error
+	if len(reqFilter) > 0 {
+		if selector.filter, err = filter.NewFactory(coll.QueryableFields, nil).WrappedFilter(reqFilter); err != nil {
+			return nil, err
+		}
+	}
+	if len(reqFields) > 0 {
+		if selector.fields, err = read.BuildFields(reqFields); err != nil {
+			return nil, err
+		}
+	}
+
+	return selector, nil
+}
+
+// eventData returns the document to emit for the event and false if the event doesn't pass the filter. With
+// includeBefore the document is returned as {"before": <old document>, "after": <new document>}, either is omitted if
+// the event doesn't have it, i.e. the inserts don't have "before" and the deletes don't have "after".
+func (e *eventSelector) eventData(op *kv.Event) ([]byte, bool, error) {
+	var after, before []byte
+	if op.Op != kv.DeleteEvent && op.Op != kv.DeleteRangeEvent {
+		td, err := internal.Decode(op.Data)
+		if err != nil {
+			log.Err(err).Str("data", string(op.Data)).Msg("failed to decode data")
+			return nil, false, errors.Internal("failed to decode data")
+		}
+		after = td.RawData
+	}
+	if len(op.Before) > 0 {
+		td, err := internal.Decode(op.Before)
+		if err != nil {
+			log.Err(err).Str("data", string(op.Before)).Msg("failed to decode data")
+			return nil, false, errors.Internal("failed to decode data")
+		}
+		before = td.RawData
+	}
+
+	if !e.matches(after, before) {
+		return nil, false, nil
+	}
+
+	var err error
+	if after, err = e.project(after); err != nil {
+		return nil, false, err
+	}
+	if !e.includeBefore {
+		return after, true, nil
+	}
+
+	if before, err = e.project(before); err != nil {
+		return nil, false, err
+	}
+	images := make(map[string]jsoniter.RawMessage, 2)
+	if before != nil {
+		images["before"] = before
+	}
+	if after != nil {
+		images["after"] = after
+	}
+	data, err := jsoniter.Marshal(images)
+	return data, err == nil, err
+}
+
+func (e *eventSelector) matches(after []byte, before []byte) bool {
+	switch {
+	case e.filter == nil:
+		return true
+	case after != nil:
+		return e.filter.Matches(after)
+	case before != nil:
+		return e.filter.Matches(before)
+	default:
+		return true
+	}
+}
+
+func (e *eventSelector) project(doc []byte) ([]byte, error) {
+	if e.fields == nil || doc == nil {
+		return doc, nil
+	}
+
+	return e.fields.Apply(doc)
+}
+
+// eventsConsumer is the member of a consumer group that reads a change stream. It keeps track of the position of the
+// events delivered to the member so that it can be committed. All the methods are no-ops if the stream is not part of
+// a group.
+type eventsConsumer struct {
+	group      *cdc.Group
+	member     *cdc.Member
+	generation uint64
+	position   []byte
+	commitTime int64
+}
+
+// joinEventsGroup adds the stream to the consumer group of the request, if any. The id of the member is sent back in
+// the headers of the stream.
+func (s *apiService) joinEventsGroup(stream api.Tigris_EventsServer, r *api.EventsRequest) (*eventsConsumer, error) {
+	ctx := stream.Context()
+	name := api.GetHeader(ctx, api.HeaderCdcGroup)
+	if len(name) == 0 {
+		return nil, nil
+	}
+
+	group, err := s.cdcMgr.GetGroup(s.kvStore, r.GetDb(), r.GetCollection(), name)
+	if err != nil {
+		return nil, err
+	}
+
+	if reset := api.GetHeader(ctx, api.HeaderCdcGroupReset); len(reset) > 0 {
+		position, err := decodeGroupPosition(reset)
+		if err != nil {
+			return nil, err
+		}
+		if err = group.Reset(position); err != nil {
+			return nil, err
+		}
+	}
+
+	member, err := group.Join(api.GetHeader(ctx, api.HeaderCdcGroupMember))
+	if err != nil {
+		return nil, err
+	}
+	if err = stream.SendHeader(gmetadata.Pairs(api.HeaderCdcGroupMember, member.Id)); err != nil {
+		ulog.E(group.Leave(member))
+		return nil, err
+	}
+
+	return &eventsConsumer{
+		group:      group,
+		member:     member,
+		generation: group.Generation(),
+	}, nil
+}
+
+func (c *eventsConsumer) owns(key []byte) bool {
+	return c == nil || c.group.Owns(c.member, key)
+}
+
+func (c *eventsConsumer) delivered(position []byte, commitTime int64) {
+	if c == nil {
+		return
+	}
+
+	c.position, c.commitTime = position, commitTime
+}
+
+func (c *eventsConsumer) commit() {
+	if c == nil || c.position == nil {
+		return
+	}
+
+	ulog.E(c.group.Commit(c.member, c.generation, c.position, c.commitTime))
+}
+
+// sync commits the events delivered to the member if autoCommit is set, otherwise it only refreshes the member. Both
+// refresh the state of the group. The stream of a member that has been removed from the group stops.
+func (c *eventsConsumer) sync(autoCommit bool) error {
+	var err error
+	if autoCommit && c.position != nil {
+		err = c.group.Commit(c.member, c.generation, c.position, c.commitTime)
+	} else {
+		err = c.group.Heartbeat(c.member)
+	}
+	if cdc.IsNotActive(err) {
+		return err
+	}
+
+	ulog.E(err)
+	return nil
+}
+
+// syncInterval is how often the member is synced with its group, see sync.
+func syncInterval(cfg *config.CdcConfig) (time.Duration, bool) {
+	if cfg.GroupCommitInterval > 0 {
+		return cfg.GroupCommitInterval, true
+	}
+	return cfg.GroupMemberTimeout / 3, false
+}
+
+// rebalanced returns true if the member must restart from the committed position of the group.
+func (c *eventsConsumer) rebalanced() bool {
+	return c != nil && c.group.Generation() != c.generation
+}
+
+// restart returns the position the member restarts from.
+func (c *eventsConsumer) restart() []byte {
+	c.generation = c.group.Generation()
+	c.position, c.commitTime = nil, 0
+	return c.group.Position()
+}
+
+// leave commits the events delivered to the member and removes it from the group.
+func (c *eventsConsumer) leave() {
+	if c == nil {
+		return
+	}
+
+	c.commit()
+	ulog.E(c.group.Leave(c.member))
+}
+
+// resetGroup moves the committed position of a consumer group without joining it, the group is created if it doesn't
+// exist yet.
+func (s *apiService) resetGroup(w http.ResponseWriter, r *http.Request) {
+	namespace := chi.URLParam(r, "namespace")
+	if _, err := s.tenantMgr.GetTenant(r.Context(), namespace); err != nil {
+		writeAdminError(w, errors.NotFound("namespace '%s' doesn't exist", namespace))
+		return
+	}
+
+	req := &groupResetRequest{}
+	if r.ContentLength != 0 {
+		if err := jsoniter.NewDecoder(r.Body).Decode(req); err != nil {
+			writeAdminError(w, errors.InvalidArgument("invalid reset: %s", err.Error()))
+			return
+		}
+	}
+	position, err := decodeGroupPosition(req.Position)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	name := chi.URLParam(r, "group")
+	group, err := s.cdcMgr.GetGroup(s.kvStore, chi.URLParam(r, "db"), chi.URLParam(r, "collection"), name)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if err = group.Reset(position); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	writeAdminJSON(w, &groupResetResponse{Group: name, Position: group.Position()})
+}
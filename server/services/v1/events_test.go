@@ -0,0 +1,172 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+func encodeEventDoc(t *testing.T, doc string) []byte {
+	enc, err := internal.Encode(internal.NewTableData([]byte(doc)))
+	require.NoError(t, err)
+	return enc
+}
+
+func TestEventSelector(t *testing.T) {
+	collection := &schema.DefaultCollection{
+		QueryableFields: []*schema.QueryableField{
+			schema.NewQueryableField("id", schema.Int64Type, schema.UnknownType, nil, nil),
+			schema.NewQueryableField("status", schema.StringType, schema.UnknownType, nil, nil),
+			schema.NewQueryableField("reason", schema.StringType, schema.UnknownType, nil, nil),
+		},
+	}
+
+	failed := `{"id":1,"status":"failed","reason":"timeout"}`
+	pending := `{"id":1,"status":"pending","reason":""}`
+
+	t.Run("no_filter", func(t *testing.T) {
+		selector, err := newEventSelector(collection, nil, nil, false)
+		require.NoError(t, err)
+
+		data, matched, err := selector.eventData(&kv.Event{Op: kv.InsertEvent, Data: encodeEventDoc(t, pending)})
+		require.NoError(t, err)
+		require.True(t, matched)
+		require.JSONEq(t, pending, string(data))
+	})
+
+	t.Run("filter_on_new_document", func(t *testing.T) {
+		selector, err := newEventSelector(collection, []byte(`{"status": "failed"}`), nil, false)
+		require.NoError(t, err)
+
+		_, matched, err := selector.eventData(&kv.Event{Op: kv.InsertEvent, Data: encodeEventDoc(t, pending)})
+		require.NoError(t, err)
+		require.False(t, matched)
+
+		// the update is matched on the new document, not the old one
+		data, matched, err := selector.eventData(&kv.Event{
+			Op:     kv.UpdateEvent,
+			Data:   encodeEventDoc(t, failed),
+			Before: encodeEventDoc(t, pending),
+		})
+		require.NoError(t, err)
+		require.True(t, matched)
+		require.JSONEq(t, failed, string(data))
+
+		_, matched, err = selector.eventData(&kv.Event{
+			Op:     kv.UpdateEvent,
+			Data:   encodeEventDoc(t, pending),
+			Before: encodeEventDoc(t, failed),
+		})
+		require.NoError(t, err)
+		require.False(t, matched)
+	})
+
+	t.Run("filter_on_delete", func(t *testing.T) {
+		selector, err := newEventSelector(collection, []byte(`{"status": "failed"}`), nil, false)
+		require.NoError(t, err)
+
+		// without the old document the deletes are always emitted
+		data, matched, err := selector.eventData(&kv.Event{Op: kv.DeleteEvent})
+		require.NoError(t, err)
+		require.True(t, matched)
+		require.Nil(t, data)
+
+		_, matched, err = selector.eventData(&kv.Event{Op: kv.DeleteEvent, Before: encodeEventDoc(t, pending)})
+		require.NoError(t, err)
+		require.False(t, matched)
+
+		_, matched, err = selector.eventData(&kv.Event{Op: kv.DeleteEvent, Before: encodeEventDoc(t, failed)})
+		require.NoError(t, err)
+		require.True(t, matched)
+	})
+
+	t.Run("projection", func(t *testing.T) {
+		selector, err := newEventSelector(collection, []byte(`{"status": "failed"}`), []byte(`{"id": true, "status": true}`), true)
+		require.NoError(t, err)
+
+		data, matched, err := selector.eventData(&kv.Event{Op: kv.InsertEvent, Data: encodeEventDoc(t, failed)})
+		require.NoError(t, err)
+		require.True(t, matched)
+		require.JSONEq(t, `{"after":{"id":1,"status":"failed"}}`, string(data))
+
+		data, matched, err = selector.eventData(&kv.Event{
+			Op:     kv.UpdateEvent,
+			Data:   encodeEventDoc(t, failed),
+			Before: encodeEventDoc(t, pending),
+		})
+		require.NoError(t, err)
+		require.True(t, matched)
+		require.JSONEq(t, `{"before":{"id":1,"status":"pending"},"after":{"id":1,"status":"failed"}}`, string(data))
+	})
+
+	t.Run("pre_images", func(t *testing.T) {
+		selector, err := newEventSelector(collection, nil, nil, true)
+		require.NoError(t, err)
+
+		data, matched, err := selector.eventData(&kv.Event{
+			Op:     kv.ReplaceEvent,
+			Data:   encodeEventDoc(t, failed),
+			Before: encodeEventDoc(t, pending),
+		})
+		require.NoError(t, err)
+		require.True(t, matched)
+		require.JSONEq(t, `{"before":`+pending+`,"after":`+failed+`}`, string(data))
+
+		data, matched, err = selector.eventData(&kv.Event{Op: kv.DeleteEvent, Before: encodeEventDoc(t, failed)})
+		require.NoError(t, err)
+		require.True(t, matched)
+		require.JSONEq(t, `{"before":`+failed+`}`, string(data))
+
+		// without the pre-images stored in the log only the new document is available
+		data, matched, err = selector.eventData(&kv.Event{Op: kv.UpdateEvent, Data: encodeEventDoc(t, failed)})
+		require.NoError(t, err)
+		require.True(t, matched)
+		require.JSONEq(t, `{"after":`+failed+`}`, string(data))
+	})
+
+	t.Run("invalid_filter", func(t *testing.T) {
+		_, err := newEventSelector(collection, []byte(`{"unknown": 1}`), nil, false)
+		require.Error(t, err)
+	})
+}
+
+func TestGroupSync(t *testing.T) {
+	position, err := decodeGroupPosition("")
+	require.NoError(t, err)
+	require.Nil(t, position)
+	position, err = decodeGroupPosition(latestPosition)
+	require.NoError(t, err)
+	require.Nil(t, position)
+	position, err = decodeGroupPosition("AQI=")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x01, 0x02}, position)
+	_, err = decodeGroupPosition("?")
+	require.Error(t, err)
+
+	// the members are refreshed even if the commits are off
+	interval, autoCommit := syncInterval(&config.CdcConfig{GroupCommitInterval: time.Second, GroupMemberTimeout: 30 * time.Second})
+	require.Equal(t, time.Second, interval)
+	require.True(t, autoCommit)
+	interval, autoCommit = syncInterval(&config.CdcConfig{GroupMemberTimeout: 30 * time.Second})
+	require.Equal(t, 10*time.Second, interval)
+	require.False(t, autoCommit)
+}
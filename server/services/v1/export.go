@@ -0,0 +1,342 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/query/filter"
+	"github.com/tigrisdata/tigris/query/read"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/store/kv"
+	ulog "github.com/tigrisdata/tigris/util/log"
+)
+
+const (
+	// exportPath streams the documents of a collection as newline-delimited JSON. The optional "filter" and "fields"
+	// query parameters have the same format as the filter and the fields of a read.
+	exportPath = adminPath + "/namespaces/{namespace}/databases/{db}/collections/{collection}/export"
+
+	// exportChunkDuration is how long an export reads in a single transaction, it is below the 5 seconds limit of the
+	// transactions so that a chunk is rarely interrupted.
+	exportChunkDuration = 3 * time.Second
+	// exportProgressInterval is how often the progress of an export is written in the stream.
+	exportProgressInterval = time.Second
+)
+
+var errExportChunkDone = fmt.Errorf("export chunk done")
+
+// chunkWriter consumes the rows of a collection read in chunks, see scanChunks.
+type chunkWriter interface {
+	// write consumes the rows of the iterator until it is exhausted or the deadline is reached, in which case
+	// errExportChunkDone is returned. The rows up to the last key are skipped.
+	write(iterator Iterator, deadline time.Time) error
+	// lastKey returns the key of the last row consumed, the next chunk starts right after it.
+	lastKey() []byte
+	// advance moves the last key to the key of a row scanned after it, the rows skipped by the filter are then not
+	// scanned again.
+	advance(key []byte)
+}
+
+// chunkScanIterator records the key of the last row scanned in a chunk, the rows skipped by the filter included, and
+// stops the scan once the deadline of the chunk is reached.
+type chunkScanIterator struct {
+	Iterator
+
+	deadline time.Time
+	last     []byte
+	expired  bool
+}
+
+func (it *chunkScanIterator) Next(row *Row) bool {
+	if time.Now().After(it.deadline) {
+		it.expired = true
+		return false
+	}
+	if !it.Iterator.Next(row) {
+		return false
+	}
+	it.last = row.Key
+	return true
+}
+
+// exportProgress is written in the stream as {"metadata": {...}}. The "metadata" field is reserved, so the progress
+// lines can't be confused with the documents.
+type exportProgress struct {
+	Documents int64 `json:"documents"`
+	Bytes     int64 `json:"bytes"`
+	Done      bool  `json:"done,omitempty"`
+	// Error is set if the export failed after the documents have started streaming, it has the same format as the
+	// errors of the API.
+	Error jsoniter.RawMessage `json:"error,omitempty"`
+}
+
+// exportWriter writes the rows as NDJSON, one document per line, along with the progress of the export.
+type exportWriter struct {
+	w        io.Writer
+	flush    func() error
+	fields   *read.FieldFactory
+	interval time.Duration
+
+	progress     exportProgress
+	lastProgress time.Time
+	// last is the key of the last document exported, the next chunk starts right after it.
+	last []byte
+}
+
+func newExportWriter(w io.Writer, flush func() error, fields *read.FieldFactory, interval time.Duration) *exportWriter {
+	return &exportWriter{
+		w:            w,
+		flush:        flush,
+		fields:       fields,
+		interval:     interval,
+		lastProgress: time.Now(),
+	}
+}
+
+// write exports the rows of the iterator until it is exhausted or the deadline is reached, in which case
+// errExportChunkDone is returned. The rows up to the last key exported are skipped.
+func (e *exportWriter) write(iterator Iterator, deadline time.Time) error {
+	var row Row
+	for iterator.Next(&row) {
+		if e.last != nil && bytes.Compare(row.Key, e.last) <= 0 {
+			continue
+		}
+
+		doc, err := e.fields.Apply(row.Data.RawData)
+		if err != nil {
+			return err
+		}
+		if err = e.writeLine(doc); err != nil {
+			return err
+		}
+		e.last = row.Key
+		e.progress.Documents++
+		e.progress.Bytes += int64(len(doc))
+
+		if time.Since(e.lastProgress) >= e.interval {
+			if err = e.writeProgress(); err != nil {
+				return err
+			}
+		}
+		if time.Now().After(deadline) {
+			return errExportChunkDone
+		}
+	}
+
+	return iterator.Interrupted()
+}
+
+func (e *exportWriter) lastKey() []byte {
+	return e.last
+}
+
+func (e *exportWriter) advance(key []byte) {
+	if bytes.Compare(key, e.last) > 0 {
+		e.last = key
+	}
+}
+
+// done writes the final progress of the export.
+func (e *exportWriter) done() error {
+	e.progress.Done = true
+	return e.writeProgress()
+}
+
+// fail writes the error along with the progress, the status of the response is already sent.
+func (e *exportWriter) fail(err error) error {
+	e.progress.Error = adminErrorDetails(err)
+	return e.writeProgress()
+}
+
+func (e *exportWriter) writeProgress() error {
+	data, err := jsoniter.Marshal(map[string]*exportProgress{"metadata": &e.progress})
+	if err != nil {
+		return err
+	}
+	if err = e.writeLine(data); err != nil {
+		return err
+	}
+	e.lastProgress = time.Now()
+
+	return e.flush()
+}
+
+func (e *exportWriter) writeLine(data []byte) error {
+	if _, err := e.w.Write(data); err != nil {
+		return err
+	}
+	_, err := e.w.Write([]byte("\n"))
+	return err
+}
+
+// exportDocuments streams all the documents of a collection, in the order of their primary key. Every chunk of the
+// export is read from a snapshot with its own transaction, so that an export is not bounded by the lifetime of a
+// transaction. The export as a whole is therefore not a snapshot of the collection: a document is exported at most
+// once, as of the chunk it is read in, and the changes made to the documents during the export are exported only if
+// their document has not been read yet.
+func (s *apiService) exportDocuments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	namespace, dbName, collName := chi.URLParam(r, "namespace"), chi.URLParam(r, "db"), chi.URLParam(r, "collection")
+
+	tenant, err := s.tenantMgr.GetTenant(ctx, namespace)
+	if err != nil {
+		writeAdminError(w, errors.NotFound("namespace '%s' doesn't exist", namespace))
+		return
+	}
+	db, err := tenant.GetDatabase(ctx, dbName)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if db == nil {
+		writeAdminError(w, errors.NotFound("database doesn't exist '%s'", dbName))
+		return
+	}
+	coll := db.GetCollection(collName)
+	if coll == nil {
+		writeAdminError(w, errors.NotFound("collection doesn't exist '%s'", collName))
+		return
+	}
+
+	wrapped, err := filter.NewFactory(coll.QueryableFields, nil).WrappedFilter([]byte(r.URL.Query().Get("filter")))
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	fields, err := read.BuildFields([]byte(r.URL.Query().Get("fields")))
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	table, err := metadata.NewEncoder().EncodeTableName(tenant.GetNamespace(), db, coll)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	var out io.Writer = w
+	flush := func() error {
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	if acceptsGzip(r.Header.Get("Accept-Encoding")) {
+		gz := gzip.NewWriter(w)
+		defer func() { _ = gz.Close() }()
+
+		w.Header().Set("Content-Encoding", "gzip")
+		out, flush = gz, func() error {
+			if err := gz.Flush(); err != nil {
+				return err
+			}
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+			return nil
+		}
+	}
+
+	exp := newExportWriter(out, flush, fields, exportProgressInterval)
+	if err = s.scanChunks(ctx, table, wrapped, exp); err == nil {
+		err = exp.done()
+	}
+	if err != nil && ctx.Err() == nil {
+		log.Err(err).Str("collection", collName).Msg("export failed")
+		ulog.E(exp.fail(err))
+	}
+}
+
+// scanChunks reads the rows of the table in chunks, every chunk with its own transaction. The filter is optional. A
+// chunk resumes after the last row scanned by the previous chunk, so the chunks without any row matching the filter
+// still move the export forward.
+func (s *apiService) scanChunks(ctx context.Context, table []byte, wrapped *filter.WrappedFilter, cw chunkWriter) error {
+	for {
+		from := keys.NewKey(table)
+		if last := cw.lastKey(); last != nil {
+			var err error
+			if from, err = keys.FromBinary(table, last); err != nil {
+				return err
+			}
+		}
+
+		tx, err := s.txMgr.StartTx(ctx)
+		if err != nil {
+			return err
+		}
+
+		// the reads of the shards that are not consumed are stopped once the chunk is done
+		chunkCtx, cancel := context.WithCancel(ctx)
+		reader := NewDatabaseReader(chunkCtx, tx)
+		deadline := time.Now().Add(exportChunkDuration)
+		// the export resumes from the last key scanned, so the rows are read in order
+		var scanned *chunkScanIterator
+		iter, err := reader.ParallelScanIterator(from)
+		if err == nil {
+			scanned = &chunkScanIterator{Iterator: iter, deadline: deadline}
+			iter = scanned
+			if wrapped != nil {
+				iter, err = reader.FilteredRead(iter, wrapped)
+			}
+		}
+		if err == nil {
+			err = cw.write(iter, deadline)
+		}
+		cancel()
+		_ = tx.Rollback(ctx)
+
+		if err == nil && scanned.expired {
+			err = errExportChunkDone
+		}
+		if err == kv.ErrTransactionMaxDurationReached && kv.IsReadVersionPinned(ctx) {
+			// the next chunks would read at the same version, which is too old
+			return errors.DeadlineExceeded("the scan is not done within the lifetime of its read version")
+		}
+		if err == errExportChunkDone || err == kv.ErrTransactionMaxDurationReached {
+			if scanned != nil && scanned.last != nil {
+				cw.advance(scanned.last)
+			}
+			continue
+		}
+		return err
+	}
+}
+
+// acceptsGzip returns true if the Accept-Encoding header of the request allows gzip.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		q := strings.ReplaceAll(params, " ", "")
+		return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+	}
+	return false
+}
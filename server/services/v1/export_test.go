@@ -0,0 +1,149 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/query/read"
+)
+
+type sliceIterator struct {
+	rows []Row
+}
+
+func (s *sliceIterator) Next(row *Row) bool {
+	if len(s.rows) == 0 {
+		return false
+	}
+	*row, s.rows = s.rows[0], s.rows[1:]
+	return true
+}
+
+func (s *sliceIterator) Interrupted() error { return nil }
+
+// skipIterator is a filter matching none of the rows.
+type skipIterator struct {
+	Iterator
+}
+
+func (s *skipIterator) Next(row *Row) bool {
+	for s.Iterator.Next(row) {
+		// the row doesn't match
+	}
+	return false
+}
+
+func exportRows(docs ...string) *sliceIterator {
+	it := &sliceIterator{}
+	for i, doc := range docs {
+		it.rows = append(it.rows, Row{Key: []byte{byte(i + 1)}, Data: internal.NewTableData([]byte(doc))})
+	}
+	return it
+}
+
+func TestExportWriter(t *testing.T) {
+	noFlush := func() error { return nil }
+	allFields, err := read.BuildFields(nil)
+	require.NoError(t, err)
+
+	t.Run("documents", func(t *testing.T) {
+		var buf bytes.Buffer
+		fields, err := read.BuildFields([]byte(`{"id": true}`))
+		require.NoError(t, err)
+
+		exp := newExportWriter(&buf, noFlush, fields, time.Hour)
+		require.NoError(t, exp.write(exportRows(`{"id":1,"name":"a"}`, `{"id":2,"name":"b"}`), time.Now().Add(time.Hour)))
+		require.NoError(t, exp.done())
+
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		require.Len(t, lines, 3)
+		require.JSONEq(t, `{"id":1}`, lines[0])
+		require.JSONEq(t, `{"id":2}`, lines[1])
+		require.JSONEq(t, `{"metadata":{"documents":2,"bytes":16,"done":true}}`, lines[2])
+	})
+
+	t.Run("chunks", func(t *testing.T) {
+		var buf bytes.Buffer
+		exp := newExportWriter(&buf, noFlush, allFields, 0)
+
+		// the deadline is reached after the first document, along with the progress interval
+		require.Equal(t, errExportChunkDone, exp.write(exportRows(`{"id":1}`, `{"id":2}`), time.Now()))
+		require.Equal(t, []byte{1}, exp.last)
+
+		// the next chunk starts with the last document exported, it is not exported again
+		require.NoError(t, exp.write(exportRows(`{"id":1}`, `{"id":2}`), time.Now().Add(time.Hour)))
+
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		require.Equal(t, []string{
+			`{"id":1}`,
+			`{"metadata":{"documents":1,"bytes":8}}`,
+			`{"id":2}`,
+			`{"metadata":{"documents":2,"bytes":16}}`,
+		}, lines)
+	})
+
+	t.Run("filtered chunks", func(t *testing.T) {
+		var buf bytes.Buffer
+		exp := newExportWriter(&buf, noFlush, allFields, time.Hour)
+
+		// none of the rows scanned in the chunk match the filter, the next chunk starts after the last one scanned
+		scanned := &chunkScanIterator{Iterator: exportRows(`{"id":1}`, `{"id":2}`), deadline: time.Now().Add(time.Hour)}
+		require.NoError(t, exp.write(&skipIterator{scanned}, time.Now().Add(time.Hour)))
+		require.Nil(t, exp.lastKey())
+		require.Equal(t, []byte{2}, scanned.last)
+		exp.advance(scanned.last)
+		require.Equal(t, []byte{2}, exp.lastKey())
+
+		// the last key never moves back
+		exp.advance([]byte{1})
+		require.Equal(t, []byte{2}, exp.lastKey())
+		require.Empty(t, buf.String())
+	})
+
+	t.Run("chunk deadline", func(t *testing.T) {
+		scanned := &chunkScanIterator{Iterator: exportRows(`{"id":1}`), deadline: time.Now().Add(-time.Second)}
+		var row Row
+		require.False(t, scanned.Next(&row))
+		require.True(t, scanned.expired)
+		require.Nil(t, scanned.last)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		var buf bytes.Buffer
+		exp := newExportWriter(&buf, noFlush, allFields, time.Hour)
+		require.NoError(t, exp.write(exportRows(`{"id":1}`), time.Now().Add(time.Hour)))
+		require.NoError(t, exp.fail(errors.Internal("read failed")))
+
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		require.Len(t, lines, 2)
+		require.JSONEq(t, `{"metadata":{"documents":1,"bytes":8,"error":{"code":"INTERNAL","message":"read failed"}}}`, lines[1])
+	})
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	require.True(t, acceptsGzip("gzip"))
+	require.True(t, acceptsGzip("deflate, gzip;q=0.8"))
+	require.True(t, acceptsGzip("GZIP"))
+	require.False(t, acceptsGzip(""))
+	require.False(t, acceptsGzip("deflate, br"))
+	require.False(t, acceptsGzip("gzip;q=0"))
+}
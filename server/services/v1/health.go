@@ -15,41 +15,160 @@
 package v1
 
 import (
+	"bytes"
 	"context"
 	"net/http"
+	"sync"
 
 	"github.com/fullstorydev/grpchan/inprocgrpc"
 	"github.com/go-chi/chi/v5"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/metadata"
 	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/search"
+	"go.uber.org/atomic"
 	"google.golang.org/grpc"
 )
 
 const (
-	healthPath = "/health"
+	healthPath    = "/health"
+	livenessPath  = healthPath + "/live"
+	readinessPath = healthPath + "/ready"
 )
 
+// The states of the components checked by the health probes, and of the server as a whole.
+const (
+	healthStatusOK          = "ok"
+	healthStatusDegraded    = "degraded"
+	healthStatusUnavailable = "unavailable"
+)
+
+// shuttingDown is set once the server is asked to stop, the readiness probe fails from then on.
+var shuttingDown atomic.Bool
+
+// SetShuttingDown makes the readiness probe fail, so that the load balancers stop routing new requests to the server
+// while it stops.
+func SetShuttingDown() {
+	shuttingDown.Store(true)
+}
+
+// componentHealth is the state of one of the components the server depends on.
+type componentHealth struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+
+	// err is the error returned by the Health method when the component is unavailable
+	err error
+}
+
+type healthReport struct {
+	Status     string            `json:"status"`
+	Components []componentHealth `json:"components"`
+}
+
+// newHealthReport returns the report of the components, the server is unavailable if any of them is unavailable.
+func newHealthReport(components ...componentHealth) *healthReport {
+	report := &healthReport{Status: healthStatusOK, Components: components}
+	for _, c := range components {
+		switch {
+		case c.Status == healthStatusUnavailable:
+			report.Status = healthStatusUnavailable
+		case c.Status == healthStatusDegraded && report.Status == healthStatusOK:
+			report.Status = healthStatusDegraded
+		}
+	}
+	return report
+}
+
+// err returns the error of the first unavailable component.
+func (r *healthReport) err() error {
+	for _, c := range r.Components {
+		if c.Status == healthStatusUnavailable {
+			return c.err
+		}
+	}
+	return nil
+}
+
+func unavailableComponent(name string, err error) componentHealth {
+	return componentHealth{Name: name, Status: healthStatusUnavailable, Message: err.Error(), err: err}
+}
+
 type healthService struct {
 	api.UnimplementedHealthAPIServer
 
-	versionH *metadata.VersionHandler
-	txMgr    *transaction.Manager
+	versionH    *metadata.VersionHandler
+	txMgr       *transaction.Manager
+	searchStore search.Store
+	tenantMgr   *metadata.TenantManager
 }
 
-func newHealthService(txMgr *transaction.Manager) *healthService {
+func newHealthService(txMgr *transaction.Manager, searchStore search.Store, tenantMgr *metadata.TenantManager) *healthService {
 	return &healthService{
-		versionH: &metadata.VersionHandler{},
-		txMgr:    txMgr,
+		versionH:    &metadata.VersionHandler{},
+		txMgr:       txMgr,
+		searchStore: searchStore,
+		tenantMgr:   tenantMgr,
+	}
+}
+
+// check pings FoundationDB and the search backend concurrently, and compares the metadata version of the cache to the
+// latest one.
+func (h *healthService) check(ctx context.Context) *healthReport {
+	if timeout := config.DefaultConfig.Server.HealthCheckTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var (
+		wg                sync.WaitGroup
+		latest            metadata.Version
+		fdb, searchEngine componentHealth
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		fdb = componentHealth{Name: "fdb", Status: healthStatusOK}
+		var err error
+		if latest, err = h.versionH.ReadInOwnTxn(ctx, h.txMgr, false); err != nil {
+			fdb = unavailableComponent(fdb.Name, errors.Unavailable("Could not read metadata version"))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		searchEngine = componentHealth{Name: "search", Status: healthStatusOK}
+		// the search store fails fast while the search backend is unhealthy, the error carries the retry hint
+		if err := h.searchStore.Health(ctx); err != nil {
+			searchEngine = unavailableComponent(searchEngine.Name, err)
+		}
+	}()
+	wg.Wait()
+
+	cached, loaded := h.tenantMgr.CachedVersion()
+	return newHealthReport(fdb, searchEngine, metadataHealth(cached, loaded, latest))
+}
+
+// metadataHealth returns the state of the metadata cache. A cache behind the latest version is only degraded, the
+// tenants are reloaded by the next requests that use them.
+func metadataHealth(cached metadata.Version, loaded bool, latest metadata.Version) componentHealth {
+	c := componentHealth{Name: "metadata", Status: healthStatusOK}
+	switch {
+	case !loaded:
+		return unavailableComponent(c.Name, errors.Unavailable("Metadata is not loaded"))
+	case latest != nil && bytes.Compare(cached, latest) < 0:
+		c.Status, c.Message = healthStatusDegraded, "metadata cache is behind the latest version"
 	}
+	return c
 }
 
 func (h *healthService) Health(ctx context.Context, _ *api.HealthCheckInput) (*api.HealthCheckResponse, error) {
-	_, err := h.versionH.ReadInOwnTxn(ctx, h.txMgr, false)
-	if err != nil {
-		return nil, errors.Unavailable("Could not read metadata version")
+	if err := h.check(ctx).err(); err != nil {
+		return nil, err
 	}
 
 	return &api.HealthCheckResponse{
@@ -57,6 +176,32 @@ func (h *healthService) Health(ctx context.Context, _ *api.HealthCheckInput) (*a
 	}, nil
 }
 
+// readiness reports the state of the components, the server is not ready while its metadata is not loaded, while any of
+// the backends is unavailable and once it is shutting down.
+func (h *healthService) readiness(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, h.check(r.Context()), shuttingDown.Load())
+}
+
+func writeHealthReport(w http.ResponseWriter, report *healthReport, stopping bool) {
+	if stopping {
+		report.Status = healthStatusUnavailable
+		report.Components = append(report.Components, componentHealth{
+			Name:    "server",
+			Status:  healthStatusUnavailable,
+			Message: "server is shutting down",
+		})
+	}
+	if report.Status == healthStatusUnavailable {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	writeAdminJSON(w, report)
+}
+
+// liveness only reports that the server is serving requests, the failures of the backends don't require a restart.
+func liveness(w http.ResponseWriter, _ *http.Request) {
+	writeAdminJSON(w, &healthReport{Status: healthStatusOK, Components: []componentHealth{}})
+}
+
 func (h *healthService) RegisterHTTP(router chi.Router, inproc *inprocgrpc.Channel) error {
 	mux := runtime.NewServeMux(
 		runtime.WithMarshalerOption(runtime.MIMEWildcard, &api.CustomMarshaler{JSONBuiltin: &runtime.JSONBuiltin{}}),
@@ -68,6 +213,9 @@ func (h *healthService) RegisterHTTP(router chi.Router, inproc *inprocgrpc.Chann
 	router.HandleFunc(apiPathPrefix+healthPath, func(w http.ResponseWriter, r *http.Request) {
 		mux.ServeHTTP(w, r)
 	})
+	// the probes are plain HTTP routes, they are neither authenticated nor measured like the API
+	router.Get(apiPathPrefix+livenessPath, liveness)
+	router.Get(apiPathPrefix+readinessPath, h.readiness)
 	return nil
 }
 
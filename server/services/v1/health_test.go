@@ -0,0 +1,99 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/metadata"
+)
+
+func TestHealthReport(t *testing.T) {
+	ok := componentHealth{Name: "fdb", Status: healthStatusOK}
+	degraded := componentHealth{Name: "metadata", Status: healthStatusDegraded}
+	unavailable := unavailableComponent("search", errors.Unavailable("search is unhealthy"))
+
+	require.Equal(t, healthStatusOK, newHealthReport(ok).Status)
+	require.NoError(t, newHealthReport(ok).err())
+	require.Equal(t, healthStatusDegraded, newHealthReport(ok, degraded).Status)
+	require.NoError(t, newHealthReport(ok, degraded).err())
+
+	report := newHealthReport(ok, unavailable, degraded)
+	require.Equal(t, healthStatusUnavailable, report.Status)
+	require.Equal(t, errors.Unavailable("search is unhealthy"), report.err())
+	require.Equal(t, "search is unhealthy", report.Components[1].Message)
+}
+
+func TestMetadataHealth(t *testing.T) {
+	c := metadataHealth(nil, false, metadata.Version{0x02})
+	require.Equal(t, healthStatusUnavailable, c.Status)
+	require.Equal(t, errors.Unavailable("Metadata is not loaded"), c.err)
+
+	require.Equal(t, healthStatusOK, metadataHealth(metadata.Version{0x02}, true, metadata.Version{0x02}).Status)
+	// the latest version is unknown when FoundationDB is unavailable
+	require.Equal(t, healthStatusOK, metadataHealth(metadata.Version{0x02}, true, nil).Status)
+
+	c = metadataHealth(metadata.Version{0x01}, true, metadata.Version{0x02})
+	require.Equal(t, healthStatusDegraded, c.Status)
+	require.Equal(t, "metadata cache is behind the latest version", c.Message)
+}
+
+func TestWriteHealthReport(t *testing.T) {
+	t.Run("ready", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		writeHealthReport(w, newHealthReport(componentHealth{Name: "fdb", Status: healthStatusOK}), false)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.JSONEq(t, `{"status":"ok","components":[{"name":"fdb","status":"ok"}]}`, w.Body.String())
+	})
+
+	t.Run("degraded is ready", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		writeHealthReport(w, newHealthReport(metadataHealth(metadata.Version{0x01}, true, metadata.Version{0x02})), false)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.JSONEq(t, `{"status":"degraded","components":[
+			{"name":"metadata","status":"degraded","message":"metadata cache is behind the latest version"}
+		]}`, w.Body.String())
+	})
+
+	t.Run("unavailable", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		writeHealthReport(w, newHealthReport(metadataHealth(nil, false, nil)), false)
+		require.Equal(t, http.StatusServiceUnavailable, w.Code)
+		require.JSONEq(t, `{"status":"unavailable","components":[
+			{"name":"metadata","status":"unavailable","message":"Metadata is not loaded"}
+		]}`, w.Body.String())
+	})
+
+	t.Run("shutting down", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		writeHealthReport(w, newHealthReport(componentHealth{Name: "fdb", Status: healthStatusOK}), true)
+		require.Equal(t, http.StatusServiceUnavailable, w.Code)
+		require.JSONEq(t, `{"status":"unavailable","components":[
+			{"name":"fdb","status":"ok"},
+			{"name":"server","status":"unavailable","message":"server is shutting down"}
+		]}`, w.Body.String())
+	})
+
+	t.Run("liveness", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		liveness(w, httptest.NewRequest(http.MethodGet, apiPathPrefix+livenessPath, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.JSONEq(t, `{"status":"ok","components":[]}`, w.Body.String())
+	})
+}
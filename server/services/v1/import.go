@@ -0,0 +1,483 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/request"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+	ulog "github.com/tigrisdata/tigris/util/log"
+)
+
+const (
+	// importPath writes the documents of a newline-delimited JSON body, or of a JSON array, in the collection. The
+	// "conflict" query parameter is the policy applied to the documents whose primary key already exists.
+	importPath = adminPath + "/namespaces/{namespace}/databases/{db}/collections/{collection}/import"
+	// importStreamPath is an import whose response streams the outcome of every document, see importStream.
+	importStreamPath = importPath + "/stream"
+
+	// importBatchSize and importBatchBytes bound the documents written in a single transaction.
+	importBatchSize  = 100
+	importBatchBytes = 1024 * 1024
+	// importMaxLineSize is the maximum size of a line of the body.
+	importMaxLineSize = 4 * 1024 * 1024
+	// importMaxFailures is the number of failures returned in the summary of an import.
+	importMaxFailures = 100
+	// importSkippedStatus is the status of a document skipped by a streamed import.
+	importSkippedStatus = "skipped"
+)
+
+const (
+	// ImportConflictFail stops the import at the first document whose primary key already exists.
+	ImportConflictFail = "fail"
+	// ImportConflictSkip keeps the existing documents.
+	ImportConflictSkip = "skip"
+	// ImportConflictOverwrite replaces the existing documents.
+	ImportConflictOverwrite = "overwrite"
+)
+
+type importDoc struct {
+	// index is the position of the document in the body, starting at 0.
+	index int
+	line  int
+	data  []byte
+}
+
+// importFailure is a document that is not imported. The line is the line of the document in the body, or its
+// position starting at 1 if the body is a JSON array.
+type importFailure struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+// importResult is the outcome of a document of a streamed import. The status is inserted or skipped, or the error is
+// set if the document is not imported, it has the same format as the errors of the API.
+type importResult struct {
+	Index  int                 `json:"index"`
+	Status string              `json:"status,omitempty"`
+	Error  jsoniter.RawMessage `json:"error,omitempty"`
+}
+
+// importSummary is the response of an import. The documents replaced with the overwrite policy are counted as
+// inserted. Completed is false if the import stopped at a conflict with the fail policy, or at an error once some
+// documents have been processed, the documents before are imported.
+type importSummary struct {
+	Inserted  int64               `json:"inserted"`
+	Skipped   int64               `json:"skipped"`
+	Failed    int64               `json:"failed"`
+	Completed bool                `json:"completed"`
+	Failures  []importFailure     `json:"failures,omitempty"`
+	Error     jsoniter.RawMessage `json:"error,omitempty"`
+}
+
+func (s *importSummary) add(batch *importBatch) {
+	s.Inserted += batch.inserted
+	s.Skipped += batch.skipped
+	s.Failed += int64(len(batch.failures))
+	for _, f := range batch.failures {
+		if len(s.Failures) < importMaxFailures {
+			s.Failures = append(s.Failures, f)
+		}
+	}
+}
+
+// importReader reads the documents of the body one at a time, so that the body is never buffered as a whole.
+type importReader struct {
+	reader  *bufio.Reader
+	scanner *bufio.Scanner
+	decoder *json.Decoder
+	line    int
+	count   int
+}
+
+func newImportReader(body io.Reader) (*importReader, error) {
+	r := &importReader{reader: bufio.NewReader(body)}
+
+	// the body is a JSON array if its first character is '['
+	for {
+		b, err := r.reader.Peek(1)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if b[0] == ' ' || b[0] == '\t' || b[0] == '\r' || b[0] == '\n' {
+			_, _ = r.reader.ReadByte()
+			continue
+		}
+		if b[0] == '[' {
+			r.decoder = json.NewDecoder(r.reader)
+			if _, err = r.decoder.Token(); err != nil {
+				return nil, errors.InvalidArgument("invalid JSON array: %s", err.Error())
+			}
+			return r, nil
+		}
+		break
+	}
+
+	r.scanner = bufio.NewScanner(r.reader)
+	r.scanner.Buffer(make([]byte, 0, 64*1024), importMaxLineSize)
+	return r, nil
+}
+
+// next returns the next document, or io.EOF once all the documents are read. The empty lines are skipped.
+func (r *importReader) next() (*importDoc, error) {
+	if r.decoder != nil {
+		if !r.decoder.More() {
+			return nil, io.EOF
+		}
+		r.line++
+
+		var raw json.RawMessage
+		if err := r.decoder.Decode(&raw); err != nil {
+			return nil, errors.InvalidArgument("invalid document at the position %d: %s", r.line, err.Error())
+		}
+		r.count++
+		return &importDoc{index: r.count - 1, line: r.line, data: raw}, nil
+	}
+
+	for r.scanner.Scan() {
+		r.line++
+		if line := bytes.TrimSpace(r.scanner.Bytes()); len(line) > 0 {
+			// the buffer of the scanner is reused by the next line
+			r.count++
+			return &importDoc{index: r.count - 1, line: r.line, data: append([]byte(nil), line...)}, nil
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		if err == bufio.ErrTooLong {
+			return nil, errors.InvalidArgument("line %d exceeds the limit of %d bytes", r.line+1, importMaxLineSize)
+		}
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// importBatch is the outcome of the documents written in a single transaction.
+type importBatch struct {
+	inserted int64
+	skipped  int64
+	failures []importFailure
+	// results is the outcome of every document of the batch, in the order of the documents.
+	results []*importResult
+	// conflict is set if a conflict stopped the import with the fail policy.
+	conflict bool
+}
+
+func (b *importBatch) fail(doc *importDoc, err error) {
+	b.failures = append(b.failures, importFailure{Line: doc.line, Reason: err.Error()})
+	b.results = append(b.results, &importResult{Index: doc.index, Error: adminErrorDetails(err)})
+}
+
+// ImportQueryRunner writes a batch of imported documents. The documents that fail the schema validation are reported
+// as failures and the rest of the batch is written.
+type ImportQueryRunner struct {
+	*BaseQueryRunner
+
+	db         string
+	collection string
+	conflict   string
+	docs       []*importDoc
+	batch      *importBatch
+}
+
+func (runner *ImportQueryRunner) Run(ctx context.Context, tx transaction.Tx, tenant *metadata.Tenant) (*Response, context.Context, error) {
+	// the batch is reset as the transaction may be retried
+	runner.batch = &importBatch{}
+
+	db, err := runner.getDatabase(ctx, tx, tenant, runner.db)
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	ctx = runner.cdcMgr.WrapContext(ctx, db.Name())
+
+	coll, err := runner.getCollection(db, runner.collection)
+	if err != nil {
+		return nil, ctx, err
+	}
+	if err = runner.mustBeDocumentsCollection(coll, "import"); err != nil {
+		return nil, ctx, err
+	}
+	if runner.conflict == ImportConflictOverwrite {
+		// the existing documents would be replaced
+		if err = runner.mustNotBeAppendOnly(coll, "import with overwrite"); err != nil {
+			return nil, ctx, err
+		}
+	}
+
+	table, err := runner.encoder.EncodeTableName(tenant.GetNamespace(), db, coll)
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	ts := internal.NewTimestamp()
+	var documentsWritten, bytesWritten int64
+	defer func() {
+		recordWriteUsage(ctx, tenant, documentsWritten, bytesWritten)
+		recordStorage(ctx, tenant, bytesWritten)
+	}()
+	writeCtx := withCompression(ctx, db, coll)
+	err = runner.importDocs(coll, func(data []byte) (error, error) {
+		keyGen := newKeyGenerator(data, tenant.TableKeyGenerator, coll.Indexes.PrimaryKey)
+		key, err := keyGen.generate(ctx, runner.txMgr, runner.encoder, table)
+		if err != nil {
+			return err, nil
+		}
+
+		tableData := internal.NewTableDataWithTS(ts, nil, keyGen.document)
+		tableData.SetVersion(coll.GetVersion())
+		if runner.conflict == ImportConflictOverwrite && !keyGen.forceInsert {
+			err = runner.replace(writeCtx, tx, coll, key, tableData)
+		} else {
+			err = tx.Insert(writeCtx, key, tableData)
+		}
+		if err == nil {
+			documentsWritten++
+			bytesWritten += int64(len(keyGen.document))
+		}
+		return nil, err
+	})
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	return &Response{
+		createdAt: ts,
+		status:    InsertedStatus,
+	}, ctx, nil
+}
+
+// importDocs validates the documents of the batch against the schema of the collection and writes the valid ones.
+// The write returns the reason the document is not imported, or the error that stops the batch.
+func (runner *ImportQueryRunner) importDocs(coll *schema.DefaultCollection, write func(data []byte) (failure error, err error)) error {
+	for _, doc := range runner.docs {
+		data, err := normalizeImportPayload(coll, doc.data)
+		if err != nil {
+			runner.batch.fail(doc, err)
+			continue
+		}
+
+		failure, err := write(data)
+		switch {
+		case err == kv.ErrDuplicateKey && runner.conflict == ImportConflictSkip:
+			runner.batch.skipped++
+			runner.batch.results = append(runner.batch.results, &importResult{Index: doc.index, Status: importSkippedStatus})
+		case err == kv.ErrDuplicateKey:
+			runner.batch.fail(doc, errors.AlreadyExists(err.Error()))
+			runner.batch.conflict = true
+			return nil
+		case err != nil:
+			return err
+		case failure != nil:
+			runner.batch.fail(doc, failure)
+		default:
+			runner.batch.inserted++
+			runner.batch.results = append(runner.batch.results, &importResult{Index: doc.index, Status: InsertedStatus})
+		}
+	}
+
+	return nil
+}
+
+// importDocuments writes the documents of the body in bounded transactions, so that an import is not limited by the
+// size and the lifetime of a transaction. The body is read one batch at a time.
+func (s *apiService) importDocuments(w http.ResponseWriter, r *http.Request) {
+	ctx, reader, runner, err := s.newImport(r)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	summary, err := s.importBatches(ctx, reader, runner, nil)
+	if err != nil {
+		if summary.Inserted == 0 && summary.Skipped == 0 && summary.Failed == 0 {
+			writeAdminError(w, err)
+			return
+		}
+		summary.Completed, summary.Error = false, adminErrorDetails(err)
+	}
+
+	writeAdminJSON(w, summary)
+}
+
+// importStream is an import whose response streams the outcome of every document as newline-delimited JSON, in the
+// order of the documents, followed by the summary of the import as {"metadata": {...}}. A document that fails the
+// schema validation is reported with its error and the rest of the import goes on. Over HTTP/2 the outcomes of a batch
+// are written once the batch is committed. The HTTP/1.x server discards the rest of the body as soon as the response
+// starts, so over HTTP/1.x the outcomes are written once the whole body is read.
+func (s *apiService) importStream(w http.ResponseWriter, r *http.Request) {
+	ctx, reader, runner, err := s.newImport(r)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	s.streamImport(ctx, w, r.ProtoMajor >= 2, reader, runner)
+}
+
+// streamImport writes the outcomes of the documents of the import as they are imported, or once the import is done
+// unless the response can be written while the body is read.
+func (s *apiService) streamImport(ctx context.Context, w http.ResponseWriter, fullDuplex bool, reader *importReader,
+	runner *ImportQueryRunner,
+) {
+	var out io.Writer = w
+	flush := func() {
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+	var buffered *bytes.Buffer
+	if !fullDuplex {
+		buffered = &bytes.Buffer{}
+		out, flush = buffered, func() {}
+	}
+
+	var started bool
+	summary, err := s.importBatches(ctx, reader, runner, func(batch *importBatch) error {
+		if !started {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			started = true
+		}
+		if err := writeImportResults(out, batch.results); err != nil {
+			return err
+		}
+		flush()
+		return nil
+	})
+	if err != nil {
+		if !started {
+			writeAdminError(w, err)
+			return
+		}
+		summary.Completed, summary.Error = false, adminErrorDetails(err)
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	data, err := jsoniter.Marshal(map[string]*importSummary{"metadata": summary})
+	if err == nil {
+		_, err = out.Write(append(data, '\n'))
+	}
+	if err == nil && buffered != nil {
+		_, err = w.Write(buffered.Bytes())
+	}
+	ulog.E(err)
+}
+
+func writeImportResults(w io.Writer, results []*importResult) error {
+	for _, result := range results {
+		data, err := jsoniter.Marshal(result)
+		if err != nil {
+			return err
+		}
+		if _, err = w.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *apiService) newImport(r *http.Request) (context.Context, *importReader, *ImportQueryRunner, error) {
+	namespace := chi.URLParam(r, "namespace")
+	conflict := r.URL.Query().Get("conflict")
+	switch conflict {
+	case "":
+		conflict = ImportConflictFail
+	case ImportConflictFail, ImportConflictSkip, ImportConflictOverwrite:
+	default:
+		return nil, nil, nil, errors.InvalidArgument("unsupported conflict policy '%s'", conflict)
+	}
+
+	if _, err := s.tenantMgr.GetTenant(r.Context(), namespace); err != nil {
+		return nil, nil, nil, errors.NotFound("namespace '%s' doesn't exist", namespace)
+	}
+	md := &request.Metadata{}
+	md.SetNamespace(r.Context(), namespace)
+	ctx := md.SaveToContext(r.Context())
+
+	reader, err := newImportReader(r.Body)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return ctx, reader, s.runnerFactory.GetImportQueryRunner(chi.URLParam(r, "db"), chi.URLParam(r, "collection"), conflict), nil
+}
+
+// importBatches imports the documents of the reader, the optional onBatch is called once every batch is committed.
+// The documents read before an invalid document are imported, the invalid document stops the import after them.
+func (s *apiService) importBatches(ctx context.Context, reader *importReader, runner *ImportQueryRunner,
+	onBatch func(batch *importBatch) error,
+) (*importSummary, error) {
+	summary := &importSummary{Completed: true}
+	for done := false; !done; {
+		var (
+			size    int
+			readErr error
+		)
+		runner.docs = runner.docs[:0]
+		for len(runner.docs) < importBatchSize && size < importBatchBytes {
+			doc, err := reader.next()
+			if err == io.EOF {
+				done = true
+				break
+			}
+			if err != nil {
+				readErr, done = err, true
+				break
+			}
+			runner.docs = append(runner.docs, doc)
+			size += len(doc.data)
+		}
+		if len(runner.docs) == 0 {
+			if readErr != nil {
+				return summary, readErr
+			}
+			break
+		}
+
+		if _, err := s.sessions.Execute(ctx, runner, &ReqOptions{}); err != nil {
+			return summary, err
+		}
+		summary.add(runner.batch)
+		if onBatch != nil {
+			if err := onBatch(runner.batch); err != nil {
+				return summary, err
+			}
+		}
+		if runner.batch.conflict {
+			summary.Completed = false
+			break
+		}
+		if readErr != nil {
+			return summary, readErr
+		}
+	}
+
+	return summary, nil
+}
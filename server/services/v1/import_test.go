@@ -0,0 +1,210 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+func readImportDocs(t *testing.T, body string) ([]*importDoc, error) {
+	reader, err := newImportReader(strings.NewReader(body))
+	require.NoError(t, err)
+
+	var docs []*importDoc
+	for {
+		doc, err := reader.next()
+		if err == io.EOF {
+			return docs, nil
+		}
+		if err != nil {
+			return docs, err
+		}
+		docs = append(docs, doc)
+	}
+}
+
+func TestImportReader(t *testing.T) {
+	t.Run("ndjson", func(t *testing.T) {
+		docs, err := readImportDocs(t, "{\"id\":1}\n\n  {\"id\":2}  \r\n{\"id\":3}")
+		require.NoError(t, err)
+		require.Equal(t, []*importDoc{
+			{index: 0, line: 1, data: []byte(`{"id":1}`)},
+			{index: 1, line: 3, data: []byte(`{"id":2}`)},
+			{index: 2, line: 4, data: []byte(`{"id":3}`)},
+		}, docs)
+	})
+
+	t.Run("array", func(t *testing.T) {
+		docs, err := readImportDocs(t, " \n[{\"id\":1},\n {\"id\":2}]")
+		require.NoError(t, err)
+		require.Equal(t, []*importDoc{
+			{index: 0, line: 1, data: []byte(`{"id":1}`)},
+			{index: 1, line: 2, data: []byte(`{"id":2}`)},
+		}, docs)
+
+		docs, err = readImportDocs(t, `[{"id":1}, {"id":]`)
+		require.Len(t, docs, 1)
+		require.Error(t, err)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		docs, err := readImportDocs(t, "")
+		require.NoError(t, err)
+		require.Empty(t, docs)
+	})
+
+	t.Run("line_too_long", func(t *testing.T) {
+		docs, err := readImportDocs(t, "{\"id\":1}\n"+strings.Repeat("a", importMaxLineSize+1))
+		require.Len(t, docs, 1)
+		require.EqualError(t, err, "line 2 exceeds the limit of 4194304 bytes")
+	})
+}
+
+func TestImportSummary(t *testing.T) {
+	summary := &importSummary{}
+	batch := &importBatch{inserted: 2, skipped: 1}
+	for i := 0; i < importMaxFailures; i++ {
+		batch.failures = append(batch.failures, importFailure{Line: i + 1, Reason: "invalid"})
+	}
+
+	summary.add(batch)
+	summary.add(batch)
+	require.Equal(t, int64(4), summary.Inserted)
+	require.Equal(t, int64(2), summary.Skipped)
+	require.Equal(t, int64(2*importMaxFailures), summary.Failed)
+	require.Len(t, summary.Failures, importMaxFailures)
+	require.Equal(t, 1, summary.Failures[0].Line)
+}
+
+// importSessions runs the batches of the imports without a transaction, the documents are validated against the
+// collection and written by write.
+type importSessions struct {
+	Session
+
+	coll    *schema.DefaultCollection
+	write   func(data []byte) (error, error)
+	batches int
+}
+
+func (s *importSessions) Execute(_ context.Context, runner QueryRunner, _ *ReqOptions) (*Response, error) {
+	r := runner.(*ImportQueryRunner)
+	r.batch = &importBatch{}
+	s.batches++
+	return &Response{}, r.importDocs(s.coll, s.write)
+}
+
+func TestImportStream(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": {"type": "integer"},
+			"name": {"type": "string"}
+		},
+		"primary_key": ["id"]
+	}`)
+	factory, err := schema.Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	coll := schema.NewDefaultCollection("t1", 1, 1, factory.CollectionType, factory, "search_t1", nil)
+
+	// the documents span two batches, every tenth one is invalid and the document 5 already exists
+	var body strings.Builder
+	for i := 0; i < importBatchSize+50; i++ {
+		if i%10 == 9 {
+			body.WriteString(fmt.Sprintf(`{"id":%d,"name":["b"]}`+"\n", i))
+		} else {
+			body.WriteString(fmt.Sprintf(`{"id":%d,"name":"a"}`+"\n", i))
+		}
+	}
+	write := func(data []byte) (error, error) {
+		if jsoniter.Get(data, "id").ToInt() == 5 {
+			return nil, kv.ErrDuplicateKey
+		}
+		return nil, nil
+	}
+
+	for _, fullDuplex := range []bool{true, false} {
+		sessions := &importSessions{coll: coll, write: write}
+		s := &apiService{sessions: sessions}
+		reader, err := newImportReader(strings.NewReader(body.String()))
+		require.NoError(t, err)
+		runner := &ImportQueryRunner{BaseQueryRunner: &BaseQueryRunner{}, conflict: ImportConflictSkip}
+
+		w := httptest.NewRecorder()
+		s.streamImport(context.Background(), w, fullDuplex, reader, runner)
+		require.Equal(t, 2, sessions.batches)
+		// without full duplex the outcomes are written once the body is read
+		require.Equal(t, fullDuplex, w.Flushed)
+		require.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+		lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+		require.Len(t, lines, importBatchSize+50+1)
+		for i, line := range lines[:len(lines)-1] {
+			require.Equal(t, i, jsoniter.Get([]byte(line), "index").ToInt(), line)
+			switch {
+			case i%10 == 9:
+				require.Equal(t, "INVALID_ARGUMENT", jsoniter.Get([]byte(line), "error", "code").ToString(), line)
+				require.NotEmpty(t, jsoniter.Get([]byte(line), "error", "message").ToString(), line)
+			case i == 5:
+				require.JSONEq(t, `{"index":5,"status":"skipped"}`, line)
+			default:
+				require.JSONEq(t, fmt.Sprintf(`{"index":%d,"status":"inserted"}`, i), line)
+			}
+		}
+
+		summary := jsoniter.Get([]byte(lines[len(lines)-1]), "metadata")
+		require.Equal(t, int64(134), summary.Get("inserted").ToInt64())
+		require.Equal(t, int64(1), summary.Get("skipped").ToInt64())
+		require.Equal(t, int64(15), summary.Get("failed").ToInt64())
+		require.True(t, summary.Get("completed").ToBool())
+		require.Equal(t, 10, summary.Get("failures", 0, "line").ToInt())
+	}
+}
+
+func TestImportBatches_InvalidDocument(t *testing.T) {
+	factory, err := schema.Build("t1", []byte(`{
+		"title": "t1",
+		"properties": { "id": {"type": "integer"} },
+		"primary_key": ["id"]
+	}`), false)
+	require.NoError(t, err)
+	coll := schema.NewDefaultCollection("t1", 1, 1, factory.CollectionType, factory, "search_t1", nil)
+
+	var written []int
+	sessions := &importSessions{coll: coll, write: func(data []byte) (error, error) {
+		written = append(written, jsoniter.Get(data, "id").ToInt())
+		return nil, nil
+	}}
+	s := &apiService{sessions: sessions}
+	reader, err := newImportReader(strings.NewReader(`[{"id":1}, {"id":2}, {"id":]`))
+	require.NoError(t, err)
+	runner := &ImportQueryRunner{BaseQueryRunner: &BaseQueryRunner{}, conflict: ImportConflictFail}
+
+	// the documents read before the invalid one are imported
+	summary, err := s.importBatches(context.Background(), reader, runner, nil)
+	require.Error(t, err)
+	require.Equal(t, 1, sessions.batches)
+	require.Equal(t, []int{1, 2}, written)
+	require.Equal(t, int64(2), summary.Inserted)
+}
@@ -0,0 +1,55 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"net/http"
+
+	"github.com/tigrisdata/tigris/server/config"
+)
+
+// limitsPath returns the limits of the requests of the server, so that the clients can check the requests before
+// sending them.
+const limitsPath = infoPath + "/limits"
+
+// serverLimits are the limits of the requests, the timeouts are in milliseconds. A zero value means there is no
+// limit, apart from the limits of gRPC itself for the messages.
+type serverLimits struct {
+	HTTP struct {
+		MaxBodySize         int64 `json:"max_body_size"`
+		MaxDocumentBodySize int64 `json:"max_document_body_size"`
+		TimeoutMs           int64 `json:"timeout_ms"`
+		DocumentTimeoutMs   int64 `json:"document_timeout_ms"`
+	} `json:"http"`
+	GRPC struct {
+		MaxRecvMsgSize int `json:"max_recv_msg_size"`
+		MaxSendMsgSize int `json:"max_send_msg_size"`
+	} `json:"grpc"`
+}
+
+func getServerLimits(cfg *config.ServerConfig) *serverLimits {
+	limits := &serverLimits{}
+	limits.HTTP.MaxBodySize = cfg.HTTP.MaxBodySize
+	limits.HTTP.MaxDocumentBodySize = cfg.HTTP.MaxDocumentBodySize
+	limits.HTTP.TimeoutMs = cfg.HTTP.Timeout.Milliseconds()
+	limits.HTTP.DocumentTimeoutMs = cfg.HTTP.DocumentTimeout.Milliseconds()
+	limits.GRPC.MaxRecvMsgSize = cfg.GRPC.MaxRecvMsgSize
+	limits.GRPC.MaxSendMsgSize = cfg.GRPC.MaxSendMsgSize
+	return limits
+}
+
+func writeServerLimits(w http.ResponseWriter, _ *http.Request) {
+	writeAdminJSON(w, getServerLimits(&config.DefaultConfig.Server))
+}
@@ -0,0 +1,40 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/server/config"
+)
+
+func TestGetServerLimits(t *testing.T) {
+	cfg := config.ServerConfig{
+		HTTP: config.HTTPConfig{MaxBodySize: 1024, MaxDocumentBodySize: 4096, DocumentTimeout: 3 * time.Second},
+		GRPC: config.GRPCConfig{MaxRecvMsgSize: 2048},
+	}
+
+	w := httptest.NewRecorder()
+	writeAdminJSON(w, getServerLimits(&cfg))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{
+		"http": {"max_body_size": 1024, "max_document_body_size": 4096, "timeout_ms": 0, "document_timeout_ms": 3000},
+		"grpc": {"max_recv_msg_size": 2048, "max_send_msg_size": 0}
+	}`, w.Body.String())
+}
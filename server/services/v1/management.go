@@ -30,6 +30,7 @@ import (
 	"github.com/tigrisdata/tigris/lib/uuid"
 	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/quota"
 	"github.com/tigrisdata/tigris/server/transaction"
 	ulog "github.com/tigrisdata/tigris/util/log"
 	"google.golang.org/grpc"
@@ -50,6 +51,10 @@ type managementService struct {
 
 type nsDetailsResp = map[string]map[string]map[string]map[string]string
 
+// storageDetailsKey is the entry of the namespace details with the data size of the namespace and its storage quota.
+// It doesn't clash with the databases as their names start with a letter.
+const storageDetailsKey = "$storage"
+
 func newManagementService(authProvider AuthProvider, txMgr *transaction.Manager, tenantMgr *metadata.TenantManager, userStore *metadata.UserSubspace, namespaceStore *metadata.NamespaceSubspace) *managementService {
 	if authProvider == nil && config.DefaultConfig.Auth.EnableOauth {
 		log.Error().Str("AuthProvider", config.DefaultConfig.Auth.OAuthProvider).Msg("Unable to configure external auth provider")
@@ -92,17 +97,11 @@ func (m *managementService) CreateNamespace(ctx context.Context, req *api.Create
 
 	code := req.GetCode()
 	if req.GetCode() == 0 {
-		namespaces, err := m.TenantManager.ListNamespaces(ctx, tx)
-		if err != nil {
+		// the ids of the deleted namespaces are never assigned again
+		if code, err = m.TenantManager.NextNamespaceId(ctx, tx); err != nil {
+			_ = tx.Rollback(ctx)
 			return nil, errors.Internal("Failed to generate ID")
 		}
-		var maxId uint32 = 0
-		for _, namespace := range namespaces {
-			if maxId < namespace.Id() {
-				maxId = namespace.Id()
-			}
-		}
-		code = maxId + 1
 	}
 	// API id maps to internal strId
 	// API code maps to internal Id
@@ -178,6 +177,7 @@ func (m *managementService) getNameSpaceDetails(ctx context.Context) (nsDetailsR
 		if err != nil {
 			return nil, err
 		}
+		res[nsName] = make(map[string]map[string]map[string]string)
 
 		for _, dbName := range tenant.ListDatabases(ctx) {
 			db, err := tenant.GetDatabase(ctx, dbName)
@@ -190,6 +190,7 @@ func (m *managementService) getNameSpaceDetails(ctx context.Context) (nsDetailsR
 				continue
 			}
 
+			res[nsName][dbName] = make(map[string]map[string]string)
 			for _, coll := range db.ListCollection() {
 				size, err := tenant.CollectionSize(ctx, db, coll)
 				if err != nil {
@@ -201,10 +202,34 @@ func (m *managementService) getNameSpaceDetails(ctx context.Context) (nsDetailsR
 				}
 			}
 		}
+
+		storage, err := storageDetails(ctx, tenant)
+		if err != nil {
+			return nil, err
+		}
+		res[nsName][storageDetailsKey] = map[string]map[string]string{"size": storage}
 	}
 	return res, nil
 }
 
+// storageDetails returns the data size of the tenant tracked by the storage quota and its limit, the data size is the
+// size of the ranges of the tenant if the storage quota is disabled.
+func storageDetails(ctx context.Context, tenant *metadata.Tenant) (map[string]string, error) {
+	usage, limit, ok := quota.StorageUsage(tenant.GetNamespace().StrId())
+	if !ok {
+		size, err := tenant.Size(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{"usage": strconv.FormatInt(size, 10)}, nil
+	}
+
+	return map[string]string{
+		"usage": strconv.FormatInt(usage, 10),
+		"limit": strconv.FormatInt(limit, 10),
+	}, nil
+}
+
 func (m *managementService) DescribeNamespaces(ctx context.Context, _ *api.DescribeNamespacesRequest) (*api.DescribeNamespacesResponse, error) {
 	data, err := m.getNameSpaceDetails(ctx)
 	if ulog.E(err) {
@@ -0,0 +1,330 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/buger/jsonparser"
+	jsoniter "github.com/json-iterator/go"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/lib/container"
+	"github.com/tigrisdata/tigris/query/filter"
+	qsearch "github.com/tigrisdata/tigris/query/search"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/metrics"
+	ulog "github.com/tigrisdata/tigris/util/log"
+	gmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// MultiCollectionSearchQueryRunner runs the search query on a list of collections of the database. The collections are
+// queried in parallel, bounded by the configured concurrency, and the hits are merged by their text match score
+// normalized per collection. The overall page size is applied after merging. Every hit is tagged with the collection
+// it is coming from. A collection that fails doesn't fail the request, the hits of the remaining collections are
+// returned and the failures are reported to the client in the "Tigris-Search-Warnings" header.
+type MultiCollectionSearchQueryRunner struct {
+	*SearchQueryRunner
+
+	concurrency int
+}
+
+type collectionSearchHit struct {
+	rank  int
+	score float64
+	hit   *api.SearchHit
+}
+
+type collectionSearchResult struct {
+	collection string
+	found      int64
+	hits       []*collectionSearchHit
+	err        error
+}
+
+func (runner *MultiCollectionSearchQueryRunner) ReadOnly(ctx context.Context, tenant *metadata.Tenant) (*Response, context.Context, error) {
+	db, err := runner.getDatabaseFromTenant(ctx, tenant, runner.req.GetDb())
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	ctx = runner.cdcMgr.WrapContext(ctx, db.Name())
+
+	if len(runner.req.Facet) > 0 {
+		return nil, ctx, errors.InvalidArgument("facets are not supported when searching multiple collections")
+	}
+	if len(runner.req.Sort) > 0 {
+		return nil, ctx, errors.InvalidArgument("sort is not supported when searching multiple collections, " +
+			"hits are ordered by relevance")
+	}
+	if runner.req.Page > defaultPageNo {
+		return nil, ctx, errors.InvalidArgument("pagination is not supported when searching multiple collections, " +
+			"use page_size to limit the number of hits")
+	}
+
+	collections := runner.getCollectionNames(db)
+
+	runner.queryMetrics.SetSearchType("multi_collection")
+	runner.queryMetrics.SetSort(false)
+	ctx = metrics.UpdateSpanTags(ctx, runner.queryMetrics)
+
+	limit := int(runner.req.PageSize)
+	if limit == 0 {
+		limit = defaultPerPage
+	}
+
+	var (
+		found    int64
+		hits     []*collectionSearchHit
+		warnings []string
+		firstErr error
+	)
+	usage := metrics.Usage{SearchQueries: 1}
+	defer func() {
+		recordReadUsage(ctx, tenant, usage)
+	}()
+	results := runner.fanOut(ctx, db, collections, limit)
+	for _, r := range results {
+		if r.err != nil {
+			ulog.E(r.err)
+			warnings = append(warnings, fmt.Sprintf("collection '%s': %s", r.collection, r.err.Error()))
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			continue
+		}
+
+		found += r.found
+		hits = append(hits, r.hits...)
+		for _, h := range r.hits {
+			usage.DocumentsRead++
+			usage.BytesRead += int64(len(h.hit.Data))
+		}
+	}
+
+	if len(results) > 0 && len(warnings) == len(results) {
+		// nothing to degrade to, every collection failed
+		return nil, ctx, firstErr
+	}
+
+	// results are in the order of the collections, so on the same score the hits of the collections are interleaved
+	// by their rank
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return hits[i].rank < hits[j].rank
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	if len(warnings) > 0 {
+		md := gmetadata.MD{}
+		md.Append(api.HeaderSearchWarnings, warnings...)
+		if err = runner.streaming.SetHeader(md); ulog.E(err) {
+			return nil, ctx, err
+		}
+	}
+
+	resp := &api.SearchResponse{
+		Hits:   make([]*api.SearchHit, 0, len(hits)),
+		Facets: map[string]*api.SearchFacet{},
+		Meta: &api.SearchMetadata{
+			Found:      found,
+			TotalPages: 1,
+			Page: &api.Page{
+				Current: defaultPageNo,
+				Size:    int32(limit),
+			},
+		},
+	}
+	for _, h := range hits {
+		resp.Hits = append(resp.Hits, h.hit)
+	}
+
+	if err = runner.streaming.Send(resp); err != nil {
+		return nil, ctx, err
+	}
+
+	return &Response{}, ctx, nil
+}
+
+// getCollectionNames returns the collections the search is fanned out to. The collections of the database are sorted
+// by name for "*" so that the order of the hits with the same score is stable across requests.
+func (runner *MultiCollectionSearchQueryRunner) getCollectionNames(db *metadata.Database) []string {
+	var names []string
+	if runner.req.GetCollection() == api.SearchAllCollections {
+		for _, c := range db.ListCollection() {
+			names = append(names, c.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	seen := container.NewHashSet()
+	for _, name := range runner.req.GetCollections() {
+		if !seen.Contains(name) {
+			seen.Insert(name)
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+func (runner *MultiCollectionSearchQueryRunner) fanOut(ctx context.Context, db *metadata.Database, collections []string, limit int) []*collectionSearchResult {
+	concurrency := runner.concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	tokens := make(chan struct{}, concurrency)
+	results := make([]*collectionSearchResult, len(collections))
+	for i, name := range collections {
+		wg.Add(1)
+		tokens <- struct{}{}
+		go func(i int, name string) {
+			defer func() {
+				<-tokens
+				wg.Done()
+			}()
+
+			results[i] = runner.searchCollection(ctx, db, name, limit)
+		}(i, name)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func (runner *MultiCollectionSearchQueryRunner) searchCollection(ctx context.Context, db *metadata.Database, name string, limit int) *collectionSearchResult {
+	result := &collectionSearchResult{collection: name}
+
+	collection, err := runner.getCollection(db, name)
+	if err != nil {
+		result.err = err
+		return result
+	}
+
+	// the helpers of the search runner rewrite the field names in the request to the names used in the indexing store,
+	// so every collection needs its own copy of the request
+	collRunner := &SearchQueryRunner{
+		BaseQueryRunner: runner.BaseQueryRunner,
+		req:             proto.Clone(runner.req).(*api.SearchRequest),
+	}
+
+	searchQ, wrappedF, err := collRunner.buildCollectionQuery(collection, limit)
+	if err != nil {
+		result.err = err
+		return result
+	}
+
+	var (
+		row      Row
+		maxScore int64
+		scores   []int64
+	)
+	iterator := NewSearchReader(ctx, runner.searchStore, collection, searchQ).Iterator(collection, wrappedF)
+	for len(result.hits) < limit && iterator.Next(&row) {
+		data := row.Data.RawData
+		if searchQ.ReadFields != nil {
+			if data, err = searchQ.ReadFields.Apply(data); err != nil {
+				result.err = err
+				return result
+			}
+		}
+
+		if data, err = tagSourceCollection(data, name); err != nil {
+			result.err = err
+			return result
+		}
+
+		score := iterator.getTextMatchScore()
+		if score > maxScore {
+			maxScore = score
+		}
+		scores = append(scores, score)
+
+		result.hits = append(result.hits, &collectionSearchHit{
+			rank: len(result.hits),
+			hit: &api.SearchHit{
+				Data: data,
+				Metadata: &api.SearchHitMeta{
+					CreatedAt: row.Data.CreateToProtoTS(),
+					UpdatedAt: row.Data.UpdatedToProtoTS(),
+				},
+			},
+		})
+	}
+	if err = iterator.Interrupted(); err != nil {
+		result.hits = nil
+		result.err = err
+		return result
+	}
+
+	// text match scores are relative to the collection, normalizing them to [0, 1] makes the best hit of every
+	// collection comparable
+	if maxScore > 0 {
+		for i, h := range result.hits {
+			h.score = float64(scores[i]) / float64(maxScore)
+		}
+	}
+	result.found = iterator.getTotalFound()
+
+	return result
+}
+
+func (runner *SearchQueryRunner) buildCollectionQuery(collection *schema.DefaultCollection, pageSize int) (*qsearch.Query, *filter.WrappedFilter, error) {
+	wrappedF, err := filter.NewFactory(collection.QueryableFields, runner.req.Collation).WrappedFilter(runner.req.Filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	searchFields, err := runner.getSearchFields(collection)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fieldSelection, err := runner.getFieldSelection(collection)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return qsearch.NewBuilder().
+		Query(runner.req.Q).
+		SearchFields(searchFields).
+		PageSize(pageSize).
+		Filter(wrappedF).
+		ReadFields(fieldSelection).
+		Build(), wrappedF, nil
+}
+
+// tagSourceCollection adds the name of the collection to the document, the field name is reserved, so it never
+// collides with a user field.
+func tagSourceCollection(data []byte, collection string) ([]byte, error) {
+	value, err := jsoniter.Marshal(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonparser.Set(data, value, schema.ReservedFields[schema.SourceCollection])
+}
@@ -0,0 +1,289 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	jsoniter "github.com/json-iterator/go"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/lib/uuid"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/quota"
+	"github.com/tigrisdata/tigris/server/ratelimit"
+	"github.com/tigrisdata/tigris/server/transaction"
+)
+
+const (
+	// namespacesPath creates and lists the namespaces.
+	namespacesPath = adminPath + "/namespaces"
+	// namespacePath describes and deletes a namespace, a namespace with databases is only deleted with "?force=true".
+	namespacePath = adminPath + "/namespaces/{namespace}"
+)
+
+// namespaceSettings are the settings of a namespace recorded in its metadata at the creation.
+type namespaceSettings struct {
+	StorageQuota *quota.StorageQuota `json:"storage_quota,omitempty"`
+	RateLimits   *ratelimit.Limits   `json:"rate_limits,omitempty"`
+}
+
+// createNamespaceRequest creates a namespace, the id and the code are assigned if they are not set. The codes of the
+// deleted namespaces are never assigned again.
+type createNamespaceRequest struct {
+	Id       string             `json:"id"`
+	Code     uint32             `json:"code"`
+	Name     string             `json:"name"`
+	Settings *namespaceSettings `json:"settings,omitempty"`
+}
+
+type namespaceInfo struct {
+	Id   string `json:"id"`
+	Code uint32 `json:"code"`
+	Name string `json:"name"`
+}
+
+type namespaceDatabase struct {
+	Name        string   `json:"name"`
+	Collections []string `json:"collections"`
+}
+
+type namespaceDescription struct {
+	namespaceInfo
+
+	Databases  []namespaceDatabase    `json:"databases"`
+	Storage    *namespaceStorageQuota `json:"storage"`
+	RateLimits *namespaceRateLimits   `json:"rate_limits"`
+}
+
+// registerNamespaceRoutes registers the routes of the namespaces, they are authorized as the namespace methods of the
+// management API.
+func (s *apiService) registerNamespaceRoutes(router chi.Router) {
+	s.adminMethodRoute(router, http.MethodPost, namespacesPath, api.CreateNamespaceMethodName, s.createNamespace)
+	s.adminMethodRoute(router, http.MethodGet, namespacesPath, api.ListNamespaceMethodName, s.listNamespaces)
+	s.adminMethodRoute(router, http.MethodGet, namespacePath, api.DescribeNamespaceMethodName, s.describeNamespace)
+	s.adminMethodRoute(router, http.MethodDelete, namespacePath, api.DeleteNamespaceMethodName, s.deleteNamespace)
+}
+
+func (s *apiService) createNamespace(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req := &createNamespaceRequest{}
+	if err := jsoniter.NewDecoder(r.Body).Decode(req); err != nil {
+		writeAdminError(w, errors.InvalidArgument("invalid namespace: %s", err.Error()))
+		return
+	}
+	if err := req.validate(); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if req.Id == "" {
+		req.Id = uuid.New().String()
+	}
+
+	tx, err := s.txMgr.StartTx(ctx)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	namespace, err := s.createNamespaceInternal(ctx, tx, req)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		writeAdminError(w, err)
+		return
+	}
+	if err = tx.Commit(ctx); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if req.Settings != nil && req.Settings.StorageQuota != nil {
+		quota.SetStorageQuota(namespace.StrId(), req.Settings.StorageQuota)
+	}
+	ratelimit.Invalidate(namespace.StrId())
+
+	writeAdminJSON(w, newNamespaceInfo(namespace))
+}
+
+func (s *apiService) createNamespaceInternal(ctx context.Context, tx transaction.Tx, req *createNamespaceRequest) (metadata.Namespace, error) {
+	code := req.Code
+	if code == 0 {
+		var err error
+		if code, err = s.tenantMgr.NextNamespaceId(ctx, tx); err != nil {
+			return nil, err
+		}
+	}
+
+	namespace, err := s.tenantMgr.CreateTenant(ctx, tx, metadata.NewTenantNamespace(req.Id, metadata.NewNamespaceMetadata(code, req.Id, req.Name)))
+	if err != nil {
+		return nil, err
+	}
+	if req.Settings == nil {
+		return namespace, nil
+	}
+	if req.Settings.StorageQuota != nil {
+		if err = s.storageQuotas.SetStorageQuota(ctx, tx, code, req.Settings.StorageQuota); err != nil {
+			return nil, err
+		}
+	}
+	if req.Settings.RateLimits != nil {
+		if err = s.rateLimits.SetLimits(ctx, tx, code, req.Settings.RateLimits); err != nil {
+			return nil, err
+		}
+	}
+
+	return namespace, nil
+}
+
+func (s *apiService) listNamespaces(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tx, err := s.txMgr.StartTx(ctx)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	namespaces, err := s.tenantMgr.ListNamespaces(ctx, tx)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	infos := make([]*namespaceInfo, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		infos = append(infos, newNamespaceInfo(namespace))
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Code < infos[j].Code })
+
+	writeAdminJSON(w, infos)
+}
+
+// describeNamespace returns the databases of the namespace with their collections, its data size with its storage
+// quota, and its request rate limits.
+func (s *apiService) describeNamespace(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	namespace := chi.URLParam(r, "namespace")
+
+	tenant, err := s.tenantMgr.GetTenant(ctx, namespace)
+	if err != nil {
+		writeAdminError(w, errors.NotFound("namespace '%s' doesn't exist", namespace))
+		return
+	}
+
+	tx, err := s.txMgr.StartTx(ctx)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	storageQuota, err := s.storageQuotas.GetStorageQuota(ctx, tx, tenant.GetNamespace().Id())
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	rateLimits, err := s.rateLimits.GetLimits(ctx, tx, tenant.GetNamespace().Id())
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	writeAdminJSON(w, &namespaceDescription{
+		namespaceInfo: *newNamespaceInfo(tenant.GetNamespace()),
+		Databases:     namespaceDatabases(ctx, tenant),
+		Storage:       newNamespaceStorageQuota(namespace, storageQuota),
+		RateLimits:    newNamespaceRateLimits(namespace, rateLimits),
+	})
+}
+
+// deleteNamespace deletes the namespace, the databases of the namespace are dropped with "?force=true" otherwise the
+// namespace must be empty. The code of the namespace is never assigned again.
+func (s *apiService) deleteNamespace(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	namespace := chi.URLParam(r, "namespace")
+
+	force := false
+	if value := r.URL.Query().Get("force"); value != "" {
+		var err error
+		if force, err = strconv.ParseBool(value); err != nil {
+			writeAdminError(w, errors.InvalidArgument("invalid force '%s', expected true or false", value))
+			return
+		}
+	}
+
+	if _, err := s.tenantMgr.DeleteTenant(ctx, namespace, force); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	quota.SetStorageQuota(namespace, nil)
+	ratelimit.Invalidate(namespace)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (req *createNamespaceRequest) validate() error {
+	if req.Name == "" {
+		return errors.InvalidArgument("empty namespace name is not allowed")
+	}
+	if req.Settings == nil {
+		return nil
+	}
+	if req.Settings.StorageQuota != nil {
+		if err := req.Settings.StorageQuota.Validate(); err != nil {
+			return err
+		}
+	}
+	if req.Settings.RateLimits != nil {
+		if err := req.Settings.RateLimits.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newNamespaceInfo(namespace metadata.Namespace) *namespaceInfo {
+	// the API id is the internal strId and the API code is the internal id
+	return &namespaceInfo{
+		Id:   namespace.StrId(),
+		Code: namespace.Id(),
+		Name: namespace.Metadata().Name,
+	}
+}
+
+func namespaceDatabases(ctx context.Context, tenant *metadata.Tenant) []namespaceDatabase {
+	databases := make([]namespaceDatabase, 0)
+	for _, dbName := range tenant.ListDatabases(ctx) {
+		db, _ := tenant.GetDatabase(ctx, dbName)
+		if db == nil {
+			// database was dropped in the meantime
+			continue
+		}
+
+		collections := make([]string, 0)
+		for _, coll := range db.ListCollection() {
+			collections = append(collections, coll.Name)
+		}
+		sort.Strings(collections)
+		databases = append(databases, namespaceDatabase{Name: dbName, Collections: collections})
+	}
+	sort.Slice(databases, func(i, j int) bool { return databases[i].Name < databases[j].Name })
+
+	return databases
+}
@@ -0,0 +1,58 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/quota"
+	"github.com/tigrisdata/tigris/server/ratelimit"
+)
+
+func TestCreateNamespaceRequest(t *testing.T) {
+	negative := -1
+	require.NoError(t, (&createNamespaceRequest{Name: "ns1"}).validate())
+	require.NoError(t, (&createNamespaceRequest{Name: "ns1", Settings: &namespaceSettings{
+		StorageQuota: &quota.StorageQuota{Size: 1024},
+	}}).validate())
+
+	require.Equal(t, errors.InvalidArgument("empty namespace name is not allowed"), (&createNamespaceRequest{}).validate())
+	require.Equal(t, errors.InvalidArgument("the storage quota must be greater than 0, received 0"),
+		(&createNamespaceRequest{Name: "ns1", Settings: &namespaceSettings{StorageQuota: &quota.StorageQuota{}}}).validate())
+	require.Error(t, (&createNamespaceRequest{Name: "ns1", Settings: &namespaceSettings{
+		RateLimits: &ratelimit.Limits{Read: &negative},
+	}}).validate())
+
+	// the invalid requests are rejected before the namespace is created
+	s := &apiService{}
+	for _, body := range []string{`{"id": "ns1"}`, `[]`} {
+		w := httptest.NewRecorder()
+		s.createNamespace(w, httptest.NewRequest(http.MethodPost, namespacesPath, strings.NewReader(body)))
+		require.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func TestDeleteNamespaceForce(t *testing.T) {
+	s := &apiService{}
+	w := httptest.NewRecorder()
+	s.deleteNamespace(w, httptest.NewRequest(http.MethodDelete, "/admin/namespaces/ns1?force=yes", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "invalid force 'yes', expected true or false")
+}
@@ -0,0 +1,68 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+const (
+	// normalizePath returns the document of the body as it would be stored by an insert, without writing it.
+	normalizePath = adminPath + "/namespaces/{namespace}/databases/{db}/collections/{collection}/normalize"
+
+	// normalizeMaxBodySize is the maximum size of the document to normalize.
+	normalizeMaxBodySize = 16 * 1024 * 1024
+)
+
+// normalizeDocument validates the document of the body against the schema of the collection and returns its
+// normalized form, the same document an insert writes.
+func (s *apiService) normalizeDocument(w http.ResponseWriter, r *http.Request) {
+	namespace, db, collection := chi.URLParam(r, "namespace"), chi.URLParam(r, "db"), chi.URLParam(r, "collection")
+
+	tenant, err := s.tenantMgr.GetTenant(r.Context(), namespace)
+	if err != nil {
+		writeAdminError(w, errors.NotFound("namespace '%s' doesn't exist", namespace))
+		return
+	}
+
+	coll := tenant.GetCollection(db, collection)
+	if coll == nil {
+		writeAdminError(w, errors.NotFound("collection '%s' doesn't exist in the database '%s'", collection, db))
+		return
+	}
+
+	doc, err := io.ReadAll(io.LimitReader(r.Body, normalizeMaxBodySize+1))
+	if err != nil {
+		writeAdminError(w, errors.InvalidArgument("failed to read the document: %s", err.Error()))
+		return
+	}
+	if len(doc) > normalizeMaxBodySize {
+		writeAdminError(w, errors.InvalidArgument("document exceeds the limit of %d bytes", normalizeMaxBodySize))
+		return
+	}
+
+	normalized, err := normalizePayload(coll, doc)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(normalized)
+}
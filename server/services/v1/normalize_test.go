@@ -0,0 +1,160 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/schema"
+)
+
+func TestNormalizePayload(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": {"type": "integer"},
+			"name": {"type": "string"},
+			"obj": {
+				"type": "object",
+				"properties": {
+					"count": {"type": "integer"}
+				}
+			}
+		},
+		"primary_key": ["id"]
+	}`)
+	factory, err := schema.Build("t1", reqSchema, false)
+	require.NoError(t, err)
+	coll := schema.NewDefaultCollection("t1", 1, 1, factory.CollectionType, factory, "t1", nil)
+
+	t.Run("unchanged", func(t *testing.T) {
+		doc := []byte(`{"id":1,"name":"a"}`)
+		normalized, err := normalizePayload(coll, doc)
+		require.NoError(t, err)
+		require.Equal(t, doc, normalized)
+	})
+
+	t.Run("int64 strings", func(t *testing.T) {
+		normalized, err := normalizePayload(coll, []byte(`{"id":"1","name":null,"obj":{"count":"2"}}`))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id":1,"name":null,"obj":{"count":2}}`, string(normalized))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := normalizePayload(coll, []byte(`{"id":1,"name":5}`))
+		require.Error(t, err)
+
+		var tigrisErr *api.TigrisError
+		require.True(t, errors.As(err, &tigrisErr))
+		require.Equal(t, api.Code_INVALID_ARGUMENT, tigrisErr.Code)
+	})
+}
+
+func TestNormalizePayload_DefaultsAndAliases(t *testing.T) {
+	factory, err := schema.Build("t1", []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": {"type": "integer"},
+			"status": {"type": "string", "default": "active"},
+			"tags": {"type": "array", "items": {"type": "string"}, "default": ["new"]},
+			"full_name": {"type": "string", "x-tigris-aliases": ["name"]},
+			"address": {
+				"type": "object",
+				"properties": {
+					"zip_code": {"type": "string", "x-tigris-aliases": ["zip"]},
+					"country": {"type": "string", "default": "US"}
+				}
+			}
+		},
+		"primary_key": ["id"]
+	}`), false)
+	require.NoError(t, err)
+	coll := schema.NewDefaultCollection("t1", 1, 1, factory.CollectionType, factory, "t1", nil)
+
+	t.Run("defaults and aliases", func(t *testing.T) {
+		normalized, err := normalizePayload(coll, []byte(`{"id":1,"name":"a b","address":{"zip":"94105"}}`))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id":1,"status":"active","tags":["new"],"full_name":"a b","address":{"zip_code":"94105","country":"US"}}`,
+			string(normalized))
+	})
+
+	t.Run("set fields", func(t *testing.T) {
+		// the fields set, to null included, are kept and the defaults of a missing object are not set
+		normalized, err := normalizePayload(coll, []byte(`{"id":1,"status":null,"tags":[],"full_name":"a"}`))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id":1,"status":null,"tags":[],"full_name":"a"}`, string(normalized))
+	})
+
+	t.Run("field and alias", func(t *testing.T) {
+		_, err := normalizePayload(coll, []byte(`{"id":1,"name":"a","full_name":"b"}`))
+		require.Equal(t, errors.InvalidArgument("field 'full_name' is set along with its alias 'name'"), err)
+	})
+
+	t.Run("partial", func(t *testing.T) {
+		// the fields of an update are renamed but the defaults are not set
+		normalized, err := normalizePartialPayload(coll, []byte(`{"name":"a"}`))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"full_name":"a"}`, string(normalized))
+	})
+}
+
+func TestNormalizePayload_ComputedFields(t *testing.T) {
+	require.NoError(t, schema.RegisterComputeHook("test-services-full-name", func(doc map[string]interface{}) (interface{}, error) {
+		first, _ := doc["first"].(string)
+		last, _ := doc["last"].(string)
+		return first + " " + last, nil
+	}))
+	factory, err := schema.Build("t1", []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": {"type": "integer"},
+			"first": {"type": "string"},
+			"last": {"type": "string"},
+			"full_name": {"type": "string", "x-tigris-computed": "test-services-full-name"}
+		},
+		"primary_key": ["id"]
+	}`), false)
+	require.NoError(t, err)
+	coll := schema.NewDefaultCollection("t1", 1, 1, factory.CollectionType, factory, "t1", nil)
+
+	normalized, err := normalizePayload(coll, []byte(`{"id":1,"first":"John","last":"Doe"}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":1,"first":"John","last":"Doe","full_name":"John Doe"}`, string(normalized))
+
+	_, err = normalizePayload(coll, []byte(`{"id":1,"first":"John","last":"Doe","full_name":"Jane Doe"}`))
+	require.Equal(t, errors.InvalidArgument("field 'full_name' is computed and can't be set"), err)
+
+	// the fields of an update are not computed, the merged document is
+	partial, err := normalizePartialPayload(coll, []byte(`{"first":"Jane"}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"first":"Jane"}`, string(partial))
+	_, err = normalizePartialPayload(coll, []byte(`{"full_name":"Jane Doe"}`))
+	require.Equal(t, errors.InvalidArgument("field 'full_name' is computed and can't be set"), err)
+
+	merged, err := recomputeFields(coll, []byte(`{"id":1,"first":"Jane","last":"Doe","full_name":"John Doe"}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":1,"first":"Jane","last":"Doe","full_name":"Jane Doe"}`, string(merged))
+
+	// the exported documents are imported with their computed fields computed again
+	imported, err := normalizeImportPayload(coll, []byte(`{"id":1,"first":"Jane","last":"Doe","full_name":"John Doe"}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":1,"first":"Jane","last":"Doe","full_name":"Jane Doe"}`, string(imported))
+	imported, err = normalizeImportPayload(coll, []byte(`{"id":1,"first":"Jane","last":"Doe","full_name":null}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":1,"first":"Jane","last":"Doe","full_name":"Jane Doe"}`, string(imported))
+}
@@ -107,6 +107,25 @@ func (f *QueryRunnerFactory) GetDeleteQueryRunner(r *api.DeleteRequest, qm *metr
 	}
 }
 
+// GetImportQueryRunner returns ImportQueryRunner, the documents of every batch are set on the runner before it runs.
+func (f *QueryRunnerFactory) GetImportQueryRunner(db string, collection string, conflict string) *ImportQueryRunner {
+	return &ImportQueryRunner{
+		BaseQueryRunner: NewBaseQueryRunner(f.encoder, f.cdcMgr, f.txMgr, f.searchStore),
+		db:              db,
+		collection:      collection,
+		conflict:        conflict,
+	}
+}
+
+// GetRestoreQueryRunner returns RestoreQueryRunner, the documents of every batch are set on the runner before it runs.
+func (f *QueryRunnerFactory) GetRestoreQueryRunner(db string, collection string) *RestoreQueryRunner {
+	return &RestoreQueryRunner{
+		BaseQueryRunner: NewBaseQueryRunner(f.encoder, f.cdcMgr, f.txMgr, f.searchStore),
+		db:              db,
+		collection:      collection,
+	}
+}
+
 // GetStreamingQueryRunner returns StreamingQueryRunner.
 func (f *QueryRunnerFactory) GetStreamingQueryRunner(r *api.ReadRequest, streaming Streaming, qm *metrics.StreamingQueryMetrics) *StreamingQueryRunner {
 	return &StreamingQueryRunner{
@@ -127,6 +146,14 @@ func (f *QueryRunnerFactory) GetSearchQueryRunner(r *api.SearchRequest, streamin
 	}
 }
 
+// GetMultiCollectionSearchQueryRunner for executing Search on multiple collections of a database.
+func (f *QueryRunnerFactory) GetMultiCollectionSearchQueryRunner(r *api.SearchRequest, streaming SearchStreaming, qm *metrics.SearchQueryMetrics) *MultiCollectionSearchQueryRunner {
+	return &MultiCollectionSearchQueryRunner{
+		SearchQueryRunner: f.GetSearchQueryRunner(r, streaming, qm),
+		concurrency:       config.DefaultConfig.Search.MultiCollectionConcurrency,
+	}
+}
+
 func (f *QueryRunnerFactory) GetPublishQueryRunner(r *api.PublishRequest) *PublishQueryRunner {
 	return &PublishQueryRunner{
 		BaseQueryRunner: NewBaseQueryRunner(f.encoder, f.cdcMgr, f.txMgr, f.searchStore),
@@ -225,6 +252,8 @@ func (runner *BaseQueryRunner) insertOrReplace(ctx context.Context, tx transacti
 	var err error
 	ts := internal.NewTimestamp()
 	allKeys := make([][]byte, 0, len(documents))
+	var bytesWritten int64
+	ctx = withCompression(ctx, db, coll)
 	for _, doc := range documents {
 		// reset it back to doc
 		doc, err = runner.mutateAndValidatePayload(coll, doc)
@@ -251,22 +280,109 @@ func (runner *BaseQueryRunner) insertOrReplace(ctx context.Context, tx transacti
 			// as Int64 or timestamp to ensure uniqueness if multiple workers end up generating same timestamp.
 			err = tx.Insert(ctx, key, tableData)
 		} else {
-			err = tx.Replace(ctx, key, tableData, false)
+			err = runner.replace(ctx, tx, coll, key, tableData)
 		}
 		if err != nil {
 			return nil, nil, err
 		}
 		allKeys = append(allKeys, keyGen.getKeysForResp())
+		bytesWritten += int64(len(keyGen.document))
+		metrics.RecordDocumentSize(db.Name(), coll.GetName(), len(keyGen.document))
 	}
+	recordWriteUsage(ctx, tenant, int64(len(documents)), bytesWritten)
+	// the size of the replaced documents is reconciled by the storage quota
+	recordStorage(ctx, tenant, bytesWritten)
 	return ts, allKeys, err
 }
 
+// replace writes the document, the current document is read beforehand if the collection stores the pre-images.
+func (runner *BaseQueryRunner) replace(ctx context.Context, tx transaction.Tx, coll *schema.DefaultCollection, key keys.Key, tableData *internal.TableData) error {
+	if coll.PreImages {
+		it, err := tx.Read(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		var current kv.KeyValue
+		if it.Next(&current) {
+			if ctx, err = withPreImage(ctx, coll, current.Data); err != nil {
+				return err
+			}
+		} else if err = it.Err(); err != nil {
+			return err
+		}
+	}
+
+	return tx.Replace(ctx, key, tableData, false)
+}
+
+// withPreImage attaches the current document to the context of the write if the collection stores the pre-images in
+// its change stream.
+func withPreImage(ctx context.Context, coll *schema.DefaultCollection, current *internal.TableData) (context.Context, error) {
+	if !coll.PreImages || current == nil {
+		return ctx, nil
+	}
+
+	enc, err := internal.Encode(current)
+	if err != nil {
+		return nil, err
+	}
+	return kv.WithPreImage(ctx, enc), nil
+}
+
+// withCompression attaches the codec of the collection to the context of the writes, the documents are compressed
+// with it when they are stored. The documents read are decompressed whatever the codec they were written with.
+func withCompression(ctx context.Context, db *metadata.Database, coll *schema.DefaultCollection) context.Context {
+	if coll.Compression == internal.NoCompression {
+		return ctx
+	}
+
+	return kv.WithCompression(ctx, coll.Compression, db.Name(), coll.GetName())
+}
+
 func (runner *BaseQueryRunner) mutateAndValidatePayload(coll *schema.DefaultCollection, doc []byte) ([]byte, error) {
+	return normalizePayload(coll, doc)
+}
+
+// normalizePayload validates the document against the schema of the collection and returns the document as it is
+// stored, with the fields set with an alias renamed, the defaults of the missing fields set, the int64 fields sent as
+// strings converted to numbers and the computed fields populated. The document is returned unchanged when it isn't
+// mutated.
+func normalizePayload(coll *schema.DefaultCollection, doc []byte) ([]byte, error) {
+	return normalize(coll, doc, false, false)
+}
+
+// normalizePartialPayload is normalizePayload for the fields of an update, the required fields are not checked, the
+// defaults are not applied and the computed fields are only populated once the fields are merged with the document.
+func normalizePartialPayload(coll *schema.DefaultCollection, doc []byte) ([]byte, error) {
+	return normalize(coll, doc, true, false)
+}
+
+// normalizeImportPayload is normalizePayload for the imported documents, the values of the computed fields, like in
+// the documents exported from the collection, are dropped and computed again.
+func normalizeImportPayload(coll *schema.DefaultCollection, doc []byte) ([]byte, error) {
+	return normalize(coll, doc, false, true)
+}
+
+func normalize(coll *schema.DefaultCollection, doc []byte, partial bool, removeComputed bool) ([]byte, error) {
 	deserializedDoc, err := json.Decode(doc)
 	if ulog.E(err) {
 		return doc, err
 	}
 
+	// the aliases are resolved and the defaults applied before the nulls are removed, a field set to null is not
+	// replaced by its default
+	aliased, err := coll.ResolveAliases(deserializedDoc)
+	if err != nil {
+		return doc, err
+	}
+	defaulted := false
+	if !partial {
+		if defaulted, err = coll.ApplyDefaults(deserializedDoc); err != nil {
+			return doc, err
+		}
+	}
+
 	var nulls []string
 	for k, v := range deserializedDoc {
 		// for schema validation, if the field is set to null, remove it.
@@ -277,20 +393,36 @@ func (runner *BaseQueryRunner) mutateAndValidatePayload(coll *schema.DefaultColl
 		}
 	}
 
+	removed := removeComputed && coll.RemoveComputedValues(deserializedDoc)
+
 	p := newPayloadMutator(coll)
 	// this will mutate map, so we need to serialize this map again
 	if err := p.convertStringToInt64(deserializedDoc); err != nil {
-		return doc, err
+		return doc, schema.ToAPIError(err)
 	}
 
-	if err := coll.Validate(deserializedDoc); err != nil {
+	validate := coll.Validate
+	if partial {
+		validate = coll.ValidatePartial
+	}
+	if err := validate(deserializedDoc); err != nil {
 		// schema validation failed
-		return doc, err
+		return doc, schema.ToAPIError(err)
+	}
+
+	computed := false
+	if !partial {
+		if computed, err = coll.Compute(deserializedDoc); err != nil {
+			return doc, err
+		}
 	}
 
-	if p.isMutated() {
+	if p.isMutated() || computed || removed || aliased || defaulted {
 		for _, n := range nulls {
-			deserializedDoc[n] = nil
+			// the computed fields set to null are populated
+			if _, ok := deserializedDoc[n]; !ok {
+				deserializedDoc[n] = nil
+			}
 		}
 
 		return json.Encode(deserializedDoc)
@@ -329,6 +461,15 @@ func (runner *BaseQueryRunner) mustBeDocumentsCollection(collection *schema.Defa
 	return nil
 }
 
+// mustNotBeAppendOnly rejects the methods changing or removing the existing documents of an append-only collection.
+func (runner *BaseQueryRunner) mustNotBeAppendOnly(collection *schema.DefaultCollection, method string) error {
+	if collection.AppendOnly {
+		return errors.PermissionDenied("%s is not allowed on the append-only collection '%s'", method, collection.GetName())
+	}
+
+	return nil
+}
+
 func (runner *BaseQueryRunner) mustBeMessagesCollection(collection *schema.DefaultCollection, method string) error {
 	if collection.Type() != schema.TopicType {
 		return errors.InvalidArgument("%s is only supported on collection type of 'messages'", method)
@@ -343,11 +484,60 @@ func (runner *BaseQueryRunner) getSortOrdering(coll *schema.DefaultCollection, s
 		return nil, err
 	}
 
+	return resolveSortOrdering(coll, ordering)
+}
+
+// getEffectiveSortOrdering returns the ordering of the request like getSortOrdering, or the default ordering of the
+// collection if the request doesn't sort. The default ordering is the primary key ascending, the primary key fields
+// that can't be sorted on are left out of it.
+func (runner *BaseQueryRunner) getEffectiveSortOrdering(coll *schema.DefaultCollection, sortReq jsoniter.RawMessage) (*sort.Ordering, error) {
+	ordering, err := sort.UnmarshalSortOrDefault(sortReq, defaultSortOrdering(coll))
+	if err != nil || ordering == nil {
+		return nil, err
+	}
+
+	return resolveSortOrdering(coll, ordering)
+}
+
+// getSearchSortOrdering returns the ordering of the search, the searches without a query are listings that are ordered
+// by the default ordering of the collection if it is enabled and the request doesn't sort.
+func (runner *SearchQueryRunner) getSearchSortOrdering(coll *schema.DefaultCollection) (*sort.Ordering, error) {
+	if config.DefaultConfig.Search.DefaultSortByPrimaryKey && len(runner.req.Q) == 0 {
+		return runner.getEffectiveSortOrdering(coll, runner.req.Sort)
+	}
+
+	return runner.getSortOrdering(coll, runner.req.Sort)
+}
+
+func defaultSortOrdering(coll *schema.DefaultCollection) sort.Ordering {
+	if coll.Indexes == nil || coll.Indexes.PrimaryKey == nil {
+		return nil
+	}
+
+	var fields []string
+	for _, f := range coll.Indexes.PrimaryKey.Fields {
+		if cf, err := coll.GetQueryableField(f.FieldName); err == nil && cf.Sortable {
+			fields = append(fields, f.FieldName)
+		}
+	}
+	return sort.Ascending(fields...)
+}
+
+// resolveSortOrdering checks that the fields of the ordering can be sorted on and replaces their names with the names
+// of the fields in the search backend.
+func resolveSortOrdering(coll *schema.DefaultCollection, ordering *sort.Ordering) (*sort.Ordering, error) {
 	for i, sf := range *ordering {
 		cf, err := coll.GetQueryableField(sf.Name)
 		if err != nil {
 			return nil, err
 		}
+		if sf.Aggregate == sort.CountAggregate {
+			if cf.IsReserved() || cf.DataType != schema.ArrayType {
+				return nil, errors.InvalidArgument("Cannot sort on the count of `%s` field, it is not an array", sf.Name)
+			}
+			(*ordering)[i].Name = schema.ToSearchArrayLengthKey(cf.Name())
+			continue
+		}
 		if cf.InMemoryName() != cf.Name() {
 			(*ordering)[i].Name = cf.InMemoryName()
 		}
@@ -355,6 +545,10 @@ func (runner *BaseQueryRunner) getSortOrdering(coll *schema.DefaultCollection, s
 		if !cf.Sortable {
 			return nil, errors.InvalidArgument("Cannot sort on `%s` field", sf.Name)
 		}
+		// the search backend collates the string values of a field with the locale of its schema only
+		if sf.Locale != "" {
+			return nil, errors.InvalidArgument("Cannot sort on `%s` field with the `%s` locale, the `%s` of a sort order is not supported yet", sf.Name, sf.Locale, sort.LocaleKey)
+		}
 	}
 	return ordering, nil
 }
@@ -423,6 +617,9 @@ func (runner *ReplaceQueryRunner) Run(ctx context.Context, tx transaction.Tx, te
 	if err = runner.mustBeDocumentsCollection(coll, "replace"); err != nil {
 		return nil, ctx, err
 	}
+	if err = runner.mustNotBeAppendOnly(coll, "replace"); err != nil {
+		return nil, ctx, err
+	}
 
 	ts, allKeys, err := runner.insertOrReplace(ctx, tx, tenant, db, coll, runner.req.GetDocuments(), false)
 	if err != nil {
@@ -462,20 +659,29 @@ func (runner *UpdateQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 	if err = runner.mustBeDocumentsCollection(collection, "update"); err != nil {
 		return nil, ctx, err
 	}
+	if err = runner.mustNotBeAppendOnly(collection, "update"); err != nil {
+		return nil, ctx, err
+	}
 
 	var factory *update.FieldOperatorFactory
 	factory, err = update.BuildFieldOperators(runner.req.Fields)
 	if err != nil {
 		return nil, ctx, err
 	}
+	metrics.UpdateOperatorsUsed(tenant.GetNamespace().StrId(), db.Name(), collection.Name, factory.Operators())
+	if err = runner.validateImmutableFields(collection, factory); err != nil {
+		return nil, ctx, err
+	}
 
 	if fieldOperator, ok := factory.FieldOperators[string(update.Set)]; ok {
 		// Set operation needs schema validation as well as mutation if we need to convert numeric fields from string to int64
-		fieldOperator.Input, err = runner.mutateAndValidatePayload(collection, fieldOperator.Input)
+		fieldOperator.Input, err = normalizePartialPayload(collection, fieldOperator.Input)
 		if err != nil {
 			return nil, ctx, err
 		}
+		factory.CoerceNumbers(collection)
 	}
+	factory.CheckBitFields(collection)
 
 	table, err := runner.encoder.EncodeTableName(tenant.GetNamespace(), db, collection)
 	if err != nil {
@@ -522,12 +728,19 @@ func (runner *UpdateQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 		limit = int32(runner.req.Options.Limit)
 	}
 	modifiedCount := int32(0)
+	var readUsage metrics.Usage
+	var bytesWritten, storageDelta int64
+	defer func() {
+		recordReadUsage(ctx, tenant, readUsage)
+	}()
 	var row Row
 	for iterator.Next(&row) {
 		key, err := keys.FromBinary(table, row.Key)
 		if err != nil {
 			return nil, ctx, err
 		}
+		readUsage.DocumentsRead++
+		readUsage.BytesRead += int64(len(row.Data.RawData))
 
 		// MergeAndGet merge the user input with existing doc and return the merged JSON document which we need to
 		// persist back.
@@ -535,19 +748,34 @@ func (runner *UpdateQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 		if er != nil {
 			return nil, ctx, err
 		}
+		if merged, err = recomputeFields(collection, merged); err != nil {
+			return nil, ctx, err
+		}
 
 		newData := internal.NewTableDataWithTS(row.Data.CreatedAt, ts, merged)
 		newData.SetVersion(collection.GetVersion())
+		// the document is already read for the merge, it is the pre-image of the change
+		writeCtx, err := withPreImage(withCompression(ctx, db, collection), collection, row.Data)
+		if err != nil {
+			return nil, ctx, err
+		}
 		// as we have merged the data, it is safe to call replace
-		if err = tx.Replace(ctx, key, newData, true); ulog.E(err) {
+		if err = tx.Replace(writeCtx, key, newData, true); ulog.E(err) {
 			return nil, ctx, err
 		}
+		bytesWritten += int64(len(merged))
+		storageDelta += int64(len(merged) - len(row.Data.RawData))
+		metrics.RecordDocumentSize(db.Name(), collection.GetName(), len(merged))
 		modifiedCount++
 		if limit > 0 && modifiedCount == limit {
 			break
 		}
 	}
 
+	recordWriteUsage(ctx, tenant, int64(modifiedCount), bytesWritten)
+	recordStorage(ctx, tenant, storageDelta)
+	countFieldWrites(db.Name(), collection.GetName(), factory, modifiedCount)
+	metrics.SetRowCounts(ctx, rowsScanned(iterator, int64(modifiedCount)), int64(modifiedCount))
 	ctx = metrics.UpdateSpanTags(ctx, runner.queryMetrics)
 	return &Response{
 		status:        UpdatedStatus,
@@ -556,6 +784,57 @@ func (runner *UpdateQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 	}, ctx, err
 }
 
+// countFieldWrites counts the writes of the fields changed by the field operators, once per document merged.
+func countFieldWrites(db string, collection string, factory *update.FieldOperatorFactory, modifiedCount int32) {
+	if modifiedCount == 0 {
+		return
+	}
+
+	fields, err := factory.Fields()
+	if ulog.E(err) {
+		return
+	}
+	metrics.CountFieldWrites(db, collection, fields, int64(modifiedCount))
+}
+
+// recomputeFields populates the computed fields of the merged document again, as the fields they are derived from may
+// have been updated.
+func recomputeFields(collection *schema.DefaultCollection, merged []byte) ([]byte, error) {
+	if len(collection.ComputedFields) == 0 {
+		return merged, nil
+	}
+
+	doc, err := json.Decode(merged)
+	if err != nil {
+		return nil, err
+	}
+	computed, err := collection.Compute(doc)
+	if err != nil || !computed {
+		return merged, err
+	}
+	return json.Encode(doc)
+}
+
+// validateImmutableFields rejects the updates that change a field annotated with "x-tigris-immutable".
+func (runner *UpdateQueryRunner) validateImmutableFields(collection *schema.DefaultCollection, factory *update.FieldOperatorFactory) error {
+	if len(collection.ImmutableFields) == 0 {
+		return nil
+	}
+
+	fields, err := factory.Fields()
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if immutable, ok := collection.GetImmutableField(f); ok {
+			return errors.InvalidArgument("field '%s' is immutable and can't be updated", immutable)
+		}
+	}
+
+	return nil
+}
+
+// validateBitFields checks that the fields of the "$bit" operator are integer fields of the collection.
 type DeleteQueryRunner struct {
 	*BaseQueryRunner
 
@@ -579,6 +858,9 @@ func (runner *DeleteQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 	if err = runner.mustBeDocumentsCollection(collection, "delete"); err != nil {
 		return nil, ctx, err
 	}
+	if err = runner.mustNotBeAppendOnly(collection, "delete"); err != nil {
+		return nil, ctx, err
+	}
 
 	table, err := runner.encoder.EncodeTableName(tenant.GetNamespace(), db, collection)
 	if err != nil {
@@ -628,16 +910,28 @@ func (runner *DeleteQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 		limit = int32(runner.req.Options.Limit)
 	}
 	modifiedCount := int32(0)
+	var readUsage metrics.Usage
+	var bytesDeleted int64
+	defer func() {
+		recordReadUsage(ctx, tenant, readUsage)
+	}()
 	var row Row
 	for iterator.Next(&row) {
 		key, err := keys.FromBinary(table, row.Key)
 		if err != nil {
 			return nil, ctx, err
 		}
+		readUsage.DocumentsRead++
+		readUsage.BytesRead += int64(len(row.Data.RawData))
 
-		if err = tx.Delete(ctx, key); ulog.E(err) {
+		writeCtx, err := withPreImage(ctx, collection, row.Data)
+		if err != nil {
 			return nil, ctx, err
 		}
+		if err = tx.Delete(writeCtx, key); ulog.E(err) {
+			return nil, ctx, err
+		}
+		bytesDeleted += int64(len(row.Data.RawData))
 
 		modifiedCount++
 		if limit > 0 && modifiedCount == limit {
@@ -645,6 +939,10 @@ func (runner *DeleteQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 		}
 	}
 
+	// the deleted documents are written without a value
+	recordWriteUsage(ctx, tenant, int64(modifiedCount), 0)
+	recordStorage(ctx, tenant, -bytesDeleted)
+	metrics.SetRowCounts(ctx, rowsScanned(iterator, int64(modifiedCount)), int64(modifiedCount))
 	ctx = metrics.UpdateSpanTags(ctx, runner.queryMetrics)
 	return &Response{
 		status:        DeletedStatus,
@@ -660,6 +958,8 @@ type StreamingQueryRunner struct {
 	req          *api.ReadRequest
 	streaming    Streaming
 	queryMetrics *metrics.StreamingQueryMetrics
+	// usage is the documents read by the request
+	usage metrics.Usage
 }
 
 type readerOptions struct {
@@ -755,6 +1055,10 @@ func (runner *StreamingQueryRunner) instrumentRunner(ctx context.Context, option
 // ReadOnly is used by the read query runner to handle long-running reads. This method operates by starting a new
 // transaction when needed which means a single user request may end up creating multiple read only transactions.
 func (runner *StreamingQueryRunner) ReadOnly(ctx context.Context, tenant *metadata.Tenant) (*Response, context.Context, error) {
+	defer func() {
+		recordReadUsage(ctx, tenant, runner.usage)
+	}()
+
 	db, err := runner.getDatabaseFromTenant(ctx, tenant, runner.req.GetDb())
 	if err != nil {
 		return nil, ctx, err
@@ -811,6 +1115,12 @@ func (runner *StreamingQueryRunner) ReadOnly(ctx context.Context, tenant *metada
 // if we see ErrTransactionMaxDurationReached which is expected because we do not expect caller to do long reads in an
 // explicit transaction.
 func (runner *StreamingQueryRunner) Run(ctx context.Context, tx transaction.Tx, tenant *metadata.Tenant) (*Response, context.Context, error) {
+	// the runner is run again when the transaction is retried, only the reads of the last attempt are recorded
+	runner.usage = metrics.Usage{}
+	defer func() {
+		recordReadUsage(ctx, tenant, runner.usage)
+	}()
+
 	db, err := runner.getDatabase(ctx, tx, tenant, runner.req.GetDb())
 	if err != nil {
 		return nil, ctx, err
@@ -891,6 +1201,8 @@ func (runner *StreamingQueryRunner) iterate(iterator Iterator, fieldFactory *rea
 		if limit > 0 && limit <= totalResults {
 			return lastRowKey, nil
 		}
+		runner.usage.DocumentsRead++
+		runner.usage.BytesRead += int64(len(row.Data.RawData))
 
 		newValue, err := fieldFactory.Apply(row.Data.RawData)
 		if ulog.E(err) {
@@ -964,7 +1276,7 @@ func (runner *SearchQueryRunner) ReadOnly(ctx context.Context, tenant *metadata.
 		return nil, ctx, err
 	}
 
-	sortOrder, err := runner.getSortOrdering(collection, runner.req.Sort)
+	sortOrder, err := runner.getSearchSortOrdering(collection)
 	if err != nil {
 		return nil, ctx, err
 	}
@@ -1004,6 +1316,11 @@ func (runner *SearchQueryRunner) ReadOnly(ctx context.Context, tenant *metadata.
 		return nil, ctx, err
 	}
 
+	usage := metrics.Usage{SearchQueries: 1}
+	defer func() {
+		recordReadUsage(ctx, tenant, usage)
+	}()
+
 	pageNo := int32(defaultPageNo)
 	if runner.req.Page > 0 {
 		pageNo = runner.req.Page
@@ -1012,6 +1329,8 @@ func (runner *SearchQueryRunner) ReadOnly(ctx context.Context, tenant *metadata.
 		resp := &api.SearchResponse{}
 		var row Row
 		for iterator.Next(&row) {
+			usage.DocumentsRead++
+			usage.BytesRead += int64(len(row.Data.RawData))
 			if searchQ.ReadFields != nil {
 				// apply field selection
 				newValue, err := searchQ.ReadFields.Apply(row.Data.RawData)
@@ -1129,7 +1448,7 @@ func (runner *SearchQueryRunner) getFieldSelection(coll *schema.DefaultCollectio
 		selectionFields = runner.req.IncludeFields
 	} else if len(runner.req.ExcludeFields) > 0 {
 		selectionFields = runner.req.ExcludeFields
-	} else {
+	} else if len(coll.SearchHiddenFields) == 0 {
 		return nil, nil
 	}
 
@@ -1138,6 +1457,14 @@ func (runner *SearchQueryRunner) getFieldSelection(coll *schema.DefaultCollectio
 		Exclude: map[string]read.Field{},
 	}
 
+	// fields annotated with "searchReturn": false are never returned, even if they are explicitly included
+	for _, hidden := range coll.SearchHiddenFields {
+		factory.AddField(&read.SimpleField{
+			Name: hidden,
+			Incl: false,
+		})
+	}
+
 	for _, sf := range selectionFields {
 		cf, err := coll.GetQueryableField(sf)
 		if err != nil {
@@ -1236,7 +1563,7 @@ func (runner *PublishQueryRunner) publish(ctx context.Context, tx transaction.Tx
 		}
 
 		tableData := internal.NewTableDataWithTS(ts, nil, message)
-		err = tx.Replace(ctx, key, tableData, false)
+		err = tx.Replace(withCompression(ctx, db, coll), key, tableData, false)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -1473,7 +1800,8 @@ func (runner *CollectionQueryRunner) Run(ctx context.Context, tx transaction.Tx,
 			return nil, ctx, errors.AlreadyExists("collection already exist")
 		}
 
-		schFactory, err := schema.Build(runner.createOrUpdateReq.GetCollection(), runner.createOrUpdateReq.GetSchema())
+		schFactory, err := schema.Build(runner.createOrUpdateReq.GetCollection(), runner.createOrUpdateReq.GetSchema(),
+			config.DefaultConfig.Schema.StrictFormats)
 		if err != nil {
 			return nil, ctx, err
 		}
@@ -1528,23 +1856,29 @@ func (runner *CollectionQueryRunner) Run(ctx context.Context, tx transaction.Tx,
 			return nil, ctx, err
 		}
 
-		size, err := tenant.CollectionSize(ctx, db, coll)
+		sizeOpts, err := sizeOptionsFromHeaders(ctx)
 		if err != nil {
 			return nil, ctx, err
 		}
+		size, err := describeSize(coll.GetName(), sizeOpts, func() (int64, error) {
+			return tenant.CollectionSize(ctx, db, coll)
+		}, func() (int64, error) {
+			return tenant.CollectionSizeExact(ctx, db, coll, sizeOpts.RowLimit)
+		})
+		if err != nil {
+			return nil, ctx, err
+		}
+		setSizeEstimatedHeader(ctx, sizeOpts)
+		setCollectionsMetadataHeader(ctx, []*schema.DefaultCollection{coll})
 
-		tenantName := tenant.GetNamespace().Metadata().Name
-
-		metrics.UpdateCollectionSizeMetrics(namespace, tenantName, db.Name(), coll.GetName(), size)
-		// remove indexing version from the schema before returning the response
-		sch := schema.RemoveIndexingVersion(coll.Schema)
+		if !sizeOpts.Exact {
+			tenantName := tenant.GetNamespace().Metadata().Name
+			metrics.UpdateCollectionSizeMetrics(namespace, tenantName, db.Name(), coll.GetName(), size)
+		}
 
-		// Generate schema in the requested language format
-		if runner.describeReq.SchemaFormat != "" {
-			sch, err = schema.Generate(sch, runner.describeReq.SchemaFormat)
-			if err != nil {
-				return nil, ctx, err
-			}
+		sch, err := describeSchema(coll, runner.describeReq.SchemaFormat)
+		if err != nil {
+			return nil, ctx, err
 		}
 
 		return &Response{
@@ -1637,26 +1971,34 @@ func (runner *DatabaseQueryRunner) Run(ctx context.Context, tx transaction.Tx, t
 		}
 		tenantName := tenant.GetNamespace().Metadata().Name
 
-		collectionList := db.ListCollection()
+		sizeOpts, err := sizeOptionsFromHeaders(ctx)
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		collectionList, err := describedCollections(ctx, db)
+		if err != nil {
+			return nil, ctx, err
+		}
 
 		collections := make([]*api.CollectionDescription, len(collectionList))
 		for i, c := range collectionList {
-			size, err := tenant.CollectionSize(ctx, db, c)
+			size, err := describeSize(c.GetName(), sizeOpts, func() (int64, error) {
+				return tenant.CollectionSize(ctx, db, c)
+			}, func() (int64, error) {
+				return tenant.CollectionSizeExact(ctx, db, c, sizeOpts.RowLimit)
+			})
 			if err != nil {
 				return nil, ctx, err
 			}
 
-			metrics.UpdateCollectionSizeMetrics(namespace, tenantName, db.Name(), c.GetName(), size)
-
-			// remove indexing version from the schema before returning the response
-			sch := schema.RemoveIndexingVersion(c.Schema)
+			if !sizeOpts.Exact {
+				metrics.UpdateCollectionSizeMetrics(namespace, tenantName, db.Name(), c.GetName(), size)
+			}
 
-			// Generate schema in the requested language format
-			if runner.describe.SchemaFormat != "" {
-				sch, err = schema.Generate(sch, runner.describe.SchemaFormat)
-				if err != nil {
-					return nil, ctx, err
-				}
+			sch, err := describeSchema(c, runner.describe.SchemaFormat)
+			if err != nil {
+				return nil, ctx, err
 			}
 
 			collections[i] = &api.CollectionDescription{
@@ -1667,12 +2009,20 @@ func (runner *DatabaseQueryRunner) Run(ctx context.Context, tx transaction.Tx, t
 			}
 		}
 
-		size, err := tenant.DatabaseSize(ctx, db)
+		size, err := describeSize(db.Name(), sizeOpts, func() (int64, error) {
+			return tenant.DatabaseSize(ctx, db)
+		}, func() (int64, error) {
+			return tenant.DatabaseSizeExact(ctx, db, sizeOpts.RowLimit)
+		})
 		if err != nil {
 			return nil, ctx, err
 		}
+		setSizeEstimatedHeader(ctx, sizeOpts)
+		setCollectionsMetadataHeader(ctx, collectionList)
 
-		metrics.UpdateDbSizeMetrics(namespace, tenantName, db.Name(), size)
+		if !sizeOpts.Exact {
+			metrics.UpdateDbSizeMetrics(namespace, tenantName, db.Name(), size)
+		}
 
 		return &Response{
 			Response: &api.DescribeDatabaseResponse{
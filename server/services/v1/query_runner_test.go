@@ -18,9 +18,14 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/query/sort"
+	"github.com/tigrisdata/tigris/query/update"
 	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/uber-go/tally"
 )
 
 func TestSearchQueryRunner_getFacetFields(t *testing.T) {
@@ -203,10 +208,112 @@ func TestSearchQueryRunner_getSortOrdering(t *testing.T) {
 		assert.Exactly(t, expected, sortOrder)
 	})
 
+	t.Run("sort with a locale", func(t *testing.T) {
+		runner.req.Sort = []byte(`[{"field_1":{"order":"$asc","locale":"de"}}]`)
+		sortOrder, err := runner.getSortOrdering(collection, runner.req.Sort)
+		assert.ErrorContains(t, err, "Cannot sort on `field_1` field with the `de` locale, the `locale` of a sort order is not supported yet")
+		assert.Nil(t, sortOrder)
+	})
+
 	t.Run("Invalid sort input", func(t *testing.T) {
 		runner.req.Sort = []byte(`[{"field_1":"descending"}]`)
 		sort, err := runner.getSortOrdering(collection, runner.req.Sort)
 		assert.ErrorContains(t, err, "Sort order can only be `$asc` or `$desc`")
 		assert.Nil(t, sort)
 	})
+
+	t.Run("sort on the count of an array field", func(t *testing.T) {
+		collection := &schema.DefaultCollection{
+			QueryableFields: []*schema.QueryableField{
+				schema.NewQueryableField("tags", schema.ArrayType, schema.StringType, nil, nil),
+				schema.NewQueryableField("field_1", schema.StringType, schema.UnknownType, nil, nil),
+			},
+		}
+
+		runner.req.Sort = []byte(`[{"$count":"tags","order":"$desc"}]`)
+		sortOrder, err := runner.getSortOrdering(collection, runner.req.Sort)
+		assert.NoError(t, err)
+		assert.Exactly(t, &sort.Ordering{
+			{Name: schema.ToSearchArrayLengthKey("tags"), Ascending: false, Aggregate: sort.CountAggregate},
+		}, sortOrder)
+
+		runner.req.Sort = []byte(`[{"$count":"field_1","order":"$asc"}]`)
+		sortOrder, err = runner.getSortOrdering(collection, runner.req.Sort)
+		assert.ErrorContains(t, err, "Cannot sort on the count of `field_1` field, it is not an array")
+		assert.Nil(t, sortOrder)
+	})
+}
+
+func TestSearchQueryRunner_getEffectiveSortOrdering(t *testing.T) {
+	factory, err := schema.Build("t1", []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"price": { "type": "number" },
+			"payload": { "type": "string", "format": "byte" }
+		},
+		"primary_key": ["id", "payload"]
+	}`), false)
+	require.NoError(t, err)
+	collection := schema.NewDefaultCollection("t1", 1, 1, factory.CollectionType, factory, "t1", nil)
+	runner := &SearchQueryRunner{req: &api.SearchRequest{}}
+
+	t.Run("empty input yields the primary key", func(t *testing.T) {
+		for _, input := range []string{``, `[]`} {
+			ordering, err := runner.getEffectiveSortOrdering(collection, []byte(input))
+			require.NoError(t, err)
+			// the binary field of the primary key can't be sorted on
+			require.Exactly(t, &sort.Ordering{{Name: schema.ReservedFields[schema.IdToSearchKey], Ascending: true}}, ordering)
+		}
+	})
+
+	t.Run("explicit input overrides the default", func(t *testing.T) {
+		ordering, err := runner.getEffectiveSortOrdering(collection, []byte(`[{"price":"$desc"}]`))
+		require.NoError(t, err)
+		require.Exactly(t, &sort.Ordering{{Name: "price", Ascending: false}}, ordering)
+
+		_, err = runner.getEffectiveSortOrdering(collection, []byte(`[{"payload":"$desc"}]`))
+		require.ErrorContains(t, err, "Cannot sort on `payload` field")
+	})
+
+	t.Run("searches", func(t *testing.T) {
+		defer func(enabled bool) { config.DefaultConfig.Search.DefaultSortByPrimaryKey = enabled }(config.DefaultConfig.Search.DefaultSortByPrimaryKey)
+
+		config.DefaultConfig.Search.DefaultSortByPrimaryKey = false
+		ordering, err := runner.getSearchSortOrdering(collection)
+		require.NoError(t, err)
+		require.Nil(t, ordering)
+
+		// only the listings are ordered by the primary key, the other searches keep the order of the relevance
+		config.DefaultConfig.Search.DefaultSortByPrimaryKey = true
+		ordering, err = runner.getSearchSortOrdering(collection)
+		require.NoError(t, err)
+		require.Exactly(t, &sort.Ordering{{Name: schema.ReservedFields[schema.IdToSearchKey], Ascending: true}}, ordering)
+
+		runner.req.Q = "cheap"
+		defer func() { runner.req.Q = "" }()
+		ordering, err = runner.getSearchSortOrdering(collection)
+		require.NoError(t, err)
+		require.Nil(t, ordering)
+	})
+}
+
+func TestCountFieldWrites(t *testing.T) {
+	scope := tally.NewTestScope("", nil)
+	metrics.FieldMetrics = scope
+	defer func() { metrics.FieldMetrics = nil }()
+
+	factory, err := update.BuildFieldOperators([]byte(`{"$set": {"views": 10, "stats.likes": 2}, "$unset": ["views"]}`))
+	require.NoError(t, err)
+	countFieldWrites("db1", "posts", factory, 3)
+	factory, err = update.BuildFieldOperators([]byte(`{"$set": {"views": 11}}`))
+	require.NoError(t, err)
+	countFieldWrites("db1", "posts", factory, 1)
+	// nothing is counted when no document is written
+	countFieldWrites("db1", "posts", factory, 0)
+
+	counters := scope.Snapshot().Counters()
+	require.Len(t, counters, 2)
+	require.Equal(t, int64(4), counters["writes+collection=posts,db=db1,field=views"].Value())
+	require.Equal(t, int64(3), counters["writes+collection=posts,db=db1,field=stats.likes"].Value())
 }
@@ -0,0 +1,146 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/ratelimit"
+)
+
+// rateLimitsPath is the request rate limits of a namespace, the limits set for it override the default limits.
+const rateLimitsPath = adminPath + "/namespaces/{namespace}/rate_limits"
+
+type namespaceRateLimits struct {
+	Namespace string `json:"namespace"`
+	// Limits are the limits applied to the namespace.
+	Limits config.RequestRateLimits `json:"limits"`
+	// Override are the limits set for the namespace, if any.
+	Override *ratelimit.Limits `json:"override,omitempty"`
+}
+
+func (s *apiService) registerRateLimitRoutes(router chi.Router) {
+	s.adminRoute(router, http.MethodGet, rateLimitsPath, "GetRateLimits", s.getRateLimits)
+	s.adminRoute(router, http.MethodPut, rateLimitsPath, "SetRateLimits", s.setRateLimits)
+	s.adminRoute(router, http.MethodDelete, rateLimitsPath, "DeleteRateLimits", s.deleteRateLimits)
+}
+
+func (s *apiService) getRateLimits(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	namespace := chi.URLParam(r, "namespace")
+
+	tenant, err := s.tenantMgr.GetTenant(ctx, namespace)
+	if err != nil {
+		writeAdminError(w, errors.NotFound("namespace '%s' doesn't exist", namespace))
+		return
+	}
+
+	tx, err := s.txMgr.StartTx(ctx)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	override, err := s.rateLimits.GetLimits(ctx, tx, tenant.GetNamespace().Id())
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	writeAdminJSON(w, newNamespaceRateLimits(namespace, override))
+}
+
+// setRateLimits sets the request rate limits of a namespace, {"read": 100, "ddl": 1}, the limits that are not set are
+// the default limits. The other nodes apply them once they read the limits again.
+func (s *apiService) setRateLimits(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	namespace := chi.URLParam(r, "namespace")
+
+	override := &ratelimit.Limits{}
+	if err := jsoniter.NewDecoder(r.Body).Decode(override); err != nil {
+		writeAdminError(w, errors.InvalidArgument("invalid rate limits: %s", err.Error()))
+		return
+	}
+	if err := override.Validate(); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	tenant, err := s.tenantMgr.GetTenant(ctx, namespace)
+	if err != nil {
+		writeAdminError(w, errors.NotFound("namespace '%s' doesn't exist", namespace))
+		return
+	}
+
+	tx, err := s.txMgr.StartTx(ctx)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if err = s.rateLimits.SetLimits(ctx, tx, tenant.GetNamespace().Id(), override); err != nil {
+		_ = tx.Rollback(ctx)
+		writeAdminError(w, err)
+		return
+	}
+	if err = tx.Commit(ctx); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	ratelimit.Invalidate(namespace)
+
+	writeAdminJSON(w, newNamespaceRateLimits(namespace, override))
+}
+
+func (s *apiService) deleteRateLimits(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	namespace := chi.URLParam(r, "namespace")
+
+	tenant, err := s.tenantMgr.GetTenant(ctx, namespace)
+	if err != nil {
+		writeAdminError(w, errors.NotFound("namespace '%s' doesn't exist", namespace))
+		return
+	}
+
+	tx, err := s.txMgr.StartTx(ctx)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if err = s.rateLimits.DeleteLimits(ctx, tx, tenant.GetNamespace().Id()); err != nil {
+		_ = tx.Rollback(ctx)
+		writeAdminError(w, err)
+		return
+	}
+	if err = tx.Commit(ctx); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	ratelimit.Invalidate(namespace)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newNamespaceRateLimits(namespace string, override *ratelimit.Limits) *namespaceRateLimits {
+	return &namespaceRateLimits{
+		Namespace: namespace,
+		Limits:    override.Apply(config.DefaultConfig.Quota.Requests.Default),
+		Override:  override,
+	}
+}
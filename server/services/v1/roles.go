@@ -0,0 +1,146 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/authz"
+)
+
+// rolePath is the role assigned to a principal of a namespace, the principal is the subject of its tokens.
+const rolePath = adminPath + "/namespaces/{namespace}/roles/{sub}"
+
+type roleAssignment struct {
+	Namespace string     `json:"namespace"`
+	Sub       string     `json:"sub"`
+	Role      authz.Role `json:"role"`
+}
+
+func (s *apiService) registerRoleRoutes(router chi.Router) {
+	s.adminRoute(router, http.MethodGet, rolePath, "GetRole", s.getRole)
+	s.adminRoute(router, http.MethodPut, rolePath, "SetRole", s.setRole)
+	s.adminRoute(router, http.MethodDelete, rolePath, "DeleteRole", s.deleteRole)
+}
+
+func (s *apiService) getRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	namespace, sub := chi.URLParam(r, "namespace"), chi.URLParam(r, "sub")
+
+	tenant, err := s.tenantMgr.GetTenant(ctx, namespace)
+	if err != nil {
+		writeAdminError(w, errors.NotFound("namespace '%s' doesn't exist", namespace))
+		return
+	}
+
+	tx, err := s.txMgr.StartTx(ctx)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	role, err := s.roles.GetRole(ctx, tx, tenant.GetNamespace().Id(), sub)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if role == "" {
+		writeAdminError(w, errors.NotFound("no role is assigned to '%s' in the namespace '%s'", sub, namespace))
+		return
+	}
+
+	writeAdminJSON(w, &roleAssignment{Namespace: namespace, Sub: sub, Role: role})
+}
+
+// setRole assigns a role to a principal, the principals without a role have the role of their token or the default
+// role.
+func (s *apiService) setRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	namespace, sub := chi.URLParam(r, "namespace"), chi.URLParam(r, "sub")
+
+	role, err := decodeRole(r)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	tenant, err := s.tenantMgr.GetTenant(ctx, namespace)
+	if err != nil {
+		writeAdminError(w, errors.NotFound("namespace '%s' doesn't exist", namespace))
+		return
+	}
+
+	tx, err := s.txMgr.StartTx(ctx)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if err = s.roles.SetRole(ctx, tx, tenant.GetNamespace().Id(), sub, role); err != nil {
+		_ = tx.Rollback(ctx)
+		writeAdminError(w, err)
+		return
+	}
+	if err = tx.Commit(ctx); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	authz.Invalidate(namespace, sub)
+
+	writeAdminJSON(w, &roleAssignment{Namespace: namespace, Sub: sub, Role: role})
+}
+
+func (s *apiService) deleteRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	namespace, sub := chi.URLParam(r, "namespace"), chi.URLParam(r, "sub")
+
+	tenant, err := s.tenantMgr.GetTenant(ctx, namespace)
+	if err != nil {
+		writeAdminError(w, errors.NotFound("namespace '%s' doesn't exist", namespace))
+		return
+	}
+
+	tx, err := s.txMgr.StartTx(ctx)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if err = s.roles.DeleteRole(ctx, tx, tenant.GetNamespace().Id(), sub); err != nil {
+		_ = tx.Rollback(ctx)
+		writeAdminError(w, err)
+		return
+	}
+	if err = tx.Commit(ctx); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	authz.Invalidate(namespace, sub)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeRole reads the role of the body of an assignment, {"role": "editor"}.
+func decodeRole(r *http.Request) (authz.Role, error) {
+	req := &struct {
+		Role string `json:"role"`
+	}{}
+	if err := jsoniter.NewDecoder(r.Body).Decode(req); err != nil {
+		return "", errors.InvalidArgument("invalid role assignment: %s", err.Error())
+	}
+	return authz.ParseRole(req.Role)
+}
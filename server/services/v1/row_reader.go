@@ -54,6 +54,19 @@ func NewScanIterator(ctx context.Context, tx transaction.Tx, from keys.Key) (*Sc
 	}, nil
 }
 
+// NewParallelScanIterator is like NewScanIterator, but the shards of the range are read in parallel, see
+// kv.KeyValueStore.ReadRangeParallel. The rows are returned in the order of their keys.
+func NewParallelScanIterator(ctx context.Context, tx transaction.Tx, from keys.Key) (*ScanIterator, error) {
+	it, err := tx.ReadRangeParallel(ctx, from, nil, false, true)
+	if ulog.E(err) {
+		return nil, err
+	}
+
+	return &ScanIterator{
+		it: it,
+	}, nil
+}
+
 func (s *ScanIterator) Next(row *Row) bool {
 	if s.err != nil {
 		return false
@@ -129,6 +142,7 @@ func (k *KeyIterator) Interrupted() error { return k.err }
 type FilterIterator struct {
 	iterator Iterator
 	filter   *filter.WrappedFilter
+	scanned  int64
 }
 
 func NewFilterIterator(iterator Iterator, filter *filter.WrappedFilter) *FilterIterator {
@@ -150,6 +164,7 @@ func (it *FilterIterator) Next(row *Row) bool {
 		if !it.iterator.Next(row) {
 			return false
 		}
+		it.scanned++
 
 		if it.advanceToMatchingRow(row) {
 			return true
@@ -157,10 +172,24 @@ func (it *FilterIterator) Next(row *Row) bool {
 	}
 }
 
+// Scanned returns the number of rows read so far, including the rows that didn't match.
+func (it *FilterIterator) Scanned() int64 {
+	return it.scanned
+}
+
 func (it *FilterIterator) advanceToMatchingRow(row *Row) bool {
 	return it.filter.Matches(row.Data.RawData)
 }
 
+// rowsScanned returns the number of rows the iterator read from the storage, returned is the number of rows it
+// returned so far.
+func rowsScanned(iterator Iterator, returned int64) int64 {
+	if it, ok := iterator.(*FilterIterator); ok {
+		return it.Scanned()
+	}
+	return returned
+}
+
 type DatabaseReader struct {
 	tx  transaction.Tx
 	ctx context.Context
@@ -183,6 +212,12 @@ func (reader *DatabaseReader) ScanIterator(from keys.Key) (Iterator, error) {
 	return NewScanIterator(reader.ctx, reader.tx, from)
 }
 
+// ParallelScanIterator is like ScanIterator, but the rows are read in parallel, it is meant for the scans that read
+// the whole range.
+func (reader *DatabaseReader) ParallelScanIterator(from keys.Key) (Iterator, error) {
+	return NewParallelScanIterator(reader.ctx, reader.tx, from)
+}
+
 // StrictlyKeysFrom is an optimized version that takes input keys and filter out keys that are lower than the "from".
 func (reader *DatabaseReader) StrictlyKeysFrom(ikeys []keys.Key, from []byte) (Iterator, error) {
 	// this means we have returned data to the user, and now we need to stream again but only from the last offset
@@ -0,0 +1,98 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/schema"
+)
+
+// schemaHistoryPath streams all the versions of the schema of a collection as newline-delimited JSON, in the order of
+// the versions.
+const schemaHistoryPath = adminPath + "/namespaces/{namespace}/databases/{db}/collections/{collection}/schemas"
+
+// schemaVersion is a line of the schema history, the schema is the one stored for the version without the indexing
+// version, like the schema of a describe.
+type schemaVersion struct {
+	Version int                 `json:"version"`
+	Schema  jsoniter.RawMessage `json:"schema"`
+}
+
+func (s *apiService) getSchemaHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	namespace, dbName, collName := chi.URLParam(r, "namespace"), chi.URLParam(r, "db"), chi.URLParam(r, "collection")
+
+	tenant, err := s.tenantMgr.GetTenant(ctx, namespace)
+	if err != nil {
+		writeAdminError(w, errors.NotFound("namespace '%s' doesn't exist", namespace))
+		return
+	}
+	db, err := tenant.GetDatabase(ctx, dbName)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if db == nil {
+		writeAdminError(w, errors.NotFound("database doesn't exist '%s'", dbName))
+		return
+	}
+	coll := db.GetCollection(collName)
+	if coll == nil {
+		writeAdminError(w, errors.NotFound("collection doesn't exist '%s'", collName))
+		return
+	}
+
+	tx, err := s.txMgr.StartTx(ctx)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	schemas, versions, err := tenant.GetCollectionSchemas(ctx, tx, db, coll)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flush := func() {
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+	if err = writeSchemaHistory(w, flush, schemas, versions); err != nil && ctx.Err() == nil {
+		log.Err(err).Str("collection", collName).Msg("writing the schema history failed")
+	}
+}
+
+// writeSchemaHistory writes a line per version of the schema, the lines are flushed as they are written.
+func writeSchemaHistory(w io.Writer, flush func(), schemas [][]byte, versions []int) error {
+	enc := jsoniter.NewEncoder(w)
+	for i, sch := range schemas {
+		if err := enc.Encode(&schemaVersion{Version: versions[i], Schema: schema.RemoveIndexingVersion(sch)}); err != nil {
+			return err
+		}
+		flush()
+	}
+
+	return nil
+}
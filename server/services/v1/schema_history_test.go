@@ -0,0 +1,61 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/schema"
+)
+
+func TestWriteSchemaHistory(t *testing.T) {
+	v1 := []byte(`{"title":"t1","properties":{"id":{"type":"integer"}},"primary_key":["id"]}`)
+	v2 := []byte(`{"title":"t1","properties":{"id":{"type":"integer"},"name":{"type":"string"}},"primary_key":["id"]}`)
+
+	// the second version is a compatible update of the first one
+	f1, err := schema.Build("t1", v1, false)
+	require.NoError(t, err)
+	f2, err := schema.Build("t1", v2, false)
+	require.NoError(t, err)
+	coll := schema.NewDefaultCollection("t1", 1, 1, f1.CollectionType, f1, "t1", nil)
+	require.NoError(t, schema.ApplySchemaRules(coll, f2))
+
+	// the schemas are stored with the indexing version, it is not returned
+	require.NoError(t, schema.SetIndexingVersion(f1))
+	require.NoError(t, schema.SetIndexingVersion(f2))
+	require.NotEqual(t, string(v1), string(f1.Schema))
+
+	var buf bytes.Buffer
+	flushed := 0
+	require.NoError(t, writeSchemaHistory(&buf, func() { flushed++ }, [][]byte{f1.Schema, f2.Schema}, []int{1, 2}))
+	require.Equal(t, 2, flushed)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	for i, sch := range [][]byte{v1, v2} {
+		var version schemaVersion
+		require.NoError(t, jsoniter.Unmarshal([]byte(lines[i]), &version))
+		require.Equal(t, i+1, version.Version)
+		require.JSONEq(t, string(sch), string(version.Schema))
+	}
+
+	buf.Reset()
+	require.NoError(t, writeSchemaHistory(&buf, func() {}, nil, nil))
+	require.Empty(t, buf.String())
+}
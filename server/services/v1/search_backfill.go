@@ -0,0 +1,216 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	goerrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/errors"
+	tjson "github.com/tigrisdata/tigris/lib/json"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/store/search"
+)
+
+const (
+	// searchBackfillPath indexes the fields of the search collection of a collection derived from its documents, like
+	// the number of elements of the arrays, for the documents indexed before these fields were added.
+	searchBackfillPath = adminPath + "/namespaces/{namespace}/databases/{db}/collections/{collection}/search/backfill"
+
+	// searchBackfillBatchSize is the number of documents updated in the search backend with a single request.
+	searchBackfillBatchSize = 256
+)
+
+// searchBackfillResponse is the result of a backfill, the skipped documents are not indexed in the search backend.
+type searchBackfillResponse struct {
+	Documents int64 `json:"documents"`
+	Skipped   int64 `json:"skipped"`
+}
+
+// searchBackfillWriter updates the derived fields of the documents read in chunks in the search backend. Only the
+// derived fields are sent, so the fields of a document indexed after it is read are not overwritten.
+type searchBackfillWriter struct {
+	ctx         context.Context
+	searchStore search.Store
+	collection  *schema.DefaultCollection
+	table       []byte
+
+	batch    bytes.Buffer
+	batched  int
+	progress searchBackfillResponse
+	last     []byte
+}
+
+func newSearchBackfillWriter(ctx context.Context, searchStore search.Store, collection *schema.DefaultCollection, table []byte) *searchBackfillWriter {
+	return &searchBackfillWriter{
+		ctx:         ctx,
+		searchStore: searchStore,
+		collection:  collection,
+		table:       table,
+	}
+}
+
+func (b *searchBackfillWriter) write(iterator Iterator, deadline time.Time) error {
+	var row Row
+	for iterator.Next(&row) {
+		if b.last != nil && bytes.Compare(row.Key, b.last) <= 0 {
+			continue
+		}
+
+		doc, err := b.derivedFields(&row)
+		if err != nil {
+			return err
+		}
+		if doc != nil {
+			b.batch.Write(bytes.TrimRight(doc, "\n"))
+			b.batch.WriteByte('\n')
+			b.batched++
+		}
+		b.last = row.Key
+		b.progress.Documents++
+
+		if b.batched >= searchBackfillBatchSize {
+			if err = b.flush(); err != nil {
+				return err
+			}
+		}
+		if time.Now().After(deadline) {
+			if err = b.flush(); err != nil {
+				return err
+			}
+			return errExportChunkDone
+		}
+	}
+
+	if err := iterator.Interrupted(); err != nil {
+		return err
+	}
+	return b.flush()
+}
+
+// derivedFields returns the update of the search document of the row, it is nil if the document has no derived field.
+func (b *searchBackfillWriter) derivedFields(row *Row) ([]byte, error) {
+	decData, err := tjson.Decode(row.Data.RawData)
+	if err != nil {
+		return nil, err
+	}
+
+	flattened := FlattenObjects(decData)
+	setArrayLengths(flattened, b.collection)
+
+	update := make(map[string]interface{})
+	for _, f := range b.collection.QueryableFields {
+		key := schema.ToSearchArrayLengthKey(f.Name())
+		if v, ok := flattened[key]; ok {
+			update[key] = v
+		}
+	}
+	if len(update) == 0 {
+		return nil, nil
+	}
+
+	if update[schema.SearchId], err = CreateSearchKey(b.table, row.Key); err != nil {
+		return nil, err
+	}
+	return tjson.Encode(update)
+}
+
+// flush sends the batched updates, the documents not indexed in the search backend are skipped.
+func (b *searchBackfillWriter) flush() error {
+	if b.batched == 0 {
+		return nil
+	}
+	defer func() {
+		b.batch.Reset()
+		b.batched = 0
+	}()
+
+	err := b.searchStore.IndexDocuments(b.ctx, b.collection.SearchCollectionName(), bytes.NewReader(b.batch.Bytes()), search.IndexDocumentsOptions{
+		Action:    searchUpdate,
+		BatchSize: searchBackfillBatchSize,
+	})
+	var docErrs *search.IndexDocumentsError
+	if !goerrors.As(err, &docErrs) {
+		return err
+	}
+	for _, docErr := range docErrs.Errors {
+		if docErr == nil {
+			continue
+		}
+		var se search.Error
+		if !goerrors.As(docErr, &se) || se.HTTPCode() != http.StatusNotFound {
+			return docErr
+		}
+		b.progress.Skipped++
+	}
+	return nil
+}
+
+func (b *searchBackfillWriter) lastKey() []byte {
+	return b.last
+}
+
+func (b *searchBackfillWriter) advance(key []byte) {
+	if bytes.Compare(key, b.last) > 0 {
+		b.last = key
+	}
+}
+
+// backfillSearchFields indexes the derived fields of all the documents of a collection, in chunks like an export.
+// The documents written during the backfill are indexed with their derived fields already.
+func (s *apiService) backfillSearchFields(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	namespace, dbName, collName := chi.URLParam(r, "namespace"), chi.URLParam(r, "db"), chi.URLParam(r, "collection")
+
+	tenant, err := s.tenantMgr.GetTenant(ctx, namespace)
+	if err != nil {
+		writeAdminError(w, errors.NotFound("namespace '%s' doesn't exist", namespace))
+		return
+	}
+	db, err := tenant.GetDatabase(ctx, dbName)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if db == nil {
+		writeAdminError(w, errors.NotFound("database doesn't exist '%s'", dbName))
+		return
+	}
+	coll := db.GetCollection(collName)
+	if coll == nil {
+		writeAdminError(w, errors.NotFound("collection doesn't exist '%s'", collName))
+		return
+	}
+	table, err := metadata.NewEncoder().EncodeTableName(tenant.GetNamespace(), db, coll)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	backfill := newSearchBackfillWriter(ctx, s.searchStore, coll, table)
+	if err = s.scanChunks(ctx, table, nil, backfill); err != nil {
+		log.Err(err).Str("collection", collName).Int64("documents", backfill.progress.Documents).Msg("search backfill failed")
+		writeAdminError(w, err)
+		return
+	}
+
+	writeAdminJSON(w, &backfill.progress)
+}
@@ -0,0 +1,125 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/store/search"
+)
+
+// backfillSearchStore records the documents updated and rejects the ids missing from the index.
+type backfillSearchStore struct {
+	search.NoopStore
+
+	missing map[string]bool
+	updates []string
+	actions []string
+}
+
+func (s *backfillSearchStore) IndexDocuments(_ context.Context, _ string, documents io.Reader, options search.IndexDocumentsOptions) error {
+	data, err := io.ReadAll(documents)
+	if err != nil {
+		return err
+	}
+
+	var (
+		failed bool
+		errs   []error
+	)
+	for _, doc := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		s.updates = append(s.updates, doc)
+		s.actions = append(s.actions, options.Action)
+		if s.missing[jsoniter.Get([]byte(doc), schema.SearchId).ToString()] {
+			failed = true
+			errs = append(errs, search.NewSearchError(http.StatusNotFound, search.ErrCodeNotFound, "not found"))
+		} else {
+			errs = append(errs, nil)
+		}
+	}
+	if failed {
+		return &search.IndexDocumentsError{Errors: errs}
+	}
+	return nil
+}
+
+func TestSearchBackfillWriter(t *testing.T) {
+	factory, err := schema.Build("t1", []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": { "type": "integer" },
+		"tags": { "type": "array", "items": { "type": "string" } },
+		"obj": { "type": "object", "properties": { "values": { "type": "array", "items": { "type": "integer" } } } }
+	},
+	"primary_key": ["id"]
+}`), false)
+	require.NoError(t, err)
+	coll := schema.NewDefaultCollection("t1", 1, 1, factory.CollectionType, factory, "search_t1", nil)
+
+	table := append([]byte{}, internal.UserTableKeyPrefix...)
+	table = append(table, 1, 2, 3)
+	rows := func(docs ...string) *sliceIterator {
+		it := &sliceIterator{}
+		for i, doc := range docs {
+			it.rows = append(it.rows, Row{
+				Key:  keys.NewKey(table, "pkey", int64(i+1)).SerializeToBytes(),
+				Data: internal.NewTableData([]byte(doc)),
+			})
+		}
+		return it
+	}
+
+	t.Run("array lengths", func(t *testing.T) {
+		store := &backfillSearchStore{missing: map[string]bool{"3": true}}
+		b := newSearchBackfillWriter(context.Background(), store, coll, table)
+		require.NoError(t, b.write(rows(
+			`{"id":1,"tags":["a","b"],"obj":{"values":[1,2,3]}}`,
+			`{"id":2}`,
+			`{"id":3,"tags":["a"]}`,
+		), time.Now().Add(time.Hour)))
+
+		// the documents without an array are not updated and the ones missing from the index are skipped
+		require.Len(t, store.updates, 2)
+		require.JSONEq(t, `{"id":"1","_tigris_len_tags":2,"_tigris_len_obj.values":3}`, store.updates[0])
+		require.JSONEq(t, `{"id":"3","_tigris_len_tags":1}`, store.updates[1])
+		require.Equal(t, []string{searchUpdate, searchUpdate}, store.actions)
+		require.Equal(t, searchBackfillResponse{Documents: 3, Skipped: 1}, b.progress)
+	})
+
+	t.Run("chunks", func(t *testing.T) {
+		store := &backfillSearchStore{}
+		b := newSearchBackfillWriter(context.Background(), store, coll, table)
+
+		// the updates of a chunk are sent once its deadline is reached
+		require.Equal(t, errExportChunkDone, b.write(rows(`{"id":1,"tags":[]}`, `{"id":2,"tags":["a"]}`), time.Now()))
+		require.Len(t, store.updates, 1)
+
+		// the next chunk starts after the last document
+		require.NoError(t, b.write(rows(`{"id":1,"tags":[]}`, `{"id":2,"tags":["a"]}`), time.Now().Add(time.Hour)))
+		require.Len(t, store.updates, 2)
+		require.JSONEq(t, `{"id":"2","_tigris_len_tags":1}`, store.updates[1])
+		require.Equal(t, int64(2), b.progress.Documents)
+	})
+}
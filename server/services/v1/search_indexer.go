@@ -177,6 +177,7 @@ func PackSearchFields(data *internal.TableData, collection *schema.DefaultCollec
 	}
 
 	decData = FlattenObjects(decData)
+	setArrayLengths(decData, collection)
 
 	// pack any date time or array fields here
 	for _, f := range collection.QueryableFields {
@@ -218,8 +219,23 @@ func PackSearchFields(data *internal.TableData, collection *schema.DefaultCollec
 	return encoded, nil
 }
 
+// setArrayLengths indexes the number of elements of the array fields of the flattened document, to sort on it.
+func setArrayLengths(decData map[string]interface{}, collection *schema.DefaultCollection) {
+	for _, f := range collection.QueryableFields {
+		if f.IsReserved() || f.DataType != schema.ArrayType {
+			continue
+		}
+		if arr, ok := decData[f.Name()].([]interface{}); ok {
+			decData[schema.ToSearchArrayLengthKey(f.Name())] = len(arr)
+		}
+	}
+}
+
 func UnpackSearchFields(doc map[string]interface{}, collection *schema.DefaultCollection) (string, *internal.TableData, map[string]interface{}, error) {
 	for _, f := range collection.QueryableFields {
+		if f.DataType == schema.ArrayType {
+			delete(doc, schema.ToSearchArrayLengthKey(f.Name()))
+		}
 		if f.ShouldPack() {
 			if v, ok := doc[f.Name()]; ok {
 				switch f.DataType {
@@ -169,6 +169,7 @@ func TestPackSearchFields(t *testing.T) {
 		decData, err := encoder.Decode(res)
 		require.NoError(t, err)
 		require.Equal(t, "[1,2,3,4,5]", decData["arrayField"])
+		require.Equal(t, json.Number("5"), decData[schema.ToSearchArrayLengthKey("arrayField")])
 	})
 
 	t.Run("dateTime type of schema fields are unpacked", func(t *testing.T) {
@@ -297,8 +298,9 @@ func TestUnpackSearchFields(t *testing.T) {
 
 	t.Run("array type not packed as string", func(t *testing.T) {
 		doc := map[string]any{
-			"id":         "123",
-			"arrayField": []interface{}{1.1, 2.1, 3.0, 4.3, 5.5},
+			"id":                     "123",
+			"arrayField":             []interface{}{1.1, 2.1, 3.0, 4.3, 5.5},
+			"_tigris_len_arrayField": 5,
 		}
 		f := &schema.Field{DataType: schema.ArrayType, FieldName: "arrayField"}
 		coll := &schema.DefaultCollection{
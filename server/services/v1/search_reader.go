@@ -61,12 +61,12 @@ func (p *page) hasCapacity() bool {
 
 // readRow should be used to read search data because this is the single point where we unpack search fields, apply
 // filter and then pack the document into bytes.
-func (p *page) readRow() map[string]interface{} {
+func (p *page) readRow() *tsearch.Hit {
 	for p.idx < len(p.hits) {
-		document := p.hits[p.idx].Document
+		hit := p.hits[p.idx]
 		p.idx++
-		if document != nil {
-			return document
+		if hit != nil && hit.Document != nil {
+			return hit
 		}
 	}
 
@@ -97,6 +97,10 @@ func newPageReader(ctx context.Context, store search.Store, coll *schema.Default
 }
 
 func (p *pageReader) read() error {
+	if err := p.query.WrappedF.SearchError(); err != nil {
+		return err
+	}
+
 	result, err := p.store.Search(p.ctx, p.collection.SearchCollectionName(), p.query, p.pageNo)
 	if err != nil {
 		return err
@@ -193,6 +197,7 @@ type FilterableSearchIterator struct {
 	err        error
 	single     bool
 	last       bool
+	score      int64
 	page       *page
 	filter     *filter.WrappedFilter
 	pageReader *pageReader
@@ -220,24 +225,26 @@ func (it *FilterableSearchIterator) Next(row *Row) bool {
 			}
 		}
 
-		if doc := it.page.readRow(); doc != nil {
+		if hit := it.page.readRow(); hit != nil {
 			var searchKey string
-			if searchKey, row.Data, doc, it.err = UnpackSearchFields(doc, it.collection); it.err != nil {
+			var doc map[string]interface{}
+			if searchKey, row.Data, doc, it.err = UnpackSearchFields(hit.Document, it.collection); it.err != nil {
 				return false
 			}
 			row.Key = []byte(searchKey)
 
-			// now apply the filter
-			if !it.filter.MatchesDoc(doc) {
-				continue
-			}
-
 			var rawData []byte
 			// marshal the doc as bytes
 			if rawData, it.err = json.Encode(doc); it.err != nil {
 				return false
 			}
+
+			// now apply the filter, this also applies the part of the filter that is not supported by the indexing store
+			if !it.filter.MatchesSearchDoc(doc, rawData) {
+				continue
+			}
 			row.Data.RawData = rawData
+			it.score = hit.TextMatchScore
 			return true
 		}
 
@@ -257,6 +264,11 @@ func (it *FilterableSearchIterator) Interrupted() error {
 	return it.err
 }
 
+// getTextMatchScore returns the text match score of the row last returned by Next.
+func (it *FilterableSearchIterator) getTextMatchScore() int64 {
+	return it.score
+}
+
 func (it *FilterableSearchIterator) getTotalFound() int64 {
 	return it.pageReader.found
 }
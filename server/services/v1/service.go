@@ -20,6 +20,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/middleware"
 	"github.com/tigrisdata/tigris/server/transaction"
 	"github.com/tigrisdata/tigris/store/kv"
 	"github.com/tigrisdata/tigris/store/search"
@@ -37,10 +38,10 @@ type Service interface {
 	RegisterGRPC(grpc *grpc.Server) error
 }
 
-func GetRegisteredServices(kvStore kv.KeyValueStore, searchStore search.Store, tenantMgr *metadata.TenantManager, txMgr *transaction.Manager) []Service {
+func GetRegisteredServices(kvStore kv.KeyValueStore, searchStore search.Store, tenantMgr *metadata.TenantManager, txMgr *transaction.Manager, adminAuth *middleware.AdminAuth) []Service {
 	var v1Services []Service
-	v1Services = append(v1Services, newApiService(kvStore, searchStore, tenantMgr, txMgr))
-	v1Services = append(v1Services, newHealthService(txMgr))
+	v1Services = append(v1Services, newApiService(kvStore, searchStore, tenantMgr, txMgr, adminAuth))
+	v1Services = append(v1Services, newHealthService(txMgr, searchStore, tenantMgr))
 
 	userStore := metadata.NewUserStore(&metadata.DefaultMDNameRegistry{})
 	namespaceStore := metadata.NewNamespaceStore(&metadata.DefaultMDNameRegistry{})
@@ -26,6 +26,7 @@ import (
 	"github.com/tigrisdata/tigris/server/metadata"
 	"github.com/tigrisdata/tigris/server/metrics"
 	"github.com/tigrisdata/tigris/server/middleware"
+	"github.com/tigrisdata/tigris/server/quota"
 	"github.com/tigrisdata/tigris/server/request"
 	"github.com/tigrisdata/tigris/server/transaction"
 	"github.com/tigrisdata/tigris/store/kv"
@@ -183,7 +184,14 @@ func (sessMgr *SessionManager) Create(ctx context.Context, trackVerInOwnTxn bool
 		return nil, errors.NotFound("Tenant %s not found", namespaceForThisSession)
 	}
 
-	tx, err := sessMgr.txMgr.StartTx(ctx)
+	// a DDL of the namespace committed while the session runs aborts it, the cached metadata the session uses may be
+	// stale
+	txStartCtx := metadata.WithVersionConflict(ctx, tenant.GetNamespace().Id())
+	if track {
+		// the conflicts of the interactive transactions report the keys they conflicted on
+		txStartCtx = kv.WithConflictReport(txStartCtx)
+	}
+	tx, err := sessMgr.txMgr.StartTx(txStartCtx)
 	if err != nil {
 		return nil, err
 	}
@@ -205,6 +213,9 @@ func (sessMgr *SessionManager) Create(ctx context.Context, trackVerInOwnTxn bool
 	txCtx := tx.GetTxCtx()
 	sessCtx, cancel := context.WithCancel(ctx)
 	sessCtx = kv.WrapEventListenerCtx(sessCtx)
+	// the writes of the session are attributed to the namespace that started it
+	sessCtx = metrics.WithPendingUsage(sessCtx, tenant.GetNamespace().StrId(), tenant.GetNamespace().Metadata().Name)
+	sessCtx = quota.WithPendingStorage(sessCtx, tenant.GetNamespace().StrId())
 
 	q := &QuerySession{
 		tx:             tx,
@@ -333,6 +344,8 @@ func (s *QuerySession) Run(runner QueryRunner) (*Response, context.Context, erro
 
 func (s *QuerySession) Rollback() error {
 	defer s.cancel()
+	metrics.DiscardPendingUsage(s.ctx)
+	quota.DiscardPendingStorage(s.ctx)
 
 	for _, listener := range s.txListeners {
 		listener.OnRollback(s.ctx, s.tenant, kv.GetEventListener(s.ctx))
@@ -344,6 +357,8 @@ func (s *QuerySession) Commit(versionMgr *metadata.VersionHandler, incVersion bo
 	defer s.cancel()
 
 	if err != nil {
+		metrics.DiscardPendingUsage(s.ctx)
+		quota.DiscardPendingStorage(s.ctx)
 		_ = s.tx.Rollback(s.ctx)
 		return err
 	}
@@ -351,7 +366,7 @@ func (s *QuerySession) Commit(versionMgr *metadata.VersionHandler, incVersion bo
 	if incVersion {
 		// metadata change will bump up the metadata version, we are doing it in a different transaction
 		// because it is not allowed to read and write the version in the same transaction
-		if err = versionMgr.Increment(s.ctx, s.tx); ulog.E(err) {
+		if err = versionMgr.IncrementNamespace(s.ctx, s.tx, s.tenant.GetNamespace().Id()); ulog.E(err) {
 			_ = s.tx.Rollback(s.ctx)
 			return err
 		}
@@ -363,7 +378,12 @@ func (s *QuerySession) Commit(versionMgr *metadata.VersionHandler, incVersion bo
 		}
 	}
 
-	if err = s.tx.Commit(s.ctx); err == nil {
+	if err = s.tx.Commit(s.ctx); err != nil {
+		metrics.DiscardPendingUsage(s.ctx)
+		quota.DiscardPendingStorage(s.ctx)
+	} else {
+		metrics.CommitPendingUsage(s.ctx)
+		quota.CommitPendingStorage(s.ctx)
 		for _, listener := range s.txListeners {
 			if err = listener.OnPostCommit(s.ctx, s.tenant, kv.GetEventListener(s.ctx)); err != nil {
 				log.Err(err).Msg("post commit failure")
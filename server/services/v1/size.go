@@ -0,0 +1,112 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/go-chi/chi/v5"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+const (
+	// rangeSizesPath splits the collection in sub-ranges and returns their estimated sizes, so that the operators can
+	// spot the hot shards of a collection.
+	rangeSizesPath = adminPath + "/namespaces/{namespace}/databases/{db}/collections/{collection}/size/ranges"
+
+	rangeSizesDefaultBuckets = 10
+	rangeSizesMaxBuckets     = 100
+)
+
+type rangeSizesResponse struct {
+	Collection string       `json:"collection"`
+	Size       int64        `json:"size"`
+	Ranges     []*rangeSize `json:"ranges"`
+}
+
+// rangeSize is a sub-range of the collection, the keys are the printable form of the storage keys.
+type rangeSize struct {
+	Begin string `json:"begin"`
+	End   string `json:"end"`
+	Size  int64  `json:"size"`
+}
+
+func (s *apiService) getRangeSizes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	namespace, dbName, collName := chi.URLParam(r, "namespace"), chi.URLParam(r, "db"), chi.URLParam(r, "collection")
+
+	buckets, err := parseBuckets(r.URL.Query().Get("buckets"))
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	tenant, err := s.tenantMgr.GetTenant(ctx, namespace)
+	if err != nil {
+		writeAdminError(w, errors.NotFound("namespace '%s' doesn't exist", namespace))
+		return
+	}
+	db, err := tenant.GetDatabase(ctx, dbName)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if db == nil {
+		writeAdminError(w, errors.NotFound("database doesn't exist '%s'", dbName))
+		return
+	}
+	coll := db.GetCollection(collName)
+	if coll == nil {
+		writeAdminError(w, errors.NotFound("collection doesn't exist '%s'", collName))
+		return
+	}
+
+	sizes, err := tenant.CollectionRangeSizes(ctx, db, coll, buckets)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	writeAdminJSON(w, newRangeSizesResponse(collName, sizes))
+}
+
+func parseBuckets(value string) (int, error) {
+	if len(value) == 0 {
+		return rangeSizesDefaultBuckets, nil
+	}
+
+	buckets, err := strconv.Atoi(value)
+	if err != nil || buckets < 1 || buckets > rangeSizesMaxBuckets {
+		return 0, errors.InvalidArgument("buckets must be between 1 and %d", rangeSizesMaxBuckets)
+	}
+	return buckets, nil
+}
+
+func newRangeSizesResponse(collection string, sizes []kv.RangeSize) *rangeSizesResponse {
+	resp := &rangeSizesResponse{Collection: collection, Ranges: make([]*rangeSize, 0, len(sizes))}
+	for _, sz := range sizes {
+		resp.Size += sz.Size
+		resp.Ranges = append(resp.Ranges, &rangeSize{
+			Begin: fdb.Printable(sz.Begin),
+			End:   fdb.Printable(sz.End),
+			Size:  sz.Size,
+		})
+	}
+
+	return resp
+}
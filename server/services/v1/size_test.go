@@ -0,0 +1,92 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/store/kv"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRangeSizes(t *testing.T) {
+	resp := newRangeSizesResponse("orders", []kv.RangeSize{
+		{Begin: []byte("data\x00"), End: []byte("data\x01a"), Size: 100},
+		{Begin: []byte("data\x01a"), End: []byte("data\xff"), Size: 5000},
+	})
+	data, err := jsoniter.Marshal(resp)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"collection": "orders",
+		"size": 5100,
+		"ranges": [
+			{"begin": "data\\x00", "end": "data\\x01a", "size": 100},
+			{"begin": "data\\x01a", "end": "data\\xff", "size": 5000}
+		]
+	}`, string(data))
+
+	buckets, err := parseBuckets("")
+	require.NoError(t, err)
+	require.Equal(t, rangeSizesDefaultBuckets, buckets)
+	buckets, err = parseBuckets("3")
+	require.NoError(t, err)
+	require.Equal(t, 3, buckets)
+	for _, v := range []string{"0", "101", "ten"} {
+		_, err = parseBuckets(v)
+		require.Equal(t, errors.InvalidArgument("buckets must be between 1 and 100"), err, v)
+	}
+}
+
+func TestDescribeSize(t *testing.T) {
+	estimated := func() (int64, error) { return 10, nil }
+	exact := func() (int64, error) { return 12, nil }
+
+	size, err := describeSize("orders", &sizeOptions{}, estimated, exact)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), size)
+
+	size, err = describeSize("orders", &sizeOptions{Exact: true, RowLimit: 5}, estimated, exact)
+	require.NoError(t, err)
+	require.Equal(t, int64(12), size)
+
+	_, err = describeSize("orders", &sizeOptions{Exact: true, RowLimit: 5}, estimated, func() (int64, error) {
+		return 0, kv.ErrRowLimitExceeded
+	})
+	require.Equal(t, errors.InvalidArgument("'orders' has more than 5 documents, its exact size is not computed"), err)
+}
+
+func TestSizeOptionsFromHeaders(t *testing.T) {
+	headers := func(pairs ...string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(pairs...))
+	}
+
+	opts, err := sizeOptionsFromHeaders(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, &sizeOptions{RowLimit: describeExactSizeDefaultRowLimit}, opts)
+
+	opts, err = sizeOptionsFromHeaders(headers(api.HeaderSizeExact, "true", api.HeaderSizeRowLimit, "50"))
+	require.NoError(t, err)
+	require.Equal(t, &sizeOptions{Exact: true, RowLimit: 50}, opts)
+
+	_, err = sizeOptionsFromHeaders(headers(api.HeaderSizeExact, "true", api.HeaderSizeRowLimit, "many"))
+	require.Equal(t, errors.InvalidArgument("invalid row limit 'many'"), err)
+	_, err = sizeOptionsFromHeaders(headers(api.HeaderSizeRowLimit, "2000000"))
+	require.Equal(t, errors.InvalidArgument("row limit must be between 1 and 1000000"), err)
+}
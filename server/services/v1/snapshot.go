@@ -0,0 +1,723 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/request"
+	"github.com/tigrisdata/tigris/server/snapshot"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+	ulog "github.com/tigrisdata/tigris/util/log"
+)
+
+const (
+	// snapshotsPath creates a snapshot of the database in the background.
+	snapshotsPath       = adminPath + "/namespaces/{namespace}/databases/{db}/snapshots"
+	allSnapshotsPath    = adminPath + "/snapshots"
+	snapshotPath        = allSnapshotsPath + "/{id}"
+	snapshotRestorePath = snapshotPath + "/restore"
+)
+
+const (
+	// SnapshotComplete is the state of a snapshot whose manifest is written.
+	SnapshotComplete = "complete"
+	// SnapshotPartial is the state of a snapshot without a manifest, the snapshot failed or is in progress.
+	SnapshotPartial = "partial"
+	// SnapshotCorrupted is the state of a snapshot that doesn't match its checksums.
+	SnapshotCorrupted = "corrupted"
+	// SnapshotRunning is the state of a snapshot being created in the background.
+	SnapshotRunning = "running"
+	// SnapshotFailed is the state of a snapshot that failed since the server started, its data is removed.
+	SnapshotFailed = "failed"
+)
+
+// snapshotJobs are the snapshots created in the background by this server. The failed snapshots are kept until the
+// server restarts, so that their error can be described.
+type snapshotJobs struct {
+	sync.Mutex
+
+	running map[string]*snapshot.Manifest
+	failed  map[string]error
+}
+
+func newSnapshotJobs() *snapshotJobs {
+	return &snapshotJobs{
+		running: make(map[string]*snapshot.Manifest),
+		failed:  make(map[string]error),
+	}
+}
+
+func (j *snapshotJobs) start(m *snapshot.Manifest) {
+	j.Lock()
+	defer j.Unlock()
+
+	j.running[m.Id] = m
+}
+
+func (j *snapshotJobs) finish(id string, err error) {
+	j.Lock()
+	defer j.Unlock()
+
+	delete(j.running, id)
+	if err != nil {
+		j.failed[id] = err
+	}
+}
+
+// forget removes the failed snapshot.
+func (j *snapshotJobs) forget(id string) {
+	j.Lock()
+	defer j.Unlock()
+
+	delete(j.failed, id)
+}
+
+// get returns the response of the snapshot if it is running or failed.
+func (j *snapshotJobs) get(id string) (*snapshotResponse, bool) {
+	j.Lock()
+	defer j.Unlock()
+
+	if m, ok := j.running[id]; ok {
+		return &snapshotResponse{Id: id, State: SnapshotRunning, Namespace: m.Namespace, Db: m.Db, CreatedAt: &m.CreatedAt}, true
+	}
+	if err, ok := j.failed[id]; ok {
+		return &snapshotResponse{Id: id, State: SnapshotFailed, Error: err.Error()}, true
+	}
+	return nil, false
+}
+
+func (j *snapshotJobs) failedIds() []string {
+	j.Lock()
+	defer j.Unlock()
+
+	ids := make([]string, 0, len(j.failed))
+	for id := range j.failed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+type snapshotCollectionResponse struct {
+	Name      string `json:"name"`
+	Versions  []int  `json:"versions"`
+	Documents int64  `json:"documents"`
+	Size      int64  `json:"size"`
+}
+
+type snapshotResponse struct {
+	Id          string                        `json:"id"`
+	State       string                        `json:"state"`
+	Error       string                        `json:"error,omitempty"`
+	Namespace   string                        `json:"namespace,omitempty"`
+	Db          string                        `json:"db,omitempty"`
+	CreatedAt   *time.Time                    `json:"created_at,omitempty"`
+	Size        int64                         `json:"size"`
+	Documents   int64                         `json:"documents"`
+	Collections []*snapshotCollectionResponse `json:"collections,omitempty"`
+}
+
+func newSnapshotResponse(id string, m *snapshot.Manifest, err error, withCollections bool) *snapshotResponse {
+	switch {
+	case err == snapshot.ErrIncomplete:
+		return &snapshotResponse{Id: id, State: SnapshotPartial}
+	case err != nil:
+		return &snapshotResponse{Id: id, State: SnapshotCorrupted, Error: err.Error()}
+	}
+
+	resp := &snapshotResponse{
+		Id:        m.Id,
+		State:     SnapshotComplete,
+		Namespace: m.Namespace,
+		Db:        m.Db,
+		CreatedAt: &m.CreatedAt,
+		Size:      m.Size(),
+		Documents: m.Documents(),
+	}
+	if withCollections {
+		for _, c := range m.Collections {
+			coll := &snapshotCollectionResponse{Name: c.Name, Documents: c.Documents, Size: c.Size}
+			for _, s := range c.Schemas {
+				coll.Versions = append(coll.Versions, s.Version)
+			}
+			resp.Collections = append(resp.Collections, coll)
+		}
+	}
+	return resp
+}
+
+type restoreSnapshotRequest struct {
+	// Namespace defaults to the namespace of the snapshot.
+	Namespace string `json:"namespace"`
+	Db        string `json:"db"`
+}
+
+type restoreCollectionResponse struct {
+	Name      string `json:"name"`
+	Version   int32  `json:"version"`
+	Documents int64  `json:"documents"`
+}
+
+type restoreSnapshotResponse struct {
+	Namespace   string                       `json:"namespace"`
+	Db          string                       `json:"db"`
+	Collections []*restoreCollectionResponse `json:"collections"`
+}
+
+func (s *apiService) registerSnapshotRoutes(router chi.Router) {
+	s.adminRoute(router, http.MethodPost, snapshotsPath, "CreateSnapshot", s.createSnapshotHandler)
+	s.adminRoute(router, http.MethodGet, allSnapshotsPath, "ListSnapshots", s.listSnapshots)
+	s.adminRoute(router, http.MethodGet, snapshotPath, "DescribeSnapshot", s.describeSnapshot)
+	s.adminRoute(router, http.MethodDelete, snapshotPath, "DeleteSnapshot", s.deleteSnapshot)
+	s.adminRoute(router, http.MethodPost, snapshotRestorePath, "RestoreSnapshot", s.restoreSnapshotHandler)
+}
+
+// createSnapshotHandler starts the snapshot of the database in the background, the snapshot is running until it is
+// described as complete or failed.
+func (s *apiService) createSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	m, err := s.startSnapshot(r.Context(), chi.URLParam(r, "namespace"), chi.URLParam(r, "db"))
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	resp, _ := s.snapshotJobs.get(m.Id)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	ulog.E(jsoniter.NewEncoder(w).Encode(resp))
+}
+
+// listSnapshots returns all the snapshots of the store, including the running, the partial and the corrupted ones,
+// along with the snapshots that failed since the server started.
+func (s *apiService) listSnapshots(w http.ResponseWriter, _ *http.Request) {
+	ids, err := s.snapshots.List()
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	resp := make([]*snapshotResponse, 0, len(ids))
+	for _, id := range ids {
+		if job, ok := s.snapshotJobs.get(id); ok {
+			resp = append(resp, job)
+			continue
+		}
+		m, err := snapshot.ReadManifest(s.snapshots, id)
+		resp = append(resp, newSnapshotResponse(id, m, err, false))
+	}
+	for _, id := range s.snapshotJobs.failedIds() {
+		job, _ := s.snapshotJobs.get(id)
+		resp = append(resp, job)
+	}
+
+	writeAdminJSON(w, resp)
+}
+
+// describeSnapshot returns the snapshot along with its collections. With the "verify" query parameter the data files
+// are checked against their checksums, which reads the whole snapshot.
+func (s *apiService) describeSnapshot(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if job, ok := s.snapshotJobs.get(id); ok {
+		writeAdminJSON(w, job)
+		return
+	}
+	if err := s.snapshotExists(id); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	m, err := snapshot.ReadManifest(s.snapshots, id)
+	if err == nil && r.URL.Query().Get("verify") == "true" {
+		err = snapshot.Verify(s.snapshots, m)
+	}
+	if err != nil && err != snapshot.ErrIncomplete && !goerrors.Is(err, snapshot.ErrCorrupted) {
+		writeAdminError(w, err)
+		return
+	}
+
+	writeAdminJSON(w, newSnapshotResponse(id, m, err, true))
+}
+
+func (s *apiService) deleteSnapshot(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if job, ok := s.snapshotJobs.get(id); ok {
+		if job.State == SnapshotRunning {
+			writeAdminError(w, errors.FailedPrecondition("snapshot '%s' is running", id))
+			return
+		}
+		// the data of a failed snapshot is already removed
+		s.snapshotJobs.forget(id)
+		writeAdminJSON(w, map[string]string{"status": DeletedStatus})
+		return
+	}
+	if err := s.snapshotExists(id); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if err := s.snapshots.Delete(id); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	writeAdminJSON(w, map[string]string{"status": DeletedStatus})
+}
+
+func (s *apiService) restoreSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := s.snapshotExists(id); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	req := &restoreSnapshotRequest{}
+	if err := jsoniter.NewDecoder(r.Body).Decode(req); err != nil {
+		writeAdminError(w, errors.InvalidArgument("invalid restore request: %s", err.Error()))
+		return
+	}
+	if len(req.Db) == 0 {
+		writeAdminError(w, errors.InvalidArgument("database of the restore is missing"))
+		return
+	}
+
+	m, err := snapshot.ReadManifest(s.snapshots, id)
+	if err != nil {
+		writeAdminError(w, snapshotError(id, err))
+		return
+	}
+	if len(req.Namespace) == 0 {
+		req.Namespace = m.Namespace
+	}
+
+	resp, err := s.restoreSnapshot(r.Context(), m, req.Namespace, req.Db)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	writeAdminJSON(w, resp)
+}
+
+func (s *apiService) snapshotExists(id string) error {
+	if snapshot.ValidateId(id) != nil {
+		return errors.NotFound("snapshot '%s' doesn't exist", id)
+	}
+
+	ids, err := s.snapshots.List()
+	if err != nil {
+		return err
+	}
+	for _, existing := range ids {
+		if existing == id {
+			return nil
+		}
+	}
+	return errors.NotFound("snapshot '%s' doesn't exist", id)
+}
+
+func snapshotError(id string, err error) error {
+	switch {
+	case err == snapshot.ErrIncomplete:
+		return errors.FailedPrecondition("snapshot '%s' is incomplete", id)
+	case goerrors.Is(err, snapshot.ErrCorrupted):
+		return errors.FailedPrecondition("snapshot '%s' is corrupted: %s", id, err.Error())
+	default:
+		return err
+	}
+}
+
+// startSnapshot starts writing a snapshot of the database in the store in the background, the snapshot doesn't depend
+// on the request that started it.
+func (s *apiService) startSnapshot(ctx context.Context, namespace string, dbName string) (*snapshot.Manifest, error) {
+	tenant, err := s.tenantMgr.GetTenant(ctx, namespace)
+	if err != nil {
+		return nil, errors.NotFound("namespace '%s' doesn't exist", namespace)
+	}
+	db, err := tenant.GetDatabase(ctx, dbName)
+	if err != nil {
+		return nil, err
+	}
+	if db == nil {
+		return nil, errors.NotFound("database doesn't exist '%s'", dbName)
+	}
+
+	m := &snapshot.Manifest{
+		Id:        uuid.New().String(),
+		Namespace: namespace,
+		Db:        dbName,
+		CreatedAt: time.Now().UTC(),
+	}
+	s.snapshotJobs.start(m)
+	go func() {
+		s.snapshotJobs.finish(m.Id, s.createSnapshot(context.Background(), tenant, db, m))
+	}()
+
+	return m, nil
+}
+
+// createSnapshot writes a snapshot of the database in the store. All the reads of the snapshot, the schemas of the
+// collections, the version of the metadata and the documents, are at the same read version: the snapshot is a point
+// in time image of the database. The documents are read in chunks, each chunk with its own transaction, and the
+// snapshot fails if it is not done within the MVCC window of the database, 5 seconds by default. A failed snapshot is
+// removed from the store, a snapshot that is interrupted before it is complete has no manifest and is listed as
+// partial.
+func (s *apiService) createSnapshot(ctx context.Context, tenant *metadata.Tenant, db *metadata.Database, m *snapshot.Manifest) error {
+	ctx = kv.WithReadVersion(ctx, &kv.ReadVersion{})
+
+	collections, err := s.snapshotSchemas(ctx, tenant, db, m)
+	if err == nil {
+		err = s.snapshotCollections(ctx, tenant, db, collections, m)
+	}
+	if err != nil {
+		log.Err(err).Str("db", m.Db).Str("snapshot", m.Id).Msg("snapshot failed")
+		ulog.E(s.snapshots.Delete(m.Id))
+		return err
+	}
+
+	return nil
+}
+
+func (s *apiService) snapshotSchemas(ctx context.Context, tenant *metadata.Tenant, db *metadata.Database, m *snapshot.Manifest) ([]*schema.DefaultCollection, error) {
+	tx, err := s.txMgr.StartTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if m.MetadataVersion, err = s.versionH.Read(ctx, tx, false); err != nil {
+		return nil, err
+	}
+
+	collections := db.ListCollection()
+	sort.Slice(collections, func(i, j int) bool { return collections[i].Name < collections[j].Name })
+	for i, coll := range collections {
+		schemas, revisions, err := tenant.GetCollectionSchemas(ctx, tx, db, coll)
+		if err != nil {
+			return nil, err
+		}
+
+		c := &snapshot.CollectionManifest{Name: coll.Name, File: fmt.Sprintf("%d.data", i)}
+		for j := range schemas {
+			c.Schemas = append(c.Schemas, &snapshot.SchemaRevision{Version: revisions[j], Schema: schemas[j]})
+		}
+		m.Collections = append(m.Collections, c)
+	}
+
+	return collections, nil
+}
+
+func (s *apiService) snapshotCollections(ctx context.Context, tenant *metadata.Tenant, db *metadata.Database,
+	collections []*schema.DefaultCollection, m *snapshot.Manifest,
+) error {
+	for i, coll := range collections {
+		table, err := metadata.NewEncoder().EncodeTableName(tenant.GetNamespace(), db, coll)
+		if err != nil {
+			return err
+		}
+
+		w, err := s.snapshots.Create(m.Id, m.Collections[i].File)
+		if err != nil {
+			return err
+		}
+		sw := &snapshotWriter{data: snapshot.NewDataWriter(w), table: table}
+		if err = s.scanChunks(ctx, table, nil, sw); err != nil {
+			_ = w.Close()
+			return err
+		}
+		if err = sw.data.Close(m.Collections[i]); err != nil {
+			return err
+		}
+	}
+
+	return snapshot.WriteManifest(s.snapshots, m)
+}
+
+// snapshotWriter writes the rows of a collection in its data file. The key of a document is written without the
+// table and the index, so that the document can be restored in another database.
+type snapshotWriter struct {
+	data  *snapshot.DataWriter
+	table []byte
+	last  []byte
+}
+
+func (sw *snapshotWriter) write(iterator Iterator, deadline time.Time) error {
+	var row Row
+	for iterator.Next(&row) {
+		if sw.last != nil && bytes.Compare(row.Key, sw.last) <= 0 {
+			continue
+		}
+
+		key, err := encodeSnapshotKey(sw.table, row.Key)
+		if err != nil {
+			return err
+		}
+		value, err := internal.Encode(row.Data)
+		if err != nil {
+			return err
+		}
+		if err = sw.data.Write(key, value); err != nil {
+			return err
+		}
+		sw.last = row.Key
+
+		if time.Now().After(deadline) {
+			return errExportChunkDone
+		}
+	}
+
+	return iterator.Interrupted()
+}
+
+func (sw *snapshotWriter) lastKey() []byte {
+	return sw.last
+}
+
+func (sw *snapshotWriter) advance(key []byte) {
+	if bytes.Compare(key, sw.last) > 0 {
+		sw.last = key
+	}
+}
+
+// encodeSnapshotKey returns the primary key of the document as a packed tuple, the first part of the key is the
+// encoded primary key index.
+func encodeSnapshotKey(table []byte, fdbKey []byte) ([]byte, error) {
+	key, err := keys.FromBinary(table, fdbKey)
+	if err != nil {
+		return nil, err
+	}
+	parts := key.IndexParts()
+	if len(parts) < 2 {
+		return nil, errors.Internal("unexpected key of the document '%v'", parts)
+	}
+
+	t := make(tuple.Tuple, 0, len(parts)-1)
+	for _, p := range parts[1:] {
+		t = append(t, p)
+	}
+	return t.Pack(), nil
+}
+
+func decodeSnapshotKey(data []byte) ([]interface{}, error) {
+	t, err := tuple.Unpack(data)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]interface{}, 0, len(t))
+	for _, p := range t {
+		parts = append(parts, p)
+	}
+	return parts, nil
+}
+
+// restoreSnapshot restores the snapshot into a new database. The data files are verified before the database is
+// created. The collections are recreated by replaying all the revisions of their schema, so that they end up with
+// their original schema version, and the documents are written with their original version and timestamps. The
+// documents are written through the sessions, so that they are indexed in the search. The database is dropped if the
+// restore fails.
+func (s *apiService) restoreSnapshot(ctx context.Context, m *snapshot.Manifest, namespace string, dbName string) (*restoreSnapshotResponse, error) {
+	tenant, err := s.tenantMgr.GetTenant(ctx, namespace)
+	if err != nil {
+		return nil, errors.NotFound("namespace '%s' doesn't exist", namespace)
+	}
+	if db, err := tenant.GetDatabase(ctx, dbName); err != nil || db != nil {
+		if err == nil {
+			err = errors.AlreadyExists("database already exist")
+		}
+		return nil, err
+	}
+
+	if err = snapshot.Verify(s.snapshots, m); err != nil {
+		return nil, snapshotError(m.Id, err)
+	}
+
+	md := &request.Metadata{}
+	md.SetNamespace(ctx, namespace)
+	ctx = md.SaveToContext(ctx)
+
+	runner := s.runnerFactory.GetDatabaseQueryRunner()
+	runner.SetCreateDatabaseReq(&api.CreateDatabaseRequest{Db: dbName})
+	if _, err = s.sessions.Execute(ctx, runner, &ReqOptions{metadataChange: true, instantVerTracking: true}); err != nil {
+		return nil, err
+	}
+
+	resp := &restoreSnapshotResponse{Namespace: namespace, Db: dbName}
+	for _, c := range m.Collections {
+		coll, err := s.restoreCollection(ctx, m, c, namespace, dbName)
+		if err != nil {
+			log.Err(err).Str("db", dbName).Str("snapshot", m.Id).Msg("restore failed")
+			s.dropRestoredDatabase(ctx, dbName)
+			return nil, err
+		}
+		resp.Collections = append(resp.Collections, coll)
+	}
+
+	return resp, nil
+}
+
+func (s *apiService) restoreCollection(ctx context.Context, m *snapshot.Manifest, c *snapshot.CollectionManifest,
+	namespace string, dbName string,
+) (*restoreCollectionResponse, error) {
+	for _, revision := range c.Schemas {
+		runner := s.runnerFactory.GetCollectionQueryRunner()
+		runner.SetCreateOrUpdateCollectionReq(&api.CreateOrUpdateCollectionRequest{
+			Db:         dbName,
+			Collection: c.Name,
+			Schema:     revision.Schema,
+		})
+		if _, err := s.sessions.Execute(ctx, runner, &ReqOptions{metadataChange: true, instantVerTracking: true}); err != nil {
+			return nil, err
+		}
+	}
+
+	tenant, err := s.tenantMgr.GetTenant(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	coll := tenant.GetCollection(dbName, c.Name)
+	if coll == nil {
+		return nil, errors.NotFound("collection doesn't exist '%s'", c.Name)
+	}
+	if len(c.Schemas) > 0 && int(coll.GetVersion()) != c.Schemas[len(c.Schemas)-1].Version {
+		return nil, errors.Internal("collection '%s' is restored with the schema version %d instead of %d", c.Name,
+			coll.GetVersion(), c.Schemas[len(c.Schemas)-1].Version)
+	}
+
+	reader, err := snapshot.OpenData(s.snapshots, m.Id, c)
+	if err != nil {
+		return nil, snapshotError(m.Id, err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	runner := s.runnerFactory.GetRestoreQueryRunner(dbName, c.Name)
+	resp := &restoreCollectionResponse{Name: c.Name, Version: coll.GetVersion()}
+	for done := false; !done; {
+		var size int
+		runner.docs = runner.docs[:0]
+		for len(runner.docs) < importBatchSize && size < importBatchBytes {
+			key, value, err := reader.Next()
+			if err == io.EOF {
+				done = true
+				break
+			}
+			if err != nil {
+				return nil, snapshotError(m.Id, err)
+			}
+
+			doc, err := newRestoreDoc(key, value)
+			if err != nil {
+				return nil, snapshotError(m.Id, err)
+			}
+			runner.docs = append(runner.docs, doc)
+			size += len(value)
+		}
+		if len(runner.docs) == 0 {
+			break
+		}
+
+		if _, err = s.sessions.Execute(ctx, runner, &ReqOptions{}); err != nil {
+			return nil, err
+		}
+		resp.Documents += int64(len(runner.docs))
+	}
+
+	return resp, nil
+}
+
+func (s *apiService) dropRestoredDatabase(ctx context.Context, dbName string) {
+	runner := s.runnerFactory.GetDatabaseQueryRunner()
+	runner.SetDropDatabaseReq(&api.DropDatabaseRequest{Db: dbName})
+	_, err := s.sessions.Execute(ctx, runner, &ReqOptions{metadataChange: true, instantVerTracking: true})
+	ulog.E(err)
+}
+
+type restoreDoc struct {
+	key  []interface{}
+	data *internal.TableData
+}
+
+func newRestoreDoc(key []byte, value []byte) (*restoreDoc, error) {
+	parts, err := decodeSnapshotKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid key", snapshot.ErrCorrupted)
+	}
+	data, err := internal.Decode(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid document", snapshot.ErrCorrupted)
+	}
+	return &restoreDoc{key: parts, data: data}, nil
+}
+
+// RestoreQueryRunner writes a batch of the documents of a snapshot, the documents keep their schema version and their
+// timestamps.
+type RestoreQueryRunner struct {
+	*BaseQueryRunner
+
+	db         string
+	collection string
+	docs       []*restoreDoc
+}
+
+func (runner *RestoreQueryRunner) Run(ctx context.Context, tx transaction.Tx, tenant *metadata.Tenant) (*Response, context.Context, error) {
+	db, err := runner.getDatabase(ctx, tx, tenant, runner.db)
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	ctx = runner.cdcMgr.WrapContext(ctx, db.Name())
+
+	coll, err := runner.getCollection(db, runner.collection)
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	table, err := runner.encoder.EncodeTableName(tenant.GetNamespace(), db, coll)
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	writeCtx := withCompression(ctx, db, coll)
+	for _, doc := range runner.docs {
+		key, err := runner.encoder.EncodeKey(table, coll.Indexes.PrimaryKey, doc.key)
+		if err != nil {
+			return nil, ctx, err
+		}
+		if err = tx.Insert(writeCtx, key, doc.data); err != nil {
+			return nil, ctx, err
+		}
+	}
+
+	return &Response{status: InsertedStatus}, ctx, nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/server/snapshot"
+)
+
+func TestSnapshotWriter(t *testing.T) {
+	table := []byte("table")
+	rows := &sliceIterator{}
+	for i, id := range []interface{}{[]byte("a"), "b", int64(3)} {
+		key := keys.NewKey(table, "pk", id).SerializeToBytes()
+		data := internal.NewTableData([]byte(fmt.Sprintf(`{"id":%d}`, i)))
+		data.SetVersion(2)
+		rows.rows = append(rows.rows, Row{Key: key, Data: data})
+	}
+
+	store := snapshot.NewFileStore(t.TempDir())
+	w, err := store.Create("snap1", "0.data")
+	require.NoError(t, err)
+	coll := &snapshot.CollectionManifest{Name: "coll1", File: "0.data"}
+
+	sw := &snapshotWriter{data: snapshot.NewDataWriter(w), table: table}
+	// the first chunk stops right after the first document
+	require.Equal(t, errExportChunkDone, sw.write(&sliceIterator{rows: rows.rows[:2]}, time.Now()))
+	require.Equal(t, rows.rows[0].Key, sw.lastKey())
+	// the next chunk starts again with the last document written
+	require.NoError(t, sw.write(&sliceIterator{rows: rows.rows}, time.Now().Add(time.Hour)))
+	require.Equal(t, rows.rows[2].Key, sw.lastKey())
+	require.NoError(t, sw.data.Close(coll))
+	require.Equal(t, int64(3), coll.Documents)
+
+	r, err := snapshot.OpenData(store, "snap1", coll)
+	require.NoError(t, err)
+	defer func() { _ = r.Close() }()
+
+	var restored []*restoreDoc
+	for {
+		key, value, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		doc, err := newRestoreDoc(key, value)
+		require.NoError(t, err)
+		restored = append(restored, doc)
+	}
+
+	require.Len(t, restored, 3)
+	for i, doc := range restored {
+		// the key is restored without the index, in another table
+		require.Equal(t, rows.rows[i].Key, keys.NewKey(table, append([]interface{}{"pk"}, doc.key...)...).SerializeToBytes())
+		require.Equal(t, rows.rows[i].Data.RawData, doc.data.RawData)
+		require.Equal(t, int32(2), doc.data.Ver)
+	}
+
+	_, err = newRestoreDoc([]byte{0xff}, []byte("{}"))
+	require.ErrorIs(t, err, snapshot.ErrCorrupted)
+}
+
+func TestNewSnapshotResponse(t *testing.T) {
+	m := &snapshot.Manifest{
+		Id:        "snap1",
+		Namespace: "ns",
+		Db:        "db1",
+		CreatedAt: time.Unix(1000, 0).UTC(),
+		Collections: []*snapshot.CollectionManifest{{
+			Name:      "coll1",
+			Schemas:   []*snapshot.SchemaRevision{{Version: 1}, {Version: 2}},
+			Documents: 3,
+			Size:      30,
+		}},
+	}
+
+	resp := newSnapshotResponse("snap1", m, nil, true)
+	require.Equal(t, SnapshotComplete, resp.State)
+	require.Equal(t, int64(30), resp.Size)
+	require.Equal(t, int64(3), resp.Documents)
+	require.Equal(t, []*snapshotCollectionResponse{{Name: "coll1", Versions: []int{1, 2}, Documents: 3, Size: 30}}, resp.Collections)
+	require.Nil(t, newSnapshotResponse("snap1", m, nil, false).Collections)
+
+	require.Equal(t, &snapshotResponse{Id: "snap2", State: SnapshotPartial}, newSnapshotResponse("snap2", nil, snapshot.ErrIncomplete, true))
+	require.Equal(t, SnapshotCorrupted, newSnapshotResponse("snap3", nil, snapshot.ErrCorrupted, true).State)
+}
+
+func TestSnapshotJobs(t *testing.T) {
+	jobs := newSnapshotJobs()
+	m := &snapshot.Manifest{Id: "snap1", Namespace: "ns", Db: "db1", CreatedAt: time.Unix(1000, 0).UTC()}
+
+	_, ok := jobs.get("snap1")
+	require.False(t, ok)
+
+	jobs.start(m)
+	resp, ok := jobs.get("snap1")
+	require.True(t, ok)
+	require.Equal(t, &snapshotResponse{Id: "snap1", State: SnapshotRunning, Namespace: "ns", Db: "db1", CreatedAt: &m.CreatedAt}, resp)
+
+	// a complete snapshot is described from its manifest
+	jobs.finish("snap1", nil)
+	_, ok = jobs.get("snap1")
+	require.False(t, ok)
+
+	// the failed snapshots are described until they are deleted
+	jobs.start(&snapshot.Manifest{Id: "snap2"})
+	jobs.finish("snap2", errors.DeadlineExceeded("the scan is not done within the lifetime of its read version"))
+	resp, ok = jobs.get("snap2")
+	require.True(t, ok)
+	require.Equal(t, &snapshotResponse{Id: "snap2", State: SnapshotFailed, Error: "the scan is not done within the lifetime of its read version"}, resp)
+	require.Equal(t, []string{"snap2"}, jobs.failedIds())
+	jobs.forget("snap2")
+	require.Empty(t, jobs.failedIds())
+}
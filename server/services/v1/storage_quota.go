@@ -0,0 +1,158 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/quota"
+)
+
+// storageQuotaPath is the storage quota of a namespace, the quota set for it overrides the data size limits of the
+// config.
+const storageQuotaPath = adminPath + "/namespaces/{namespace}/storage_quota"
+
+type namespaceStorageQuota struct {
+	Namespace string `json:"namespace"`
+	// Usage is the data size of the namespace tracked by the storage quota of this node.
+	Usage int64 `json:"usage"`
+	// Limit is the limit applied to the namespace, it is zero if the storage quota is disabled.
+	Limit int64 `json:"limit"`
+	// Override is the quota set for the namespace, if any.
+	Override *quota.StorageQuota `json:"override,omitempty"`
+}
+
+// recordStorage records the change of the data size of the tenant by a request, it is applied once the transaction
+// of the request is committed.
+func recordStorage(ctx context.Context, tenant *metadata.Tenant, delta int64) {
+	quota.RecordStorage(ctx, tenant.GetNamespace().StrId(), delta)
+}
+
+func (s *apiService) registerStorageQuotaRoutes(router chi.Router) {
+	s.adminRoute(router, http.MethodGet, storageQuotaPath, "GetStorageQuota", s.getStorageQuota)
+	s.adminRoute(router, http.MethodPut, storageQuotaPath, "SetStorageQuota", s.setStorageQuota)
+	s.adminRoute(router, http.MethodDelete, storageQuotaPath, "DeleteStorageQuota", s.deleteStorageQuota)
+}
+
+func (s *apiService) getStorageQuota(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	namespace := chi.URLParam(r, "namespace")
+
+	tenant, err := s.tenantMgr.GetTenant(ctx, namespace)
+	if err != nil {
+		writeAdminError(w, errors.NotFound("namespace '%s' doesn't exist", namespace))
+		return
+	}
+
+	tx, err := s.txMgr.StartTx(ctx)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	override, err := s.storageQuotas.GetStorageQuota(ctx, tx, tenant.GetNamespace().Id())
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	writeAdminJSON(w, newNamespaceStorageQuota(namespace, override))
+}
+
+// setStorageQuota sets the storage quota of a namespace, {"size": 1073741824}. The writes growing the data of the
+// namespace past the quota are rejected, the other nodes apply it once they refresh the data size of the namespace.
+func (s *apiService) setStorageQuota(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	namespace := chi.URLParam(r, "namespace")
+
+	override := &quota.StorageQuota{}
+	if err := jsoniter.NewDecoder(r.Body).Decode(override); err != nil {
+		writeAdminError(w, errors.InvalidArgument("invalid storage quota: %s", err.Error()))
+		return
+	}
+	if err := override.Validate(); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	tenant, err := s.tenantMgr.GetTenant(ctx, namespace)
+	if err != nil {
+		writeAdminError(w, errors.NotFound("namespace '%s' doesn't exist", namespace))
+		return
+	}
+
+	tx, err := s.txMgr.StartTx(ctx)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if err = s.storageQuotas.SetStorageQuota(ctx, tx, tenant.GetNamespace().Id(), override); err != nil {
+		_ = tx.Rollback(ctx)
+		writeAdminError(w, err)
+		return
+	}
+	if err = tx.Commit(ctx); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	quota.SetStorageQuota(namespace, override)
+
+	writeAdminJSON(w, newNamespaceStorageQuota(namespace, override))
+}
+
+func (s *apiService) deleteStorageQuota(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	namespace := chi.URLParam(r, "namespace")
+
+	tenant, err := s.tenantMgr.GetTenant(ctx, namespace)
+	if err != nil {
+		writeAdminError(w, errors.NotFound("namespace '%s' doesn't exist", namespace))
+		return
+	}
+
+	tx, err := s.txMgr.StartTx(ctx)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if err = s.storageQuotas.DeleteStorageQuota(ctx, tx, tenant.GetNamespace().Id()); err != nil {
+		_ = tx.Rollback(ctx)
+		writeAdminError(w, err)
+		return
+	}
+	if err = tx.Commit(ctx); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	quota.SetStorageQuota(namespace, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newNamespaceStorageQuota(namespace string, override *quota.StorageQuota) *namespaceStorageQuota {
+	usage, limit, _ := quota.StorageUsage(namespace)
+	return &namespaceStorageQuota{
+		Namespace: namespace,
+		Usage:     usage,
+		Limit:     limit,
+		Override:  override,
+	}
+}
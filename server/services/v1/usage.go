@@ -0,0 +1,48 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/metrics"
+)
+
+// usagePath returns the usage of the namespaces since the server started, for the billing pipeline.
+const usagePath = adminPath + "/usage"
+
+// recordReadUsage records the documents read and the search queries of a request on behalf of the tenant. The reads
+// done in a transaction are recorded once it is committed, so only the committed attempt of a request is metered.
+func recordReadUsage(ctx context.Context, tenant *metadata.Tenant, usage metrics.Usage) {
+	namespace := tenant.GetNamespace()
+	metrics.RecordTxUsage(ctx, namespace.StrId(), namespace.Metadata().Name, usage)
+}
+
+// recordWriteUsage records the documents written by a request on behalf of the tenant, they are recorded once the
+// transaction of the request is committed.
+func recordWriteUsage(ctx context.Context, tenant *metadata.Tenant, documents int64, bytes int64) {
+	namespace := tenant.GetNamespace()
+	metrics.RecordWriteUsage(ctx, namespace.StrId(), namespace.Metadata().Name, documents, bytes)
+}
+
+func (s *apiService) getUsage(w http.ResponseWriter, _ *http.Request) {
+	writeAdminJSON(w, struct {
+		Namespaces []metrics.NamespaceUsage `json:"namespaces"`
+	}{
+		Namespaces: metrics.UsageSnapshot(),
+	})
+}
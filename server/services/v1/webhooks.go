@@ -0,0 +1,634 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/cdc"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/tigrisdata/tigris/store/kv"
+	ulog "github.com/tigrisdata/tigris/util/log"
+)
+
+const (
+	webhooksPath      = adminPath + "/namespaces/{namespace}/databases/{db}/collections/{collection}/webhooks"
+	webhookPath       = webhooksPath + "/{id}"
+	webhookResumePath = webhookPath + "/resume"
+
+	// webhookIdHeader is the id of the webhook a delivery is sent to.
+	webhookIdHeader = "Tigris-Webhook-Id"
+	// webhookDeliveryHeader is the id of a delivery, it is the same for all the attempts of a delivery so that the
+	// receiver can discard the duplicates.
+	webhookDeliveryHeader = "Tigris-Webhook-Delivery"
+	// webhookSignatureHeader is the signature of a delivery, see cdc.SignWebhook.
+	webhookSignatureHeader = "Tigris-Webhook-Signature"
+
+	// webhookSyncInterval is how often the workers are reconciled with the webhooks registered on any server.
+	webhookSyncInterval = 10 * time.Second
+)
+
+var errWebhookDeadLettered = fmt.Errorf("webhook is dead-lettered")
+
+// webhookEvent is an event of a delivery.
+type webhookEvent struct {
+	TxId []byte              `json:"tx_id"`
+	Op   string              `json:"op"`
+	Key  []byte              `json:"key,omitempty"`
+	Data jsoniter.RawMessage `json:"data,omitempty"`
+}
+
+// webhookDelivery is the body of the requests sent to the webhooks.
+type webhookDelivery struct {
+	Webhook    string          `json:"webhook"`
+	Db         string          `json:"db"`
+	Collection string          `json:"collection"`
+	Events     []*webhookEvent `json:"events"`
+}
+
+// webhookDispatcher runs a delivery worker for every active webhook. The deliveries are at-least-once, the position of
+// a webhook only moves once the receiver has acknowledged the delivery with a 2xx response, so the events of a
+// delivery are sent again after a failure or a restart.
+type webhookDispatcher struct {
+	sync.Mutex
+
+	cfg       *config.WebhooksConfig
+	store     cdc.WebhookStore
+	kvStore   kv.KeyValueStore
+	tenantMgr *metadata.TenantManager
+	cdcMgr    *cdc.Manager
+	client    *http.Client
+	started   bool
+	workers   map[string]*webhookWorker
+}
+
+func newWebhookDispatcher(cfg *config.WebhooksConfig, store cdc.WebhookStore, kvStore kv.KeyValueStore, tenantMgr *metadata.TenantManager, cdcMgr *cdc.Manager) *webhookDispatcher {
+	return &webhookDispatcher{
+		cfg:       cfg,
+		store:     store,
+		kvStore:   kvStore,
+		tenantMgr: tenantMgr,
+		cdcMgr:    cdcMgr,
+		client:    newWebhookClient(cfg),
+		workers:   make(map[string]*webhookWorker),
+	}
+}
+
+// newWebhookClient returns the client of the deliveries. Unless the private addresses are allowed, the client refuses
+// to connect to them, so that a webhook host resolving to another address after its validation, or redirecting to
+// one, can't reach the internal services.
+func newWebhookClient(cfg *config.WebhooksConfig) *http.Client {
+	client := &http.Client{Timeout: cfg.Timeout}
+	if cfg.AllowPrivateAddresses {
+		return client
+	}
+
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(_ string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("webhook address %s is not public", host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	client.Transport = transport
+	return client
+}
+
+// isPublicIP returns false for the loopback, private, link-local, unspecified and multicast addresses.
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// webhookLookupIP resolves the hosts of the webhooks.
+var webhookLookupIP = net.DefaultResolver.LookupIPAddr
+
+// position returns the position of the latest change of the database, a webhook created at this position receives
+// all the changes committed after it.
+func (d *webhookDispatcher) position(db string) ([]byte, error) {
+	return d.cdcMgr.GetPublisher(db).Position(d.kvStore)
+}
+
+// start delivers the changes to the webhooks from this server.
+func (d *webhookDispatcher) start() {
+	d.Lock()
+	d.started = true
+	d.Unlock()
+
+	go func() {
+		for {
+			ulog.E(d.sync())
+			time.Sleep(webhookSyncInterval)
+		}
+	}()
+}
+
+// sync starts the workers of the new and resumed webhooks and stops the workers of the deleted ones.
+func (d *webhookDispatcher) sync() error {
+	d.Lock()
+	defer d.Unlock()
+
+	if !d.started {
+		return nil
+	}
+
+	webhooks, err := d.store.List()
+	if err != nil {
+		return err
+	}
+
+	active := make(map[string]struct{}, len(webhooks))
+	for _, webhook := range webhooks {
+		active[webhook.Id] = struct{}{}
+		if _, ok := d.workers[webhook.Id]; ok {
+			continue
+		}
+
+		_, status, err := d.store.Get(webhook.Id)
+		if err != nil {
+			return err
+		}
+		if status.State != cdc.WebhookActive {
+			continue
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		w := newWebhookWorker(d.cfg, webhook, status, d.store, d.client)
+		w.cancel = cancel
+		d.workers[webhook.Id] = w
+		go d.run(ctx, w)
+	}
+
+	for id, w := range d.workers {
+		if _, ok := active[id]; !ok {
+			w.cancel()
+			delete(d.workers, id)
+		}
+	}
+
+	return nil
+}
+
+func (d *webhookDispatcher) run(ctx context.Context, w *webhookWorker) {
+	defer func() {
+		d.Lock()
+		if d.workers[w.webhook.Id] == w {
+			delete(d.workers, w.webhook.Id)
+		}
+		d.Unlock()
+	}()
+
+	for ctx.Err() == nil {
+		err := d.stream(ctx, w)
+		if err == errWebhookDeadLettered {
+			return
+		}
+		if err != nil {
+			log.Err(err).Str("webhook", w.webhook.Id).Msg("webhook delivery failed")
+			select {
+			case <-ctx.Done():
+			case <-time.After(w.cfg.InitialBackoff):
+			}
+		}
+	}
+}
+
+// stream reads the changes after the position of the webhook and delivers them in batches. It returns without an
+// error if the stream needs to be restarted from the position of the webhook.
+func (d *webhookDispatcher) stream(ctx context.Context, w *webhookWorker) error {
+	selector, err := d.webhookSelector(ctx, w.webhook)
+	if err != nil {
+		return err
+	}
+
+	streamer, err := d.cdcMgr.GetPublisher(w.webhook.Db).NewStreamer(d.kvStore, w.status.Position)
+	if cdc.IsResumeTokenExpired(err) {
+		// the changes after the position are lost, the webhook is resumed from the latest change
+		w.status.Expired, w.status.LastError = true, err.Error()
+		return w.deadLetter(0)
+	}
+	if err != nil {
+		return err
+	}
+	defer streamer.Close()
+
+	ticker := time.NewTicker(w.batchInterval())
+	defer ticker.Stop()
+
+	var batch []*webhookEvent
+	position := w.status.Position
+	for {
+		select {
+		case <-ctx.Done():
+			// the events that are not delivered yet are delivered again from the position of the webhook
+			return nil
+		case <-ticker.C:
+			if err = w.flush(ctx, batch, position); err != nil {
+				return err
+			}
+			batch = nil
+		case tx, ok := <-streamer.Txs:
+			if !ok {
+				// the stream buffer overflowed while delivering, restart from the position of the webhook
+				return nil
+			}
+
+			for _, op := range tx.Ops {
+				ns, db, coll, ok := d.tenantMgr.DecodeTableName(op.Table)
+				if !ok || ns != w.webhook.Namespace || db != w.webhook.Db || coll != w.webhook.Collection {
+					continue
+				}
+
+				data, matched, err := selector.eventData(op)
+				if err != nil {
+					return err
+				}
+				if matched {
+					batch = append(batch, &webhookEvent{TxId: tx.Id, Op: op.Op, Key: op.Key, Data: data})
+				}
+			}
+			position = tx.Id
+
+			if len(batch) >= w.batchSize() {
+				if err = w.flush(ctx, batch, position); err != nil {
+					return err
+				}
+				batch = nil
+			}
+		}
+	}
+}
+
+func (d *webhookDispatcher) webhookSelector(ctx context.Context, webhook *cdc.Webhook) (*eventSelector, error) {
+	tenant, err := d.tenantMgr.GetTenant(ctx, webhook.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	coll := tenant.GetCollection(webhook.Db, webhook.Collection)
+	if coll == nil {
+		return nil, errors.NotFound("collection '%s' doesn't exist in the database '%s'", webhook.Collection, webhook.Db)
+	}
+
+	return newEventSelector(coll, webhook.Filter, nil, webhook.IncludeBefore)
+}
+
+// webhookWorker delivers the changes to a webhook, it owns the status of the webhook while it runs.
+type webhookWorker struct {
+	cfg     *config.WebhooksConfig
+	webhook *cdc.Webhook
+	status  *cdc.WebhookStatus
+	store   cdc.WebhookStore
+	client  *http.Client
+	cancel  context.CancelFunc
+}
+
+func newWebhookWorker(cfg *config.WebhooksConfig, webhook *cdc.Webhook, status *cdc.WebhookStatus, store cdc.WebhookStore, client *http.Client) *webhookWorker {
+	return &webhookWorker{
+		cfg:     cfg,
+		webhook: webhook,
+		status:  status,
+		store:   store,
+		client:  client,
+	}
+}
+
+func (w *webhookWorker) batchSize() int {
+	if w.webhook.BatchSize > 0 {
+		return w.webhook.BatchSize
+	}
+	if w.cfg.BatchSize > 0 {
+		return w.cfg.BatchSize
+	}
+	return 1
+}
+
+func (w *webhookWorker) batchInterval() time.Duration {
+	if w.webhook.BatchIntervalMs > 0 {
+		return time.Duration(w.webhook.BatchIntervalMs) * time.Millisecond
+	}
+	if w.cfg.BatchInterval > 0 {
+		return w.cfg.BatchInterval
+	}
+	return time.Second
+}
+
+func (w *webhookWorker) maxRetries() int {
+	if w.webhook.MaxRetries > 0 {
+		return w.webhook.MaxRetries
+	}
+	return w.cfg.MaxRetries
+}
+
+// flush delivers the batch and moves the webhook to the position. Without any event the position still moves, so that
+// the changes filtered out are not read again.
+func (w *webhookWorker) flush(ctx context.Context, batch []*webhookEvent, position []byte) error {
+	if len(batch) == 0 {
+		if position == nil || bytes.Equal(position, w.status.Position) {
+			return nil
+		}
+		w.status.Position = position
+		return w.store.SaveStatus(w.webhook.Id, w.status)
+	}
+
+	if err := w.deliver(ctx, batch); err != nil {
+		return err
+	}
+
+	w.status.Position = position
+	w.status.Delivered += int64(len(batch))
+	w.status.Retries, w.status.LastError = 0, ""
+	return w.store.SaveStatus(w.webhook.Id, w.status)
+}
+
+// deliver sends the events to the webhook, the failed attempts are retried with an exponential backoff. Once all the
+// retries have failed the webhook is dead-lettered.
+func (w *webhookWorker) deliver(ctx context.Context, events []*webhookEvent) error {
+	body, err := jsoniter.Marshal(&webhookDelivery{
+		Webhook:    w.webhook.Id,
+		Db:         w.webhook.Db,
+		Collection: w.webhook.Collection,
+		Events:     events,
+	})
+	if err != nil {
+		return err
+	}
+
+	deliveryId := uuid.New().String()
+	start := time.Now()
+	for {
+		w.status.LastAttempt = time.Now()
+		err = w.post(ctx, deliveryId, body)
+		if err == nil {
+			metrics.UpdateCdcWebhookDelivered(w.webhook.Db, w.webhook.Collection, w.webhook.Id, len(events), time.Since(start))
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		w.status.Retries++
+		w.status.LastError = err.Error()
+		if w.status.Retries > w.maxRetries() {
+			return w.deadLetter(len(events))
+		}
+
+		metrics.UpdateCdcWebhookRetry(w.webhook.Db, w.webhook.Collection, w.webhook.Id)
+		// the status is saved so that the retries are visible while the delivery is failing
+		ulog.E(w.store.SaveStatus(w.webhook.Id, w.status))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(cdc.WebhookBackoff(w.status.Retries, w.cfg.InitialBackoff, w.cfg.MaxBackoff)):
+		}
+	}
+}
+
+// deadLetter stops the delivery of the webhook with the events of the delivery that failed, the webhook is delivered
+// to again once it is resumed.
+func (w *webhookWorker) deadLetter(events int) error {
+	w.status.State = cdc.WebhookDeadLetter
+	w.status.DeadLetter = events
+	metrics.UpdateCdcWebhookDeadLetter(w.webhook.Db, w.webhook.Collection, w.webhook.Id)
+	log.Warn().Str("webhook", w.webhook.Id).Str("error", w.status.LastError).Msg("webhook dead-lettered")
+	if err := w.store.SaveStatus(w.webhook.Id, w.status); err != nil {
+		return err
+	}
+	return errWebhookDeadLettered
+}
+
+func (w *webhookWorker) post(ctx context.Context, deliveryId string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookIdHeader, w.webhook.Id)
+	req.Header.Set(webhookDeliveryHeader, deliveryId)
+	if len(w.webhook.Secret) > 0 {
+		req.Header.Set(webhookSignatureHeader, cdc.SignWebhook(w.webhook.Secret, time.Now(), body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// webhookResponse is a webhook along with its delivery status, the secret is never returned.
+type webhookResponse struct {
+	Webhook *cdc.Webhook       `json:"webhook"`
+	Status  *cdc.WebhookStatus `json:"status"`
+}
+
+func newWebhookResponse(webhook *cdc.Webhook, status *cdc.WebhookStatus) *webhookResponse {
+	hidden := *webhook
+	hidden.Secret = ""
+	return &webhookResponse{Webhook: &hidden, Status: status}
+}
+
+func (s *apiService) registerWebhookRoutes(router chi.Router) {
+	s.adminRoute(router, http.MethodPost, webhooksPath, "CreateWebhook", s.createWebhook)
+	s.adminRoute(router, http.MethodGet, webhooksPath, "ListWebhooks", s.listWebhooks)
+	s.adminRoute(router, http.MethodGet, webhookPath, "GetWebhook", s.getWebhook)
+	s.adminRoute(router, http.MethodDelete, webhookPath, "DeleteWebhook", s.deleteWebhook)
+	s.adminRoute(router, http.MethodPost, webhookResumePath, "ResumeWebhook", s.resumeWebhook)
+}
+
+// createWebhook registers a webhook on the collection, the changes committed after the webhook is created are delivered
+// to it once its worker starts.
+func (s *apiService) createWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook := &cdc.Webhook{}
+	if err := jsoniter.NewDecoder(r.Body).Decode(webhook); err != nil {
+		writeAdminError(w, errors.InvalidArgument("invalid webhook: %s", err.Error()))
+		return
+	}
+	webhook.Id = uuid.New().String()
+	webhook.Namespace, webhook.Db, webhook.Collection = chi.URLParam(r, "namespace"), chi.URLParam(r, "db"), chi.URLParam(r, "collection")
+
+	if err := validateWebhook(r.Context(), webhook, s.webhooks.cfg.AllowPrivateAddresses); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	// the filter is validated against the schema of the collection
+	if _, err := s.webhooks.webhookSelector(r.Context(), webhook); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	position, err := s.webhooks.position(webhook.Db)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	status := &cdc.WebhookStatus{State: cdc.WebhookActive, Position: position}
+	if err := s.webhooks.store.Save(webhook, status); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	ulog.E(s.webhooks.sync())
+
+	writeAdminJSON(w, newWebhookResponse(webhook, status))
+}
+
+// validateWebhook validates the webhook, the host of its url must only resolve to public addresses unless the private
+// addresses are allowed.
+func validateWebhook(ctx context.Context, webhook *cdc.Webhook, allowPrivate bool) error {
+	u, err := url.Parse(webhook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return errors.InvalidArgument("webhook url must be an absolute http or https url")
+	}
+	if webhook.BatchSize < 0 || webhook.BatchIntervalMs < 0 || webhook.MaxRetries < 0 {
+		return errors.InvalidArgument("webhook batch_size, batch_interval_ms and max_retries can't be negative")
+	}
+	if allowPrivate {
+		return nil
+	}
+
+	addrs, err := webhookLookupIP(ctx, u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return errors.InvalidArgument("webhook host '%s' can't be resolved", u.Hostname())
+	}
+	for _, addr := range addrs {
+		if !isPublicIP(addr.IP) {
+			return errors.InvalidArgument("webhook host '%s' must not resolve to a loopback, private or link-local address", u.Hostname())
+		}
+	}
+	return nil
+}
+
+func (s *apiService) listWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := s.webhooks.store.List()
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	resp := make([]*webhookResponse, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		if webhook.Namespace != chi.URLParam(r, "namespace") || webhook.Db != chi.URLParam(r, "db") || webhook.Collection != chi.URLParam(r, "collection") {
+			continue
+		}
+
+		_, status, err := s.webhooks.store.Get(webhook.Id)
+		if err != nil {
+			writeAdminError(w, err)
+			return
+		}
+		resp = append(resp, newWebhookResponse(webhook, status))
+	}
+
+	writeAdminJSON(w, resp)
+}
+
+// getWebhook returns the webhook with its delivery status, the status tells whether the webhook is dead-lettered.
+func (s *apiService) getWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, status, err := s.getCollectionWebhook(r)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	writeAdminJSON(w, newWebhookResponse(webhook, status))
+}
+
+func (s *apiService) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, status, err := s.getCollectionWebhook(r)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if err = s.webhooks.store.Delete(webhook.Id); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	ulog.E(s.webhooks.sync())
+
+	writeAdminJSON(w, newWebhookResponse(webhook, status))
+}
+
+// resumeWebhook restarts the delivery of a dead-lettered webhook, starting with the delivery that failed. A webhook
+// whose changes are no longer retained is resumed from the latest change.
+func (s *apiService) resumeWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, status, err := s.getCollectionWebhook(r)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if status.State != cdc.WebhookDeadLetter {
+		writeAdminError(w, errors.FailedPrecondition("webhook '%s' is not dead-lettered", webhook.Id))
+		return
+	}
+
+	if status.Expired {
+		if status.Position, err = s.webhooks.position(webhook.Db); err != nil {
+			writeAdminError(w, err)
+			return
+		}
+	}
+	status.State, status.Retries, status.DeadLetter, status.Expired = cdc.WebhookActive, 0, 0, false
+	if err = s.webhooks.store.SaveStatus(webhook.Id, status); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	ulog.E(s.webhooks.sync())
+
+	writeAdminJSON(w, newWebhookResponse(webhook, status))
+}
+
+func (s *apiService) getCollectionWebhook(r *http.Request) (*cdc.Webhook, *cdc.WebhookStatus, error) {
+	id := chi.URLParam(r, "id")
+	webhook, status, err := s.webhooks.store.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if webhook.Namespace != chi.URLParam(r, "namespace") || webhook.Db != chi.URLParam(r, "db") || webhook.Collection != chi.URLParam(r, "collection") {
+		return nil, nil, errors.NotFound("webhook '%s' doesn't exist", id)
+	}
+	return webhook, status, nil
+}
@@ -0,0 +1,299 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/cdc"
+	"github.com/tigrisdata/tigris/server/config"
+)
+
+type memWebhookStore struct {
+	sync.Mutex
+
+	webhooks map[string]*cdc.Webhook
+	statuses map[string]cdc.WebhookStatus
+}
+
+func newMemWebhookStore() *memWebhookStore {
+	return &memWebhookStore{webhooks: map[string]*cdc.Webhook{}, statuses: map[string]cdc.WebhookStatus{}}
+}
+
+func (m *memWebhookStore) List() ([]*cdc.Webhook, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	var webhooks []*cdc.Webhook
+	for _, webhook := range m.webhooks {
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, nil
+}
+
+func (m *memWebhookStore) Get(id string) (*cdc.Webhook, *cdc.WebhookStatus, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	webhook, ok := m.webhooks[id]
+	if !ok {
+		return nil, nil, errors.NotFound("webhook '%s' doesn't exist", id)
+	}
+	status := m.statuses[id]
+	return webhook, &status, nil
+}
+
+func (m *memWebhookStore) Save(webhook *cdc.Webhook, status *cdc.WebhookStatus) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.webhooks[webhook.Id], m.statuses[webhook.Id] = webhook, *status
+	return nil
+}
+
+func (m *memWebhookStore) SaveStatus(id string, status *cdc.WebhookStatus) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.webhooks[id]; ok {
+		m.statuses[id] = *status
+	}
+	return nil
+}
+
+func (m *memWebhookStore) Delete(id string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.webhooks, id)
+	delete(m.statuses, id)
+	return nil
+}
+
+func TestWebhookWorker(t *testing.T) {
+	cfg := &config.WebhooksConfig{
+		BatchSize:      10,
+		BatchInterval:  time.Second,
+		MaxRetries:     2,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+	}
+	events := []*webhookEvent{{TxId: []byte("tx1"), Op: "insert", Key: []byte("k1"), Data: []byte(`{"id":1}`)}}
+
+	newWorker := func(url string) (*webhookWorker, *memWebhookStore) {
+		store := newMemWebhookStore()
+		webhook := &cdc.Webhook{Id: "w1", Namespace: "ns", Db: "db1", Collection: "c1", URL: url, Secret: "secret"}
+		status := &cdc.WebhookStatus{State: cdc.WebhookActive}
+		require.NoError(t, store.Save(webhook, status))
+		return newWebhookWorker(cfg, webhook, status, store, http.DefaultClient), store
+	}
+
+	t.Run("delivered", func(t *testing.T) {
+		var received webhookDelivery
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.Equal(t, "w1", r.Header.Get(webhookIdHeader))
+			require.NotEmpty(t, r.Header.Get(webhookDeliveryHeader))
+			require.NoError(t, cdc.VerifyWebhookSignature("secret", r.Header.Get(webhookSignatureHeader), body, time.Minute, time.Now()))
+			require.NoError(t, jsoniter.Unmarshal(body, &received))
+		}))
+		defer srv.Close()
+
+		w, store := newWorker(srv.URL)
+		require.NoError(t, w.flush(context.Background(), events, []byte("tx1")))
+		require.Equal(t, "db1", received.Db)
+		require.Equal(t, "c1", received.Collection)
+		require.Len(t, received.Events, 1)
+		require.JSONEq(t, `{"id":1}`, string(received.Events[0].Data))
+
+		_, status, err := store.Get("w1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("tx1"), status.Position)
+		require.Equal(t, int64(1), status.Delivered)
+		require.Equal(t, cdc.WebhookActive, status.State)
+	})
+
+	t.Run("retried", func(t *testing.T) {
+		var attempts int
+		var deliveries []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			deliveries = append(deliveries, r.Header.Get(webhookDeliveryHeader))
+			if attempts < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer srv.Close()
+
+		w, store := newWorker(srv.URL)
+		require.NoError(t, w.flush(context.Background(), events, []byte("tx1")))
+		require.Equal(t, 3, attempts)
+		// all the attempts of a delivery have the same id
+		require.Equal(t, deliveries[0], deliveries[2])
+
+		_, status, err := store.Get("w1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("tx1"), status.Position)
+		require.Equal(t, 0, status.Retries)
+		require.Empty(t, status.LastError)
+	})
+
+	t.Run("dead_letter", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		w, store := newWorker(srv.URL)
+		require.Equal(t, errWebhookDeadLettered, w.flush(context.Background(), events, []byte("tx1")))
+
+		_, status, err := store.Get("w1")
+		require.NoError(t, err)
+		require.Equal(t, cdc.WebhookDeadLetter, status.State)
+		require.Nil(t, status.Position)
+		require.Equal(t, 3, status.Retries)
+		require.Equal(t, 1, status.DeadLetter)
+		require.Equal(t, "webhook responded with status 500", status.LastError)
+	})
+
+	t.Run("idle", func(t *testing.T) {
+		w, store := newWorker("http://localhost")
+		require.NoError(t, w.flush(context.Background(), nil, []byte("tx2")))
+
+		_, status, err := store.Get("w1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("tx2"), status.Position)
+		require.Equal(t, int64(0), status.Delivered)
+	})
+}
+
+func TestWebhookRoutes(t *testing.T) {
+	store := newMemWebhookStore()
+	s := &apiService{webhooks: newWebhookDispatcher(&config.WebhooksConfig{}, store, nil, nil, nil)}
+	router := chi.NewRouter()
+	s.registerAdminRoutes(router)
+
+	webhook := &cdc.Webhook{Id: "w1", Namespace: "ns", Db: "db1", Collection: "c1", URL: "http://localhost", Secret: "secret"}
+	require.NoError(t, store.Save(webhook, &cdc.WebhookStatus{State: cdc.WebhookDeadLetter, Retries: 3, DeadLetter: 2}))
+
+	call := func(method string, path string) (int, string) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w.Code, w.Body.String()
+	}
+
+	code, body := call(http.MethodGet, "/admin/namespaces/ns/databases/db1/collections/c1/webhooks/w1")
+	require.Equal(t, http.StatusOK, code)
+	require.JSONEq(t, `{
+		"webhook": {"id":"w1","namespace":"ns","db":"db1","collection":"c1","url":"http://localhost"},
+		"status": {"state":"dead_letter","delivered":0,"retries":3,"dead_letter":2,"last_attempt":"0001-01-01T00:00:00Z"}
+	}`, body)
+
+	code, _ = call(http.MethodGet, "/admin/namespaces/ns/databases/db1/collections/c2/webhooks/w1")
+	require.Equal(t, http.StatusNotFound, code)
+
+	code, body = call(http.MethodPost, "/admin/namespaces/ns/databases/db1/collections/c1/webhooks/w1/resume")
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, `"state":"active"`)
+
+	code, _ = call(http.MethodPost, "/admin/namespaces/ns/databases/db1/collections/c1/webhooks/w1/resume")
+	require.Equal(t, http.StatusPreconditionFailed, code)
+
+	code, body = call(http.MethodGet, "/admin/namespaces/ns/databases/db1/collections/c1/webhooks")
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, `"id":"w1"`)
+
+	code, _ = call(http.MethodDelete, "/admin/namespaces/ns/databases/db1/collections/c1/webhooks/w1")
+	require.Equal(t, http.StatusOK, code)
+
+	code, body = call(http.MethodGet, "/admin/namespaces/ns/databases/db1/collections/c1/webhooks")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, `[]`, body)
+}
+
+func TestValidateWebhook(t *testing.T) {
+	addrs := map[string]string{
+		"example.com":  "93.184.216.34",
+		"internal.com": "10.0.0.1",
+		"local.com":    "127.0.0.1",
+		"metadata.com": "169.254.169.254",
+	}
+	lookup := webhookLookupIP
+	defer func() { webhookLookupIP = lookup }()
+	webhookLookupIP = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		if ip := net.ParseIP(host); ip != nil {
+			return []net.IPAddr{{IP: ip}}, nil
+		}
+		if addr, ok := addrs[host]; ok {
+			return []net.IPAddr{{IP: net.ParseIP(addr)}}, nil
+		}
+		return nil, fmt.Errorf("no such host")
+	}
+
+	validate := func(webhook *cdc.Webhook) error {
+		return validateWebhook(context.Background(), webhook, false)
+	}
+	require.NoError(t, validate(&cdc.Webhook{URL: "https://example.com/hook"}))
+	require.Error(t, validate(&cdc.Webhook{URL: "example.com/hook"}))
+	require.Error(t, validate(&cdc.Webhook{URL: "ftp://example.com/hook"}))
+	require.Error(t, validate(&cdc.Webhook{URL: "https://example.com", BatchSize: -1}))
+
+	// the hosts resolving to the internal addresses are rejected
+	require.Error(t, validate(&cdc.Webhook{URL: "https://internal.com/hook"}))
+	require.Error(t, validate(&cdc.Webhook{URL: "https://local.com:8080/hook"}))
+	require.Error(t, validate(&cdc.Webhook{URL: "http://metadata.com/latest/meta-data"}))
+	require.Error(t, validate(&cdc.Webhook{URL: "http://[::1]/hook"}))
+	require.Error(t, validate(&cdc.Webhook{URL: "http://unknown.com/hook"}))
+
+	require.NoError(t, validateWebhook(context.Background(), &cdc.Webhook{URL: "https://local.com:8080/hook"}, true))
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for _, ip := range []string{"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946"} {
+		require.True(t, isPublicIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{
+		"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "0.0.0.0", "224.0.0.1",
+		"::1", "fc00::1", "fe80::1", "::",
+	} {
+		require.False(t, isPublicIP(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestWebhookClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+
+	// the server listens on the loopback address
+	_, err := newWebhookClient(&config.WebhooksConfig{Timeout: time.Second}).Get(srv.URL)
+	require.ErrorContains(t, err, "is not public")
+
+	resp, err := newWebhookClient(&config.WebhooksConfig{Timeout: time.Second, AllowPrivateAddresses: true}).Get(srv.URL)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+}
@@ -0,0 +1,454 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package snapshot persists the snapshots of the databases in an object store. A snapshot is a set of objects under
+// the id of the snapshot: a data file per collection, holding the documents as they are stored, and a manifest
+// describing the collections with all their schema revisions and the checksums of the data files. The manifest is
+// written last, so a snapshot without a manifest is partial.
+package snapshot
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ManifestFile is the name of the manifest of a snapshot.
+const ManifestFile = "manifest.json"
+
+// maxRecordSize bounds the size of a record of a data file, a larger size means the file is corrupted.
+const maxRecordSize = 64 * 1024 * 1024
+
+var (
+	// ErrIncomplete is returned for a snapshot whose manifest is missing, i.e. the snapshot failed or is in progress.
+	ErrIncomplete = fmt.Errorf("snapshot is incomplete")
+	// ErrCorrupted is returned for a snapshot whose manifest or data files don't match their checksums.
+	ErrCorrupted = fmt.Errorf("snapshot is corrupted")
+
+	validId = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+)
+
+// Store is the object store the snapshots are written to. The objects are addressed by the id of their snapshot and
+// their name.
+type Store interface {
+	// Create returns a writer of the object, the object is visible once the writer is closed.
+	Create(id string, name string) (io.WriteCloser, error)
+	// Open returns a reader of the object, os.ErrNotExist is returned if the object doesn't exist.
+	Open(id string, name string) (io.ReadCloser, error)
+	// List returns the ids of the snapshots, including the partial ones.
+	List() ([]string, error)
+	// Delete removes all the objects of the snapshot.
+	Delete(id string) error
+}
+
+// ValidateId returns an error if the id can't be the id of a snapshot, the ids are part of the paths of the objects.
+func ValidateId(id string) error {
+	if !validId.MatchString(id) {
+		return fmt.Errorf("invalid snapshot id '%s'", id)
+	}
+	return nil
+}
+
+// FileStore is a Store in a directory of the local filesystem, every snapshot is a subdirectory.
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+func (f *FileStore) path(id string, name string) (string, error) {
+	if err := ValidateId(id); err != nil {
+		return "", err
+	}
+	if name != filepath.Base(name) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid snapshot object '%s'", name)
+	}
+	return filepath.Join(f.dir, id, name), nil
+}
+
+func (f *FileStore) Create(id string, name string) (io.WriteCloser, error) {
+	path, err := f.path(id, name)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+
+	// the object is written to a temporary file and renamed on close, so that a partial object is never visible
+	file, err := os.CreateTemp(filepath.Dir(path), name+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &fileObject{File: file, path: path}, nil
+}
+
+func (f *FileStore) Open(id string, name string) (io.ReadCloser, error) {
+	path, err := f.path(id, name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (f *FileStore) List() ([]string, error) {
+	entries, err := os.ReadDir(f.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, e := range entries {
+		if e.IsDir() && ValidateId(e.Name()) == nil {
+			ids = append(ids, e.Name())
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (f *FileStore) Delete(id string) error {
+	if err := ValidateId(id); err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(f.dir, id))
+}
+
+type fileObject struct {
+	*os.File
+	path string
+	// failed is set on the first failed write, the object is then discarded on close.
+	failed bool
+}
+
+func (o *fileObject) Write(p []byte) (int, error) {
+	n, err := o.File.Write(p)
+	if err != nil {
+		o.failed = true
+	}
+	return n, err
+}
+
+func (o *fileObject) Close() error {
+	if o.failed {
+		_ = o.File.Close()
+		return os.Remove(o.File.Name())
+	}
+	if err := o.File.Sync(); err != nil {
+		_ = o.File.Close()
+		_ = os.Remove(o.File.Name())
+		return err
+	}
+	if err := o.File.Close(); err != nil {
+		_ = os.Remove(o.File.Name())
+		return err
+	}
+	return os.Rename(o.File.Name(), o.path)
+}
+
+// Manifest describes a snapshot of a database.
+type Manifest struct {
+	Id        string    `json:"id"`
+	Namespace string    `json:"namespace"`
+	Db        string    `json:"db"`
+	CreatedAt time.Time `json:"created_at"`
+	// MetadataVersion is the version of the metadata the schemas of the collections are read at.
+	MetadataVersion []byte                `json:"metadata_version"`
+	Collections     []*CollectionManifest `json:"collections"`
+	// Checksum is the hex encoded SHA-256 of the manifest with an empty checksum.
+	Checksum string `json:"checksum"`
+}
+
+// CollectionManifest describes a collection of a snapshot along with its data file.
+type CollectionManifest struct {
+	Name string `json:"name"`
+	// Schemas are all the revisions of the schema of the collection, in the order of their version.
+	Schemas   []*SchemaRevision `json:"schemas"`
+	File      string            `json:"file"`
+	Documents int64             `json:"documents"`
+	// Size and Checksum are the size and the hex encoded SHA-256 of the data file.
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum"`
+}
+
+type SchemaRevision struct {
+	Version int                 `json:"version"`
+	Schema  jsoniter.RawMessage `json:"schema"`
+}
+
+// Size returns the size of the data files of the snapshot.
+func (m *Manifest) Size() int64 {
+	var size int64
+	for _, c := range m.Collections {
+		size += c.Size
+	}
+	return size
+}
+
+// Documents returns the number of documents of the snapshot.
+func (m *Manifest) Documents() int64 {
+	var documents int64
+	for _, c := range m.Collections {
+		documents += c.Documents
+	}
+	return documents
+}
+
+func (m *Manifest) checksum() (string, error) {
+	checksum := m.Checksum
+	defer func() { m.Checksum = checksum }()
+
+	m.Checksum = ""
+	data, err := jsoniter.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// WriteManifest sets the checksum of the manifest and writes it, which completes the snapshot.
+func WriteManifest(store Store, m *Manifest) error {
+	checksum, err := m.checksum()
+	if err != nil {
+		return err
+	}
+	m.Checksum = checksum
+
+	data, err := jsoniter.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	w, err := store.Create(m.Id, ManifestFile)
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(data); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// ReadManifest reads the manifest of the snapshot. ErrIncomplete is returned if the manifest is missing, and
+// ErrCorrupted if it doesn't match its checksum.
+func ReadManifest(store Store, id string) (*Manifest, error) {
+	r, err := store.Open(id, ManifestFile)
+	if os.IsNotExist(err) {
+		return nil, ErrIncomplete
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{}
+	if err = jsoniter.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("%w: invalid manifest", ErrCorrupted)
+	}
+	checksum, err := m.checksum()
+	if err != nil {
+		return nil, err
+	}
+	if checksum != m.Checksum || m.Id != id {
+		return nil, fmt.Errorf("%w: manifest checksum mismatch", ErrCorrupted)
+	}
+	return m, nil
+}
+
+// Verify reads all the data files of the snapshot and returns ErrCorrupted if any of them doesn't match its checksum.
+func Verify(store Store, m *Manifest) error {
+	for _, c := range m.Collections {
+		if err := verifyCollection(store, m.Id, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyCollection(store Store, id string, c *CollectionManifest) error {
+	r, err := OpenData(store, id, c)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+
+	for {
+		if _, _, err = r.Next(); err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// DataWriter writes the documents of a collection in its data file. Every document is a record made of its primary
+// key followed by its value as it is stored, each of them prefixed with its length as an unsigned varint.
+type DataWriter struct {
+	closer    io.Closer
+	buf       *bufio.Writer
+	hash      hash.Hash
+	documents int64
+	size      int64
+}
+
+func NewDataWriter(w io.WriteCloser) *DataWriter {
+	h := sha256.New()
+	return &DataWriter{
+		closer: w,
+		buf:    bufio.NewWriter(io.MultiWriter(w, h)),
+		hash:   h,
+	}
+}
+
+func (d *DataWriter) Write(key []byte, doc []byte) error {
+	for _, field := range [][]byte{key, doc} {
+		var length [binary.MaxVarintLen64]byte
+		n := binary.PutUvarint(length[:], uint64(len(field)))
+		if _, err := d.buf.Write(length[:n]); err != nil {
+			return err
+		}
+		if _, err := d.buf.Write(field); err != nil {
+			return err
+		}
+		d.size += int64(n + len(field))
+	}
+
+	d.documents++
+	return nil
+}
+
+// Close flushes the data file and fills the documents, the size and the checksum of the collection.
+func (d *DataWriter) Close(c *CollectionManifest) error {
+	if err := d.buf.Flush(); err != nil {
+		_ = d.closer.Close()
+		return err
+	}
+	if err := d.closer.Close(); err != nil {
+		return err
+	}
+
+	c.Documents, c.Size, c.Checksum = d.documents, d.size, hex.EncodeToString(d.hash.Sum(nil))
+	return nil
+}
+
+// DataReader reads the documents of a data file, it checks the data file against the manifest once all the
+// documents are read.
+type DataReader struct {
+	closer     io.Closer
+	buf        *bufio.Reader
+	hash       hash.Hash
+	collection *CollectionManifest
+	documents  int64
+	size       int64
+}
+
+// OpenData opens the data file of the collection of the snapshot.
+func OpenData(store Store, id string, c *CollectionManifest) (*DataReader, error) {
+	r, err := store.Open(id, c.File)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: data file of the collection '%s' is missing", ErrCorrupted, c.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.New()
+	return &DataReader{
+		closer:     r,
+		buf:        bufio.NewReader(io.TeeReader(r, h)),
+		hash:       h,
+		collection: c,
+	}, nil
+}
+
+// Next returns the key and the value of the next document, or io.EOF once all the documents are read and the data
+// file matches its checksum.
+func (d *DataReader) Next() ([]byte, []byte, error) {
+	key, err := d.field()
+	if err == io.EOF {
+		return nil, nil, d.verify()
+	}
+	if err != nil {
+		return nil, nil, d.corrupted()
+	}
+	doc, err := d.field()
+	if err != nil {
+		return nil, nil, d.corrupted()
+	}
+
+	d.documents++
+	return key, doc, nil
+}
+
+func (d *DataReader) field() ([]byte, error) {
+	length, err := binary.ReadUvarint(d.buf)
+	if err != nil {
+		return nil, err
+	}
+	d.size += int64(uvarintLen(length)) + int64(length)
+	if length > maxRecordSize || d.size > d.collection.Size {
+		return nil, d.corrupted()
+	}
+
+	field := make([]byte, length)
+	if _, err = io.ReadFull(d.buf, field); err != nil {
+		return nil, err
+	}
+	return field, nil
+}
+
+func (d *DataReader) Close() error {
+	return d.closer.Close()
+}
+
+func (d *DataReader) verify() error {
+	c := d.collection
+	if d.documents != c.Documents || d.size != c.Size || hex.EncodeToString(d.hash.Sum(nil)) != c.Checksum {
+		return d.corrupted()
+	}
+	return io.EOF
+}
+
+func (d *DataReader) corrupted() error {
+	return fmt.Errorf("%w: data file of the collection '%s' doesn't match its checksum", ErrCorrupted, d.collection.Name)
+}
+
+func uvarintLen(x uint64) int {
+	var length [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(length[:], x)
+}
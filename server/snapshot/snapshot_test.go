@@ -0,0 +1,172 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeSnapshot(t *testing.T, store Store, docs ...string) *Manifest {
+	t.Helper()
+
+	m := &Manifest{
+		Id:        "snap1",
+		Namespace: "ns",
+		Db:        "db1",
+		CreatedAt: time.Unix(1000, 0).UTC(),
+		Collections: []*CollectionManifest{{
+			Name:    "coll1",
+			Schemas: []*SchemaRevision{{Version: 1, Schema: []byte(`{"title":"coll1"}`)}},
+			File:    "0.data",
+		}},
+	}
+
+	w, err := store.Create(m.Id, "0.data")
+	require.NoError(t, err)
+	data := NewDataWriter(w)
+	for i, doc := range docs {
+		require.NoError(t, data.Write([]byte{byte(i)}, []byte(doc)))
+	}
+	require.NoError(t, data.Close(m.Collections[0]))
+	require.NoError(t, WriteManifest(store, m))
+
+	return m
+}
+
+func readDocs(t *testing.T, store Store, m *Manifest) ([]string, error) {
+	t.Helper()
+
+	r, err := OpenData(store, m.Id, m.Collections[0])
+	require.NoError(t, err)
+	defer func() { _ = r.Close() }()
+
+	var docs []string
+	for {
+		key, doc, err := r.Next()
+		if err == io.EOF {
+			return docs, nil
+		}
+		if err != nil {
+			return docs, err
+		}
+		require.Equal(t, []byte{byte(len(docs))}, key)
+		docs = append(docs, string(doc))
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	t.Run("round_trip", func(t *testing.T) {
+		store := NewFileStore(t.TempDir())
+		m := writeSnapshot(t, store, `{"a":1}`, `{"a":2}`, "")
+
+		require.Equal(t, int64(3), m.Documents())
+		require.Equal(t, int64(23), m.Size())
+		require.NotEmpty(t, m.Checksum)
+
+		ids, err := store.List()
+		require.NoError(t, err)
+		require.Equal(t, []string{"snap1"}, ids)
+
+		read, err := ReadManifest(store, "snap1")
+		require.NoError(t, err)
+		require.Equal(t, m, read)
+		require.NoError(t, Verify(store, read))
+
+		docs, err := readDocs(t, store, read)
+		require.NoError(t, err)
+		require.Equal(t, []string{`{"a":1}`, `{"a":2}`, ""}, docs)
+
+		require.NoError(t, store.Delete("snap1"))
+		ids, err = store.List()
+		require.NoError(t, err)
+		require.Empty(t, ids)
+	})
+	t.Run("incomplete", func(t *testing.T) {
+		store := NewFileStore(t.TempDir())
+		w, err := store.Create("snap1", "0.data")
+		require.NoError(t, err)
+		require.NoError(t, NewDataWriter(w).Close(&CollectionManifest{}))
+
+		ids, err := store.List()
+		require.NoError(t, err)
+		require.Equal(t, []string{"snap1"}, ids)
+
+		_, err = ReadManifest(store, "snap1")
+		require.Equal(t, ErrIncomplete, err)
+	})
+	t.Run("corrupted_manifest", func(t *testing.T) {
+		dir := t.TempDir()
+		store := NewFileStore(dir)
+		writeSnapshot(t, store, `{"a":1}`)
+
+		path := filepath.Join(dir, "snap1", ManifestFile)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, []byte(string(data)[:len(data)-10]), 0o600))
+		_, err = ReadManifest(store, "snap1")
+		require.True(t, errors.Is(err, ErrCorrupted))
+
+		tampered := []byte(string(data))
+		copy(tampered[len(`{"id":"snap1","namespace":"`):], "xx")
+		require.NoError(t, os.WriteFile(path, tampered, 0o600))
+		_, err = ReadManifest(store, "snap1")
+		require.True(t, errors.Is(err, ErrCorrupted))
+	})
+	t.Run("corrupted_data", func(t *testing.T) {
+		dir := t.TempDir()
+		store := NewFileStore(dir)
+		m := writeSnapshot(t, store, `{"a":1}`, `{"a":2}`)
+
+		path := filepath.Join(dir, "snap1", "0.data")
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		// a flipped byte
+		flipped := append([]byte(nil), data...)
+		flipped[3] = 'b'
+		require.NoError(t, os.WriteFile(path, flipped, 0o600))
+		require.True(t, errors.Is(Verify(store, m), ErrCorrupted))
+
+		// a truncated record
+		require.NoError(t, os.WriteFile(path, data[:len(data)-2], 0o600))
+		_, err = readDocs(t, store, m)
+		require.True(t, errors.Is(err, ErrCorrupted))
+
+		// a missing record
+		require.NoError(t, os.WriteFile(path, data[:len(data)/2], 0o600))
+		require.True(t, errors.Is(Verify(store, m), ErrCorrupted))
+
+		// a missing data file
+		require.NoError(t, os.Remove(path))
+		require.True(t, errors.Is(Verify(store, m), ErrCorrupted))
+	})
+	t.Run("invalid_paths", func(t *testing.T) {
+		store := NewFileStore(t.TempDir())
+		_, err := store.Create("../snap1", "0.data")
+		require.Error(t, err)
+		_, err = store.Create("snap1", "../0.data")
+		require.Error(t, err)
+		_, err = store.Open("snap1/..", ManifestFile)
+		require.Error(t, err)
+		require.Error(t, store.Delete(".."))
+	})
+}
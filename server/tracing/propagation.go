@@ -0,0 +1,124 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// GrpcTraceBinHeader is the binary trace context header set by the gRPC clients instrumented with OpenCensus.
+const GrpcTraceBinHeader = "grpc-trace-bin"
+
+const (
+	binaryVersion      = 0
+	binaryTraceIDField = 0
+	binarySpanIDField  = 1
+	binaryOptionsField = 2
+	binaryLength       = 29
+)
+
+// BinaryPropagator propagates the trace context in the OpenCensus binary format of the grpc-trace-bin header. The
+// value is the version byte followed by the trace id, span id and trace options fields, each prefixed by its id.
+type BinaryPropagator struct{}
+
+var _ propagation.TextMapPropagator = BinaryPropagator{}
+
+func (BinaryPropagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+
+	carrier.Set(GrpcTraceBinHeader, string(marshalBinary(sc)))
+}
+
+func (BinaryPropagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	sc, ok := unmarshalBinary([]byte(carrier.Get(GrpcTraceBinHeader)))
+	if !ok {
+		return ctx
+	}
+
+	return trace.ContextWithRemoteSpanContext(ctx, sc)
+}
+
+func (BinaryPropagator) Fields() []string {
+	return []string{GrpcTraceBinHeader}
+}
+
+func marshalBinary(sc trace.SpanContext) []byte {
+	traceID, spanID := sc.TraceID(), sc.SpanID()
+
+	b := make([]byte, 0, binaryLength)
+	b = append(b, binaryVersion, binaryTraceIDField)
+	b = append(b, traceID[:]...)
+	b = append(b, binarySpanIDField)
+	b = append(b, spanID[:]...)
+	return append(b, binaryOptionsField, byte(sc.TraceFlags()&trace.FlagsSampled))
+}
+
+func unmarshalBinary(b []byte) (trace.SpanContext, bool) {
+	if len(b) < binaryLength || b[0] != binaryVersion || b[1] != binaryTraceIDField || b[18] != binarySpanIDField ||
+		b[27] != binaryOptionsField {
+		return trace.SpanContext{}, false
+	}
+
+	var cfg trace.SpanContextConfig
+	copy(cfg.TraceID[:], b[2:18])
+	copy(cfg.SpanID[:], b[19:27])
+	cfg.TraceFlags = trace.TraceFlags(b[28]) & trace.FlagsSampled
+	cfg.Remote = true
+
+	sc := trace.NewSpanContext(cfg)
+	return sc, sc.IsValid()
+}
+
+// metadataCarrier reads and writes the trace context from the gRPC metadata. The binary headers are already decoded
+// by gRPC.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key string, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// ExtractIncoming returns the context with the trace context of the upstream caller, found either in the
+// traceparent or the grpc-trace-bin header of the incoming request, as the remote parent of the spans of the request.
+func ExtractIncoming(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	return otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+func testSpanContext(t *testing.T, traceID string, spanID string, flags trace.TraceFlags) trace.SpanContext {
+	t.Helper()
+
+	var cfg trace.SpanContextConfig
+	_, err := hex.Decode(cfg.TraceID[:], []byte(traceID))
+	require.NoError(t, err)
+	_, err = hex.Decode(cfg.SpanID[:], []byte(spanID))
+	require.NoError(t, err)
+	cfg.TraceFlags = flags
+	cfg.Remote = true
+
+	return trace.NewSpanContext(cfg)
+}
+
+func TestBinaryPropagator(t *testing.T) {
+	sc := testSpanContext(t, "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", trace.FlagsSampled)
+
+	carrier := propagation.MapCarrier{}
+	BinaryPropagator{}.Inject(trace.ContextWithSpanContext(context.Background(), sc), carrier)
+	b := []byte(carrier.Get(GrpcTraceBinHeader))
+	require.Len(t, b, binaryLength)
+	require.Equal(t, "00004bf92f3577b34da6a3ce929d0e0e47360100f067aa0ba902b70201", hex.EncodeToString(b))
+
+	ctx := BinaryPropagator{}.Extract(context.Background(), carrier)
+	require.Equal(t, sc, trace.SpanContextFromContext(ctx))
+
+	// a context without a valid span is not propagated
+	carrier = propagation.MapCarrier{}
+	BinaryPropagator{}.Inject(context.Background(), carrier)
+	require.Empty(t, carrier.Keys())
+
+	for _, invalid := range []string{
+		"",
+		"00004bf92f3577b34da6a3ce929d0e0e47360100f067aa0ba902b702",
+		"01004bf92f3577b34da6a3ce929d0e0e47360100f067aa0ba902b70201",
+		"00004bf92f3577b34da6a3ce929d0e0e47360200f067aa0ba902b70101",
+		"000000000000000000000000000000000000010000000000000000000201",
+	} {
+		b, err := hex.DecodeString(invalid)
+		require.NoError(t, err)
+		ctx = BinaryPropagator{}.Extract(context.Background(), propagation.MapCarrier{GrpcTraceBinHeader: string(b)})
+		require.False(t, trace.SpanContextFromContext(ctx).IsValid(), invalid)
+	}
+}
+
+func TestExtractIncoming(t *testing.T) {
+	prev := otel.GetTextMapPropagator()
+	defer otel.SetTextMapPropagator(prev)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(BinaryPropagator{}, propagation.TraceContext{}))
+
+	w3c := testSpanContext(t, "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", trace.FlagsSampled)
+	binary := testSpanContext(t, "0af7651916cd43dd8448eb211c80319c", "b7ad6b7169203331", 0)
+
+	require.False(t, trace.SpanContextFromContext(ExtractIncoming(context.Background())).IsValid())
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	))
+	require.Equal(t, w3c, trace.SpanContextFromContext(ExtractIncoming(ctx)))
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		GrpcTraceBinHeader, string(marshalBinary(binary)),
+	))
+	require.Equal(t, binary, trace.SpanContextFromContext(ExtractIncoming(ctx)))
+
+	// the W3C trace context takes precedence when both are set
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		GrpcTraceBinHeader, string(marshalBinary(binary)),
+		"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	))
+	require.Equal(t, w3c, trace.SpanContextFromContext(ExtractIncoming(ctx)))
+}
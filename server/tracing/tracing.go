@@ -15,12 +15,46 @@
 package tracing
 
 import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
 	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/util"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/profiler"
 )
 
+const otlpShutdownTimeout = 5 * time.Second
+
+// KeepAttribute is set on the root spans of the requests that are kept regardless of the sample rate.
+const KeepAttribute = attribute.Key("tigris.sampling.keep")
+
+// keepSampler samples the spans with KeepAttribute and delegates the decision of the other spans.
+type keepSampler struct {
+	sdktrace.Sampler
+}
+
+func (s keepSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	for _, attr := range p.Attributes {
+		if attr.Key == KeepAttribute && attr.Value.AsBool() {
+			return sdktrace.SamplingResult{
+				Decision:   sdktrace.RecordAndSample,
+				Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+			}
+		}
+	}
+	return s.Sampler.ShouldSample(p)
+}
+
 func getTracingOptions(c *config.Config) []tracer.StartOption {
 	var opts []tracer.StartOption
 	rules := []tracer.SamplingRule{tracer.ServiceRule(util.Service, c.Tracing.SampleRate)}
@@ -52,18 +86,69 @@ func getProfilingOptions() []profiler.Option {
 	return opts
 }
 
+func getOtlpOptions(c *config.Config) []otlptracegrpc.Option {
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(c.Tracing.Otlp.Endpoint)}
+	if c.Tracing.Otlp.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	return opts
+}
+
+// initOtlp registers the global OpenTelemetry tracer provider which exports the spans to the OTLP collector, and the
+// propagator used to extract the trace context of the incoming requests.
+func initOtlp(c *config.Config) (func(), error) {
+	exporter, err := otlptracegrpc.New(context.Background(), getOtlpOptions(c)...)
+	if err != nil {
+		return func() {}, err
+	}
+
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceNameKey.String(util.Service),
+		semconv.ServiceVersionKey.String(util.Version),
+		semconv.DeploymentEnvironmentKey.String(config.GetEnvironment()),
+	)
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(keepSampler{sdktrace.TraceIDRatioBased(c.Tracing.SampleRate)})),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(BinaryPropagator{}, propagation.TraceContext{}))
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), otlpShutdownTimeout)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			log.Err(err).Msg("failed to flush the OTLP spans")
+		}
+	}, nil
+}
+
 func InitTracer(config *config.Config) (func(), error) {
 	if !config.Tracing.Enabled {
 		return func() {}, nil
 	}
 
+	closeOtlp := func() {}
+	if config.Tracing.Otlp.Enabled {
+		var err error
+		if closeOtlp, err = initOtlp(config); err != nil {
+			return func() {}, err
+		}
+	}
+
+	if !config.Tracing.DatadogEnabled {
+		return closeOtlp, nil
+	}
+
 	tracer.Start(getTracingOptions(config)...)
 
 	if config.Profiling.Enabled {
 		if err := profiler.Start(getProfilingOptions()...); err != nil {
+			closeOtlp()
 			return func() {}, err
 		}
 	}
 
-	return func() { tracer.Stop(); profiler.Stop() }, nil
+	return func() { tracer.Stop(); profiler.Stop(); closeOtlp() }, nil
 }
@@ -0,0 +1,48 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestKeepSampler(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(recorder),
+		sdktrace.WithSampler(sdktrace.ParentBased(keepSampler{sdktrace.NeverSample()})),
+	)
+	tracer := provider.Tracer("test")
+
+	_, span := tracer.Start(context.Background(), "dropped")
+	require.False(t, span.SpanContext().IsSampled())
+	span.End()
+
+	ctx, span := tracer.Start(context.Background(), "kept", trace.WithAttributes(KeepAttribute.Bool(true)))
+	require.True(t, span.SpanContext().IsSampled())
+	// the children follow the decision of their parent
+	_, child := tracer.Start(ctx, "child")
+	require.True(t, child.SpanContext().IsSampled())
+	child.End()
+	span.End()
+
+	require.Len(t, recorder.Ended(), 2)
+}
@@ -46,6 +46,7 @@ type BaseTx interface {
 	Delete(ctx context.Context, key keys.Key) error
 	Read(ctx context.Context, key keys.Key) (kv.Iterator, error)
 	ReadRange(ctx context.Context, lKey keys.Key, rKey keys.Key, isSnapshot bool) (kv.Iterator, error)
+	ReadRangeParallel(ctx context.Context, lKey keys.Key, rKey keys.Key, isSnapshot bool, ordered bool) (kv.Iterator, error)
 	Get(ctx context.Context, key []byte, isSnapshot bool) (kv.Future, error)
 	SetVersionstampedValue(ctx context.Context, key []byte, value []byte) error
 	SetVersionstampedKey(ctx context.Context, key []byte, value []byte) error
@@ -241,6 +242,23 @@ func (s *TxSession) ReadRange(ctx context.Context, lKey keys.Key, rKey keys.Key,
 	return s.kTx.ReadRange(ctx, lKey.Table(), nil, kv.BuildKey(rKey.IndexParts()...), isSnapshot)
 }
 
+func (s *TxSession) ReadRangeParallel(ctx context.Context, lKey keys.Key, rKey keys.Key, isSnapshot bool, ordered bool) (kv.Iterator, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.validateSession(); err != nil {
+		return nil, err
+	}
+
+	if rKey != nil && lKey != nil {
+		return s.kTx.ReadRangeParallel(ctx, lKey.Table(), kv.BuildKey(lKey.IndexParts()...), kv.BuildKey(rKey.IndexParts()...), isSnapshot, ordered)
+	} else if lKey != nil {
+		return s.kTx.ReadRangeParallel(ctx, lKey.Table(), kv.BuildKey(lKey.IndexParts()...), nil, isSnapshot, ordered)
+	}
+
+	return s.kTx.ReadRangeParallel(ctx, lKey.Table(), nil, kv.BuildKey(rKey.IndexParts()...), isSnapshot, ordered)
+}
+
 func (s *TxSession) SetVersionstampedValue(ctx context.Context, key []byte, value []byte) error {
 	s.Lock()
 	defer s.Unlock()
@@ -0,0 +1,139 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+const (
+	// maxValueSize is the size above which the values are split in chunks, FoundationDB limits a value to 100KB.
+	maxValueSize = 90000
+
+	chunkHeaderSize = 16
+	chunkKeyMarker  = "_tigris_chunk"
+)
+
+var (
+	// chunkHeaderMagic starts the header stored in place of a chunked value, the encoded documents start with their
+	// data type and never with 0xFF.
+	chunkHeaderMagic = []byte{0xFF, 'c', 'h', 'k'}
+
+	// chunkKeySuffix follows the key of a chunked value in the keys of its chunks, so that the chunks are stored right
+	// after the key and are read by the same ranges.
+	chunkKeySuffix = tuple.Tuple{chunkKeyMarker}.Pack()
+)
+
+// chunkHeader is stored in place of a value that is split in chunks.
+type chunkHeader struct {
+	count    int
+	size     int
+	checksum uint32
+}
+
+func (h chunkHeader) encode() []byte {
+	b := make([]byte, chunkHeaderSize)
+	copy(b, chunkHeaderMagic)
+	binary.BigEndian.PutUint32(b[4:], uint32(h.count))
+	binary.BigEndian.PutUint32(b[8:], uint32(h.size))
+	binary.BigEndian.PutUint32(b[12:], h.checksum)
+	return b
+}
+
+// decodeChunkHeader returns the header of the value and whether the value is the header of a chunked value.
+func decodeChunkHeader(value []byte) (chunkHeader, bool) {
+	if len(value) != chunkHeaderSize || !bytes.HasPrefix(value, chunkHeaderMagic) {
+		return chunkHeader{}, false
+	}
+
+	return chunkHeader{
+		count:    int(binary.BigEndian.Uint32(value[4:])),
+		size:     int(binary.BigEndian.Uint32(value[8:])),
+		checksum: binary.BigEndian.Uint32(value[12:]),
+	}, true
+}
+
+// splitValue returns the header and the chunks of a value larger than maxValueSize.
+func splitValue(value []byte) (chunkHeader, [][]byte) {
+	chunks := make([][]byte, 0, (len(value)+maxValueSize-1)/maxValueSize)
+	for start := 0; start < len(value); start += maxValueSize {
+		end := start + maxValueSize
+		if end > len(value) {
+			end = len(value)
+		}
+		chunks = append(chunks, value[start:end])
+	}
+
+	return chunkHeader{count: len(chunks), size: len(value), checksum: crc32.ChecksumIEEE(value)}, chunks
+}
+
+// joinChunks reassembles a chunked value and verifies it against its header.
+func joinChunks(h chunkHeader, chunks [][]byte) ([]byte, error) {
+	if len(chunks) != h.count {
+		return nil, NewStoreError(ErrCodeCorruptedValue, "chunked value has %d chunks, expected %d", len(chunks), h.count)
+	}
+
+	value := make([]byte, 0, h.size)
+	for _, c := range chunks {
+		value = append(value, c...)
+	}
+	if len(value) != h.size || crc32.ChecksumIEEE(value) != h.checksum {
+		return nil, NewStoreError(ErrCodeCorruptedValue, "chunked value doesn't match its checksum")
+	}
+
+	return value, nil
+}
+
+// chunkKey returns the key of the chunk of the value stored at the key.
+func chunkKey(k fdb.Key, chunk int) fdb.Key {
+	ck := make(fdb.Key, 0, len(k)+len(chunkKeySuffix)+9)
+	ck = append(ck, k...)
+	ck = append(ck, chunkKeySuffix...)
+	return append(ck, tuple.Tuple{int64(chunk)}.Pack()...)
+}
+
+// chunkRange returns the range of the keys of all the chunks of the value stored at the key.
+func chunkRange(k fdb.Key) fdb.KeyRange {
+	prefix := make(fdb.Key, 0, len(k)+len(chunkKeySuffix)+1)
+	prefix = append(prefix, k...)
+	prefix = append(prefix, chunkKeySuffix...)
+	end := append(append(fdb.Key{}, prefix...), 0xFF)
+	return fdb.KeyRange{Begin: prefix, End: end}
+}
+
+// isChunkKey returns true if the key is the key of a chunk of the value stored at the key k.
+func isChunkKey(key fdb.Key, k fdb.Key) bool {
+	return len(k) > 0 && len(key) > len(k) && bytes.HasPrefix(key, k) && bytes.HasPrefix(key[len(k):], chunkKeySuffix)
+}
+
+// storedSize returns the number of bytes stored for the value of the key, including the keys of the chunks.
+func storedSize(k fdb.Key, value []byte) int64 {
+	if len(value) <= maxValueSize {
+		return int64(len(k) + len(value))
+	}
+
+	_, chunks := splitValue(value)
+	size := int64(len(k) + chunkHeaderSize)
+	for i, c := range chunks {
+		size += int64(len(chunkKey(k, i)) + len(c))
+	}
+
+	return size
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/internal"
+)
+
+func TestChunks(t *testing.T) {
+	value := bytes.Repeat([]byte("0123456789"), 25000)
+
+	t.Run("split", func(t *testing.T) {
+		h, chunks := splitValue(value)
+		require.Equal(t, 3, h.count)
+		require.Equal(t, len(value), h.size)
+		require.Len(t, chunks, 3)
+		require.Len(t, chunks[0], maxValueSize)
+		require.Len(t, chunks[2], len(value)-2*maxValueSize)
+
+		decoded, ok := decodeChunkHeader(h.encode())
+		require.True(t, ok)
+		require.Equal(t, h, decoded)
+
+		joined, err := joinChunks(decoded, chunks)
+		require.NoError(t, err)
+		require.Equal(t, value, joined)
+	})
+
+	t.Run("corrupted", func(t *testing.T) {
+		h, chunks := splitValue(value)
+
+		_, err := joinChunks(h, chunks[:2])
+		require.Equal(t, NewStoreError(ErrCodeCorruptedValue, "chunked value has 2 chunks, expected 3"), err)
+
+		corrupted := append([]byte{}, chunks[1]...)
+		corrupted[0] = 'x'
+		_, err = joinChunks(h, [][]byte{chunks[0], corrupted, chunks[2]})
+		require.Equal(t, NewStoreError(ErrCodeCorruptedValue, "chunked value doesn't match its checksum"), err)
+	})
+
+	t.Run("header", func(t *testing.T) {
+		encoded, err := internal.Encode(internal.NewTableData([]byte(`{"a": 1}`)))
+		require.NoError(t, err)
+		_, ok := decodeChunkHeader(encoded)
+		require.False(t, ok)
+
+		_, ok = decodeChunkHeader(append(append([]byte{}, chunkHeaderMagic...), 1, 2, 3))
+		require.False(t, ok)
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		table := []byte("table")
+		k := getFDBKey(table, BuildKey("a", int64(1)))
+		next := getFDBKey(table, BuildKey("a", int64(2)))
+
+		r := chunkRange(k)
+		for i := 0; i < 3; i++ {
+			ck := chunkKey(k, i)
+			// the chunks are stored right after the key and before the next key
+			require.Equal(t, 1, bytes.Compare(ck, k))
+			require.Equal(t, -1, bytes.Compare(ck, next))
+			require.True(t, bytes.Compare(ck, r.Begin.FDBKey()) >= 0 && bytes.Compare(ck, r.End.FDBKey()) < 0)
+			require.True(t, isChunkKey(ck, k))
+			require.False(t, isChunkKey(ck, next))
+			if i > 0 {
+				require.Equal(t, 1, bytes.Compare(ck, chunkKey(k, i-1)))
+			}
+		}
+		require.False(t, isChunkKey(next, k))
+		require.False(t, isChunkKey(k, nil))
+	})
+
+	t.Run("size", func(t *testing.T) {
+		k := getFDBKey([]byte("table"), BuildKey("a"))
+		require.Equal(t, int64(len(k)+10), storedSize(k, make([]byte, 10)))
+
+		size := storedSize(k, value)
+		require.Greater(t, size, int64(len(k)+len(value)+chunkHeaderSize))
+		require.Less(t, size, int64(len(value)+4*(len(k)+chunkHeaderSize+len(chunkKeySuffix)+9)))
+	})
+}
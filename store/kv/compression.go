@@ -0,0 +1,54 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/server/metrics"
+)
+
+type compressionCtxKey struct{}
+
+type compression struct {
+	codec      internal.Compression
+	db         string
+	collection string
+}
+
+// WithCompression sets the codec compressing the values written with the context to the collection. The values are
+// compressed before they are chunked, and the sizes before and after the compression are recorded for the collection.
+func WithCompression(ctx context.Context, codec internal.Compression, db string, collection string) context.Context {
+	return context.WithValue(ctx, compressionCtxKey{}, &compression{codec: codec, db: db, collection: collection})
+}
+
+// encode encodes the table data and compresses it with the codec of the context, if any.
+func encode(ctx context.Context, data *internal.TableData) ([]byte, error) {
+	enc, err := internal.Encode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	c, ok := ctx.Value(compressionCtxKey{}).(*compression)
+	if !ok || c.codec == internal.NoCompression {
+		return enc, nil
+	}
+
+	compressed := internal.Compress(enc, c.codec)
+	metrics.RecordDocumentCompression(c.db, c.collection, c.codec.String(), len(enc), len(compressed))
+
+	return compressed, nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/internal"
+)
+
+func TestEncodeWithCompression(t *testing.T) {
+	ctx := context.Background()
+	data := internal.NewTableData(bytes.Repeat([]byte(`{"name": "this is a verbose document"}`), 3000))
+
+	raw, err := encode(ctx, data)
+	require.NoError(t, err)
+	require.Equal(t, byte(internal.TableDataType), raw[0])
+
+	compressed, err := encode(WithCompression(ctx, internal.SnappyCompression, "db1", "coll1"), data)
+	require.NoError(t, err)
+	require.Equal(t, byte(internal.CompressedTableDataType), compressed[0])
+	// the compressed value is split in chunks and accounted for by its compressed size
+	require.Less(t, len(compressed), maxValueSize)
+	require.Greater(t, len(raw), maxValueSize)
+
+	// the values written with any codec are read alike
+	for _, value := range [][]byte{raw, compressed} {
+		decoded, err := internal.Decode(value)
+		require.NoError(t, err)
+		require.Equal(t, data, decoded)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/metrics"
+)
+
+// conflictingKeysPrefix is the prefix of the special keys with the conflicting keys of a transaction that failed to
+// commit, see https://apple.github.io/foundationdb/developer-guide.html#special-keys.
+var conflictingKeysPrefix = fdb.Key("\xff\xff/transaction/conflicting_keys/")
+
+type (
+	conflictReportCtxKey struct{}
+	readConflictCtxKey   struct{}
+)
+
+// WithConflictReport marks the transactions begun with the context as reporting their conflicting keys, if the
+// reporting is enabled in the configuration. A conflict of these transactions is returned as a ConflictError.
+func WithConflictReport(ctx context.Context) context.Context {
+	return context.WithValue(ctx, conflictReportCtxKey{}, true)
+}
+
+func isConflictReported(ctx context.Context) bool {
+	reported, _ := ctx.Value(conflictReportCtxKey{}).(bool)
+	return reported
+}
+
+// WithReadConflictKeys adds the keys to the read conflict ranges of the transactions begun with the context, without
+// reading them. The transactions then fail to commit with a conflict if another transaction writes one of the keys
+// while they run.
+func WithReadConflictKeys(ctx context.Context, keys ...[]byte) context.Context {
+	parent := getReadConflictKeys(ctx)
+	return context.WithValue(ctx, readConflictCtxKey{}, append(append(make([][]byte, 0, len(parent)+len(keys)), parent...), keys...))
+}
+
+func getReadConflictKeys(ctx context.Context) [][]byte {
+	keys, _ := ctx.Value(readConflictCtxKey{}).([][]byte)
+	return keys
+}
+
+// ConflictRange is a range of keys read by a transaction and written by another transaction committed before it.
+type ConflictRange struct {
+	Begin []byte
+	End   []byte
+}
+
+// ConflictError is the ErrConflictingTransaction of a transaction reporting its conflicting keys. It matches
+// ErrConflictingTransaction with errors.Is.
+type ConflictError struct {
+	Ranges []ConflictRange
+}
+
+func (e *ConflictError) Error() string {
+	return ErrConflictingTransaction.Error()
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflictingTransaction
+}
+
+// ErrorCategory returns the category of the error reported by the metrics.
+func (e *ConflictError) ErrorCategory() string {
+	return metrics.ErrorCategoryConflict
+}
+
+// ErrorSubsystem returns the subsystem the error originates from.
+func (e *ConflictError) ErrorSubsystem() string {
+	return errors.SubsystemKV
+}
+
+// ErrorRetryable returns true, the transaction can succeed once it is retried.
+func (e *ConflictError) ErrorRetryable() bool {
+	return true
+}
+
+// conflictError reads the conflicting keys of the transaction that failed to commit with a conflict. The conflict is
+// returned without the keys if they can't be read.
+func (t *ftx) conflictError() error {
+	kvs, err := t.tx.Snapshot().GetRange(fdb.KeyRange{
+		Begin: conflictingKeysPrefix,
+		End:   append(append(fdb.Key{}, conflictingKeysPrefix...), 0xff),
+	}, fdb.RangeOptions{}).GetSliceWithError()
+	if err != nil {
+		log.Warn().Err(err).Msg("reading the conflicting keys")
+		return ErrConflictingTransaction
+	}
+
+	return &ConflictError{Ranges: parseConflictingKeys(kvs)}
+}
+
+// parseConflictingKeys returns the ranges of the special keys of the conflicting keys. A key with the value "1" is the
+// beginning of a conflicting range and the next key, with the value "0", is its end.
+func parseConflictingKeys(kvs []fdb.KeyValue) []ConflictRange {
+	var (
+		ranges []ConflictRange
+		begin  []byte
+	)
+	for _, kv := range kvs {
+		key := bytes.TrimPrefix(kv.Key, conflictingKeysPrefix)
+		switch {
+		case bytes.Equal(kv.Value, []byte("1")):
+			begin = key
+		case begin != nil:
+			ranges = append(ranges, ConflictRange{Begin: begin, End: key})
+			begin = nil
+		}
+	}
+
+	return ranges
+}
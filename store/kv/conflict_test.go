@@ -0,0 +1,67 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/server/metrics"
+)
+
+func conflictingKey(key string) fdb.Key {
+	return append(append(fdb.Key{}, conflictingKeysPrefix...), key...)
+}
+
+func TestParseConflictingKeys(t *testing.T) {
+	require.Nil(t, parseConflictingKeys(nil))
+
+	require.Equal(t, []ConflictRange{
+		{Begin: []byte("a"), End: []byte("a\x00")},
+		{Begin: []byte("c"), End: []byte("d")},
+	}, parseConflictingKeys([]fdb.KeyValue{
+		{Key: conflictingKey("a"), Value: []byte("1")},
+		{Key: conflictingKey("a\x00"), Value: []byte("0")},
+		{Key: conflictingKey("c"), Value: []byte("1")},
+		{Key: conflictingKey("d"), Value: []byte("0")},
+		// the end of a range without a beginning is ignored
+		{Key: conflictingKey("e"), Value: []byte("0")},
+	}))
+}
+
+func TestReadConflictKeys(t *testing.T) {
+	require.Empty(t, getReadConflictKeys(context.Background()))
+
+	ctx := WithReadConflictKeys(context.Background(), []byte("a"))
+	ctx1 := WithReadConflictKeys(ctx, []byte("b"))
+	ctx2 := WithReadConflictKeys(ctx, []byte("c"), []byte("d"))
+	require.Equal(t, [][]byte{[]byte("a")}, getReadConflictKeys(ctx))
+	require.Equal(t, [][]byte{[]byte("a"), []byte("b")}, getReadConflictKeys(ctx1))
+	require.Equal(t, [][]byte{[]byte("a"), []byte("c"), []byte("d")}, getReadConflictKeys(ctx2))
+}
+
+func TestConflictError(t *testing.T) {
+	require.False(t, isConflictReported(context.Background()))
+	require.True(t, isConflictReported(WithConflictReport(context.Background())))
+
+	var err error = &ConflictError{Ranges: []ConflictRange{{Begin: []byte("a"), End: []byte("b")}}}
+	require.True(t, errors.Is(err, ErrConflictingTransaction))
+	require.False(t, errors.Is(err, ErrDuplicateKey))
+	require.Equal(t, ErrConflictingTransaction.Error(), err.Error())
+	require.Equal(t, metrics.ErrorCategoryConflict, err.(*ConflictError).ErrorCategory())
+}
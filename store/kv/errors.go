@@ -15,10 +15,14 @@
 package kv
 
 import (
-	"errors"
+	goerrors "errors"
 	"fmt"
 
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"google.golang.org/grpc/status"
 )
 
 type StoreErrCode byte
@@ -28,6 +32,8 @@ const (
 	ErrCodeDuplicateKey           StoreErrCode = 0x01
 	ErrCodeConflictingTransaction StoreErrCode = 0x02
 	ErrCodeTransactionMaxDuration StoreErrCode = 0x03
+	ErrCodeCorruptedValue         StoreErrCode = 0x04
+	ErrCodeRowLimitExceeded       StoreErrCode = 0x05
 )
 
 var (
@@ -37,6 +43,8 @@ var (
 	ErrConflictingTransaction = NewStoreError(ErrCodeConflictingTransaction, "transaction not committed due to conflict with another transaction")
 	// ErrTransactionMaxDurationReached is returned when transaction running beyond 5seconds.
 	ErrTransactionMaxDurationReached = NewStoreError(ErrCodeTransactionMaxDuration, "transaction is old to perform reads or be committed")
+	// ErrRowLimitExceeded is returned when the exact size of a table is requested and it has more rows than the limit.
+	ErrRowLimitExceeded = NewStoreError(ErrCodeRowLimitExceeded, "table has more rows than the limit of the exact size")
 )
 
 type StoreError struct {
@@ -52,9 +60,97 @@ func (se StoreError) Error() string {
 	return se.msg
 }
 
+// ErrorCategory returns the category of the error reported by the metrics.
+func (se StoreError) ErrorCategory() string {
+	switch se.code {
+	case ErrCodeDuplicateKey, ErrCodeConflictingTransaction:
+		return metrics.ErrorCategoryConflict
+	case ErrCodeTransactionMaxDuration:
+		return metrics.ErrorCategoryTimeout
+	default:
+		return metrics.ErrorCategoryInternal
+	}
+}
+
+// ErrorSubsystem returns the subsystem the error originates from.
+func (se StoreError) ErrorSubsystem() string {
+	return errors.SubsystemKV
+}
+
+// ErrorRetryable returns true if the request can be retried on the error, a conflicting transaction and a transaction
+// running for too long can succeed once they are retried.
+func (se StoreError) ErrorRetryable() bool {
+	return se.code == ErrCodeConflictingTransaction || se.code == ErrCodeTransactionMaxDuration
+}
+
+// fdbErrorCodes are the codes the FoundationDB errors are returned with once they are not retried, see
+// https://apple.github.io/foundationdb/api-error-codes.html. The other errors are internal errors.
+var fdbErrorCodes = map[int]api.Code{
+	1004: api.Code_DEADLINE_EXCEEDED,   // timed_out
+	1007: api.Code_DEADLINE_EXCEEDED,   // transaction_too_old
+	1009: api.Code_UNAVAILABLE,         // future_version
+	1021: api.Code_UNAVAILABLE,         // commit_unknown_result
+	1025: api.Code_FAILED_PRECONDITION, // transaction_cancelled
+	1031: api.Code_DEADLINE_EXCEEDED,   // transaction_timed_out
+	1037: api.Code_UNAVAILABLE,         // process_behind
+	1038: api.Code_UNAVAILABLE,         // database_locked
+	1039: api.Code_UNAVAILABLE,         // cluster_version_changed
+	1213: api.Code_UNAVAILABLE,         // tag_throttled
+	2101: api.Code_INVALID_ARGUMENT,    // transaction_too_large
+	2102: api.Code_INVALID_ARGUMENT,    // key_too_large
+	2103: api.Code_INVALID_ARGUMENT,    // value_too_large
+}
+
+// FdbError is a FoundationDB error annotated with the code it is returned to the clients with. The FoundationDB error
+// is kept, so that the metrics are tagged with its code.
+type FdbError struct {
+	err  fdb.Error
+	code api.Code
+}
+
+func (e *FdbError) Error() string {
+	return e.err.Error()
+}
+
+func (e *FdbError) Unwrap() error {
+	return e.err
+}
+
+// Code returns the code the error is returned with.
+func (e *FdbError) Code() api.Code {
+	return e.code
+}
+
+// ErrorSubsystem returns the subsystem the error originates from.
+func (e *FdbError) ErrorSubsystem() string {
+	return errors.SubsystemKV
+}
+
+func (e *FdbError) GRPCStatus() *status.Status {
+	return api.Errorf(e.code, "%s", e.err.Error()).GRPCStatus()
+}
+
+// classifyError returns the error of an operation of the store that is not retried. A conflict is returned as
+// ErrConflictingTransaction and the other FoundationDB errors are annotated with their code.
+func classifyError(err error) error {
+	var ep fdb.Error
+	if !goerrors.As(err, &ep) {
+		return err
+	}
+	if ep.Code == 1020 {
+		return ErrConflictingTransaction
+	}
+
+	code, ok := fdbErrorCodes[ep.Code]
+	if !ok {
+		code = api.Code_INTERNAL
+	}
+	return &FdbError{err: ep, code: code}
+}
+
 func IsTimedOut(err error) bool {
 	var ep fdb.Error
-	if !errors.As(err, &ep) {
+	if !goerrors.As(err, &ep) {
 		return false
 	}
 
@@ -15,6 +15,7 @@
 package kv
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -37,7 +38,11 @@ const (
 
 // fdbkv is an implementation of kv on top of FoundationDB.
 type fdbkv struct {
-	db fdb.Database
+	db    fdb.Database
+	retry *retryPolicy
+	scan  *scanPolicy
+	// reportConflicts enables the reporting of the conflicting keys of the transactions begun with WithConflictReport.
+	reportConflicts bool
 }
 
 type fbatch struct {
@@ -50,12 +55,17 @@ type ftx struct {
 	d   *fdbkv
 	tx  *fdb.Transaction
 	err error
+	// reportConflicts is set if the conflicting keys are read once the commit fails with a conflict.
+	reportConflicts bool
 }
 
 type fdbIterator struct {
 	it       *fdb.RangeIterator
 	subspace subspace.Subspace
 	err      error
+	// last is the key of the last value returned, the chunks of the value written by the updates of the range being
+	// iterated are skipped.
+	last fdb.Key
 }
 
 type fdbIteratorTxCloser struct {
@@ -65,7 +75,7 @@ type fdbIteratorTxCloser struct {
 
 // newFoundationDB initializes instance of FoundationDB KV interface implementation.
 func newFoundationDB(cfg *config.FoundationDBConfig) (*fdbkv, error) {
-	d := &fdbkv{}
+	d := &fdbkv{retry: newRetryPolicy(cfg), scan: newScanPolicy(cfg), reportConflicts: cfg.ReportConflicts}
 	if err := d.init(cfg); err != nil {
 		return nil, err
 	}
@@ -105,47 +115,51 @@ func (d *fdbkv) ReadRange(ctx context.Context, table []byte, lKey Key, rKey Key,
 	return &fdbIteratorTxCloser{it, tx}, nil
 }
 
+// txWithRetry runs fn in a transaction and commits it, the transient errors are retried by the retry policy. The
+// errors that are not retried are classified.
 func (d *fdbkv) txWithRetry(ctx context.Context, fn func(fdb.Transaction) (interface{}, error)) (interface{}, error) {
-	for {
-		retry, res, err := d.txWithRetryLow(ctx, fn)
-		if !retry {
-			return res, err
+	for attempt := 0; ; attempt++ {
+		res, onErrorRetried, err := d.txWithRetryLow(ctx, fn)
+		if err == nil {
+			return res, nil
+		}
+		if !d.retry.retry(ctx, attempt, err, onErrorRetried) {
+			return nil, classifyError(err)
 		}
 	}
 }
 
-func (d *fdbkv) txWithRetryLow(ctx context.Context, fn func(fdb.Transaction) (interface{}, error)) (bool, interface{}, error) {
+// txWithRetryLow runs fn in a transaction and commits it. It returns true with the FoundationDB errors that OnError of
+// the transaction retries.
+func (d *fdbkv) txWithRetryLow(ctx context.Context, fn func(fdb.Transaction) (interface{}, error)) (interface{}, bool, error) {
 	tr, err := d.db.CreateTransaction()
 	defer tr.Cancel()
 
 	if err != nil {
-		return false, nil, err
+		return nil, false, err
 	}
 
 	if err := setTxTimeout(&tr, getCtxTimeout(ctx)); err != nil {
-		return false, nil, err
+		return nil, false, err
 	}
-
-	var res interface{}
-	if res, err = fn(tr); err != nil {
-		return false, nil, err
+	if err := tr.Options().SetMaxRetryDelay(d.retry.maxBackoff.Milliseconds()); err != nil {
+		return nil, false, err
 	}
 
-	if err := tr.Commit().Get(); err == nil {
-		return false, res, nil
+	var res interface{}
+	if res, err = fn(tr); err == nil {
+		if err = tr.Commit().Get(); err == nil {
+			return res, false, nil
+		}
 	}
 
 	var ep fdb.Error
 	if errors.As(err, &ep) {
 		// OnError returns nil if error is retryable
-		err = tr.OnError(ep).Get()
+		return nil, tr.OnError(ep).Get() == nil, err
 	}
 
-	if err != nil {
-		return false, nil, err
-	}
-
-	return true, nil, nil
+	return nil, false, err
 }
 
 func (d *fdbkv) Insert(ctx context.Context, table []byte, key Key, data []byte) error {
@@ -258,16 +272,20 @@ func (d *fdbkv) Batch() (baseTx, error) {
 	return b, nil
 }
 
-func (b *fbatch) flushBatch(ctx context.Context, _ Key, _ Key, data []byte) error {
+func (b *fbatch) flushBatch(ctx context.Context, table []byte, lKey Key, _ Key, data []byte) error {
 	fsz := b.rtx.GetApproximateSize()
 	sz, err := fsz.Get()
 	if ulog.E(err) {
 		return err
 	}
 
-	// FIXME: Include lkey and rKey in size calculation
+	// FIXME: Include rKey in size calculation
+	var dataSize int64
+	if data != nil {
+		dataSize = storedSize(getFDBKey(table, lKey), data)
+	}
 
-	if sz+int64(len(data)) > maxTxSizeBytes {
+	if sz+dataSize > maxTxSizeBytes {
 		log.Debug().Int64("size", sz).Msg("flush batch")
 		err = b.tx.Commit(ctx)
 		if ulog.E(err) {
@@ -285,56 +303,56 @@ func (b *fbatch) flushBatch(ctx context.Context, _ Key, _ Key, data []byte) erro
 }
 
 func (b *fbatch) Insert(ctx context.Context, table []byte, key Key, data []byte) error {
-	if err := b.flushBatch(ctx, key, nil, data); err != nil {
+	if err := b.flushBatch(ctx, table, key, nil, data); err != nil {
 		return err
 	}
 	return b.tx.Insert(ctx, table, key, data)
 }
 
 func (b *fbatch) Replace(ctx context.Context, table []byte, key Key, data []byte, isUpdate bool) error {
-	if err := b.flushBatch(ctx, key, nil, data); err != nil {
+	if err := b.flushBatch(ctx, table, key, nil, data); err != nil {
 		return err
 	}
 	return b.tx.Replace(ctx, table, key, data, isUpdate)
 }
 
 func (b *fbatch) Delete(ctx context.Context, table []byte, key Key) error {
-	if err := b.flushBatch(ctx, key, nil, nil); err != nil {
+	if err := b.flushBatch(ctx, table, key, nil, nil); err != nil {
 		return err
 	}
 	return b.tx.Delete(ctx, table, key)
 }
 
 func (b *fbatch) DeleteRange(ctx context.Context, table []byte, lKey Key, rKey Key) error {
-	if err := b.flushBatch(ctx, lKey, rKey, nil); err != nil {
+	if err := b.flushBatch(ctx, table, lKey, rKey, nil); err != nil {
 		return err
 	}
 	return b.tx.DeleteRange(ctx, table, lKey, rKey)
 }
 
 func (b *fbatch) Update(ctx context.Context, table []byte, key Key, apply func([]byte) ([]byte, error)) (int32, error) {
-	if err := b.flushBatch(ctx, key, nil, nil); err != nil {
+	if err := b.flushBatch(ctx, table, key, nil, nil); err != nil {
 		return -1, err
 	}
 	return b.tx.Update(ctx, table, key, apply)
 }
 
 func (b *fbatch) UpdateRange(ctx context.Context, table []byte, lKey Key, rKey Key, apply func([]byte) ([]byte, error)) (int32, error) {
-	if err := b.flushBatch(ctx, lKey, rKey, nil); err != nil {
+	if err := b.flushBatch(ctx, table, lKey, rKey, nil); err != nil {
 		return -1, err
 	}
 	return b.tx.UpdateRange(ctx, table, lKey, rKey, apply)
 }
 
 func (b *fbatch) Read(ctx context.Context, table []byte, key Key) (baseIterator, error) {
-	if err := b.flushBatch(ctx, key, nil, nil); err != nil {
+	if err := b.flushBatch(ctx, table, key, nil, nil); err != nil {
 		return nil, err
 	}
 	return b.tx.Read(ctx, table, key)
 }
 
 func (b *fbatch) ReadRange(ctx context.Context, table []byte, lKey Key, rKey Key, isSnapshot bool) (baseIterator, error) {
-	if err := b.flushBatch(ctx, lKey, rKey, nil); err != nil {
+	if err := b.flushBatch(ctx, table, lKey, rKey, nil); err != nil {
 		return nil, err
 	}
 	return b.tx.ReadRange(ctx, table, lKey, rKey, isSnapshot)
@@ -374,8 +392,27 @@ func (d *fdbkv) BeginTx(ctx context.Context) (baseTx, error) {
 		return nil, err
 	}
 
+	if rv := getReadVersion(ctx); rv != nil {
+		if err := rv.pin(&tx); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, k := range getReadConflictKeys(ctx) {
+		if err := tx.AddReadConflictKey(fdb.Key(k)); err != nil {
+			return nil, err
+		}
+	}
+
+	reportConflicts := d.reportConflicts && isConflictReported(ctx)
+	if reportConflicts {
+		if err := tx.Options().SetReportConflictingKeys(); err != nil {
+			return nil, err
+		}
+	}
+
 	log.Trace().Msg("create transaction")
-	return &ftx{d: d, tx: &tx}, nil
+	return &ftx{d: d, tx: &tx, reportConflicts: reportConflicts}, nil
 }
 
 func (t *ftx) Insert(ctx context.Context, table []byte, key Key, data []byte) error {
@@ -392,7 +429,7 @@ func (t *ftx) Insert(ctx context.Context, table []byte, key Key, data []byte) er
 		return ErrDuplicateKey
 	}
 
-	t.tx.Set(k, data)
+	t.setValue(k, data, false)
 	listener.OnSet(InsertEvent, table, k, data)
 
 	log.Debug().Str("table", string(table)).Interface("key", key).Msg("Insert")
@@ -404,11 +441,11 @@ func (t *ftx) Replace(ctx context.Context, table []byte, key Key, data []byte, i
 	listener := GetEventListener(ctx)
 	k := getFDBKey(table, key)
 
-	t.tx.Set(k, data)
+	t.setValue(k, data, true)
 	if isUpdate {
-		listener.OnSet(UpdateEvent, table, k, data)
+		listener.OnUpdate(UpdateEvent, table, k, getPreImage(ctx), data)
 	} else {
-		listener.OnSet(ReplaceEvent, table, k, data)
+		listener.OnUpdate(ReplaceEvent, table, k, getPreImage(ctx), data)
 	}
 
 	log.Debug().Str("table", string(table)).Interface("key", key).Msg("tx Replace")
@@ -424,7 +461,7 @@ func (t *ftx) Delete(ctx context.Context, table []byte, key Key) error {
 	}
 
 	t.tx.ClearRange(kr)
-	listener.OnClearRange(DeleteEvent, table, kr.Begin.FDBKey(), kr.End.FDBKey())
+	listener.OnClearRange(DeleteEvent, table, kr.Begin.FDBKey(), kr.End.FDBKey(), getPreImage(ctx))
 
 	log.Debug().Str("table", string(table)).Interface("key", key).Msg("tx delete")
 
@@ -437,7 +474,7 @@ func (t *ftx) DeleteRange(ctx context.Context, table []byte, lKey Key, rKey Key)
 	rk := getFDBKey(table, rKey)
 
 	t.tx.ClearRange(fdb.KeyRange{Begin: lk, End: rk})
-	listener.OnClearRange(DeleteRangeEvent, table, lk, rk)
+	listener.OnClearRange(DeleteRangeEvent, table, lk, rk, nil)
 
 	log.Debug().Str("table", string(table)).Interface("lKey", lKey).Interface("rKey", rKey).Msg("tx delete range")
 
@@ -452,24 +489,24 @@ func (t *ftx) Update(ctx context.Context, table []byte, key Key, apply func([]by
 	}
 
 	r := t.tx.GetRange(k, fdb.RangeOptions{})
-	it := r.Iterator()
+	it := &fdbIterator{it: r.Iterator(), subspace: subspace.FromBytes(table)}
 
 	modifiedCount := int32(0)
-	for it.Advance() {
-		kv, err := it.Get()
-		if ulog.E(err) {
-			return -1, err
-		}
+	var kv baseKeyValue
+	for it.Next(&kv) {
 		v, err := apply(kv.Value)
 		if ulog.E(err) {
 			return -1, err
 		}
 
-		t.tx.Set(kv.Key, v)
-		listener.OnSet(UpdateEvent, table, kv.Key, v)
+		t.setValue(kv.FDBKey, v, true)
+		listener.OnUpdate(UpdateEvent, table, kv.FDBKey, kv.Value, v)
 
 		modifiedCount++
 	}
+	if err := it.Err(); err != nil {
+		return -1, err
+	}
 
 	log.Debug().Str("table", string(table)).Interface("Key", key).Msg("tx update")
 
@@ -484,28 +521,47 @@ func (t *ftx) UpdateRange(ctx context.Context, table []byte, lKey Key, rKey Key,
 	r := t.tx.GetRange(fdb.KeyRange{Begin: lk, End: rk}, fdb.RangeOptions{})
 
 	modifiedCount := int32(0)
-	it := r.Iterator()
-	for it.Advance() {
-		kv, err := it.Get()
-		if ulog.E(err) {
-			return -1, err
-		}
+	it := &fdbIterator{it: r.Iterator(), subspace: subspace.FromBytes(table)}
+	var kv baseKeyValue
+	for it.Next(&kv) {
 		v, err := apply(kv.Value)
 		if ulog.E(err) {
 			return -1, err
 		}
 
-		t.tx.Set(kv.Key, v)
-		listener.OnSet(UpdateRangeEvent, table, kv.Key, v)
+		t.setValue(kv.FDBKey, v, true)
+		listener.OnUpdate(UpdateRangeEvent, table, kv.FDBKey, kv.Value, v)
 
 		modifiedCount++
 	}
+	if err := it.Err(); err != nil {
+		return -1, err
+	}
 
 	log.Debug().Str("table", string(table)).Interface("lKey", lKey).Interface("rKey", rKey).Msg("tx update range")
 
 	return modifiedCount, nil
 }
 
+// setValue stores the value of the key, the values larger than maxValueSize are split in chunks stored after the key
+// in the same transaction. The chunks of the previous value of the key are cleared unless the key is new.
+func (t *ftx) setValue(k fdb.Key, value []byte, clearChunks bool) {
+	if clearChunks {
+		t.tx.ClearRange(chunkRange(k))
+	}
+
+	if len(value) <= maxValueSize {
+		t.tx.Set(k, value)
+		return
+	}
+
+	h, chunks := splitValue(value)
+	t.tx.Set(k, h.encode())
+	for i, c := range chunks {
+		t.tx.Set(chunkKey(k, i), c)
+	}
+}
+
 func (t *ftx) Read(_ context.Context, table []byte, key Key) (baseIterator, error) {
 	k, err := fdb.PrefixRange(getFDBKey(table, key))
 	if ulog.E(err) {
@@ -518,19 +574,7 @@ func (t *ftx) Read(_ context.Context, table []byte, key Key) (baseIterator, erro
 }
 
 func (t *ftx) ReadRange(_ context.Context, table []byte, lKey Key, rKey Key, isSnapshot bool) (baseIterator, error) {
-	lk := getFDBKey(table, lKey)
-	var rk fdb.Key
-	if rKey == nil {
-		// add a table boundary
-		rk1 := make([]byte, len(table)+1)
-		copy(rk1, table)
-		rk1[len(rk1)-1] = byte(0xFF)
-		rk = rk1
-	} else {
-		rk = getFDBKey(table, rKey)
-	}
-
-	kr := fdb.KeyRange{Begin: lk, End: rk}
+	kr := getFDBKeyRange(table, lKey, rKey)
 	ro := fdb.RangeOptions{}
 
 	var r fdb.RangeResult
@@ -575,11 +619,9 @@ func (t *ftx) Commit(_ context.Context) error {
 
 	log.Err(t.err).Msg("tx Commit")
 
-	var ep fdb.Error
-	if errors.As(t.err, &ep) {
-		if ep.Code == 1020 {
-			t.err = ErrConflictingTransaction
-		}
+	t.err = classifyError(t.err)
+	if t.err == ErrConflictingTransaction && t.reportConflicts {
+		t.err = t.conflictError()
 	}
 
 	t.tx.Cancel()
@@ -622,10 +664,43 @@ func (i *fdbIterator) Next(kv *baseKeyValue) bool {
 		return false
 	}
 
-	if !i.it.Advance() {
+	tkv, ok := i.advance()
+	for ok && isChunkKey(tkv.Key, i.last) {
+		tkv, ok = i.advance()
+	}
+	if !ok {
 		return false
 	}
 
+	t, err := i.subspace.Unpack(tkv.Key)
+	if ulog.E(err) {
+		i.err = err
+		return false
+	}
+
+	value := tkv.Value
+	if h, chunked := decodeChunkHeader(value); chunked {
+		if value, err = i.readChunks(tkv.Key, h); ulog.E(err) {
+			i.err = err
+			return false
+		}
+	}
+	i.last = tkv.Key
+
+	if kv != nil {
+		kv.Key = tupleToKey(&t)
+		kv.FDBKey = tkv.Key
+		kv.Value = value
+	}
+
+	return true
+}
+
+func (i *fdbIterator) advance() (fdb.KeyValue, bool) {
+	if !i.it.Advance() {
+		return fdb.KeyValue{}, false
+	}
+
 	tkv, err := i.it.Get()
 	if ulog.E(err) {
 		i.err = err
@@ -636,22 +711,30 @@ func (i *fdbIterator) Next(kv *baseKeyValue) bool {
 				i.err = ErrTransactionMaxDurationReached
 			}
 		}
-		return false
+		return fdb.KeyValue{}, false
 	}
 
-	t, err := i.subspace.Unpack(tkv.Key)
-	if ulog.E(err) {
-		i.err = err
-		return false
-	}
+	return tkv, true
+}
 
-	if kv != nil {
-		kv.Key = tupleToKey(&t)
-		kv.FDBKey = tkv.Key
-		kv.Value = tkv.Value
+// readChunks reads the chunks of the value of the key, that follow the key in the range.
+func (i *fdbIterator) readChunks(k fdb.Key, h chunkHeader) ([]byte, error) {
+	chunks := make([][]byte, 0, h.count)
+	for n := 0; n < h.count; n++ {
+		tkv, ok := i.advance()
+		if !ok {
+			if i.err != nil {
+				return nil, i.err
+			}
+			return nil, NewStoreError(ErrCodeCorruptedValue, "chunk %d of the value is missing", n)
+		}
+		if !bytes.Equal(tkv.Key, chunkKey(k, n)) {
+			return nil, NewStoreError(ErrCodeCorruptedValue, "chunk %d of the value is missing", n)
+		}
+		chunks = append(chunks, tkv.Value)
 	}
 
-	return true
+	return joinChunks(h, chunks)
 }
 
 func (i *fdbIterator) Err() error {
@@ -686,6 +769,23 @@ func getFDBKey(table []byte, key Key) fdb.Key {
 	return k
 }
 
+// getFDBKeyRange returns the range of the keys from lKey to rKey, the range ends at the table boundary if rKey is nil.
+func getFDBKeyRange(table []byte, lKey Key, rKey Key) fdb.KeyRange {
+	lk := getFDBKey(table, lKey)
+	var rk fdb.Key
+	if rKey == nil {
+		// add a table boundary
+		rk1 := make([]byte, len(table)+1)
+		copy(rk1, table)
+		rk1[len(rk1)-1] = byte(0xFF)
+		rk = rk1
+	} else {
+		rk = getFDBKey(table, rKey)
+	}
+
+	return fdb.KeyRange{Begin: lk, End: rk}
+}
+
 // getCtxTimeout returns timeout in ms if it's set in the context
 // returns 0 if timeout is not set
 // returns negative number if timeout has expired.
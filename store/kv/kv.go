@@ -39,6 +39,7 @@ type KV interface {
 	DeleteRange(ctx context.Context, table []byte, lKey Key, rKey Key) error
 	Read(ctx context.Context, table []byte, key Key) (Iterator, error)
 	ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool) (Iterator, error)
+	ReadRangeParallel(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool, ordered bool) (Iterator, error)
 	Update(ctx context.Context, table []byte, key Key, apply func(*internal.TableData) (*internal.TableData, error)) (int32, error)
 	UpdateRange(ctx context.Context, table []byte, lKey Key, rKey Key, apply func(*internal.TableData) (*internal.TableData, error)) (int32, error)
 	SetVersionstampedValue(ctx context.Context, key []byte, value []byte) error
@@ -60,6 +61,8 @@ type KeyValueStore interface {
 	DropTable(ctx context.Context, name []byte) error
 	GetInternalDatabase() (interface{}, error) // TODO: CDC remove workaround
 	TableSize(ctx context.Context, name []byte) (int64, error)
+	TableSizeExact(ctx context.Context, table []byte, prefix Key, rowLimit int64) (int64, int64, error)
+	TableRangeSizes(ctx context.Context, name []byte, buckets int) ([]RangeSize, error)
 }
 
 type Iterator interface {
@@ -157,6 +160,22 @@ func (m *KeyValueStoreImplWithMetrics) TableSize(ctx context.Context, name []byt
 	return
 }
 
+func (m *KeyValueStoreImplWithMetrics) TableSizeExact(ctx context.Context, table []byte, prefix Key, rowLimit int64) (size int64, rows int64, err error) {
+	m.measure(ctx, "TableSizeExact", func() error {
+		size, rows, err = m.kv.TableSizeExact(ctx, table, prefix, rowLimit)
+		return err
+	})
+	return
+}
+
+func (m *KeyValueStoreImplWithMetrics) TableRangeSizes(ctx context.Context, name []byte, buckets int) (sizes []RangeSize, err error) {
+	m.measure(ctx, "TableRangeSizes", func() error {
+		sizes, err = m.kv.TableRangeSizes(ctx, name, buckets)
+		return err
+	})
+	return
+}
+
 func (m *KeyValueStoreImplWithMetrics) SetVersionstampedValue(ctx context.Context, key []byte, value []byte) (err error) {
 	m.measure(ctx, "SetVersionstampedValue", func() error {
 		err = m.kv.SetVersionstampedValue(ctx, key, value)
@@ -182,7 +201,7 @@ func (m *KeyValueStoreImplWithMetrics) Get(ctx context.Context, key []byte, isSn
 }
 
 func (k *KeyValueStoreImpl) Insert(ctx context.Context, table []byte, key Key, data *internal.TableData) error {
-	enc, err := internal.Encode(data)
+	enc, err := encode(ctx, data)
 	if err != nil {
 		return err
 	}
@@ -200,7 +219,7 @@ func (m *KeyValueStoreImplWithMetrics) Insert(ctx context.Context, table []byte,
 }
 
 func (k *KeyValueStoreImpl) Replace(ctx context.Context, table []byte, key Key, data *internal.TableData, isUpdate bool) error {
-	enc, err := internal.Encode(data)
+	enc, err := encode(ctx, data)
 	if err != nil {
 		return err
 	}
@@ -252,6 +271,24 @@ func (m *KeyValueStoreImplWithMetrics) ReadRange(ctx context.Context, table []by
 	return
 }
 
+func (k *KeyValueStoreImpl) ReadRangeParallel(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool, ordered bool) (Iterator, error) {
+	iter, err := k.fdbkv.ReadRangeParallel(ctx, table, lkey, rkey, isSnapshot, ordered)
+	if err != nil {
+		return nil, err
+	}
+	return &IteratorImpl{
+		baseIterator: iter,
+	}, nil
+}
+
+func (m *KeyValueStoreImplWithMetrics) ReadRangeParallel(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool, ordered bool) (it Iterator, err error) {
+	m.measure(ctx, "ReadRangeParallel", func() error {
+		it, err = m.kv.ReadRangeParallel(ctx, table, lkey, rkey, isSnapshot, ordered)
+		return err
+	})
+	return
+}
+
 func (k *KeyValueStoreImpl) Update(ctx context.Context, table []byte, key Key, apply func(*internal.TableData) (*internal.TableData, error)) (int32, error) {
 	return k.fdbkv.Update(ctx, table, key, func(existing []byte) ([]byte, error) {
 		decoded, err := internal.Decode(existing)
@@ -264,7 +301,7 @@ func (k *KeyValueStoreImpl) Update(ctx context.Context, table []byte, key Key, a
 			return nil, err
 		}
 
-		encoded, err := internal.Encode(newData)
+		encoded, err := encode(ctx, newData)
 		if err != nil {
 			return nil, err
 		}
@@ -293,7 +330,7 @@ func (k *KeyValueStoreImpl) UpdateRange(ctx context.Context, table []byte, lKey
 			return nil, err
 		}
 
-		encoded, err := internal.Encode(newData)
+		encoded, err := encode(ctx, newData)
 		if err != nil {
 			return nil, err
 		}
@@ -416,7 +453,7 @@ func (m *TxImplWithMetrics) IsRetriable() bool {
 }
 
 func (tx *TxImpl) Insert(ctx context.Context, table []byte, key Key, data *internal.TableData) error {
-	enc, err := internal.Encode(data)
+	enc, err := encode(ctx, data)
 	if err != nil {
 		return err
 	}
@@ -433,7 +470,7 @@ func (m *TxImplWithMetrics) Insert(ctx context.Context, table []byte, key Key, d
 }
 
 func (tx *TxImpl) Replace(ctx context.Context, table []byte, key Key, data *internal.TableData, isUpdate bool) error {
-	enc, err := internal.Encode(data)
+	enc, err := encode(ctx, data)
 	if err != nil {
 		return err
 	}
@@ -485,6 +522,24 @@ func (m *TxImplWithMetrics) ReadRange(ctx context.Context, table []byte, lkey Ke
 	return
 }
 
+func (tx *TxImpl) ReadRangeParallel(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool, ordered bool) (Iterator, error) {
+	iter, err := tx.ftx.ReadRangeParallel(ctx, table, lkey, rkey, isSnapshot, ordered)
+	if err != nil {
+		return nil, err
+	}
+	return &IteratorImpl{
+		baseIterator: iter,
+	}, nil
+}
+
+func (m *TxImplWithMetrics) ReadRangeParallel(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool, ordered bool) (it Iterator, err error) {
+	m.measure(ctx, "ReadRangeParallel", func() error {
+		it, err = m.tx.ReadRangeParallel(ctx, table, lkey, rkey, isSnapshot, ordered)
+		return err
+	})
+	return
+}
+
 func (tx *TxImpl) Update(ctx context.Context, table []byte, key Key, apply func(*internal.TableData) (*internal.TableData, error)) (int32, error) {
 	return tx.ftx.Update(ctx, table, key, func(existing []byte) ([]byte, error) {
 		decoded, err := internal.Decode(existing)
@@ -497,7 +552,7 @@ func (tx *TxImpl) Update(ctx context.Context, table []byte, key Key, apply func(
 			return nil, err
 		}
 
-		encoded, err := internal.Encode(newData)
+		encoded, err := encode(ctx, newData)
 		if err != nil {
 			return nil, err
 		}
@@ -526,7 +581,7 @@ func (tx *TxImpl) UpdateRange(ctx context.Context, table []byte, lKey Key, rKey
 			return nil, err
 		}
 
-		encoded, err := internal.Encode(newData)
+		encoded, err := encode(ctx, newData)
 		if err != nil {
 			return nil, err
 		}
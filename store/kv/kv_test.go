@@ -607,6 +607,99 @@ func testSetVersionstampedValue(t *testing.T, kv baseKVStore) {
 	require.NoError(t, tx.Commit(ctx))
 }
 
+func testReadConflictKeys(t *testing.T, kv baseKVStore) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	table := []byte("t1_read_conflict")
+	conflictKey := []byte("t1_read_conflict_key")
+	require.NoError(t, kv.DropTable(ctx, table))
+
+	tx, err := kv.BeginTx(WithReadConflictKeys(ctx, conflictKey))
+	require.NoError(t, err)
+	_, err = tx.Read(ctx, table, BuildKey("p1"))
+	require.NoError(t, err)
+
+	// the key is written by another transaction while the transaction runs
+	other, err := kv.BeginTx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, other.Replace(ctx, conflictKey, nil, []byte("value"), false))
+	require.NoError(t, other.Commit(ctx))
+
+	require.NoError(t, tx.Replace(ctx, table, BuildKey("p1"), []byte("value"), false))
+	require.Equal(t, ErrConflictingTransaction, tx.Commit(ctx))
+
+	require.NoError(t, kv.DropTable(ctx, table))
+	require.NoError(t, kv.DropTable(ctx, conflictKey))
+}
+
+func testReadVersion(t *testing.T, kv baseKVStore) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	table := []byte("t1_read_version")
+	require.NoError(t, kv.DropTable(ctx, table))
+	require.NoError(t, kv.Replace(ctx, table, BuildKey("p1"), []byte("value1"), false))
+
+	pinned := WithReadVersion(ctx, &ReadVersion{})
+	require.True(t, IsReadVersionPinned(pinned))
+	require.False(t, IsReadVersionPinned(ctx))
+
+	read := func() string {
+		tx, err := kv.BeginTx(pinned)
+		require.NoError(t, err)
+		defer func() { _ = tx.Rollback(ctx) }()
+
+		it, err := tx.Read(ctx, table, BuildKey("p1"))
+		require.NoError(t, err)
+		var v baseKeyValue
+		require.True(t, it.Next(&v))
+		return string(v.Value)
+	}
+	require.Equal(t, "value1", read())
+
+	// the next transactions read at the version of the first one
+	require.NoError(t, kv.Replace(ctx, table, BuildKey("p1"), []byte("value2"), false))
+	require.Equal(t, "value1", read())
+
+	require.NoError(t, kv.DropTable(ctx, table))
+}
+
+func testTableSizeExact(t *testing.T, kv *fdbkv) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	chunkKeys := exactSizeChunkKeys
+	defer func() { exactSizeChunkKeys = chunkKeys }()
+	// the rows are read across several transactions
+	exactSizeChunkKeys = 3
+
+	table := []byte("t1_size_exact")
+	require.NoError(t, kv.DropTable(ctx, table))
+
+	var expected int64
+	for i := 0; i < 10; i++ {
+		require.NoError(t, kv.Replace(ctx, table, BuildKey("data", i), []byte("value"), false))
+		expected += int64(len(getFDBKey(table, BuildKey("data", i))) + len("value"))
+	}
+	// the keys outside the prefix are not counted
+	require.NoError(t, kv.Replace(ctx, table, BuildKey("other", 1), []byte("value"), false))
+
+	size, rows, err := kv.TableSizeExact(ctx, table, BuildKey("data"), 100)
+	require.NoError(t, err)
+	require.Equal(t, expected, size)
+	require.Equal(t, int64(10), rows)
+
+	_, rows, err = kv.TableSizeExact(ctx, table, nil, 100)
+	require.NoError(t, err)
+	require.Equal(t, int64(11), rows)
+
+	_, _, err = kv.TableSizeExact(ctx, table, BuildKey("data"), 9)
+	require.Equal(t, ErrRowLimitExceeded, err)
+
+	require.NoError(t, kv.DropTable(ctx, table))
+}
+
 func TestKVFDB(t *testing.T) {
 	cfg, err := config.GetTestFDBConfig("../..")
 	require.NoError(t, err)
@@ -642,6 +735,15 @@ func TestKVFDB(t *testing.T) {
 	t.Run("TestSetVersionstampedValue", func(t *testing.T) {
 		testSetVersionstampedValue(t, kv)
 	})
+	t.Run("TestReadConflictKeys", func(t *testing.T) {
+		testReadConflictKeys(t, kv)
+	})
+	t.Run("TestReadVersion", func(t *testing.T) {
+		testReadVersion(t, kv)
+	})
+	t.Run("TestTableSizeExact", func(t *testing.T) {
+		testTableSizeExact(t, kv)
+	})
 }
 
 func TestGetCtxTimeout(t *testing.T) {
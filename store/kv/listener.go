@@ -39,8 +39,10 @@ type EventListenerCtxKey struct{}
 type EventListener interface {
 	// OnSet buffers insert/replace/update events
 	OnSet(op string, table []byte, key []byte, data []byte)
-	// OnClearRange buffers delete events
-	OnClearRange(op string, table []byte, lKey []byte, rKey []byte)
+	// OnUpdate buffers update events of the keys that are read before being changed, along with their old value
+	OnUpdate(op string, table []byte, key []byte, before []byte, data []byte)
+	// OnClearRange buffers delete events, along with the old value of the key if it is known
+	OnClearRange(op string, table []byte, lKey []byte, rKey []byte, before []byte)
 	// GetEvents is used to access buffered events. These events may be shared by different participants callers are
 	// strongly discourage to modify the event and if needed copy it to some other buffer. Once transaction completes
 	// session may discard all the buffered events.
@@ -54,7 +56,10 @@ type Event struct {
 	LKey  []byte `json:",omitempty"`
 	RKey  []byte `json:",omitempty"`
 	Data  []byte `json:",omitempty"`
-	Last  bool
+	// Before is the value of the key before the change, it is set for the updates of the keys that are read before
+	// being changed and for the writes done with a pre-image, see WithPreImage.
+	Before []byte `json:",omitempty"`
+	Last   bool
 }
 
 type DefaultListener struct {
@@ -79,17 +84,32 @@ func (l *DefaultListener) OnSet(op string, table []byte, key []byte, data []byte
 	})
 }
 
-func (l *DefaultListener) OnClearRange(op string, table []byte, lKey []byte, rKey []byte) {
+func (l *DefaultListener) OnUpdate(op string, table []byte, key []byte, before []byte, data []byte) {
 	if l.skip(table) {
 		return
 	}
 
 	l.Events = append(l.Events, &Event{
-		Op:    op,
-		Table: table,
-		Key:   lKey,
-		LKey:  lKey,
-		RKey:  rKey,
+		Op:     op,
+		Table:  table,
+		Key:    key,
+		Data:   data,
+		Before: before,
+	})
+}
+
+func (l *DefaultListener) OnClearRange(op string, table []byte, lKey []byte, rKey []byte, before []byte) {
+	if l.skip(table) {
+		return
+	}
+
+	l.Events = append(l.Events, &Event{
+		Op:     op,
+		Table:  table,
+		Key:    lKey,
+		LKey:   lKey,
+		RKey:   rKey,
+		Before: before,
 	})
 }
 
@@ -99,9 +119,10 @@ func (l *DefaultListener) GetEvents() []*Event {
 
 type NoopEventListener struct{}
 
-func (l *NoopEventListener) OnSet(op string, table []byte, key []byte, data []byte)         {}
-func (l *NoopEventListener) OnClearRange(op string, table []byte, lKey []byte, rKey []byte) {}
-func (l *NoopEventListener) GetEvents() []*Event                                            { return nil }
+func (l *NoopEventListener) OnSet(op string, table []byte, key []byte, data []byte) {}
+func (l *NoopEventListener) OnUpdate(string, []byte, []byte, []byte, []byte)        {}
+func (l *NoopEventListener) OnClearRange(string, []byte, []byte, []byte, []byte)    {}
+func (l *NoopEventListener) GetEvents() []*Event                                    { return nil }
 
 func WrapEventListenerCtx(ctx context.Context) context.Context {
 	return context.WithValue(ctx, EventListenerCtxKey{}, &DefaultListener{})
@@ -117,3 +138,19 @@ func GetEventListener(ctx context.Context) EventListener {
 
 	return &NoopEventListener{}
 }
+
+type preImageCtxKey struct{}
+
+// WithPreImage attaches the current value of a key, already read by the caller, to the context of the write that
+// replaces or deletes the key. The change event of the write carries the value as the value before the change.
+func WithPreImage(ctx context.Context, value []byte) context.Context {
+	return context.WithValue(ctx, preImageCtxKey{}, value)
+}
+
+func getPreImage(ctx context.Context) []byte {
+	if value, ok := ctx.Value(preImageCtxKey{}).([]byte); ok {
+		return value
+	}
+
+	return nil
+}
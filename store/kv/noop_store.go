@@ -44,6 +44,13 @@ func (n *NoopKVStore) CreateTable(_ context.Context, _ []byte) error        { re
 func (n *NoopKVStore) DropTable(_ context.Context, _ []byte) error          { return nil }
 func (n *NoopKVStore) GetInternalDatabase() (interface{}, error)            { return nil, nil }
 func (n *NoopKVStore) TableSize(_ context.Context, _ []byte) (int64, error) { return 0, nil }
+func (n *NoopKVStore) TableSizeExact(_ context.Context, _ []byte, _ Key, _ int64) (int64, int64, error) {
+	return 0, 0, nil
+}
+
+func (n *NoopKVStore) TableRangeSizes(_ context.Context, _ []byte, _ int) ([]RangeSize, error) {
+	return nil, nil
+}
 
 type NoopKV struct{}
 
@@ -64,6 +71,10 @@ func (n *NoopKV) ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key
 	return &NoopIterator{}, nil
 }
 
+func (n *NoopKV) ReadRangeParallel(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool, ordered bool) (Iterator, error) {
+	return &NoopIterator{}, nil
+}
+
 func (n *NoopKV) Update(ctx context.Context, table []byte, key Key, apply func(*internal.TableData) (*internal.TableData, error)) (int32, error) {
 	return 0, nil
 }
@@ -0,0 +1,305 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/server/config"
+)
+
+const defaultScanShardBuffer = 1000
+
+// scanPolicy configures the parallel scans of the ranges.
+type scanPolicy struct {
+	parallelism int
+	shardBuffer int
+}
+
+func newScanPolicy(cfg *config.FoundationDBConfig) *scanPolicy {
+	p := &scanPolicy{
+		parallelism: cfg.ScanParallelism,
+		shardBuffer: cfg.ScanShardBuffer,
+	}
+	if p.shardBuffer <= 0 {
+		p.shardBuffer = defaultScanShardBuffer
+	}
+	return p
+}
+
+// ReadRangeParallel reads the range like ReadRange, but it splits the range along the boundaries of the shards of the
+// database and reads the shards concurrently. The values are returned in the order of their keys if ordered is set,
+// otherwise the values of the shards are interleaved. The scan is serial if the parallelism is not configured or the
+// range is in a single shard.
+func (t *ftx) ReadRangeParallel(ctx context.Context, table []byte, lKey Key, rKey Key, isSnapshot bool, ordered bool) (baseIterator, error) {
+	if t.d.scan.parallelism <= 1 {
+		return t.ReadRange(ctx, table, lKey, rKey, isSnapshot)
+	}
+
+	kr := getFDBKeyRange(table, lKey, rKey)
+	readVersion, err := t.tx.GetReadVersion().Get()
+	if err != nil {
+		return nil, err
+	}
+	boundaries, err := t.d.db.LocalityGetBoundaryKeys(kr, 0, readVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	shards := splitRange(kr, boundaries)
+	if len(shards) == 1 {
+		return t.ReadRange(ctx, table, lKey, rKey, isSnapshot)
+	}
+
+	log.Trace().Str("table", string(table)).Int("shards", len(shards)).Bool("ordered", ordered).Msg("tx parallel read range")
+
+	s := subspace.FromBytes(table)
+	return newParallelIterator(ctx, shards, func(r fdb.KeyRange) baseIterator {
+		var res fdb.RangeResult
+		if isSnapshot {
+			res = t.tx.Snapshot().GetRange(r, fdb.RangeOptions{})
+		} else {
+			res = t.tx.GetRange(r, fdb.RangeOptions{})
+		}
+		return &fdbIterator{it: res.Iterator(), subspace: s}
+	}, t.d.scan.parallelism, t.d.scan.shardBuffer, ordered), nil
+}
+
+func (d *fdbkv) ReadRangeParallel(ctx context.Context, table []byte, lKey Key, rKey Key, isSnapshot bool, ordered bool) (baseIterator, error) {
+	tx, err := d.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	it, err := tx.(*ftx).ReadRangeParallel(ctx, table, lKey, rKey, isSnapshot, ordered)
+	if err != nil {
+		return nil, err
+	}
+	return &fdbIteratorTxCloser{it, tx}, nil
+}
+
+// splitRange splits the range at the boundary keys inside of it. The boundaries are moved past the chunks of a value,
+// so that a chunked value is read by a single shard.
+func splitRange(kr fdb.KeyRange, boundaries []fdb.Key) []fdb.KeyRange {
+	begin, end := kr.Begin.FDBKey(), kr.End.FDBKey()
+
+	var shards []fdb.KeyRange
+	for _, b := range boundaries {
+		b = alignBoundary(b)
+		if bytes.Compare(b, begin) <= 0 || bytes.Compare(b, end) >= 0 {
+			continue
+		}
+		shards = append(shards, fdb.KeyRange{Begin: begin, End: b})
+		begin = b
+	}
+
+	return append(shards, fdb.KeyRange{Begin: begin, End: end})
+}
+
+// alignBoundary returns the end of the chunks of the value if the boundary is the key of one of its chunks.
+func alignBoundary(b fdb.Key) fdb.Key {
+	i := bytes.LastIndex(b, chunkKeySuffix)
+	if i <= 0 {
+		return b
+	}
+	if t, err := tuple.Unpack(b[i+len(chunkKeySuffix):]); err != nil || len(t) != 1 {
+		return b
+	} else if _, ok := t[0].(int64); !ok {
+		return b
+	}
+
+	return chunkRange(b[:i]).End.FDBKey()
+}
+
+// scanShard is a shard of a parallel scan, err is set before out is closed.
+type scanShard struct {
+	r   fdb.KeyRange
+	out chan baseKeyValue
+	err error
+}
+
+// parallelIterator reads the shards of a range concurrently. At most parallelism shards are read or buffered at any
+// time and every shard buffers at most shardBuffer values, so that the memory is bounded whatever the size of the
+// range. The reads are stopped once the iterator is exhausted, fails or the context is done.
+type parallelIterator struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	ordered bool
+	shards  []*scanShard
+	// slots limits the number of shards in flight, a slot is released once the shard is consumed if the iterator is
+	// ordered or once the shard is read otherwise.
+	slots chan struct{}
+	// merged has the values of all the shards if the iterator is not ordered.
+	merged  chan baseKeyValue
+	current int
+
+	mu       sync.Mutex
+	shardErr error
+	err      error
+}
+
+func newParallelIterator(ctx context.Context, ranges []fdb.KeyRange, open func(fdb.KeyRange) baseIterator, parallelism int, shardBuffer int, ordered bool) *parallelIterator {
+	ctx, cancel := context.WithCancel(ctx)
+	it := &parallelIterator{
+		ctx:     ctx,
+		cancel:  cancel,
+		ordered: ordered,
+		slots:   make(chan struct{}, parallelism),
+	}
+	for _, r := range ranges {
+		s := &scanShard{r: r}
+		if ordered {
+			s.out = make(chan baseKeyValue, shardBuffer)
+		}
+		it.shards = append(it.shards, s)
+	}
+	if !ordered {
+		it.merged = make(chan baseKeyValue, parallelism*shardBuffer)
+	}
+
+	go it.dispatch(open)
+
+	return it
+}
+
+// dispatch reads the shards in their order as soon as a slot is available.
+func (it *parallelIterator) dispatch(open func(fdb.KeyRange) baseIterator) {
+	var wg sync.WaitGroup
+	for i, s := range it.shards {
+		select {
+		case it.slots <- struct{}{}:
+		case <-it.ctx.Done():
+			it.abort(it.shards[i:], it.ctx.Err())
+			wg.Wait()
+			if !it.ordered {
+				close(it.merged)
+			}
+			return
+		}
+
+		wg.Add(1)
+		go func(s *scanShard) {
+			defer wg.Done()
+
+			out := s.out
+			if !it.ordered {
+				out = it.merged
+			}
+			err := it.read(open(s.r), out)
+			if it.ordered {
+				s.err = err
+				close(s.out)
+				return
+			}
+
+			<-it.slots
+			if err != nil {
+				it.fail(err)
+			}
+		}(s)
+	}
+
+	if !it.ordered {
+		wg.Wait()
+		close(it.merged)
+	}
+}
+
+func (it *parallelIterator) read(shard baseIterator, out chan baseKeyValue) error {
+	var kv baseKeyValue
+	for shard.Next(&kv) {
+		select {
+		case out <- kv:
+		case <-it.ctx.Done():
+			return it.ctx.Err()
+		}
+		kv = baseKeyValue{}
+	}
+
+	return shard.Err()
+}
+
+// abort fails the shards that are not read.
+func (it *parallelIterator) abort(shards []*scanShard, err error) {
+	if !it.ordered {
+		it.fail(err)
+		return
+	}
+	for _, s := range shards {
+		s.err = err
+		close(s.out)
+	}
+}
+
+// fail records the first error of the shards of an iterator that is not ordered and stops the other shards.
+func (it *parallelIterator) fail(err error) {
+	it.mu.Lock()
+	if it.shardErr == nil {
+		it.shardErr = err
+	}
+	it.mu.Unlock()
+	it.cancel()
+}
+
+func (it *parallelIterator) Next(kv *baseKeyValue) bool {
+	if it.err != nil {
+		return false
+	}
+
+	if !it.ordered {
+		v, ok := <-it.merged
+		if !ok {
+			it.mu.Lock()
+			it.err = it.shardErr
+			it.mu.Unlock()
+			it.cancel()
+			return false
+		}
+		if kv != nil {
+			*kv = v
+		}
+		return true
+	}
+
+	for it.current < len(it.shards) {
+		s := it.shards[it.current]
+		if v, ok := <-s.out; ok {
+			if kv != nil {
+				*kv = v
+			}
+			return true
+		}
+		if s.err != nil {
+			it.err = s.err
+			it.cancel()
+			return false
+		}
+
+		it.current++
+		<-it.slots
+	}
+	it.cancel()
+
+	return false
+}
+
+func (it *parallelIterator) Err() error {
+	return it.err
+}
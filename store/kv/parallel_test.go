@@ -0,0 +1,257 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/server/config"
+)
+
+// testShards serves the shards of a range from memory and tracks the number of shards read at once.
+type testShards struct {
+	sync.Mutex
+
+	values  map[string][]baseKeyValue
+	fail    map[string]error
+	open    int
+	maxOpen int
+}
+
+type testShardIterator struct {
+	shards *testShards
+	values []baseKeyValue
+	err    error
+	done   bool
+}
+
+func newTestShards(shards int, values int) (*testShards, []fdb.KeyRange) {
+	s := &testShards{values: map[string][]baseKeyValue{}, fail: map[string]error{}}
+	var ranges []fdb.KeyRange
+	for i := 0; i < shards; i++ {
+		r := fdb.KeyRange{Begin: fdb.Key(fmt.Sprintf("%03d", i)), End: fdb.Key(fmt.Sprintf("%03d", i+1))}
+		for j := 0; j < values; j++ {
+			k := fmt.Sprintf("%03d/%03d", i, j)
+			s.values[string(r.Begin.FDBKey())] = append(s.values[string(r.Begin.FDBKey())], baseKeyValue{FDBKey: []byte(k)})
+		}
+		ranges = append(ranges, r)
+	}
+	return s, ranges
+}
+
+func (s *testShards) iterator(r fdb.KeyRange) baseIterator {
+	s.Lock()
+	defer s.Unlock()
+
+	s.open++
+	if s.open > s.maxOpen {
+		s.maxOpen = s.open
+	}
+	return &testShardIterator{shards: s, values: s.values[string(r.Begin.FDBKey())], err: s.fail[string(r.Begin.FDBKey())]}
+}
+
+func (i *testShardIterator) Next(kv *baseKeyValue) bool {
+	if len(i.values) == 0 || (i.err != nil && len(i.values) <= 5) {
+		if !i.done {
+			i.done = true
+			i.shards.Lock()
+			i.shards.open--
+			i.shards.Unlock()
+		}
+		return false
+	}
+	*kv = i.values[0]
+	i.values = i.values[1:]
+	return true
+}
+
+func (i *testShardIterator) Err() error {
+	return i.err
+}
+
+func readKeys(t *testing.T, it baseIterator) []string {
+	var (
+		kv   baseKeyValue
+		keys []string
+	)
+	for it.Next(&kv) {
+		keys = append(keys, string(kv.FDBKey))
+	}
+	return keys
+}
+
+func expectedKeys(s *testShards) []string {
+	var keys []string
+	for _, values := range s.values {
+		for _, v := range values {
+			keys = append(keys, string(v.FDBKey))
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestParallelIterator(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("ordered", func(t *testing.T) {
+		s, ranges := newTestShards(8, 100)
+		it := newParallelIterator(ctx, ranges, s.iterator, 3, 10, true)
+
+		// a slow consumer doesn't let the shards ahead be read past their buffers
+		var (
+			kv   baseKeyValue
+			keys []string
+		)
+		for it.Next(&kv) {
+			keys = append(keys, string(kv.FDBKey))
+			if len(keys)%100 == 1 {
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+		require.NoError(t, it.Err())
+		require.Equal(t, expectedKeys(s), keys)
+		require.LessOrEqual(t, s.maxOpen, 3)
+	})
+
+	t.Run("interleaved", func(t *testing.T) {
+		s, ranges := newTestShards(8, 100)
+		it := newParallelIterator(ctx, ranges, s.iterator, 3, 10, false)
+
+		keys := readKeys(t, it)
+		require.NoError(t, it.Err())
+		sort.Strings(keys)
+		require.Equal(t, expectedKeys(s), keys)
+		require.LessOrEqual(t, s.maxOpen, 3)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		for _, ordered := range []bool{true, false} {
+			s, ranges := newTestShards(4, 20)
+			failure := fmt.Errorf("shard failed")
+			s.fail["002"] = failure
+			it := newParallelIterator(ctx, ranges, s.iterator, 2, 5, ordered)
+
+			keys := readKeys(t, it)
+			require.Equal(t, failure, it.Err())
+			if ordered {
+				// the values of the shards before the failed one are returned
+				require.Equal(t, expectedKeys(s)[:40+15], keys)
+			}
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		for _, ordered := range []bool{true, false} {
+			s, ranges := newTestShards(4, 100)
+			ctx, cancel := context.WithCancel(ctx)
+			it := newParallelIterator(ctx, ranges, s.iterator, 2, 5, ordered)
+
+			var kv baseKeyValue
+			require.True(t, it.Next(&kv))
+			cancel()
+			for it.Next(&kv) {
+			}
+			require.Equal(t, context.Canceled, it.Err())
+		}
+	})
+}
+
+func TestSplitRange(t *testing.T) {
+	table := []byte("table")
+	kr := getFDBKeyRange(table, nil, nil)
+	k1 := getFDBKey(table, BuildKey("a", int64(1)))
+	k2 := getFDBKey(table, BuildKey("a", int64(2)))
+
+	require.Equal(t, []fdb.KeyRange{kr}, splitRange(kr, nil))
+	// the boundaries outside of the range are ignored
+	require.Equal(t, []fdb.KeyRange{
+		{Begin: kr.Begin, End: k1},
+		{Begin: k1, End: k2},
+		{Begin: k2, End: kr.End},
+	}, splitRange(kr, []fdb.Key{fdb.Key("a"), kr.Begin.FDBKey(), k1, k2, kr.End.FDBKey(), fdb.Key("z")}))
+
+	// a chunked value is read by a single shard
+	shards := splitRange(kr, []fdb.Key{chunkKey(k1, 1)})
+	require.Equal(t, []fdb.KeyRange{
+		{Begin: kr.Begin, End: chunkRange(k1).End},
+		{Begin: chunkRange(k1).End, End: kr.End},
+	}, shards)
+	require.Equal(t, -1, bytes.Compare(chunkKey(k1, 3), shards[0].End.FDBKey()))
+	require.Equal(t, 1, bytes.Compare(k2, shards[0].End.FDBKey()))
+	// the boundaries that only look like the key of a chunk are kept
+	notChunk := append(append(fdb.Key{}, k1...), chunkKeySuffix...)
+	require.Equal(t, notChunk, alignBoundary(notChunk))
+}
+
+// BenchmarkReadRangeParallel compares the serial and the parallel scans of a table of a few million keys.
+func BenchmarkReadRangeParallel(b *testing.B) {
+	cfg, err := config.GetTestFDBConfig("../..")
+	require.NoError(b, err)
+
+	const keys = 2000000
+	ctx := context.Background()
+	table := []byte("bench_parallel_scan")
+
+	kv, err := newFoundationDB(cfg)
+	require.NoError(b, err)
+	require.NoError(b, kv.DropTable(ctx, table))
+	defer func() { _ = kv.DropTable(ctx, table) }()
+
+	value, err := internal.Encode(internal.NewTableData([]byte(`{"field1": "this is a random string", "field2": 1}`)))
+	require.NoError(b, err)
+
+	batch, err := kv.Batch()
+	require.NoError(b, err)
+	for i := 0; i < keys; i++ {
+		require.NoError(b, batch.Replace(ctx, table, BuildKey(int64(i)), value, false))
+	}
+	require.NoError(b, batch.Commit(ctx))
+
+	for _, c := range []struct {
+		name        string
+		parallelism int
+		ordered     bool
+	}{
+		{"serial", 0, true},
+		{"ordered", 16, true},
+		{"interleaved", 16, false},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			kv.scan = newScanPolicy(&config.FoundationDBConfig{ScanParallelism: c.parallelism})
+			for n := 0; n < b.N; n++ {
+				// the scans are snapshot reads below the 5 seconds limit of the transactions
+				it, err := kv.ReadRangeParallel(ctx, table, nil, nil, true, c.ordered)
+				require.NoError(b, err)
+
+				count := 0
+				for it.Next(nil) {
+					count++
+				}
+				require.NoError(b, it.Err())
+				require.Equal(b, keys, count)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"sync"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+type readVersionCtxKey struct{}
+
+// ReadVersion is the read version shared by the transactions begun with a context, see WithReadVersion.
+type ReadVersion struct {
+	sync.Mutex
+
+	version int64
+}
+
+// WithReadVersion pins the read version of the transactions begun with the context. The first transaction reads at
+// the latest version of the database and the next ones at the same version, so that they all read the same snapshot
+// of the data. The reads at a version older than the MVCC window of the database, 5 seconds by default, fail with
+// ErrTransactionMaxDurationReached.
+func WithReadVersion(ctx context.Context, rv *ReadVersion) context.Context {
+	return context.WithValue(ctx, readVersionCtxKey{}, rv)
+}
+
+// IsReadVersionPinned returns true if the transactions begun with the context share their read version.
+func IsReadVersionPinned(ctx context.Context) bool {
+	return getReadVersion(ctx) != nil
+}
+
+func getReadVersion(ctx context.Context) *ReadVersion {
+	rv, _ := ctx.Value(readVersionCtxKey{}).(*ReadVersion)
+	return rv
+}
+
+// pin sets the read version of the transaction, or records the read version of the transaction if it is the first
+// one.
+func (rv *ReadVersion) pin(tx *fdb.Transaction) error {
+	rv.Lock()
+	defer rv.Unlock()
+
+	if rv.version != 0 {
+		tx.SetReadVersion(rv.version)
+		return nil
+	}
+
+	version, err := tx.GetReadVersion().Get()
+	if err != nil {
+		return err
+	}
+	rv.version = version
+	return nil
+}
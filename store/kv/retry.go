@@ -0,0 +1,127 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metrics"
+)
+
+const (
+	defaultMaxRetries = 10
+	defaultMinBackoff = 10 * time.Millisecond
+	defaultMaxBackoff = time.Second
+)
+
+// transientErrorCodes are the FoundationDB errors that are always retried, in addition to the ones OnError retries.
+var transientErrorCodes = map[int]struct{}{
+	1009: {}, // future_version
+	1020: {}, // not_committed
+	1031: {}, // transaction_timed_out
+}
+
+// retryPolicy decides whether the operations of the store are retried on their errors, and waits between the
+// retries. The retries stop once the context of the caller is done or its deadline is closer than the backoff.
+type retryPolicy struct {
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	// sleep waits for the delay or until the context is done
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func newRetryPolicy(cfg *config.FoundationDBConfig) *retryPolicy {
+	p := &retryPolicy{
+		maxRetries: cfg.MaxRetries,
+		minBackoff: cfg.MinBackoff,
+		maxBackoff: cfg.MaxBackoff,
+		sleep:      sleepWithContext,
+	}
+	if p.maxRetries <= 0 {
+		p.maxRetries = defaultMaxRetries
+	}
+	if p.minBackoff <= 0 {
+		p.minBackoff = defaultMinBackoff
+	}
+	if p.maxBackoff <= 0 {
+		p.maxBackoff = defaultMaxBackoff
+	}
+	if p.maxBackoff < p.minBackoff {
+		p.maxBackoff = p.minBackoff
+	}
+	return p
+}
+
+// retry returns true if the operation that failed with the error is retried, attempt is the number of retries so far.
+// onErrorRetried is true if OnError of the transaction retries the error, it has then already waited for the backoff
+// of FoundationDB, otherwise the backoff of the policy is waited for before returning.
+func (p *retryPolicy) retry(ctx context.Context, attempt int, err error, onErrorRetried bool) bool {
+	var ep fdb.Error
+	if !errors.As(err, &ep) {
+		return false
+	}
+	if _, transient := transientErrorCodes[ep.Code]; !transient && !onErrorRetried {
+		return false
+	}
+	if attempt >= p.maxRetries {
+		metrics.CountFdbRetriesExhausted(ep.Code)
+		return false
+	}
+
+	var delay time.Duration
+	if !onErrorRetried {
+		delay = p.backoff(attempt)
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+		return false
+	}
+
+	metrics.CountFdbRetry(ep.Code)
+	return delay == 0 || p.sleep(ctx, delay) == nil
+}
+
+// backoff returns the exponential backoff of the retry, it is jittered down to half of it.
+func (p *retryPolicy) backoff(attempt int) time.Duration {
+	d := p.minBackoff
+	for i := 0; i < attempt && d < p.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.maxBackoff {
+		d = p.maxBackoff
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1)) //nolint:gosec
+}
+
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
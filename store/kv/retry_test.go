@@ -0,0 +1,130 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/uber-go/tally"
+)
+
+func newTestRetryPolicy(maxRetries int) (*retryPolicy, *[]time.Duration) {
+	var slept []time.Duration
+	p := newRetryPolicy(&config.FoundationDBConfig{MaxRetries: maxRetries, MinBackoff: 10 * time.Millisecond, MaxBackoff: 40 * time.Millisecond})
+	p.sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return ctx.Err()
+	}
+	return p, &slept
+}
+
+func TestRetryPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("transient", func(t *testing.T) {
+		p, slept := newTestRetryPolicy(3)
+		for attempt := 0; attempt < 3; attempt++ {
+			require.True(t, p.retry(ctx, attempt, fdb.Error{Code: 1020}, false))
+		}
+		require.False(t, p.retry(ctx, 3, fdb.Error{Code: 1020}, false))
+
+		require.Len(t, *slept, 3)
+		for i, max := range []time.Duration{10, 20, 40} {
+			require.GreaterOrEqual(t, (*slept)[i], max*time.Millisecond/2)
+			require.LessOrEqual(t, (*slept)[i], max*time.Millisecond)
+		}
+	})
+
+	t.Run("on error", func(t *testing.T) {
+		p, slept := newTestRetryPolicy(3)
+		// OnError has already waited for the retries it accepts
+		require.True(t, p.retry(ctx, 0, fdb.Error{Code: 1007}, true))
+		require.Empty(t, *slept)
+		// the other errors are not retried
+		require.False(t, p.retry(ctx, 0, fdb.Error{Code: 2101}, false))
+		require.False(t, p.retry(ctx, 0, fmt.Errorf("not an fdb error"), false))
+		require.False(t, p.retry(ctx, 0, ErrDuplicateKey, false))
+	})
+
+	t.Run("context", func(t *testing.T) {
+		p, slept := newTestRetryPolicy(3)
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		require.False(t, p.retry(cancelled, 0, fdb.Error{Code: 1009}, false))
+
+		// the deadline is closer than the backoff
+		short, cancel := context.WithTimeout(ctx, time.Millisecond)
+		defer cancel()
+		require.False(t, p.retry(short, 0, fdb.Error{Code: 1009}, false))
+
+		long, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		require.True(t, p.retry(long, 0, fdb.Error{Code: 1009}, false))
+		require.Len(t, *slept, 1)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		p := newRetryPolicy(&config.FoundationDBConfig{})
+		require.Equal(t, defaultMaxRetries, p.maxRetries)
+		require.Equal(t, defaultMinBackoff, p.minBackoff)
+		require.Equal(t, defaultMaxBackoff, p.maxBackoff)
+	})
+}
+
+func TestRetryMetrics(t *testing.T) {
+	scope := tally.NewTestScope("", nil)
+	metrics.FdbRetryCount = scope
+	defer func() { metrics.FdbRetryCount = nil }()
+
+	p, _ := newTestRetryPolicy(1)
+	require.True(t, p.retry(context.Background(), 0, fdb.Error{Code: 1020}, false))
+	require.False(t, p.retry(context.Background(), 1, fdb.Error{Code: 1020}, false))
+	require.True(t, p.retry(context.Background(), 0, fdb.Error{Code: 1007}, true))
+
+	counters := scope.Snapshot().Counters()
+	require.Equal(t, int64(1), counters["retries+error_category=conflict,error_source=fdb,error_value=1020"].Value())
+	require.Equal(t, int64(1), counters["exhausted+error_category=conflict,error_source=fdb,error_value=1020"].Value())
+	require.Equal(t, int64(1), counters["retries+error_category=timeout,error_source=fdb,error_value=1007"].Value())
+}
+
+func TestClassifyError(t *testing.T) {
+	require.Equal(t, ErrConflictingTransaction, classifyError(fdb.Error{Code: 1020}))
+	require.Equal(t, ErrDuplicateKey, classifyError(ErrDuplicateKey))
+
+	for code, expected := range map[int]api.Code{
+		1031: api.Code_DEADLINE_EXCEEDED,
+		1009: api.Code_UNAVAILABLE,
+		2101: api.Code_INVALID_ARGUMENT,
+		1500: api.Code_INTERNAL,
+	} {
+		err := classifyError(fdb.Error{Code: code})
+		require.Equal(t, expected, err.(*FdbError).Code(), code)
+
+		// the fdb error is kept for the metrics
+		var ep fdb.Error
+		require.ErrorAs(t, err, &ep)
+		require.Equal(t, code, ep.Code)
+		require.Equal(t, IsTimedOut(fdb.Error{Code: code}), IsTimedOut(err))
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/rs/zerolog/log"
+)
+
+// RangeSize is the estimated size of a sub-range of a table.
+type RangeSize struct {
+	Begin []byte
+	End   []byte
+	Size  int64
+}
+
+// exactSizeChunkKeys is the number of keys TableSizeExact reads in a transaction, the table is read in several
+// transactions so that the reads of a large table don't exceed the duration of a transaction.
+var exactSizeChunkKeys = 10000
+
+// TableSizeExact computes the size of the keys of the table starting with the prefix by reading them with their values,
+// unlike TableSize that returns the estimate of the storage engine. The size includes the chunks of the chunked values.
+// It returns the number of rows read, and stops with ErrRowLimitExceeded once more than rowLimit rows are read. The
+// keys are read in chunks of exactSizeChunkKeys keys, each in its own transaction, so the size is not the size of the
+// table at a single version. Like TableSize, it works with the prefix of the table names when the prefix is empty.
+func (d *fdbkv) TableSizeExact(ctx context.Context, table []byte, prefix Key, rowLimit int64) (int64, int64, error) {
+	kr, err := fdb.PrefixRange(getFDBKey(table, prefix))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var (
+		sz, rows int64
+		// last is the key of the last row read, the keys of its chunks follow it
+		last fdb.Key
+	)
+	begin := kr.Begin
+	for {
+		var (
+			chunkSize, chunkRows int64
+			chunkLast, next      fdb.Key
+			keys                 int
+		)
+		_, err = d.txWithRetry(ctx, func(tr fdb.Transaction) (interface{}, error) {
+			chunkSize, chunkRows, chunkLast, keys = 0, 0, last, 0
+			it := tr.Snapshot().GetRange(fdb.KeyRange{Begin: begin, End: kr.End}, fdb.RangeOptions{
+				Limit: exactSizeChunkKeys,
+				Mode:  fdb.StreamingModeWantAll,
+			}).Iterator()
+			for it.Advance() {
+				kv, err := it.Get()
+				if err != nil {
+					return nil, err
+				}
+				keys++
+				next = kv.Key
+				chunkSize += int64(len(kv.Key) + len(kv.Value))
+				if isChunkKey(kv.Key, chunkLast) {
+					continue
+				}
+				if chunkRows++; rows+chunkRows > rowLimit {
+					return nil, ErrRowLimitExceeded
+				}
+				chunkLast = kv.Key
+			}
+			return nil, nil
+		})
+		if err != nil {
+			log.Err(err).Str("table", string(table)).Int64("row_limit", rowLimit).Msg("exact table size")
+			return 0, 0, err
+		}
+
+		sz, rows, last = sz+chunkSize, rows+chunkRows, chunkLast
+		if keys < exactSizeChunkKeys {
+			return sz, rows, nil
+		}
+		// the next chunk starts right after the last key read
+		begin = append(next[:len(next):len(next)], 0x00)
+	}
+}
+
+// TableRangeSizes splits the table along the boundaries of the shards of the database in at most buckets sub-ranges
+// of contiguous shards, and returns the estimated size of every sub-range. A table stored in a single shard has a
+// single sub-range.
+func (d *fdbkv) TableRangeSizes(ctx context.Context, name []byte, buckets int) ([]RangeSize, error) {
+	begin, end := subspace.FromBytes(name).FDBRangeKeys()
+	kr := fdb.KeyRange{Begin: begin, End: end}
+
+	var sizes []RangeSize
+	_, err := d.txWithRetry(ctx, func(tr fdb.Transaction) (interface{}, error) {
+		readVersion, err := tr.GetReadVersion().Get()
+		if err != nil {
+			return nil, err
+		}
+		boundaries, err := d.db.LocalityGetBoundaryKeys(kr, 0, readVersion)
+		if err != nil {
+			return nil, err
+		}
+
+		ranges := mergeShards(splitRange(kr, boundaries), buckets)
+		futures := make([]fdb.FutureInt64, 0, len(ranges))
+		for _, r := range ranges {
+			futures = append(futures, tr.GetEstimatedRangeSizeBytes(r))
+		}
+
+		sizes = make([]RangeSize, 0, len(ranges))
+		for i, f := range futures {
+			sz, err := f.Get()
+			if err != nil {
+				return nil, err
+			}
+			sizes = append(sizes, RangeSize{Begin: ranges[i].Begin.FDBKey(), End: ranges[i].End.FDBKey(), Size: sz})
+		}
+		return nil, nil
+	})
+	if err != nil {
+		log.Err(err).Str("name", string(name)).Int("buckets", buckets).Msg("table range sizes")
+		return nil, err
+	}
+
+	return sizes, nil
+}
+
+// mergeShards merges the contiguous shards in at most buckets ranges, the ranges have the same number of shards give or
+// take one.
+func mergeShards(shards []fdb.KeyRange, buckets int) []fdb.KeyRange {
+	if buckets <= 0 || len(shards) <= buckets {
+		return shards
+	}
+
+	merged := make([]fdb.KeyRange, 0, buckets)
+	for i := 0; i < buckets; i++ {
+		first, last := i*len(shards)/buckets, (i+1)*len(shards)/buckets-1
+		merged = append(merged, fdb.KeyRange{Begin: shards[first].Begin, End: shards[last].End})
+	}
+
+	return merged
+}
@@ -0,0 +1,42 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeShards(t *testing.T) {
+	var shards []fdb.KeyRange
+	for i := 0; i < 10; i++ {
+		shards = append(shards, fdb.KeyRange{Begin: fdb.Key(fmt.Sprintf("%02d", i)), End: fdb.Key(fmt.Sprintf("%02d", i+1))})
+	}
+
+	require.Equal(t, shards, mergeShards(shards, 10))
+	require.Equal(t, shards, mergeShards(shards, 20))
+	require.Equal(t, shards[:1], mergeShards(shards[:1], 3))
+
+	// the ranges are contiguous and cover all the shards
+	require.Equal(t, []fdb.KeyRange{
+		{Begin: fdb.Key("00"), End: fdb.Key("03")},
+		{Begin: fdb.Key("03"), End: fdb.Key("06")},
+		{Begin: fdb.Key("06"), End: fdb.Key("10")},
+	}, mergeShards(shards, 3))
+	require.Equal(t, []fdb.KeyRange{{Begin: fdb.Key("00"), End: fdb.Key("10")}}, mergeShards(shards, 1))
+}
@@ -0,0 +1,253 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/server/config"
+)
+
+// BatchingStore buffers the index operations of every collection and sends them to the underlying store in bulk,
+// either once the buffer has MaxDocuments operations or FlushInterval after the first buffered operation. The calls
+// to IndexDocuments and DeleteDocuments still return the outcome of their own documents, they block until the batch
+// that carries them is flushed.
+//
+// The operations of a collection are flushed in the order they are buffered, consecutive operations with the same
+// action are grouped in a single import, so a delete following an upsert of the same key is never reordered.
+type BatchingStore struct {
+	Store
+
+	maxDocuments  int
+	flushInterval time.Duration
+
+	mu       sync.Mutex
+	closed   bool
+	batchers map[string]*batcher
+	wg       sync.WaitGroup
+}
+
+// NewBatchingStore returns the store that batches the index operations on top of the given store. Close must be
+// called on shutdown to flush the buffered operations.
+func NewBatchingStore(store Store, cfg *config.SearchIndexBatchConfig) *BatchingStore {
+	maxDocuments := cfg.MaxDocuments
+	if maxDocuments <= 0 {
+		maxDocuments = 1
+	}
+
+	return &BatchingStore{
+		Store:         store,
+		maxDocuments:  maxDocuments,
+		flushInterval: cfg.FlushInterval,
+		batchers:      make(map[string]*batcher),
+	}
+}
+
+// batchOp is a single buffered operation, the action is empty for a delete.
+type batchOp struct {
+	action string
+	key    string
+	doc    []byte
+	done   chan error
+}
+
+// batcher buffers the operations of a collection. Its lock serializes the senders of the collection, so that the
+// operations of a caller are not interleaved with the ones of another caller, and guards the closing of the channel.
+// A sender blocked on a full buffer only holds the lock of its collection.
+type batcher struct {
+	table string
+	ops   chan *batchOp
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (b *BatchingStore) IndexDocuments(ctx context.Context, table string, documents io.Reader, options IndexDocumentsOptions) error {
+	var ops []*batchOp
+	scanner := bufio.NewScanner(documents)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		doc := make([]byte, len(scanner.Bytes()))
+		copy(doc, scanner.Bytes())
+		ops = append(ops, &batchOp{action: options.Action, doc: doc, done: make(chan error, 1)})
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return b.enqueue(ctx, table, ops)
+}
+
+func (b *BatchingStore) DeleteDocuments(ctx context.Context, table string, key string) error {
+	return b.enqueue(ctx, table, []*batchOp{{key: key, done: make(chan error, 1)}})
+}
+
+// enqueue buffers the operations and waits for them to be flushed. The first failure is returned, a cancelled context
+// stops the wait but the operations are still flushed as they are already committed to the database.
+func (b *BatchingStore) enqueue(ctx context.Context, table string, ops []*batchOp) error {
+	b.mu.Lock()
+	bt, ok := b.batchers[table]
+	if !ok && !b.closed {
+		bt = &batcher{table: table, ops: make(chan *batchOp, b.maxDocuments)}
+		b.batchers[table] = bt
+		b.wg.Add(1)
+		go b.run(bt)
+	}
+	b.mu.Unlock()
+
+	if bt != nil {
+		bt.mu.Lock()
+		if !bt.closed {
+			for _, op := range ops {
+				bt.ops <- op
+			}
+			bt.mu.Unlock()
+			return firstError(ctx, ops)
+		}
+		bt.mu.Unlock()
+	}
+
+	// the buffers are already drained, the operations go directly to the store
+	flushOps(b.Store, table, ops)
+	return firstError(ctx, ops)
+}
+
+func firstError(ctx context.Context, ops []*batchOp) error {
+	var first error
+	for _, op := range ops {
+		select {
+		case err := <-op.done:
+			if err != nil && first == nil {
+				first = err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return first
+}
+
+func (b *BatchingStore) run(bt *batcher) {
+	defer b.wg.Done()
+
+	for {
+		op, ok := <-bt.ops
+		if !ok {
+			return
+		}
+
+		batch := []*batchOp{op}
+		timer := time.NewTimer(b.flushInterval)
+	collect:
+		for len(batch) < b.maxDocuments {
+			select {
+			case op, ok := <-bt.ops:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, op)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		flushOps(b.Store, bt.table, batch)
+	}
+}
+
+// Close stops buffering and flushes the buffered operations of all the collections. The operations received after
+// Close are sent directly to the underlying store.
+func (b *BatchingStore) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	batchers := make([]*batcher, 0, len(b.batchers))
+	for _, bt := range b.batchers {
+		batchers = append(batchers, bt)
+	}
+	b.mu.Unlock()
+
+	for _, bt := range batchers {
+		// waits for the senders of the collection, the buffer keeps being drained meanwhile
+		bt.mu.Lock()
+		bt.closed = true
+		close(bt.ops)
+		bt.mu.Unlock()
+	}
+
+	b.wg.Wait()
+	log.Info().Msg("flushed the buffered index operations")
+}
+
+// flushOps sends the operations to the store, the consecutive operations with the same action are imported together.
+func flushOps(store Store, table string, ops []*batchOp) {
+	for start := 0; start < len(ops); {
+		end := start + 1
+		for end < len(ops) && ops[end].action == ops[start].action {
+			end++
+		}
+
+		run := ops[start:end]
+		if len(run[0].action) == 0 {
+			for _, op := range run {
+				op.done <- store.DeleteDocuments(context.Background(), table, op.key)
+			}
+		} else {
+			importOps(store, table, run)
+		}
+
+		start = end
+	}
+}
+
+func importOps(store Store, table string, ops []*batchOp) {
+	var buf bytes.Buffer
+	for _, op := range ops {
+		buf.Write(op.doc)
+		buf.WriteByte('\n')
+	}
+
+	err := store.IndexDocuments(context.Background(), table, &buf, IndexDocumentsOptions{
+		Action:    ops[0].action,
+		BatchSize: len(ops),
+	})
+
+	var docErr *IndexDocumentsError
+	if errors.As(err, &docErr) && len(docErr.Errors) == len(ops) {
+		for i, op := range ops {
+			op.done <- docErr.Errors[i]
+		}
+		return
+	}
+
+	for _, op := range ops {
+		op.done <- err
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/server/config"
+)
+
+type recordingStore struct {
+	NoopStore
+
+	sync.Mutex
+	calls  []string
+	reject string
+}
+
+func (r *recordingStore) IndexDocuments(_ context.Context, table string, documents io.Reader, options IndexDocumentsOptions) error {
+	data, _ := io.ReadAll(documents)
+	docs := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	r.Lock()
+	r.calls = append(r.calls, fmt.Sprintf("%s %s %s", table, options.Action, strings.Join(docs, ",")))
+	r.Unlock()
+
+	var failed bool
+	docErrors := make([]error, len(docs))
+	for i, doc := range docs {
+		if doc == r.reject {
+			failed = true
+			docErrors[i] = NewSearchError(http.StatusBadRequest, ErrCodeIndexingDocuments, "rejected %s", doc)
+		}
+	}
+	if failed {
+		return &IndexDocumentsError{Errors: docErrors}
+	}
+	return nil
+}
+
+func (r *recordingStore) DeleteDocuments(_ context.Context, table string, key string) error {
+	r.Lock()
+	r.calls = append(r.calls, fmt.Sprintf("%s delete %s", table, key))
+	r.Unlock()
+	return nil
+}
+
+// blockingStore blocks the imports of a table until it is released.
+type blockingStore struct {
+	recordingStore
+
+	table   string
+	release chan struct{}
+}
+
+func (b *blockingStore) IndexDocuments(ctx context.Context, table string, documents io.Reader, options IndexDocumentsOptions) error {
+	if table == b.table {
+		<-b.release
+	}
+	return b.recordingStore.IndexDocuments(ctx, table, documents, options)
+}
+
+func TestBatchingStore(t *testing.T) {
+	t.Run("ordering", func(t *testing.T) {
+		inner := &recordingStore{}
+		store := NewBatchingStore(inner, &config.SearchIndexBatchConfig{MaxDocuments: 10, FlushInterval: time.Hour})
+
+		var wg sync.WaitGroup
+		ops := []func() error{
+			func() error {
+				return store.IndexDocuments(context.TODO(), "t1", bytes.NewReader([]byte("a\nb\n")), IndexDocumentsOptions{Action: "upsert"})
+			},
+			func() error { return store.DeleteDocuments(context.TODO(), "t1", "a") },
+			func() error {
+				return store.IndexDocuments(context.TODO(), "t1", bytes.NewReader([]byte("c")), IndexDocumentsOptions{Action: "upsert"})
+			},
+			func() error {
+				return store.IndexDocuments(context.TODO(), "t2", bytes.NewReader([]byte("d")), IndexDocumentsOptions{Action: "create"})
+			},
+		}
+		for _, op := range ops {
+			wg.Add(1)
+			go func(op func() error) {
+				defer wg.Done()
+				require.NoError(t, op())
+			}(op)
+			// let the operation reach the buffer before the next one
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		inner.Lock()
+		require.Empty(t, inner.calls)
+		inner.Unlock()
+
+		// nothing reached the size or the interval, the operations are only flushed on close
+		store.Close()
+		wg.Wait()
+		require.ElementsMatch(t, []string{
+			"t1 upsert a,b",
+			"t1 delete a",
+			"t1 upsert c",
+			"t2 create d",
+		}, inner.calls)
+
+		var t1 []string
+		for _, call := range inner.calls {
+			if strings.HasPrefix(call, "t1") {
+				t1 = append(t1, call)
+			}
+		}
+		require.Equal(t, []string{"t1 upsert a,b", "t1 delete a", "t1 upsert c"}, t1)
+
+		// after close the operations are not buffered
+		require.NoError(t, store.DeleteDocuments(context.TODO(), "t1", "c"))
+		require.Equal(t, "t1 delete c", inner.calls[len(inner.calls)-1])
+	})
+
+	t.Run("max_documents", func(t *testing.T) {
+		inner := &recordingStore{}
+		store := NewBatchingStore(inner, &config.SearchIndexBatchConfig{MaxDocuments: 2, FlushInterval: time.Hour})
+		defer store.Close()
+
+		err := store.IndexDocuments(context.TODO(), "t1", bytes.NewReader([]byte("a\nb\n")), IndexDocumentsOptions{Action: "upsert"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"t1 upsert a,b"}, inner.calls)
+	})
+
+	t.Run("flush_interval", func(t *testing.T) {
+		inner := &recordingStore{}
+		store := NewBatchingStore(inner, &config.SearchIndexBatchConfig{MaxDocuments: 100, FlushInterval: time.Millisecond})
+		defer store.Close()
+
+		err := store.IndexDocuments(context.TODO(), "t1", bytes.NewReader([]byte("a")), IndexDocumentsOptions{Action: "upsert"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"t1 upsert a"}, inner.calls)
+	})
+
+	t.Run("full_buffer", func(t *testing.T) {
+		inner := &blockingStore{table: "t1", release: make(chan struct{})}
+		store := NewBatchingStore(inner, &config.SearchIndexBatchConfig{MaxDocuments: 1, FlushInterval: time.Hour})
+
+		// the first document of t1 is being flushed, the second one fills the buffer and the third one waits for room
+		var wg sync.WaitGroup
+		for _, doc := range []string{"a", "b", "c"} {
+			wg.Add(1)
+			go func(doc string) {
+				defer wg.Done()
+				_ = store.IndexDocuments(context.TODO(), "t1", bytes.NewReader([]byte(doc)), IndexDocumentsOptions{Action: "upsert"})
+			}(doc)
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		// the other collections are not blocked by the full buffer of t1
+		done := make(chan error)
+		go func() {
+			done <- store.IndexDocuments(context.TODO(), "t2", bytes.NewReader([]byte("d")), IndexDocumentsOptions{Action: "upsert"})
+		}()
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "the index operations of t2 are blocked by t1")
+		}
+
+		close(inner.release)
+		wg.Wait()
+		store.Close()
+		require.Len(t, inner.calls, 4)
+	})
+
+	t.Run("per_document_errors", func(t *testing.T) {
+		inner := &recordingStore{reject: "b"}
+		store := NewBatchingStore(inner, &config.SearchIndexBatchConfig{MaxDocuments: 2, FlushInterval: time.Hour})
+		defer store.Close()
+
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		for i, doc := range []string{"a", "b"} {
+			wg.Add(1)
+			go func(i int, doc string) {
+				defer wg.Done()
+				errs[i] = store.IndexDocuments(context.TODO(), "t1", bytes.NewReader([]byte(doc)), IndexDocumentsOptions{Action: "upsert"})
+			}(i, doc)
+			time.Sleep(10 * time.Millisecond)
+		}
+		wg.Wait()
+
+		require.Len(t, inner.calls, 1)
+		require.NoError(t, errs[0])
+		require.Equal(t, NewSearchError(http.StatusBadRequest, ErrCodeIndexingDocuments, "rejected b"), errs[1])
+	})
+}
+
+func TestStoreIndexDocumentsErrors(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"success":true}
+{"success":false,"code":400,"error":"Bad JSON.","document":"{"}
+{"success":true}`))
+	})
+	defer server.Close()
+
+	store := newStoreImpl(testSearchConfig(t, server.Listener.Addr().String()))
+	err := store.IndexDocuments(context.TODO(), "t1", bytes.NewReader([]byte("{}\n{\n{}")), IndexDocumentsOptions{
+		Action:    "upsert",
+		BatchSize: 3,
+	})
+	require.Equal(t, &IndexDocumentsError{Errors: []error{
+		nil,
+		NewSearchError(http.StatusBadRequest, ErrCodeIndexingDocuments, "Bad JSON."),
+		nil,
+	}}, err)
+	require.Equal(t, "Bad JSON.", err.Error())
+}
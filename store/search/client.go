@@ -0,0 +1,168 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	goerrors "errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sony/gobreaker"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/typesense/typesense-go/typesense"
+	tsApi "github.com/typesense/typesense-go/typesense/api"
+	"github.com/typesense/typesense-go/typesense/api/circuit"
+)
+
+const breakerName = "search"
+
+// breaker keeps track of the state of the circuit breaker in front of the search backend, so that the health of the
+// backend can be reported and the calls failing fast can tell the client when to retry.
+type breaker struct {
+	sync.RWMutex
+
+	openTimeout time.Duration
+	state       gobreaker.State
+	openedAt    time.Time
+}
+
+func (b *breaker) onStateChange(_ string, from gobreaker.State, to gobreaker.State) {
+	b.Lock()
+	b.state = to
+	if to == gobreaker.StateOpen {
+		b.openedAt = time.Now()
+	}
+	b.Unlock()
+
+	log.Warn().Str("from", from.String()).Str("to", to.String()).Msg("search circuit breaker state changed")
+	metrics.UpdateSearchCircuitBreakerState(from.String(), to.String(), to == gobreaker.StateOpen)
+}
+
+// retryAfter returns how long the breaker stays open, it is zero if the breaker is not open.
+func (b *breaker) retryAfter() time.Duration {
+	b.RLock()
+	defer b.RUnlock()
+
+	if b.state != gobreaker.StateOpen {
+		return 0
+	}
+
+	if remaining := b.openTimeout - time.Since(b.openedAt); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// newClient returns the search client with a bounded connection pool and a per-call timeout. The calls go through a
+// circuit breaker that opens after the configured number of consecutive failures.
+func newClient(cfg *config.SearchConfig, b *breaker) *typesense.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.MaxConnections > 0 {
+		transport.MaxConnsPerHost = cfg.MaxConnections
+		transport.MaxIdleConnsPerHost = cfg.MaxConnections
+	}
+
+	cb := circuit.NewGoBreaker(
+		circuit.WithGoBreakerName(breakerName),
+		circuit.WithGoBreakerMaxRequests(cfg.CircuitBreaker.HalfOpenRequests),
+		circuit.WithGoBreakerTimeout(cfg.CircuitBreaker.OpenTimeout),
+		circuit.WithGoBreakerReadyToTrip(func(counts gobreaker.Counts) bool {
+			return cfg.CircuitBreaker.ConsecutiveFailures > 0 && counts.ConsecutiveFailures >= cfg.CircuitBreaker.ConsecutiveFailures
+		}),
+		circuit.WithGoBreakerOnStateChange(b.onStateChange),
+	)
+
+	httpClient := circuit.NewHTTPClient(
+		circuit.WithHTTPRequestDoer(&http.Client{
+			Transport: transport,
+			Timeout:   cfg.Timeout,
+		}),
+		circuit.WithCircuitBreaker(cb),
+	)
+
+	// the error is only returned for an invalid server url which is caught by the first call to the backend
+	apiClient, _ := tsApi.NewClientWithResponses(fmt.Sprintf("http://%s:%d", cfg.Host, cfg.Port),
+		tsApi.WithAPIKey(cfg.AuthKey),
+		tsApi.WithHTTPClient(httpClient))
+
+	return typesense.NewClient(typesense.WithAPIClient(apiClient))
+}
+
+func newStoreImpl(cfg *config.SearchConfig) *storeImpl {
+	b := &breaker{
+		openTimeout: cfg.CircuitBreaker.OpenTimeout,
+		state:       gobreaker.StateClosed,
+	}
+
+	return &storeImpl{
+		client:       newClient(cfg, b),
+		breaker:      b,
+		maxRetries:   cfg.MaxRetries,
+		retryBackoff: cfg.RetryBackoff,
+	}
+}
+
+// retry runs the call to the search backend again on a transient failure, with an exponential backoff. It must only be
+// used for idempotent calls.
+func (s *storeImpl) retry(f func() error) error {
+	backoff := s.retryBackoff
+	for attempt := 0; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= s.maxRetries || !isTransient(err) {
+			return err
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// isTransient returns true for the failures that may succeed on retry. A call rejected by the circuit breaker is not
+// retried as the breaker stays open for longer than the retries.
+func isTransient(err error) bool {
+	if goerrors.Is(err, gobreaker.ErrOpenState) || goerrors.Is(err, gobreaker.ErrTooManyRequests) {
+		return false
+	}
+
+	var netErr net.Error
+	if goerrors.As(err, &netErr) {
+		return true
+	}
+
+	var httpErr *typesense.HTTPError
+	if goerrors.As(err, &httpErr) {
+		return httpErr.Status >= http.StatusInternalServerError
+	}
+
+	return false
+}
+
+// unavailable is returned when the calls to the search backend are failing fast.
+func (s *storeImpl) unavailable() error {
+	retryAfter := s.breaker.retryAfter()
+	if retryAfter == 0 {
+		// the breaker is half-open, trial calls are already in flight
+		retryAfter = s.retryBackoff
+	}
+
+	return api.Errorf(api.Code_UNAVAILABLE, "search is unavailable").WithRetry(retryAfter).WithSubsystem(errors.SubsystemSearch)
+}
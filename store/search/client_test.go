@@ -0,0 +1,147 @@
+// Copyright 2022 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/server/config"
+)
+
+// newTestServer starts the server on a port that fits the port of the search config.
+func newTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	server := httptest.NewUnstartedServer(handler)
+	for port := 20000; port < 30000; port++ {
+		l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			continue
+		}
+
+		_ = server.Listener.Close()
+		server.Listener = l
+		server.Start()
+		return server
+	}
+
+	require.Fail(t, "no free port found")
+	return nil
+}
+
+func testSearchConfig(t *testing.T, url string) *config.SearchConfig {
+	host, port, err := net.SplitHostPort(url)
+	require.NoError(t, err)
+	p, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	return &config.SearchConfig{
+		Host:         host,
+		Port:         int16(p),
+		Timeout:      time.Second,
+		MaxRetries:   2,
+		RetryBackoff: time.Millisecond,
+		CircuitBreaker: config.SearchCircuitBreakerConfig{
+			ConsecutiveFailures: 2,
+			OpenTimeout:         time.Minute,
+			HalfOpenRequests:    1,
+		},
+	}
+}
+
+func TestStoreRetry(t *testing.T) {
+	t.Run("transient_failures", func(t *testing.T) {
+		var calls int32
+		server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`[]`))
+		})
+		defer server.Close()
+
+		store := newStoreImpl(testSearchConfig(t, server.Listener.Addr().String()))
+		_, err := store.AllCollections(context.TODO())
+		require.NoError(t, err)
+		require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("retries_exhausted", func(t *testing.T) {
+		var calls int32
+		server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+		defer server.Close()
+
+		store := newStoreImpl(testSearchConfig(t, server.Listener.Addr().String()))
+		_, err := store.AllCollections(context.TODO())
+		require.Error(t, err)
+		require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("not_transient", func(t *testing.T) {
+		var calls int32
+		server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusNotFound)
+		})
+		defer server.Close()
+
+		store := newStoreImpl(testSearchConfig(t, server.Listener.Addr().String()))
+		_, err := store.DescribeCollection(context.TODO(), "t1")
+		require.Equal(t, ErrNotFound, err)
+		require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+}
+
+func TestStoreCircuitBreaker(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	addr := server.Listener.Addr().String()
+	// connections are refused from now on
+	server.Close()
+
+	cfg := testSearchConfig(t, addr)
+	cfg.MaxRetries = 0
+	store := newStoreImpl(cfg)
+	require.NoError(t, store.Health(context.TODO()))
+
+	for i := 0; i < int(cfg.CircuitBreaker.ConsecutiveFailures); i++ {
+		_, err := store.AllCollections(context.TODO())
+		require.Error(t, err)
+	}
+
+	_, err := store.AllCollections(context.TODO())
+	require.Error(t, err)
+	tigrisErr, ok := err.(*api.TigrisError)
+	require.True(t, ok)
+	require.Equal(t, api.Code_UNAVAILABLE, tigrisErr.Code)
+	require.Greater(t, tigrisErr.RetryDelay(), time.Duration(0))
+	require.LessOrEqual(t, tigrisErr.RetryDelay(), cfg.CircuitBreaker.OpenTimeout)
+
+	err = store.Health(context.TODO())
+	require.Error(t, err)
+	require.Equal(t, api.Code_UNAVAILABLE, err.(*api.TigrisError).Code)
+}
@@ -17,6 +17,10 @@ package search
 import (
 	"fmt"
 	"net/http"
+	"strings"
+
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/metrics"
 )
 
 type ErrCode byte
@@ -48,7 +52,48 @@ func (se Error) Error() string {
 	return se.msg
 }
 
+// HTTPCode returns the status of the response of the search backend.
+func (se Error) HTTPCode() int {
+	return se.httpCode
+}
+
+// ErrorCategory returns the category of the error reported by the metrics.
+func (se Error) ErrorCategory() string {
+	return metrics.HTTPErrorCategory(se.httpCode)
+}
+
+// ErrorSubsystem returns the subsystem the error originates from.
+func (se Error) ErrorSubsystem() string {
+	return errors.SubsystemSearch
+}
+
+// ErrorRetryable returns true if the search backend is throttling the requests or is not available.
+func (se Error) ErrorRetryable() bool {
+	switch se.httpCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
 func IsSearchError(err error) bool {
 	_, ok := err.(*Error)
 	return ok
 }
+
+// IndexDocumentsError is returned when some of the documents of an import are rejected by the search backend. Errors
+// has an entry per document of the import, in the same order, which is nil for the indexed documents.
+type IndexDocumentsError struct {
+	Errors []error
+}
+
+func (e *IndexDocumentsError) Error() string {
+	var msgs []string
+	for _, err := range e.Errors {
+		if err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
@@ -16,13 +16,11 @@ package search
 
 import (
 	"context"
-	"fmt"
 	"io"
 
 	"github.com/rs/zerolog/log"
 	qsearch "github.com/tigrisdata/tigris/query/search"
 	"github.com/tigrisdata/tigris/server/config"
-	"github.com/typesense/typesense-go/typesense"
 	tsApi "github.com/typesense/typesense-go/typesense/api"
 )
 
@@ -35,27 +33,21 @@ type Store interface {
 	IndexDocuments(ctx context.Context, table string, documents io.Reader, options IndexDocumentsOptions) error
 	DeleteDocuments(ctx context.Context, table string, key string) error
 	Search(ctx context.Context, table string, query *qsearch.Query, pageNo int) ([]tsApi.SearchResult, error)
+	// Health returns an error if the search backend is known to be unhealthy.
+	Health(ctx context.Context) error
 }
 
 func NewStore(config *config.SearchConfig) (Store, error) {
-	client := typesense.NewClient(
-		typesense.WithServer(fmt.Sprintf("http://%s:%d", config.Host, config.Port)),
-		typesense.WithAPIKey(config.AuthKey))
+	store := newStoreImpl(config)
 	log.Info().Str("host", config.Host).Int16("port", config.Port).Msg("initialized search store")
-	return &storeImpl{
-		client: client,
-	}, nil
+	return store, nil
 }
 
 func NewStoreWithMetrics(config *config.SearchConfig) (Store, error) {
-	client := typesense.NewClient(
-		typesense.WithServer(fmt.Sprintf("http://%s:%d", config.Host, config.Port)),
-		typesense.WithAPIKey(config.AuthKey))
+	store := newStoreImpl(config)
 	log.Info().Str("host", config.Host).Int16("port", config.Port).Msg("initialized search store")
 	return &storeImplWithMetrics{
-		&storeImpl{
-			client: client,
-		},
+		store,
 	}, nil
 }
 
@@ -80,3 +72,4 @@ func (n *NoopStore) DeleteDocuments(context.Context, string, string) error { ret
 func (n *NoopStore) Search(context.Context, string, *qsearch.Query, int) ([]tsApi.SearchResult, error) {
 	return nil, nil
 }
+func (n *NoopStore) Health(context.Context) error { return nil }
@@ -18,11 +18,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
 	"net/http"
+	"time"
 
 	jsoniter "github.com/json-iterator/go"
+	"github.com/sony/gobreaker"
 	qsearch "github.com/tigrisdata/tigris/query/search"
 	"github.com/tigrisdata/tigris/server/metrics"
 	ulog "github.com/tigrisdata/tigris/util/log"
@@ -31,7 +33,10 @@ import (
 )
 
 type storeImpl struct {
-	client *typesense.Client
+	client       *typesense.Client
+	breaker      *breaker
+	maxRetries   int
+	retryBackoff time.Duration
 }
 
 type storeImplWithMetrics struct {
@@ -122,12 +127,20 @@ func (m *storeImplWithMetrics) Search(ctx context.Context, table string, query *
 	return
 }
 
+func (m *storeImplWithMetrics) Health(ctx context.Context) error {
+	return m.s.Health(ctx)
+}
+
 type IndexDocumentsOptions struct {
 	Action    string
 	BatchSize int
 }
 
 func (s *storeImpl) convertToInternalError(err error) error {
+	if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+		return s.unavailable()
+	}
+
 	if e, ok := err.(*typesense.HTTPError); ok {
 		switch e.Status {
 		case http.StatusConflict:
@@ -147,7 +160,10 @@ func (s *storeImpl) convertToInternalError(err error) error {
 }
 
 func (s *storeImpl) DeleteDocuments(_ context.Context, table string, key string) error {
-	_, err := s.client.Collection(table).Document(key).Delete()
+	err := s.retry(func() error {
+		_, err := s.client.Collection(table).Document(key).Delete()
+		return err
+	})
 	return s.convertToInternalError(err)
 }
 
@@ -169,18 +185,29 @@ func (s *storeImpl) IndexDocuments(_ context.Context, table string, reader io.Re
 		Success  bool
 	}
 	if closer != nil {
-		var r resp
-		res, err := io.ReadAll(closer)
-		if err != nil {
-			return err
-		}
-		if err = jsoniter.Unmarshal(res, &r); err != nil {
-			return err
-		}
-		if len(r.Error) > 0 {
-			if err = fmt.Errorf(r.Error); err != nil {
+		// the response has a line per document, in the same order as the documents of the request
+		var (
+			failed    bool
+			docErrors []error
+		)
+		decoder := jsoniter.NewDecoder(closer)
+		for {
+			var r resp
+			if err = decoder.Decode(&r); err == io.EOF {
+				break
+			} else if err != nil {
 				return err
 			}
+
+			if len(r.Error) > 0 {
+				failed = true
+				docErrors = append(docErrors, NewSearchError(r.Code, ErrCodeIndexingDocuments, r.Error))
+			} else {
+				docErrors = append(docErrors, nil)
+			}
+		}
+		if failed {
+			return &IndexDocumentsError{Errors: docErrors}
 		}
 	}
 
@@ -229,9 +256,13 @@ func (s *storeImpl) Search(_ context.Context, table string, query *qsearch.Query
 		})
 	}
 
-	res, err := s.client.MultiSearch.PerformWithContentType(&tsApi.MultiSearchParams{}, tsApi.MultiSearchSearchesParameter{
-		Searches: params,
-	}, StreamContentType)
+	var res *tsApi.MultiSearchResponse
+	err := s.retry(func() (err error) {
+		res, err = s.client.MultiSearch.PerformWithContentType(&tsApi.MultiSearchParams{}, tsApi.MultiSearchSearchesParameter{
+			Searches: params,
+		}, StreamContentType)
+		return
+	})
 	if err != nil {
 		return nil, s.convertToInternalError(err)
 	}
@@ -249,7 +280,11 @@ func (s *storeImpl) Search(_ context.Context, table string, query *qsearch.Query
 }
 
 func (s *storeImpl) AllCollections(_ context.Context) (map[string]*tsApi.CollectionResponse, error) {
-	resp, err := s.client.Collections().Retrieve()
+	var resp []*tsApi.CollectionResponse
+	err := s.retry(func() (err error) {
+		resp, err = s.client.Collections().Retrieve()
+		return
+	})
 	if err != nil {
 		return nil, s.convertToInternalError(err)
 	}
@@ -262,7 +297,11 @@ func (s *storeImpl) AllCollections(_ context.Context) (map[string]*tsApi.Collect
 }
 
 func (s *storeImpl) DescribeCollection(_ context.Context, name string) (*tsApi.CollectionResponse, error) {
-	resp, err := s.client.Collection(name).Retrieve()
+	var resp *tsApi.CollectionResponse
+	err := s.retry(func() (err error) {
+		resp, err = s.client.Collection(name).Retrieve()
+		return
+	})
 	if err != nil {
 		return nil, s.convertToInternalError(err)
 	}
@@ -283,3 +322,11 @@ func (s *storeImpl) DropCollection(_ context.Context, table string) error {
 	_, err := s.client.Collection(table).Delete()
 	return s.convertToInternalError(err)
 }
+
+// Health returns an error with a retry hint while the circuit breaker in front of the search backend is open.
+func (s *storeImpl) Health(_ context.Context) error {
+	if s.breaker != nil && s.breaker.retryAfter() > 0 {
+		return s.unavailable()
+	}
+	return nil
+}
@@ -198,6 +198,16 @@ func TestCollection_Update(t *testing.T) {
 			Map{"schema": Map{"title": coll, "properties": Map{"int_field": Map{"type": "integer"}, "string_field": Map{"type": "string"}, "extra_field": Map{"type": "string"}}, "primary_key": []any{"int_field"}}},
 			http.StatusOK,
 		},
+		{
+			"explicit primary key order",
+			Map{"schema": Map{"title": coll, "properties": Map{"int_field": Map{"type": "integer", "primaryKey": 1}, "string_field": Map{"type": "string"}, "extra_field": Map{"type": "string"}}, "primary_key": []any{"int_field"}}},
+			http.StatusOK,
+		},
+		{
+			"conflicting primary key order",
+			Map{"schema": Map{"title": coll, "properties": Map{"int_field": Map{"type": "integer", "primaryKey": 2}, "string_field": Map{"type": "string"}, "extra_field": Map{"type": "string"}}, "primary_key": []any{"int_field"}}},
+			http.StatusBadRequest,
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
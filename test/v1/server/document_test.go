@@ -1727,6 +1727,178 @@ func TestUpdate_SetAndUnset(t *testing.T) {
 	}
 }
 
+func TestUpdate_Bit(t *testing.T) {
+	db, coll := setupTests(t)
+	defer cleanupTests(t, db)
+
+	insertDocuments(t, db, coll, []Doc{{"pkey_int": 100, "int_value": 13}}, false).
+		Status(http.StatusOK)
+
+	cases := []struct {
+		bit    Map
+		expOut []Doc
+	}{
+		{Map{"int_value": Map{"and": 6}}, []Doc{{"pkey_int": 100, "int_value": 4}}},
+		{Map{"int_value": Map{"or": 3}}, []Doc{{"pkey_int": 100, "int_value": 7}}},
+		{Map{"int_value": Map{"xor": 5}}, []Doc{{"pkey_int": 100, "int_value": 2}}},
+	}
+	for _, c := range cases {
+		updateByFilter(t,
+			db,
+			coll,
+			Map{
+				"filter": Map{
+					"pkey_int": 100,
+				},
+			},
+			Map{
+				"fields": Map{
+					"$bit": c.bit,
+				},
+			},
+			nil).Status(http.StatusOK).
+			JSON().
+			Object().
+			ValueEqual("modified_count", 1)
+
+		readAndValidate(t,
+			db,
+			coll,
+			Map{
+				"pkey_int": 100,
+			},
+			nil,
+			c.expOut)
+	}
+
+	resp := updateByFilter(t,
+		db,
+		coll,
+		Map{
+			"filter": Map{
+				"pkey_int": 100,
+			},
+		},
+		Map{
+			"fields": Map{
+				"$bit": Map{"double_value": Map{"and": 1}},
+			},
+		},
+		nil)
+	testError(resp, http.StatusBadRequest, api.Code_INVALID_ARGUMENT,
+		"$bit can only be applied to an integer field, field 'double_value' is not an integer")
+}
+
+func TestUpdate_ImmutableFields(t *testing.T) {
+	dbName := "db_test_immutable"
+	dropDatabase(t, dbName)
+	createDatabase(t, dbName)
+	defer dropDatabase(t, dbName)
+
+	collectionName := "test_immutable"
+	createCollection(t, dbName, collectionName,
+		Map{
+			"schema": Map{
+				"title": collectionName,
+				"properties": Map{
+					"id":        Map{"type": "integer"},
+					"tenant_id": Map{"type": "string", "x-tigris-immutable": true},
+					"name":      Map{"type": "string"},
+					"account": Map{
+						"type": "object",
+						"properties": Map{
+							"opened_at": Map{"type": "string", "format": "date-time", "x-tigris-immutable": true},
+							"balance":   Map{"type": "integer"},
+						},
+					},
+				},
+				"primary_key": []string{"id"},
+			},
+		}).Status(http.StatusOK)
+
+	insertDocuments(t, dbName, collectionName, []Doc{{
+		"id":        1,
+		"tenant_id": "t1",
+		"name":      "foo",
+		"account":   Map{"opened_at": "2022-10-11T04:19:32+05:30", "balance": 10},
+	}}, false).Status(http.StatusOK)
+
+	cases := []struct {
+		fields    Map
+		immutable string
+	}{
+		{Map{"$set": Map{"tenant_id": "t2"}}, "tenant_id"},
+		{Map{"$unset": []string{"tenant_id"}}, "tenant_id"},
+		{Map{"$set": Map{"account.opened_at": "2022-10-12T04:19:32+05:30"}}, "account.opened_at"},
+		// setting the parent object replaces the immutable field
+		{Map{"$set": Map{"account": Map{"balance": 20}}}, "account.opened_at"},
+		{Map{"$set": Map{"name": "bar"}, "$unset": []string{"tenant_id"}}, "tenant_id"},
+	}
+	for _, c := range cases {
+		resp := updateByFilter(t, dbName, collectionName, Map{"filter": Map{"id": 1}}, Map{"fields": c.fields}, nil)
+		testError(resp, http.StatusBadRequest, api.Code_INVALID_ARGUMENT,
+			fmt.Sprintf("field '%s' is immutable and can't be updated", c.immutable))
+	}
+
+	updateByFilter(t, dbName, collectionName, Map{"filter": Map{"id": 1}},
+		Map{"fields": Map{"$set": Map{"name": "bar", "account.balance": 20}}}, nil).
+		Status(http.StatusOK).
+		JSON().
+		Object().
+		ValueEqual("modified_count", 1)
+
+	readAndValidate(t, dbName, collectionName, Map{"id": 1}, nil, []Doc{{
+		"id":        1,
+		"tenant_id": "t1",
+		"name":      "bar",
+		"account":   Map{"opened_at": "2022-10-11T04:19:32+05:30", "balance": 20},
+	}})
+}
+
+func TestAppendOnlyCollection(t *testing.T) {
+	dbName := "db_test_append_only"
+	dropDatabase(t, dbName)
+	createDatabase(t, dbName)
+	defer dropDatabase(t, dbName)
+
+	collectionName := "test_append_only"
+	createCollection(t, dbName, collectionName,
+		Map{
+			"schema": Map{
+				"title": collectionName,
+				"properties": Map{
+					"id":     Map{"type": "integer"},
+					"action": Map{"type": "string"},
+				},
+				"primary_key":          []string{"id"},
+				"x-tigris-append-only": true,
+			},
+		}).Status(http.StatusOK)
+
+	insertDocuments(t, dbName, collectionName, []Doc{
+		{"id": 1, "action": "login"},
+		{"id": 2, "action": "logout"},
+	}, true).Status(http.StatusOK)
+
+	updateResp := updateByFilter(t, dbName, collectionName, Map{"filter": Map{"id": 1}},
+		Map{"fields": Map{"$set": Map{"action": "logout"}}}, nil)
+	testError(updateResp, http.StatusForbidden, api.Code_PERMISSION_DENIED,
+		fmt.Sprintf("update is not allowed on the append-only collection '%s'", collectionName))
+
+	replaceResp := insertDocuments(t, dbName, collectionName, []Doc{{"id": 2, "action": "login"}}, false)
+	testError(replaceResp, http.StatusForbidden, api.Code_PERMISSION_DENIED,
+		fmt.Sprintf("replace is not allowed on the append-only collection '%s'", collectionName))
+
+	deleteResp := deleteByFilter(t, dbName, collectionName, Map{"filter": Map{"id": 1}})
+	testError(deleteResp, http.StatusForbidden, api.Code_PERMISSION_DENIED,
+		fmt.Sprintf("delete is not allowed on the append-only collection '%s'", collectionName))
+
+	readAndValidate(t, dbName, collectionName, nil, nil, []Doc{
+		{"id": 1, "action": "login"},
+		{"id": 2, "action": "logout"},
+	})
+}
+
 func TestDelete_BadRequest(t *testing.T) {
 	db, coll := setupTests(t)
 	defer cleanupTests(t, db)
@@ -2563,6 +2735,255 @@ func TestTransaction_BadID(t *testing.T) {
 	testError(resp, http.StatusInternalServerError, api.Code_INTERNAL, "session is gone")
 }
 
+func TestTransaction_Search(t *testing.T) {
+	db, coll := setupTests(t)
+	defer cleanupTests(t, db)
+
+	insertDocuments(t, db, coll, []Doc{
+		{"pkey_int": 1, "string_value": "simple_insert1"},
+		{"pkey_int": 2, "string_value": "simple_insert2"},
+	}, true).Status(http.StatusOK)
+
+	e := expect(t)
+	r := e.POST(fmt.Sprintf("/v1/databases/%s/transactions/begin", db)).
+		Expect().Status(http.StatusOK).
+		Body().Raw()
+
+	res := struct {
+		TxCtx api.TransactionCtx `json:"tx_ctx"`
+	}{}
+
+	err := json.Unmarshal([]byte(r), &res)
+	require.NoError(t, err)
+
+	e.POST(getDocumentURL(db, coll, "insert")).
+		WithJSON(Map{"documents": []Doc{{"pkey_int": 3, "string_value": "simple_insert3"}}}).
+		WithHeader("Tigris-Tx-Id", res.TxCtx.Id).
+		WithHeader("Tigris-Tx-Origin", res.TxCtx.Origin).
+		Expect().Status(http.StatusOK).JSON().Object().ValueEqual("status", "inserted")
+
+	e.PUT(getDocumentURL(db, coll, "update")).
+		WithJSON(Map{"filter": Map{"pkey_int": 1}, "fields": Map{"$set": Map{"string_value": "simple_update"}}}).
+		WithHeader("Tigris-Tx-Id", res.TxCtx.Id).
+		WithHeader("Tigris-Tx-Origin", res.TxCtx.Origin).
+		Expect().Status(http.StatusOK).JSON().Object().ValueEqual("status", "updated")
+
+	e.DELETE(getDocumentURL(db, coll, "delete")).
+		WithJSON(Map{"filter": Map{"pkey_int": 2}}).
+		WithHeader("Tigris-Tx-Id", res.TxCtx.Id).
+		WithHeader("Tigris-Tx-Origin", res.TxCtx.Origin).
+		Expect().Status(http.StatusOK).JSON().Object().ValueEqual("status", "deleted")
+
+	for _, q := range []string{"simple_insert3", "simple_update", "simple_insert2"} {
+		resp := e.POST(getDocumentURL(db, coll, "search")).
+			WithJSON(Map{"q": q}).
+			WithHeader("Tigris-Tx-Id", res.TxCtx.Id).
+			WithHeader("Tigris-Tx-Origin", res.TxCtx.Origin).
+			Expect()
+		testError(resp, http.StatusPreconditionFailed, api.Code_FAILED_PRECONDITION,
+			"search is not supported inside an interactive transaction, uncommitted writes of the transaction are not visible to search")
+	}
+
+	e.POST(fmt.Sprintf("/v1/databases/%s/transactions/rollback", db)).
+		WithHeader("Tigris-Tx-Id", res.TxCtx.Id).
+		WithHeader("Tigris-Tx-Origin", res.TxCtx.Origin).
+		Expect().
+		Status(http.StatusOK)
+}
+
+func TestSearch_MultiCollection(t *testing.T) {
+	db, coll := setupTests(t)
+	defer cleanupTests(t, db)
+
+	otherColl := "test_collection_other"
+	createCollection(t, db, otherColl, Map{
+		"schema": Map{
+			"title": otherColl,
+			"properties": Map{
+				"id":           Map{"type": "integer"},
+				"string_value": Map{"type": "string"},
+			},
+			"primary_key": []interface{}{"id"},
+		},
+	}).Status(http.StatusOK)
+
+	insertDocuments(t, db, coll, []Doc{
+		{"pkey_int": 1, "string_value": "multi search term"},
+		{"pkey_int": 2, "string_value": "unrelated"},
+	}, true).Status(http.StatusOK)
+	insertDocuments(t, db, otherColl, []Doc{
+		{"id": 1, "string_value": "multi search term"},
+		{"id": 2, "string_value": "multi search term again"},
+	}, true).Status(http.StatusOK)
+
+	type searchResp struct {
+		Result struct {
+			Hits []struct {
+				Data map[string]interface{} `json:"data"`
+			} `json:"hits"`
+			Meta struct {
+				Found int64 `json:"found"`
+			} `json:"meta"`
+		} `json:"result"`
+	}
+
+	e := expect(t)
+	cases := []struct {
+		collection string
+		pageSize   int
+		hits       int
+		warnings   int
+	}{
+		{"*", 0, 3, 0},
+		{coll + "," + otherColl, 0, 3, 0},
+		{coll + "," + otherColl, 2, 2, 0},
+		{coll + ",not_exists", 0, 1, 1},
+	}
+	for _, c := range cases {
+		resp := e.POST(getDocumentURL(db, c.collection, "search")).
+			WithJSON(Map{"q": "multi", "page_size": c.pageSize}).
+			Expect().Status(http.StatusOK)
+
+		var res searchResp
+		require.NoError(t, json.Unmarshal([]byte(resp.Body().Raw()), &res))
+		require.Len(t, res.Result.Hits, c.hits)
+		for _, h := range res.Result.Hits {
+			require.Contains(t, []interface{}{coll, otherColl}, h.Data["_tigris_collection"])
+		}
+		require.Len(t, resp.Raw().Header.Values(api.HeaderSearchWarnings), c.warnings)
+	}
+
+	resp := e.POST(getDocumentURL(db, "not_exists,not_exists_either", "search")).
+		WithJSON(Map{"q": "multi"}).
+		Expect()
+	testError(resp, http.StatusNotFound, api.Code_NOT_FOUND, "collection doesn't exist 'not_exists'")
+
+	resp = e.POST(getDocumentURL(db, "*", "search")).
+		WithJSON(Map{"q": "multi", "sort": []Map{{"string_value": "$asc"}}}).
+		Expect()
+	testError(resp, http.StatusBadRequest, api.Code_INVALID_ARGUMENT,
+		"sort is not supported when searching multiple collections, hits are ordered by relevance")
+}
+
+func TestSearch_FilterSameAsRead(t *testing.T) {
+	db, coll := setupTests(t)
+	defer cleanupTests(t, db)
+
+	insertDocuments(t, db, coll, []Doc{
+		{"pkey_int": 1, "int_value": 10, "string_value": "alpha, beta"},
+		{"pkey_int": 2, "int_value": 10, "string_value": "gamma"},
+		{"pkey_int": 3, "int_value": 20, "string_value": "omega"},
+	}, true).Status(http.StatusOK)
+
+	e := expect(t)
+	cases := []struct {
+		filter Map
+		keys   []int
+	}{
+		// string with the characters of the filter syntax of the search backend
+		{Map{"string_value": "alpha, beta"}, []int{1}},
+		// range on string is evaluated by the server
+		{Map{"int_value": 10, "string_value": Map{"$gt": "beta"}}, []int{2}},
+		{Map{"$or": []Map{{"int_value": 20}, {"string_value": Map{"$lt": "beta"}}}}, []int{1, 3}},
+	}
+	for _, c := range cases {
+		str := e.POST(getDocumentURL(db, coll, "search")).
+			WithJSON(Map{"filter": c.filter, "sort": []Map{{"pkey_int": "$asc"}}}).
+			Expect().Status(http.StatusOK).
+			Body().Raw()
+
+		var res struct {
+			Result struct {
+				Hits []struct {
+					Data map[string]interface{} `json:"data"`
+				} `json:"hits"`
+			} `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(str), &res))
+
+		var keys []int
+		for _, h := range res.Result.Hits {
+			keys = append(keys, int(h.Data["pkey_int"].(float64)))
+		}
+		require.Equal(t, c.keys, keys)
+	}
+}
+
+func TestSearch_HiddenAndExcludedFields(t *testing.T) {
+	db, _ := setupTests(t)
+	defer cleanupTests(t, db)
+
+	collection := "test_search_hidden_fields"
+	schema := Map{
+		"schema": Map{
+			"title": collection,
+			"properties": Map{
+				"id":    Map{"type": "integer"},
+				"title": Map{"type": "string"},
+				"notes": Map{"type": "string", "searchReturn": false},
+				"owner": Map{
+					"type": "object",
+					"properties": Map{
+						"name":  Map{"type": "string"},
+						"email": Map{"type": "string"},
+					},
+				},
+			},
+			"primary_key": []string{"id"},
+		},
+	}
+	createCollection(t, db, collection, schema).Status(http.StatusOK)
+
+	insertDocuments(t, db, collection, []Doc{
+		{"id": 1, "title": "first", "notes": "confidential remark", "owner": Map{"name": "alice", "email": "alice@example.com"}},
+		{"id": 2, "title": "second", "notes": "public", "owner": Map{"name": "bob", "email": "bob@example.com"}},
+	}, true).Status(http.StatusOK)
+
+	search := func(req Map) []map[string]any {
+		str := expect(t).POST(getDocumentURL(db, collection, "search")).
+			WithJSON(req).
+			Expect().Status(http.StatusOK).
+			Body().Raw()
+
+		var res struct {
+			Result struct {
+				Hits []struct {
+					Data map[string]any `json:"data"`
+				} `json:"hits"`
+			} `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(str), &res))
+
+		var docs []map[string]any
+		for _, h := range res.Result.Hits {
+			docs = append(docs, h.Data)
+		}
+		return docs
+	}
+
+	// the hidden field matches the query, the hit is returned without the matched content
+	docs := search(Map{"q": "confidential", "search_fields": []string{"notes"}})
+	require.Len(t, docs, 1)
+	require.Equal(t, "first", docs[0]["title"])
+	require.NotContains(t, docs[0], "notes")
+
+	// explicitly including the hidden field doesn't return it
+	docs = search(Map{"q": "confidential", "search_fields": []string{"notes"}, "include_fields": []string{"title", "notes"}})
+	require.Len(t, docs, 1)
+	require.Equal(t, map[string]any{"title": "first"}, docs[0])
+
+	// excluding a nested field that matches the query
+	docs = search(Map{"q": "alice", "search_fields": []string{"owner.email"}, "exclude_fields": []string{"owner.email"}})
+	require.Len(t, docs, 1)
+	require.Equal(t, map[string]any{"name": "alice"}, docs[0]["owner"])
+	require.NotContains(t, docs[0], "notes")
+
+	// including a nested field
+	docs = search(Map{"q": "bob", "search_fields": []string{"owner.name"}, "include_fields": []string{"id", "owner.email"}})
+	require.Len(t, docs, 1)
+	require.Equal(t, map[string]any{"id": float64(2), "owner": map[string]any{"email": "bob@example.com"}}, docs[0])
+}
+
 func TestFilteringOnArrays_Primitives(t *testing.T) {
 	db, _ := setupTests(t)
 	defer cleanupTests(t, db)
@@ -45,6 +45,25 @@ func info(t *testing.T) *httpexpect.Response {
 		Expect()
 }
 
+func TestHealthProbes(t *testing.T) {
+	e := httpexpect.New(t, config.GetBaseURL())
+
+	e.GET("/v1/health/live").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().ValueEqual("status", "ok")
+
+	components := e.GET("/v1/health/ready").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		Value("components").Array()
+	components.Length().Equal(3)
+	for i, name := range []string{"fdb", "search", "metadata"} {
+		components.Element(i).Object().ValueEqual("name", name)
+	}
+}
+
 func TestTxForwarder(t *testing.T) {
 	e1 := expectLow(t, config.GetBaseURL())
 	e2 := expectLow(t, config.GetBaseURL2())